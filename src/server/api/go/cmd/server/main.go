@@ -18,6 +18,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,10 +27,13 @@ import (
 	"github.com/memodb-io/Acontext/internal/config"
 	"github.com/memodb-io/Acontext/internal/infra/cache"
 	dbpkg "github.com/memodb-io/Acontext/internal/infra/db"
+	mq "github.com/memodb-io/Acontext/internal/infra/queue"
 	"github.com/memodb-io/Acontext/internal/modules/handler"
+	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"github.com/memodb-io/Acontext/internal/router"
 	"github.com/memodb-io/Acontext/internal/telemetry"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"github.com/samber/do"
 	"go.uber.org/zap"
@@ -90,20 +94,67 @@ func main() {
 	artifactHandler := do.MustInvoke[*handler.ArtifactHandler](inj)
 	taskHandler := do.MustInvoke[*handler.TaskHandler](inj)
 	toolHandler := do.MustInvoke[*handler.ToolHandler](inj)
+	toolReferenceHandler := do.MustInvoke[*handler.ToolReferenceHandler](inj)
+	toolSOPHandler := do.MustInvoke[*handler.ToolSOPHandler](inj)
+	blockReferenceHandler := do.MustInvoke[*handler.BlockReferenceHandler](inj)
+	assetReferenceHandler := do.MustInvoke[*handler.AssetReferenceHandler](inj)
+	projectHandler := do.MustInvoke[*handler.ProjectHandler](inj)
 
 	engine := router.NewRouter(router.RouterDeps{
-		Config:          cfg,
-		DB:              db,
-		Log:             log,
-		SpaceHandler:    spaceHandler,
-		BlockHandler:    blockHandler,
-		SessionHandler:  sessionHandler,
-		DiskHandler:     diskHandler,
-		ArtifactHandler: artifactHandler,
-		TaskHandler:     taskHandler,
-		ToolHandler:     toolHandler,
+		Config:                cfg,
+		DB:                    db,
+		Log:                   log,
+		Redis:                 rdb,
+		SpaceHandler:          spaceHandler,
+		BlockHandler:          blockHandler,
+		SessionHandler:        sessionHandler,
+		DiskHandler:           diskHandler,
+		ArtifactHandler:       artifactHandler,
+		TaskHandler:           taskHandler,
+		ToolHandler:           toolHandler,
+		ToolReferenceHandler:  toolReferenceHandler,
+		ToolSOPHandler:        toolSOPHandler,
+		BlockReferenceHandler: blockReferenceHandler,
+		AssetReferenceHandler: assetReferenceHandler,
+		ProjectHandler:        projectHandler,
+		Metrics:               do.MustInvoke[telemetry.Metrics](inj),
 	})
 
+	// flush artifact access metrics, asset touches and block views periodically, and once more on shutdown;
+	// purge the disks deleted past their grace period
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	var flushers sync.WaitGroup
+	interval := time.Duration(max(cfg.Artifact.AccessFlushIntervalSec, 1)) * time.Second
+	if cfg.Artifact.AccessMetricsEnabled {
+		flushers.Add(1)
+		go func() {
+			defer flushers.Done()
+			service.RunArtifactAccessFlusher(flushCtx, do.MustInvoke[service.ArtifactAccessCounter](inj), interval, log)
+		}()
+	}
+	if cfg.Artifact.TouchAssetRefsEnabled {
+		flushers.Add(1)
+		go func() {
+			defer flushers.Done()
+			service.RunAssetRefTouchFlusher(flushCtx, do.MustInvoke[service.AssetRefToucher](inj), interval, log)
+		}()
+	}
+	if cfg.Block.TrackViewsEnabled {
+		flushers.Add(1)
+		go func() {
+			defer flushers.Done()
+			viewInterval := time.Duration(max(cfg.Block.ViewFlushIntervalSec, 1)) * time.Second
+			service.RunBlockViewFlusher(flushCtx, do.MustInvoke[service.BlockViewRecorder](inj), viewInterval, log)
+		}()
+	}
+	flushers.Add(1)
+	go func() {
+		defer flushers.Done()
+		grace := time.Duration(max(cfg.Artifact.DiskDeleteGraceSec, 0)) * time.Second
+		purgeInterval := time.Duration(max(cfg.Artifact.DiskPurgeIntervalSec, 1)) * time.Second
+		service.RunDiskPurger(flushCtx, do.MustInvoke[service.DiskService](inj), grace, purgeInterval, log)
+	}()
+
 	addr := fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port)
 	srv := &http.Server{Addr: addr, Handler: engine}
 
@@ -123,7 +174,17 @@ func main() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := bootstrap.Shutdown(ctx, bootstrap.ShutdownDeps{
+		Server: srv,
+		StopWorkers: func() {
+			stopFlush()
+			flushers.Wait()
+		},
+		Publisher: do.MustInvoke[*mq.Publisher](inj),
+		MQ:        do.MustInvoke[*amqp.Connection](inj),
+		Redis:     rdb,
+		DB:        db,
+	}); err != nil {
 		log.Sugar().Errorw("server shutdown", "err", err)
 	}
 	log.Sugar().Info("server exited")
@@ -3,6 +3,7 @@ package bootstrap
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"strings"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/memodb-io/Acontext/internal/telemetry"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"github.com/samber/do"
@@ -29,7 +32,14 @@ func BuildContainer() *do.Injector {
 
 	// config
 	do.Provide(inj, func(i *do.Injector) (*config.Config, error) {
-		return config.Load()
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
 	})
 
 	// logger
@@ -55,14 +65,25 @@ func BuildContainer() *do.Injector {
 				&model.Task{},
 				&model.Message{},
 				&model.Block{},
+				&model.BlockMove{},
 				&model.Disk{},
 				&model.Artifact{},
 				&model.AssetReference{},
+				&model.ProjectBucket{},
 				&model.ToolReference{},
 				&model.ToolSOP{},
 				&model.ExperienceConfirmation{},
 				&model.Metric{},
 			)
+			// Asset references used to be unique per content hash alone (migration 003)
+			if d.Migrator().HasIndex(&model.AssetReference{}, "idx_project_sha256") {
+				if err := d.Exec("UPDATE asset_references SET bucket = COALESCE(asset_meta->>'bucket', '') WHERE bucket = ''").Error; err != nil {
+					return nil, err
+				}
+				if err := d.Migrator().DropIndex(&model.AssetReference{}, "idx_project_sha256"); err != nil {
+					return nil, err
+				}
+			}
 		}
 
 		// ensure default project exists
@@ -110,10 +131,40 @@ func BuildContainer() *do.Injector {
 		return mq.NewPublisher(conn, log, cfg)
 	})
 
+	// Prometheus metrics, discarded when disabled
+	do.Provide(inj, func(i *do.Injector) (telemetry.Metrics, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		if !cfg.Telemetry.MetricsEnabled {
+			return telemetry.NopMetrics{}, nil
+		}
+		return telemetry.NewPrometheusMetrics(func(ctx context.Context) (*model.StorageTotals, error) {
+			return do.MustInvoke[repo.StorageStatsRepo](i).Totals(ctx)
+		}), nil
+	})
+
 	// S3
 	do.Provide(inj, func(i *do.Injector) (*blob.S3Deps, error) {
 		cfg := do.MustInvoke[*config.Config](i)
-		return blob.NewS3(context.Background(), cfg)
+		deps, err := blob.NewS3(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		deps.Metrics = do.MustInvoke[telemetry.Metrics](i)
+		return deps, nil
+	})
+	// S3 bucket of each project; projects only get their own bucket when a key to decrypt
+	// its credentials is configured
+	do.Provide(inj, func(i *do.Injector) (blob.S3Resolver, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		deps := do.MustInvoke[*blob.S3Deps](i)
+		if cfg.S3.ProjectBucketKey == "" {
+			return blob.NewDefaultS3Resolver(deps), nil
+		}
+		key, err := base64.StdEncoding.DecodeString(cfg.S3.ProjectBucketKey)
+		if err != nil {
+			return nil, err
+		}
+		return blob.NewProjectS3Resolver(deps, service.ProjectBucketLoader(do.MustInvoke[repo.ProjectBucketRepo](i), key)), nil
 	})
 	// get presign expire duration
 	do.Provide(inj, func(i *do.Injector) (func() time.Duration, error) {
@@ -138,8 +189,12 @@ func BuildContainer() *do.Injector {
 		return repo.NewAssetReferenceRepo(
 			do.MustInvoke[*gorm.DB](i),
 			do.MustInvoke[*blob.S3Deps](i),
+			do.MustInvoke[blob.S3Resolver](i),
 		), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (repo.ProjectRepo, error) {
+		return repo.NewProjectRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
 	do.Provide(inj, func(i *do.Injector) (repo.SpaceRepo, error) {
 		return repo.NewSpaceRepo(do.MustInvoke[*gorm.DB](i)), nil
 	})
@@ -152,7 +207,8 @@ func BuildContainer() *do.Injector {
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (repo.BlockRepo, error) {
-		return repo.NewBlockRepo(do.MustInvoke[*gorm.DB](i)), nil
+		cfg := do.MustInvoke[*config.Config](i)
+		return repo.NewBlockRepo(do.MustInvoke[*gorm.DB](i), cfg.Block.SortStep), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (repo.DiskRepo, error) {
 		return repo.NewDiskRepo(
@@ -169,6 +225,18 @@ func BuildContainer() *do.Injector {
 	do.Provide(inj, func(i *do.Injector) (repo.TaskRepo, error) {
 		return repo.NewTaskRepo(do.MustInvoke[*gorm.DB](i)), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (repo.ToolReferenceRepo, error) {
+		return repo.NewToolReferenceRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (repo.ToolSOPRepo, error) {
+		return repo.NewToolSOPRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (repo.ProjectBucketRepo, error) {
+		return repo.NewProjectBucketRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (repo.StorageStatsRepo, error) {
+		return repo.NewStorageStatsRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
 
 	// Service
 	do.Provide(inj, func(i *do.Injector) (service.SpaceService, error) {
@@ -185,21 +253,67 @@ func BuildContainer() *do.Injector {
 			do.MustInvoke[repo.AssetReferenceRepo](i),
 			do.MustInvoke[*zap.Logger](i),
 			do.MustInvoke[*blob.S3Deps](i),
+			do.MustInvoke[blob.S3Resolver](i),
 			do.MustInvoke[*mq.Publisher](i),
 			do.MustInvoke[*config.Config](i),
 			do.MustInvoke[*redis.Client](i),
 		), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (service.BlockViewRecorder, error) {
+		return service.NewRedisBlockViewRecorder(
+			do.MustInvoke[*redis.Client](i),
+			do.MustInvoke[repo.BlockRepo](i),
+			do.MustInvoke[*zap.Logger](i),
+		), nil
+	})
 	do.Provide(inj, func(i *do.Injector) (service.BlockService, error) {
-		return service.NewBlockService(do.MustInvoke[repo.BlockRepo](i)), nil
+		cfg := do.MustInvoke[*config.Config](i)
+		var views service.BlockViewRecorder
+		if cfg.Block.TrackViewsEnabled {
+			views = do.MustInvoke[service.BlockViewRecorder](i)
+		}
+		return service.NewBlockService(
+			do.MustInvoke[repo.BlockRepo](i),
+			views,
+			service.BlockTreeLimits{MaxDepth: cfg.Block.MaxDepth, MaxChildren: cfg.Block.MaxChildren},
+		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.DiskService, error) {
 		return service.NewDiskService(do.MustInvoke[repo.DiskRepo](i)), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (service.ArtifactAccessCounter, error) {
+		return service.NewRedisArtifactAccessCounter(
+			do.MustInvoke[*redis.Client](i),
+			do.MustInvoke[repo.ArtifactRepo](i),
+			do.MustInvoke[*zap.Logger](i),
+		), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (service.AssetRefToucher, error) {
+		return service.NewRedisAssetRefToucher(
+			do.MustInvoke[*redis.Client](i),
+			do.MustInvoke[repo.DiskRepo](i),
+			do.MustInvoke[repo.AssetReferenceRepo](i),
+			do.MustInvoke[*zap.Logger](i),
+		), nil
+	})
 	do.Provide(inj, func(i *do.Injector) (service.ArtifactService, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		var access service.ArtifactAccessCounter
+		if cfg.Artifact.AccessMetricsEnabled {
+			access = do.MustInvoke[service.ArtifactAccessCounter](i)
+		}
+		var touch service.AssetRefToucher
+		if cfg.Artifact.TouchAssetRefsEnabled {
+			touch = do.MustInvoke[service.AssetRefToucher](i)
+		}
 		return service.NewArtifactService(
 			do.MustInvoke[repo.ArtifactRepo](i),
-			do.MustInvoke[*blob.S3Deps](i),
+			do.MustInvoke[repo.AssetReferenceRepo](i),
+			do.MustInvoke[blob.S3Resolver](i),
+			access,
+			touch,
+			httpclient.NewRemoteFetcher(time.Duration(cfg.Artifact.FromURLTimeoutSec)*time.Second, cfg.Artifact.FromURLMaxSizeB),
+			cfg.Artifact.VerifyClaimedSHA256,
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.TaskService, error) {
@@ -208,6 +322,33 @@ func BuildContainer() *do.Injector {
 			do.MustInvoke[*zap.Logger](i),
 		), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (service.ToolReferenceService, error) {
+		return service.NewToolReferenceService(do.MustInvoke[repo.ToolReferenceRepo](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (service.ToolSOPService, error) {
+		return service.NewToolSOPService(
+			do.MustInvoke[repo.ToolSOPRepo](i),
+			do.MustInvoke[repo.BlockRepo](i),
+			do.MustInvoke[repo.ToolReferenceRepo](i),
+		), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (service.BlockReferenceService, error) {
+		return service.NewBlockReferenceService(
+			do.MustInvoke[repo.BlockRepo](i),
+			do.MustInvoke[repo.DiskRepo](i),
+			do.MustInvoke[repo.ArtifactRepo](i),
+			do.MustInvoke[service.ArtifactService](i),
+		), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (service.AssetReferenceService, error) {
+		return service.NewAssetReferenceService(do.MustInvoke[repo.AssetReferenceRepo](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (service.ProjectService, error) {
+		return service.NewProjectService(
+			do.MustInvoke[repo.ProjectRepo](i),
+			do.MustInvoke[*config.Config](i),
+		), nil
+	})
 
 	// Handler
 	do.Provide(inj, func(i *do.Injector) (*handler.SpaceHandler, error) {
@@ -217,9 +358,15 @@ func BuildContainer() *do.Injector {
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (*handler.SessionHandler, error) {
+		cfg := do.MustInvoke[*config.Config](i)
 		return handler.NewSessionHandler(
 			do.MustInvoke[service.SessionService](i),
 			do.MustInvoke[*httpclient.CoreClient](i),
+			normalizer.Limits{
+				MaxParts:         cfg.Normalizer.MaxParts,
+				MaxPartDataSizeB: cfg.Normalizer.MaxPartDataSizeB,
+				MaxMessageSizeB:  cfg.Normalizer.MaxMessageSizeB,
+			},
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (*handler.BlockHandler, error) {
@@ -232,7 +379,13 @@ func BuildContainer() *do.Injector {
 		return handler.NewDiskHandler(do.MustInvoke[service.DiskService](i)), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (*handler.ArtifactHandler, error) {
-		return handler.NewArtifactHandler(do.MustInvoke[service.ArtifactService](i)), nil
+		cfg := do.MustInvoke[*config.Config](i)
+		return handler.NewArtifactHandler(
+			do.MustInvoke[service.ArtifactService](i),
+			do.MustInvoke[service.DiskService](i),
+			cfg.Artifact.MaxInlineContentSizeB,
+			cfg.Artifact.AutoCreateDefaultDisk,
+		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (*handler.TaskHandler, error) {
 		return handler.NewTaskHandler(do.MustInvoke[service.TaskService](i)), nil
@@ -240,6 +393,21 @@ func BuildContainer() *do.Injector {
 	do.Provide(inj, func(i *do.Injector) (*handler.ToolHandler, error) {
 		return handler.NewToolHandler(do.MustInvoke[*httpclient.CoreClient](i)), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (*handler.ToolReferenceHandler, error) {
+		return handler.NewToolReferenceHandler(do.MustInvoke[service.ToolReferenceService](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (*handler.ToolSOPHandler, error) {
+		return handler.NewToolSOPHandler(do.MustInvoke[service.ToolSOPService](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (*handler.BlockReferenceHandler, error) {
+		return handler.NewBlockReferenceHandler(do.MustInvoke[service.BlockReferenceService](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (*handler.AssetReferenceHandler, error) {
+		return handler.NewAssetReferenceHandler(do.MustInvoke[service.AssetReferenceService](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (*handler.ProjectHandler, error) {
+		return handler.NewProjectHandler(do.MustInvoke[service.ProjectService](i)), nil
+	})
 
 	return inj
 }
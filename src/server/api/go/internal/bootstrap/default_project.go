@@ -5,6 +5,7 @@ import (
 
 	"github.com/memodb-io/Acontext/internal/config"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/tokens"
 	"go.uber.org/zap"
@@ -66,6 +67,14 @@ func EnsureDefaultProjectExists(ctx context.Context, db *gorm.DB, cfg *config.Co
 			return cErr
 		}
 		log.Sugar().Infow("default project created", "project", newP.ID)
+
+		if cfg.Artifact.AutoCreateDefaultDisk {
+			disk, dErr := repo.NewDiskRepo(db, nil).GetOrCreateDefault(ctx, newP.ID)
+			if dErr != nil {
+				return dErr
+			}
+			log.Sugar().Infow("default disk created", "project", newP.ID, "disk", disk.ID)
+		}
 		return nil
 
 	default:
@@ -0,0 +1,84 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/infra/cache"
+	dbpkg "github.com/memodb-io/Acontext/internal/infra/db"
+	mq "github.com/memodb-io/Acontext/internal/infra/queue"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// DefaultWorkersTimeout is how long Shutdown waits for the workers when WorkersTimeout is zero
+const DefaultWorkersTimeout = 5 * time.Second
+
+// ShutdownDeps lists what Shutdown stops. Nil fields are skipped.
+type ShutdownDeps struct {
+	Server *http.Server
+	// StopWorkers stops the background workers and waits for them to finish their last
+	// run, e.g. the artifact access flusher persisting the buffered counts
+	StopWorkers func()
+	// WorkersTimeout bounds the wait for StopWorkers, DefaultWorkersTimeout when zero
+	WorkersTimeout time.Duration
+	Publisher      *mq.Publisher
+	MQ             *amqp.Connection
+	Redis          *redis.Client
+	DB             *gorm.DB
+}
+
+// Shutdown stops the server gracefully. It stops accepting requests and waits for the
+// in-flight ones until ctx is done, stops the background workers within WorkersTimeout,
+// then closes the pools. The workers get their own timeout so that slow requests using up
+// ctx do not close the pools under a worker still writing. Every step runs even if an
+// earlier one fails, and the errors are joined.
+func Shutdown(ctx context.Context, d ShutdownDeps) error {
+	var errs []error
+	step := func(name string, fn func() error) {
+		if err := fn(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	if d.Server != nil {
+		step("drain http server", func() error { return d.Server.Shutdown(ctx) })
+	}
+	if d.StopWorkers != nil {
+		step("stop workers", func() error {
+			timeout := d.WorkersTimeout
+			if timeout <= 0 {
+				timeout = DefaultWorkersTimeout
+			}
+			done := make(chan struct{})
+			go func() {
+				d.StopWorkers()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-time.After(timeout):
+				return fmt.Errorf("workers still running after %s", timeout)
+			}
+		})
+	}
+	// Close the pools last, the workers may still use them
+	if d.Publisher != nil {
+		step("close rabbitmq channel", d.Publisher.Close)
+	}
+	if d.MQ != nil {
+		step("close rabbitmq connection", d.MQ.Close)
+	}
+	if d.Redis != nil {
+		step("close redis", func() error { return cache.Close(d.Redis) })
+	}
+	if d.DB != nil {
+		step("close database", func() error { return dbpkg.Close(d.DB) })
+	}
+	return errors.Join(errs...)
+}
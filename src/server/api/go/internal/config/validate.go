@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Validate checks the configuration once at boot and normalizes the S3 endpoints in place.
+// It returns every misconfiguration found, joined in a single error.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.App.Port < 1 || c.App.Port > 65535 {
+		fail("app.port: %d is not a valid port", c.App.Port)
+	}
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		fail("database.dsn is required")
+	}
+
+	if c.Redis.Addr == "" {
+		fail("redis.addr is required")
+	} else if _, _, err := net.SplitHostPort(c.Redis.Addr); err != nil {
+		fail("redis.addr: %q is not host:port", c.Redis.Addr)
+	}
+
+	if c.RabbitMQ.URL == "" {
+		fail("rabbitmq.url is required")
+	} else if u, err := url.Parse(c.RabbitMQ.URL); err != nil || (u.Scheme != "amqp" && u.Scheme != "amqps") {
+		fail("rabbitmq.url: must be an amqp:// or amqps:// URL")
+	}
+
+	if c.S3.Bucket == "" {
+		fail("s3.bucket is required")
+	}
+	if c.S3.Region == "" {
+		fail("s3.region is required")
+	}
+	if (c.S3.AccessKey == "") != (c.S3.SecretKey == "") {
+		fail("s3.accessKey and s3.secretKey must be set together")
+	}
+	endpoint, err := normalizeEndpoint(c.S3.Endpoint)
+	if err != nil {
+		fail("s3.endpoint: %w", err)
+	}
+	internalEndpoint, err := normalizeEndpoint(c.S3.InternalEndpoint)
+	if err != nil {
+		fail("s3.internalEndpoint: %w", err)
+	}
+	c.S3.Endpoint, c.S3.InternalEndpoint = endpoint, internalEndpoint
+	for projectID, sse := range c.S3.ProjectSSE {
+		if _, err := uuid.Parse(projectID); err != nil {
+			fail("s3.projectSSE: %q is not a project ID", projectID)
+		}
+		if sse.KMSKeyID == "" {
+			fail("s3.projectSSE.%s.kmsKeyID is required", projectID)
+		}
+	}
+	if c.S3.ProjectBucketKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.S3.ProjectBucketKey); err != nil || len(key) != 32 {
+			fail("s3.projectBucketKey: must be a base64-encoded 32-byte key")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// normalizeEndpoint trims an endpoint and defaults its scheme to https. Empty stays empty.
+func normalizeEndpoint(endpoint string) (string, error) {
+	ep := strings.TrimSpace(endpoint)
+	if ep == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(ep, "http://") && !strings.HasPrefix(ep, "https://") {
+		ep = "https://" + ep
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid URL", endpoint)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%q has no host", endpoint)
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,97 @@
+package blob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DefaultKeyPrefixTemplate keeps objects under `<kind>/<project_id>`, e.g. `disks/<project_id>`
+const DefaultKeyPrefixTemplate = "{kind}/{project_id}"
+
+// Kinds of objects stored in the bucket, available to templates as {kind}
+const (
+	KeyKindDisks  = "disks"
+	KeyKindAssets = "assets"
+	KeyKindParts  = "parts"
+)
+
+var keyPrefixPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var allowedKeyPrefixPlaceholders = map[string]bool{
+	"env":        true,
+	"kind":       true,
+	"project_id": true,
+	"disk_id":    true,
+}
+
+// KeyPrefixVars holds the values substituted into a key prefix template
+type KeyPrefixVars struct {
+	Env       string
+	Kind      string
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+}
+
+// ValidateKeyPrefixTemplate checks that the template only references the allowed
+// placeholders ({env}, {kind}, {project_id}, {disk_id}) and scopes keys by project.
+func ValidateKeyPrefixTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("key prefix template is empty")
+	}
+	for _, m := range keyPrefixPlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		if !allowedKeyPrefixPlaceholders[m[1]] {
+			return fmt.Errorf("key prefix template: unknown placeholder {%s}", m[1])
+		}
+	}
+	if rest := keyPrefixPlaceholder.ReplaceAllString(tmpl, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("key prefix template: unbalanced braces in %q", tmpl)
+	}
+	if !strings.Contains(tmpl, "{project_id}") {
+		return fmt.Errorf("key prefix template must contain {project_id}")
+	}
+	return nil
+}
+
+// RenderKeyPrefix substitutes vars into a validated template. Placeholders without a
+// value (e.g. {disk_id} for session assets) are dropped together with their empty segment.
+func RenderKeyPrefix(tmpl string, vars KeyPrefixVars) string {
+	values := map[string]string{
+		"env":  vars.Env,
+		"kind": vars.Kind,
+	}
+	if vars.ProjectID != uuid.Nil {
+		values["project_id"] = vars.ProjectID.String()
+	}
+	if vars.DiskID != uuid.Nil {
+		values["disk_id"] = vars.DiskID.String()
+	}
+
+	rendered := keyPrefixPlaceholder.ReplaceAllStringFunc(tmpl, func(p string) string {
+		return values[p[1:len(p)-1]]
+	})
+
+	segments := make([]string, 0)
+	for _, seg := range strings.Split(rendered, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// KeyPrefix renders the configured key prefix template for an object kind
+func (u *S3Deps) KeyPrefix(kind string, projectID uuid.UUID, diskID uuid.UUID) string {
+	tmpl := u.KeyPrefixTemplate
+	if tmpl == "" {
+		tmpl = DefaultKeyPrefixTemplate
+	}
+	return RenderKeyPrefix(tmpl, KeyPrefixVars{
+		Env:       u.Env,
+		Kind:      kind,
+		ProjectID: projectID,
+		DiskID:    diskID,
+	})
+}
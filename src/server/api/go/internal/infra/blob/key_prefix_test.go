@@ -0,0 +1,73 @@
+package blob
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderKeyPrefix(t *testing.T) {
+	projectID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	diskID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	tests := []struct {
+		name string
+		tmpl string
+		vars KeyPrefixVars
+		want string
+	}{
+		{
+			name: "all placeholders",
+			tmpl: "{env}/{kind}/{project_id}/{disk_id}",
+			vars: KeyPrefixVars{Env: "staging", Kind: KeyKindDisks, ProjectID: projectID, DiskID: diskID},
+			want: "staging/disks/11111111-1111-1111-1111-111111111111/22222222-2222-2222-2222-222222222222",
+		},
+		{
+			name: "default template keeps legacy layout",
+			tmpl: DefaultKeyPrefixTemplate,
+			vars: KeyPrefixVars{Env: "prod", Kind: KeyKindAssets, ProjectID: projectID},
+			want: "assets/11111111-1111-1111-1111-111111111111",
+		},
+		{
+			name: "missing disk drops its segment",
+			tmpl: "{env}/{kind}/{project_id}/{disk_id}",
+			vars: KeyPrefixVars{Env: "prod", Kind: KeyKindParts, ProjectID: projectID},
+			want: "prod/parts/11111111-1111-1111-1111-111111111111",
+		},
+		{
+			name: "static text around placeholders",
+			tmpl: "acontext-{env}/{project_id}",
+			vars: KeyPrefixVars{Env: "dev", ProjectID: projectID},
+			want: "acontext-dev/11111111-1111-1111-1111-111111111111",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, ValidateKeyPrefixTemplate(tt.tmpl))
+			assert.Equal(t, tt.want, RenderKeyPrefix(tt.tmpl, tt.vars))
+		})
+	}
+}
+
+func TestValidateKeyPrefixTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		tmpl        string
+		errContains string
+	}{
+		{name: "unknown placeholder", tmpl: "{region}/{project_id}", errContains: "unknown placeholder {region}"},
+		{name: "unbalanced braces", tmpl: "{env/{project_id}", errContains: "unbalanced braces"},
+		{name: "missing project", tmpl: "{env}/{kind}", errContains: "must contain {project_id}"},
+		{name: "empty", tmpl: " ", errContains: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateKeyPrefixTemplate(tt.tmpl)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
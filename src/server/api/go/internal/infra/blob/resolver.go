@@ -0,0 +1,166 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsCfg "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/otel"
+)
+
+// S3Resolver returns the S3 dependencies storing the objects of a project
+type S3Resolver interface {
+	// ForProject returns where new objects of the project are stored
+	ForProject(ctx context.Context, projectID uuid.UUID) (*S3Deps, error)
+	// ForObject returns where an existing object of the project is stored, given the bucket
+	// recorded for it when it was uploaded
+	ForObject(ctx context.Context, projectID uuid.UUID, bucket string) (*S3Deps, error)
+}
+
+type defaultS3Resolver struct{ deps *S3Deps }
+
+// NewDefaultS3Resolver resolves every project to the deployment's bucket
+func NewDefaultS3Resolver(deps *S3Deps) S3Resolver {
+	return defaultS3Resolver{deps: deps}
+}
+
+func (r defaultS3Resolver) ForProject(ctx context.Context, projectID uuid.UUID) (*S3Deps, error) {
+	return r.deps, nil
+}
+
+func (r defaultS3Resolver) ForObject(ctx context.Context, projectID uuid.UUID, bucket string) (*S3Deps, error) {
+	return r.deps, nil
+}
+
+// ProjectBucket is the S3 bucket of a project, with its decrypted credentials
+type ProjectBucket struct {
+	// Endpoint is empty for AWS S3
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	UsePathStyle bool
+}
+
+// ProjectBucketLoader returns the bucket of a project, or nil if the project uses the
+// deployment's bucket
+type ProjectBucketLoader func(ctx context.Context, projectID uuid.UUID) (*ProjectBucket, error)
+
+// projectBucketTTL bounds how long the bucket of a project is reused before it is loaded
+// again, so changed credentials are picked up without a restart
+const projectBucketTTL = 5 * time.Minute
+
+type projectS3Entry struct {
+	deps *S3Deps
+	// bucket is nil for projects using the deployment's bucket
+	bucket   *ProjectBucket
+	loadedAt time.Time
+}
+
+type projectS3Resolver struct {
+	defaultDeps *S3Deps
+	load        ProjectBucketLoader
+	build       func(ctx context.Context, b ProjectBucket) (*S3Deps, error)
+	now         func() time.Time
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]projectS3Entry
+}
+
+// NewProjectS3Resolver resolves the projects that have a bucket of their own, as returned by
+// load, to clients of that bucket, and every other project to defaultDeps. Clients are
+// cached per project and only rebuilt when the bucket of the project changes.
+func NewProjectS3Resolver(defaultDeps *S3Deps, load ProjectBucketLoader) S3Resolver {
+	return &projectS3Resolver{
+		defaultDeps: defaultDeps,
+		load:        load,
+		build:       defaultDeps.forBucket,
+		now:         time.Now,
+		cache:       map[uuid.UUID]projectS3Entry{},
+	}
+}
+
+func (r *projectS3Resolver) ForProject(ctx context.Context, projectID uuid.UUID) (*S3Deps, error) {
+	r.mu.Lock()
+	entry, cached := r.cache[projectID]
+	r.mu.Unlock()
+	if cached && r.now().Sub(entry.loadedAt) < projectBucketTTL {
+		return entry.deps, nil
+	}
+
+	bucket, err := r.load(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("load bucket of project %s: %w", projectID, err)
+	}
+
+	deps := r.defaultDeps
+	if bucket != nil {
+		if cached && entry.bucket != nil && *entry.bucket == *bucket {
+			deps = entry.deps
+		} else if deps, err = r.build(ctx, *bucket); err != nil {
+			return nil, fmt.Errorf("connect to bucket of project %s: %w", projectID, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[projectID] = projectS3Entry{deps: deps, bucket: bucket, loadedAt: r.now()}
+	r.mu.Unlock()
+	return deps, nil
+}
+
+// ForObject returns the bucket of the project when the object was stored there, else the
+// deployment's bucket, which also keeps message parts and the objects uploaded before the
+// project had a bucket of its own
+func (r *projectS3Resolver) ForObject(ctx context.Context, projectID uuid.UUID, bucket string) (*S3Deps, error) {
+	deps, err := r.ForProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if bucket != "" && bucket == deps.Bucket {
+		return deps, nil
+	}
+	return r.defaultDeps, nil
+}
+
+// forBucket returns S3 dependencies for the bucket of a project, keeping the key layout of u.
+// The bucket is reached through its own endpoint for both requests and presigned URLs, and
+// new objects get the default encryption of the bucket.
+func (u *S3Deps) forBucket(ctx context.Context, b ProjectBucket) (*S3Deps, error) {
+	acfg, err := awsCfg.LoadDefaultConfig(ctx,
+		awsCfg.WithRegion(b.Region),
+		awsCfg.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(b.AccessKey, b.SecretKey, "")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if otel.GetTracerProvider() != nil {
+		otelaws.AppendMiddlewares(&acfg.APIOptions)
+	}
+
+	client := s3.NewFromConfig(acfg, func(o *s3.Options) {
+		if b.Endpoint != "" {
+			o.BaseEndpoint = aws.String(b.Endpoint)
+		}
+		o.UsePathStyle = b.UsePathStyle
+	})
+
+	return &S3Deps{
+		Client:    client,
+		Uploader:  manager.NewUploader(client),
+		Presigner: s3.NewPresignClient(client),
+		Bucket:    b.Bucket,
+
+		KeyPrefixTemplate: u.KeyPrefixTemplate,
+		Env:               u.Env,
+		Metrics:           u.Metrics,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectS3Resolver_ForProject(t *testing.T) {
+	ctx := context.Background()
+	projectA, projectB, projectC := uuid.New(), uuid.New(), uuid.New()
+	buckets := map[uuid.UUID]*ProjectBucket{
+		projectA: {Endpoint: "https://s3.a.example.com", Region: "us-east-1", Bucket: "bucket-a", AccessKey: "a", SecretKey: "a", UsePathStyle: true},
+		projectB: {Endpoint: "https://s3.b.example.com", Region: "eu-west-1", Bucket: "bucket-b", AccessKey: "b", SecretKey: "b", UsePathStyle: true},
+	}
+	loads := 0
+	load := func(ctx context.Context, projectID uuid.UUID) (*ProjectBucket, error) {
+		loads++
+		if b, ok := buckets[projectID]; ok {
+			copied := *b
+			return &copied, nil
+		}
+		return nil, nil
+	}
+	defaultDeps := &S3Deps{Bucket: "acontext-assets", KeyPrefixTemplate: DefaultKeyPrefixTemplate, Env: "test"}
+
+	t.Run("projects resolve to their own bucket", func(t *testing.T) {
+		r := NewProjectS3Resolver(defaultDeps, load)
+
+		depsA, err := r.ForProject(ctx, projectA)
+		require.NoError(t, err)
+		depsB, err := r.ForProject(ctx, projectB)
+		require.NoError(t, err)
+		depsC, err := r.ForProject(ctx, projectC)
+		require.NoError(t, err)
+
+		assert.Equal(t, "bucket-a", depsA.Bucket)
+		assert.Equal(t, "bucket-b", depsB.Bucket)
+		assert.Same(t, defaultDeps, depsC)
+		assert.Equal(t, defaultDeps.KeyPrefixTemplate, depsA.KeyPrefixTemplate)
+
+		urlA, err := depsA.PresignGet(ctx, "disks/report.pdf", time.Minute)
+		require.NoError(t, err)
+		assert.Contains(t, urlA, "https://s3.a.example.com/bucket-a/disks/report.pdf")
+		urlB, err := depsB.PresignGet(ctx, "disks/report.pdf", time.Minute)
+		require.NoError(t, err)
+		assert.Contains(t, urlB, "https://s3.b.example.com/bucket-b/disks/report.pdf")
+	})
+
+	t.Run("clients are cached until the bucket changes", func(t *testing.T) {
+		now := time.Now()
+		builds := 0
+		r := NewProjectS3Resolver(defaultDeps, load).(*projectS3Resolver)
+		r.now = func() time.Time { return now }
+		r.build = func(ctx context.Context, b ProjectBucket) (*S3Deps, error) {
+			builds++
+			return &S3Deps{Bucket: b.Bucket}, nil
+		}
+		loads = 0
+
+		first, err := r.ForProject(ctx, projectA)
+		require.NoError(t, err)
+		again, err := r.ForProject(ctx, projectA)
+		require.NoError(t, err)
+		assert.Same(t, first, again)
+		assert.Equal(t, 1, loads)
+
+		// Reloaded after the TTL, but the client is kept while the bucket is the same
+		now = now.Add(projectBucketTTL)
+		again, err = r.ForProject(ctx, projectA)
+		require.NoError(t, err)
+		assert.Same(t, first, again)
+		assert.Equal(t, 2, loads)
+		assert.Equal(t, 1, builds)
+
+		buckets[projectA].SecretKey = "rotated"
+		now = now.Add(projectBucketTTL)
+		rotated, err := r.ForProject(ctx, projectA)
+		require.NoError(t, err)
+		assert.NotSame(t, first, rotated)
+		assert.Equal(t, 2, builds)
+	})
+
+	t.Run("load errors are returned", func(t *testing.T) {
+		r := NewProjectS3Resolver(defaultDeps, func(ctx context.Context, projectID uuid.UUID) (*ProjectBucket, error) {
+			return nil, errors.New("database unavailable")
+		})
+		_, err := r.ForProject(ctx, projectA)
+		assert.ErrorContains(t, err, "database unavailable")
+	})
+}
+
+func TestProjectS3Resolver_ForObject(t *testing.T) {
+	ctx := context.Background()
+	withBucket, withoutBucket := uuid.New(), uuid.New()
+	defaultDeps := &S3Deps{Bucket: "acontext-assets"}
+	r := NewProjectS3Resolver(defaultDeps, func(ctx context.Context, projectID uuid.UUID) (*ProjectBucket, error) {
+		if projectID == withBucket {
+			return &ProjectBucket{Region: "us-east-1", Bucket: "project-own", AccessKey: "a", SecretKey: "a"}, nil
+		}
+		return nil, nil
+	})
+
+	for _, tt := range []struct {
+		name    string
+		project uuid.UUID
+		bucket  string
+		want    string
+	}{
+		{name: "object in the bucket of the project", project: withBucket, bucket: "project-own", want: "project-own"},
+		// Message parts and objects from before the project had its own bucket
+		{name: "object in the deployment's bucket", project: withBucket, bucket: "acontext-assets", want: "acontext-assets"},
+		{name: "object without a recorded bucket", project: withBucket, bucket: "", want: "acontext-assets"},
+		{name: "project without a bucket", project: withoutBucket, bucket: "project-own", want: "acontext-assets"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := r.ForObject(ctx, tt.project, tt.bucket)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, deps.Bucket)
+		})
+	}
+}
+
+func TestDefaultS3Resolver_ForProject(t *testing.T) {
+	deps := &S3Deps{Bucket: "acontext-assets"}
+	got, err := NewDefaultS3Resolver(deps).ForProject(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Same(t, deps, got)
+}
+
+func TestDefaultS3Resolver_ForObject(t *testing.T) {
+	deps := &S3Deps{Bucket: "acontext-assets"}
+	got, err := NewDefaultS3Resolver(deps).ForObject(context.Background(), uuid.New(), "other")
+	require.NoError(t, err)
+	assert.Same(t, deps, got)
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/url"
 	"path/filepath"
@@ -20,9 +22,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/config"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
+	"github.com/memodb-io/Acontext/internal/telemetry"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 	"go.opentelemetry.io/otel"
 )
@@ -33,6 +39,39 @@ type S3Deps struct {
 	Presigner *s3.PresignClient
 	Bucket    string
 	SSE       *s3types.ServerSideEncryption
+	// ProjectSSE overrides SSE for the uploads of a project, see WithProject
+	ProjectSSE map[uuid.UUID]ProjectSSE
+	// KeyPrefixTemplate and Env build object key prefixes, see KeyPrefix
+	KeyPrefixTemplate string
+	Env               string
+	// Metrics counts the bytes moved to and from the bucket, nil to skip counting
+	Metrics telemetry.Metrics
+}
+
+// addBytes records n bytes moved in the given direction, see telemetry.StorageUpload
+func (u *S3Deps) addBytes(direction string, n int64) {
+	if u.Metrics != nil && n > 0 {
+		u.Metrics.AddStorageBytes(direction, n)
+	}
+}
+
+// countingReadCloser records the bytes read from an object when it is closed
+type countingReadCloser struct {
+	io.ReadCloser
+	u *S3Deps
+	n int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReadCloser) Close() error {
+	r.u.addBytes(telemetry.StorageDownload, r.n)
+	r.n = 0
+	return r.ReadCloser.Close()
 }
 
 func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
@@ -56,24 +95,12 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 		otelaws.AppendMiddlewares(&acfg.APIOptions)
 	}
 
-	// Helper function to normalize endpoint URL
-	normalizeEndpoint := func(endpoint string) string {
-		ep := strings.TrimSpace(endpoint)
-		if ep == "" {
-			return ""
-		}
-		if !strings.HasPrefix(ep, "http://") && !strings.HasPrefix(ep, "https://") {
-			ep = "https://" + ep
-		}
-		return ep
-	}
-
-	// Use InternalEndpoint for S3 operations if available, otherwise fall back to Endpoint
+	// Endpoints are normalized by config.Validate. Use InternalEndpoint for S3 operations
+	// if available, otherwise fall back to Endpoint
 	internalEp := cfg.S3.InternalEndpoint
 	if internalEp == "" {
 		internalEp = cfg.S3.Endpoint
 	}
-	internalEp = normalizeEndpoint(internalEp)
 
 	// S3 client options for internal operations
 	s3InternalOpts := func(o *s3.Options) {
@@ -90,7 +117,7 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 	uploader := manager.NewUploader(client)
 
 	// Create presigner using public endpoint for external access
-	publicEp := normalizeEndpoint(cfg.S3.Endpoint)
+	publicEp := cfg.S3.Endpoint
 	s3PublicOpts := func(o *s3.Options) {
 		if publicEp != "" {
 			if u, uerr := url.Parse(publicEp); uerr == nil {
@@ -108,9 +135,25 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 		sse = &v
 	}
 
+	projectSSE := make(map[uuid.UUID]ProjectSSE, len(cfg.S3.ProjectSSE))
+	for rawID, c := range cfg.S3.ProjectSSE {
+		projectID, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("s3 project sse: invalid project id %q", rawID)
+		}
+		projectSSE[projectID] = ProjectSSE{KMSKeyID: c.KMSKeyID, EncryptionContext: c.EncryptionContext}
+	}
+
 	if cfg.S3.Bucket == "" {
 		return nil, errors.New("s3 bucket is empty")
 	}
+	keyPrefixTemplate := cfg.S3.KeyPrefixTemplate
+	if keyPrefixTemplate == "" {
+		keyPrefixTemplate = DefaultKeyPrefixTemplate
+	}
+	if err := ValidateKeyPrefixTemplate(keyPrefixTemplate); err != nil {
+		return nil, err
+	}
 	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(cfg.S3.Bucket),
 	}); err != nil {
@@ -123,6 +166,10 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 		Presigner: presigner,
 		Bucket:    cfg.S3.Bucket,
 		SSE:       sse,
+
+		ProjectSSE:        projectSSE,
+		KeyPrefixTemplate: keyPrefixTemplate,
+		Env:               cfg.App.Env,
 	}, nil
 }
 
@@ -145,15 +192,46 @@ func (s *S3Deps) PresignPut(ctx context.Context, key, contentType string, expire
 	return ps.URL, nil
 }
 
+type presignGetOptions struct {
+	contentDisposition string
+	contentType        string
+}
+
+// PresignGetOption configures a presigned GET URL
+type PresignGetOption func(*presignGetOptions)
+
+// AsAttachment makes browsers save the download as filename instead of the last segment of
+// the object key, and serves it as contentType when set
+func AsAttachment(filename string, contentType string) PresignGetOption {
+	return func(o *presignGetOptions) {
+		o.contentDisposition = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+		if o.contentDisposition == "" {
+			o.contentDisposition = "attachment"
+		}
+		o.contentType = contentType
+	}
+}
+
 // Generate a pre-signed GET URL
-func (s *S3Deps) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
+func (s *S3Deps) PresignGet(ctx context.Context, key string, expire time.Duration, opts ...PresignGetOption) (string, error) {
 	if key == "" {
 		return "", errors.New("key is empty")
 	}
-	ps, err := s.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+	o := presignGetOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	input := &s3.GetObjectInput{
 		Bucket: &s.Bucket,
 		Key:    &key,
-	}, func(po *s3.PresignOptions) {
+	}
+	if o.contentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(o.contentDisposition)
+	}
+	if o.contentType != "" {
+		input.ResponseContentType = aws.String(o.contentType)
+	}
+	ps, err := s.Presigner.PresignGetObject(ctx, input, func(po *s3.PresignOptions) {
 		po.Expires = expire
 	})
 	if err != nil {
@@ -171,9 +249,123 @@ func cleanETag(etag string) string {
 	return strings.Trim(etag, `"`)
 }
 
+// KeyLookup resolves a content hash to the S3 key of an already stored object,
+// e.g. from the asset_references table. It returns an empty key when the hash is unknown.
+type KeyLookup func(ctx context.Context, sha256 string) (string, error)
+
+// ProjectSSE encrypts the objects of a project with its own KMS key
+type ProjectSSE struct {
+	KMSKeyID string
+	// EncryptionContext is bound to the objects and required by KMS to decrypt them
+	EncryptionContext map[string]string
+}
+
+type uploadOptions struct {
+	keyLookup KeyLookup
+	projectID uuid.UUID
+	key       string
+}
+
+// UploadOption configures a deduplicated upload
+type UploadOption func(*uploadOptions)
+
+// WithKeyLookup checks lookup for an existing object before falling back to
+// scanning the key prefix, turning the common dedup case into a single lookup.
+func WithKeyLookup(lookup KeyLookup) UploadOption {
+	return func(o *uploadOptions) {
+		o.keyLookup = lookup
+	}
+}
+
+// WithProject encrypts the upload with the project's KMS key when one is configured,
+// instead of the global SSE.
+func WithProject(projectID uuid.UUID) UploadOption {
+	return func(o *uploadOptions) {
+		o.projectID = projectID
+	}
+}
+
+// WithKey stores the upload under key, replacing any object there, instead of under a
+// content-addressed key. Existing copies of the content are neither looked up nor reused.
+func WithKey(key string) UploadOption {
+	return func(o *uploadOptions) {
+		o.key = key
+	}
+}
+
+// objectSSE is the server-side encryption of a new object, as set on S3 write requests
+type objectSSE struct {
+	mode              s3types.ServerSideEncryption
+	kmsKeyID          *string
+	encryptionContext *string
+}
+
+// sseFor returns the server-side encryption of a new object of projectID
+func (u *S3Deps) sseFor(projectID uuid.UUID) (objectSSE, error) {
+	if sse, ok := u.ProjectSSE[projectID]; ok && projectID != uuid.Nil {
+		out := objectSSE{mode: s3types.ServerSideEncryptionAwsKms, kmsKeyID: aws.String(sse.KMSKeyID)}
+		if len(sse.EncryptionContext) > 0 {
+			// S3 expects the context as base64-encoded JSON
+			raw, err := sonic.ConfigStd.Marshal(sse.EncryptionContext)
+			if err != nil {
+				return objectSSE{}, fmt.Errorf("marshal encryption context: %w", err)
+			}
+			out.encryptionContext = aws.String(base64.StdEncoding.EncodeToString(raw))
+		}
+		return out, nil
+	}
+	if u.SSE != nil {
+		return objectSSE{mode: *u.SSE}, nil
+	}
+	return objectSSE{}, nil
+}
+
+// applySSE sets the server-side encryption of an upload of projectID
+func (u *S3Deps) applySSE(input *s3.PutObjectInput, projectID uuid.UUID) error {
+	sse, err := u.sseFor(projectID)
+	if err != nil {
+		return err
+	}
+	input.ServerSideEncryption = sse.mode
+	input.SSEKMSKeyId = sse.kmsKeyID
+	input.SSEKMSEncryptionContext = sse.encryptionContext
+	return nil
+}
+
+// lookupExisting returns the asset stored under the key known for sumHex, if the
+// lookup knows one and the object still exists.
+func (u *S3Deps) lookupExisting(ctx context.Context, lookup KeyLookup, sumHex string, contentType string) *model.Asset {
+	if lookup == nil {
+		return nil
+	}
+	key, err := lookup(ctx, sumHex)
+	if err != nil || key == "" {
+		return nil
+	}
+
+	headResult, err := u.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &u.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return &model.Asset{
+		Bucket: u.Bucket,
+		S3Key:  key,
+		ETag:   cleanETag(aws.ToString(headResult.ETag)),
+		SHA256: sumHex,
+		MIME:   contentType,
+		SizeB:  aws.ToInt64(headResult.ContentLength),
+	}
+}
+
 // uploadWithDedup performs content-addressed deduplicated upload.
-// It searches for existing objects under keyPrefix that contain the given sumHex in the key.
+// It first asks the optional key lookup for an existing object with the given sumHex, then
+// searches for existing objects under keyPrefix that contain the given sumHex in the key.
 // If found, returns its metadata; otherwise uploads the new content using date + sumHex + ext as key.
+// With WithKey, the content is uploaded under the given key without any dedup.
 func (u *S3Deps) uploadWithDedup(
 	ctx context.Context,
 	keyPrefix string,
@@ -183,7 +375,22 @@ func (u *S3Deps) uploadWithDedup(
 	size int64,
 	body io.Reader,
 	metadata map[string]string,
+	opts ...UploadOption,
 ) (*model.Asset, error) {
+	o := uploadOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.key != "" {
+		return u.putObject(ctx, o.key, sumHex, contentType, size, body, metadata, o.projectID)
+	}
+
+	// Fast path: content-addressed index hit
+	if asset := u.lookupExisting(ctx, o.keyLookup, sumHex, contentType); asset != nil {
+		return asset, nil
+	}
+
 	// Check for existing object with pagination support
 	listInput := &s3.ListObjectsV2Input{
 		Bucket: &u.Bucket,
@@ -228,7 +435,20 @@ func (u *S3Deps) uploadWithDedup(
 	// No existing file found, upload new file with date prefix
 	datePrefix := time.Now().UTC().Format("2006/01/02")
 	key := fmt.Sprintf("%s/%s/%s%s", keyPrefix, datePrefix, sumHex, ext)
+	return u.putObject(ctx, key, sumHex, contentType, size, body, metadata, o.projectID)
+}
 
+// putObject uploads body under key and returns the asset stored there
+func (u *S3Deps) putObject(
+	ctx context.Context,
+	key string,
+	sumHex string,
+	contentType string,
+	size int64,
+	body io.Reader,
+	metadata map[string]string,
+	projectID uuid.UUID,
+) (*model.Asset, error) {
 	input := &s3.PutObjectInput{
 		Bucket:      aws.String(u.Bucket),
 		Key:         aws.String(key),
@@ -236,14 +456,15 @@ func (u *S3Deps) uploadWithDedup(
 		ContentType: aws.String(contentType),
 		Metadata:    metadata,
 	}
-	if u.SSE != nil {
-		input.ServerSideEncryption = *u.SSE
+	if err := u.applySSE(input, projectID); err != nil {
+		return nil, err
 	}
 
 	out, err := u.Uploader.Upload(ctx, input)
 	if err != nil {
 		return nil, err
 	}
+	u.addBytes(telemetry.StorageUpload, size)
 
 	return &model.Asset{
 		Bucket: u.Bucket,
@@ -258,7 +479,7 @@ func (u *S3Deps) uploadWithDedup(
 // UploadFormFile uploads a file to S3 with automatic deduplication
 // It checks if a file with the same SHA256 already exists under the keyPrefix
 // If found, returns the existing file metadata; otherwise uploads the new file
-func (u *S3Deps) UploadFormFile(ctx context.Context, keyPrefix string, fh *multipart.FileHeader) (*model.Asset, error) {
+func (u *S3Deps) UploadFormFile(ctx context.Context, keyPrefix string, fh *multipart.FileHeader, opts ...UploadOption) (*model.Asset, error) {
 	file, err := fh.Open()
 	if err != nil {
 		return nil, err
@@ -279,6 +500,10 @@ func (u *S3Deps) UploadFormFile(ctx context.Context, keyPrefix string, fh *multi
 
 	ext := strings.ToLower(filepath.Ext(fh.Filename))
 	contentType := fh.Header.Get("Content-Type")
+	// Clients often send no type, or a generic one, for files such as HEIC photos
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = fileparser.DetectContentType(fileContent)
+	}
 
 	return u.uploadWithDedup(
 		ctx,
@@ -292,11 +517,40 @@ func (u *S3Deps) UploadFormFile(ctx context.Context, keyPrefix string, fh *multi
 			"sha256": sumHex,
 			"name":   fh.Filename,
 		},
+		opts...,
+	)
+}
+
+// UploadBytes uploads an in-memory buffer to S3 with automatic deduplication, like
+// UploadFormFile. filename is optional and only sets the key extension and object metadata.
+func (u *S3Deps) UploadBytes(ctx context.Context, keyPrefix string, data []byte, contentType string, filename string, opts ...UploadOption) (*model.Asset, error) {
+	h := sha256.New()
+	h.Write(data)
+	sumHex := hex.EncodeToString(h.Sum(nil))
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	metadata := map[string]string{
+		"sha256": sumHex,
+	}
+	if filename != "" {
+		metadata["name"] = filename
+	}
+
+	return u.uploadWithDedup(
+		ctx,
+		keyPrefix,
+		sumHex,
+		contentType,
+		ext,
+		int64(len(data)),
+		bytes.NewReader(data),
+		metadata,
+		opts...,
 	)
 }
 
 // UploadJSON uploads JSON data to S3 and returns metadata
-func (u *S3Deps) UploadJSON(ctx context.Context, keyPrefix string, data interface{}) (*model.Asset, error) {
+func (u *S3Deps) UploadJSON(ctx context.Context, keyPrefix string, data interface{}, opts ...UploadOption) (*model.Asset, error) {
 	// Serialize data to JSON
 	jsonData, err := sonic.Marshal(data)
 	if err != nil {
@@ -319,6 +573,7 @@ func (u *S3Deps) UploadJSON(ctx context.Context, keyPrefix string, data interfac
 		map[string]string{
 			"sha256": sumHex,
 		},
+		opts...,
 	)
 }
 
@@ -339,6 +594,7 @@ func (u *S3Deps) DownloadJSON(ctx context.Context, key string, target interface{
 	if err != nil {
 		return fmt.Errorf("read response body: %w", err)
 	}
+	u.addBytes(telemetry.StorageDownload, int64(buf.Len()))
 
 	// Unmarshal JSON
 	if err := sonic.Unmarshal(buf.Bytes(), target); err != nil {
@@ -348,6 +604,67 @@ func (u *S3Deps) DownloadJSON(ctx context.Context, key string, target interface{
 	return nil
 }
 
+// OpenFile streams file content from S3. The caller must close the returned reader.
+func (u *S3Deps) OpenFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+
+	result, err := u.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &u.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object from S3: %w", err)
+	}
+	if u.Metrics == nil {
+		return result.Body, nil
+	}
+	return &countingReadCloser{ReadCloser: result.Body, u: u}, nil
+}
+
+// ErrObjectNotFound is returned by HeadObject when no object is stored under the key
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectMeta is the metadata S3 holds for a stored object
+type ObjectMeta struct {
+	Key          string     `json:"key"`
+	SizeB        int64      `json:"size_b"`
+	ETag         string     `json:"etag"`
+	ContentType  string     `json:"content_type"`
+	LastModified *time.Time `json:"last_modified"`
+	// Metadata holds the x-amz-meta-* headers without their prefix, e.g. sha256 and name
+	Metadata map[string]string `json:"metadata"`
+}
+
+// HeadObject returns the metadata of the object stored under key, or ErrObjectNotFound
+func (u *S3Deps) HeadObject(ctx context.Context, key string) (*ObjectMeta, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+
+	result, err := u.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &u.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *s3types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("head object from S3: %w", err)
+	}
+
+	return &ObjectMeta{
+		Key:          key,
+		SizeB:        aws.ToInt64(result.ContentLength),
+		ETag:         cleanETag(aws.ToString(result.ETag)),
+		ContentType:  aws.ToString(result.ContentType),
+		LastModified: result.LastModified,
+		Metadata:     result.Metadata,
+	}, nil
+}
+
 // DownloadFile downloads file content from S3 and returns the content as bytes
 func (u *S3Deps) DownloadFile(ctx context.Context, key string) ([]byte, error) {
 	if key == "" {
@@ -369,10 +686,59 @@ func (u *S3Deps) DownloadFile(ctx context.Context, key string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read response body: %w", err)
 	}
+	u.addBytes(telemetry.StorageDownload, int64(buf.Len()))
 
 	return buf.Bytes(), nil
 }
 
+// CopyObject copies the object stored under srcKey to dstKey within the bucket, without
+// downloading it. The copy is encrypted with the configured SSE, or with the project's
+// KMS key when WithProject is given.
+func (u *S3Deps) CopyObject(ctx context.Context, srcKey, dstKey string, opts ...UploadOption) error {
+	if srcKey == "" || dstKey == "" {
+		return errors.New("key is empty")
+	}
+
+	o := uploadOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sse, err := u.sseFor(o.projectID)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:                  &u.Bucket,
+		Key:                     &dstKey,
+		CopySource:              aws.String(copySource(u.Bucket, srcKey)),
+		ServerSideEncryption:    sse.mode,
+		SSEKMSKeyId:             sse.kmsKeyID,
+		SSEKMSEncryptionContext: sse.encryptionContext,
+	})
+	if err != nil {
+		// CopyObject has no modeled errors: a missing source only shows in the error code
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey" {
+			return ErrObjectNotFound
+		}
+		return fmt.Errorf("copy object in S3: %w", err)
+	}
+	return nil
+}
+
+// copySource builds the x-amz-copy-source of an object: "bucket/key", URL-encoded segment by
+// segment. The SDK sends it as is, so keys with spaces or non-ASCII characters would otherwise
+// be rejected or resolve to another key.
+func copySource(bucket, key string) string {
+	segments := strings.Split(bucket+"/"+key, "/")
+	for i, s := range segments {
+		// QueryEscape encodes a space as "+", which S3 would keep as a literal plus
+		segments[i] = strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	return strings.Join(segments, "/")
+}
+
 // DeleteObject deletes an object from S3
 func (u *S3Deps) DeleteObject(ctx context.Context, key string) error {
 	if key == "" {
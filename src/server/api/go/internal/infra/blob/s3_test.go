@@ -0,0 +1,324 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 records the requests it receives and answers HEAD, ListObjectsV2, PUT and copies
+type fakeS3 struct {
+	mu    sync.Mutex
+	calls []string
+	// putHeader holds the headers of the last PUT
+	putHeader http.Header
+	// headMissing answers HEAD with 404 Not Found
+	headMissing bool
+	// objects, when set, holds the stored keys: HEAD and copies of other keys are 404,
+	// listings return them and PUTs add to them
+	objects map[string]bool
+}
+
+// key returns the object key of a path-style request
+func (f *fakeS3) key(r *http.Request) string {
+	return strings.TrimPrefix(r.URL.Path, "/test-bucket/")
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		f.calls = append(f.calls, "copy")
+		f.putHeader = r.Header.Clone()
+		src, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		if err != nil || !strings.HasPrefix(src, "test-bucket/") || (f.objects != nil && !f.objects[strings.TrimPrefix(src, "test-bucket/")]) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		if f.objects != nil {
+			f.objects[f.key(r)] = true
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<CopyObjectResult><ETag>"etag-existing"</ETag></CopyObjectResult>`))
+	case r.Method == http.MethodHead && (f.headMissing || (f.objects != nil && !f.objects[f.key(r)])):
+		f.calls = append(f.calls, "head")
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodHead:
+		f.calls = append(f.calls, "head")
+		w.Header().Set("ETag", `"etag-existing"`)
+		w.Header().Set("Content-Length", "42")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Amz-Meta-Sha256", "abc123")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		f.calls = append(f.calls, "list")
+		var contents strings.Builder
+		for key := range f.objects {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				contents.WriteString("<Contents><Key>" + key + "</Key></Contents>")
+			}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>test-bucket</Name>` + contents.String() + `<MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated></ListBucketResult>`))
+	case r.Method == http.MethodPut:
+		f.calls = append(f.calls, "put")
+		f.putHeader = r.Header.Clone()
+		if f.objects != nil {
+			f.objects[f.key(r)] = true
+		}
+		w.Header().Set("ETag", `"etag-new"`)
+		w.WriteHeader(http.StatusOK)
+	default:
+		f.calls = append(f.calls, r.Method)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newTestS3Deps(t *testing.T) (*S3Deps, *fakeS3) {
+	fake := &fakeS3{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+	})
+	return &S3Deps{
+		Client:   client,
+		Uploader: manager.NewUploader(client),
+		Bucket:   "test-bucket",
+	}, fake
+}
+
+func TestUploadWithDedup_KeyLookupHit(t *testing.T) {
+	deps, fake := newTestS3Deps(t)
+	existingKey := "parts/project/2025/01/01/abc123.json"
+
+	var lookedUp string
+	lookup := func(ctx context.Context, sha256 string) (string, error) {
+		lookedUp = sha256
+		return existingKey, nil
+	}
+
+	body := []byte(`{"a":1}`)
+	asset, err := deps.uploadWithDedup(context.Background(), "parts/project", "abc123", "application/json", ".json",
+		int64(len(body)), bytes.NewReader(body), nil, WithKeyLookup(lookup))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", lookedUp)
+	assert.Equal(t, existingKey, asset.S3Key)
+	assert.Equal(t, "etag-existing", asset.ETag)
+	assert.Equal(t, int64(42), asset.SizeB)
+	// Only the existence check hits S3: no prefix scan and no upload
+	assert.Equal(t, []string{"head"}, fake.calls)
+}
+
+func TestUploadWithDedup_KeyLookupMiss(t *testing.T) {
+	deps, fake := newTestS3Deps(t)
+
+	lookup := func(ctx context.Context, sha256 string) (string, error) {
+		return "", nil
+	}
+
+	body := []byte(`{"a":1}`)
+	asset, err := deps.uploadWithDedup(context.Background(), "parts/project", "abc123", "application/json", ".json",
+		int64(len(body)), bytes.NewReader(body), nil, WithKeyLookup(lookup))
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(asset.S3Key, "parts/project/"))
+	assert.True(t, strings.HasSuffix(asset.S3Key, "abc123.json"))
+	assert.Equal(t, []string{"list", "put"}, fake.calls)
+}
+
+func TestUploadWithDedup_ProjectSSE(t *testing.T) {
+	projectID := uuid.New()
+	aes := s3types.ServerSideEncryptionAes256
+
+	// upload returns the headers of the PUT of an upload for uploadProjectID
+	upload := func(t *testing.T, uploadProjectID uuid.UUID) http.Header {
+		deps, fake := newTestS3Deps(t)
+		deps.SSE = &aes
+		deps.ProjectSSE = map[uuid.UUID]ProjectSSE{
+			projectID: {KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/project", EncryptionContext: map[string]string{"project_id": projectID.String()}},
+		}
+		body := []byte(`{"a":1}`)
+		_, err := deps.uploadWithDedup(context.Background(), "parts/project", "abc123", "application/json", ".json",
+			int64(len(body)), bytes.NewReader(body), nil, WithProject(uploadProjectID))
+		require.NoError(t, err)
+		return fake.putHeader
+	}
+
+	t.Run("project key", func(t *testing.T) {
+		header := upload(t, projectID)
+		assert.Equal(t, "aws:kms", header.Get("X-Amz-Server-Side-Encryption"))
+		assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/project", header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+		encCtx, err := base64.StdEncoding.DecodeString(header.Get("X-Amz-Server-Side-Encryption-Context"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"project_id":"`+projectID.String()+`"}`, string(encCtx))
+	})
+
+	t.Run("global sse fallback", func(t *testing.T) {
+		header := upload(t, uuid.New())
+		assert.Equal(t, "AES256", header.Get("X-Amz-Server-Side-Encryption"))
+		assert.Empty(t, header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+		assert.Empty(t, header.Get("X-Amz-Server-Side-Encryption-Context"))
+	})
+}
+
+func TestUploadBytes_Dedup(t *testing.T) {
+	ctx := context.Background()
+	deps, fake := newTestS3Deps(t)
+	fake.objects = map[string]bool{}
+	data := []byte("\x89PNG\r\n\x1a\nimage")
+
+	first, err := deps.UploadBytes(ctx, "assets/project", data, "image/png", "photo.png")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(first.S3Key, "assets/project/"))
+	assert.True(t, strings.HasSuffix(first.S3Key, first.SHA256+".png"))
+	assert.Equal(t, "image/png", first.MIME)
+	assert.Equal(t, int64(len(data)), first.SizeB)
+	assert.Equal(t, first.SHA256, fake.putHeader.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "photo.png", fake.putHeader.Get("X-Amz-Meta-Name"))
+
+	second, err := deps.UploadBytes(ctx, "assets/project", data, "image/png", "")
+	require.NoError(t, err)
+	assert.Equal(t, first.S3Key, second.S3Key)
+	assert.Equal(t, first.SHA256, second.SHA256)
+	assert.Equal(t, []string{"list", "put", "list", "head"}, fake.calls, "the same bytes are uploaded once")
+}
+
+func TestUploadBytes_WithKey(t *testing.T) {
+	ctx := context.Background()
+	deps, fake := newTestS3Deps(t)
+	fake.objects = map[string]bool{}
+	data := []byte("\x89PNG\r\n\x1a\nimage")
+	lookup := func(ctx context.Context, sha256 string) (string, error) {
+		return "assets/project/2025/01/01/" + sha256 + ".png", nil
+	}
+
+	for range 2 {
+		asset, err := deps.UploadBytes(ctx, "assets/project", data, "image/png", "photo.png", WithKey("disks/disk/images/photo.png"), WithKeyLookup(lookup))
+		require.NoError(t, err)
+		assert.Equal(t, "disks/disk/images/photo.png", asset.S3Key)
+		assert.Equal(t, "etag-new", asset.ETag)
+		assert.Equal(t, int64(len(data)), asset.SizeB)
+	}
+	// The key is written as given every time: no lookup, listing or reuse of another copy
+	assert.Equal(t, []string{"put", "put"}, fake.calls)
+	assert.Equal(t, map[string]bool{"disks/disk/images/photo.png": true}, fake.objects)
+}
+
+func TestHeadObject(t *testing.T) {
+	deps, fake := newTestS3Deps(t)
+
+	meta, err := deps.HeadObject(context.Background(), "disks/project/report.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "disks/project/report.txt", meta.Key)
+	assert.Equal(t, int64(42), meta.SizeB)
+	assert.Equal(t, "etag-existing", meta.ETag)
+	assert.Equal(t, "text/plain", meta.ContentType)
+	assert.Equal(t, "abc123", meta.Metadata["sha256"])
+
+	fake.headMissing = true
+	_, err = deps.HeadObject(context.Background(), "disks/project/report.txt")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestCopyObject(t *testing.T) {
+	ctx := context.Background()
+	srcKey := "disks/project/2025/01/01/abc123.pdf"
+
+	t.Run("destination exists after copy", func(t *testing.T) {
+		deps, fake := newTestS3Deps(t)
+		fake.objects = map[string]bool{srcKey: true}
+		dstKey := "disks/project/reports/q1 résumé+final.pdf"
+
+		_, err := deps.HeadObject(ctx, dstKey)
+		require.ErrorIs(t, err, ErrObjectNotFound)
+
+		require.NoError(t, deps.CopyObject(ctx, srcKey, dstKey))
+		meta, err := deps.HeadObject(ctx, dstKey)
+		require.NoError(t, err)
+		assert.Equal(t, dstKey, meta.Key)
+		assert.True(t, fake.objects[srcKey], "the source is kept")
+	})
+
+	t.Run("copy source is URL-encoded", func(t *testing.T) {
+		deps, fake := newTestS3Deps(t)
+		require.NoError(t, deps.CopyObject(ctx, "disks/project/q1 résumé+final.pdf", "disks/project/copy.pdf"))
+		assert.Equal(t, "test-bucket/disks/project/q1%20r%C3%A9sum%C3%A9%2Bfinal.pdf", fake.putHeader.Get("X-Amz-Copy-Source"))
+	})
+
+	t.Run("applies SSE to the destination", func(t *testing.T) {
+		projectID := uuid.New()
+		aes := s3types.ServerSideEncryptionAes256
+		deps, fake := newTestS3Deps(t)
+		deps.SSE = &aes
+		deps.ProjectSSE = map[uuid.UUID]ProjectSSE{projectID: {KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/project"}}
+
+		require.NoError(t, deps.CopyObject(ctx, srcKey, "disks/project/copy.pdf"))
+		assert.Equal(t, "AES256", fake.putHeader.Get("X-Amz-Server-Side-Encryption"))
+
+		require.NoError(t, deps.CopyObject(ctx, srcKey, "disks/project/copy.pdf", WithProject(projectID)))
+		assert.Equal(t, "aws:kms", fake.putHeader.Get("X-Amz-Server-Side-Encryption"))
+		assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/project", fake.putHeader.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		deps, fake := newTestS3Deps(t)
+		fake.objects = map[string]bool{}
+		err := deps.CopyObject(ctx, srcKey, "disks/project/copy.pdf")
+		assert.ErrorIs(t, err, ErrObjectNotFound)
+		assert.Empty(t, fake.objects)
+	})
+}
+
+func TestPresignGet_AsAttachment(t *testing.T) {
+	deps, _ := newTestS3Deps(t)
+	deps.Presigner = s3.NewPresignClient(deps.Client)
+	ctx := context.Background()
+
+	plain, err := deps.PresignGet(ctx, "disks/2024/01/01/abc.pdf", time.Minute)
+	require.NoError(t, err)
+	assert.NotContains(t, plain, "response-content-disposition")
+
+	raw, err := deps.PresignGet(ctx, "disks/2024/01/01/abc.pdf", time.Minute, AsAttachment("Q1 report.pdf", "application/pdf"))
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, `attachment; filename="Q1 report.pdf"`, u.Query().Get("response-content-disposition"))
+	assert.Equal(t, "application/pdf", u.Query().Get("response-content-type"))
+
+	// Non-ASCII names are encoded per RFC 2231
+	raw, err = deps.PresignGet(ctx, "disks/2024/01/01/abc.pdf", time.Minute, AsAttachment("报告.pdf", ""))
+	require.NoError(t, err)
+	u, err = url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A.pdf", u.Query().Get("response-content-disposition"))
+	assert.Empty(t, u.Query().Get("response-content-type"))
+}
@@ -57,3 +57,12 @@ func RegisterOpenTelemetryPlugin(db *gorm.DB) error {
 	// NewPlugin() automatically uses the global tracer provider
 	return db.Use(tracing.NewPlugin())
 }
+
+// Close closes the underlying connection pool
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"context"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
+)
+
+// RemoteFile is a file downloaded from a URL
+type RemoteFile struct {
+	Data []byte
+	// ContentType is the media type announced by the server, empty when it sent none
+	ContentType string
+	// Filename is the name from the Content-Disposition header, or else the last segment
+	// of the final URL path, empty when neither has one
+	Filename string
+}
+
+// RemoteFetcher downloads files from public URLs on behalf of clients, see safehttp.Client
+type RemoteFetcher struct {
+	client   *safehttp.Client
+	maxSizeB int64
+}
+
+// NewRemoteFetcher creates a fetcher giving up after timeout and refusing files larger than
+// maxSizeB
+func NewRemoteFetcher(timeout time.Duration, maxSizeB int64) *RemoteFetcher {
+	return &RemoteFetcher{client: &safehttp.Client{Timeout: timeout}, maxSizeB: maxSizeB}
+}
+
+// Fetch downloads the file at rawURL. It fails with the safehttp errors.
+func (f *RemoteFetcher) Fetch(ctx context.Context, rawURL string) (*RemoteFile, error) {
+	resp, err := f.client.Get(ctx, rawURL, f.maxSizeB)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &RemoteFile{Data: resp.Data}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		file.ContentType = mediaType
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		file.Filename = path.Base(params["filename"])
+	}
+	if file.Filename == "" || file.Filename == "." || file.Filename == "/" {
+		file.Filename = ""
+		if name := path.Base(resp.URL.Path); name != "." && name != "/" {
+			file.Filename = name
+		}
+	}
+	return file, nil
+}
@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteFetcher_Fetch(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/report.csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			_, _ = w.Write([]byte("a,b\n1,2\n"))
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="../export.json"`)
+			_, _ = w.Write([]byte(`{"a":1}`))
+		case "/redirect":
+			http.Redirect(w, r, "/files/report.csv", http.StatusFound)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	// The test server listens on loopback, which only this fetcher may reach
+	f := &RemoteFetcher{client: &safehttp.Client{AllowAddr: func(netip.Addr) bool { return true }}, maxSizeB: 32}
+
+	t.Run("file with its content type", func(t *testing.T) {
+		file, err := f.Fetch(ctx, srv.URL+"/files/report.csv")
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n1,2\n", string(file.Data))
+		assert.Equal(t, "text/csv", file.ContentType)
+		assert.Equal(t, "report.csv", file.Filename)
+	})
+
+	t.Run("filename from content disposition", func(t *testing.T) {
+		file, err := f.Fetch(ctx, srv.URL+"/download")
+		require.NoError(t, err)
+		// Path components of the announced name are dropped
+		assert.Equal(t, "export.json", file.Filename)
+	})
+
+	t.Run("follows redirects", func(t *testing.T) {
+		file, err := f.Fetch(ctx, srv.URL+"/redirect")
+		require.NoError(t, err)
+		assert.Equal(t, "report.csv", file.Filename)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := f.Fetch(ctx, srv.URL+"/large")
+		assert.ErrorIs(t, err, safehttp.ErrTooLarge)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := f.Fetch(ctx, srv.URL+"/missing")
+		assert.ErrorIs(t, err, safehttp.ErrFetchFailed)
+		assert.ErrorContains(t, err, "404 Not Found")
+	})
+}
+
+func TestRemoteFetcher_Forbidden(t *testing.T) {
+	f := NewRemoteFetcher(5*time.Second, 1024)
+	_, err := f.Fetch(context.Background(), "http://169.254.169.254/latest/meta-data/")
+	assert.ErrorIs(t, err, safehttp.ErrForbiddenURL)
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/config"
+)
+
+// CORS returns a middleware answering cross-origin requests from the configured origins
+// and short-circuiting their preflight OPTIONS requests. Requests from other origins get
+// no CORS headers, so browsers block them. With credentials allowed, a "*" origin echoes
+// the request origin instead, since browsers reject "*" on credentialed requests.
+// Without allowed origins the middleware does nothing.
+func CORS(cfg config.CORSCfg) gin.HandlerFunc {
+	allowAny := false
+	var exact, wildcards []string
+	for _, o := range cfg.AllowOrigins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		switch {
+		case o == "*":
+			allowAny = true
+		case strings.Contains(o, "*"):
+			wildcards = append(wildcards, o)
+		case o != "":
+			exact = append(exact, o)
+		}
+	}
+	if !allowAny && len(exact) == 0 && len(wildcards) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	allowed := func(origin string) bool {
+		if allowAny {
+			return true
+		}
+		for _, o := range exact {
+			if strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		for _, o := range wildcards {
+			prefix, suffix, _ := strings.Cut(o, "*")
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	methods := strings.Join(cfg.AllowMethods, ", ")
+	headers := strings.Join(cfg.AllowHeaders, ", ")
+	expose := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAgeSec > 0 {
+		maxAge = strconv.Itoa(cfg.MaxAgeSec)
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		// The response depends on the origin unless every origin gets "*"
+		if !allowAny || cfg.AllowCredentials {
+			h.Add("Vary", "Origin")
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAny && !cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if expose != "" {
+				h.Set("Access-Control-Expose-Headers", expose)
+			}
+			c.Next()
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if methods != "" {
+			h.Set("Access-Control-Allow-Methods", methods)
+		}
+		if headers != "" {
+			h.Set("Access-Control-Allow-Headers", headers)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
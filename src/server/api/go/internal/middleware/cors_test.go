@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSRouter(cfg config.CORSCfg) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS(cfg))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/ping", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.CORSCfg{
+		AllowOrigins:  []string{"https://app.acontext.io", "https://*.example.com"},
+		AllowMethods:  []string{"GET", "POST"},
+		AllowHeaders:  []string{"Authorization", "Content-Type"},
+		ExposeHeaders: []string{"ETag"},
+		MaxAgeSec:     600,
+	}
+	r := newCORSRouter(cfg)
+
+	t.Run("allowed origin", func(t *testing.T) {
+		w := corsRequest(r, http.MethodGet, "https://app.acontext.io")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.acontext.io", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "ETag", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("wildcard subdomain", func(t *testing.T) {
+		w := corsRequest(r, http.MethodGet, "https://docs.example.com")
+		assert.Equal(t, "https://docs.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = corsRequest(r, http.MethodGet, "https://.example.com")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		w := corsRequest(r, http.MethodGet, "https://evil.io")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		w := corsRequest(r, http.MethodOptions, "https://app.acontext.io")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.acontext.io", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Authorization, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+		w = corsRequest(r, http.MethodOptions, "https://evil.io")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("same-origin request", func(t *testing.T) {
+		w := corsRequest(r, http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("wildcard origin", func(t *testing.T) {
+		w := corsRequest(newCORSRouter(config.CORSCfg{AllowOrigins: []string{"*"}}), http.MethodGet, "https://evil.io")
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("wildcard origin with credentials echoes the origin", func(t *testing.T) {
+		w := corsRequest(newCORSRouter(config.CORSCfg{AllowOrigins: []string{"*"}, AllowCredentials: true}), http.MethodGet, "https://app.acontext.io")
+		assert.Equal(t, "https://app.acontext.io", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, w.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("disabled without origins", func(t *testing.T) {
+		w := corsRequest(newCORSRouter(config.CORSCfg{}), http.MethodGet, "https://app.acontext.io")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGzipRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Gzip(DefaultGzipMinSize))
+	r.GET("/list", func(c *gin.Context) {
+		items := make([]string, 500)
+		for i := range items {
+			items[i] = "artifact.txt"
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+	r.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"msg": "ok"})
+	})
+	r.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(strings.Repeat("x", 4096)))
+	})
+	return r
+}
+
+func TestGzip(t *testing.T) {
+	r := newGzipRouter()
+
+	t.Run("large list response is compressed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set("Accept-Encoding", "br, gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(body), `{"items":["artifact.txt"`))
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Body.String(), `"artifact.txt"`)
+	})
+
+	t.Run("gzip refused with q=0", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list", nil)
+		req.Header.Set("Accept-Encoding", "gzip;q=0")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("small response below threshold", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/small", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.JSONEq(t, `{"msg":"ok"}`, w.Body.String())
+	})
+
+	t.Run("compressed media passes through", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, 4096, w.Body.Len())
+	})
+}
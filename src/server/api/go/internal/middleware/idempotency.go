@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client supplied idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// DefaultIdempotencyTTL is how long a stored response is replayed for the same key
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyLease is how long a key stays reserved while its request runs. It bounds
+	// how long a request that crashed blocks the retries with its key.
+	idempotencyLease = 5 * time.Minute
+
+	maxIdempotencyKeyLen     = 255
+	redisKeyPrefixIdempotent = "idempotency:"
+	// multipartMemory is the memory gin parses multipart forms with by default
+	multipartMemory = 32 << 20
+)
+
+// IdempotentResponse is the response recorded for an idempotency key
+type IdempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// IdempotencyRecord is what is stored for an idempotency key: the fingerprint of the request
+// that used it and, once that request completed, its response
+type IdempotencyRecord struct {
+	Fingerprint string              `json:"fingerprint"`
+	Response    *IdempotentResponse `json:"response,omitempty"`
+}
+
+// IdempotencyStore persists idempotency keys and the responses recorded for them
+type IdempotencyStore interface {
+	// Reserve marks the key as in flight for the request of the fingerprint, for lease. It
+	// returns false if the key is already known.
+	Reserve(ctx context.Context, key string, fingerprint string, lease time.Duration) (bool, error)
+	// Get returns the record of the key, without a response while the original request is
+	// still in flight, or nil if the key is unknown.
+	Get(ctx context.Context, key string) (*IdempotencyRecord, error)
+	// Save records the response of the key, kept for ttl.
+	Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+type redisIdempotencyStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisIdempotencyStore(rdb *redis.Client) IdempotencyStore {
+	return &redisIdempotencyStore{rdb: rdb}
+}
+
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, key string, fingerprint string, lease time.Duration) (bool, error) {
+	data, err := sonic.Marshal(IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return false, err
+	}
+	return s.rdb.SetNX(ctx, redisKeyPrefixIdempotent+key, data, lease).Result()
+}
+
+func (s *redisIdempotencyStore) Get(ctx context.Context, key string) (*IdempotencyRecord, error) {
+	val, err := s.rdb.Get(ctx, redisKeyPrefixIdempotent+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	if err := sonic.Unmarshal(val, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *redisIdempotencyStore) Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	data, err := sonic.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, redisKeyPrefixIdempotent+key, data, ttl).Err()
+}
+
+func (s *redisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, redisKeyPrefixIdempotent+key).Err()
+}
+
+// bodyRecorder tees the response body so it can be stored for replay
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestFingerprint hashes the body of the request, leaving it for the handler to read.
+// Multipart forms are hashed by their fields and files, so a retry that encodes the same
+// form with another boundary gets the same fingerprint.
+func requestFingerprint(c *gin.Context) (string, error) {
+	h := sha256.New()
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		// The parsed form is kept on the request and reused by the handler
+		if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+			return "", err
+		}
+		if err := hashMultipartForm(h, c.Request); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashMultipartForm writes the fields and files of the parsed form of r to h, sorted by name
+func hashMultipartForm(h hash.Hash, r *http.Request) error {
+	form := r.MultipartForm
+	field := func(s string) { fmt.Fprintf(h, "%d:%s", len(s), s) }
+
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field(name)
+		for _, v := range form.Value[name] {
+			field(v)
+		}
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field(name)
+		for _, fh := range form.File[name] {
+			field(fh.Filename)
+			fmt.Fprintf(h, "%d:", fh.Size)
+			f, err := fh.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Idempotency returns a middleware that makes create endpoints safe to retry.
+// When the request carries an Idempotency-Key header, the first successful response is
+// stored per project and replayed for later requests with the same key and body instead of
+// running the handler again; a different body with the same key is rejected with 422.
+// Failed requests release the key so the client can retry. The key is only reserved for
+// idempotencyLease while the handler runs, so a crashed request does not block it for ttl.
+// It must run after ProjectAuth.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idemKey := c.GetHeader(IdempotencyKeyHeader)
+		if idemKey == "" {
+			c.Next()
+			return
+		}
+		if len(idemKey) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, serializer.ParamErr(IdempotencyKeyHeader, errors.New("idempotency key is too long")))
+			return
+		}
+
+		project, ok := c.MustGet("project").(*model.Project)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := project.ID.String() + ":" + c.Request.Method + ":" + c.Request.URL.Path + ":" + idemKey
+
+		reserved, err := store.Reserve(ctx, key, fingerprint, min(idempotencyLease, ttl))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, serializer.DBErr("", err))
+			return
+		}
+		if !reserved {
+			record, err := store.Get(ctx, key)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, serializer.DBErr("", err))
+				return
+			}
+			if record != nil && record.Fingerprint != fingerprint {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, serializer.Err(http.StatusUnprocessableEntity, "the idempotency key was used with a different request body", nil))
+				return
+			}
+			if record == nil || record.Response == nil {
+				c.AbortWithStatusJSON(http.StatusConflict, serializer.Err(http.StatusConflict, "a request with this idempotency key is still in progress", nil))
+				return
+			}
+			resp := record.Response
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+
+		// Use a fresh context so a cancelled request still records or releases its key
+		storeCtx := context.WithoutCancel(ctx)
+		defer func() {
+			if r := recover(); r != nil {
+				_ = store.Release(storeCtx, key)
+				panic(r)
+			}
+		}()
+
+		rec := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		status := rec.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			_ = store.Release(storeCtx, key)
+			return
+		}
+		_ = store.Save(storeCtx, key, IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Response: &IdempotentResponse{
+				Status:      status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			},
+		}, ttl)
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore used by tests
+type memoryIdempotencyStore struct {
+	mu    sync.Mutex
+	items map[string]IdempotencyRecord
+	// ttls holds the lease or ttl each key was last stored with
+	ttls map[string]time.Duration
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{items: map[string]IdempotencyRecord{}, ttls: map[string]time.Duration{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, key string, fingerprint string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[key]; ok {
+		return false, nil
+	}
+	s.items[key] = IdempotencyRecord{Fingerprint: fingerprint}
+	s.ttls[key] = lease
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Get(ctx context.Context, key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (s *memoryIdempotencyStore) Save(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = record
+	s.ttls[key] = ttl
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	delete(s.ttls, key)
+	return nil
+}
+
+func setupIdempotencyRouter(store IdempotencyStore, projectID uuid.UUID, created *int, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/space/:space_id/block", func(c *gin.Context) {
+		c.Set("project", &model.Project{ID: projectID})
+		c.Next()
+	}, Idempotency(store, time.Hour), func(c *gin.Context) {
+		*created++
+		c.JSON(status, serializer.Response{Data: map[string]string{"id": uuid.NewString()}})
+	})
+	return r
+}
+
+func TestIdempotency_SameKeyCreatesOnce(t *testing.T) {
+	created := 0
+	router := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &created, http.StatusCreated)
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/space/s1/block", nil)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("retry-1")
+	second := send("retry-1")
+
+	assert.Equal(t, 1, created)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+
+	// A different key or no key creates a new entity
+	send("retry-2")
+	send("")
+	assert.Equal(t, 3, created)
+}
+
+func TestIdempotency_KeysAreScopedPerProject(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	created := 0
+	routerA := setupIdempotencyRouter(store, uuid.New(), &created, http.StatusCreated)
+	routerB := setupIdempotencyRouter(store, uuid.New(), &created, http.StatusCreated)
+
+	for _, router := range []*gin.Engine{routerA, routerB} {
+		req := httptest.NewRequest("POST", "/space/s1/block", nil)
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2, created)
+}
+
+func TestIdempotency_FailedRequestReleasesKey(t *testing.T) {
+	created := 0
+	router := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &created, http.StatusInternalServerError)
+
+	for range 2 {
+		req := httptest.NewRequest("POST", "/space/s1/block", nil)
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+
+	assert.Equal(t, 2, created)
+}
+
+func TestIdempotency_InFlightKeyConflicts(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	projectID := uuid.New()
+	created := 0
+	router := setupIdempotencyRouter(store, projectID, &created, http.StatusCreated)
+
+	_, _ = store.Reserve(context.Background(), projectID.String()+":POST:/space/s1/block:retry-1", emptyBodyFingerprint, time.Hour)
+
+	req := httptest.NewRequest("POST", "/space/s1/block", nil)
+	req.Header.Set(IdempotencyKeyHeader, "retry-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 0, created)
+}
+
+// emptyBodyFingerprint is the fingerprint of a request without a body
+var emptyBodyFingerprint = hex.EncodeToString(sha256.New().Sum(nil))
+
+func TestIdempotency_DifferentBodyIsRejected(t *testing.T) {
+	created := 0
+	router := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &created, http.StatusCreated)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/space/s1/block", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, send(`{"title":"a"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"title":"b"}`).Code)
+	assert.Equal(t, http.StatusCreated, send(`{"title":"a"}`).Code)
+	assert.Equal(t, 1, created)
+}
+
+func TestIdempotency_MultipartRetryWithNewBoundary(t *testing.T) {
+	created := 0
+	router := setupIdempotencyRouter(newMemoryIdempotencyStore(), uuid.New(), &created, http.StatusCreated)
+
+	send := func(boundary string, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		require.NoError(t, mw.SetBoundary(boundary))
+		require.NoError(t, mw.WriteField("file_path", "/docs/"))
+		fw, err := mw.CreateFormFile("file", "a.txt")
+		require.NoError(t, err)
+		_, _ = fw.Write([]byte(content))
+		require.NoError(t, mw.Close())
+
+		req := httptest.NewRequest("POST", "/space/s1/block", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set(IdempotencyKeyHeader, "upload-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, send("boundary-one", "hello").Code)
+	replay := send("boundary-two", "hello")
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, http.StatusUnprocessableEntity, send("boundary-three", "changed").Code)
+	assert.Equal(t, 1, created)
+}
+
+func TestIdempotency_LeaseUntilSaved(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	projectID := uuid.New()
+	key := projectID.String() + ":POST:/space/s1/block:retry-1"
+	var leased time.Duration
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/space/:space_id/block", func(c *gin.Context) {
+		c.Set("project", &model.Project{ID: projectID})
+		c.Next()
+	}, Idempotency(store, time.Hour), func(c *gin.Context) {
+		leased = store.ttls[key]
+		c.JSON(http.StatusCreated, serializer.Response{})
+	})
+
+	req := httptest.NewRequest("POST", "/space/s1/block", nil)
+	req.Header.Set(IdempotencyKeyHeader, "retry-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Reserved for the lease while running, kept for the ttl once the response is saved
+	assert.Equal(t, idempotencyLease, leased)
+	assert.Equal(t, time.Hour, store.ttls[key])
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	projectID := uuid.New()
+	calls := 0
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/space/:space_id/block", func(c *gin.Context) {
+		c.Set("project", &model.Project{ID: projectID})
+		c.Next()
+	}, Idempotency(store, time.Hour), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler crashed")
+		}
+		c.JSON(http.StatusCreated, serializer.Response{})
+	})
+
+	for _, want := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		req := httptest.NewRequest("POST", "/space/s1/block", nil)
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := telemetry.NewPrometheusMetrics(func(ctx context.Context) (*model.StorageTotals, error) {
+		return &model.StorageTotals{Artifacts: 3, Blocks: 5, AssetReferences: 2, AssetRefs: 4, AssetBytes: 2048}, nil
+	})
+	m.AddStorageBytes(telemetry.StorageUpload, 1024)
+
+	r := gin.New()
+	r.Use(Metrics(m))
+	r.GET("/disk/:disk_id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("disk_id")}) })
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/disk/a", "/disk/b", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		`acontext_http_request_duration_seconds_count{method="GET",route="/disk/:disk_id",status="200"} 2`,
+		`acontext_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`acontext_storage_bytes_total{direction="upload"} 1024`,
+		`acontext_artifacts 3`,
+		`acontext_blocks 5`,
+		`acontext_asset_references 2`,
+		`acontext_asset_refs 4`,
+		`acontext_asset_stored_bytes 2048`,
+		`acontext_storage_totals_up 1`,
+	} {
+		assert.Contains(t, string(body), want)
+	}
+}
@@ -12,36 +12,129 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
+	"gorm.io/gorm"
 )
 
+// defaultMaxInlineContentSizeB is used when no inline content limit is configured
+const defaultMaxInlineContentSizeB = 10 << 20
+
+// defaultDiskAlias is the disk_id of the artifact routes naming the default disk of the project
+const defaultDiskAlias = "default"
+
 type ArtifactHandler struct {
-	svc service.ArtifactService
+	svc                   service.ArtifactService
+	diskSvc               service.DiskService
+	maxInlineContentSizeB int64
+	autoCreateDefaultDisk bool
+}
+
+// NewArtifactHandler creates an ArtifactHandler. Files larger than maxInlineContentSizeB are
+// returned without parsed content; a value <= 0 uses defaultMaxInlineContentSizeB. With
+// autoCreateDefaultDisk the disk_id "default" names the default disk of the project, created
+// on first use.
+func NewArtifactHandler(s service.ArtifactService, diskSvc service.DiskService, maxInlineContentSizeB int64, autoCreateDefaultDisk bool) *ArtifactHandler {
+	if maxInlineContentSizeB <= 0 {
+		maxInlineContentSizeB = defaultMaxInlineContentSizeB
+	}
+	return &ArtifactHandler{
+		svc:                   s,
+		diskSvc:               diskSvc,
+		maxInlineContentSizeB: maxInlineContentSizeB,
+		autoCreateDefaultDisk: autoCreateDefaultDisk,
+	}
+}
+
+// projectDisk parses the disk_id path parameter and checks the disk belongs to the
+// authenticated project. Disks of other projects are reported as not found, like missing
+// ones, so their existence is not leaked. It writes the error response and returns false
+// when the request must stop.
+func (h *ArtifactHandler) projectDisk(c *gin.Context) (uuid.UUID, bool) {
+	if h.autoCreateDefaultDisk && c.Param("disk_id") == defaultDiskAlias {
+		return h.defaultDisk(c)
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return uuid.Nil, false
+	}
+	if !h.checkProjectDisk(c, diskID, "disk not found") {
+		return uuid.Nil, false
+	}
+	return diskID, true
+}
+
+// projectID returns the ID of the authenticated project, which projectDisk has already checked
+func projectID(c *gin.Context) uuid.UUID {
+	return c.MustGet("project").(*model.Project).ID
+}
+
+// defaultDisk returns the default disk of the authenticated project, creating it if needed
+func (h *ArtifactHandler) defaultDisk(c *gin.Context) (uuid.UUID, bool) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return uuid.Nil, false
+	}
+
+	disk, err := h.diskSvc.GetOrCreateDefault(c.Request.Context(), project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return uuid.Nil, false
+	}
+	return disk.ID, true
 }
 
-func NewArtifactHandler(s service.ArtifactService) *ArtifactHandler {
-	return &ArtifactHandler{svc: s}
+// checkProjectDisk checks the disk belongs to the authenticated project, answering 404
+// with notFoundMsg otherwise
+func (h *ArtifactHandler) checkProjectDisk(c *gin.Context, diskID uuid.UUID, notFoundMsg string) bool {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return false
+	}
+
+	disk, err := h.diskSvc.GetByID(c.Request.Context(), diskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, notFoundMsg, err))
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return false
+	}
+	if disk.ProjectID != project.ID {
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, notFoundMsg, errors.New("disk does not belong to project")))
+		return false
+	}
+	return true
 }
 
 type CreateArtifactReq struct {
 	FilePath string `form:"file_path" json:"file_path"` // Optional, defaults to "/"
 	Meta     string `form:"meta" json:"meta"`
+	SHA256   string `form:"sha256" json:"sha256" binding:"omitempty,len=64,hexadecimal"` // Optional sha256 of the file, skips the upload when unchanged
 }
 
 // UpsertArtifact godoc
 //
 //	@Summary		Upsert artifact
-//	@Description	Upload a file and create or update an artifact record under a disk
+//	@Description	Upload a file and create or update an artifact record under a disk. When sha256 is given and matches the content of the artifact already at the path, the file is not uploaded again: the existing artifact is returned with its meta replaced.
 //	@Tags			artifact
 //	@Accept			multipart/form-data
 //	@Produce		json
-//	@Param			disk_id		path		string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			file_path	formData	string	false	"File path in the disk storage (optional, defaults to '/')"
-//	@Param			file		formData	file	true	"File to upload"
-//	@Param			meta		formData	string	false	"Custom metadata as JSON string (optional, system metadata will be stored under '__artifact_info__' key)"
+//	@Param			disk_id			path		string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path		formData	string	false	"File path in the disk storage (optional, defaults to '/')"
+//	@Param			file			formData	file	true	"File to upload"
+//	@Param			meta			formData	string	false	"Custom metadata as JSON string (optional, system metadata will be stored under '__artifact_info__' key)"
+//	@Param			sha256			formData	string	false	"Hex sha256 of the file (optional); an unchanged file is not uploaded again"
+//	@Param			Idempotency-Key	header		string	false	"Retrying with the same key returns the original response instead of uploading again"
 //	@Security		BearerAuth
 //	@Success		201	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		400	{object}	serializer.Response
 //	@Router			/disk/{disk_id}/artifact [post]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Upload a file to disk\nwith open('report.pdf', 'rb') as f:\n    artifact = client.disks.upload_artifact(\n        disk_id='disk-uuid',\n        file=f,\n        file_path='/documents/',\n        meta={'category': 'reports', 'year': 2024}\n    )\nprint(f\"Uploaded artifact: {artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\nimport fs from 'fs';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Upload a file to disk\nconst fileBuffer = fs.readFileSync('report.pdf');\nconst artifact = await client.disks.uploadArtifact('disk-uuid', {\n  file: fileBuffer,\n  filePath: '/documents/',\n  meta: { category: 'reports', year: 2024 }\n});\nconsole.log(`Uploaded artifact: ${artifact.id}`);\n","label":"JavaScript"}]
 func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
@@ -57,9 +150,8 @@ func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
 		return
 	}
 
-	diskID, err := uuid.Parse(c.Param("disk_id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+	diskID, ok := h.projectDisk(c)
+	if !ok {
 		return
 	}
 
@@ -89,13 +181,9 @@ func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
 			return
 		}
 
-		// Validate that user meta doesn't contain system reserved keys
-		reservedKeys := model.GetReservedKeys()
-		for _, reservedKey := range reservedKeys {
-			if _, exists := userMeta[reservedKey]; exists {
-				c.JSON(http.StatusBadRequest, serializer.ParamErr("", fmt.Errorf("reserved key '%s' is not allowed in user meta", reservedKey)))
-				return
-			}
+		if err := model.ValidateUserMeta(userMeta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
 		}
 	}
 
@@ -106,8 +194,13 @@ func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
 		Filename:   actualFilename,
 		FileHeader: file,
 		UserMeta:   userMeta,
+		SHA256:     req.SHA256,
 	})
 	if err != nil {
+		if errors.Is(err, service.ErrArtifactSHA256Mismatch) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
@@ -115,6 +208,86 @@ func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
 	c.JSON(http.StatusCreated, serializer.Response{Data: artifactRecord})
 }
 
+type CreateArtifactFromURLReq struct {
+	URL      string `form:"url" json:"url" binding:"required" example:"https://example.com/report.pdf"` // Public http(s) URL of the file
+	FilePath string `form:"file_path" json:"file_path"`                                                 // Optional, defaults to "/"
+	Filename string `form:"filename" json:"filename"`                                                   // Optional, defaults to the name given by the server or the last segment of the URL
+	Meta     string `form:"meta" json:"meta"`                                                           // Custom metadata as JSON string
+}
+
+// CreateArtifactFromURL godoc
+//
+//	@Summary		Create artifact from URL
+//	@Description	Download a file from a public http(s) URL on the server and create or update an artifact record with it under a disk. URLs reaching private or internal addresses are refused, and files above the configured size limit (100 MiB by default) are rejected with 413.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string								true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.CreateArtifactFromURLReq	true	"Create artifact from URL request"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		413	{object}	serializer.Response
+//	@Failure		502	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/from-url [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Create an artifact from a URL\nartifact = client.disks.create_artifact_from_url(\n    disk_id='disk-uuid',\n    url='https://example.com/report.pdf',\n    file_path='/documents/',\n    meta={'source': 'web'}\n)\nprint(f\"Created artifact: {artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Create an artifact from a URL\nconst artifact = await client.disks.createArtifactFromUrl('disk-uuid', {\n  url: 'https://example.com/report.pdf',\n  filePath: '/documents/',\n  meta: { source: 'web' }\n});\nconsole.log(`Created artifact: ${artifact.id}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) CreateArtifactFromURL(c *gin.Context) {
+	req := CreateArtifactFromURLReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	filePath, _ := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	var userMeta map[string]interface{}
+	if req.Meta != "" {
+		if err := sonic.Unmarshal([]byte(req.Meta), &userMeta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid meta JSON format", err))
+			return
+		}
+		if err := model.ValidateUserMeta(userMeta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+	}
+
+	artifact, err := h.svc.CreateFromURL(c.Request.Context(), service.CreateFromURLInput{
+		ProjectID: projectID(c),
+		DiskID:    diskID,
+		Path:      filePath,
+		Filename:  req.Filename,
+		SourceURL: req.URL,
+		UserMeta:  userMeta,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, safehttp.ErrForbiddenURL):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("url not allowed", err))
+		case errors.Is(err, service.ErrInvalidArtifactPath), errors.Is(err, service.ErrURLFetchDisabled):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, safehttp.ErrTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, "remote file too large", err))
+		case errors.Is(err, safehttp.ErrFetchFailed):
+			c.JSON(http.StatusBadGateway, serializer.Err(http.StatusBadGateway, "could not fetch url", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: artifact})
+}
+
 type DeleteArtifactReq struct {
 	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
 }
@@ -145,17 +318,13 @@ func (h *ArtifactHandler) DeleteArtifact(c *gin.Context) {
 		return
 	}
 
-	diskID, err := uuid.Parse(c.Param("disk_id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+	diskID, ok := h.projectDisk(c)
+	if !ok {
 		return
 	}
 
-	// Parse FilePath to extract path and filename
-	filePath, filename := path.SplitFilePath(req.FilePath)
-
-	// Validate the path parameter
-	if err := path.ValidatePath(filePath); err != nil {
+	filePath, filename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
 		return
 	}
@@ -173,18 +342,21 @@ type GetArtifactReq struct {
 	WithPublicURL bool   `form:"with_public_url,default=true" json:"with_public_url" example:"true"`
 	WithContent   bool   `form:"with_content,default=true" json:"with_content" example:"true"`
 	Expire        int    `form:"expire,default=3600" json:"expire" example:"3600"` // Expire time in seconds for presigned URL
+	Download      bool   `form:"download" json:"download" example:"false"`         // Presigned URL downloads the file as an attachment
+	Filename      string `form:"filename" json:"filename" example:"report.pdf"`    // Name of the downloaded file, defaults to the artifact filename
 }
 
 type GetArtifactResp struct {
-	Artifact  *model.Artifact         `json:"artifact"`
-	PublicURL *string                 `json:"public_url,omitempty"`
-	Content   *fileparser.FileContent `json:"content,omitempty"`
+	Artifact         *model.Artifact         `json:"artifact"`
+	PublicURL        *string                 `json:"public_url,omitempty"`
+	Content          *fileparser.FileContent `json:"content,omitempty"`
+	ContentTruncated bool                    `json:"content_truncated,omitempty"` // Content was requested but the file is too large to inline
 }
 
 // GetArtifact godoc
 //
 //	@Summary		Get artifact
-//	@Description	Get artifact information by path and filename. Optionally include a presigned URL for downloading and parsed file content.
+//	@Description	Get artifact information by path and filename. Optionally include a presigned URL for downloading and parsed file content. The content type tells how the file was parsed: text, json (with pretty-printed text), csv, code, markdown (with plain text), pdf (extracted text) or binary (raw bytes, base64 encoded in data). Content is only inlined for files up to the configured size limit (10 MiB by default); for larger files the response sets content_truncated and always includes the presigned URL instead. With download=true, the presigned URL makes browsers save the file under its filename, or the given filename, instead of displaying it. Responses without a presigned URL (with_public_url=false, no download, content not truncated) carry ETag and Last-Modified headers; conditional requests for them with If-None-Match or If-Modified-Since get 304 Not Modified when the artifact is unchanged.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
@@ -193,8 +365,11 @@ type GetArtifactResp struct {
 //	@Param			with_public_url	query	boolean	false	"Whether to return public URL, default is true"				example(true)
 //	@Param			with_content	query	boolean	false	"Whether to return parsed file content, default is true"	example(true)
 //	@Param			expire			query	int		false	"Expire time in seconds for presigned URL (default: 3600)"	example(3600)
+//	@Param			download		query	boolean	false	"Whether the presigned URL downloads the file as an attachment"	example(true)
+//	@Param			filename		query	string	false	"Name of the downloaded file, defaults to the artifact filename"	example(report.pdf)
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=handler.GetArtifactResp}
+//	@Success		304	"Not Modified"
 //	@Router			/disk/{disk_id}/artifact [get]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get artifact information\nartifact_info = client.disks.get_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    with_public_url=True,\n    with_content=True,\n    expire=3600\n)\nprint(f\"Artifact: {artifact_info.artifact.filename}\")\nif artifact_info.public_url:\n    print(f\"Download URL: {artifact_info.public_url}\")\nif artifact_info.content:\n    print(f\"Content: {artifact_info.content.text[:100]}...\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get artifact information\nconst artifactInfo = await client.disks.getArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  withPublicUrl: true,\n  withContent: true,\n  expire: 3600\n});\nconsole.log(`Artifact: ${artifactInfo.artifact.filename}`);\nif (artifactInfo.publicUrl) {\n  console.log(`Download URL: ${artifactInfo.publicUrl}`);\n}\nif (artifactInfo.content) {\n  console.log(`Content: ${artifactInfo.content.text.substring(0, 100)}...`);\n}\n","label":"JavaScript"}]
 func (h *ArtifactHandler) GetArtifact(c *gin.Context) {
@@ -204,32 +379,45 @@ func (h *ArtifactHandler) GetArtifact(c *gin.Context) {
 		return
 	}
 
-	diskID, err := uuid.Parse(c.Param("disk_id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
-		return
-	}
-
-	// Parse FilePath to extract path and filename
-	filePath, filename := path.SplitFilePath(req.FilePath)
-
-	// Validate the path parameter
-	if err := path.ValidatePath(filePath); err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+	diskID, ok := h.projectDisk(c)
+	if !ok {
 		return
 	}
 
-	artifact, err := h.svc.GetByPath(c.Request.Context(), diskID, filePath, filename)
+	artifact, err := h.svc.GetByFullPath(c.Request.Context(), diskID, req.FilePath)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidArtifactPath) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
 	resp := GetArtifactResp{Artifact: artifact}
 
+	// Large files are not inlined; the client downloads them through the presigned URL
+	if req.WithContent && artifact.AssetMeta.Data().SizeB > h.maxInlineContentSizeB {
+		req.WithContent = false
+		req.WithPublicURL = true
+		resp.ContentTruncated = true
+	}
+
+	// A presigned URL expires, so a response carrying one must not be reused after a 304
+	withURL := req.WithPublicURL || req.Download
+	if !withURL && notModified(c, artifactETag(artifact), artifact.UpdatedAt) {
+		return
+	}
+
 	// Generate presigned URL if requested
-	if req.WithPublicURL {
-		url, err := h.svc.GetPresignedURL(c.Request.Context(), artifact, time.Duration(req.Expire)*time.Second)
+	if withURL {
+		var url string
+		expire := time.Duration(req.Expire) * time.Second
+		if req.Download {
+			url, err = h.svc.GetDownloadURL(c.Request.Context(), projectID(c), artifact, req.Filename, expire)
+		} else {
+			url, err = h.svc.GetPresignedURL(c.Request.Context(), projectID(c), artifact, expire)
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 			return
@@ -239,158 +427,1027 @@ func (h *ArtifactHandler) GetArtifact(c *gin.Context) {
 
 	// Parse file content if requested
 	if req.WithContent {
-		content, err := h.svc.GetFileContent(c.Request.Context(), artifact)
-		// Only set content if parsing succeeded
-		// Unsupported file types (images, binaries, etc.) will not have content
+		content, err := h.svc.GetFileContent(c.Request.Context(), projectID(c), artifact)
+		// Only set content if parsing succeeded; unsupported file types (images, binaries,
+		// etc.) come back with type "binary" and their bytes
 		if err == nil && content != nil {
 			resp.Content = content
 		}
-		// Don't return error for unsupported file types - just don't include content
+		// Don't return error for malformed files (e.g. invalid JSON) - just don't include content
 	}
 
 	c.JSON(http.StatusOK, serializer.Response{Data: resp})
 }
 
-type UpdateArtifactReq struct {
-	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
-	Meta     string `form:"meta" json:"meta" binding:"required"`           // Custom metadata as JSON string
+type GetArtifactMetaReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required" example:"/documents/report.pdf"` // File path including filename
 }
 
-type UpdateArtifactResp struct {
-	Artifact *model.Artifact `json:"artifact"`
+type ArtifactMetaResp struct {
+	Path      string    `json:"path"`
+	Filename  string    `json:"filename"`
+	SizeB     int64     `json:"size_b"`
+	MIME      string    `json:"mime"`
+	ETag      string    `json:"etag"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// UpdateArtifact godoc
+// GetArtifactMeta godoc
 //
-//	@Summary		Update artifact meta
-//	@Description	Update an artifact's metadata (user-defined metadata only)
+//	@Summary		Get artifact metadata
+//	@Description	Get the size, MIME type, ETag and timestamps of an artifact from its record, without presigning a URL or reading the content. Use it to check that an artifact exists. Supports If-None-Match and If-Modified-Since like GetArtifact.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
-//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			request	body	handler.UpdateArtifactReq	true	"Update artifact request"
+//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.pdf)
 //	@Security		BearerAuth
-//	@Success		200	{object}	serializer.Response{data=handler.UpdateArtifactResp}
-//	@Router			/disk/{disk_id}/artifact [put]
-//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update artifact metadata\nartifact = client.disks.update_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    meta={'category': 'updated', 'reviewed': True, 'version': 2}\n)\nprint(f\"Updated artifact: {artifact.artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update artifact metadata\nconst artifact = await client.disks.updateArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  meta: { category: 'updated', reviewed: true, version: 2 }\n});\nconsole.log(`Updated artifact: ${artifact.artifact.id}`);\n","label":"JavaScript"}]
-func (h *ArtifactHandler) UpdateArtifact(c *gin.Context) {
-	req := UpdateArtifactReq{}
-	if err := c.ShouldBind(&req); err != nil {
+//	@Success		200	{object}	serializer.Response{data=handler.ArtifactMetaResp}
+//	@Success		304	"Not Modified"
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/meta [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get artifact metadata only\nmeta = client.disks.get_artifact_meta(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf'\n)\nprint(f\"{meta.mime}, {meta.size_b} bytes, updated at {meta.updated_at}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get artifact metadata only\nconst meta = await client.disks.getArtifactMeta('disk-uuid', {\n  filePath: '/documents/report.pdf'\n});\nconsole.log(`${meta.mime}, ${meta.size_b} bytes, updated at ${meta.updated_at}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) GetArtifactMeta(c *gin.Context) {
+	req := GetArtifactMetaReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
 		return
 	}
 
-	diskID, err := uuid.Parse(c.Param("disk_id"))
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.svc.GetByFullPath(c.Request.Context(), diskID, req.FilePath)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		if errors.Is(err, service.ErrInvalidArtifactPath) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
-	// Parse FilePath to extract path and filename
-	filePath, filename := path.SplitFilePath(req.FilePath)
+	if notModified(c, artifactETag(artifact), artifact.UpdatedAt) {
+		return
+	}
 
-	// Validate the path parameter
-	if err := path.ValidatePath(filePath); err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+	asset := artifact.AssetMeta.Data()
+	c.JSON(http.StatusOK, serializer.Response{Data: ArtifactMetaResp{
+		Path:      artifact.Path,
+		Filename:  artifact.Filename,
+		SizeB:     asset.SizeB,
+		MIME:      asset.MIME,
+		ETag:      asset.ETag,
+		CreatedAt: artifact.CreatedAt,
+		UpdatedAt: artifact.UpdatedAt,
+	}})
+}
+
+type VerifyArtifactReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required" example:"/documents/report.pdf"` // File path including filename
+}
+
+// VerifyArtifact godoc
+//
+//	@Summary		Verify artifact checksum
+//	@Description	Download the stored object, recompute its sha256 and compare it with the checksum recorded at upload, to detect corruption or tampering. This reads the whole object from storage, so it is slow and costly for large files; only a few verifications run at once and further requests get 429 Too Many Requests.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.pdf)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ChecksumResult}
+//	@Failure		404	{object}	serializer.Response
+//	@Failure		429	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/verify [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Verify the stored object against its checksum\nresult = client.disks.verify_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf'\n)\nif not result.valid:\n    print(f\"Checksum mismatch: expected {result.expected}, got {result.actual}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Verify the stored object against its checksum\nconst result = await client.disks.verifyArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf'\n});\nif (!result.valid) {\n  console.log(`Checksum mismatch: expected ${result.expected}, got ${result.actual}`);\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) VerifyArtifact(c *gin.Context) {
+	req := VerifyArtifactReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
 		return
 	}
 
-	// Parse user meta from JSON string
-	var userMeta map[string]interface{}
-	if err := sonic.Unmarshal([]byte(req.Meta), &userMeta); err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid meta JSON format", err))
+	diskID, ok := h.projectDisk(c)
+	if !ok {
 		return
 	}
 
-	// Validate that user meta doesn't contain system reserved keys
-	reservedKeys := model.GetReservedKeys()
-	for _, reservedKey := range reservedKeys {
-		if _, exists := userMeta[reservedKey]; exists {
-			c.JSON(http.StatusBadRequest, serializer.ParamErr("", fmt.Errorf("reserved key '%s' is not allowed in user meta", reservedKey)))
+	artifact, err := h.svc.GetByFullPath(c.Request.Context(), diskID, req.FilePath)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArtifactPath) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
 			return
 		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
 	}
 
-	// Update artifact meta
-	artifactRecord, err := h.svc.UpdateArtifactMetaByPath(c.Request.Context(), diskID, filePath, filename, userMeta)
+	result, err := h.svc.VerifyChecksum(c.Request.Context(), projectID(c), artifact)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		if errors.Is(err, service.ErrChecksumVerifyBusy) {
+			c.JSON(http.StatusTooManyRequests, serializer.Err(http.StatusTooManyRequests, "too many checksum verifications, retry later", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.Err(http.StatusInternalServerError, "failed to verify checksum", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, serializer.Response{
-		Data: UpdateArtifactResp{Artifact: artifactRecord},
-	})
-}
-
-type ListArtifactsReq struct {
-	Path string `form:"path" json:"path"` // Optional path filter
+	c.JSON(http.StatusOK, serializer.Response{Data: result})
 }
 
-type ListArtifactsResp struct {
-	Artifacts   []*model.Artifact `json:"artifacts"`
-	Directories []string          `json:"directories"`
+type GetArtifactS3MetaReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required" example:"/documents/report.pdf"` // File path including filename
 }
 
-// ListArtifacts godoc
+// GetArtifactS3Meta godoc
 //
-//	@Summary		List artifacts
-//	@Description	List artifacts in a specific path or all artifacts in a disk
+//	@Summary		Get stored object metadata of an artifact
+//	@Description	Head the object stored for an artifact and return its live metadata (size, ETag, content type and the x-amz-meta-* metadata such as sha256 and name), compared with the artifact record. Fields that disagree are listed in mismatches. Returns 404 when the object is missing from storage although the artifact exists, to detect drift.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
-//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			path	query	string	false	"Path filter (optional, defaults to root '/')"
+//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.pdf)
 //	@Security		BearerAuth
-//	@Success		200	{object}	serializer.Response{data=handler.ListArtifactsResp}
-//	@Router			/disk/{disk_id}/artifact/ls [get]
-//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List artifacts in a path\nresult = client.disks.list_artifacts(\n    disk_id='disk-uuid',\n    path='/documents/'\n)\nprint(f\"Found {len(result.artifacts)} artifacts\")\nfor artifact in result.artifacts:\n    print(f\"  - {artifact.path}{artifact.filename}\")\nprint(f\"Subdirectories: {', '.join(result.directories)}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List artifacts in a path\nconst result = await client.disks.listArtifacts('disk-uuid', {\n  path: '/documents/'\n});\nconsole.log(`Found ${result.artifacts.length} artifacts`);\nfor (const artifact of result.artifacts) {\n  console.log(`  - ${artifact.path}${artifact.filename}`);\n}\nconsole.log(`Subdirectories: ${result.directories.join(', ')}`);\n","label":"JavaScript"}]
-func (h *ArtifactHandler) ListArtifacts(c *gin.Context) {
-	diskID, err := uuid.Parse(c.Param("disk_id"))
-	if err != nil {
+//	@Success		200	{object}	serializer.Response{data=service.ArtifactObjectMeta}
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/s3-meta [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Compare the stored object with the artifact record\nresult = client.disks.get_artifact_s3_meta(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf'\n)\nfor m in result.mismatches:\n    print(f\"{m.field}: recorded {m.recorded}, stored {m.stored}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Compare the stored object with the artifact record\nconst result = await client.disks.getArtifactS3Meta('disk-uuid', {\n  filePath: '/documents/report.pdf'\n});\nfor (const m of result.mismatches) {\n  console.log(`${m.field}: recorded ${m.recorded}, stored ${m.stored}`);\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) GetArtifactS3Meta(c *gin.Context) {
+	req := GetArtifactS3MetaReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
 		return
 	}
 
-	pathQuery := c.Query("path")
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
 
-	// Set default path to root directory if not provided
-	if pathQuery == "" {
-		pathQuery = "/"
-	} else {
-		// Validate that path does not contain filename
-		if path, _ := path.SplitFilePath(pathQuery); path != pathQuery {
-			c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/'")))
+	artifact, err := h.svc.GetByFullPath(c.Request.Context(), diskID, req.FilePath)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArtifactPath) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
 			return
 		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
 	}
 
-	// Validate the path parameter
-	if err := path.ValidatePath(pathQuery); err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+	meta, err := h.svc.GetObjectMeta(c.Request.Context(), projectID(c), artifact)
+	if err != nil {
+		if errors.Is(err, service.ErrArtifactObjectMissing) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact object missing from storage", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.Err(http.StatusInternalServerError, "failed to get object metadata", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: meta})
+}
+
+type GetArtifactStatsReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required" example:"/documents/report.pdf"` // File path including filename
+}
+
+// GetArtifactStats godoc
+//
+//	@Summary		Get artifact access stats
+//	@Description	Get how many times an artifact was downloaded, counting presigned URLs and content fetches, and when it was last accessed. last_accessed_at is null for an artifact never accessed. Counts stay at 0 when access metrics are disabled on the server.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.pdf)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ArtifactAccessStats}
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/stats [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get artifact access stats\nstats = client.disks.get_artifact_stats(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf'\n)\nprint(f\"{stats.access_count} accesses, last at {stats.last_accessed_at}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get artifact access stats\nconst stats = await client.disks.getArtifactStats('disk-uuid', {\n  filePath: '/documents/report.pdf'\n});\nconsole.log(`${stats.access_count} accesses, last at ${stats.last_accessed_at}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) GetArtifactStats(c *gin.Context) {
+	req := GetArtifactStatsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
 		return
 	}
 
-	artifacts, err := h.svc.ListByPath(c.Request.Context(), diskID, pathQuery)
+	artifact, err := h.svc.GetByFullPath(c.Request.Context(), diskID, req.FilePath)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidArtifactPath) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
-	// Get all paths to extract directory names
-	allPaths, err := h.svc.GetAllPaths(c.Request.Context(), diskID)
+	stats, err := h.svc.GetAccessStats(c.Request.Context(), artifact)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		c.JSON(http.StatusInternalServerError, serializer.Err(http.StatusInternalServerError, "failed to get access stats", err))
 		return
 	}
 
-	// Extract direct subdirectories
-	directories := path.GetDirectoriesFromPaths(pathQuery, allPaths)
+	c.JSON(http.StatusOK, serializer.Response{Data: stats})
+}
 
-	c.JSON(http.StatusOK, serializer.Response{
-		Data: ListArtifactsResp{
-			Artifacts:   artifacts,
-			Directories: directories,
-		},
-	})
+type PresignArtifactsBatchReq struct {
+	FilePaths []string `json:"file_paths" binding:"required,min=1,max=500,dive,required" example:"/images/cat.png"` // File paths including filename
+	Expire    int      `json:"expire" example:"3600"`                                                               // Expire time in seconds for the presigned URLs (default: 3600)
+}
+
+type PresignArtifactsBatchResp struct {
+	URLs map[string]string `json:"urls"` // Presigned URLs keyed by file path; missing artifacts are omitted
+}
+
+// PresignArtifactsBatch godoc
+//
+//	@Summary		Presign artifacts in batch
+//	@Description	Generate presigned download URLs for many artifacts in one request. Artifacts that do not exist are left out of the result.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string							true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.PresignArtifactsBatchReq	true	"Presign artifacts request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.PresignArtifactsBatchResp}
+//	@Router			/disk/{disk_id}/artifact/presign-batch [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Presign many artifacts at once\nresult = client.disks.presign_artifacts(\n    disk_id='disk-uuid',\n    file_paths=['/images/cat.png', '/images/dog.png'],\n    expire=600\n)\nfor file_path, url in result.urls.items():\n    print(f\"{file_path}: {url}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Presign many artifacts at once\nconst result = await client.disks.presignArtifacts('disk-uuid', {\n  filePaths: ['/images/cat.png', '/images/dog.png'],\n  expire: 600\n});\nfor (const [filePath, url] of Object.entries(result.urls)) {\n  console.log(`${filePath}: ${url}`);\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) PresignArtifactsBatch(c *gin.Context) {
+	req := PresignArtifactsBatchReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	items := make([]model.ArtifactPath, 0, len(req.FilePaths))
+	for _, filePath := range req.FilePaths {
+		p, filename, err := service.SplitArtifactPath(filePath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("file_paths", err))
+			return
+		}
+		items = append(items, model.ArtifactPath{Path: p, Filename: filename})
+	}
+
+	expire := req.Expire
+	if expire <= 0 {
+		expire = 3600
+	}
+
+	urls, err := h.svc.GetPresignedURLsByPaths(c.Request.Context(), projectID(c), diskID, items, time.Duration(expire)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: PresignArtifactsBatchResp{URLs: urls}})
+}
+
+type UpdateArtifactReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
+	Meta     string `form:"meta" json:"meta" binding:"required"`           // Custom metadata as JSON string
+	Patch    bool   `form:"patch" json:"patch" example:"false"`            // Merge meta into existing user meta instead of replacing it
+}
+
+type UpdateArtifactResp struct {
+	Artifact *model.Artifact `json:"artifact"`
+}
+
+// UpdateArtifact godoc
+//
+//	@Summary		Update artifact meta
+//	@Description	Update an artifact's metadata (user-defined metadata only). With patch=true the given keys are merged into the existing user meta and keys set to null are removed; otherwise the user meta is replaced.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.UpdateArtifactReq	true	"Update artifact request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.UpdateArtifactResp}
+//	@Router			/disk/{disk_id}/artifact [put]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update artifact metadata\nartifact = client.disks.update_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    meta={'category': 'updated', 'reviewed': True, 'version': 2}\n)\nprint(f\"Updated artifact: {artifact.artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update artifact metadata\nconst artifact = await client.disks.updateArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  meta: { category: 'updated', reviewed: true, version: 2 }\n});\nconsole.log(`Updated artifact: ${artifact.artifact.id}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) UpdateArtifact(c *gin.Context) {
+	req := UpdateArtifactReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	filePath, filename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	// Parse user meta from JSON string
+	var userMeta map[string]interface{}
+	if err := sonic.Unmarshal([]byte(req.Meta), &userMeta); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid meta JSON format", err))
+		return
+	}
+
+	if err := model.ValidateUserMeta(userMeta); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	// Update artifact meta
+	var artifactRecord *model.Artifact
+	if req.Patch {
+		artifactRecord, err = h.svc.PatchArtifactMetaByPath(c.Request.Context(), diskID, filePath, filename, userMeta)
+	} else {
+		artifactRecord, err = h.svc.UpdateArtifactMetaByPath(c.Request.Context(), diskID, filePath, filename, userMeta)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{
+		Data: UpdateArtifactResp{Artifact: artifactRecord},
+	})
+}
+
+type CopyArtifactReq struct {
+	FilePath    string `form:"file_path" json:"file_path" binding:"required"`         // Source file path including filename
+	DstDiskID   string `form:"dst_disk_id" json:"dst_disk_id"`                        // Destination disk, defaults to the source disk
+	DstFilePath string `form:"dst_file_path" json:"dst_file_path" binding:"required"` // Destination file path including filename
+}
+
+// ArtifactConflictResp is the data of a 409 answer: the artifact already at the destination,
+// with its ID, to update or copy under another name
+type ArtifactConflictResp struct {
+	ID       uuid.UUID       `json:"id"`
+	Artifact *model.Artifact `json:"artifact"`
+}
+
+// CopyArtifact godoc
+//
+//	@Summary		Copy artifact
+//	@Description	Copy an artifact to another path, on the same disk or another disk of the project. The copy shares the stored file with the source, so no content is uploaded. Fails with 409 if the destination already exists; the response data then holds the existing artifact and its ID.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Source disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.CopyArtifactReq	true	"Copy artifact request"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		404	{object}	serializer.Response
+//	@Failure		409	{object}	serializer.Response{data=handler.ArtifactConflictResp}
+//	@Router			/disk/{disk_id}/artifact/copy [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Copy an artifact to another disk\nartifact = client.disks.copy_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    dst_disk_id='other-disk-uuid',\n    dst_file_path='/archive/report.pdf'\n)\nprint(f\"Copied to: {artifact.path}{artifact.filename}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Copy an artifact to another disk\nconst artifact = await client.disks.copyArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  dstDiskId: 'other-disk-uuid',\n  dstFilePath: '/archive/report.pdf'\n});\nconsole.log(`Copied to: ${artifact.path}${artifact.filename}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) CopyArtifact(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := CopyArtifactReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	dstDiskID := diskID
+	if req.DstDiskID != "" {
+		var err error
+		if dstDiskID, err = uuid.Parse(req.DstDiskID); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid dst_disk_id", err))
+			return
+		}
+		// Asset references are counted per project, so both disks must belong to it
+		if dstDiskID != diskID && !h.checkProjectDisk(c, dstDiskID, "destination disk not found") {
+			return
+		}
+	}
+
+	srcPath, srcFilename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid file_path", err))
+		return
+	}
+	dstPath, dstFilename, err := service.SplitArtifactPath(req.DstFilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid dst_file_path", err))
+		return
+	}
+
+	artifact, err := h.svc.CopyArtifact(c.Request.Context(), service.CopyArtifactInput{
+		ProjectID:   project.ID,
+		SrcDiskID:   diskID,
+		SrcPath:     srcPath,
+		SrcFilename: srcFilename,
+		DstDiskID:   dstDiskID,
+		DstPath:     dstPath,
+		DstFilename: dstFilename,
+	})
+	if err != nil {
+		var exists *service.ErrArtifactExists
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+		case errors.As(err, &exists):
+			resp := serializer.Err(http.StatusConflict, "destination artifact already exists", err)
+			resp.Data = ArtifactConflictResp{ID: exists.Artifact.ID, Artifact: exists.Artifact}
+			c.JSON(http.StatusConflict, resp)
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: artifact})
+}
+
+type RenameArtifactReq struct {
+	FilePath    string `form:"file_path" json:"file_path" binding:"required"`       // File path including filename
+	NewFilename string `form:"new_filename" json:"new_filename" binding:"required"` // New filename, in the same directory
+}
+
+// RenameArtifact godoc
+//
+//	@Summary		Rename artifact
+//	@Description	Rename an artifact within its directory. The tags and meta are kept; only the filename and the filename in the system meta change. On a disk with path addressed keys the stored file is moved to its new key. Fails with 409 if an artifact already has the new name; the response data then holds it and its ID.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.RenameArtifactReq	true	"Rename artifact request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		404	{object}	serializer.Response
+//	@Failure		409	{object}	serializer.Response{data=handler.ArtifactConflictResp}
+//	@Router			/disk/{disk_id}/artifact/rename [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Rename an artifact\nartifact = client.disks.rename_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    new_filename='report-2024.pdf'\n)\nprint(f\"Renamed to: {artifact.path}{artifact.filename}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Rename an artifact\nconst artifact = await client.disks.renameArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  newFilename: 'report-2024.pdf'\n});\nconsole.log(`Renamed to: ${artifact.path}${artifact.filename}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) RenameArtifact(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := RenameArtifactReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	path, filename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid file_path", err))
+		return
+	}
+
+	artifact, err := h.svc.RenameArtifact(c.Request.Context(), project.ID, diskID, path, filename, req.NewFilename)
+	if err != nil {
+		var exists *service.ErrArtifactExists
+		switch {
+		case errors.Is(err, service.ErrInvalidArtifactPath):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid new_filename", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+		case errors.As(err, &exists):
+			resp := serializer.Err(http.StatusConflict, "an artifact with the new filename already exists", err)
+			resp.Data = ArtifactConflictResp{ID: exists.Artifact.ID, Artifact: exists.Artifact}
+			c.JSON(http.StatusConflict, resp)
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: artifact})
+}
+
+type MoveArtifactReq struct {
+	FilePath    string `form:"file_path" json:"file_path" binding:"required"`         // File path including filename
+	DstFilePath string `form:"dst_file_path" json:"dst_file_path" binding:"required"` // New file path including filename, on the same disk
+}
+
+// MoveArtifact godoc
+//
+//	@Summary		Move artifact
+//	@Description	Move an artifact to another path of its disk. The tags and meta are kept; the path and filename in the system meta change. On a disk with path addressed keys the stored file is copied to its new key and the old one is deleted. Fails with 409 if an artifact is already at the destination; the response data then holds it and its ID.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.MoveArtifactReq	true	"Move artifact request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		404	{object}	serializer.Response
+//	@Failure		409	{object}	serializer.Response{data=handler.ArtifactConflictResp}
+//	@Router			/disk/{disk_id}/artifact/move [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Move an artifact to another directory\nartifact = client.disks.move_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    dst_file_path='/archive/2024/report.pdf'\n)\nprint(f\"Moved to: {artifact.path}{artifact.filename}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Move an artifact to another directory\nconst artifact = await client.disks.moveArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  dstFilePath: '/archive/2024/report.pdf'\n});\nconsole.log(`Moved to: ${artifact.path}${artifact.filename}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) MoveArtifact(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := MoveArtifactReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	path, filename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid file_path", err))
+		return
+	}
+	dstPath, dstFilename, err := service.SplitArtifactPath(req.DstFilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid dst_file_path", err))
+		return
+	}
+
+	artifact, err := h.svc.MoveArtifact(c.Request.Context(), service.MoveArtifactInput{
+		ProjectID:   project.ID,
+		DiskID:      diskID,
+		Path:        path,
+		Filename:    filename,
+		DstPath:     dstPath,
+		DstFilename: dstFilename,
+	})
+	if err != nil {
+		var exists *service.ErrArtifactExists
+		switch {
+		case errors.Is(err, service.ErrInvalidArtifactPath):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid dst_file_path", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+		case errors.As(err, &exists):
+			resp := serializer.Err(http.StatusConflict, "destination artifact already exists", err)
+			resp.Data = ArtifactConflictResp{ID: exists.Artifact.ID, Artifact: exists.Artifact}
+			c.JSON(http.StatusConflict, resp)
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: artifact})
+}
+
+type ListArtifactsReq struct {
+	Path       string `form:"path" json:"path"`                                // Optional path filter
+	MIMEPrefix string `form:"mime_prefix" json:"mime_prefix" example:"image/"` // Optional MIME type prefix filter
+}
+
+type ListArtifactsResp struct {
+	Artifacts   []*model.Artifact `json:"artifacts"`
+	Directories []string          `json:"directories"`
+}
+
+// ListArtifacts godoc
+//
+//	@Summary		List artifacts
+//	@Description	List artifacts in a specific path or all artifacts in a disk
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			path		query	string	false	"Directory path (optional, defaults to root '/'); normalized to a leading and trailing slash"
+//	@Param			mime_prefix	query	string	false	"Only list artifacts whose MIME type starts with this prefix, e.g. image/"	example(image/)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ListArtifactsResp}
+//	@Router			/disk/{disk_id}/artifact/ls [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List artifacts in a path\nresult = client.disks.list_artifacts(\n    disk_id='disk-uuid',\n    path='/documents/'\n)\nprint(f\"Found {len(result.artifacts)} artifacts\")\nfor artifact in result.artifacts:\n    print(f\"  - {artifact.path}{artifact.filename}\")\nprint(f\"Subdirectories: {', '.join(result.directories)}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List artifacts in a path\nconst result = await client.disks.listArtifacts('disk-uuid', {\n  path: '/documents/'\n});\nconsole.log(`Found ${result.artifacts.length} artifacts`);\nfor (const artifact of result.artifacts) {\n  console.log(`  - ${artifact.path}${artifact.filename}`);\n}\nconsole.log(`Subdirectories: ${result.directories.join(', ')}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) ListArtifacts(c *gin.Context) {
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	req := ListArtifactsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	// "/docs", "docs/" and "/docs//" all list the same directory; empty lists the root
+	pathQuery := path.CanonicalDir(req.Path)
+
+	// Validate the path parameter
+	if err := path.ValidatePath(pathQuery); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	artifacts, err := h.svc.ListByPath(c.Request.Context(), diskID, pathQuery, req.MIMEPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	// Get all paths to extract directory names
+	allPaths, err := h.svc.GetAllPaths(c.Request.Context(), diskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	// Extract direct subdirectories
+	directories := path.GetDirectoriesFromPaths(pathQuery, allPaths)
+
+	c.JSON(http.StatusOK, serializer.Response{
+		Data: ListArtifactsResp{
+			Artifacts:   artifacts,
+			Directories: directories,
+		},
+	})
+}
+
+type ListAllArtifactsReq struct {
+	Limit    int    `form:"limit,default=100" json:"limit" binding:"required,min=1,max=1000" example:"100"`
+	Cursor   string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
+	TimeDesc bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
+}
+
+// ListAllArtifacts godoc
+//
+//	@Summary		List all artifacts
+//	@Description	List every artifact of a disk whatever its path, ordered by creation time. Use it to export or back up a disk.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			limit		query	integer	false	"Limit of artifacts to return, default 100. Max 1000."
+//	@Param			cursor		query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
+//	@Param			time_desc	query	boolean	false	"Order by created_at descending if true, ascending if false (default false)"	example(false)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ListArtifactsByDiskOutput}
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/all [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Page through every artifact of a disk\ncursor = None\nwhile True:\n    page = client.disks.list_all_artifacts(disk_id='disk-uuid', limit=100, cursor=cursor)\n    for artifact in page.items:\n        print(f\"{artifact.path}{artifact.filename}\")\n    if not page.has_more:\n        break\n    cursor = page.next_cursor\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Page through every artifact of a disk\nlet cursor;\nwhile (true) {\n  const page = await client.disks.listAllArtifacts('disk-uuid', { limit: 100, cursor });\n  for (const artifact of page.items) {\n    console.log(`${artifact.path}${artifact.filename}`);\n  }\n  if (!page.hasMore) break;\n  cursor = page.nextCursor;\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) ListAllArtifacts(c *gin.Context) {
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	req := ListAllArtifactsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	out, err := h.svc.ListByDisk(c.Request.Context(), service.ListArtifactsByDiskInput{
+		DiskID:   diskID,
+		Limit:    req.Limit,
+		Cursor:   req.Cursor,
+		TimeDesc: req.TimeDesc,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+// DownloadDiskArchive godoc
+//
+//	@Summary		Download disk archive
+//	@Description	Stream a ZIP archive of every artifact of a disk, each stored under its path and filename, for backups. The last entry, manifest.json, lists the metadata of every file and the name it is stored under; files whose name is taken, such as an artifact at /manifest.json, get a " (2)" suffix. Errors after the download started truncate the archive.
+//	@Tags			artifact
+//	@Produce		application/zip
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{file}		file
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/archive [get]
+//	@x-code-samples	[{"lang":"python","source":"import requests\n\nwith requests.get(\n    'https://api.acontext.io/api/v1/disk/disk-uuid/artifact/archive',\n    headers={'Authorization': 'Bearer sk_project_token'},\n    stream=True,\n) as resp:\n    resp.raise_for_status()\n    with open('disk.zip', 'wb') as f:\n        for chunk in resp.iter_content(chunk_size=1 << 20):\n            f.write(chunk)\n","label":"Python"},{"lang":"javascript","source":"import { createWriteStream } from 'node:fs';\nimport { Readable } from 'node:stream';\nimport { pipeline } from 'node:stream/promises';\n\nconst resp = await fetch('https://api.acontext.io/api/v1/disk/disk-uuid/artifact/archive', {\n  headers: { Authorization: 'Bearer sk_project_token' },\n});\nawait pipeline(Readable.fromWeb(resp.body), createWriteStream('disk.zip'));\n","label":"JavaScript"}]
+func (h *ArtifactHandler) DownloadDiskArchive(c *gin.Context) {
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	w := &archiveWriter{c: c, filename: fmt.Sprintf("disk-%s.zip", diskID)}
+	if err := h.svc.WriteArchive(c.Request.Context(), projectID(c), diskID, w); err != nil {
+		if w.started {
+			// The archive is partly sent: stop here and leave it truncated
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
+
+// archiveWriter commits the archive headers and status with its first bytes, so a failure
+// before any byte is written can still be answered with an error
+type archiveWriter struct {
+	c        *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *archiveWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/zip")
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+type ImportDiskArchiveReq struct {
+	Mode string `form:"mode,default=skip" json:"mode" binding:"omitempty,oneof=skip overwrite" example:"skip"`
+}
+
+// ImportDiskArchive godoc
+//
+//	@Summary		Import disk archive
+//	@Description	Create an artifact for every file of an uploaded ZIP archive, at the path given by its entry name, e.g. docs/2024/report.pdf is stored as /docs/2024/report.pdf. Archives downloaded from /disk/{disk_id}/artifact/archive also get the paths, meta and tags of their manifest back. Files already in the disk are kept with mode=skip and replaced with mode=overwrite. Files that cannot be imported, e.g. with an invalid path or larger than 100 MiB, are reported in failed and do not stop the import.
+//	@Tags			artifact
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			disk_id	path		string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file	formData	file	true	"ZIP archive to import"
+//	@Param			mode	formData	string	false	"What to do with files already in the disk: skip (default) or overwrite"	Enums(skip, overwrite)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ImportArchiveOutput}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/archive [post]
+//	@x-code-samples	[{"lang":"python","source":"import requests\n\nwith open('disk.zip', 'rb') as f:\n    resp = requests.post(\n        'https://api.acontext.io/api/v1/disk/disk-uuid/artifact/archive',\n        headers={'Authorization': 'Bearer sk_project_token'},\n        files={'file': ('disk.zip', f, 'application/zip')},\n        data={'mode': 'skip'},\n    )\nsummary = resp.json()['data']\nprint(f\"created={summary['created']} skipped={summary['skipped']} failed={len(summary['failed'])}\")\n","label":"Python"},{"lang":"javascript","source":"import fs from 'fs';\n\nconst form = new FormData();\nform.append('file', new Blob([fs.readFileSync('disk.zip')]), 'disk.zip');\nform.append('mode', 'skip');\n\nconst resp = await fetch('https://api.acontext.io/api/v1/disk/disk-uuid/artifact/archive', {\n  method: 'POST',\n  headers: { Authorization: 'Bearer sk_project_token' },\n  body: form,\n});\nconst summary = (await resp.json()).data;\nconsole.log(`created=${summary.created} skipped=${summary.skipped} failed=${summary.failed.length}`);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) ImportDiskArchive(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := ImportDiskArchiveReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("file is required", err))
+		return
+	}
+	file, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("file", err))
+		return
+	}
+	defer file.Close()
+
+	out, err := h.svc.ImportArchive(c.Request.Context(), service.ImportArchiveInput{
+		ProjectID: project.ID,
+		DiskID:    diskID,
+		Archive:   file,
+		Size:      fh.Size,
+		Mode:      service.ArchiveImportMode(req.Mode),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArchive) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("file", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+type ListArtifactDirsResp struct {
+	Directories []model.DirectoryCount `json:"directories"`
+}
+
+// ListArtifactDirs godoc
+//
+//	@Summary		List artifact directories
+//	@Description	List every directory of a disk, ordered by path, with the number of artifacts directly in each. Directories that only hold subdirectories, and the root "/", have a count of 0. Use it to render a file tree.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ListArtifactDirsResp}
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/disk/{disk_id}/artifact/dirs [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List the directory tree of a disk\nresult = client.disks.list_artifact_dirs(disk_id='disk-uuid')\nfor directory in result.directories:\n    print(f\"{directory.path} ({directory.count} files)\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List the directory tree of a disk\nconst result = await client.disks.listArtifactDirs('disk-uuid');\nfor (const directory of result.directories) {\n  console.log(`${directory.path} (${directory.count} files)`);\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) ListArtifactDirs(c *gin.Context) {
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	directories, err := h.svc.GetDirectoryTree(c.Request.Context(), diskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: ListArtifactDirsResp{Directories: directories}})
+}
+
+// artifactTagsErr maps artifact tag service errors to HTTP responses
+func artifactTagsErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "artifact not found", err))
+	case errors.Is(err, service.ErrInvalidArtifactTags):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("tags", err))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
+
+type ArtifactTagsReq struct {
+	FilePath string   `form:"file_path" json:"file_path" binding:"required" example:"/invoices/march.pdf"` // File path including filename
+	Tags     []string `form:"tags" json:"tags" binding:"required,min=1" example:"invoice,2024"`
+}
+
+type ArtifactTagsResp struct {
+	Artifact *model.Artifact `json:"artifact"`
+}
+
+// bindArtifactTags binds an artifact tags request, answering 400 when it is invalid and 404
+// when the disk is not one of the project
+func (h *ArtifactHandler) bindArtifactTags(c *gin.Context) (uuid.UUID, model.ArtifactPath, []string, bool) {
+	req := ArtifactTagsReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return uuid.Nil, model.ArtifactPath{}, nil, false
+	}
+
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return uuid.Nil, model.ArtifactPath{}, nil, false
+	}
+
+	filePath, filename, err := service.SplitArtifactPath(req.FilePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return uuid.Nil, model.ArtifactPath{}, nil, false
+	}
+	return diskID, model.ArtifactPath{Path: filePath, Filename: filename}, req.Tags, true
+}
+
+// AddArtifactTags godoc
+//
+//	@Summary		Add artifact tags
+//	@Description	Add tags to an artifact. Tags the artifact already has are ignored. Tags are stored separately from the artifact meta.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.ArtifactTagsReq	true	"Add tags request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ArtifactTagsResp}
+//	@Router			/disk/{disk_id}/artifact/tags [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Tag an artifact\nresult = client.disks.add_artifact_tags(\n    disk_id='disk-uuid',\n    file_path='/invoices/march.pdf',\n    tags=['invoice', '2024']\n)\nprint(result.artifact.tags)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Tag an artifact\nconst result = await client.disks.addArtifactTags('disk-uuid', {\n  filePath: '/invoices/march.pdf',\n  tags: ['invoice', '2024']\n});\nconsole.log(result.artifact.tags);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) AddArtifactTags(c *gin.Context) {
+	diskID, item, tags, ok := h.bindArtifactTags(c)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.svc.AddTags(c.Request.Context(), diskID, item.Path, item.Filename, tags)
+	if err != nil {
+		artifactTagsErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: ArtifactTagsResp{Artifact: artifact}})
+}
+
+// RemoveArtifactTags godoc
+//
+//	@Summary		Remove artifact tags
+//	@Description	Remove tags from an artifact. Tags the artifact does not have are ignored.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string		true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string		true	"File path including filename"	example(/invoices/march.pdf)
+//	@Param			tags		query	[]string	true	"Tags to remove"				collectionFormat(multi)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ArtifactTagsResp}
+//	@Router			/disk/{disk_id}/artifact/tags [delete]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Remove a tag from an artifact\nresult = client.disks.remove_artifact_tags(\n    disk_id='disk-uuid',\n    file_path='/invoices/march.pdf',\n    tags=['2024']\n)\nprint(result.artifact.tags)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Remove a tag from an artifact\nconst result = await client.disks.removeArtifactTags('disk-uuid', {\n  filePath: '/invoices/march.pdf',\n  tags: ['2024']\n});\nconsole.log(result.artifact.tags);\n","label":"JavaScript"}]
+func (h *ArtifactHandler) RemoveArtifactTags(c *gin.Context) {
+	diskID, item, tags, ok := h.bindArtifactTags(c)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.svc.RemoveTags(c.Request.Context(), diskID, item.Path, item.Filename, tags)
+	if err != nil {
+		artifactTagsErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: ArtifactTagsResp{Artifact: artifact}})
+}
+
+type ListArtifactsByTagResp struct {
+	Artifacts []*model.Artifact `json:"artifacts"`
+}
+
+// ListArtifactsByTag godoc
+//
+//	@Summary		List artifacts by tag
+//	@Description	List the artifacts of a disk carrying a tag
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			tag		query	string	true	"Tag"		example(invoice)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ListArtifactsByTagResp}
+//	@Router			/disk/{disk_id}/artifact/tags [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List artifacts by tag\nresult = client.disks.list_artifacts_by_tag(\n    disk_id='disk-uuid',\n    tag='invoice'\n)\nfor artifact in result.artifacts:\n    print(f\"{artifact.path}{artifact.filename}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List artifacts by tag\nconst result = await client.disks.listArtifactsByTag('disk-uuid', { tag: 'invoice' });\nfor (const artifact of result.artifacts) {\n  console.log(`${artifact.path}${artifact.filename}`);\n}\n","label":"JavaScript"}]
+func (h *ArtifactHandler) ListArtifactsByTag(c *gin.Context) {
+	diskID, ok := h.projectDisk(c)
+	if !ok {
+		return
+	}
+
+	tag := c.Query("tag")
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("tag", errors.New("tag is required")))
+		return
+	}
+
+	artifacts, err := h.svc.ListByTag(c.Request.Context(), diskID, tag)
+	if err != nil {
+		artifactTagsErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: ListArtifactsByTagResp{Artifacts: artifacts}})
 }
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -13,13 +15,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockArtifactService is a mock implementation of ArtifactService
@@ -42,18 +48,95 @@ func (m *MockArtifactService) GetByID(ctx context.Context, diskID uuid.UUID, art
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) GetPresignedURL(ctx context.Context, artifact *model.Artifact, expire time.Duration) (string, error) {
-	args := m.Called(ctx, artifact, expire)
+func (m *MockArtifactService) CopyArtifact(ctx context.Context, in service.CopyArtifactInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) RenameArtifact(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, oldFilename string, newFilename string) (*model.Artifact, error) {
+	args := m.Called(ctx, projectID, diskID, path, oldFilename, newFilename)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) MoveArtifact(ctx context.Context, in service.MoveArtifactInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateFromURL(ctx context.Context, in service.CreateFromURLInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) GetDownloadURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, filename string, expire time.Duration) (string, error) {
+	args := m.Called(ctx, projectID, artifact, filename, expire)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockArtifactService) GetPresignedURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration) (string, error) {
+	args := m.Called(ctx, projectID, artifact, expire)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockArtifactService) AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, tag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) GetAccessStats(ctx context.Context, artifact *model.Artifact) (*service.ArtifactAccessStats, error) {
+	args := m.Called(ctx, artifact)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactAccessStats), args.Error(1)
+}
+
+func (m *MockArtifactService) GetPresignedURLsByPaths(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, items []model.ArtifactPath, expire time.Duration) (map[string]string, error) {
+	args := m.Called(ctx, projectID, diskID, items, expire)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
 func (m *MockArtifactService) UpdateArtifact(ctx context.Context, diskID uuid.UUID, artifactID uuid.UUID, fileHeader *multipart.FileHeader, newPath *string, newFilename *string) (*model.Artifact, error) {
 	args := m.Called(ctx, diskID, artifactID, fileHeader, newPath, newFilename)
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error) {
-	args := m.Called(ctx, diskID, path)
+func (m *MockArtifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, mimePrefix)
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
@@ -62,9 +145,33 @@ func (m *MockArtifactService) GetAllPaths(ctx context.Context, diskID uuid.UUID)
 	return args.Get(0).([]string), args.Error(1)
 }
 
-func (m *MockArtifactService) GetByDiskID(ctx context.Context, diskID uuid.UUID) ([]*model.Artifact, error) {
+func (m *MockArtifactService) GetDirectoryTree(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error) {
 	args := m.Called(ctx, diskID)
-	return args.Get(0).([]*model.Artifact), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.DirectoryCount), args.Error(1)
+}
+
+func (m *MockArtifactService) ListByDisk(ctx context.Context, in service.ListArtifactsByDiskInput) (*service.ListArtifactsByDiskOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ListArtifactsByDiskOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) WriteArchive(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, w io.Writer) error {
+	args := m.Called(ctx, projectID, diskID, w)
+	return args.Error(0)
+}
+
+func (m *MockArtifactService) ImportArchive(ctx context.Context, in service.ImportArchiveInput) (*service.ImportArchiveOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ImportArchiveOutput), args.Error(1)
 }
 
 func (m *MockArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
@@ -77,6 +184,14 @@ func (m *MockArtifactService) GetByPath(ctx context.Context, diskID uuid.UUID, p
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactService) GetByFullPath(ctx context.Context, diskID uuid.UUID, fullPath string) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, fullPath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
 func (m *MockArtifactService) UpdateArtifactByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, fileHeader *multipart.FileHeader, newPath *string, newFilename *string) (*model.Artifact, error) {
 	args := m.Called(ctx, diskID, path, filename, fileHeader, newPath, newFilename)
 	return args.Get(0).(*model.Artifact), args.Error(1)
@@ -87,14 +202,56 @@ func (m *MockArtifactService) UpdateArtifactMetaByPath(ctx context.Context, disk
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) GetFileContent(ctx context.Context, artifact *model.Artifact) (*fileparser.FileContent, error) {
-	args := m.Called(ctx, artifact)
+func (m *MockArtifactService) PatchArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]interface{}) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, patch)
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) GetFileContent(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*fileparser.FileContent, error) {
+	args := m.Called(ctx, projectID, artifact)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*fileparser.FileContent), args.Error(1)
 }
 
+func (m *MockArtifactService) GetObjectMeta(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*service.ArtifactObjectMeta, error) {
+	args := m.Called(ctx, projectID, artifact)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactObjectMeta), args.Error(1)
+}
+
+func (m *MockArtifactService) VerifyChecksum(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*service.ChecksumResult, error) {
+	args := m.Called(ctx, projectID, artifact)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ChecksumResult), args.Error(1)
+}
+
+// testProjectID is the project authenticated in artifact handler tests
+var testProjectID = uuid.New()
+
+// projectDisks serves every disk as owned by projectID
+type projectDisks struct {
+	service.DiskService
+	projectID uuid.UUID
+}
+
+func (d projectDisks) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	return &model.Disk{ID: diskID, ProjectID: d.projectID}, nil
+}
+
+// withProject authenticates requests as the given project
+func withProject(projectID uuid.UUID) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("project", &model.Project{ID: projectID})
+		c.Next()
+	}
+}
+
 func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -103,6 +260,7 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 		diskID         string
 		filePath       string
 		meta           string
+		sha256         string
 		fileContent    string
 		fileName       string
 		mockSetup      func(*MockArtifactService, string, uuid.UUID)
@@ -146,6 +304,42 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name:        "claimed sha256 is passed on",
+			diskID:      uuid.New().String(),
+			filePath:    "/test/test.txt",
+			sha256:      "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72",
+			fileContent: "test content",
+			fileName:    "test.txt",
+			mockSetup: func(m *MockArtifactService, diskIDStr string, projectID uuid.UUID) {
+				m.On("Create", mock.Anything, mock.MatchedBy(func(in service.CreateArtifactInput) bool {
+					return in.SHA256 == "6ae8a75555209fd6c44157c0aed8016e763ff435a19cf186f76863140143ff72"
+				})).Return(&model.Artifact{ID: uuid.New()}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "invalid sha256",
+			diskID:         uuid.New().String(),
+			filePath:       "/test/test.txt",
+			sha256:         "not-a-hash",
+			fileContent:    "test content",
+			fileName:       "test.txt",
+			mockSetup:      func(m *MockArtifactService, diskIDStr string, projectID uuid.UUID) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "sha256 mismatch",
+			diskID:      uuid.New().String(),
+			filePath:    "/test/test.txt",
+			sha256:      "0000000000000000000000000000000000000000000000000000000000000000",
+			fileContent: "test content",
+			fileName:    "test.txt",
+			mockSetup: func(m *MockArtifactService, diskIDStr string, projectID uuid.UUID) {
+				m.On("Create", mock.Anything, mock.Anything).Return((*model.Artifact)(nil), service.ErrArtifactSHA256Mismatch)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,7 +348,7 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 			projectID := uuid.New()
 			tt.mockSetup(mockService, tt.diskID, projectID)
 
-			handler := NewArtifactHandler(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: projectID}, defaultMaxInlineContentSizeB, false)
 
 			// Create multipart form data
 			body := &bytes.Buffer{}
@@ -173,6 +367,9 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 			if tt.meta != "" {
 				writer.WriteField("meta", tt.meta)
 			}
+			if tt.sha256 != "" {
+				writer.WriteField("sha256", tt.sha256)
+			}
 
 			writer.Close()
 
@@ -238,7 +435,7 @@ func TestArtifactHandler_DeleteArtifact(t *testing.T) {
 			projectID := uuid.New()
 			tt.mockSetup(mockService, tt.diskID, tt.filePath, projectID)
 
-			handler := NewArtifactHandler(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: projectID}, defaultMaxInlineContentSizeB, false)
 
 			// Create request with query parameters
 			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/disk/%s/artifact?file_path=%s", tt.diskID, tt.filePath), nil)
@@ -356,6 +553,16 @@ func TestArtifactHandler_UpdateArtifact(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:     "meta update with nested reserved key",
+			diskID:   uuid.New().String(),
+			filePath: "/test/report.pdf",
+			meta:     `{"source": {"__artifact_info__": {"test": "value"}}}`,
+			mockSetup: func(m *MockArtifactService, diskIDStr string) {
+				// Rejected before reaching the service
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -363,7 +570,7 @@ func TestArtifactHandler_UpdateArtifact(t *testing.T) {
 			mockService := new(MockArtifactService)
 			tt.mockSetup(mockService, tt.diskID)
 
-			handler := NewArtifactHandler(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
 
 			// Create JSON request body
 			requestBody := map[string]string{
@@ -383,6 +590,7 @@ func TestArtifactHandler_UpdateArtifact(t *testing.T) {
 			// Create gin context
 			c, _ := gin.CreateTestContext(w)
 			c.Request = req
+			c.Set("project", &model.Project{ID: testProjectID})
 			c.Params = []gin.Param{
 				{Key: "disk_id", Value: tt.diskID},
 			}
@@ -451,9 +659,9 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 					Type: "csv",
 					Raw:  "name,age\nJohn,25",
 				}
-				m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(expectedFile, nil)
-				m.On("GetPresignedURL", mock.Anything, expectedFile, mock.AnythingOfType("time.Duration")).Return("https://example.com/presigned-url", nil)
-				m.On("GetFileContent", mock.Anything, expectedFile).Return(expectedContent, nil)
+				m.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(expectedFile, nil)
+				m.On("GetPresignedURL", mock.Anything, mock.Anything, expectedFile, mock.AnythingOfType("time.Duration")).Return("https://example.com/presigned-url", nil)
+				m.On("GetFileContent", mock.Anything, mock.Anything, expectedFile).Return(expectedContent, nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -487,7 +695,7 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 						SizeB:  1024,
 					}),
 				}
-				m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(expectedFile, nil)
+				m.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(expectedFile, nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -509,7 +717,7 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 			mockService := new(MockArtifactService)
 			tt.mockSetup(mockService, tt.diskID, tt.filePath)
 
-			handler := NewArtifactHandler(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
 
 			// Create request with query parameters
 			url := fmt.Sprintf("/disk/%s/artifact?file_path=%s", tt.diskID, tt.filePath)
@@ -532,6 +740,7 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 			// Create gin context
 			c, _ := gin.CreateTestContext(w)
 			c.Request = req
+			c.Set("project", &model.Project{ID: testProjectID})
 			c.Params = []gin.Param{
 				{Key: "disk_id", Value: tt.diskID},
 			}
@@ -562,3 +771,1473 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactHandler_GetArtifact_Conditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	updatedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	artifact := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/test/",
+		Filename:  "data.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{ETag: `"abc123"`, MIME: "text/csv"}),
+		UpdatedAt: updatedAt,
+	}
+	etag := artifactETag(artifact)
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "no conditional headers",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "matching If-None-Match",
+			headers:        map[string]string{"If-None-Match": `"other", ` + etag},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "stale If-None-Match",
+			headers:        map[string]string{"If-None-Match": `W/"abc123-0"`},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "If-Modified-Since not older than the artifact",
+			headers:        map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "If-Modified-Since older than the artifact",
+			headers:        map[string]string{"If-Modified-Since": updatedAt.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			mockService.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/disk/%s/artifact?file_path=/test/data.csv&with_content=false&with_public_url=false", diskID), nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			assert.Contains(t, etag, "abc123")
+			assert.Equal(t, updatedAt.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_GetArtifact_PresignedURLNotConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	artifact := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/test/",
+		Filename:  "data.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{ETag: `"abc123"`, MIME: "text/csv"}),
+		UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	mockService := new(MockArtifactService)
+	mockService.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+	mockService.On("GetPresignedURL", mock.Anything, testProjectID, artifact, time.Hour).Return("https://s3.example.com/fresh", nil)
+	handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+	router := gin.New()
+	router.Use(withProject(testProjectID))
+	router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+
+	// The client copy holds a URL that may have expired, so a fresh one is sent
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/disk/%s/artifact?file_path=/test/data.csv&with_content=false", diskID), nil)
+	req.Header.Set("If-None-Match", artifactETag(artifact))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), "https://s3.example.com/fresh")
+	mockService.AssertExpectations(t)
+}
+
+func TestArtifactHandler_GetArtifactMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	updatedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	artifact := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/test/",
+		Filename:  "data.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{ETag: `"abc123"`, MIME: "text/csv", SizeB: 2048, S3Key: "disks/data.csv"}),
+		CreatedAt: updatedAt.Add(-time.Hour),
+		UpdatedAt: updatedAt,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name:  "existing artifact",
+			query: "?file_path=/test/data.csv",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "missing artifact",
+			query: "?file_path=/test/missing.csv",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/missing.csv").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "directory path",
+			query: "?file_path=/test/",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/").Return(nil, service.ErrInvalidArtifactPath)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing file_path",
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/meta", handler.GetArtifactMeta)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/meta"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var resp struct {
+					Data ArtifactMetaResp `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, int64(2048), resp.Data.SizeB)
+				assert.Equal(t, "text/csv", resp.Data.MIME)
+				assert.Equal(t, `"abc123"`, resp.Data.ETag)
+				assert.True(t, updatedAt.Equal(resp.Data.UpdatedAt))
+				assert.NotContains(t, w.Body.String(), "s3_key")
+				assert.Equal(t, artifactETag(artifact), w.Header().Get("ETag"))
+			}
+			mockService.AssertExpectations(t)
+			mockService.AssertNotCalled(t, "GetPresignedURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestArtifactHandler_GetArtifactStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	artifact := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/test/", Filename: "data.csv"}
+	lastAccessedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name:  "existing artifact",
+			query: "?file_path=/test/data.csv",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("GetAccessStats", mock.Anything, artifact).Return(&service.ArtifactAccessStats{AccessCount: 7, LastAccessedAt: &lastAccessedAt}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "missing artifact",
+			query: "?file_path=/test/missing.csv",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/missing.csv").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "stats error",
+			query: "?file_path=/test/data.csv",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("GetAccessStats", mock.Anything, artifact).Return(nil, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "missing file_path",
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/stats", handler.GetArtifactStats)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/stats"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var resp struct {
+					Data service.ArtifactAccessStats `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, int64(7), resp.Data.AccessCount)
+				require.NotNil(t, resp.Data.LastAccessedAt)
+				assert.True(t, lastAccessedAt.Equal(*resp.Data.LastAccessedAt))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_PresignArtifactsBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+
+	tests := []struct {
+		name           string
+		diskID         string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedURLs   map[string]string
+	}{
+		{
+			name:   "presign batch",
+			diskID: diskID.String(),
+			body:   `{"file_paths":["/images/cat.png","images/dog.png","/images/missing.png"],"expire":600}`,
+			setup: func(m *MockArtifactService) {
+				m.On("GetPresignedURLsByPaths", mock.Anything, mock.Anything, diskID, []model.ArtifactPath{
+					{Path: "/images/", Filename: "cat.png"},
+					{Path: "/images/", Filename: "dog.png"},
+					{Path: "/images/", Filename: "missing.png"},
+				}, 600*time.Second).Return(map[string]string{
+					"/images/cat.png": "https://s3/cat",
+					"/images/dog.png": "https://s3/dog",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedURLs: map[string]string{
+				"/images/cat.png": "https://s3/cat",
+				"/images/dog.png": "https://s3/dog",
+			},
+		},
+		{
+			name:   "default expire",
+			diskID: diskID.String(),
+			body:   `{"file_paths":["/a.txt"]}`,
+			setup: func(m *MockArtifactService) {
+				m.On("GetPresignedURLsByPaths", mock.Anything, mock.Anything, diskID, []model.ArtifactPath{{Path: "/", Filename: "a.txt"}}, time.Hour).
+					Return(map[string]string{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedURLs:   map[string]string{},
+		},
+		{
+			name:           "empty file paths",
+			diskID:         diskID.String(),
+			body:           `{"file_paths":[]}`,
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "directory instead of file",
+			diskID:         diskID.String(),
+			body:           `{"file_paths":["/images/"]}`,
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid disk ID",
+			diskID:         "invalid-uuid",
+			body:           `{"file_paths":["/a.txt"]}`,
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "service error",
+			diskID: diskID.String(),
+			body:   `{"file_paths":["/a.txt"]}`,
+			setup: func(m *MockArtifactService) {
+				m.On("GetPresignedURLsByPaths", mock.Anything, mock.Anything, diskID, mock.Anything, time.Hour).Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/presign-batch", handler.PresignArtifactsBatch)
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+tt.diskID+"/artifact/presign-batch", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedURLs != nil {
+				var response struct {
+					Data PresignArtifactsBatchResp `json:"data"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedURLs, response.Data.URLs)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_ArtifactTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+	tagged := &model.Artifact{DiskID: diskID, Path: "/invoices/", Filename: "march.pdf", Tags: datatypes.JSONSlice[string]{"invoice", "2024"}}
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name:   "add tags",
+			method: http.MethodPost,
+			url:    "/disk/" + diskID.String() + "/artifact/tags",
+			body:   `{"file_path":"/invoices/march.pdf","tags":["invoice","2024"]}`,
+			setup: func(m *MockArtifactService) {
+				m.On("AddTags", mock.Anything, diskID, "/invoices/", "march.pdf", []string{"invoice", "2024"}).Return(tagged, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "add without tags",
+			method:         http.MethodPost,
+			url:            "/disk/" + diskID.String() + "/artifact/tags",
+			body:           `{"file_path":"/invoices/march.pdf","tags":[]}`,
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "add invalid tag",
+			method: http.MethodPost,
+			url:    "/disk/" + diskID.String() + "/artifact/tags",
+			body:   `{"file_path":"/invoices/march.pdf","tags":[" "]}`,
+			setup: func(m *MockArtifactService) {
+				m.On("AddTags", mock.Anything, diskID, "/invoices/", "march.pdf", []string{" "}).Return(nil, service.ErrInvalidArtifactTags)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "add to missing artifact",
+			method: http.MethodPost,
+			url:    "/disk/" + diskID.String() + "/artifact/tags",
+			body:   `{"file_path":"/missing.pdf","tags":["invoice"]}`,
+			setup: func(m *MockArtifactService) {
+				m.On("AddTags", mock.Anything, diskID, "/", "missing.pdf", []string{"invoice"}).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "remove tags",
+			method: http.MethodDelete,
+			url:    "/disk/" + diskID.String() + "/artifact/tags?file_path=/invoices/march.pdf&tags=2024&tags=draft",
+			setup: func(m *MockArtifactService) {
+				m.On("RemoveTags", mock.Anything, diskID, "/invoices/", "march.pdf", []string{"2024", "draft"}).Return(tagged, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "list by tag",
+			method: http.MethodGet,
+			url:    "/disk/" + diskID.String() + "/artifact/tags?tag=invoice",
+			setup: func(m *MockArtifactService) {
+				m.On("ListByTag", mock.Anything, diskID, "invoice").Return([]*model.Artifact{tagged}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "list without tag",
+			method:         http.MethodGet,
+			url:            "/disk/" + diskID.String() + "/artifact/tags",
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/tags", handler.ListArtifactsByTag)
+			router.POST("/disk/:disk_id/artifact/tags", handler.AddArtifactTags)
+			router.DELETE("/disk/:disk_id/artifact/tags", handler.RemoveArtifactTags)
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"tags":["invoice","2024"]`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_ListArtifacts_NormalizesPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+
+	for _, query := range []string{"/docs", "docs/", "/docs//", "//docs/"} {
+		t.Run(query, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			mockService.On("ListByPath", mock.Anything, diskID, "/docs/", "").Return([]*model.Artifact{}, nil)
+			mockService.On("GetAllPaths", mock.Anything, diskID).Return([]string{"/docs/", "/docs/2024/"}, nil)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/ls", handler.ListArtifacts)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/ls?path="+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `"directories":["2024"]`)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_ListArtifacts_MIMEPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+	image := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/", Filename: "a.png"}
+
+	mockService := new(MockArtifactService)
+	mockService.On("ListByPath", mock.Anything, diskID, "/", "image/").Return([]*model.Artifact{image}, nil)
+	mockService.On("GetAllPaths", mock.Anything, diskID).Return([]string{"/"}, nil)
+	handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+	router := gin.New()
+	router.Use(withProject(testProjectID))
+	router.GET("/disk/:disk_id/artifact/ls", handler.ListArtifacts)
+
+	req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/ls?mime_prefix=image/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"filename":"a.png"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestArtifactHandler_ListAllArtifacts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+	artifacts := []*model.Artifact{
+		{ID: uuid.New(), DiskID: diskID, Path: "/", Filename: "readme.md"},
+		{ID: uuid.New(), DiskID: diskID, Path: "/docs/", Filename: "a.pdf"},
+		{ID: uuid.New(), DiskID: diskID, Path: "/docs/2024/", Filename: "b.pdf"},
+		{ID: uuid.New(), DiskID: diskID, Path: "/images/", Filename: "c.png"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:  "artifacts of every path",
+			query: "",
+			setup: func(m *MockArtifactService) {
+				m.On("ListByDisk", mock.Anything, service.ListArtifactsByDiskInput{DiskID: diskID, Limit: 100}).
+					Return(&service.ListArtifactsByDiskOutput{Items: artifacts}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				for _, a := range artifacts {
+					assert.Contains(t, body, `"path":"`+a.Path+`","filename":"`+a.Filename+`"`)
+				}
+				assert.Contains(t, body, `"has_more":false`)
+			},
+		},
+		{
+			name:  "next page",
+			query: "?limit=2&cursor=abc&time_desc=true",
+			setup: func(m *MockArtifactService) {
+				m.On("ListByDisk", mock.Anything, service.ListArtifactsByDiskInput{DiskID: diskID, Limit: 2, Cursor: "abc", TimeDesc: true}).
+					Return(&service.ListArtifactsByDiskOutput{Items: artifacts[2:], NextCursor: "def", HasMore: true}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"next_cursor":"def"`)
+				assert.Contains(t, body, `"has_more":true`)
+			},
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=1001",
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/all", handler.ListAllArtifacts)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/all"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_DownloadDiskArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+
+	tests := []struct {
+		name           string
+		writeErr       error
+		written        string
+		expectedStatus int
+		expectedType   string
+	}{
+		{
+			name:           "archive streamed",
+			written:        "PK\x03\x04",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/zip",
+		},
+		{
+			name:           "error before the first byte",
+			writeErr:       errors.New("db down"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "application/json; charset=utf-8",
+		},
+		{
+			name:           "error while streaming",
+			writeErr:       errors.New("s3 down"),
+			written:        "PK\x03\x04",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/zip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			mockService.On("WriteArchive", mock.Anything, mock.Anything, diskID, mock.Anything).
+				Run(func(args mock.Arguments) {
+					if tt.written != "" {
+						_, _ = io.WriteString(args.Get(3).(io.Writer), tt.written)
+					}
+				}).
+				Return(tt.writeErr)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/archive", handler.DownloadDiskArchive)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/archive", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedType, w.Header().Get("Content-Type"))
+			if tt.written != "" {
+				assert.Equal(t, tt.written, w.Body.String())
+				assert.Equal(t, `attachment; filename="disk-`+diskID.String()+`.zip"`, w.Header().Get("Content-Disposition"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_ImportDiskArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	diskID := uuid.New()
+
+	tests := []struct {
+		name           string
+		mode           string
+		withFile       bool
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:     "import summary",
+			mode:     "overwrite",
+			withFile: true,
+			setup: func(m *MockArtifactService) {
+				m.On("ImportArchive", mock.Anything, mock.MatchedBy(func(in service.ImportArchiveInput) bool {
+					return in.ProjectID == testProjectID && in.DiskID == diskID && in.Mode == service.ArchiveImportOverwrite && in.Size == int64(len("PK-archive"))
+				})).Return(&service.ImportArchiveOutput{
+					Created: 2,
+					Skipped: 1,
+					Failed:  []service.ArchiveImportFailure{{Name: "../evil.txt", Error: "invalid path"}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"created":2`)
+				assert.Contains(t, body, `"skipped":1`)
+				assert.Contains(t, body, `"failed":[{"name":"../evil.txt","error":"invalid path"}]`)
+			},
+		},
+		{
+			name:     "mode defaults to skip",
+			withFile: true,
+			setup: func(m *MockArtifactService) {
+				m.On("ImportArchive", mock.Anything, mock.MatchedBy(func(in service.ImportArchiveInput) bool {
+					return in.Mode == service.ArchiveImportSkip
+				})).Return(&service.ImportArchiveOutput{Failed: []service.ArchiveImportFailure{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid mode",
+			mode:           "merge",
+			withFile:       true,
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing file",
+			setup:          func(m *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "not a zip",
+			withFile: true,
+			setup: func(m *MockArtifactService) {
+				m.On("ImportArchive", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("%w: not a valid zip file", service.ErrInvalidArchive))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/archive", handler.ImportDiskArchive)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			if tt.mode != "" {
+				_ = writer.WriteField("mode", tt.mode)
+			}
+			if tt.withFile {
+				part, _ := writer.CreateFormFile("file", "disk.zip")
+				_, _ = part.Write([]byte("PK-archive"))
+			}
+			_ = writer.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+diskID.String()+"/artifact/archive", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_VerifyArtifact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	artifact := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/test/", Filename: "data.csv"}
+
+	tests := []struct {
+		name           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "checksum mismatch",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("VerifyChecksum", mock.Anything, mock.Anything, artifact).Return(&service.ChecksumResult{Valid: false, Expected: "aa", Actual: "bb"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"valid":false`,
+		},
+		{
+			name: "artifact not found",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "too many verifications",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("VerifyChecksum", mock.Anything, mock.Anything, artifact).Return(nil, service.ErrChecksumVerifyBusy)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/verify", handler.VerifyArtifact)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/verify?file_path=/test/data.csv", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_GetArtifactS3Meta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	artifact := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/test/", Filename: "data.csv"}
+
+	tests := []struct {
+		name           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "drifted object",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("GetObjectMeta", mock.Anything, mock.Anything, artifact).Return(&service.ArtifactObjectMeta{
+					Object:     &blob.ObjectMeta{Key: "disks/data.csv", SizeB: 10},
+					Mismatches: []service.ObjectMetaMismatch{{Field: "size_b", Recorded: "12", Stored: "10"}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"in_sync":false`,
+		},
+		{
+			name: "artifact not found",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "object missing from storage",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("GetObjectMeta", mock.Anything, mock.Anything, artifact).Return(nil, service.ErrArtifactObjectMissing)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "missing from storage",
+		},
+		{
+			name: "storage error",
+			setup: func(svc *MockArtifactService) {
+				svc.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+				svc.On("GetObjectMeta", mock.Anything, mock.Anything, artifact).Return(nil, errors.New("s3 unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact/s3-meta", handler.GetArtifactS3Meta)
+
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact/s3-meta?file_path=/test/data.csv", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_GetArtifact_ContentSizeLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	newArtifact := func(size int64) *model.Artifact {
+		return &model.Artifact{
+			ID:        uuid.New(),
+			DiskID:    diskID,
+			Path:      "/test/",
+			Filename:  "data.csv",
+			AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/data.csv", MIME: "text/csv", SizeB: size}),
+		}
+	}
+
+	t.Run("over the limit returns a presigned URL instead of content", func(t *testing.T) {
+		artifact := newArtifact(2048)
+		mockService := new(MockArtifactService)
+		mockService.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+		mockService.On("GetPresignedURL", mock.Anything, mock.Anything, artifact, time.Hour).Return("https://s3/data.csv", nil)
+		handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, 1024, false)
+
+		router := gin.New()
+		router.Use(withProject(testProjectID))
+		router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+
+		req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact?file_path=/test/data.csv&with_public_url=false&with_content=true", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data GetArtifactResp `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.ContentTruncated)
+		assert.Nil(t, resp.Data.Content)
+		require.NotNil(t, resp.Data.PublicURL)
+		assert.Equal(t, "https://s3/data.csv", *resp.Data.PublicURL)
+		mockService.AssertExpectations(t)
+		mockService.AssertNotCalled(t, "GetFileContent", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("within the limit inlines content", func(t *testing.T) {
+		artifact := newArtifact(512)
+		mockService := new(MockArtifactService)
+		mockService.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+		mockService.On("GetFileContent", mock.Anything, mock.Anything, artifact).Return(&fileparser.FileContent{Type: "csv", Raw: "a,b"}, nil)
+		handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, 1024, false)
+
+		router := gin.New()
+		router.Use(withProject(testProjectID))
+		router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+
+		req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact?file_path=/test/data.csv&with_public_url=false&with_content=true", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "content_truncated")
+		assert.Contains(t, w.Body.String(), `"raw":"a,b"`)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestArtifactHandler_GetArtifact_Download(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	artifact := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/test/",
+		Filename:  "data.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/2024/01/01/abc.csv", MIME: "text/csv", SizeB: 512}),
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		filename string
+	}{
+		{name: "artifact filename", query: "&download=true", filename: ""},
+		{name: "custom filename", query: "&download=true&filename=export.csv", filename: "export.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			mockService.On("GetByFullPath", mock.Anything, diskID, "/test/data.csv").Return(artifact, nil)
+			mockService.On("GetDownloadURL", mock.Anything, testProjectID, artifact, tt.filename, time.Hour).Return("https://s3/abc.csv?response-content-disposition=attachment", nil)
+			handler := NewArtifactHandler(mockService, projectDisks{projectID: testProjectID}, 1024, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+
+			// download presigns the URL even when the public URL is not requested
+			req := httptest.NewRequest(http.MethodGet, "/disk/"+diskID.String()+"/artifact?file_path=/test/data.csv&with_public_url=false&with_content=false"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "response-content-disposition=attachment")
+			mockService.AssertExpectations(t)
+			mockService.AssertNotCalled(t, "GetPresignedURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestArtifactHandler_DiskOfAnotherProject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	foreignDiskID := uuid.New()
+	missingDiskID := uuid.New()
+	diskService := new(MockDiskService)
+	diskService.On("GetByID", mock.Anything, foreignDiskID).Return(&model.Disk{ID: foreignDiskID, ProjectID: uuid.New()}, nil)
+	diskService.On("GetByID", mock.Anything, missingDiskID).Return(nil, gorm.ErrRecordNotFound)
+
+	// The artifact service has no expectations: any call fails the test
+	handler := NewArtifactHandler(new(MockArtifactService), diskService, defaultMaxInlineContentSizeB, false)
+	router := gin.New()
+	router.Use(withProject(testProjectID))
+	router.POST("/disk/:disk_id/artifact", handler.UpsertArtifact)
+	router.GET("/disk/:disk_id/artifact", handler.GetArtifact)
+	router.PUT("/disk/:disk_id/artifact", handler.UpdateArtifact)
+	router.DELETE("/disk/:disk_id/artifact", handler.DeleteArtifact)
+	router.POST("/disk/:disk_id/artifact/copy", handler.CopyArtifact)
+	router.POST("/disk/:disk_id/artifact/rename", handler.RenameArtifact)
+	router.POST("/disk/:disk_id/artifact/from-url", handler.CreateArtifactFromURL)
+	router.GET("/disk/:disk_id/artifact/ls", handler.ListArtifacts)
+	router.GET("/disk/:disk_id/artifact/all", handler.ListAllArtifacts)
+	router.GET("/disk/:disk_id/artifact/archive", handler.DownloadDiskArchive)
+	router.POST("/disk/:disk_id/artifact/archive", handler.ImportDiskArchive)
+	router.GET("/disk/:disk_id/artifact/dirs", handler.ListArtifactDirs)
+	router.GET("/disk/:disk_id/artifact/meta", handler.GetArtifactMeta)
+	router.GET("/disk/:disk_id/artifact/verify", handler.VerifyArtifact)
+	router.GET("/disk/:disk_id/artifact/stats", handler.GetArtifactStats)
+	router.POST("/disk/:disk_id/artifact/presign-batch", handler.PresignArtifactsBatch)
+	router.GET("/disk/:disk_id/artifact/tags", handler.ListArtifactsByTag)
+	router.POST("/disk/:disk_id/artifact/tags", handler.AddArtifactTags)
+	router.DELETE("/disk/:disk_id/artifact/tags", handler.RemoveArtifactTags)
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, ""},
+		{http.MethodGet, ""},
+		{http.MethodPut, ""},
+		{http.MethodDelete, ""},
+		{http.MethodPost, "/copy"},
+		{http.MethodPost, "/rename"},
+		{http.MethodPost, "/from-url"},
+		{http.MethodGet, "/ls"},
+		{http.MethodGet, "/all"},
+		{http.MethodGet, "/archive"},
+		{http.MethodPost, "/archive"},
+		{http.MethodGet, "/dirs"},
+		{http.MethodGet, "/meta"},
+		{http.MethodGet, "/verify"},
+		{http.MethodGet, "/stats"},
+		{http.MethodPost, "/presign-batch"},
+		{http.MethodGet, "/tags"},
+		{http.MethodPost, "/tags"},
+		{http.MethodDelete, "/tags"},
+	}
+	body := `{"file_path":"/docs/a.txt","dst_file_path":"/docs/b.txt","new_filename":"b.txt","url":"https://example.com/a.txt","meta":"{}","tags":["invoice"],"file_paths":["/docs/a.txt"]}`
+
+	for _, diskID := range []uuid.UUID{foreignDiskID, missingDiskID} {
+		for _, route := range routes {
+			t.Run(route.method+" "+route.path, func(t *testing.T) {
+				url := "/disk/" + diskID.String() + "/artifact" + route.path + "?file_path=/docs/a.txt&tag=invoice"
+				req := httptest.NewRequest(route.method, url, bytes.NewBufferString(body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusNotFound, w.Code)
+				assert.Contains(t, w.Body.String(), "disk not found")
+			})
+		}
+	}
+}
+
+func TestArtifactHandler_CopyArtifact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	dstDiskID := uuid.New()
+	foreignDiskID := uuid.New()
+	copied := &model.Artifact{ID: uuid.New(), DiskID: dstDiskID, Path: "/archive/", Filename: "report.pdf"}
+	existing := &model.Artifact{ID: uuid.New(), DiskID: dstDiskID, Path: "/archive/", Filename: "report.pdf", Meta: map[string]interface{}{"version": "v1"}}
+	in := service.CopyArtifactInput{
+		ProjectID:   testProjectID,
+		SrcDiskID:   diskID,
+		SrcPath:     "/docs/",
+		SrcFilename: "report.pdf",
+		DstDiskID:   dstDiskID,
+		DstPath:     "/archive/",
+		DstFilename: "report.pdf",
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedMsg    string
+		checkBody      func(*testing.T, []byte)
+	}{
+		{
+			name: "to another disk",
+			body: `{"file_path":"/docs/report.pdf","dst_disk_id":"` + dstDiskID.String() + `","dst_file_path":"/archive/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CopyArtifact", mock.Anything, in).Return(copied, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "within the disk",
+			body: `{"file_path":"/docs/report.pdf","dst_file_path":"/docs/copy.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				same := in
+				same.DstDiskID, same.DstPath, same.DstFilename = diskID, "/docs/", "copy.pdf"
+				svc.On("CopyArtifact", mock.Anything, same).Return(copied, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "destination disk of another project",
+			body:           `{"file_path":"/docs/report.pdf","dst_disk_id":"` + foreignDiskID.String() + `","dst_file_path":"/archive/report.pdf"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "destination disk not found",
+		},
+		{
+			name:           "invalid destination disk",
+			body:           `{"file_path":"/docs/report.pdf","dst_disk_id":"invalid","dst_file_path":"/archive/report.pdf"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "destination without filename",
+			body:           `{"file_path":"/docs/report.pdf","dst_file_path":"/archive/"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "source not found",
+			body: `{"file_path":"/docs/report.pdf","dst_disk_id":"` + dstDiskID.String() + `","dst_file_path":"/archive/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CopyArtifact", mock.Anything, in).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "artifact not found",
+		},
+		{
+			name: "destination exists",
+			body: `{"file_path":"/docs/report.pdf","dst_disk_id":"` + dstDiskID.String() + `","dst_file_path":"/archive/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CopyArtifact", mock.Anything, in).Return(nil, &service.ErrArtifactExists{Artifact: existing})
+			},
+			expectedStatus: http.StatusConflict,
+			expectedMsg:    "destination artifact already exists",
+			checkBody: func(t *testing.T, body []byte) {
+				var resp struct {
+					Data ArtifactConflictResp `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(body, &resp))
+				assert.Equal(t, existing.ID, resp.Data.ID)
+				require.NotNil(t, resp.Data.Artifact)
+				assert.Equal(t, "/archive/", resp.Data.Artifact.Path)
+				assert.Equal(t, "report.pdf", resp.Data.Artifact.Filename)
+				assert.Equal(t, "v1", resp.Data.Artifact.Meta["version"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			diskService := new(MockDiskService)
+			diskService.On("GetByID", mock.Anything, diskID).Return(&model.Disk{ID: diskID, ProjectID: testProjectID}, nil)
+			diskService.On("GetByID", mock.Anything, dstDiskID).Return(&model.Disk{ID: dstDiskID, ProjectID: testProjectID}, nil)
+			diskService.On("GetByID", mock.Anything, foreignDiskID).Return(&model.Disk{ID: foreignDiskID, ProjectID: uuid.New()}, nil)
+			handler := NewArtifactHandler(mockService, diskService, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/copy", handler.CopyArtifact)
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+diskID.String()+"/artifact/copy", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMsg != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedMsg)
+			}
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.Bytes())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_RenameArtifact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	renamed := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/docs/", Filename: "final.pdf"}
+	existing := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/docs/", Filename: "final.pdf"}
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name: "renamed",
+			body: `{"file_path":"/docs/draft.pdf","new_filename":"final.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("RenameArtifact", mock.Anything, testProjectID, diskID, "/docs/", "draft.pdf", "final.pdf").Return(renamed, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedMsg:    `"filename":"final.pdf"`,
+		},
+		{
+			name:           "missing new filename",
+			body:           `{"file_path":"/docs/draft.pdf"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid new filename",
+			body: `{"file_path":"/docs/draft.pdf","new_filename":"other/final.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("RenameArtifact", mock.Anything, testProjectID, diskID, "/docs/", "draft.pdf", "other/final.pdf").Return(nil, service.ErrInvalidArtifactPath)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "invalid new_filename",
+		},
+		{
+			name: "not found",
+			body: `{"file_path":"/docs/draft.pdf","new_filename":"final.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("RenameArtifact", mock.Anything, testProjectID, diskID, "/docs/", "draft.pdf", "final.pdf").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "artifact not found",
+		},
+		{
+			name: "name taken",
+			body: `{"file_path":"/docs/draft.pdf","new_filename":"final.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("RenameArtifact", mock.Anything, testProjectID, diskID, "/docs/", "draft.pdf", "final.pdf").Return(nil, &service.ErrArtifactExists{Artifact: existing})
+			},
+			expectedStatus: http.StatusConflict,
+			expectedMsg:    existing.ID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			diskService := new(MockDiskService)
+			diskService.On("GetByID", mock.Anything, diskID).Return(&model.Disk{ID: diskID, ProjectID: testProjectID}, nil)
+			handler := NewArtifactHandler(mockService, diskService, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/rename", handler.RenameArtifact)
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+diskID.String()+"/artifact/rename", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMsg != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedMsg)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_MoveArtifact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	moved := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/archive/2024/", Filename: "report.pdf"}
+	existing := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/archive/2024/", Filename: "report.pdf"}
+	input := service.MoveArtifactInput{
+		ProjectID:   testProjectID,
+		DiskID:      diskID,
+		Path:        "/docs/",
+		Filename:    "report.pdf",
+		DstPath:     "/archive/2024/",
+		DstFilename: "report.pdf",
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name: "moved",
+			body: `{"file_path":"/docs/report.pdf","dst_file_path":"/archive/2024/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("MoveArtifact", mock.Anything, input).Return(moved, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedMsg:    `"path":"/archive/2024/"`,
+		},
+		{
+			name:           "missing destination",
+			body:           `{"file_path":"/docs/report.pdf"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "destination without filename",
+			body:           `{"file_path":"/docs/report.pdf","dst_file_path":"/archive/"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "invalid dst_file_path",
+		},
+		{
+			name: "not found",
+			body: `{"file_path":"/docs/report.pdf","dst_file_path":"/archive/2024/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("MoveArtifact", mock.Anything, input).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "artifact not found",
+		},
+		{
+			name: "destination taken",
+			body: `{"file_path":"/docs/report.pdf","dst_file_path":"/archive/2024/report.pdf"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("MoveArtifact", mock.Anything, input).Return(nil, &service.ErrArtifactExists{Artifact: existing})
+			},
+			expectedStatus: http.StatusConflict,
+			expectedMsg:    existing.ID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			diskService := new(MockDiskService)
+			diskService.On("GetByID", mock.Anything, diskID).Return(&model.Disk{ID: diskID, ProjectID: testProjectID}, nil)
+			handler := NewArtifactHandler(mockService, diskService, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/move", handler.MoveArtifact)
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+diskID.String()+"/artifact/move", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMsg != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedMsg)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_CreateArtifactFromURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	diskID := uuid.New()
+	created := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: "/docs/", Filename: "report.pdf"}
+	inputFor := func(url string) service.CreateFromURLInput {
+		return service.CreateFromURLInput{ProjectID: testProjectID, DiskID: diskID, Path: "/docs/", SourceURL: url}
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockArtifactService)
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name: "created",
+			body: `{"url":"https://example.com/report.pdf","file_path":"/docs/"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CreateFromURL", mock.Anything, inputFor("https://example.com/report.pdf")).Return(created, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedMsg:    `"filename":"report.pdf"`,
+		},
+		{
+			name:           "missing url",
+			body:           `{"file_path":"/docs/"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "reserved meta key",
+			body:           `{"url":"https://example.com/report.pdf","file_path":"/docs/","meta":"{\"__artifact_info__\":{}}"}`,
+			setup:          func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "internal address",
+			body: `{"url":"http://169.254.169.254/latest/meta-data","file_path":"/docs/"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CreateFromURL", mock.Anything, inputFor("http://169.254.169.254/latest/meta-data")).
+					Return(nil, fmt.Errorf("%w: 169.254.169.254 is not a public address", safehttp.ErrForbiddenURL))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "url not allowed",
+		},
+		{
+			name: "too large",
+			body: `{"url":"https://example.com/huge.bin","file_path":"/docs/"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CreateFromURL", mock.Anything, inputFor("https://example.com/huge.bin")).Return(nil, safehttp.ErrTooLarge)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "unreachable",
+			body: `{"url":"https://example.com/missing.pdf","file_path":"/docs/"}`,
+			setup: func(svc *MockArtifactService) {
+				svc.On("CreateFromURL", mock.Anything, inputFor("https://example.com/missing.pdf")).Return(nil, safehttp.ErrFetchFailed)
+			},
+			expectedStatus: http.StatusBadGateway,
+			expectedMsg:    "could not fetch url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockArtifactService)
+			tt.setup(mockService)
+			diskService := new(MockDiskService)
+			diskService.On("GetByID", mock.Anything, diskID).Return(&model.Disk{ID: diskID, ProjectID: testProjectID}, nil)
+			handler := NewArtifactHandler(mockService, diskService, defaultMaxInlineContentSizeB, false)
+
+			router := gin.New()
+			router.Use(withProject(testProjectID))
+			router.POST("/disk/:disk_id/artifact/from-url", handler.CreateArtifactFromURL)
+
+			req := httptest.NewRequest(http.MethodPost, "/disk/"+diskID.String()+"/artifact/from-url", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMsg != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedMsg)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+type AssetReferenceHandler struct {
+	svc service.AssetReferenceService
+}
+
+func NewAssetReferenceHandler(s service.AssetReferenceService) *AssetReferenceHandler {
+	return &AssetReferenceHandler{svc: s}
+}
+
+type SweepOrphanedAssetsReq struct {
+	DryRun bool `form:"dry_run,default=false" json:"dry_run" example:"true"`
+}
+
+type SweepOrphanedAssetsResp struct {
+	Deleted int  `json:"deleted"`
+	DryRun  bool `json:"dry_run"`
+}
+
+// SweepOrphanedAssets godoc
+//
+//	@Summary		Sweep orphaned assets
+//	@Description	Delete stored assets of the project that are no longer referenced by any message or artifact and have been orphaned for over 24 hours. With dry_run=true nothing is deleted and the number of assets that would be removed is returned.
+//	@Tags			project
+//	@Accept			json
+//	@Produce		json
+//	@Param			dry_run	query	boolean	false	"Only count the orphaned assets without deleting them"	example(true)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.SweepOrphanedAssetsResp}
+//	@Router			/project/asset/sweep-orphans [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Preview, then sweep orphaned assets\npreview = client.assets.sweep_orphans(dry_run=True)\nprint(preview.deleted)\nresult = client.assets.sweep_orphans()\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Preview, then sweep orphaned assets\nconst preview = await client.assets.sweepOrphans({ dryRun: true });\nconsole.log(preview.deleted);\nconst result = await client.assets.sweepOrphans();\n","label":"JavaScript"}]
+func (h *AssetReferenceHandler) SweepOrphanedAssets(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := SweepOrphanedAssetsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	deleted, err := h.svc.SweepOrphanedAssets(c.Request.Context(), project.ID, req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: SweepOrphanedAssetsResp{Deleted: deleted, DryRun: req.DryRun}})
+}
+
+type ListAssetReferencesReq struct {
+	Limit                int        `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
+	Cursor               string     `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
+	MinRefCount          int        `form:"min_ref_count" json:"min_ref_count" binding:"min=0" example:"2"`
+	OrphanedOnly         bool       `form:"orphaned_only,default=false" json:"orphaned_only" example:"false"`
+	LastReferencedBefore *time.Time `form:"last_referenced_before" json:"last_referenced_before" time_format:"2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+}
+
+// ListAssetReferences godoc
+//
+//	@Summary		List stored assets
+//	@Description	List the stored assets of the project with their reference counts, oldest first, for capacity planning and garbage collection decisions. Filter by a minimum reference count, orphaned assets only, or assets last referenced before a time. total_count and total_size_b cover every asset matching the filters, not only the returned page.
+//	@Tags			project
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit					query	integer	false	"Limit of assets to return, default 20. Max 200."
+//	@Param			cursor					query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
+//	@Param			min_ref_count			query	integer	false	"Only assets with at least this many references"			example(2)
+//	@Param			orphaned_only			query	boolean	false	"Only assets no longer referenced"							example(false)
+//	@Param			last_referenced_before	query	string	false	"Only assets last referenced before this RFC3339 time"	example(2025-01-01T00:00:00Z)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ListAssetReferencesOutput}
+//	@Router			/project/asset [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List orphaned assets and the space they take\nresult = client.assets.list(orphaned_only=True, limit=50)\nprint(f\"{result.total_count} orphaned assets, {result.total_size_b} bytes\")\nfor asset in result.items:\n    print(asset.s3_key, asset.ref_count)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List orphaned assets and the space they take\nconst result = await client.assets.list({ orphanedOnly: true, limit: 50 });\nconsole.log(`${result.total_count} orphaned assets, ${result.total_size_b} bytes`);\nfor (const asset of result.items) {\n  console.log(asset.s3_key, asset.ref_count);\n}\n","label":"JavaScript"}]
+func (h *AssetReferenceHandler) ListAssetReferences(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := ListAssetReferencesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	in := service.ListAssetReferencesInput{
+		ProjectID:    project.ID,
+		MinRefCount:  req.MinRefCount,
+		OrphanedOnly: req.OrphanedOnly,
+		Limit:        req.Limit,
+		Cursor:       req.Cursor,
+	}
+	if req.LastReferencedBefore != nil {
+		in.LastReferencedBefore = *req.LastReferencedBefore
+	}
+
+	out, err := h.svc.ListAssetReferences(c.Request.Context(), in)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
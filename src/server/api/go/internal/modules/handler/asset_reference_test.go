@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAssetReferenceService is a mock implementation of AssetReferenceService
+type MockAssetReferenceService struct {
+	mock.Mock
+}
+
+func (m *MockAssetReferenceService) SweepOrphanedAssets(ctx context.Context, projectID uuid.UUID, dryRun bool) (int, error) {
+	args := m.Called(ctx, projectID, dryRun)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAssetReferenceService) ListAssetReferences(ctx context.Context, in service.ListAssetReferencesInput) (*service.ListAssetReferencesOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ListAssetReferencesOutput), args.Error(1)
+}
+
+func TestAssetReferenceHandler_SweepOrphanedAssets(t *testing.T) {
+	projectID := uuid.New()
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		setup          func(*MockAssetReferenceService)
+		expectedStatus int
+	}{
+		{
+			name:        "dry run",
+			queryParams: "?dry_run=true",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("SweepOrphanedAssets", mock.Anything, projectID, true).Return(2, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "sweep",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("SweepOrphanedAssets", mock.Anything, projectID, false).Return(2, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid dry_run",
+			queryParams:    "?dry_run=maybe",
+			setup:          func(svc *MockAssetReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("SweepOrphanedAssets", mock.Anything, projectID, false).Return(0, errors.New("s3 unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockAssetReferenceService{}
+			tt.setup(mockService)
+			handler := NewAssetReferenceHandler(mockService)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/project/asset/sweep-orphans", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.SweepOrphanedAssets(c)
+			})
+
+			req := httptest.NewRequest("POST", "/project/asset/sweep-orphans"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAssetReferenceHandler_ListAssetReferences(t *testing.T) {
+	projectID := uuid.New()
+	out := &service.ListAssetReferencesOutput{
+		Items:      []model.AssetReference{{ID: uuid.New(), SHA256: "a", RefCount: 2}},
+		TotalCount: 1,
+		TotalSizeB: 1024,
+	}
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		setup          func(*MockAssetReferenceService)
+		expectedStatus int
+	}{
+		{
+			name: "default listing",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("ListAssetReferences", mock.Anything, service.ListAssetReferencesInput{ProjectID: projectID, Limit: 20}).Return(out, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "filters",
+			queryParams: "?min_ref_count=2&orphaned_only=true&last_referenced_before=2025-01-01T00:00:00Z&limit=50&cursor=abc",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("ListAssetReferences", mock.Anything, service.ListAssetReferencesInput{
+					ProjectID:            projectID,
+					MinRefCount:          2,
+					OrphanedOnly:         true,
+					LastReferencedBefore: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Limit:                50,
+					Cursor:               "abc",
+				}).Return(out, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid time",
+			queryParams:    "?last_referenced_before=yesterday",
+			setup:          func(svc *MockAssetReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative min ref count",
+			queryParams:    "?min_ref_count=-1",
+			setup:          func(svc *MockAssetReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockAssetReferenceService) {
+				svc.On("ListAssetReferences", mock.Anything, mock.Anything).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockAssetReferenceService{}
+			tt.setup(mockService)
+			handler := NewAssetReferenceHandler(mockService)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/project/asset", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.ListAssetReferences(c)
+			})
+
+			req := httptest.NewRequest("GET", "/project/asset"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
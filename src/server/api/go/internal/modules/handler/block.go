@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +13,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type BlockHandler struct {
@@ -36,12 +38,13 @@ type CreateBlockReq struct {
 // CreateBlock godoc
 //
 //	@Summary		Create block
-//	@Description	Create a new block (supports all types: page, folder, text, sop, etc.). For page and folder types, parent_id is optional. For other types, parent_id is required.
+//	@Description	Create a new block (supports all types: page, folder, text, sop, etc.). For page and folder types, parent_id is optional. For other types, parent_id is required. Props are validated against the JSON Schema of the block type, for example sop props need a string preferences; a violation returns 400. So does a block nested deeper than the maximum depth of the server, or under a parent that already has the maximum number of children.
 //	@Tags			block
 //	@Accept			json
 //	@Produce		json
-//	@Param			space_id	path	string					true	"Space ID"	Format(uuid)
-//	@Param			payload		body	handler.CreateBlockReq	true	"CreateBlock payload"
+//	@Param			space_id		path	string					true	"Space ID"	Format(uuid)
+//	@Param			payload			body	handler.CreateBlockReq	true	"CreateBlock payload"
+//	@Param			Idempotency-Key	header	string					false	"Retrying with the same key returns the original response instead of creating another block"
 //	@Security		BearerAuth
 //	@Success		201	{object}	serializer.Response{data=httpclient.InsertBlockResponse}
 //	@Router			/space/{space_id}/block [post]
@@ -76,6 +79,16 @@ func (h *BlockHandler) CreateBlock(c *gin.Context) {
 		return
 	}
 
+	if _, err := model.ParseArtifactRefs(req.Props); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		return
+	}
+
+	if err := model.ValidateBlockProps(req.Type, req.Props); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		return
+	}
+
 	// Pre-validation before calling Core service
 	// 1. Create a temporary block for validation
 	tempBlock := &model.Block{
@@ -93,7 +106,7 @@ func (h *BlockHandler) CreateBlock(c *gin.Context) {
 
 	// 3. If parent_id is provided, validate parent-child relationship
 	if req.ParentID != nil {
-		parent, err := h.svc.GetBlockProperties(c.Request.Context(), *req.ParentID)
+		parent, err := h.svc.GetBlockProperties(c.Request.Context(), spaceID, *req.ParentID)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("parent block not found")))
 			return
@@ -112,6 +125,16 @@ func (h *BlockHandler) CreateBlock(c *gin.Context) {
 		}
 	}
 
+	// 4. Keep the tree within the configured depth and breadth
+	if err := h.svc.CheckTreeLimits(c.Request.Context(), spaceID, req.ParentID); err != nil {
+		if errors.Is(err, service.ErrBlockTreeLimit) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
 	// Prepare request for Core service
 	coreReq := httpclient.InsertBlockRequest{
 		ParentID: req.ParentID,
@@ -167,7 +190,7 @@ func (h *BlockHandler) DeleteBlock(c *gin.Context) {
 // GetBlockProperties godoc
 //
 //	@Summary		Get block properties
-//	@Description	Get a block's properties by its ID (works for all block types: page, folder, text, sop, etc.)
+//	@Description	Get a block's properties by its ID (works for all block types: page, folder, text, sop, etc.). The response carries ETag and Last-Modified headers; conditional requests with If-None-Match or If-Modified-Since get 304 Not Modified when the block is unchanged.
 //	@Tags			block
 //	@Accept			json
 //	@Produce		json
@@ -175,24 +198,108 @@ func (h *BlockHandler) DeleteBlock(c *gin.Context) {
 //	@Param			block_id	path	string	true	"Block ID"	Format(uuid)
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=model.Block}
+//	@Success		304	"Not Modified"
+//	@Failure		404	{object}	serializer.Response
 //	@Router			/space/{space_id}/block/{block_id}/properties [get]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get block properties\nblock = client.blocks.get_properties(\n    space_id='space-uuid',\n    block_id='block-uuid'\n)\nprint(f\"{block.title}: {block.props}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get block properties\nconst block = await client.blocks.getProperties('space-uuid', 'block-uuid');\nconsole.log(`${block.title}: ${JSON.stringify(block.props)}`);\n","label":"JavaScript"}]
 func (h *BlockHandler) GetBlockProperties(c *gin.Context) {
+	h.getBlock(c)
+}
+
+// GetBlock godoc
+//
+//	@Summary		Get block
+//	@Description	Get a block by its ID (works for all block types: page, folder, text, sop, etc.). Same as GET /space/{space_id}/block/{block_id}/properties, which is kept for compatibility. The response carries ETag and Last-Modified headers; conditional requests with If-None-Match or If-Modified-Since get 304 Not Modified when the block is unchanged.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string	true	"Block ID"	Format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Block}
+//	@Success		304	"Not Modified"
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id} [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get a block\nblock = client.blocks.get(\n    space_id='space-uuid',\n    block_id='block-uuid'\n)\nprint(f\"{block.title}: {block.props}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get a block\nconst block = await client.blocks.get('space-uuid', 'block-uuid');\nconsole.log(`${block.title}: ${JSON.stringify(block.props)}`);\n","label":"JavaScript"}]
+func (h *BlockHandler) GetBlock(c *gin.Context) {
+	h.getBlock(c)
+}
+
+// getBlock writes the block of the path, 404 when it is not in the space of the path
+func (h *BlockHandler) getBlock(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
 	blockID, err := uuid.Parse(c.Param("block_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
 		return
 	}
 
-	b, err := h.svc.GetBlockProperties(c.Request.Context(), blockID)
+	b, err := h.svc.GetBlockProperties(c.Request.Context(), spaceID, blockID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
+	if notModified(c, blockETag(b), b.UpdatedAt) {
+		return
+	}
+
 	c.JSON(http.StatusOK, serializer.Response{Data: b})
 }
 
+type GetBlocksBatchReq struct {
+	BlockIDs []uuid.UUID `json:"block_ids" binding:"required,min=1,max=100"`
+}
+
+type GetBlocksBatchResp struct {
+	Blocks  []model.Block `json:"blocks"`
+	Missing []uuid.UUID   `json:"missing"`
+}
+
+// GetBlocksBatch godoc
+//
+//	@Summary		Get blocks in batch
+//	@Description	Get up to 100 blocks of a space by their IDs in one call. The blocks are returned in the order of block_ids, each once; IDs without a block in the space are listed in missing.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string						true	"Space ID"	Format(uuid)
+//	@Param			payload		body	handler.GetBlocksBatchReq	true	"GetBlocksBatch payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.GetBlocksBatchResp}
+//	@Router			/space/{space_id}/block/batch-get [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get several blocks at once\nresult = client.blocks.batch_get(\n    space_id='space-uuid',\n    block_ids=['block-uuid-1', 'block-uuid-2']\n)\nfor block in result.blocks:\n    print(f\"{block.title}: {block.props}\")\nprint(f\"Missing: {result.missing}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get several blocks at once\nconst result = await client.blocks.batchGet('space-uuid', ['block-uuid-1', 'block-uuid-2']);\nfor (const block of result.blocks) {\n  console.log(`${block.title}: ${JSON.stringify(block.props)}`);\n}\nconsole.log(`Missing: ${result.missing}`);\n","label":"JavaScript"}]
+func (h *BlockHandler) GetBlocksBatch(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := GetBlocksBatchReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blocks, missing, err := h.svc.GetMany(c.Request.Context(), spaceID, req.BlockIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: GetBlocksBatchResp{Blocks: blocks, Missing: missing}})
+}
+
 type UpdateBlockPropertiesReq struct {
 	Title string         `form:"title" json:"title"`
 	Props map[string]any `form:"props" json:"props"`
@@ -201,7 +308,7 @@ type UpdateBlockPropertiesReq struct {
 // UpdateBlockProperties godoc
 //
 //	@Summary		Update block properties
-//	@Description	Update a block's title and properties by its ID (works for all block types: page, folder, text, sop, etc.)
+//	@Description	Update a block's title and properties by its ID (works for all block types: page, folder, text, sop, etc.). Props are validated against the schema of the block type, like in CreateBlock.
 //	@Tags			block
 //	@Accept			json
 //	@Produce		json
@@ -210,9 +317,17 @@ type UpdateBlockPropertiesReq struct {
 //	@Param			payload		body	handler.UpdateBlockPropertiesReq	true	"UpdateBlockProperties payload"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
 //	@Router			/space/{space_id}/block/{block_id}/properties [put]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update block properties\nclient.blocks.update_properties(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    title='Updated Title',\n    props={\"text\": \"Updated content\"}\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update block properties\nawait client.blocks.updateProperties('space-uuid', 'block-uuid', {\n  title: 'Updated Title',\n  props: { text: 'Updated content' }\n});\n","label":"JavaScript"}]
 func (h *BlockHandler) UpdateBlockProperties(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
 	blockID, err := uuid.Parse(c.Param("block_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
@@ -230,12 +345,25 @@ func (h *BlockHandler) UpdateBlockProperties(c *gin.Context) {
 		return
 	}
 
+	if _, err := model.ParseArtifactRefs(req.Props); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		return
+	}
+
 	b := model.Block{
 		ID:    blockID,
 		Title: req.Title,
 		Props: datatypes.NewJSONType(req.Props),
 	}
-	if err := h.svc.UpdateBlockProperties(c.Request.Context(), &b); err != nil {
+	if err := h.svc.UpdateBlockProperties(c.Request.Context(), spaceID, &b); err != nil {
+		if errors.Is(err, model.ErrInvalidBlockProps) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
@@ -243,6 +371,171 @@ func (h *BlockHandler) UpdateBlockProperties(c *gin.Context) {
 	c.JSON(http.StatusOK, serializer.Response{})
 }
 
+type PatchBlockPropertiesReq struct {
+	Props map[string]any `form:"props" json:"props" binding:"required"`
+}
+
+// PatchBlockProperties godoc
+//
+//	@Summary		Patch block properties
+//	@Description	Merge the given keys into a block's properties, keeping untouched keys. Set a key to null to delete it. The merged properties must satisfy the schema of the block type.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string							true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string							true	"Block ID"	Format(uuid)
+//	@Param			payload		body	handler.PatchBlockPropertiesReq	true	"PatchBlockProperties payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Block}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/properties [patch]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Set one prop and delete another, keeping the rest\nblock = client.blocks.patch_properties(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    props={\"text\": \"Updated content\", \"obsolete\": None}\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Set one prop and delete another, keeping the rest\nconst block = await client.blocks.patchProperties('space-uuid', 'block-uuid', {\n  props: { text: 'Updated content', obsolete: null }\n});\n","label":"JavaScript"}]
+func (h *BlockHandler) PatchBlockProperties(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := PatchBlockPropertiesReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if _, err := model.ParseArtifactRefs(req.Props); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+		return
+	}
+
+	b, err := h.svc.PatchBlockProperties(c.Request.Context(), spaceID, blockID, req.Props)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidBlockProps) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("props", err))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: b})
+}
+
+type BulkUpdateBlockItem struct {
+	BlockID uuid.UUID      `json:"block_id" binding:"required" format:"uuid"`
+	Title   *string        `json:"title"`
+	Props   map[string]any `json:"props"` // Replaces the props of the block
+}
+
+type BulkUpdateBlocksReq struct {
+	Blocks     []BulkUpdateBlockItem `json:"blocks" binding:"required,min=1,max=100,dive"`
+	PropsPatch map[string]any        `json:"props_patch"` // Merged into the props of every block, null removes a key
+}
+
+type BulkUpdateBlockResult struct {
+	BlockID uuid.UUID `json:"block_id"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type BulkUpdateBlocksResp struct {
+	Results []BulkUpdateBlockResult `json:"results"`
+}
+
+// BulkUpdateBlocks godoc
+//
+//	@Summary		Bulk update blocks
+//	@Description	Update the title and props of up to 100 blocks of a space in one transaction. Each item may set a title and replace the props; props_patch is then merged into the props of every block, with null removing a key. Titles cannot contain a path and props are validated against the schema of the block type. Returns the outcome of each block in request order: blocks that fail are left untouched while the others are updated.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string						true	"Space ID"	Format(uuid)
+//	@Param			payload		body	handler.BulkUpdateBlocksReq	true	"BulkUpdateBlocks payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.BulkUpdateBlocksResp}
+//	@Failure		400	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/bulk-update [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Tag several blocks at once\nresult = client.blocks.bulk_update(\n    space_id='space-uuid',\n    blocks=[{\"block_id\": 'block-uuid-1'}, {\"block_id\": 'block-uuid-2', \"title\": 'Renamed'}],\n    props_patch={\"tag\": \"reviewed\"}\n)\nfor r in result.results:\n    print(r.block_id, r.success, r.error)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Tag several blocks at once\nconst result = await client.blocks.bulkUpdate('space-uuid', {\n  blocks: [{ blockId: 'block-uuid-1' }, { blockId: 'block-uuid-2', title: 'Renamed' }],\n  propsPatch: { tag: 'reviewed' }\n});\nfor (const r of result.results) {\n  console.log(r.blockId, r.success, r.error);\n}\n","label":"JavaScript"}]
+func (h *BlockHandler) BulkUpdateBlocks(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := BulkUpdateBlocksReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if _, err := model.ParseArtifactRefs(req.PropsPatch); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("props_patch", err))
+		return
+	}
+
+	// Items with an invalid title or props fail on their own, the others are still updated
+	failed := make(map[uuid.UUID]error)
+	updates := make([]service.BlockUpdate, 0, len(req.Blocks))
+	seen := make(map[uuid.UUID]bool, len(req.Blocks))
+	for _, item := range req.Blocks {
+		if seen[item.BlockID] {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("blocks", fmt.Errorf("block %s is listed more than once", item.BlockID)))
+			return
+		}
+		seen[item.BlockID] = true
+		if item.Title != nil {
+			if _, filename := path.SplitFilePath(*item.Title); filename != *item.Title {
+				failed[item.BlockID] = errors.New("title cannot contain path")
+				continue
+			}
+		}
+		if _, err := model.ParseArtifactRefs(item.Props); err != nil {
+			failed[item.BlockID] = err
+			continue
+		}
+		updates = append(updates, service.BlockUpdate{BlockID: item.BlockID, Title: item.Title, Props: item.Props})
+	}
+
+	updateFailed, err := h.svc.BulkUpdate(c.Request.Context(), spaceID, updates, req.PropsPatch)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBlockUpdate) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("blocks", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+	for id, err := range updateFailed {
+		failed[id] = err
+	}
+
+	results := make([]BulkUpdateBlockResult, 0, len(req.Blocks))
+	for _, item := range req.Blocks {
+		result := BulkUpdateBlockResult{BlockID: item.BlockID, Success: true}
+		if err, ok := failed[item.BlockID]; ok {
+			result.Success = false
+			result.Error = err.Error()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result.Error = "block not found"
+			}
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, serializer.Response{Data: BulkUpdateBlocksResp{Results: results}})
+}
+
 type ListBlocksReq struct {
 	Type     string `form:"type" json:"type"`
 	ParentID string `form:"parent_id" json:"parent_id"`
@@ -296,15 +589,116 @@ func (h *BlockHandler) ListBlocks(c *gin.Context) {
 	c.JSON(http.StatusOK, serializer.Response{Data: list})
 }
 
+type CountBlocksReq struct {
+	ParentID string `form:"parent_id" json:"parent_id"`
+}
+
+type CountBlocksResp struct {
+	Counts map[string]int64 `json:"counts"`
+	Total  int64            `json:"total"`
+}
+
+// CountBlocks godoc
+//
+//	@Summary		Count blocks
+//	@Description	Count the blocks of a space per block type without listing them. Use parent_id to count only the direct children of a block.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"	Format(uuid)
+//	@Param			parent_id	query	string	false	"Parent ID"	Format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.CountBlocksResp}
+//	@Router			/space/{space_id}/block/count [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Count blocks per type\nresult = client.blocks.count(space_id='space-uuid')\nprint(f\"{result.total} blocks: {result.counts}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Count blocks per type\nconst result = await client.blocks.count('space-uuid');\nconsole.log(`${result.total} blocks:`, result.counts);\n","label":"JavaScript"}]
+func (h *BlockHandler) CountBlocks(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := CountBlocksReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	var parentID *uuid.UUID
+	if req.ParentID != "" {
+		pid, err := uuid.Parse(req.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", err))
+			return
+		}
+		parentID = &pid
+	}
+
+	counts, err := h.svc.CountByType(c.Request.Context(), spaceID, parentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	c.JSON(http.StatusOK, serializer.Response{Data: CountBlocksResp{Counts: counts, Total: total}})
+}
+
+type ListRecentBlocksReq struct {
+	Limit int `form:"limit" json:"limit" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+// ListRecentBlocks godoc
+//
+//	@Summary		List recently viewed blocks
+//	@Description	List the blocks of a space by their last view, latest first. A block is viewed when it is fetched on its own or in a batch get. Returns nothing when view tracking is disabled on the server.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"								Format(uuid)
+//	@Param			limit		query	int		false	"Maximum number of blocks, default 20"	minimum(1)	maximum(100)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=[]model.Block}
+//	@Router			/space/{space_id}/block/recent [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List recently viewed blocks\nblocks = client.blocks.list_recent(space_id='space-uuid', limit=10)\nfor block in blocks:\n    print(f\"{block.title}: {block.last_viewed_at}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List recently viewed blocks\nconst blocks = await client.blocks.listRecent('space-uuid', { limit: 10 });\nfor (const block of blocks) {\n  console.log(`${block.title}: ${block.lastViewedAt}`);\n}\n","label":"JavaScript"}]
+func (h *BlockHandler) ListRecentBlocks(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := ListRecentBlocksReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	list, err := h.svc.ListRecentlyViewed(c.Request.Context(), spaceID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: list})
+}
+
 type MoveBlockReq struct {
 	ParentID *uuid.UUID `form:"parent_id" json:"parent_id"`
+	ToRoot   bool       `form:"to_root" json:"to_root" example:"false"` // Move to root level, parent_id must be empty
 	Sort     *int64     `form:"sort" json:"sort"`
 }
 
 // MoveBlock godoc
 //
 //	@Summary		Move block
-//	@Description	Move block by updating its parent_id. Works for all block types (page, folder, text, sop, etc.). For page and folder types, parent_id can be null (root level).
+//	@Description	Move block by updating its parent_id. Works for all block types (page, folder, text, sop, etc.). Page and folder blocks are moved to root level with to_root=true. Without parent_id or to_root, sort reorders the block within its current parent. The block takes the place of the block holding sort in the target parent, so a sort read from a sibling can be sent back as is. A move nesting the subtree deeper than the maximum depth of the server, or exceeding the maximum children of the new parent, returns 400.
 //	@Tags			block
 //	@Accept			json
 //	@Produce		json
@@ -313,9 +707,17 @@ type MoveBlockReq struct {
 //	@Param			payload		body	handler.MoveBlockReq	true	"MoveBlock payload"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
 //	@Router			/space/{space_id}/block/{block_id}/move [put]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Move block to a different parent\nclient.blocks.move(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    parent_id='new-parent-uuid'\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Move block to a different parent\nawait client.blocks.move('space-uuid', 'block-uuid', {\n  parentId: 'new-parent-uuid'\n});\n","label":"JavaScript"}]
 func (h *BlockHandler) MoveBlock(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
 	blockID, err := uuid.Parse(c.Param("block_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
@@ -334,15 +736,201 @@ func (h *BlockHandler) MoveBlock(c *gin.Context) {
 		return
 	}
 
+	// A missing parent_id is not a request to move to root: that has to be explicit
+	if req.ToRoot && req.ParentID != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("to_root", errors.New("to_root cannot be combined with parent_id")))
+		return
+	}
+	if !req.ToRoot && req.ParentID == nil {
+		if req.Sort == nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("parent_id, to_root or sort is required")))
+			return
+		}
+		if err := h.svc.UpdateSort(c.Request.Context(), spaceID, blockID, *req.Sort); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+			return
+		}
+		c.JSON(http.StatusOK, serializer.Response{})
+		return
+	}
+
 	// Use unified Move method - it handles special logic for folder path
-	if err := h.svc.Move(c.Request.Context(), blockID, req.ParentID, req.Sort); err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	if err := h.svc.Move(c.Request.Context(), spaceID, blockID, req.ParentID, req.Sort); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBlockMove), errors.Is(err, service.ErrBlockTreeLimit):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
+
+type MoveBlocksBatchReq struct {
+	BlockIDs []uuid.UUID `json:"block_ids" binding:"required,min=1,max=200"`
+	ParentID *uuid.UUID  `json:"parent_id"`
+	ToRoot   bool        `json:"to_root" example:"false"` // Move to root level, parent_id must be empty
+}
+
+// MoveBlocksBatch godoc
+//
+//	@Summary		Move blocks in batch
+//	@Description	Move several blocks to a new parent in one transaction. The blocks are appended at the end of the target parent in the order of block_ids. Either parent_id or to_root=true is required. Like a single move, the batch must keep the tree within the depth and children limits of the server.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string						true	"Space ID"	Format(uuid)
+//	@Param			payload		body	handler.MoveBlocksBatchReq	true	"MoveBlocksBatch payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/move-batch [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Move several blocks under a folder, keeping their order\nclient.blocks.move_batch(\n    space_id='space-uuid',\n    block_ids=['block-uuid-1', 'block-uuid-2'],\n    parent_id='folder-uuid'\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Move several blocks under a folder, keeping their order\nawait client.blocks.moveBatch('space-uuid', {\n  blockIds: ['block-uuid-1', 'block-uuid-2'],\n  parentId: 'folder-uuid'\n});\n","label":"JavaScript"}]
+func (h *BlockHandler) MoveBlocksBatch(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := MoveBlocksBatchReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if req.ToRoot == (req.ParentID != nil) {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("exactly one of parent_id or to_root is required")))
+		return
+	}
+
+	if err := h.svc.MoveBatch(c.Request.Context(), spaceID, req.BlockIDs, req.ParentID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBlockMove), errors.Is(err, service.ErrBlockTreeLimit):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
+
+type MoveBlockToSpaceReq struct {
+	TargetSpaceID uuid.UUID  `json:"target_space_id" binding:"required" format:"uuid"`
+	ParentID      *uuid.UUID `json:"parent_id"`               // Parent in the target space
+	ToRoot        bool       `json:"to_root" example:"false"` // Move to the root of the target space, parent_id must be empty
+}
+
+// MoveBlockToSpace godoc
+//
+//	@Summary		Move block to another space
+//	@Description	Move a block and all its descendants to another space of the same project. The block is appended at the end of the target parent, or of the root of the target space with to_root=true. Either parent_id or to_root=true is required. The move history of the moved blocks is cleared. The tree depth and children limits of the server apply in the target space.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string						true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string						true	"Block ID"	Format(uuid)
+//	@Param			payload		body	handler.MoveBlockToSpaceReq	true	"MoveBlockToSpace payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/move-space [put]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Move a page and its subtree to the root of another space\nclient.blocks.move_to_space(\n    space_id='space-uuid',\n    block_id='page-uuid',\n    target_space_id='other-space-uuid',\n    to_root=True\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Move a page and its subtree to the root of another space\nawait client.blocks.moveToSpace('space-uuid', 'page-uuid', {\n  targetSpaceId: 'other-space-uuid',\n  toRoot: true\n});\n","label":"JavaScript"}]
+func (h *BlockHandler) MoveBlockToSpace(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := MoveBlockToSpaceReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if req.ToRoot == (req.ParentID != nil) {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("parent_id", errors.New("exactly one of parent_id or to_root is required")))
+		return
+	}
+
+	if err := h.svc.MoveToSpace(c.Request.Context(), spaceID, blockID, req.TargetSpaceID, req.ParentID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBlockMove), errors.Is(err, service.ErrBlockTreeLimit):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block or space not found", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, serializer.Response{})
 }
 
+// UndoBlockMove godoc
+//
+//	@Summary		Undo block move
+//	@Description	Move a block back to where its latest move took it from, restoring its old parent and position. Each call undoes one more move, up to the last 20 moves of the block. Returns the undone move.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string	true	"Block ID"	Format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.BlockMove}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/undo-move [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Undo the latest move of a block\nmove = client.blocks.undo_move(space_id='space-uuid', block_id='block-uuid')\nprint(f\"Back under {move.old_parent_id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Undo the latest move of a block\nconst move = await client.blocks.undoMove('space-uuid', 'block-uuid');\nconsole.log(`Back under ${move.old_parent_id}`);\n","label":"JavaScript"}]
+func (h *BlockHandler) UndoBlockMove(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	move, err := h.svc.UndoLastMove(c.Request.Context(), spaceID, blockID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBlockMove):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "no move to undo", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: move})
+}
+
 type UpdateBlockSortReq struct {
 	Sort int64 `form:"sort" json:"sort"`
 }
@@ -350,7 +938,7 @@ type UpdateBlockSortReq struct {
 // UpdateBlockSort godoc
 //
 //	@Summary		Update block sort
-//	@Description	Update block sort value (works for all block types: page, folder, text, sop, etc.)
+//	@Description	Update block sort value (works for all block types: page, folder, text, sop, etc.). The block takes the place of the sibling holding sort.
 //	@Tags			block
 //	@Accept			json
 //	@Produce		json
@@ -359,9 +947,16 @@ type UpdateBlockSortReq struct {
 //	@Param			payload		body	handler.UpdateBlockSortReq	true	"UpdateBlockSort payload"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
 //	@Router			/space/{space_id}/block/{block_id}/sort [put]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update block sort order\nclient.blocks.update_sort(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    sort=5\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update block sort order\nawait client.blocks.updateSort('space-uuid', 'block-uuid', {\n  sort: 5\n});\n","label":"JavaScript"}]
 func (h *BlockHandler) UpdateBlockSort(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
 	blockID, err := uuid.Parse(c.Param("block_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
@@ -374,10 +969,87 @@ func (h *BlockHandler) UpdateBlockSort(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.UpdateSort(c.Request.Context(), blockID, req.Sort); err != nil {
+	if err := h.svc.UpdateSort(c.Request.Context(), spaceID, blockID, req.Sort); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, serializer.Response{})
 }
+
+type ImportBlocksReq struct {
+	ParentID *uuid.UUID          `form:"parent_id" json:"parent_id"`
+	Markdown string              `form:"markdown" json:"markdown" example:"# Guide\n\nWelcome.\n\n## Setup\n\nInstall the SDK."`
+	Outline  []service.BlockNode `form:"outline" json:"outline"`
+}
+
+type ImportBlocksResp struct {
+	RootID  uuid.UUID   `json:"root_id"`
+	RootIDs []uuid.UUID `json:"root_ids"`
+}
+
+// ImportBlocks godoc
+//
+//	@Summary		Import blocks
+//	@Description	Create a block tree from Markdown or a structured outline in one transaction. In Markdown, headings with sub-headings become folders, other headings become pages and paragraphs become text blocks under them. Provide exactly one of markdown or outline. Returns the first created top-level block as root_id and all of them as root_ids. The imported tree must fit the depth and children limits of the server.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string					true	"Space ID"	Format(uuid)
+//	@Param			payload		body	handler.ImportBlocksReq	true	"ImportBlocks payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=handler.ImportBlocksResp}
+//	@Router			/space/{space_id}/block/import [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Import a Markdown document as a block tree\nresult = client.blocks.import_markdown(\n    space_id='space-uuid',\n    markdown='# Guide\\n\\nWelcome.\\n\\n## Setup\\n\\nInstall the SDK.'\n)\nprint(result.root_id)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Import a Markdown document as a block tree\nconst result = await client.blocks.importMarkdown('space-uuid', {\n  markdown: '# Guide\\n\\nWelcome.\\n\\n## Setup\\n\\nInstall the SDK.'\n});\nconsole.log(result.rootId);\n","label":"JavaScript"}]
+func (h *BlockHandler) ImportBlocks(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := ImportBlocksReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if (req.Markdown == "") == (len(req.Outline) == 0) {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("exactly one of markdown or outline is required")))
+		return
+	}
+
+	nodes := req.Outline
+	if req.Markdown != "" {
+		nodes, err = service.ParseMarkdownOutline(req.Markdown)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("markdown", err))
+			return
+		}
+	}
+
+	roots, err := h.svc.ImportBlocks(c.Request.Context(), spaceID, req.ParentID, nodes)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBlockImport), errors.Is(err, service.ErrBlockTreeLimit):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "parent block not found", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	resp := ImportBlocksResp{RootIDs: make([]uuid.UUID, 0, len(roots))}
+	for _, b := range roots {
+		resp.RootIDs = append(resp.RootIDs, b.ID)
+	}
+	resp.RootID = resp.RootIDs[0]
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: resp})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"gorm.io/gorm"
+)
+
+type BlockReferenceHandler struct {
+	svc service.BlockReferenceService
+}
+
+func NewBlockReferenceHandler(s service.BlockReferenceService) *BlockReferenceHandler {
+	return &BlockReferenceHandler{svc: s}
+}
+
+type ResolveBlockReferencesReq struct {
+	Expire int `form:"expire,default=3600" json:"expire" binding:"min=1" example:"3600"` // Expire time in seconds for the presigned URLs
+}
+
+// ResolveBlockReferences godoc
+//
+//	@Summary		Get block with referenced artifacts
+//	@Description	Get a block together with the artifacts referenced in its props and presigned URLs to download them, so a renderer needs a single call. A reference is an object {"artifact": {"disk_id": "<disk uuid>", "path": "/dir/file.ext"}} anywhere in the props. References to missing artifacts, or to disks of another project, are listed in broken with a reason.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"											Format(uuid)
+//	@Param			block_id	path	string	true	"Block ID"											Format(uuid)
+//	@Param			expire		query	int		false	"Expire time in seconds for the presigned URLs (default: 3600)"	example(3600)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ResolvedBlock}
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/references [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get a block with the artifacts it references\nresult = client.blocks.resolve_references(\n    space_id='space-uuid',\n    block_id='block-uuid'\n)\nfor ref in result.artifacts:\n    print(f\"{ref.path}: {ref.public_url}\")\nfor ref in result.broken:\n    print(f\"Broken {ref.path}: {ref.reason}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get a block with the artifacts it references\nconst result = await client.blocks.resolveReferences('space-uuid', 'block-uuid');\nfor (const ref of result.artifacts) {\n  console.log(`${ref.path}: ${ref.public_url}`);\n}\nfor (const ref of result.broken) {\n  console.log(`Broken ${ref.path}: ${ref.reason}`);\n}\n","label":"JavaScript"}]
+func (h *BlockReferenceHandler) ResolveBlockReferences(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := ResolveBlockReferencesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	resolved, err := h.svc.ResolveBlockReferences(c.Request.Context(), service.ResolveBlockReferencesInput{
+		ProjectID: project.ID,
+		SpaceID:   spaceID,
+		BlockID:   blockID,
+		Expire:    time.Duration(req.Expire) * time.Second,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block not found", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: resolved})
+}
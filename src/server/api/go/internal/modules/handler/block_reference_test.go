@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockBlockReferenceService is a mock implementation of BlockReferenceService
+type MockBlockReferenceService struct {
+	mock.Mock
+}
+
+func (m *MockBlockReferenceService) ResolveBlockReferences(ctx context.Context, in service.ResolveBlockReferencesInput) (*service.ResolvedBlock, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ResolvedBlock), args.Error(1)
+}
+
+func TestBlockReferenceHandler_ResolveBlockReferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	projectID := uuid.New()
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	in := service.ResolveBlockReferencesInput{ProjectID: projectID, SpaceID: spaceID, BlockID: blockID, Expire: time.Hour}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockBlockReferenceService)
+		expectedStatus int
+	}{
+		{
+			name: "resolved block",
+			setup: func(svc *MockBlockReferenceService) {
+				svc.On("ResolveBlockReferences", mock.Anything, in).Return(&service.ResolvedBlock{Block: &model.Block{ID: blockID}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "custom expire",
+			query: "?expire=60",
+			setup: func(svc *MockBlockReferenceService) {
+				custom := in
+				custom.Expire = time.Minute
+				svc.On("ResolveBlockReferences", mock.Anything, custom).Return(&service.ResolvedBlock{Block: &model.Block{ID: blockID}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid expire",
+			query:          "?expire=0",
+			setup:          func(svc *MockBlockReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "block not found",
+			setup: func(svc *MockBlockReferenceService) {
+				svc.On("ResolveBlockReferences", mock.Anything, in).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockBlockReferenceService) {
+				svc.On("ResolveBlockReferences", mock.Anything, in).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockReferenceService{}
+			tt.setup(mockService)
+			handler := NewBlockReferenceHandler(mockService)
+
+			router := gin.New()
+			router.GET("/space/:space_id/block/:block_id/references", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.ResolveBlockReferences(c)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/space/"+spaceID.String()+"/block/"+blockID.String()+"/references"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/infra/httpclient"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockBlockService is a mock implementation of BlockService
@@ -34,19 +39,35 @@ func (m *MockBlockService) Delete(ctx context.Context, spaceID uuid.UUID, blockI
 	return args.Error(0)
 }
 
-func (m *MockBlockService) GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error) {
-	args := m.Called(ctx, blockID)
+func (m *MockBlockService) GetBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	args := m.Called(ctx, spaceID, blockID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*model.Block), args.Error(1)
 }
 
-func (m *MockBlockService) UpdateBlockProperties(ctx context.Context, b *model.Block) error {
-	args := m.Called(ctx, b)
+func (m *MockBlockService) GetMany(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID) ([]model.Block, []uuid.UUID, error) {
+	args := m.Called(ctx, spaceID, blockIDs)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).([]model.Block), args.Get(1).([]uuid.UUID), args.Error(2)
+}
+
+func (m *MockBlockService) UpdateBlockProperties(ctx context.Context, spaceID uuid.UUID, b *model.Block) error {
+	args := m.Called(ctx, spaceID, b)
 	return args.Error(0)
 }
 
+func (m *MockBlockService) PatchBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, patch map[string]any) (*model.Block, error) {
+	args := m.Called(ctx, spaceID, blockID, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Block), args.Error(1)
+}
+
 func (m *MockBlockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	args := m.Called(ctx, spaceID, blockType, parentID)
 	if args.Get(0) == nil {
@@ -55,16 +76,71 @@ func (m *MockBlockService) List(ctx context.Context, spaceID uuid.UUID, blockTyp
 	return args.Get(0).([]model.Block), args.Error(1)
 }
 
-func (m *MockBlockService) Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
-	args := m.Called(ctx, blockID, newParentID, targetSort)
+func (m *MockBlockService) MoveBatch(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, spaceID, blockIDs, newParentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) MoveToSpace(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, spaceID, blockID, targetSpaceID, newParentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) Move(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
+	args := m.Called(ctx, spaceID, blockID, newParentID, targetSort)
+	return args.Error(0)
+}
+
+func (m *MockBlockService) UndoLastMove(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.BlockMove, error) {
+	args := m.Called(ctx, spaceID, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BlockMove), args.Error(1)
+}
+
+func (m *MockBlockService) UpdateSort(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, sort int64) error {
+	args := m.Called(ctx, spaceID, blockID, sort)
 	return args.Error(0)
 }
 
-func (m *MockBlockService) UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error {
-	args := m.Called(ctx, blockID, sort)
+func (m *MockBlockService) CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error) {
+	args := m.Called(ctx, spaceID, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockBlockService) CheckTreeLimits(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	args := m.Called(ctx, spaceID, parentID)
 	return args.Error(0)
 }
 
+func (m *MockBlockService) BulkUpdate(ctx context.Context, spaceID uuid.UUID, updates []service.BlockUpdate, propsPatch map[string]any) (map[uuid.UUID]error, error) {
+	args := m.Called(ctx, spaceID, updates, propsPatch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]error), args.Error(1)
+}
+
+func (m *MockBlockService) ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
+func (m *MockBlockService) ImportBlocks(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, nodes []service.BlockNode) ([]*model.Block, error) {
+	args := m.Called(ctx, spaceID, parentID, nodes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Block), args.Error(1)
+}
+
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
@@ -291,6 +367,46 @@ func TestBlockHandler_CreateBlock_Text(t *testing.T) {
 			setup:          func(svc *MockBlockService) {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:         "text props violating the schema",
+			spaceIDParam: spaceID.String(),
+			requestBody: CreateBlockReq{
+				ParentID: &parentID,
+				Type:     "text",
+				Title:    "test block",
+				Props:    map[string]any{"text": 42},
+			},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "sop props violating the schema",
+			spaceIDParam: spaceID.String(),
+			requestBody: CreateBlockReq{
+				ParentID: &parentID,
+				Type:     model.BlockTypeSOP,
+				Title:    "star a repo",
+				Props:    map[string]any{"use_when": "star a repo on github.com"},
+			},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "parent at its children limit",
+			spaceIDParam: spaceID.String(),
+			requestBody: CreateBlockReq{
+				ParentID: &parentID,
+				Type:     "text",
+				Title:    "test block",
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, spaceID, parentID).
+					Return(&model.Block{ID: parentID, SpaceID: spaceID, Type: model.BlockTypePage}, nil)
+				svc.On("CheckTreeLimits", mock.Anything, spaceID, &parentID).
+					Return(fmt.Errorf("%w: block %s would have 11 children, more than the maximum of 10", service.ErrBlockTreeLimit, parentID))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:         "title contains path separator",
 			spaceIDParam: spaceID.String(),
@@ -637,7 +753,7 @@ func TestBlockHandler_UpdateBlockProperties(t *testing.T) {
 				Props: map[string]any{"color": "blue"},
 			},
 			setup: func(svc *MockBlockService) {
-				svc.On("UpdateBlockProperties", mock.Anything, mock.MatchedBy(func(b *model.Block) bool {
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything, mock.MatchedBy(func(b *model.Block) bool {
 					return b.ID == blockID && b.Title == "Updated Title"
 				})).Return(nil)
 			},
@@ -659,6 +775,19 @@ func TestBlockHandler_UpdateBlockProperties(t *testing.T) {
 			setup:          func(svc *MockBlockService) {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:         "props violating the schema of the block type",
+			blockIDParam: blockID.String(),
+			requestBody: UpdateBlockPropertiesReq{
+				Title: "Star a repo",
+				Props: map[string]any{"use_when": "star a repo on github.com"},
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything, mock.Anything).
+					Return(fmt.Errorf("%w: preferences: is required", model.ErrInvalidBlockProps))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:         "service layer error",
 			blockIDParam: blockID.String(),
@@ -666,7 +795,7 @@ func TestBlockHandler_UpdateBlockProperties(t *testing.T) {
 				Title: "Updated Title",
 			},
 			setup: func(svc *MockBlockService) {
-				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything).Return(errors.New("update failed"))
+				svc.On("UpdateBlockProperties", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("update failed"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -701,3 +830,1033 @@ func TestBlockHandler_UpdateBlockProperties(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockHandler_PatchBlockProperties(t *testing.T) {
+	blockID := uuid.New()
+
+	tests := []struct {
+		name           string
+		blockIDParam   string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:         "successful patch keeps untouched keys",
+			blockIDParam: blockID.String(),
+			requestBody:  map[string]any{"props": map[string]any{"color": "blue", "obsolete": nil}},
+			setup: func(svc *MockBlockService) {
+				svc.On("PatchBlockProperties", mock.Anything, mock.Anything, blockID, map[string]any{"color": "blue", "obsolete": nil}).
+					Return(&model.Block{
+						ID:    blockID,
+						Props: datatypes.NewJSONType(map[string]any{"text": "kept", "color": "blue"}),
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid block ID",
+			blockIDParam:   "invalid-uuid",
+			requestBody:    map[string]any{"props": map[string]any{"color": "blue"}},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing props",
+			blockIDParam:   blockID.String(),
+			requestBody:    map[string]any{},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed artifact reference",
+			blockIDParam:   blockID.String(),
+			requestBody:    map[string]any{"props": map[string]any{"file": map[string]any{"artifact": map[string]any{"disk_id": "disk-1", "path": "/a.txt"}}}},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "merged props fail the schema",
+			blockIDParam: blockID.String(),
+			requestBody:  map[string]any{"props": map[string]any{"preferences": []any{"web ui"}}},
+			setup: func(svc *MockBlockService) {
+				svc.On("PatchBlockProperties", mock.Anything, mock.Anything, blockID, mock.Anything).
+					Return(nil, fmt.Errorf("%w: preferences: expected string, got array", model.ErrInvalidBlockProps))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "service layer error",
+			blockIDParam: blockID.String(),
+			requestBody:  map[string]any{"props": map[string]any{"color": "blue"}},
+			setup: func(svc *MockBlockService) {
+				svc.On("PatchBlockProperties", mock.Anything, mock.Anything, blockID, mock.Anything).Return(nil, errors.New("patch failed"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.PATCH("/space/:space_id/block/:block_id/properties", handler.PatchBlockProperties)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PATCH", "/space/"+uuid.New().String()+"/block/"+tt.blockIDParam+"/properties", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]any
+				err := sonic.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				data := response["data"].(map[string]any)
+				props := data["props"].(map[string]any)
+				assert.Equal(t, "kept", props["text"])
+				assert.Equal(t, "blue", props["color"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_GetBlockProperties_Conditional(t *testing.T) {
+	blockID := uuid.New()
+	updatedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	block := &model.Block{ID: blockID, Type: model.BlockTypePage, Title: "Page", UpdatedAt: updatedAt}
+
+	mockService := &MockBlockService{}
+	mockService.On("GetBlockProperties", mock.Anything, mock.Anything, blockID).Return(block, nil)
+
+	handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+	router := setupRouter()
+	router.GET("/space/:space_id/block/:block_id/properties", handler.GetBlockProperties)
+	url := "/space/" + uuid.New().String() + "/block/" + blockID.String() + "/properties"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	req := httptest.NewRequest("GET", url, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	req = httptest.NewRequest("GET", url, nil)
+	req.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// The block changed since the client's copy
+	block.UpdatedAt = updatedAt.Add(time.Minute)
+	req = httptest.NewRequest("GET", url, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestBlockHandler_GetBlock(t *testing.T) {
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	block := &model.Block{ID: blockID, SpaceID: spaceID, Type: model.BlockTypeSOP, Title: "SOP", Props: datatypes.NewJSONType(map[string]any{"tool_sops": []any{}})}
+
+	tests := []struct {
+		name           string
+		path           string
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name: "block resource",
+			path: "/space/" + spaceID.String() + "/block/" + blockID.String(),
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, spaceID, blockID).Return(block, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "properties route",
+			path: "/space/" + spaceID.String() + "/block/" + blockID.String() + "/properties",
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, spaceID, blockID).Return(block, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "block of another space",
+			path: "/space/" + spaceID.String() + "/block/" + blockID.String(),
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, spaceID, blockID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid space id",
+			path:           "/space/invalid/block/" + blockID.String(),
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			path: "/space/" + spaceID.String() + "/block/" + blockID.String(),
+			setup: func(svc *MockBlockService) {
+				svc.On("GetBlockProperties", mock.Anything, spaceID, blockID).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.GET("/space/:space_id/block/:block_id", handler.GetBlock)
+			router.GET("/space/:space_id/block/:block_id/properties", handler.GetBlockProperties)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"tool_sops"`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_GetBlocksBatch(t *testing.T) {
+	spaceID := uuid.New()
+	a := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "A"}
+	b := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, Title: "B"}
+	unknown := uuid.New()
+	ids := []uuid.UUID{b.ID, unknown, a.ID}
+
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:        "blocks in requested order",
+			requestBody: map[string]any{"block_ids": []string{b.ID.String(), unknown.String(), a.ID.String()}},
+			setup: func(svc *MockBlockService) {
+				svc.On("GetMany", mock.Anything, spaceID, ids).Return([]model.Block{b, a}, []uuid.UUID{unknown}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no IDs",
+			requestBody:    map[string]any{"block_ids": []string{}},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many IDs",
+			requestBody:    map[string]any{"block_ids": tooMany},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid ID",
+			requestBody:    map[string]any{"block_ids": []string{"invalid-uuid"}},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "service layer error",
+			requestBody: map[string]any{"block_ids": []string{b.ID.String(), unknown.String(), a.ID.String()}},
+			setup: func(svc *MockBlockService) {
+				svc.On("GetMany", mock.Anything, spaceID, ids).Return(nil, nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.POST("/space/:space_id/block/batch-get", handler.GetBlocksBatch)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/batch-get", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]any
+				assert.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				data := response["data"].(map[string]any)
+				blocks := data["blocks"].([]any)
+				assert.Len(t, blocks, 2)
+				assert.Equal(t, "B", blocks[0].(map[string]any)["title"])
+				assert.Equal(t, []any{unknown.String()}, data["missing"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_ImportBlocks(t *testing.T) {
+	spaceID := uuid.New()
+	parentID := uuid.New()
+	rootID := uuid.New()
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:        "import markdown",
+			requestBody: map[string]any{"markdown": "# Guide\n\nWelcome."},
+			setup: func(svc *MockBlockService) {
+				svc.On("ImportBlocks", mock.Anything, spaceID, (*uuid.UUID)(nil), mock.MatchedBy(func(nodes []service.BlockNode) bool {
+					return len(nodes) == 1 && nodes[0].Type == model.BlockTypePage && nodes[0].Title == "Guide"
+				})).Return([]*model.Block{{ID: rootID}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "import outline under parent",
+			requestBody: map[string]any{
+				"parent_id": parentID.String(),
+				"outline": []map[string]any{
+					{"type": "text", "props": map[string]any{"text": "hello"}},
+				},
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("ImportBlocks", mock.Anything, spaceID, &parentID, mock.Anything).Return([]*model.Block{{ID: rootID}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "neither markdown nor outline",
+			requestBody:    map[string]any{},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "both markdown and outline",
+			requestBody: map[string]any{
+				"markdown": "# Guide",
+				"outline":  []map[string]any{{"type": "page", "title": "Guide"}},
+			},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid outline",
+			requestBody: map[string]any{"outline": []map[string]any{{"type": "page", "title": "a/b"}}},
+			setup: func(svc *MockBlockService) {
+				svc.On("ImportBlocks", mock.Anything, spaceID, (*uuid.UUID)(nil), mock.Anything).Return(nil, service.ErrInvalidBlockImport)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "parent not found",
+			requestBody: map[string]any{"parent_id": parentID.String(), "markdown": "text"},
+			setup: func(svc *MockBlockService) {
+				svc.On("ImportBlocks", mock.Anything, spaceID, &parentID, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "service layer error",
+			requestBody: map[string]any{"markdown": "# Guide"},
+			setup: func(svc *MockBlockService) {
+				svc.On("ImportBlocks", mock.Anything, spaceID, (*uuid.UUID)(nil), mock.Anything).Return(nil, errors.New("tx failed"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.POST("/space/:space_id/block/import", handler.ImportBlocks)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/import", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				assert.Contains(t, w.Body.String(), rootID.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_MoveBlock(t *testing.T) {
+	blockID := uuid.New()
+	parentID := uuid.New()
+	sort := int64(2)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:        "move to parent",
+			requestBody: map[string]any{"parent_id": parentID.String()},
+			setup: func(svc *MockBlockService) {
+				svc.On("Move", mock.Anything, mock.Anything, blockID, &parentID, (*int64)(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "move to root",
+			requestBody: map[string]any{"to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("Move", mock.Anything, mock.Anything, blockID, (*uuid.UUID)(nil), (*int64)(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "move to root at sort",
+			requestBody: map[string]any{"to_root": true, "sort": sort},
+			setup: func(svc *MockBlockService) {
+				svc.On("Move", mock.Anything, mock.Anything, blockID, (*uuid.UUID)(nil), &sort).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "sort only reorders within the current parent",
+			requestBody: map[string]any{"sort": sort},
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateSort", mock.Anything, mock.Anything, blockID, sort).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "null parent without to_root",
+			requestBody:    map[string]any{"parent_id": nil},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "to_root with parent",
+			requestBody:    map[string]any{"to_root": true, "parent_id": parentID.String()},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "service layer error",
+			requestBody: map[string]any{"to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("Move", mock.Anything, mock.Anything, blockID, (*uuid.UUID)(nil), (*int64)(nil)).Return(errors.New("text block must have a parent"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.PUT("/space/:space_id/block/:block_id/move", handler.MoveBlock)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PUT", "/space/"+uuid.New().String()+"/block/"+blockID.String()+"/move", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_MoveBlocksBatch(t *testing.T) {
+	spaceID := uuid.New()
+	parentID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	idStrings := []string{ids[0].String(), ids[1].String()}
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:        "move to parent",
+			requestBody: map[string]any{"block_ids": idStrings, "parent_id": parentID.String()},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveBatch", mock.Anything, spaceID, ids, &parentID).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "move to root",
+			requestBody: map[string]any{"block_ids": idStrings, "to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveBatch", mock.Anything, spaceID, ids, (*uuid.UUID)(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "neither parent nor to_root",
+			requestBody:    map[string]any{"block_ids": idStrings},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty block_ids",
+			requestBody:    map[string]any{"block_ids": []string{}, "to_root": true},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid move",
+			requestBody: map[string]any{"block_ids": idStrings, "parent_id": parentID.String()},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveBatch", mock.Anything, spaceID, ids, &parentID).Return(service.ErrInvalidBlockMove)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "block not found",
+			requestBody: map[string]any{"block_ids": idStrings, "to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveBatch", mock.Anything, spaceID, ids, (*uuid.UUID)(nil)).Return(gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.POST("/space/:space_id/block/move-batch", handler.MoveBlocksBatch)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/move-batch", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_MoveBlockToSpace(t *testing.T) {
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	targetSpaceID := uuid.New()
+	parentID := uuid.New()
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]any
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name:        "move under a parent",
+			requestBody: map[string]any{"target_space_id": targetSpaceID.String(), "parent_id": parentID.String()},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveToSpace", mock.Anything, spaceID, blockID, targetSpaceID, &parentID).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "move to root",
+			requestBody: map[string]any{"target_space_id": targetSpaceID.String(), "to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveToSpace", mock.Anything, spaceID, blockID, targetSpaceID, (*uuid.UUID)(nil)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing target space",
+			requestBody:    map[string]any{"to_root": true},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "neither parent nor to_root",
+			requestBody:    map[string]any{"target_space_id": targetSpaceID.String()},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid move",
+			requestBody: map[string]any{"target_space_id": targetSpaceID.String(), "parent_id": parentID.String()},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveToSpace", mock.Anything, spaceID, blockID, targetSpaceID, &parentID).Return(service.ErrInvalidBlockMove)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "space of another project",
+			requestBody: map[string]any{"target_space_id": targetSpaceID.String(), "to_root": true},
+			setup: func(svc *MockBlockService) {
+				svc.On("MoveToSpace", mock.Anything, spaceID, blockID, targetSpaceID, (*uuid.UUID)(nil)).Return(gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.PUT("/space/:space_id/block/:block_id/move-space", handler.MoveBlockToSpace)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PUT", "/space/"+spaceID.String()+"/block/"+blockID.String()+"/move-space", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_UndoBlockMove(t *testing.T) {
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	oldParentID := uuid.New()
+
+	tests := []struct {
+		name           string
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name: "undone",
+			setup: func(svc *MockBlockService) {
+				svc.On("UndoLastMove", mock.Anything, mock.Anything, blockID).Return(&model.BlockMove{BlockID: blockID, OldParentID: &oldParentID, OldSort: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "no move to undo",
+			setup: func(svc *MockBlockService) {
+				svc.On("UndoLastMove", mock.Anything, mock.Anything, blockID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "old parent gone",
+			setup: func(svc *MockBlockService) {
+				svc.On("UndoLastMove", mock.Anything, mock.Anything, blockID).Return(nil, service.ErrInvalidBlockMove)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.POST("/space/:space_id/block/:block_id/undo-move", handler.UndoBlockMove)
+
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/"+blockID.String()+"/undo-move", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_BlockOfAnotherSpace(t *testing.T) {
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	blockPath := "/space/" + spaceID.String() + "/block/" + blockID.String()
+
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		path   string
+		body   string
+		setup  func(*MockBlockService)
+	}{
+		{
+			name:   "update properties",
+			method: "PUT",
+			route:  "/space/:space_id/block/:block_id/properties",
+			path:   blockPath + "/properties",
+			body:   `{"title":"Stolen","props":{}}`,
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateBlockProperties", mock.Anything, spaceID, mock.Anything).Return(gorm.ErrRecordNotFound)
+			},
+		},
+		{
+			name:   "patch properties",
+			method: "PATCH",
+			route:  "/space/:space_id/block/:block_id/properties",
+			path:   blockPath + "/properties",
+			body:   `{"props":{"color":"red"}}`,
+			setup: func(svc *MockBlockService) {
+				svc.On("PatchBlockProperties", mock.Anything, spaceID, blockID, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+			},
+		},
+		{
+			name:   "move",
+			method: "PUT",
+			route:  "/space/:space_id/block/:block_id/move",
+			path:   blockPath + "/move",
+			body:   `{"to_root":true}`,
+			setup: func(svc *MockBlockService) {
+				svc.On("Move", mock.Anything, spaceID, blockID, (*uuid.UUID)(nil), (*int64)(nil)).Return(gorm.ErrRecordNotFound)
+			},
+		},
+		{
+			name:   "move with sort only",
+			method: "PUT",
+			route:  "/space/:space_id/block/:block_id/move",
+			path:   blockPath + "/move",
+			body:   `{"sort":1}`,
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateSort", mock.Anything, spaceID, blockID, int64(1)).Return(gorm.ErrRecordNotFound)
+			},
+		},
+		{
+			name:   "update sort",
+			method: "PUT",
+			route:  "/space/:space_id/block/:block_id/sort",
+			path:   blockPath + "/sort",
+			body:   `{"sort":2}`,
+			setup: func(svc *MockBlockService) {
+				svc.On("UpdateSort", mock.Anything, spaceID, blockID, int64(2)).Return(gorm.ErrRecordNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.PUT("/space/:space_id/block/:block_id/properties", handler.UpdateBlockProperties)
+			router.PATCH("/space/:space_id/block/:block_id/properties", handler.PatchBlockProperties)
+			router.PUT("/space/:space_id/block/:block_id/move", handler.MoveBlock)
+			router.PUT("/space/:space_id/block/:block_id/sort", handler.UpdateBlockSort)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("invalid space id", func(t *testing.T) {
+		handler := NewBlockHandler(&MockBlockService{}, getMockBlockCoreClient())
+		router := setupRouter()
+		router.PUT("/space/:space_id/block/:block_id/sort", handler.UpdateBlockSort)
+
+		req := httptest.NewRequest("PUT", "/space/invalid/block/"+blockID.String()+"/sort", bytes.NewBufferString(`{"sort":2}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestBlockHandler_CountBlocks(t *testing.T) {
+	spaceID := uuid.New()
+	parentID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockBlockService)
+		expectedStatus int
+		expectedTotal  float64
+	}{
+		{
+			name: "whole space",
+			setup: func(svc *MockBlockService) {
+				svc.On("CountByType", mock.Anything, spaceID, (*uuid.UUID)(nil)).
+					Return(map[string]int64{"page": 3, "folder": 1, "text": 10, "sop": 0}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTotal:  14,
+		},
+		{
+			name:  "children of a parent",
+			query: "?parent_id=" + parentID.String(),
+			setup: func(svc *MockBlockService) {
+				svc.On("CountByType", mock.Anything, spaceID, &parentID).
+					Return(map[string]int64{"page": 0, "folder": 0, "text": 2, "sop": 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTotal:  3,
+		},
+		{
+			name:           "invalid parent ID",
+			query:          "?parent_id=invalid-uuid",
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service layer error",
+			setup: func(svc *MockBlockService) {
+				svc.On("CountByType", mock.Anything, spaceID, (*uuid.UUID)(nil)).Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.GET("/space/:space_id/block/count", handler.CountBlocks)
+
+			req := httptest.NewRequest("GET", "/space/"+spaceID.String()+"/block/count"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]any
+				assert.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				data := response["data"].(map[string]any)
+				assert.Equal(t, tt.expectedTotal, data["total"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_ListRecentBlocks(t *testing.T) {
+	spaceID := uuid.New()
+	viewed := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Viewed"}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockBlockService)
+		expectedStatus int
+	}{
+		{
+			name: "default limit",
+			setup: func(svc *MockBlockService) {
+				svc.On("ListRecentlyViewed", mock.Anything, spaceID, 20).Return([]model.Block{viewed}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "custom limit",
+			query: "?limit=5",
+			setup: func(svc *MockBlockService) {
+				svc.On("ListRecentlyViewed", mock.Anything, spaceID, 5).Return([]model.Block{viewed}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=500",
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service layer error",
+			setup: func(svc *MockBlockService) {
+				svc.On("ListRecentlyViewed", mock.Anything, spaceID, 20).Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.GET("/space/:space_id/block/recent", handler.ListRecentBlocks)
+
+			req := httptest.NewRequest("GET", "/space/"+spaceID.String()+"/block/recent"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockHandler_BulkUpdateBlocks(t *testing.T) {
+	spaceID := uuid.New()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	patch := map[string]any{"tag": "reviewed"}
+
+	tests := []struct {
+		name            string
+		requestBody     map[string]any
+		setup           func(*MockBlockService)
+		expectedStatus  int
+		expectedResults []BulkUpdateBlockResult
+	}{
+		{
+			name: "three blocks tagged at once",
+			requestBody: map[string]any{
+				"blocks":      []map[string]any{{"block_id": a}, {"block_id": b, "props": map[string]any{"text": "b"}}, {"block_id": c}},
+				"props_patch": patch,
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("BulkUpdate", mock.Anything, spaceID, []service.BlockUpdate{
+					{BlockID: a},
+					{BlockID: b, Props: map[string]any{"text": "b"}},
+					{BlockID: c},
+				}, patch).Return(map[uuid.UUID]error{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedResults: []BulkUpdateBlockResult{
+				{BlockID: a, Success: true},
+				{BlockID: b, Success: true},
+				{BlockID: c, Success: true},
+			},
+		},
+		{
+			name: "failures are reported per block",
+			requestBody: map[string]any{
+				"blocks":      []map[string]any{{"block_id": a, "title": "dir/name"}, {"block_id": b}, {"block_id": c}},
+				"props_patch": patch,
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("BulkUpdate", mock.Anything, spaceID, []service.BlockUpdate{{BlockID: b}, {BlockID: c}}, patch).
+					Return(map[uuid.UUID]error{c: gorm.ErrRecordNotFound}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedResults: []BulkUpdateBlockResult{
+				{BlockID: a, Error: "title cannot contain path"},
+				{BlockID: b, Success: true},
+				{BlockID: c, Error: "block not found"},
+			},
+		},
+		{
+			name:           "repeated block",
+			requestBody:    map[string]any{"blocks": []map[string]any{{"block_id": a}, {"block_id": a}}},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no blocks",
+			requestBody:    map[string]any{"blocks": []map[string]any{}, "props_patch": patch},
+			setup:          func(svc *MockBlockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service layer error",
+			requestBody: map[string]any{
+				"blocks": []map[string]any{{"block_id": a}},
+			},
+			setup: func(svc *MockBlockService) {
+				svc.On("BulkUpdate", mock.Anything, spaceID, []service.BlockUpdate{{BlockID: a}}, map[string]any(nil)).
+					Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockBlockService{}
+			tt.setup(mockService)
+
+			handler := NewBlockHandler(mockService, getMockBlockCoreClient())
+			router := setupRouter()
+			router.POST("/space/:space_id/block/bulk-update", handler.BulkUpdateBlocks)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/bulk-update", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data BulkUpdateBlocksResp `json:"data"`
+				}
+				assert.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedResults, response.Data.Results)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
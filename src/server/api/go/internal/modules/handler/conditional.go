@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// blockETag changes whenever the block is updated
+func blockETag(b *model.Block) string {
+	return fmt.Sprintf(`W/"%s-%x"`, b.ID, b.UpdatedAt.UnixNano())
+}
+
+// artifactETag reuses the S3 ETag of the stored asset. The update time is mixed in
+// because meta updates do not touch the asset.
+func artifactETag(a *model.Artifact) string {
+	assetETag := strings.Trim(a.AssetMeta.Data().ETag, `"`)
+	if assetETag == "" {
+		assetETag = a.ID.String()
+	}
+	return fmt.Sprintf(`W/"%s-%x"`, assetETag, a.UpdatedAt.UnixNano())
+}
+
+// notModified sets the ETag and Last-Modified headers and answers 304 Not Modified
+// when the request's If-None-Match or If-Modified-Since shows the client copy is current.
+// If-None-Match takes precedence over If-Modified-Since.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	ims := c.GetHeader("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil || lastModified.Truncate(time.Second).After(since) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares an If-None-Match header with etag using weak comparison
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"gorm.io/gorm"
 )
 
 type DiskHandler struct {
@@ -19,13 +21,20 @@ func NewDiskHandler(s service.DiskService) *DiskHandler {
 	return &DiskHandler{svc: s}
 }
 
+type CreateDiskReq struct {
+	// PathAddressedKeys stores each artifact in its own object, keyed by disk ID and file
+	// path, instead of deduplicating equal files by content
+	PathAddressedKeys bool `form:"path_addressed_keys" json:"path_addressed_keys"`
+}
+
 // CreateDisk godoc
 //
 //	@Summary		Create disk
-//	@Description	Create a disk group under a project
+//	@Description	Create a disk group under a project. With path_addressed_keys, each artifact is stored under its own key, disks/{disk_id}{path}{filename}, and moving it moves the stored file; by default, files are stored and deduplicated by content.
 //	@Tags			disk
 //	@Accept			json
 //	@Produce		json
+//	@Param			request	body	handler.CreateDiskReq	false	"Create disk request"
 //	@Security		BearerAuth
 //	@Success		201	{object}	serializer.Response{data=model.Disk}
 //	@Router			/disk [post]
@@ -37,7 +46,14 @@ func (h *DiskHandler) CreateDisk(c *gin.Context) {
 		return
 	}
 
-	disk, err := h.svc.Create(c.Request.Context(), project.ID)
+	req := CreateDiskReq{}
+	// The body is optional
+	if err := c.ShouldBind(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	disk, err := h.svc.Create(c.Request.Context(), project.ID, req.PathAddressedKeys)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
@@ -46,22 +62,51 @@ func (h *DiskHandler) CreateDisk(c *gin.Context) {
 	c.JSON(http.StatusCreated, serializer.Response{Data: disk})
 }
 
+// GetDefaultDisk godoc
+//
+//	@Summary		Get default disk
+//	@Description	Get the default disk of the project, creating it if the project has none. Concurrent calls return the same disk.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Disk}
+//	@Router			/disk/default [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get the default disk, created on first use\ndisk = client.disks.get_default()\nprint(f\"Default disk: {disk.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get the default disk, created on first use\nconst disk = await client.disks.getDefault();\nconsole.log(`Default disk: ${disk.id}`);\n","label":"JavaScript"}]
+func (h *DiskHandler) GetDefaultDisk(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	disk, err := h.svc.GetOrCreateDefault(c.Request.Context(), project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: disk})
+}
+
 type ListDisksReq struct {
-	Limit    int    `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
-	Cursor   string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
-	TimeDesc bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
+	Limit     int    `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
+	Cursor    string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
+	TimeDesc  bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
+	WithStats bool   `form:"with_stats,default=false" json:"with_stats" example:"false"`
 }
 
 // ListDisks godoc
 //
 //	@Summary		List disks
-//	@Description	List all disks under a project
+//	@Description	List all disks under a project. With with_stats=true each disk carries the number of its artifacts and their total size.
 //	@Tags			disk
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit		query	integer	false	"Limit of disks to return, default 20. Max 200."
 //	@Param			cursor		query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
 //	@Param			time_desc	query	boolean	false	"Order by created_at descending if true, ascending if false (default false)"	example(false)
+//	@Param			with_stats	query	boolean	false	"Include artifact count and total size per disk (default false)"	example(false)
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=service.ListDisksOutput}
 //	@Router			/disk [get]
@@ -84,6 +129,7 @@ func (h *DiskHandler) ListDisks(c *gin.Context) {
 		Limit:     req.Limit,
 		Cursor:    req.Cursor,
 		TimeDesc:  req.TimeDesc,
+		WithStats: req.WithStats,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
@@ -93,16 +139,21 @@ func (h *DiskHandler) ListDisks(c *gin.Context) {
 	c.JSON(http.StatusOK, serializer.Response{Data: out})
 }
 
+type DeleteDiskReq struct {
+	DryRun bool `form:"dry_run,default=false" json:"dry_run" example:"false"`
+}
+
 // DeleteDisk godoc
 //
 //	@Summary		Delete disk
-//	@Description	Delete a disk by its UUID
+//	@Description	Delete a disk by its UUID. The disk and its artifacts can be restored until the grace period ends (7 days by default); then they are purged and stored objects no longer referenced are freed. With dry_run=true nothing is deleted; the response reports how many artifacts would be removed and how many stored objects would be freed by the purge.
 //	@Tags			disk
 //	@Accept			json
 //	@Produce		json
 //	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			dry_run	query	boolean	false	"Only report what would be deleted (default false)"	example(false)
 //	@Security		BearerAuth
-//	@Success		200	{object}	serializer.Response{}
+//	@Success		200	{object}	serializer.Response{data=service.DeleteDiskPreview}
 //	@Router			/disk/{disk_id} [delete]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Delete a disk\nclient.disks.delete(disk_id='disk-uuid')\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Delete a disk\nawait client.disks.delete('disk-uuid');\n","label":"JavaScript"}]
 func (h *DiskHandler) DeleteDisk(c *gin.Context) {
@@ -118,10 +169,68 @@ func (h *DiskHandler) DeleteDisk(c *gin.Context) {
 		return
 	}
 
+	req := DeleteDiskReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if req.DryRun {
+		preview, err := h.svc.PreviewDelete(c.Request.Context(), project.ID, diskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+			return
+		}
+		c.JSON(http.StatusOK, serializer.Response{Data: preview})
+		return
+	}
+
 	if err := h.svc.Delete(c.Request.Context(), project.ID, diskID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "disk not found", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, serializer.Response{})
 }
+
+// RestoreDisk godoc
+//
+//	@Summary		Restore disk
+//	@Description	Restore a deleted disk with its artifacts. Only disks whose grace period has not ended can be restored. A restored default disk comes back as a regular disk.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Disk}
+//	@Router			/disk/{disk_id}/restore [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Restore a deleted disk\ndisk = client.disks.restore(disk_id='disk-uuid')\nprint(f\"Restored disk: {disk.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Restore a deleted disk\nconst disk = await client.disks.restore('disk-uuid');\nconsole.log(`Restored disk: ${disk.id}`);\n","label":"JavaScript"}]
+func (h *DiskHandler) RestoreDisk(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	disk, err := h.svc.Restore(c.Request.Context(), project.ID, diskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "deleted disk not found", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: disk})
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockDiskService is a mock implementation of DiskService
@@ -22,7 +24,23 @@ type MockDiskService struct {
 	mock.Mock
 }
 
-func (m *MockDiskService) Create(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+func (m *MockDiskService) Create(ctx context.Context, projectID uuid.UUID, pathAddressedKeys bool) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, pathAddressedKeys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskService) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskService) GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
 	args := m.Called(ctx, projectID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,6 +53,27 @@ func (m *MockDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskI
 	return args.Error(0)
 }
 
+func (m *MockDiskService) Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDiskService) PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*service.DeleteDiskPreview, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.DeleteDiskPreview), args.Error(1)
+}
+
 func (m *MockDiskService) List(ctx context.Context, in service.ListDisksInput) (*service.ListDisksOutput, error) {
 	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
@@ -67,6 +106,7 @@ func TestDiskHandler_CreateDisk(t *testing.T) {
 
 	tests := []struct {
 		name           string
+		body           string
 		setup          func(*MockDiskService)
 		expectedStatus int
 		expectedError  string
@@ -74,14 +114,36 @@ func TestDiskHandler_CreateDisk(t *testing.T) {
 		{
 			name: "successful disk creation",
 			setup: func(svc *MockDiskService) {
-				svc.On("Create", mock.Anything, projectID).Return(disk, nil)
+				svc.On("Create", mock.Anything, projectID, false).Return(disk, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "empty json body",
+			body: " ",
+			setup: func(svc *MockDiskService) {
+				svc.On("Create", mock.Anything, projectID, false).Return(disk, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "path addressed keys",
+			body: `{"path_addressed_keys": true}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Create", mock.Anything, projectID, true).Return(disk, nil)
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name:           "invalid body",
+			body:           `{"path_addressed_keys": "yes"}`,
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "service error",
 			setup: func(svc *MockDiskService) {
-				svc.On("Create", mock.Anything, projectID).Return(nil, errors.New("service error"))
+				svc.On("Create", mock.Anything, projectID, false).Return(nil, errors.New("service error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -100,6 +162,10 @@ func TestDiskHandler_CreateDisk(t *testing.T) {
 			})
 
 			req := httptest.NewRequest("POST", "/disk", nil)
+			if tt.body != "" {
+				req = httptest.NewRequest("POST", "/disk", strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+			}
 			w := httptest.NewRecorder()
 
 			router.ServeHTTP(w, req)
@@ -125,6 +191,65 @@ func TestDiskHandler_CreateDisk(t *testing.T) {
 	}
 }
 
+func TestDiskHandler_GetDefaultDisk(t *testing.T) {
+	projectID := uuid.New()
+	disk := createTestDisk()
+	disk.ProjectID = projectID
+	disk.IsDefault = true
+
+	tests := []struct {
+		name           string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name: "default disk",
+			setup: func(svc *MockDiskService) {
+				svc.On("GetOrCreateDefault", mock.Anything, projectID).Return(disk, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockDiskService) {
+				svc.On("GetOrCreateDefault", mock.Anything, projectID).Return(nil, errors.New("service error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService)
+
+			router := setupDiskRouter()
+			router.GET("/disk/default", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.GetDefaultDisk(c)
+			})
+
+			req := httptest.NewRequest("GET", "/disk/default", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]interface{}
+				err := sonic.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				data := response["data"].(map[string]interface{})
+				assert.Equal(t, disk.ID.String(), data["id"])
+				assert.Equal(t, true, data["is_default"])
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDiskHandler_ListDisks(t *testing.T) {
 	projectID := uuid.New()
 	disk1 := createTestDisk()
@@ -273,3 +398,103 @@ func TestDiskHandler_DeleteDisk(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskHandler_RestoreDisk(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	tests := []struct {
+		name           string
+		diskID         string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name:   "deleted disk",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService) {
+				svc.On("Restore", mock.Anything, projectID, diskID).Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "disk not deleted or already purged",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService) {
+				svc.On("Restore", mock.Anything, projectID, diskID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid disk ID",
+			diskID:         "invalid-uuid",
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService)
+
+			router := setupDiskRouter()
+			router.POST("/disk/:disk_id/restore", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.RestoreDisk(c)
+			})
+
+			req := httptest.NewRequest("POST", "/disk/"+tt.diskID+"/restore", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data model.Disk `json:"data"`
+				}
+				assert.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, diskID, response.Data.ID)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskHandler_DeleteDisk_DryRun(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	mockService := &MockDiskService{}
+	mockService.On("PreviewDelete", mock.Anything, projectID, diskID).Return(&service.DeleteDiskPreview{
+		ArtifactCount:    3,
+		FreedObjectCount: 2,
+	}, nil)
+	handler := NewDiskHandler(mockService)
+
+	router := setupDiskRouter()
+	router.DELETE("/disk/:disk_id", func(c *gin.Context) {
+		c.Set("project", &model.Project{ID: projectID})
+		handler.DeleteDisk(c)
+	})
+
+	req := httptest.NewRequest("DELETE", "/disk/"+diskID.String()+"?dry_run=true", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := sonic.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, float64(3), data["artifact_count"])
+	assert.Equal(t, float64(2), data["freed_object_count"])
+
+	// Real deletion must not run on dry run
+	mockService.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+type ProjectHandler struct {
+	svc service.ProjectService
+}
+
+func NewProjectHandler(s service.ProjectService) *ProjectHandler {
+	return &ProjectHandler{svc: s}
+}
+
+type RotateProjectKeyResp struct {
+	Token string `json:"token"`
+}
+
+// RotateKey godoc
+//
+//	@Summary		Rotate project secret key
+//	@Description	Generate a new secret key for the project and return its bearer token. The token is only returned once; the previous token stops working immediately. The key of the default project is re-synced from the server configuration on restart.
+//	@Tags			project
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.RotateProjectKeyResp}
+//	@Router			/project/rotate-key [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Rotate the project key; store the new token, it is only shown once\nresult = client.project.rotate_key()\nprint(result.token)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Rotate the project key; store the new token, it is only shown once\nconst result = await client.project.rotateKey();\nconsole.log(result.token);\n","label":"JavaScript"}]
+func (h *ProjectHandler) RotateKey(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	token, err := h.svc.RotateProjectSecret(c.Request.Context(), project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	// The token must not be cached anywhere along the way
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, serializer.Response{Data: RotateProjectKeyResp{Token: token}})
+}
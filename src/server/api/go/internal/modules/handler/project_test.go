@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockProjectService is a mock implementation of ProjectService
+type MockProjectService struct {
+	mock.Mock
+}
+
+func (m *MockProjectService) RotateProjectSecret(ctx context.Context, projectID uuid.UUID) (string, error) {
+	args := m.Called(ctx, projectID)
+	return args.String(0), args.Error(1)
+}
+
+func TestProjectHandler_RotateKey(t *testing.T) {
+	projectID := uuid.New()
+
+	tests := []struct {
+		name           string
+		setup          func(*MockProjectService)
+		expectedStatus int
+		expectedToken  string
+	}{
+		{
+			name: "rotate",
+			setup: func(svc *MockProjectService) {
+				svc.On("RotateProjectSecret", mock.Anything, projectID).Return("sk-ac-new", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedToken:  "sk-ac-new",
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockProjectService) {
+				svc.On("RotateProjectSecret", mock.Anything, projectID).Return("", errors.New("tx failed"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockProjectService{}
+			tt.setup(mockService)
+			handler := NewProjectHandler(mockService)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/project/rotate-key", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.RotateKey(c)
+			})
+
+			req := httptest.NewRequest("POST", "/project/rotate-key", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedToken != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedToken)
+				assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,17 +24,20 @@ import (
 	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
 	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type SessionHandler struct {
 	svc        service.SessionService
 	coreClient *httpclient.CoreClient
+	limits     normalizer.Limits
 }
 
-func NewSessionHandler(s service.SessionService, coreClient *httpclient.CoreClient) *SessionHandler {
+func NewSessionHandler(s service.SessionService, coreClient *httpclient.CoreClient, limits normalizer.Limits) *SessionHandler {
 	return &SessionHandler{
 		svc:        s,
 		coreClient: coreClient,
+		limits:     limits,
 	}
 }
 
@@ -301,13 +308,13 @@ func (h *SessionHandler) ConnectToSpace(c *gin.Context) {
 
 type StoreMessageReq struct {
 	Blob   interface{} `form:"blob" json:"blob" binding:"required"`
-	Format string      `form:"format" json:"format" binding:"omitempty,oneof=acontext openai anthropic" example:"openai" enums:"acontext,openai,anthropic"`
+	Format string      `form:"format" json:"format" binding:"omitempty,oneof=acontext openai anthropic vercel" example:"openai" enums:"acontext,openai,anthropic,vercel"`
 }
 
 // StoreMessage godoc
 //
 //	@Summary		Store message to session
-//	@Description	Supports JSON and multipart/form-data. In multipart mode: the payload is a JSON string placed in a form field. The format parameter indicates the format of the input message (default: openai, same as GET). The blob field should be a complete message object: for openai, use OpenAI ChatCompletionMessageParam format (with role and content); for anthropic, use Anthropic MessageParam format (with role and content); for acontext (internal), use {role, parts} format.
+//	@Description	Supports JSON and multipart/form-data. In multipart mode: the payload is a JSON string placed in a form field. The format parameter indicates the format of the input message (default: openai, same as GET). The blob field should be a complete message object: for openai, use OpenAI ChatCompletionMessageParam format (with role and content); for anthropic, use Anthropic MessageParam format (with role and content); for vercel, use a Vercel AI SDK UI message (with role and parts); for acontext (internal), use {role, parts} format.
 //	@Tags			session
 //	@Accept			json
 //	@Accept			multipart/form-data
@@ -370,10 +377,10 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 	switch format {
 	case model.FormatAcontext:
 		// Parse and validate using Acontext normalizer
-		norm := &normalizer.AcontextNormalizer{}
+		norm := &normalizer.AcontextNormalizer{Limits: h.limits}
 		normalizedRole, normalizedParts, normalizedMeta, err = norm.NormalizeFromAcontextMessage(blobJSON)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, serializer.ParamErr("failed to normalize Acontext message", err))
+			normalizeErr(c, "failed to normalize Acontext message", err)
 			return
 		}
 
@@ -386,10 +393,10 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 
 	case model.FormatOpenAI:
 		// Parse and validate using official OpenAI SDK
-		norm := &normalizer.OpenAINormalizer{}
+		norm := &normalizer.OpenAINormalizer{Limits: h.limits}
 		normalizedRole, normalizedParts, normalizedMeta, err = norm.NormalizeFromOpenAIMessage(blobJSON)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, serializer.ParamErr("failed to normalize OpenAI message", err))
+			normalizeErr(c, "failed to normalize OpenAI message", err)
 			return
 		}
 
@@ -402,10 +409,10 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 
 	case model.FormatAnthropic:
 		// Parse and validate using official Anthropic SDK
-		norm := &normalizer.AnthropicNormalizer{}
+		norm := &normalizer.AnthropicNormalizer{Limits: h.limits}
 		normalizedRole, normalizedParts, normalizedMeta, err = norm.NormalizeFromAnthropicMessage(blobJSON)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, serializer.ParamErr("failed to normalize Anthropic message", err))
+			normalizeErr(c, "failed to normalize Anthropic message", err)
 			return
 		}
 
@@ -416,6 +423,14 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 			}
 		}
 
+	case model.FormatVercel:
+		norm := &normalizer.VercelNormalizer{Limits: h.limits}
+		normalizedRole, normalizedParts, normalizedMeta, err = norm.NormalizeFromVercelMessage(blobJSON)
+		if err != nil {
+			normalizeErr(c, "failed to normalize Vercel message", err)
+			return
+		}
+
 	default:
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("unsupported format", fmt.Errorf("format %s is not supported", format)))
 		return
@@ -468,11 +483,228 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, serializer.Response{Data: out})
 }
 
+const (
+	// ingestBatchSize is the number of messages stored per transaction during an ingest
+	ingestBatchSize = 100
+	// maxIngestLineSize bounds a single NDJSON line so one huge line cannot exhaust memory
+	maxIngestLineSize = 8 << 20
+	// maxIngestErrors bounds the per-line errors reported back; failed still counts all of them
+	maxIngestErrors = 100
+)
+
+type IngestMessagesReq struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=acontext openai anthropic vercel" example:"openai" enums:"acontext,openai,anthropic,vercel"`
+	Strict bool   `form:"strict" json:"strict" example:"false"`
+	// SkipDuplicates skips messages whose content the session already has, for re-imports
+	SkipDuplicates bool `form:"skip_duplicates" json:"skip_duplicates" example:"false"`
+}
+
+// ingestLine is one line of an NDJSON ingest body
+type ingestLine struct {
+	Format  string          `json:"format"`
+	Message json.RawMessage `json:"message"`
+}
+
+type IngestLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type IngestMessagesResp struct {
+	Ingested int               `json:"ingested"`
+	Skipped  int               `json:"skipped"` // Duplicates skipped with skip_duplicates
+	Failed   int               `json:"failed"`
+	Errors   []IngestLineError `json:"errors"`
+}
+
+func (r *IngestMessagesResp) fail(line int, err error) {
+	r.Failed++
+	if len(r.Errors) < maxIngestErrors {
+		r.Errors = append(r.Errors, IngestLineError{Line: line, Error: err.Error()})
+	}
+}
+
+// IngestMessages godoc
+//
+//	@Summary		Ingest messages from NDJSON
+//	@Description	Bulk import a conversation log. The body is read as a stream of application/x-ndjson lines, each `{"format": "openai", "message": {...}}`; format falls back to the format query parameter (default: openai). Messages are normalized like in StoreMessage and stored in order, in batches of 100 per transaction. Lines that fail are reported with their line number and skipped. With strict=true the import stops at the first failing line and the pending batch is not stored; batches stored before that are kept. With skip_duplicates=true, messages with the same role and parts as a message of the session, or as an earlier line, are skipped and counted in skipped; messages stored before content hashing was introduced are not matched. File parts are not supported.
+//	@Tags			session
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			session_id	path	string	true	"Session ID"	Format(uuid)
+//	@Param			format		query	string	false	"Default format of the lines, one of acontext, openai, anthropic, vercel"	example(openai)
+//	@Param			strict		query	boolean	false	"Stop at the first failing line"	example(false)
+//	@Param			skip_duplicates	query	boolean	false	"Skip messages the session already has"	example(false)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.IngestMessagesResp}
+//	@Failure		400	{object}	serializer.Response{data=handler.IngestMessagesResp}
+//	@Router			/session/{session_id}/messages/ingest [post]
+//	@x-code-samples	[{"lang":"python","source":"import json\nimport requests\n\nlines = [\n    {'format': 'openai', 'message': {'role': 'user', 'content': 'Hello!'}},\n    {'format': 'openai', 'message': {'role': 'assistant', 'content': 'Hi there!'}},\n]\nbody = '\\n'.join(json.dumps(line) for line in lines)\n\nresp = requests.post(\n    'https://api.acontext.io/api/v1/session/session-uuid/messages/ingest',\n    headers={'Authorization': 'Bearer sk_project_token', 'Content-Type': 'application/x-ndjson'},\n    data=body,\n)\nprint(resp.json()['data'])\n","label":"Python"},{"lang":"javascript","source":"const lines = [\n  { format: 'openai', message: { role: 'user', content: 'Hello!' } },\n  { format: 'openai', message: { role: 'assistant', content: 'Hi there!' } },\n];\n\nconst resp = await fetch('https://api.acontext.io/api/v1/session/session-uuid/messages/ingest', {\n  method: 'POST',\n  headers: { Authorization: 'Bearer sk_project_token', 'Content-Type': 'application/x-ndjson' },\n  body: lines.map((line) => JSON.stringify(line)).join('\\n'),\n});\nconsole.log((await resp.json()).data);\n","label":"JavaScript"}]
+func (h *SessionHandler) IngestMessages(c *gin.Context) {
+	req := IngestMessagesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	if req.Format == "" {
+		req.Format = string(model.FormatOpenAI)
+	}
+
+	if ct := c.ContentType(); ct != "application/x-ndjson" {
+		c.JSON(http.StatusUnsupportedMediaType, serializer.Err(http.StatusUnsupportedMediaType, "content type must be application/x-ndjson", fmt.Errorf("got %q", ct)))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := IngestMessagesResp{Errors: []IngestLineError{}}
+	batch := make([]service.IngestMessageIn, 0, ingestBatchSize)
+	batchLines := make([]int, 0, ingestBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() {
+			batch = batch[:0]
+			batchLines = batchLines[:0]
+		}()
+		msgs, err := h.svc.IngestMessages(ctx, project.ID, sessionID, batch, req.SkipDuplicates)
+		if err != nil {
+			for _, line := range batchLines {
+				resp.fail(line, err)
+			}
+			return err
+		}
+		resp.Ingested += len(msgs)
+		resp.Skipped += len(batch) - len(msgs)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxIngestLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		msg, err := normalizeIngestLine(raw, model.MessageFormat(req.Format), h.limits)
+		if err != nil {
+			resp.fail(lineNo, err)
+			if req.Strict {
+				c.JSON(http.StatusBadRequest, serializer.Response{Code: http.StatusBadRequest, Msg: "ingest stopped at a failing line", Data: resp})
+				return
+			}
+			continue
+		}
+
+		batch = append(batch, msg)
+		batchLines = append(batchLines, lineNo)
+		if len(batch) < ingestBatchSize {
+			continue
+		}
+		if err := flush(); err != nil && (req.Strict || ctx.Err() != nil) {
+			c.JSON(http.StatusInternalServerError, serializer.Response{Code: http.StatusInternalServerError, Msg: "failed to store messages", Data: resp})
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// The stream cannot be resumed past a broken or oversized line
+		resp.fail(lineNo+1, err)
+		c.JSON(http.StatusBadRequest, serializer.Response{Code: http.StatusBadRequest, Msg: "failed to read ingest body", Data: resp})
+		return
+	}
+
+	if err := flush(); err != nil && req.Strict {
+		c.JSON(http.StatusInternalServerError, serializer.Response{Code: http.StatusInternalServerError, Msg: "failed to store messages", Data: resp})
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: resp})
+}
+
+// normalizeErr answers 413 for messages over the normalizer limits and 400 for other invalid messages
+func normalizeErr(c *gin.Context, msg string, err error) {
+	if errors.Is(err, normalizer.ErrMessageTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, msg, err))
+		return
+	}
+	c.JSON(http.StatusBadRequest, serializer.ParamErr(msg, err))
+}
+
+// normalizeIngestLine parses one NDJSON line and normalizes its message, using
+// defaultFormat when the line has none
+func normalizeIngestLine(raw []byte, defaultFormat model.MessageFormat, limits normalizer.Limits) (service.IngestMessageIn, error) {
+	var line ingestLine
+	if err := sonic.Unmarshal(raw, &line); err != nil {
+		return service.IngestMessageIn{}, fmt.Errorf("invalid json: %w", err)
+	}
+	if len(line.Message) == 0 {
+		return service.IngestMessageIn{}, errors.New("message is required")
+	}
+
+	format := defaultFormat
+	if line.Format != "" {
+		f, err := converter.ValidateFormat(line.Format)
+		if err != nil {
+			return service.IngestMessageIn{}, err
+		}
+		format = f
+	}
+
+	var (
+		role  string
+		parts []service.PartIn
+		meta  map[string]interface{}
+		err   error
+	)
+	switch format {
+	case model.FormatAcontext:
+		role, parts, meta, err = (&normalizer.AcontextNormalizer{Limits: limits}).NormalizeFromAcontextMessage(line.Message)
+	case model.FormatOpenAI:
+		role, parts, meta, err = (&normalizer.OpenAINormalizer{Limits: limits}).NormalizeFromOpenAIMessage(line.Message)
+	case model.FormatAnthropic:
+		role, parts, meta, err = (&normalizer.AnthropicNormalizer{Limits: limits}).NormalizeFromAnthropicMessage(line.Message)
+	case model.FormatVercel:
+		role, parts, meta, err = (&normalizer.VercelNormalizer{Limits: limits}).NormalizeFromVercelMessage(line.Message)
+	default:
+		err = fmt.Errorf("format %s is not supported", format)
+	}
+	if err != nil {
+		return service.IngestMessageIn{}, fmt.Errorf("failed to normalize %s message: %w", format, err)
+	}
+
+	if len(parts) == 0 {
+		return service.IngestMessageIn{}, errors.New("message must contain at least one part")
+	}
+	for i, p := range parts {
+		if p.FileField != "" {
+			return service.IngestMessageIn{}, fmt.Errorf("parts[%d]: file parts are not supported when ingesting", i)
+		}
+	}
+
+	return service.IngestMessageIn{Role: role, Parts: parts, MessageMeta: meta}, nil
+}
+
 type GetMessagesReq struct {
 	Limit              *int   `form:"limit" json:"limit" binding:"omitempty,min=0,max=200" example:"20"`
 	Cursor             string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
 	WithAssetPublicURL bool   `form:"with_asset_public_url,default=true" json:"with_asset_public_url" example:"true"`
-	Format             string `form:"format,default=openai" json:"format" binding:"omitempty,oneof=acontext openai anthropic" example:"openai" enums:"acontext,openai,anthropic"`
+	Format             string `form:"format,default=openai" json:"format" binding:"omitempty,oneof=acontext openai anthropic vercel" example:"openai" enums:"acontext,openai,anthropic,vercel"`
 	TimeDesc           bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
 	EditStrategies     string `form:"edit_strategies" json:"edit_strategies" example:"[{\"type\":\"remove_tool_result\",\"params\":{\"keep_recent_n_tool_results\":3}}]"`
 }
@@ -480,7 +712,7 @@ type GetMessagesReq struct {
 // GetMessages godoc
 //
 //	@Summary		Get messages from session
-//	@Description	Get messages from session. Default format is openai. Can convert to acontext (original) or anthropic format.
+//	@Description	Get messages from session. Default format is openai. Can convert to acontext (original), anthropic or vercel format.
 //	@Tags			session
 //	@Accept			json
 //	@Produce		json
@@ -488,7 +720,7 @@ type GetMessagesReq struct {
 //	@Param			limit					query	integer	false	"Limit of messages to return. Max 200. If limit is 0 or not provided, all messages will be returned. \n\nWARNING!\n Use `limit` only for read-only/display purposes (pagination, viewing). Do NOT use `limit` to truncate messages before sending to LLM as it may cause tool-call and tool-result unpairing issues. Instead, use the `token_limit` edit strategy in `edit_strategies` parameter to safely manage message context size."
 //	@Param			cursor					query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
 //	@Param			with_asset_public_url	query	string	false	"Whether to return asset public url, default is true"								example(true)
-//	@Param			format					query	string	false	"Format to convert messages to: acontext (original), openai (default), anthropic."	enums(acontext,openai,anthropic)
+//	@Param			format					query	string	false	"Format to convert messages to: acontext (original), openai (default), anthropic, vercel."	enums(acontext,openai,anthropic,vercel)
 //	@Param			time_desc				query	string	false	"Order by created_at descending if true, ascending if false (default false)"		example(false)
 //	@Param			edit_strategies			query	string	false	"JSON array of edit strategies to apply before format conversion"					example([{"type":"remove_tool_result","params":{"keep_recent_n_tool_results":3}}])
 //	@Security		BearerAuth
@@ -550,6 +782,7 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 	}
 
 	convertedOut, err := converter.GetConvertedMessagesOutput(
+		c.Request.Context(),
 		out.Items,
 		format,
 		out.PublicURLs,
@@ -564,6 +797,80 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, serializer.Response{Data: convertedOut})
 }
 
+type ExportMessagesReq struct {
+	Format string     `form:"format,default=openai" json:"format" binding:"omitempty,oneof=acontext openai anthropic vercel" example:"openai" enums:"acontext,openai,anthropic,vercel"`
+	Limit  *int       `form:"limit" json:"limit" binding:"omitempty,min=1" example:"100"`
+	Since  *time.Time `form:"since" json:"since" time_format:"2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	// ExcludeEphemeral leaves out messages marked with meta.ephemeral=true
+	ExcludeEphemeral bool `form:"exclude_ephemeral" json:"exclude_ephemeral" example:"true"`
+}
+
+// ExportMessages godoc
+//
+//	@Summary		Export session messages
+//	@Description	Export the session transcript from old to new in a provider-ready format, with public URLs resolved for asset parts. Leading tool results whose tool calls fall outside the `limit`/`since` window are dropped so the transcript stays valid for replay.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id			path	string	true	"Session ID"																		format(uuid)
+//	@Param			format				query	string	false	"Format to export messages in: acontext (original), openai (default), anthropic, vercel."	enums(acontext,openai,anthropic,vercel)
+//	@Param			limit				query	integer	false	"Only export the most recent N messages"											example(100)
+//	@Param			since				query	string	false	"Only export messages created at or after this RFC3339 time"						example(2025-01-01T00:00:00Z)
+//	@Param			exclude_ephemeral	query	boolean	false	"Leave out messages whose meta has ephemeral=true"									example(true)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.GetMessagesOutput}
+//	@Router			/session/{session_id}/messages/export [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Export the last 100 messages in anthropic format\ntranscript = client.sessions.export_messages(\n    session_id='session-uuid',\n    format='anthropic',\n    limit=100\n)\nprint(len(transcript.items))\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Export the last 100 messages in anthropic format\nconst transcript = await client.sessions.exportMessages('session-uuid', {\n  format: 'anthropic',\n  limit: 100\n});\nconsole.log(transcript.items.length);\n","label":"JavaScript"}]
+func (h *SessionHandler) ExportMessages(c *gin.Context) {
+	req := ExportMessagesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	formatStr := req.Format
+	if formatStr == "" {
+		formatStr = string(model.FormatOpenAI)
+	}
+	format, err := converter.ValidateFormat(formatStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid format", err))
+		return
+	}
+
+	limit := 0
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+
+	out, err := h.svc.ExportMessages(c.Request.Context(), service.ExportMessagesInput{
+		SessionID:   sessionID,
+		Limit:       limit,
+		Since:       req.Since,
+		AssetExpire: time.Hour * 24,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	convertedOut, err := converter.GetConvertedMessagesOutputWithOptions(c.Request.Context(), out.Items, format, out.PublicURLs, "", false, converter.ConvertOptions{
+		ExcludeEphemeral: req.ExcludeEphemeral,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("failed to convert messages", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: convertedOut})
+}
+
 // SessionFlush godoc
 //
 //	@Summary		Flush session
@@ -673,3 +980,254 @@ func (h *SessionHandler) GetTokenCounts(c *gin.Context) {
 		TotalTokens: totalTokens,
 	}})
 }
+
+type TokenCountReq struct {
+	Model string `form:"model" json:"model" example:"gpt-4o"` // Model whose tokenizer is used
+}
+
+type TokenCountResp struct {
+	TotalTokens int    `json:"total_tokens"`
+	Tokenizer   string `json:"tokenizer" example:"o200k_base"` // Encoding used, or "heuristic" for the estimate
+}
+
+// tokenizerHeuristic names the estimate used for models without a known encoding
+const tokenizerHeuristic = "heuristic"
+
+// tokenizerForModel returns the tokenizer of a model and its name. Exact counts need a known
+// encoding; other models, or no model, fall back to the estimate.
+func tokenizerForModel(modelName string) (tokenizer.Tokenizer, string) {
+	if modelName != "" {
+		if bpe, err := tokenizer.ForModel(modelName); err == nil {
+			return bpe, bpe.Encoding()
+		}
+	}
+	return tokenizer.Heuristic{}, tokenizerHeuristic
+}
+
+// CountSessionTokens godoc
+//
+//	@Summary		Count session tokens for a model
+//	@Description	Count the tokens of the text, tool-call and tool-result parts of a session with the tokenizer of a model. OpenAI models are counted exactly with their encoding (cl100k_base or o200k_base); other models, or no model, get an estimate of one token per four characters. The tokenizer used is returned.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path	string	true	"Session ID"											format(uuid)
+//	@Param			model		query	string	false	"Model whose tokenizer is used, e.g. gpt-4o (optional)"	example(gpt-4o)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.TokenCountResp}
+//	@Router			/session/{session_id}/token-count [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Count the tokens of a session for a model\nresult = client.sessions.count_tokens(session_id='session-uuid', model='gpt-4o')\nprint(f\"{result.total_tokens} tokens ({result.tokenizer})\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Count the tokens of a session for a model\nconst result = await client.sessions.countTokens('session-uuid', { model: 'gpt-4o' });\nconsole.log(`${result.total_tokens} tokens (${result.tokenizer})`);\n","label":"JavaScript"}]
+func (h *SessionHandler) CountSessionTokens(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := TokenCountReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	tok, name := tokenizerForModel(req.Model)
+
+	messages, err := h.svc.GetAllMessages(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("failed to get messages", err))
+		return
+	}
+
+	total := 0
+	for _, msg := range messages {
+		n, err := tok.CountMessageTokens(msg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, serializer.Err(http.StatusInternalServerError, "failed to count tokens", err))
+			return
+		}
+		total += n
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: TokenCountResp{TotalTokens: total, Tokenizer: name}})
+}
+
+type SessionSummaryResp struct {
+	service.SessionSummary
+	Tokenizer string `json:"tokenizer" example:"heuristic"` // Encoding used for total_tokens, or "heuristic" for the estimate
+}
+
+// GetSessionSummary godoc
+//
+//	@Summary		Get session summary
+//	@Description	Get message statistics of a session for dashboards without exporting the transcript: the number of messages, per role (system and developer messages kept from OpenAI are counted under their own role), the total tokens, the number of tool calls and the number of attached artifacts. Tokens are counted like in token-count.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	path	string	true	"Session ID"											format(uuid)
+//	@Param			model		query	string	false	"Model whose tokenizer is used, e.g. gpt-4o (optional)"	example(gpt-4o)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.SessionSummaryResp}
+//	@Router			/session/{session_id}/summary [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get the statistics of a session\nsummary = client.sessions.get_summary(session_id='session-uuid')\nprint(f\"{summary.message_count} messages, {summary.total_tokens} tokens, {summary.tool_call_count} tool calls\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get the statistics of a session\nconst summary = await client.sessions.getSummary('session-uuid');\nconsole.log(`${summary.message_count} messages, ${summary.total_tokens} tokens, ${summary.tool_call_count} tool calls`);\n","label":"JavaScript"}]
+func (h *SessionHandler) GetSessionSummary(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := TokenCountReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	tok, name := tokenizerForModel(req.Model)
+
+	summary, err := h.svc.GetSummary(c.Request.Context(), sessionID, tok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: SessionSummaryResp{SessionSummary: *summary, Tokenizer: name}})
+}
+
+type DiffSessionsReq struct {
+	A string `form:"a" json:"a" binding:"required,uuid" format:"uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	B string `form:"b" json:"b" binding:"required,uuid" format:"uuid" example:"123e4567-e89b-12d3-a456-426614174001"`
+}
+
+// DiffSessions godoc
+//
+//	@Summary		Diff sessions
+//	@Description	Compare the messages of two sessions of the project, e.g. two runs of an A/B test. Messages are aligned by their role and parts, keeping their order; message meta and timestamps are not compared. Each entry is a message only in a (removed), only in b (added), or a message of a replaced by one of the same role in b (changed), with its index in the chronological messages of each session. Sessions with more than 1000 messages cannot be compared.
+//	@Tags			session
+//	@Accept			json
+//	@Produce		json
+//	@Param			a	query	string	true	"ID of the first session"	format(uuid)
+//	@Param			b	query	string	true	"ID of the second session"	format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.SessionDiff}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/session/diff [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Compare two sessions\ndiff = client.sessions.diff(a='session-a-uuid', b='session-b-uuid')\nprint(f\"{diff.unchanged} messages in common\")\nfor entry in diff.entries:\n    print(entry.op, entry.index_a, entry.index_b)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Compare two sessions\nconst diff = await client.sessions.diff('session-a-uuid', 'session-b-uuid');\nconsole.log(`${diff.unchanged} messages in common`);\nfor (const entry of diff.entries) {\n  console.log(entry.op, entry.index_a, entry.index_b);\n}\n","label":"JavaScript"}]
+func (h *SessionHandler) DiffSessions(c *gin.Context) {
+	req := DiffSessionsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	ids := [2]uuid.UUID{uuid.MustParse(req.A), uuid.MustParse(req.B)}
+	for _, id := range ids {
+		session, err := h.svc.GetByID(c.Request.Context(), &model.Session{ID: id})
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.ProjectID != project.ID) {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "session not found", fmt.Errorf("session %s not found", id)))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+			return
+		}
+	}
+
+	diff, err := h.svc.DiffSessions(c.Request.Context(), ids[0], ids[1])
+	if err != nil {
+		if errors.Is(err, service.ErrSessionDiffTooLarge) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: diff})
+}
+
+type GetPartContentReq struct {
+	Expire int `form:"expire,default=3600" json:"expire" binding:"min=1" example:"3600"` // Expire time in seconds for the presigned URL
+}
+
+// GetMessagePartContent godoc
+//
+//	@Summary		Get message part content
+//	@Description	Redirect to a presigned URL of the asset behind an image, audio, video or file part of a message. index is the position of the part in the message. Parts without an asset, out of range indexes and assets the project does not reference return 404.
+//	@Tags			session
+//	@Produce		json
+//	@Param			session_id	path	string	true	"Session ID"									format(uuid)
+//	@Param			message_id	path	string	true	"Message ID"									format(uuid)
+//	@Param			index		path	int		true	"Index of the part in the message"				example(0)
+//	@Param			expire		query	int		false	"Expire time in seconds for the presigned URL (default: 3600)"	example(3600)
+//	@Security		BearerAuth
+//	@Success		302
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		404	{object}	serializer.Response
+//	@Router			/session/{session_id}/message/{message_id}/part/{index}/content [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get a presigned URL of the image in the first part of a message\nurl = client.sessions.get_part_content_url(\n    session_id='session-uuid',\n    message_id='message-uuid',\n    index=0\n)\nprint(url)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get a presigned URL of the image in the first part of a message\nconst url = await client.sessions.getPartContentUrl('session-uuid', 'message-uuid', 0);\nconsole.log(url);\n","label":"JavaScript"}]
+func (h *SessionHandler) GetMessagePartContent(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	messageID, err := uuid.Parse(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := GetPartContentReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	session, err := h.svc.GetByID(c.Request.Context(), &model.Session{ID: sessionID})
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.ProjectID != project.ID) {
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "session not found", fmt.Errorf("session %s not found", sessionID)))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	url, err := h.svc.GetPartContentURL(c.Request.Context(), service.GetPartContentInput{
+		ProjectID: project.ID,
+		SessionID: sessionID,
+		MessageID: messageID,
+		Index:     index,
+		Expire:    time.Duration(req.Expire) * time.Second,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "message not found", err))
+		case errors.Is(err, service.ErrPartAssetNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "part content not found", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, url.URL)
+}
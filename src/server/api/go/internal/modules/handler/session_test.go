@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
@@ -15,12 +18,14 @@ import (
 	"github.com/memodb-io/Acontext/internal/infra/httpclient"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
 	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockSessionService is a mock implementation of SessionService
@@ -59,6 +64,14 @@ func (m *MockSessionService) StoreMessage(ctx context.Context, in service.StoreM
 	return args.Get(0).(*model.Message), args.Error(1)
 }
 
+func (m *MockSessionService) IngestMessages(ctx context.Context, projectID uuid.UUID, sessionID uuid.UUID, in []service.IngestMessageIn, skipDuplicates bool) ([]*model.Message, error) {
+	args := m.Called(ctx, projectID, sessionID, in, skipDuplicates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Message), args.Error(1)
+}
+
 func (m *MockSessionService) GetMessages(ctx context.Context, in service.GetMessagesInput) (*service.GetMessagesOutput, error) {
 	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
@@ -83,6 +96,38 @@ func (m *MockSessionService) GetAllMessages(ctx context.Context, sessionID uuid.
 	return args.Get(0).([]model.Message), args.Error(1)
 }
 
+func (m *MockSessionService) GetSummary(ctx context.Context, sessionID uuid.UUID, tok tokenizer.Tokenizer) (*service.SessionSummary, error) {
+	args := m.Called(ctx, sessionID, tok)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SessionSummary), args.Error(1)
+}
+
+func (m *MockSessionService) DiffSessions(ctx context.Context, sessionA uuid.UUID, sessionB uuid.UUID) (*service.SessionDiff, error) {
+	args := m.Called(ctx, sessionA, sessionB)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SessionDiff), args.Error(1)
+}
+
+func (m *MockSessionService) GetPartContentURL(ctx context.Context, in service.GetPartContentInput) (*service.PublicURL, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.PublicURL), args.Error(1)
+}
+
+func (m *MockSessionService) ExportMessages(ctx context.Context, in service.ExportMessagesInput) (*service.GetMessagesOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.GetMessagesOutput), args.Error(1)
+}
+
 func setupSessionRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
@@ -200,7 +245,7 @@ func TestSessionHandler_GetSessions(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.GET("/session", func(c *gin.Context) {
 				project := &model.Project{ID: projectID}
@@ -289,7 +334,7 @@ func TestSessionHandler_CreateSession(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.POST("/session", func(c *gin.Context) {
 				// Simulate middleware setting project information
@@ -350,7 +395,7 @@ func TestSessionHandler_DeleteSession(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.DELETE("/session/:session_id", func(c *gin.Context) {
 				project := &model.Project{ID: projectID}
@@ -422,7 +467,7 @@ func TestSessionHandler_UpdateConfigs(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.PUT("/session/:session_id/configs", handler.UpdateConfigs)
 
@@ -483,7 +528,7 @@ func TestSessionHandler_GetConfigs(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.GET("/session/:session_id/configs", handler.GetConfigs)
 
@@ -558,7 +603,7 @@ func TestSessionHandler_ConnectToSpace(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.POST("/session/:session_id/connect_to_space", handler.ConnectToSpace)
 
@@ -1851,7 +1896,7 @@ func TestSessionHandler_StoreMessage(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.POST("/session/:session_id/messages", func(c *gin.Context) {
 				project := &model.Project{ID: projectID}
@@ -2241,7 +2286,7 @@ func TestSessionHandler_GetMessages(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.GET("/session/:session_id/messages", handler.GetMessages)
 
@@ -2256,6 +2301,121 @@ func TestSessionHandler_GetMessages(t *testing.T) {
 	}
 }
 
+func TestSessionHandler_ExportMessages(t *testing.T) {
+	sessionID := uuid.New()
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		sessionIDParam string
+		queryParams    string
+		setup          func(*MockSessionService)
+		expectedStatus int
+		unexpectedBody string
+	}{
+		{
+			name:           "successful export with filters",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?format=anthropic&limit=10&since=2025-01-01T00:00:00Z",
+			setup: func(svc *MockSessionService) {
+				svc.On("ExportMessages", mock.Anything, mock.MatchedBy(func(in service.ExportMessagesInput) bool {
+					return in.SessionID == sessionID && in.Limit == 10 && in.Since != nil && in.Since.Equal(since)
+				})).Return(&service.GetMessagesOutput{
+					Items: []model.Message{
+						{
+							ID:        uuid.New(),
+							SessionID: sessionID,
+							Role:      "user",
+							Parts:     []model.Part{{Type: "text", Text: "hello"}},
+						},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "default format exports everything",
+			sessionIDParam: sessionID.String(),
+			setup: func(svc *MockSessionService) {
+				svc.On("ExportMessages", mock.Anything, mock.MatchedBy(func(in service.ExportMessagesInput) bool {
+					return in.SessionID == sessionID && in.Limit == 0 && in.Since == nil
+				})).Return(&service.GetMessagesOutput{Items: []model.Message{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "exclude ephemeral messages",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?exclude_ephemeral=true",
+			setup: func(svc *MockSessionService) {
+				svc.On("ExportMessages", mock.Anything, mock.Anything).Return(&service.GetMessagesOutput{
+					Items: []model.Message{
+						{
+							ID:        uuid.New(),
+							SessionID: sessionID,
+							Role:      "user",
+							Meta:      datatypes.NewJSONType(map[string]any{"ephemeral": true}),
+							Parts:     []model.Part{{Type: "text", Text: "scratch"}},
+						},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			unexpectedBody: "scratch",
+		},
+		{
+			name:           "invalid format",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?format=unknown",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid since",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?since=yesterday",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid session ID",
+			sessionIDParam: "invalid-uuid",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "service layer error",
+			sessionIDParam: sessionID.String(),
+			setup: func(svc *MockSessionService) {
+				svc.On("ExportMessages", mock.Anything, mock.Anything).Return(nil, errors.New("export failed"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			tt.setup(mockService)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.GET("/session/:session_id/messages/export", handler.ExportMessages)
+
+			req := httptest.NewRequest("GET", "/session/"+tt.sessionIDParam+"/messages/export"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.unexpectedBody != "" {
+				assert.NotContains(t, w.Body.String(), tt.unexpectedBody)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestSessionHandler_StoreMessage_Multipart(t *testing.T) {
 	projectID := uuid.New()
 	sessionID := uuid.New()
@@ -2431,7 +2591,7 @@ func TestSessionHandler_StoreMessage_Multipart(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.POST("/session/:session_id/messages", func(c *gin.Context) {
 				project := &model.Project{ID: projectID}
@@ -2477,7 +2637,7 @@ func TestSessionHandler_StoreMessage_InvalidJSON(t *testing.T) {
 		mockService := &MockSessionService{}
 		// No setup needed as the request should fail before reaching the service
 
-		handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+		handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 		router := setupSessionRouter()
 		router.POST("/session/:session_id/messages", func(c *gin.Context) {
 			project := &model.Project{ID: projectID}
@@ -2498,6 +2658,144 @@ func TestSessionHandler_StoreMessage_InvalidJSON(t *testing.T) {
 }
 
 // TestOpenAI_ToolCalls_FieldPreservation 测试OpenAI tool_calls字段是否在往返过程中保留
+func TestSessionHandler_IngestMessages(t *testing.T) {
+	projectID := uuid.New()
+	sessionID := uuid.New()
+
+	body := strings.Join([]string{
+		`{"format":"openai","message":{"role":"user","content":"Hello!"}}`,
+		`{"format":"openai","message":`,
+		``,
+		`{"format":"anthropic","message":{"role":"assistant","content":[{"type":"text","text":"Hi there!"}]}}`,
+		`{"message":{"role":"user","parts":[{"type":"text","text":"default format"}]}}`,
+	}, "\n")
+
+	tests := []struct {
+		name           string
+		query          string
+		contentType    string
+		body           string
+		setup          func(*MockSessionService)
+		expectedStatus int
+		expected       IngestMessagesResp
+	}{
+		{
+			name:        "skips failing lines",
+			query:       "?format=acontext",
+			contentType: "application/x-ndjson",
+			body:        body,
+			setup: func(svc *MockSessionService) {
+				svc.On("IngestMessages", mock.Anything, projectID, sessionID, mock.MatchedBy(func(in []service.IngestMessageIn) bool {
+					return len(in) == 3 && in[0].Role == "user" && in[1].Role == "assistant" && in[2].Parts[0].Text == "default format"
+				}), false).Return([]*model.Message{{}, {}, {}}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expected: IngestMessagesResp{Ingested: 3, Failed: 1, Errors: []IngestLineError{
+				{Line: 2},
+			}},
+		},
+		{
+			name:           "strict mode stops at the first failing line",
+			query:          "?format=acontext&strict=true",
+			contentType:    "application/x-ndjson",
+			body:           body,
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+			expected:       IngestMessagesResp{Failed: 1, Errors: []IngestLineError{{Line: 2}}},
+		},
+		{
+			name:        "stores in batches",
+			contentType: "application/x-ndjson",
+			body:        strings.Repeat(`{"message":{"role":"user","content":"hi"}}`+"\n", ingestBatchSize+1),
+			setup: func(svc *MockSessionService) {
+				svc.On("IngestMessages", mock.Anything, projectID, sessionID, mock.MatchedBy(func(in []service.IngestMessageIn) bool {
+					return len(in) == ingestBatchSize
+				}), false).Return(make([]*model.Message, ingestBatchSize), nil).Once()
+				svc.On("IngestMessages", mock.Anything, projectID, sessionID, mock.MatchedBy(func(in []service.IngestMessageIn) bool {
+					return len(in) == 1
+				}), false).Return(make([]*model.Message, 1), nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expected:       IngestMessagesResp{Ingested: ingestBatchSize + 1, Errors: []IngestLineError{}},
+		},
+		{
+			name:        "failed batch is reported per line",
+			contentType: "application/x-ndjson",
+			body:        "{\"message\":{\"role\":\"user\",\"content\":\"hi\"}}\n\n{\"message\":{\"role\":\"assistant\",\"content\":\"yo\"}}",
+			setup: func(svc *MockSessionService) {
+				svc.On("IngestMessages", mock.Anything, projectID, sessionID, mock.Anything, false).Return(nil, errors.New("db down")).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expected:       IngestMessagesResp{Failed: 2, Errors: []IngestLineError{{Line: 1}, {Line: 3}}},
+		},
+		{
+			name:        "skips duplicates",
+			query:       "?skip_duplicates=true",
+			contentType: "application/x-ndjson",
+			body:        strings.Repeat(`{"message":{"role":"user","content":"hi"}}`+"\n", 3),
+			setup: func(svc *MockSessionService) {
+				svc.On("IngestMessages", mock.Anything, projectID, sessionID, mock.Anything, true).Return([]*model.Message{{}}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expected:       IngestMessagesResp{Ingested: 1, Skipped: 2, Errors: []IngestLineError{}},
+		},
+		{
+			name:           "wrong content type",
+			contentType:    "application/json",
+			body:           `{"message":{"role":"user","content":"hi"}}`,
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "invalid default format",
+			query:          "?format=gemini",
+			contentType:    "application/x-ndjson",
+			body:           `{"message":{"role":"user","content":"hi"}}`,
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			tt.setup(mockService)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.POST("/session/:session_id/messages/ingest", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.IngestMessages(c)
+			})
+
+			req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/ingest"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+			if tt.expected.Errors == nil && tt.expected.Ingested == 0 && tt.expected.Failed == 0 {
+				return
+			}
+
+			var resp struct {
+				Data IngestMessagesResp `json:"data"`
+			}
+			require.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expected.Ingested, resp.Data.Ingested)
+			assert.Equal(t, tt.expected.Failed, resp.Data.Failed)
+			assert.Equal(t, tt.expected.Skipped, resp.Data.Skipped)
+			require.Len(t, resp.Data.Errors, len(tt.expected.Errors))
+			for i, e := range tt.expected.Errors {
+				assert.Equal(t, e.Line, resp.Data.Errors[i].Line)
+				assert.NotEmpty(t, resp.Data.Errors[i].Error)
+			}
+		})
+	}
+}
+
 func TestOpenAI_ToolCalls_FieldPreservation(t *testing.T) {
 	projectID := uuid.New()
 	sessionID := uuid.New()
@@ -2535,7 +2833,7 @@ func TestOpenAI_ToolCalls_FieldPreservation(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -2690,7 +2988,7 @@ func TestOpenAIToAnthropic_FieldMapping(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -2814,7 +3112,7 @@ func TestAnthropicToOpenAI_FieldMapping(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -2920,7 +3218,7 @@ func TestToolResult_OpenAIToAnthropic(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -3033,7 +3331,7 @@ func TestToolResult_AnthropicToOpenAI(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -3131,7 +3429,7 @@ func TestAnthropic_CacheControl_Preservation(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -3257,7 +3555,7 @@ func TestMultipleToolCalls_Conversion(t *testing.T) {
 		HasMore: false,
 	}, nil)
 
-	handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+	handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 	router := setupSessionRouter()
 
 	router.POST("/session/:session_id/messages", func(c *gin.Context) {
@@ -3345,6 +3643,341 @@ func TestMultipleToolCalls_Conversion(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestSessionHandler_CountSessionTokens(t *testing.T) {
+	sessionID := uuid.New()
+	messages := []model.Message{
+		{ID: uuid.New(), SessionID: sessionID, Role: "user", Parts: []model.Part{{Type: "text", Text: "hello world"}}},
+		{ID: uuid.New(), SessionID: sessionID, Role: "assistant", Parts: []model.Part{{Type: "text", Text: "hello world"}}},
+	}
+
+	tests := []struct {
+		name              string
+		query             string
+		expectedTokenizer string
+		expectedTokens    int
+	}{
+		{name: "no model uses the heuristic", query: "", expectedTokenizer: "heuristic", expectedTokens: 6},
+		{name: "openai model uses its encoding", query: "?model=gpt-4o", expectedTokenizer: "o200k_base", expectedTokens: 6},
+		{name: "cl100k model", query: "?model=gpt-4", expectedTokenizer: "cl100k_base", expectedTokens: 6},
+		{name: "unknown model falls back to the heuristic", query: "?model=claude-sonnet-4", expectedTokenizer: "heuristic", expectedTokens: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			mockService.On("GetAllMessages", mock.Anything, sessionID).Return(messages, nil)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.GET("/session/:session_id/token-count", handler.CountSessionTokens)
+
+			req := httptest.NewRequest("GET", "/session/"+sessionID.String()+"/token-count"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var response struct {
+				Data TokenCountResp `json:"data"`
+			}
+			require.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedTokenizer, response.Data.Tokenizer)
+			assert.Equal(t, tt.expectedTokens, response.Data.TotalTokens)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionHandler_GetSessionSummary(t *testing.T) {
+	sessionID := uuid.New()
+	summary := &service.SessionSummary{
+		MessageCount:  3,
+		RoleCounts:    map[string]int64{"user": 2, "assistant": 1},
+		TotalTokens:   42,
+		ToolCallCount: 1,
+		ArtifactCount: 1,
+	}
+
+	tests := []struct {
+		name              string
+		query             string
+		setup             func(*MockSessionService)
+		expectedStatus    int
+		expectedTokenizer string
+	}{
+		{
+			name: "heuristic without model",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetSummary", mock.Anything, sessionID, tokenizer.Heuristic{}).Return(summary, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedTokenizer: "heuristic",
+		},
+		{
+			name:  "openai model uses its encoding",
+			query: "?model=gpt-4o",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetSummary", mock.Anything, sessionID, mock.AnythingOfType("*tokenizer.BPE")).Return(summary, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedTokenizer: "o200k_base",
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetSummary", mock.Anything, sessionID, mock.Anything).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			tt.setup(mockService)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.GET("/session/:session_id/summary", handler.GetSessionSummary)
+
+			req := httptest.NewRequest("GET", "/session/"+sessionID.String()+"/summary"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data SessionSummaryResp `json:"data"`
+				}
+				require.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, *summary, response.Data.SessionSummary)
+				assert.Equal(t, tt.expectedTokenizer, response.Data.Tokenizer)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionHandler_DiffSessions(t *testing.T) {
+	projectID := uuid.New()
+	sessionA, sessionB := uuid.New(), uuid.New()
+	changed := 3
+	diff := &service.SessionDiff{
+		SessionA:  sessionA,
+		SessionB:  sessionB,
+		CountA:    4,
+		CountB:    4,
+		Unchanged: 3,
+		Entries: []service.SessionDiffEntry{{
+			Op:     service.SessionDiffChanged,
+			IndexA: &changed,
+			IndexB: &changed,
+			A:      &model.Message{Role: "assistant", Parts: []model.Part{{Type: "text", Text: "4"}}},
+			B:      &model.Message{Role: "assistant", Parts: []model.Part{{Type: "text", Text: "four"}}},
+		}},
+	}
+	// owned serves both sessions as sessions of the project
+	owned := func(svc *MockSessionService) {
+		for _, id := range []uuid.UUID{sessionA, sessionB} {
+			svc.On("GetByID", mock.Anything, mock.MatchedBy(func(s *model.Session) bool { return s.ID == id })).
+				Return(&model.Session{ID: id, ProjectID: projectID}, nil)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockSessionService)
+		expectedStatus int
+	}{
+		{
+			name:  "single changed message",
+			query: "?a=" + sessionA.String() + "&b=" + sessionB.String(),
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("DiffSessions", mock.Anything, sessionA, sessionB).Return(diff, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing session b",
+			query:          "?a=" + sessionA.String(),
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid session id",
+			query:          "?a=" + sessionA.String() + "&b=not-a-uuid",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "session of another project",
+			query: "?a=" + sessionA.String() + "&b=" + sessionB.String(),
+			setup: func(svc *MockSessionService) {
+				svc.On("GetByID", mock.Anything, mock.MatchedBy(func(s *model.Session) bool { return s.ID == sessionA })).
+					Return(&model.Session{ID: sessionA, ProjectID: projectID}, nil)
+				svc.On("GetByID", mock.Anything, mock.MatchedBy(func(s *model.Session) bool { return s.ID == sessionB })).
+					Return(&model.Session{ID: sessionB, ProjectID: uuid.New()}, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "unknown session",
+			query: "?a=" + sessionA.String() + "&b=" + sessionB.String(),
+			setup: func(svc *MockSessionService) {
+				svc.On("GetByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "too many messages",
+			query: "?a=" + sessionA.String() + "&b=" + sessionB.String(),
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("DiffSessions", mock.Anything, sessionA, sessionB).Return(nil, service.ErrSessionDiffTooLarge)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			tt.setup(mockService)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.GET("/session/diff", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.DiffSessions(c)
+			})
+
+			req := httptest.NewRequest("GET", "/session/diff"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data service.SessionDiff `json:"data"`
+				}
+				require.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, 3, response.Data.Unchanged)
+				require.Len(t, response.Data.Entries, 1)
+				assert.Equal(t, service.SessionDiffChanged, response.Data.Entries[0].Op)
+				assert.Equal(t, "four", response.Data.Entries[0].B.Parts[0].Text)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSessionHandler_GetMessagePartContent(t *testing.T) {
+	projectID := uuid.New()
+	sessionID, messageID := uuid.New(), uuid.New()
+	path := "/session/" + sessionID.String() + "/message/" + messageID.String() + "/part/"
+	owned := func(svc *MockSessionService) {
+		svc.On("GetByID", mock.Anything, mock.MatchedBy(func(s *model.Session) bool { return s.ID == sessionID })).
+			Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		setup            func(*MockSessionService)
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name: "redirects to the presigned url",
+			url:  path + "1/content?expire=60",
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("GetPartContentURL", mock.Anything, service.GetPartContentInput{
+					ProjectID: projectID,
+					SessionID: sessionID,
+					MessageID: messageID,
+					Index:     1,
+					Expire:    time.Minute,
+				}).Return(&service.PublicURL{URL: "https://s3.example.com/assets/cat.png?sig=1"}, nil)
+			},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://s3.example.com/assets/cat.png?sig=1",
+		},
+		{
+			name:           "invalid index",
+			url:            path + "first/content",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid expire",
+			url:            path + "0/content?expire=0",
+			setup:          func(svc *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "session of another project",
+			url:  path + "0/content",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: uuid.New()}, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "unknown message",
+			url:  path + "0/content",
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("GetPartContentURL", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "part without asset",
+			url:  path + "7/content",
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("GetPartContentURL", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("%w: part 7", service.ErrPartAssetNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "presign failure",
+			url:  path + "0/content",
+			setup: func(svc *MockSessionService) {
+				owned(svc)
+				svc.On("GetPartContentURL", mock.Anything, mock.Anything).Return(nil, errors.New("s3 unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockSessionService{}
+			tt.setup(mockService)
+
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
+			router := setupSessionRouter()
+			router.GET("/session/:session_id/message/:message_id/part/:index/content", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.GetMessagePartContent(c)
+			})
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedLocation != "" {
+				assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestSessionHandler_GetTokenCounts(t *testing.T) {
 	sessionID := uuid.New()
 
@@ -3513,7 +4146,7 @@ func TestSessionHandler_GetTokenCounts(t *testing.T) {
 			mockService := &MockSessionService{}
 			tt.setup(mockService)
 
-			handler := NewSessionHandler(mockService, getMockSessionCoreClient())
+			handler := NewSessionHandler(mockService, getMockSessionCoreClient(), normalizer.Limits{})
 			router := setupSessionRouter()
 			router.GET("/session/:session_id/token_counts", handler.GetTokenCounts)
 
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"gorm.io/gorm"
+)
+
+type ToolReferenceHandler struct {
+	svc service.ToolReferenceService
+}
+
+func NewToolReferenceHandler(s service.ToolReferenceService) *ToolReferenceHandler {
+	return &ToolReferenceHandler{svc: s}
+}
+
+type ToolReferenceReq struct {
+	Name            string         `form:"name" json:"name" binding:"required" example:"search_web"`
+	Description     *string        `form:"description" json:"description" example:"Search the web for a query"`
+	ArgumentsSchema map[string]any `form:"arguments_schema" json:"arguments_schema"`
+}
+
+// CreateToolReference godoc
+//
+//	@Summary		Create tool reference
+//	@Description	Create a tool reference under a project. Tool names are unique within a project.
+//	@Tags			tool
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	handler.ToolReferenceReq	true	"ToolReference payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.ToolReference}
+//	@Router			/project/tool-reference [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Create a tool reference\ntool = client.tool_references.create(\n    name='search_web',\n    description='Search the web for a query',\n    arguments_schema={\"type\": \"object\", \"properties\": {\"query\": {\"type\": \"string\"}}}\n)\nprint(tool.id)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Create a tool reference\nconst tool = await client.toolReferences.create({\n  name: 'search_web',\n  description: 'Search the web for a query',\n  argumentsSchema: { type: 'object', properties: { query: { type: 'string' } } }\n});\nconsole.log(tool.id);\n","label":"JavaScript"}]
+func (h *ToolReferenceHandler) CreateToolReference(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := ToolReferenceReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	t, err := h.svc.Create(c.Request.Context(), service.ToolReferenceInput{
+		ProjectID:       project.ID,
+		Name:            req.Name,
+		Description:     req.Description,
+		ArgumentsSchema: req.ArgumentsSchema,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrToolReferenceNameExists) {
+			c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, "tool reference name already exists", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: t})
+}
+
+// ImportedToolReq is one tool of an import, in any of the accepted shapes: a tool reference
+// (name, description, arguments_schema), an OpenAI tool ({"type": "function", "function":
+// {name, description, parameters}}) or an Anthropic tool (name, description, input_schema)
+type ImportedToolReq struct {
+	Name            string              `json:"name" example:"search_web"`
+	Description     *string             `json:"description" example:"Search the web for a query"`
+	ArgumentsSchema map[string]any      `json:"arguments_schema"`
+	InputSchema     map[string]any      `json:"input_schema"`
+	Function        *ImportedToolFunReq `json:"function"`
+}
+
+type ImportedToolFunReq struct {
+	Name        string         `json:"name"`
+	Description *string        `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// toInput maps the tool to a service input, whatever its shape
+func (r ImportedToolReq) toInput() service.ToolReferenceInput {
+	if r.Function != nil {
+		return service.ToolReferenceInput{
+			Name:            r.Function.Name,
+			Description:     r.Function.Description,
+			ArgumentsSchema: r.Function.Parameters,
+		}
+	}
+	schema := r.ArgumentsSchema
+	if schema == nil {
+		schema = r.InputSchema
+	}
+	return service.ToolReferenceInput{Name: r.Name, Description: r.Description, ArgumentsSchema: schema}
+}
+
+type ImportToolReferencesReq struct {
+	Mode  string            `json:"mode" binding:"omitempty,oneof=skip update" example:"skip"`
+	Tools []ImportedToolReq `json:"tools" binding:"required,min=1,max=1000"`
+}
+
+// ImportToolReferences godoc
+//
+//	@Summary		Import tool references
+//	@Description	Create many tool references at once, in a single transaction. Each tool is a tool reference, an OpenAI tool or an Anthropic tool, so a tools list sent to either API can be imported as is. Names must be unique within the batch. A tool whose name the project already uses is skipped with mode "skip" (default) and updates the existing tool reference with mode "update".
+//	@Tags			tool
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body	handler.ImportToolReferencesReq	true	"Import payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ImportToolReferencesOutput}
+//	@Failure		400	{object}	serializer.Response
+//	@Failure		409	{object}	serializer.Response
+//	@Router			/project/tool-reference/import [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Import the tools of an OpenAI request, updating the existing ones\nresult = client.tool_references.import_tools(\n    tools=[{\"type\": \"function\", \"function\": {\"name\": \"search_web\", \"description\": \"Search the web\", \"parameters\": {\"type\": \"object\"}}}],\n    mode='update'\n)\nprint(result.created, result.updated, result.skipped)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Import the tools of an OpenAI request, updating the existing ones\nconst result = await client.toolReferences.importTools({\n  tools: [{ type: 'function', function: { name: 'search_web', description: 'Search the web', parameters: { type: 'object' } } }],\n  mode: 'update'\n});\nconsole.log(result.created, result.updated, result.skipped);\n","label":"JavaScript"}]
+func (h *ToolReferenceHandler) ImportToolReferences(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	req := ImportToolReferencesReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	tools := make([]service.ToolReferenceInput, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		tools = append(tools, tool.toInput())
+	}
+
+	out, err := h.svc.Import(c.Request.Context(), service.ImportToolReferencesInput{
+		ProjectID: project.ID,
+		Mode:      service.ToolImportMode(req.Mode),
+		Tools:     tools,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToolReferenceImport) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+		if errors.Is(err, service.ErrToolReferenceNameExists) {
+			c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, "tool reference name already exists", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+// ListToolReferences godoc
+//
+//	@Summary		List tool references
+//	@Description	List all tool references under a project
+//	@Tags			tool
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=[]model.ToolReference}
+//	@Router			/project/tool-reference [get]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# List tool references\ntools = client.tool_references.list()\nfor tool in tools:\n    print(f\"{tool.id}: {tool.name}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// List tool references\nconst tools = await client.toolReferences.list();\nfor (const tool of tools) {\n  console.log(`${tool.id}: ${tool.name}`);\n}\n","label":"JavaScript"}]
+func (h *ToolReferenceHandler) ListToolReferences(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	list, err := h.svc.List(c.Request.Context(), project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: list})
+}
+
+// UpdateToolReference godoc
+//
+//	@Summary		Update tool reference
+//	@Description	Update a tool reference's name, description and arguments schema
+//	@Tags			tool
+//	@Accept			json
+//	@Produce		json
+//	@Param			tool_reference_id	path	string						true	"Tool reference ID"	Format(uuid)
+//	@Param			payload				body	handler.ToolReferenceReq	true	"ToolReference payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.ToolReference}
+//	@Router			/project/tool-reference/{tool_reference_id} [put]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update a tool reference\ntool = client.tool_references.update(\n    tool_reference_id='tool-reference-uuid',\n    name='search_web',\n    description='Search the web and return top results'\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update a tool reference\nconst tool = await client.toolReferences.update('tool-reference-uuid', {\n  name: 'search_web',\n  description: 'Search the web and return top results'\n});\n","label":"JavaScript"}]
+func (h *ToolReferenceHandler) UpdateToolReference(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	toolReferenceID, err := uuid.Parse(c.Param("tool_reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := ToolReferenceReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	t, err := h.svc.Update(c.Request.Context(), toolReferenceID, service.ToolReferenceInput{
+		ProjectID:       project.ID,
+		Name:            req.Name,
+		Description:     req.Description,
+		ArgumentsSchema: req.ArgumentsSchema,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "tool reference not found", err))
+		case errors.Is(err, service.ErrToolReferenceNameExists):
+			c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, "tool reference name already exists", err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: t})
+}
+
+// DeleteToolReference godoc
+//
+//	@Summary		Delete tool reference
+//	@Description	Delete a tool reference by its ID. Returns 409 with the IDs of the SOP blocks still using it.
+//	@Tags			tool
+//	@Accept			json
+//	@Produce		json
+//	@Param			tool_reference_id	path	string	true	"Tool reference ID"	Format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{}
+//	@Failure		409	{object}	serializer.Response{data=[]string}
+//	@Router			/project/tool-reference/{tool_reference_id} [delete]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Delete a tool reference\nclient.tool_references.delete(tool_reference_id='tool-reference-uuid')\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Delete a tool reference\nawait client.toolReferences.delete('tool-reference-uuid');\n","label":"JavaScript"}]
+func (h *ToolReferenceHandler) DeleteToolReference(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	toolReferenceID, err := uuid.Parse(c.Param("tool_reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), project.ID, toolReferenceID); err != nil {
+		var inUse *service.ToolReferenceInUseError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "tool reference not found", err))
+		case errors.As(err, &inUse):
+			resp := serializer.Err(http.StatusConflict, "tool reference is still used by sop blocks", err)
+			resp.Data = inUse.BlockIDs
+			c.JSON(http.StatusConflict, resp)
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
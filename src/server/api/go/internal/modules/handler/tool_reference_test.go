@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockToolReferenceService is a mock implementation of ToolReferenceService
+type MockToolReferenceService struct {
+	mock.Mock
+}
+
+func (m *MockToolReferenceService) Create(ctx context.Context, in service.ToolReferenceInput) (*model.ToolReference, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceService) Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error) {
+	args := m.Called(ctx, projectID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceService) List(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error) {
+	args := m.Called(ctx, projectID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceService) Update(ctx context.Context, id uuid.UUID, in service.ToolReferenceInput) (*model.ToolReference, error) {
+	args := m.Called(ctx, id, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceService) Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error {
+	args := m.Called(ctx, projectID, id)
+	return args.Error(0)
+}
+
+func (m *MockToolReferenceService) Import(ctx context.Context, in service.ImportToolReferencesInput) (*service.ImportToolReferencesOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ImportToolReferencesOutput), args.Error(1)
+}
+
+func setupToolReferenceRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return gin.New()
+}
+
+func TestToolReferenceHandler_CreateToolReference(t *testing.T) {
+	projectID := uuid.New()
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setup          func(*MockToolReferenceService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful creation",
+			requestBody: ToolReferenceReq{Name: "search_web"},
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Create", mock.Anything, mock.MatchedBy(func(in service.ToolReferenceInput) bool {
+					return in.ProjectID == projectID && in.Name == "search_web"
+				})).Return(&model.ToolReference{ID: uuid.New(), ProjectID: projectID, Name: "search_web"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing name",
+			requestBody:    map[string]interface{}{"description": "no name"},
+			setup:          func(svc *MockToolReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "duplicate name",
+			requestBody: ToolReferenceReq{Name: "search_web"},
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Create", mock.Anything, mock.Anything).Return(nil, service.ErrToolReferenceNameExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "service error",
+			requestBody: ToolReferenceReq{Name: "search_web"},
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockToolReferenceService{}
+			tt.setup(mockService)
+			handler := NewToolReferenceHandler(mockService)
+
+			router := setupToolReferenceRouter()
+			router.POST("/project/tool-reference", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.CreateToolReference(c)
+			})
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/project/tool-reference", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolReferenceHandler_DeleteToolReference(t *testing.T) {
+	projectID := uuid.New()
+	toolID := uuid.New()
+	blockID := uuid.New()
+
+	tests := []struct {
+		name           string
+		toolID         string
+		setup          func(*MockToolReferenceService)
+		expectedStatus int
+		expectBlockIDs bool
+	}{
+		{
+			name:   "successful deletion",
+			toolID: toolID.String(),
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Delete", mock.Anything, projectID, toolID).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid tool reference id",
+			toolID:         "not-a-uuid",
+			setup:          func(svc *MockToolReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "not found",
+			toolID: toolID.String(),
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Delete", mock.Anything, projectID, toolID).Return(gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "still referenced by sop block",
+			toolID: toolID.String(),
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Delete", mock.Anything, projectID, toolID).Return(&service.ToolReferenceInUseError{BlockIDs: []uuid.UUID{blockID}})
+			},
+			expectedStatus: http.StatusConflict,
+			expectBlockIDs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockToolReferenceService{}
+			tt.setup(mockService)
+			handler := NewToolReferenceHandler(mockService)
+
+			router := setupToolReferenceRouter()
+			router.DELETE("/project/tool-reference/:tool_reference_id", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.DeleteToolReference(c)
+			})
+
+			req := httptest.NewRequest("DELETE", "/project/tool-reference/"+tt.toolID, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectBlockIDs {
+				var response map[string]interface{}
+				err := sonic.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, []interface{}{blockID.String()}, response["data"])
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolReferenceHandler_ImportToolReferences(t *testing.T) {
+	projectID := uuid.New()
+	description := "Search the web"
+	schema := map[string]any{"type": "object"}
+
+	// The same tool as a tool reference, an OpenAI tool and an Anthropic tool
+	mixed := `{"mode":"update","tools":[
+		{"name":"search_web","description":"Search the web","arguments_schema":{"type":"object"}},
+		{"type":"function","function":{"name":"read_file","description":"Search the web","parameters":{"type":"object"}}},
+		{"name":"run_code","description":"Search the web","input_schema":{"type":"object"}}
+	]}`
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockToolReferenceService)
+		expectedStatus int
+	}{
+		{
+			name: "tools of every shape",
+			body: mixed,
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Import", mock.Anything, service.ImportToolReferencesInput{
+					ProjectID: projectID,
+					Mode:      service.ToolImportModeUpdate,
+					Tools: []service.ToolReferenceInput{
+						{Name: "search_web", Description: &description, ArgumentsSchema: schema},
+						{Name: "read_file", Description: &description, ArgumentsSchema: schema},
+						{Name: "run_code", Description: &description, ArgumentsSchema: schema},
+					},
+				}).Return(&service.ImportToolReferencesOutput{Created: 2, Updated: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "duplicate names",
+			body: `{"tools":[{"name":"search_web"},{"name":"search_web"}]}`,
+			setup: func(svc *MockToolReferenceService) {
+				svc.On("Import", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("%w: duplicate tool name", service.ErrInvalidToolReferenceImport))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown mode",
+			body:           `{"mode":"replace","tools":[{"name":"search_web"}]}`,
+			setup:          func(svc *MockToolReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no tools",
+			body:           `{"tools":[]}`,
+			setup:          func(svc *MockToolReferenceService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockToolReferenceService{}
+			tt.setup(mockService)
+			handler := NewToolReferenceHandler(mockService)
+
+			router := setupToolReferenceRouter()
+			router.POST("/project/tool-reference/import", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.ImportToolReferences(c)
+			})
+
+			req := httptest.NewRequest("POST", "/project/tool-reference/import", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]any
+				assert.NoError(t, sonic.Unmarshal(w.Body.Bytes(), &response))
+				data := response["data"].(map[string]any)
+				assert.Equal(t, float64(2), data["created"])
+				assert.Equal(t, float64(1), data["updated"])
+				assert.Equal(t, float64(0), data["skipped"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
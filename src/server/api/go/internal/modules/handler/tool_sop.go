@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"gorm.io/gorm"
+)
+
+type ToolSOPHandler struct {
+	svc service.ToolSOPService
+}
+
+func NewToolSOPHandler(s service.ToolSOPService) *ToolSOPHandler {
+	return &ToolSOPHandler{svc: s}
+}
+
+// toolSOPErr maps ToolSOP service errors to HTTP responses
+func toolSOPErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "block, step or tool reference not found", err))
+	case errors.Is(err, service.ErrNotSOPBlock):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("block_id", err))
+	case errors.Is(err, service.ErrEmptySOPAction):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("action", err))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
+
+type AppendSOPStepReq struct {
+	ToolReferenceID uuid.UUID `form:"tool_reference_id" json:"tool_reference_id" binding:"required" format:"uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Action          string    `form:"action" json:"action" binding:"required" example:"Search for the latest release notes"`
+}
+
+// AppendSOPStep godoc
+//
+//	@Summary		Append SOP step
+//	@Description	Append a tool step to the end of a SOP block's steps. The tool reference must belong to the same project.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string					true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string					true	"Block ID"	Format(uuid)
+//	@Param			payload		body	handler.AppendSOPStepReq	true	"AppendSOPStep payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.ToolSOP}
+//	@Router			/space/{space_id}/block/{block_id}/sop-steps [post]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Append a step to a SOP block\nstep = client.blocks.append_sop_step(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    tool_reference_id='tool-reference-uuid',\n    action='Search for the latest release notes'\n)\nprint(step.order)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Append a step to a SOP block\nconst step = await client.blocks.appendSopStep('space-uuid', 'block-uuid', {\n  toolReferenceId: 'tool-reference-uuid',\n  action: 'Search for the latest release notes'\n});\nconsole.log(step.order);\n","label":"JavaScript"}]
+func (h *ToolSOPHandler) AppendSOPStep(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := AppendSOPStepReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	step, err := h.svc.AppendStep(c.Request.Context(), service.AppendToolSOPInput{
+		ProjectID:       project.ID,
+		SpaceID:         spaceID,
+		BlockID:         blockID,
+		ToolReferenceID: req.ToolReferenceID,
+		Action:          req.Action,
+	})
+	if err != nil {
+		toolSOPErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: step})
+}
+
+// DeleteSOPStep godoc
+//
+//	@Summary		Delete SOP step
+//	@Description	Delete a tool step from a SOP block. The remaining steps keep contiguous order values.
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string	true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string	true	"Block ID"	Format(uuid)
+//	@Param			step_id		path	string	true	"Step ID"	Format(uuid)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/sop-steps/{step_id} [delete]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Delete a step from a SOP block\nclient.blocks.delete_sop_step(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    step_id='step-uuid'\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Delete a step from a SOP block\nawait client.blocks.deleteSopStep('space-uuid', 'block-uuid', 'step-uuid');\n","label":"JavaScript"}]
+func (h *ToolSOPHandler) DeleteSOPStep(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	stepID, err := uuid.Parse(c.Param("step_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if err := h.svc.DeleteStep(c.Request.Context(), spaceID, blockID, stepID); err != nil {
+		toolSOPErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
+
+type ReorderSOPStepReq struct {
+	Order *int `form:"order" json:"order" binding:"required,min=0" example:"0"`
+}
+
+// ReorderSOPStep godoc
+//
+//	@Summary		Reorder SOP step
+//	@Description	Move a tool step of a SOP block to a new order position, shifting the steps in between
+//	@Tags			block
+//	@Accept			json
+//	@Produce		json
+//	@Param			space_id	path	string						true	"Space ID"	Format(uuid)
+//	@Param			block_id	path	string						true	"Block ID"	Format(uuid)
+//	@Param			step_id		path	string						true	"Step ID"	Format(uuid)
+//	@Param			payload		body	handler.ReorderSOPStepReq	true	"ReorderSOPStep payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response
+//	@Router			/space/{space_id}/block/{block_id}/sop-steps/{step_id}/order [put]
+//	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Move a SOP step to the first position\nclient.blocks.reorder_sop_step(\n    space_id='space-uuid',\n    block_id='block-uuid',\n    step_id='step-uuid',\n    order=0\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Move a SOP step to the first position\nawait client.blocks.reorderSopStep('space-uuid', 'block-uuid', 'step-uuid', {\n  order: 0\n});\n","label":"JavaScript"}]
+func (h *ToolSOPHandler) ReorderSOPStep(c *gin.Context) {
+	spaceID, err := uuid.Parse(c.Param("space_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	blockID, err := uuid.Parse(c.Param("block_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	stepID, err := uuid.Parse(c.Param("step_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := ReorderSOPStepReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if err := h.svc.ReorderStep(c.Request.Context(), spaceID, blockID, stepID, *req.Order); err != nil {
+		toolSOPErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
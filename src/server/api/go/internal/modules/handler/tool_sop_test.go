@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockToolSOPService is a mock implementation of ToolSOPService
+type MockToolSOPService struct {
+	mock.Mock
+}
+
+func (m *MockToolSOPService) AppendStep(ctx context.Context, in service.AppendToolSOPInput) (*model.ToolSOP, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolSOP), args.Error(1)
+}
+
+func (m *MockToolSOPService) DeleteStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID) error {
+	args := m.Called(ctx, spaceID, blockID, stepID)
+	return args.Error(0)
+}
+
+func (m *MockToolSOPService) ReorderStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID, order int) error {
+	args := m.Called(ctx, spaceID, blockID, stepID, order)
+	return args.Error(0)
+}
+
+func setupToolSOPRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	return gin.New()
+}
+
+func TestToolSOPHandler_AppendSOPStep(t *testing.T) {
+	projectID := uuid.New()
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	toolID := uuid.New()
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setup          func(*MockToolSOPService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful append",
+			requestBody: AppendSOPStepReq{ToolReferenceID: toolID, Action: "search"},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("AppendStep", mock.Anything, service.AppendToolSOPInput{
+					ProjectID:       projectID,
+					SpaceID:         spaceID,
+					BlockID:         blockID,
+					ToolReferenceID: toolID,
+					Action:          "search",
+				}).Return(&model.ToolSOP{ID: uuid.New(), SOPBlockID: blockID, ToolReferenceID: toolID, Action: "search"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing tool_reference_id",
+			requestBody:    map[string]interface{}{"action": "search"},
+			setup:          func(svc *MockToolSOPService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "block is not a sop block",
+			requestBody: AppendSOPStepReq{ToolReferenceID: toolID, Action: "search"},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("AppendStep", mock.Anything, mock.Anything).Return(nil, service.ErrNotSOPBlock)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "whitespace-only action",
+			requestBody: AppendSOPStepReq{ToolReferenceID: toolID, Action: "   "},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("AppendStep", mock.Anything, mock.Anything).Return(nil, service.ErrEmptySOPAction)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "block of another space",
+			requestBody: AppendSOPStepReq{ToolReferenceID: toolID, Action: "search"},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("AppendStep", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "service error",
+			requestBody: AppendSOPStepReq{ToolReferenceID: toolID, Action: "search"},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("AppendStep", mock.Anything, mock.Anything).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockToolSOPService{}
+			tt.setup(mockService)
+			handler := NewToolSOPHandler(mockService)
+
+			router := setupToolSOPRouter()
+			router.POST("/space/:space_id/block/:block_id/sop-steps", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.AppendSOPStep(c)
+			})
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/space/"+spaceID.String()+"/block/"+blockID.String()+"/sop-steps", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolSOPHandler_ReorderSOPStep(t *testing.T) {
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	stepID := uuid.New()
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setup          func(*MockToolSOPService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful reorder",
+			requestBody: map[string]interface{}{"order": 0},
+			setup: func(svc *MockToolSOPService) {
+				svc.On("ReorderStep", mock.Anything, spaceID, blockID, stepID, 0).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing order",
+			requestBody:    map[string]interface{}{},
+			setup:          func(svc *MockToolSOPService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative order",
+			requestBody:    map[string]interface{}{"order": -1},
+			setup:          func(svc *MockToolSOPService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockToolSOPService{}
+			tt.setup(mockService)
+			handler := NewToolSOPHandler(mockService)
+
+			router := setupToolSOPRouter()
+			router.PUT("/space/:space_id/block/:block_id/sop-steps/:step_id/order", handler.ReorderSOPStep)
+
+			body, _ := sonic.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PUT", "/space/"+spaceID.String()+"/block/"+blockID.String()+"/sop-steps/"+stepID.String()+"/order", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
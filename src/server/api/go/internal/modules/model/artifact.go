@@ -1,10 +1,14 @@
 package model
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // Reserved metadata keys that are not allowed in user metadata
@@ -19,12 +23,53 @@ func GetReservedKeys() []string {
 	return []string{ArtifactInfoKey}
 }
 
+var ErrReservedMetaKey = errors.New("reserved key is not allowed in user meta")
+
+// ValidateUserMeta checks that user meta uses none of the reserved keys, at the top level or
+// in any object nested in it, so stored meta can never be mistaken for system meta
+func ValidateUserMeta(meta map[string]any) error {
+	return validateUserMetaValue(meta, "")
+}
+
+func validateUserMetaValue(value any, at string) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if slices.Contains(GetReservedKeys(), k) {
+				return fmt.Errorf("%w: '%s'", ErrReservedMetaKey, at+k)
+			}
+			if err := validateUserMetaValue(child, at+k+"."); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if err := validateUserMetaValue(child, fmt.Sprintf("%s%d.", at, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type Disk struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	ProjectID uuid.UUID `gorm:"type:uuid;not null;index" json:"project_id"`
+	ProjectID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_disks_project_default,where:is_default" json:"project_id"`
+	// IsDefault marks the default disk of the project, at most one per project
+	IsDefault bool `gorm:"not null;default:false" json:"is_default"`
+	// PathAddressedKeys stores each artifact of the disk in its own object, keyed by its path
+	// (see PathAddressedKey), instead of in a content-addressed object shared by equal files.
+	// It is set when the disk is created.
+	PathAddressedKeys bool `gorm:"not null;default:false" json:"path_addressed_keys"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	// DeletedAt is set while a deleted disk can still be restored; its artifacts are kept
+	// until the disk is purged
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Stats is only filled when listing disks with stats
+	Stats *DiskStats `gorm:"-" json:"stats,omitempty"`
 
 	// Disk <-> Project
 	Project *Project `gorm:"foreignKey:ProjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
@@ -32,13 +77,24 @@ type Disk struct {
 
 func (Disk) TableName() string { return "disks" }
 
+// DiskStats aggregates the artifacts stored in a disk
+type DiskStats struct {
+	ArtifactCount int64 `json:"artifact_count"`
+	TotalSizeB    int64 `json:"total_size_b"`
+}
+
 type Artifact struct {
-	ID        uuid.UUID                 `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
-	DiskID    uuid.UUID                 `gorm:"type:uuid;not null;index;uniqueIndex:idx_disk_path_filename" json:"disk_id"`
-	Path      string                    `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"path"`
-	Filename  string                    `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"filename"`
-	Meta      datatypes.JSONMap         `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
-	AssetMeta datatypes.JSONType[Asset] `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
+	ID        uuid.UUID                   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	DiskID    uuid.UUID                   `gorm:"type:uuid;not null;index;uniqueIndex:idx_disk_path_filename" json:"disk_id"`
+	Path      string                      `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"path"`
+	Filename  string                      `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"filename"`
+	Meta      datatypes.JSONMap           `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
+	AssetMeta datatypes.JSONType[Asset]   `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
+	Tags      datatypes.JSONSlice[string] `gorm:"type:jsonb;not null;default:'[]';index:idx_artifacts_tags,type:gin" swaggertype:"array,string" json:"tags"`
+
+	// AccessCount and LastAccessedAt hold the flushed access metrics, see ArtifactAccessCounter
+	AccessCount    int64      `gorm:"not null;default:0" json:"-"`
+	LastAccessedAt *time.Time `json:"-"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -48,3 +104,66 @@ type Artifact struct {
 }
 
 func (Artifact) TableName() string { return "artifacts" }
+
+// AddTags appends the tags the artifact does not have yet and reports whether any was added
+func (a *Artifact) AddTags(tags ...string) bool {
+	changed := false
+	for _, tag := range tags {
+		if !slices.Contains(a.Tags, tag) {
+			a.Tags = append(a.Tags, tag)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// RemoveTags removes the given tags and reports whether any was present
+func (a *Artifact) RemoveTags(tags ...string) bool {
+	n := len(a.Tags)
+	a.Tags = slices.DeleteFunc(a.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	return len(a.Tags) != n
+}
+
+// PathAddressedKey is the object key of the artifact at path and filename on a disk with
+// path addressed keys, e.g. "disks/{disk_id}/documents/report.pdf"
+func PathAddressedKey(diskID uuid.UUID, path string, filename string) string {
+	return "disks/" + diskID.String() + path + filename
+}
+
+// OwnsObject reports whether the artifact is stored under its own path addressed key. Such
+// an object belongs to the artifact alone: it has no asset reference and is deleted with it.
+func (a *Artifact) OwnsObject() bool {
+	key := a.AssetMeta.Data().S3Key
+	return key != "" && key == PathAddressedKey(a.DiskID, a.Path, a.Filename)
+}
+
+// MergeMeta shallow-merges patch into Meta; keys set to nil in patch are removed
+func (a *Artifact) MergeMeta(patch map[string]any) {
+	meta := make(datatypes.JSONMap, len(a.Meta)+len(patch))
+	for k, v := range a.Meta {
+		meta[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(meta, k)
+			continue
+		}
+		meta[k] = v
+	}
+	a.Meta = meta
+}
+
+// ArtifactPath identifies an artifact within a disk
+type ArtifactPath struct {
+	Path     string
+	Filename string
+}
+
+// Key is the full file path of the artifact, e.g. "/documents/report.pdf"
+func (p ArtifactPath) Key() string { return p.Path + p.Filename }
+
+// DirectoryCount is a directory of a disk with the number of artifacts directly in it
+type DirectoryCount struct {
+	Path  string `json:"path"`
+	Count int64  `json:"count"`
+}
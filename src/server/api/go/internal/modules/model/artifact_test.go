@@ -0,0 +1,96 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestArtifact_AddTags(t *testing.T) {
+	a := &Artifact{}
+
+	assert.True(t, a.AddTags("invoice", "2024"))
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "2024"}, a.Tags)
+
+	// Adding tags the artifact already has is a no-op
+	assert.False(t, a.AddTags("2024", "invoice"))
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "2024"}, a.Tags)
+
+	assert.True(t, a.AddTags("invoice", "paid"))
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "2024", "paid"}, a.Tags)
+}
+
+func TestArtifact_RemoveTags(t *testing.T) {
+	a := &Artifact{Tags: datatypes.JSONSlice[string]{"invoice", "2024", "paid"}}
+
+	assert.True(t, a.RemoveTags("2024", "missing"))
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "paid"}, a.Tags)
+
+	// Removing tags the artifact does not have is a no-op
+	assert.False(t, a.RemoveTags("2024"))
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "paid"}, a.Tags)
+
+	assert.True(t, a.RemoveTags("invoice", "paid"))
+	assert.Empty(t, a.Tags)
+}
+
+func TestArtifact_MergeMeta(t *testing.T) {
+	a := &Artifact{Meta: datatypes.JSONMap{
+		ArtifactInfoKey: map[string]any{"filename": "a.txt"},
+		"description":   "original",
+		"version":       "1.0",
+	}}
+
+	a.MergeMeta(map[string]any{"owner": "alice", "version": "2.0", "description": nil})
+	assert.Equal(t, datatypes.JSONMap{
+		ArtifactInfoKey: map[string]any{"filename": "a.txt"},
+		"owner":         "alice",
+		"version":       "2.0",
+	}, a.Meta)
+
+	// An artifact without meta gets one
+	b := &Artifact{}
+	b.MergeMeta(map[string]any{"owner": "bob"})
+	assert.Equal(t, datatypes.JSONMap{"owner": "bob"}, b.Meta)
+}
+
+func TestArtifact_OwnsObject(t *testing.T) {
+	diskID := uuid.MustParse("6f1c2a9e-8d4b-4f3a-9c1e-2b7d5e0a4c81")
+	assert.Equal(t, "disks/6f1c2a9e-8d4b-4f3a-9c1e-2b7d5e0a4c81/docs/report.pdf", PathAddressedKey(diskID, "/docs/", "report.pdf"))
+
+	owned := &Artifact{DiskID: diskID, Path: "/docs/", Filename: "report.pdf", AssetMeta: datatypes.NewJSONType(Asset{S3Key: PathAddressedKey(diskID, "/docs/", "report.pdf")})}
+	assert.True(t, owned.OwnsObject())
+
+	// A content-addressed object is shared, and so is an owned object once copied elsewhere
+	shared := &Artifact{DiskID: diskID, Path: "/docs/", Filename: "report.pdf", AssetMeta: datatypes.NewJSONType(Asset{S3Key: "disks/project/2025/01/01/abc123.pdf"})}
+	assert.False(t, shared.OwnsObject())
+	copied := &Artifact{DiskID: diskID, Path: "/archive/", Filename: "report.pdf", AssetMeta: owned.AssetMeta}
+	assert.False(t, copied.OwnsObject())
+}
+
+func TestValidateUserMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    map[string]any
+		wantErr string
+	}{
+		{name: "nil", meta: nil},
+		{name: "valid", meta: map[string]any{"owner": "alice", "labels": []any{"a", map[string]any{"k": 1.0}}, "nested": map[string]any{"info": "x"}}},
+		{name: "reserved", meta: map[string]any{ArtifactInfoKey: map[string]any{"path": "/"}}, wantErr: "'__artifact_info__'"},
+		{name: "nested reserved", meta: map[string]any{"source": map[string]any{ArtifactInfoKey: "x"}}, wantErr: "'source.__artifact_info__'"},
+		{name: "reserved in a list", meta: map[string]any{"items": []any{"a", map[string]any{ArtifactInfoKey: "x"}}}, wantErr: "'items.1.__artifact_info__'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUserMeta(tt.meta)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrReservedMetaKey)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -19,11 +19,15 @@ type AssetReference struct {
 
 	// Project ID for multi-tenant isolation
 	// Assets are isolated per project for security and access control
-	ProjectID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_project_sha256,priority:1" json:"project_id"`
+	ProjectID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_project_bucket_sha256,priority:1" json:"project_id"`
+
+	// Bucket holding the object. A project with a bucket of its own keeps artifacts there and
+	// message parts in the deployment's bucket, so the same content can be stored in both.
+	Bucket string `gorm:"type:text;not null;default:'';uniqueIndex:idx_project_bucket_sha256,priority:2" json:"bucket"`
 
 	// SHA256 hash as unique identifier for content-based deduplication
-	// Combined with ProjectID as composite unique key
-	SHA256 string `gorm:"type:char(64);not null;uniqueIndex:idx_project_sha256,priority:2" json:"sha256"`
+	// Combined with ProjectID and Bucket as composite unique key
+	SHA256 string `gorm:"type:char(64);not null;uniqueIndex:idx_project_bucket_sha256,priority:3" json:"sha256"`
 
 	// Canonical S3 key - the first uploaded location or preferred location
 	// When same content is uploaded multiple times within a project, we keep only one copy
@@ -1,10 +1,13 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
 	"gorm.io/datatypes"
 )
 
@@ -70,7 +73,7 @@ func GetAllBlockTypes() map[string]BlockTypeConfig {
 type Block struct {
 	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 
-	SpaceID uuid.UUID `gorm:"type:uuid;not null;index:idx_blocks_space;index:idx_blocks_space_type_archived,priority:1;uniqueIndex:ux_blocks_space_parent_sort,priority:1" json:"space_id"`
+	SpaceID uuid.UUID `gorm:"type:uuid;not null;index:idx_blocks_space;index:idx_blocks_space_last_viewed,priority:1;index:idx_blocks_space_type_archived,priority:1;uniqueIndex:ux_blocks_space_parent_sort,priority:1" json:"space_id"`
 	Space   *Space    `gorm:"constraint:fk_blocks_space,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 
 	Type string `gorm:"type:text;not null;index:idx_blocks_space_type;index:idx_blocks_space_type_archived,priority:2" json:"type"`
@@ -84,6 +87,9 @@ type Block struct {
 	Sort       int64 `gorm:"not null;default:0;uniqueIndex:ux_blocks_space_parent_sort,priority:3" json:"sort"`
 	IsArchived bool  `gorm:"not null;default:false;index:idx_blocks_space_type_archived,priority:3;index" json:"is_archived"`
 
+	// LastViewedAt is the flushed time of the latest read of the block, see BlockViewRecorder
+	LastViewedAt *time.Time `gorm:"index:idx_blocks_space_last_viewed,priority:2,sort:desc" json:"last_viewed_at"`
+
 	Children  []*Block  `gorm:"foreignKey:ParentID;constraint:fk_blocks_children,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 	ToolSOPs  []ToolSOP `gorm:"foreignKey:SOPBlockID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
@@ -188,3 +194,110 @@ func (b *Block) SetFolderPath(path string) {
 	propsData["path"] = path
 	b.Props = datatypes.NewJSONType(propsData)
 }
+
+// MergeProps Shallow-merge patch into Props; keys set to nil in patch are removed
+func (b *Block) MergeProps(patch map[string]any) {
+	propsData := b.Props.Data()
+	if propsData == nil {
+		propsData = make(map[string]any)
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(propsData, k)
+			continue
+		}
+		propsData[k] = v
+	}
+	b.Props = datatypes.NewJSONType(propsData)
+}
+
+// ArtifactRefKey is the props key linking a block to an artifact. Anywhere in the props,
+// including nested objects and arrays, an object of the form
+//
+//	{"artifact": {"disk_id": "<disk uuid>", "path": "/dir/file.ext"}}
+//
+// references the artifact at path in the disk. path is the full file path, including the
+// filename.
+const ArtifactRefKey = "artifact"
+
+// ErrInvalidArtifactRef is returned for an "artifact" props value not following ArtifactRefKey
+var ErrInvalidArtifactRef = errors.New("invalid artifact reference")
+
+// ArtifactRef is a reference from block props to an artifact
+type ArtifactRef struct {
+	DiskID uuid.UUID `json:"disk_id"`
+	Path   string    `json:"path"`
+}
+
+// Split returns the directory and filename of the referenced artifact
+func (r ArtifactRef) Split() ArtifactPath {
+	dir, filename := path.SplitFilePath(r.Path)
+	return ArtifactPath{Path: dir, Filename: filename}
+}
+
+// ParseArtifactRefs returns the artifact references in props, each once, in a stable order.
+// Malformed references are left out and reported in the error, wrapping ErrInvalidArtifactRef.
+func ParseArtifactRefs(props map[string]any) ([]ArtifactRef, error) {
+	var (
+		refs []ArtifactRef
+		errs []error
+	)
+	seen := map[ArtifactRef]bool{}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if k != ArtifactRefKey {
+					walk(v[k])
+					continue
+				}
+				ref, err := parseArtifactRef(v[k])
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if !seen[ref] {
+					seen[ref] = true
+					refs = append(refs, ref)
+				}
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(props)
+
+	return refs, errors.Join(errs...)
+}
+
+func parseArtifactRef(v any) (ArtifactRef, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return ArtifactRef{}, fmt.Errorf("%w: %q must be an object with disk_id and path", ErrInvalidArtifactRef, ArtifactRefKey)
+	}
+	rawDiskID, _ := m["disk_id"].(string)
+	diskID, err := uuid.Parse(rawDiskID)
+	if err != nil {
+		return ArtifactRef{}, fmt.Errorf("%w: disk_id %q is not a uuid", ErrInvalidArtifactRef, rawDiskID)
+	}
+	filePath, _ := m["path"].(string)
+	ref := ArtifactRef{DiskID: diskID, Path: filePath}
+	p := ref.Split()
+	if p.Filename == "" {
+		return ArtifactRef{}, fmt.Errorf("%w: path %q must include a filename", ErrInvalidArtifactRef, filePath)
+	}
+	if err := path.ValidatePath(p.Path); err != nil {
+		return ArtifactRef{}, fmt.Errorf("%w: path %q: %v", ErrInvalidArtifactRef, filePath, err)
+	}
+	ref.Path = p.Key()
+	return ref, nil
+}
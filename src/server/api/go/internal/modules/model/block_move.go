@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBlockMoveHistory is the number of latest moves kept per block
+const MaxBlockMoveHistory = 20
+
+// BlockMove records where a move took a block from and to, so it can be undone
+type BlockMove struct {
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+
+	BlockID uuid.UUID `gorm:"type:uuid;not null;index:idx_block_move_history_block_created,priority:1" json:"block_id"`
+	Block   *Block    `gorm:"constraint:fk_block_move_history_block,OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	OldParentID *uuid.UUID `gorm:"type:uuid" json:"old_parent_id"`
+	OldSort     int64      `gorm:"not null" json:"old_sort"`
+	NewParentID *uuid.UUID `gorm:"type:uuid" json:"new_parent_id"`
+	NewSort     int64      `gorm:"not null" json:"new_sort"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP;index:idx_block_move_history_block_created,priority:2" json:"created_at"`
+}
+
+func (BlockMove) TableName() string { return "block_move_history" }
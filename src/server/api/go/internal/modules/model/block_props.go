@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/pkg/utils/jsonschema"
+)
+
+var ErrInvalidBlockProps = errors.New("invalid block props")
+
+// BlockPropsSchemas are the JSON Schemas the props of each block type must satisfy. Types
+// without a schema accept any props. Unlisted keys are allowed, so props can carry artifact
+// references and client data.
+var BlockPropsSchemas = map[string]*jsonschema.Schema{
+	BlockTypeSOP: jsonschema.MustParse(`{
+		"type": "object",
+		"required": ["preferences"],
+		"properties": {
+			"use_when": {"type": "string"},
+			"preferences": {"type": "string"},
+			"tool_sops": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"tool_name": {"type": "string"},
+						"action": {"type": "string"}
+					}
+				}
+			}
+		}
+	}`),
+	BlockTypeText: jsonschema.MustParse(`{
+		"type": "object",
+		"properties": {
+			"text": {"type": "string"},
+			"use_when": {"type": "string"},
+			"notes": {"type": "string"}
+		}
+	}`),
+}
+
+// ValidateBlockProps checks props against the schema of the block type, returning an error
+// wrapping ErrInvalidBlockProps with the first violation
+func ValidateBlockProps(blockType string, props map[string]any) error {
+	schema, ok := BlockPropsSchemas[blockType]
+	if !ok {
+		return nil
+	}
+	if props == nil {
+		props = map[string]any{}
+	}
+	if err := schema.Validate(props); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlockProps, err)
+	}
+	return nil
+}
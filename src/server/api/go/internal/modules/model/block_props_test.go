@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBlockProps(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockType string
+		props     map[string]any
+		wantErr   string
+	}{
+		{
+			name:      "valid sop",
+			blockType: BlockTypeSOP,
+			props: map[string]any{
+				"use_when":    "star a repo on github.com",
+				"preferences": "",
+				"tool_sops":   []any{map[string]any{"tool_name": "click", "action": "click the star button"}},
+			},
+		},
+		{
+			name:      "sop without preferences",
+			blockType: BlockTypeSOP,
+			props:     map[string]any{"use_when": "star a repo on github.com"},
+			wantErr:   "invalid block props: preferences: is required",
+		},
+		{
+			name:      "sop with a non-string use_when",
+			blockType: BlockTypeSOP,
+			props:     map[string]any{"use_when": 3.0, "preferences": ""},
+			wantErr:   "invalid block props: use_when: expected string, got number",
+		},
+		{
+			name:      "sop with a malformed step",
+			blockType: BlockTypeSOP,
+			props:     map[string]any{"preferences": "", "tool_sops": []any{map[string]any{"action": true}}},
+			wantErr:   "invalid block props: tool_sops[0].action: expected string, got boolean",
+		},
+		{
+			name:      "text with extra keys",
+			blockType: BlockTypeText,
+			props:     map[string]any{"text": "hello", "color": "red"},
+		},
+		{
+			name:      "text with nil props",
+			blockType: BlockTypeText,
+		},
+		{
+			name:      "text with a non-string text",
+			blockType: BlockTypeText,
+			props:     map[string]any{"text": []any{"hello"}},
+			wantErr:   "invalid block props: text: expected string, got array",
+		},
+		{
+			name:      "type without a schema",
+			blockType: BlockTypePage,
+			props:     map[string]any{"text": 1.0},
+		},
+		{
+			name:      "unknown type",
+			blockType: "reference",
+			props:     map[string]any{"preferences": 1.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBlockProps(tt.blockType, tt.props)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidBlockProps)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
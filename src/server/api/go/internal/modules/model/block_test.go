@@ -691,3 +691,103 @@ func TestBlock_SetFolderPath(t *testing.T) {
 		})
 	}
 }
+
+func TestBlock_MergeProps(t *testing.T) {
+	tests := []struct {
+		name     string
+		props    map[string]any
+		patch    map[string]any
+		expected map[string]any
+	}{
+		{
+			name:     "existing keys survive partial update",
+			props:    map[string]any{"text": "hello", "color": "red"},
+			patch:    map[string]any{"color": "blue"},
+			expected: map[string]any{"text": "hello", "color": "blue"},
+		},
+		{
+			name:     "new key is added",
+			props:    map[string]any{"text": "hello"},
+			patch:    map[string]any{"color": "blue"},
+			expected: map[string]any{"text": "hello", "color": "blue"},
+		},
+		{
+			name:     "explicit null deletes key",
+			props:    map[string]any{"text": "hello", "color": "red"},
+			patch:    map[string]any{"color": nil},
+			expected: map[string]any{"text": "hello"},
+		},
+		{
+			name:     "nil props",
+			props:    nil,
+			patch:    map[string]any{"text": "hello"},
+			expected: map[string]any{"text": "hello"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Block{Type: BlockTypeText, Props: datatypes.NewJSONType(tt.props)}
+			b.MergeProps(tt.patch)
+			assert.Equal(t, tt.expected, b.Props.Data())
+		})
+	}
+}
+
+func TestParseArtifactRefs(t *testing.T) {
+	diskID := uuid.New()
+	ref := func(path string) map[string]any {
+		return map[string]any{ArtifactRefKey: map[string]any{"disk_id": diskID.String(), "path": path}}
+	}
+
+	t.Run("nested references", func(t *testing.T) {
+		props := map[string]any{
+			"text":       "See the attached report",
+			"attachment": ref("/reports/q1.pdf"),
+			"gallery":    []any{ref("/images/a.png"), ref("images//b.png"), "not a reference"},
+			"duplicate":  ref("/reports/q1.pdf"),
+		}
+
+		refs, err := ParseArtifactRefs(props)
+		assert.NoError(t, err)
+		assert.Equal(t, []ArtifactRef{
+			{DiskID: diskID, Path: "/reports/q1.pdf"},
+			{DiskID: diskID, Path: "/images/a.png"},
+			{DiskID: diskID, Path: "/images/b.png"},
+		}, refs)
+		assert.Equal(t, ArtifactPath{Path: "/images/", Filename: "b.png"}, refs[2].Split())
+	})
+
+	t.Run("no references", func(t *testing.T) {
+		refs, err := ParseArtifactRefs(map[string]any{"text": "hello"})
+		assert.NoError(t, err)
+		assert.Empty(t, refs)
+
+		refs, err = ParseArtifactRefs(nil)
+		assert.NoError(t, err)
+		assert.Empty(t, refs)
+	})
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "not an object", value: "/reports/q1.pdf"},
+		{name: "missing disk_id", value: map[string]any{"path": "/reports/q1.pdf"}},
+		{name: "invalid disk_id", value: map[string]any{"disk_id": "disk-1", "path": "/reports/q1.pdf"}},
+		{name: "missing filename", value: map[string]any{"disk_id": diskID.String(), "path": "/reports/"}},
+		{name: "missing path", value: map[string]any{"disk_id": diskID.String()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := map[string]any{
+				"valid":   ref("/reports/q1.pdf"),
+				"invalid": map[string]any{ArtifactRefKey: tt.value},
+			}
+
+			refs, err := ParseArtifactRefs(props)
+			assert.ErrorIs(t, err, ErrInvalidArtifactRef)
+			assert.Equal(t, []ArtifactRef{{DiskID: diskID, Path: "/reports/q1.pdf"}}, refs)
+		})
+	}
+}
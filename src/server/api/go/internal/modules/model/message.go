@@ -14,11 +14,12 @@ const (
 	FormatAcontext  MessageFormat = "acontext"
 	FormatOpenAI    MessageFormat = "openai"
 	FormatAnthropic MessageFormat = "anthropic"
+	FormatVercel    MessageFormat = "vercel"
 )
 
 type Message struct {
 	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	SessionID uuid.UUID  `gorm:"type:uuid;not null;index;index:idx_session_created,priority:1" json:"session_id"`
+	SessionID uuid.UUID  `gorm:"type:uuid;not null;index;index:idx_session_created,priority:1;index:idx_session_content_hash,priority:1" json:"session_id"`
 	ParentID  *uuid.UUID `gorm:"type:uuid;index" json:"parent_id"`
 	Parent    *Message   `gorm:"foreignKey:ParentID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
 	Children  []Message  `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
@@ -30,6 +31,10 @@ type Message struct {
 	PartsAssetMeta datatypes.JSONType[Asset] `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
 	Parts          []Part                    `gorm:"-" swaggertype:"array,object" json:"parts"`
 
+	// ContentHash identifies the role and parts of the message, see service.MessageContentHash.
+	// Messages stored before it was introduced have none.
+	ContentHash string `gorm:"type:text;index:idx_session_content_hash,priority:2" json:"-"`
+
 	TaskID *uuid.UUID `gorm:"type:uuid;index" json:"task_id"`
 
 	SessionTaskProcessStatus string `gorm:"type:text;not null;default:'pending';check:session_task_process_status IN ('success','failed','running','pending')" json:"session_task_process_status"`
@@ -46,10 +51,57 @@ type Message struct {
 
 func (Message) TableName() string { return "messages" }
 
+// Message meta flags used for context management
+const (
+	// MessageMetaPinned marks a message that must survive context trimming
+	MessageMetaPinned = "pinned"
+	// MessageMetaEphemeral marks a message that callers may leave out of exports
+	MessageMetaEphemeral = "ephemeral"
+	// MessageMetaRoleKind holds the original role of a system or developer message, which is
+	// stored with the user role and re-emitted with its own role by the converters
+	MessageMetaRoleKind = "role_kind"
+)
+
+// Values of meta["role_kind"]
+const (
+	RoleKindSystem    = "system"
+	RoleKindDeveloper = "developer"
+)
+
+// MetaMap returns the meta of the message, an empty map when it was never set
+func (m *Message) MetaMap() map[string]any {
+	if meta := m.Meta.Data(); meta != nil {
+		return meta
+	}
+	return map[string]any{}
+}
+
+// IsPinned reports whether meta["pinned"] is true
+func (m *Message) IsPinned() bool {
+	pinned, _ := m.MetaMap()[MessageMetaPinned].(bool)
+	return pinned
+}
+
+// IsEphemeral reports whether meta["ephemeral"] is true
+func (m *Message) IsEphemeral() bool {
+	ephemeral, _ := m.MetaMap()[MessageMetaEphemeral].(bool)
+	return ephemeral
+}
+
+// RoleKind returns meta["role_kind"], empty for regular user and assistant messages
+func (m *Message) RoleKind() string {
+	kind, _ := m.MetaMap()[MessageMetaRoleKind].(string)
+	return kind
+}
+
 type Part struct {
 	// "text" | "image" | "audio" | "video" | "file" | "tool-call" | "tool-result" | "data"
 	Type string `json:"type"`
 
+	// stable part identifier and position in the message, set by the normalizers
+	ID    string `json:"id,omitempty"`
+	Index int    `json:"index,omitempty"`
+
 	// text part
 	Text string `json:"text,omitempty"`
 
@@ -60,3 +112,13 @@ type Part struct {
 	// embedding、ocr、asr、caption...
 	Meta map[string]any `json:"meta,omitempty"`
 }
+
+// PartMetaReasoning marks a text part holding the reasoning of the model (e.g. OpenAI
+// reasoning_content) rather than visible assistant text
+const PartMetaReasoning = "is_reasoning"
+
+// IsReasoning reports whether meta["is_reasoning"] is true
+func (p *Part) IsReasoning() bool {
+	reasoning, _ := p.Meta[PartMetaReasoning].(bool)
+	return reasoning
+}
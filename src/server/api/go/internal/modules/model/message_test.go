@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func TestMessage_MetaMap(t *testing.T) {
+	t.Run("never set", func(t *testing.T) {
+		msg := &Message{Role: "user"}
+
+		assert.NotNil(t, msg.MetaMap())
+		assert.Empty(t, msg.MetaMap())
+		assert.False(t, msg.IsPinned())
+		assert.Empty(t, msg.RoleKind())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		msg := &Message{Role: "user", Meta: datatypes.NewJSONType(map[string]any{MessageMetaPinned: true})}
+
+		assert.Equal(t, map[string]any{MessageMetaPinned: true}, msg.MetaMap())
+		assert.True(t, msg.IsPinned())
+	})
+}
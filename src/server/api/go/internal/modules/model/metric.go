@@ -21,3 +21,13 @@ type Metric struct {
 }
 
 func (Metric) TableName() string { return "metrics" }
+
+// StorageTotals is the amount of data stored across all projects
+type StorageTotals struct {
+	Artifacts       int64 `json:"artifacts"`
+	Blocks          int64 `json:"blocks"`
+	AssetReferences int64 `json:"asset_references"`
+	// AssetRefs is the sum of the reference counts of the assets
+	AssetRefs  int64 `json:"asset_refs"`
+	AssetBytes int64 `json:"asset_bytes"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProjectBucket keeps the artifacts of a project in an S3 bucket of its own instead of the
+// deployment's bucket. The access and secret keys are encrypted with s3.projectBucketKey,
+// see secrets.Encrypt.
+type ProjectBucket struct {
+	ProjectID uuid.UUID `gorm:"type:uuid;primaryKey" json:"project_id"`
+
+	// Endpoint is empty for AWS S3
+	Endpoint     string `gorm:"type:text;not null;default:''" json:"endpoint"`
+	Region       string `gorm:"type:text;not null" json:"region"`
+	Bucket       string `gorm:"type:text;not null" json:"bucket"`
+	UsePathStyle bool   `gorm:"not null;default:false" json:"use_path_style"`
+
+	AccessKeyEncrypted string `gorm:"type:text;not null" json:"-"`
+	SecretKeyEncrypted string `gorm:"type:text;not null" json:"-"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// ProjectBucket <-> Project
+	Project *Project `gorm:"foreignKey:ProjectID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ProjectBucket) TableName() string { return "project_buckets" }
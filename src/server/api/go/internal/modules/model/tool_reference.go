@@ -9,9 +9,9 @@ import (
 
 type ToolReference struct {
 	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Name        string    `gorm:"type:text;not null" json:"name"`
+	Name        string    `gorm:"type:text;not null;uniqueIndex:idx_tool_reference_project_id_name,priority:2" json:"name"`
 	Description *string   `gorm:"type:text" json:"description"`
-	ProjectID   uuid.UUID `gorm:"type:uuid;not null;index:idx_tool_reference_project_id;index:idx_tool_reference_project_id_name,priority:1" json:"project_id"`
+	ProjectID   uuid.UUID `gorm:"type:uuid;not null;index:idx_tool_reference_project_id;uniqueIndex:idx_tool_reference_project_id_name,priority:1" json:"project_id"`
 	Project     *Project  `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
 
 	ArgumentsSchema datatypes.JSONMap `gorm:"type:jsonb" swaggertype:"object" json:"arguments_schema"`
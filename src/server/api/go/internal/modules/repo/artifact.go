@@ -2,21 +2,35 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ArtifactRepo interface {
 	Create(ctx context.Context, projectID uuid.UUID, a *model.Artifact) error
 	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error
 	Update(ctx context.Context, a *model.Artifact) error
+	Move(ctx context.Context, a *model.Artifact) error
+	PathAddressedKeys(ctx context.Context, diskID uuid.UUID) (bool, error)
 	GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error)
-	ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error)
+	GetByPaths(ctx context.Context, diskID uuid.UUID, items []model.ArtifactPath) ([]*model.Artifact, error)
+	ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error)
+	ListByDiskWithCursor(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error)
 	GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error)
+	CountByPath(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error)
 	ExistsByPathAndFilename(ctx context.Context, diskID uuid.UUID, path string, filename string, excludeID *uuid.UUID) (bool, error)
+	AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error)
+	RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error)
+	PatchMeta(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]any) (*model.Artifact, error)
+	ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error)
+	AddAccess(ctx context.Context, id uuid.UUID, count int64, lastAccessedAt time.Time) error
 }
 
 type artifactRepo struct {
@@ -38,6 +52,10 @@ func (r *artifactRepo) Create(ctx context.Context, projectID uuid.UUID, a *model
 			return err
 		}
 
+		// An object owned by the artifact is not shared, so it is not reference counted
+		if a.OwnsObject() {
+			return nil
+		}
 		if err := r.assetReferenceRepo.IncrementAssetRef(ctx, projectID, asset); err != nil {
 			return fmt.Errorf("increment asset reference: %w", err)
 		}
@@ -65,6 +83,12 @@ func (r *artifactRepo) DeleteByPath(ctx context.Context, projectID uuid.UUID, di
 			return err
 		}
 
+		if a.OwnsObject() {
+			if err := r.assetReferenceRepo.DeleteObjects(ctx, projectID, []model.Asset{asset}); err != nil {
+				return fmt.Errorf("delete artifact object: %w", err)
+			}
+			return nil
+		}
 		if err := r.assetReferenceRepo.DecrementAssetRef(ctx, projectID, asset); err != nil {
 			return fmt.Errorf("decrement asset reference: %w", err)
 		}
@@ -77,6 +101,31 @@ func (r *artifactRepo) Update(ctx context.Context, a *model.Artifact) error {
 	return r.db.WithContext(ctx).Where("id = ? AND disk_id = ?", a.ID, a.DiskID).Updates(a).Error
 }
 
+// Move saves the path, filename, meta and asset of a moved artifact, leaving its tags and
+// access metrics untouched
+func (r *artifactRepo) Move(ctx context.Context, a *model.Artifact) error {
+	res := r.db.WithContext(ctx).Model(a).
+		Where("disk_id = ?", a.DiskID).
+		Select("path", "filename", "meta", "asset_meta").
+		Updates(a)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PathAddressedKeys reports whether the disk stores its artifacts under path addressed keys
+func (r *artifactRepo) PathAddressedKeys(ctx context.Context, diskID uuid.UUID) (bool, error) {
+	var disk model.Disk
+	if err := r.db.WithContext(ctx).Select("path_addressed_keys").Where("id = ?", diskID).Take(&disk).Error; err != nil {
+		return false, err
+	}
+	return disk.PathAddressedKeys, nil
+}
+
 func (r *artifactRepo) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
 	var artifact model.Artifact
 	err := r.db.WithContext(ctx).Where("disk_id = ? AND path = ? AND filename = ?", diskID, path, filename).First(&artifact).Error
@@ -86,7 +135,31 @@ func (r *artifactRepo) GetByPath(ctx context.Context, diskID uuid.UUID, path str
 	return &artifact, nil
 }
 
-func (r *artifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error) {
+// GetByPaths returns the artifacts matching the given path and filename pairs; missing ones are skipped
+func (r *artifactRepo) GetByPaths(ctx context.Context, diskID uuid.UUID, items []model.ArtifactPath) ([]*model.Artifact, error) {
+	var artifacts []*model.Artifact
+	if len(items) == 0 {
+		return artifacts, nil
+	}
+
+	pairs := make([][]interface{}, 0, len(items))
+	for _, item := range items {
+		pairs = append(pairs, []interface{}{item.Path, item.Filename})
+	}
+	err := r.db.WithContext(ctx).
+		Where("disk_id = ? AND (path, filename) IN ?", diskID, pairs).
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// ListByPath returns the artifacts of a disk, in path when it is set. When mimePrefix is set
+// only artifacts whose MIME type starts with it are returned, e.g. "image/". The MIME type
+// of the stored asset (asset_meta.mime) is authoritative; the copy in
+// meta.__artifact_info__ is informational.
+func (r *artifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error) {
 	var artifacts []*model.Artifact
 	query := r.db.WithContext(ctx).Where("disk_id = ?", diskID)
 
@@ -95,6 +168,10 @@ func (r *artifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path st
 		query = query.Where("path = ?", path)
 	}
 
+	if mimePrefix != "" {
+		query = query.Where("asset_meta->>'mime' LIKE ?", likePrefix(mimePrefix))
+	}
+
 	err := query.Find(&artifacts).Error
 	if err != nil {
 		return nil, err
@@ -102,6 +179,33 @@ func (r *artifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path st
 	return artifacts, nil
 }
 
+// ListByDiskWithCursor lists the artifacts of a disk across all paths, a page at a time
+func (r *artifactRepo) ListByDiskWithCursor(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+	q := r.db.WithContext(ctx).Where("disk_id = ?", diskID)
+
+	// Apply cursor-based pagination filter if cursor is provided
+	if !afterCreatedAt.IsZero() && afterID != uuid.Nil {
+		// Determine comparison operator based on sort direction
+		comparisonOp := ">"
+		if timeDesc {
+			comparisonOp = "<"
+		}
+		q = q.Where(
+			"(created_at "+comparisonOp+" ?) OR (created_at = ? AND id "+comparisonOp+" ?)",
+			afterCreatedAt, afterCreatedAt, afterID,
+		)
+	}
+
+	// Apply ordering based on sort direction
+	orderBy := "created_at ASC, id ASC"
+	if timeDesc {
+		orderBy = "created_at DESC, id DESC"
+	}
+
+	var artifacts []*model.Artifact
+	return artifacts, q.Order(orderBy).Limit(limit).Find(&artifacts).Error
+}
+
 func (r *artifactRepo) GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error) {
 	var paths []string
 	err := r.db.WithContext(ctx).
@@ -115,6 +219,23 @@ func (r *artifactRepo) GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]str
 	return paths, nil
 }
 
+// CountByPath returns the directories of a disk holding artifacts, with the number of
+// artifacts directly in each, ordered by path
+func (r *artifactRepo) CountByPath(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error) {
+	var counts []model.DirectoryCount
+	err := r.db.WithContext(ctx).
+		Model(&model.Artifact{}).
+		Select("path, COUNT(*) AS count").
+		Where("disk_id = ?", diskID).
+		Group("path").
+		Order("path").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
 func (r *artifactRepo) ExistsByPathAndFilename(ctx context.Context, diskID uuid.UUID, path string, filename string, excludeID *uuid.UUID) (bool, error) {
 	query := r.db.WithContext(ctx).Model(&model.Artifact{}).
 		Where("disk_id = ? AND path = ? AND filename = ?",
@@ -133,3 +254,89 @@ func (r *artifactRepo) ExistsByPathAndFilename(ctx context.Context, diskID uuid.
 
 	return count > 0, nil
 }
+
+// AddTags adds tags to an artifact. Tags it already has are ignored.
+func (r *artifactRepo) AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	return r.updateTags(ctx, diskID, path, filename, func(a *model.Artifact) bool { return a.AddTags(tags...) })
+}
+
+// RemoveTags removes tags from an artifact. Tags it does not have are ignored.
+func (r *artifactRepo) RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	return r.updateTags(ctx, diskID, path, filename, func(a *model.Artifact) bool { return a.RemoveTags(tags...) })
+}
+
+// updateTags applies update to the locked artifact and saves its tags if they changed
+func (r *artifactRepo) updateTags(ctx context.Context, diskID uuid.UUID, path string, filename string, update func(a *model.Artifact) bool) (*model.Artifact, error) {
+	var a model.Artifact
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("disk_id = ? AND path = ? AND filename = ?", diskID, path, filename).
+			First(&a).Error; err != nil {
+			return err
+		}
+		if !update(&a) {
+			return nil
+		}
+		if a.Tags == nil {
+			a.Tags = []string{}
+		}
+		return tx.Model(&a).Update("tags", a.Tags).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// PatchMeta shallow-merges patch into the meta of the locked artifact, removing the keys set
+// to nil, and saves only its meta so concurrent patches and access or tag updates are kept
+func (r *artifactRepo) PatchMeta(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]any) (*model.Artifact, error) {
+	var a model.Artifact
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("disk_id = ? AND path = ? AND filename = ?", diskID, path, filename).
+			First(&a).Error; err != nil {
+			return err
+		}
+		a.MergeMeta(patch)
+		return tx.Model(&a).Update("meta", a.Meta).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListByTag returns the artifacts of a disk carrying tag, using the GIN index on tags
+func (r *artifactRepo) ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error) {
+	contains, err := json.Marshal([]string{tag})
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []*model.Artifact
+	err = r.db.WithContext(ctx).
+		Where("disk_id = ? AND tags @> ?::jsonb", diskID, string(contains)).
+		Order("path, filename").
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// AddAccess adds count accesses to an artifact and moves its last access time forward to
+// lastAccessedAt, leaving updated_at untouched
+func (r *artifactRepo) AddAccess(ctx context.Context, id uuid.UUID, count int64, lastAccessedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Artifact{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"access_count":     gorm.Expr("access_count + ?", count),
+			"last_accessed_at": gorm.Expr("GREATEST(COALESCE(last_accessed_at, ?), ?)", lastAccessedAt, lastAccessedAt),
+		}).Error
+}
+
+// likePrefix returns a LIKE pattern matching strings that start with prefix
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+}
@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestArtifactRepo_CountByPath(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}, &model.Artifact{}))
+	repo := NewArtifactRepo(db, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_artifact",
+		SecretKeyHashPHC: "test_hash_artifact",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	newDisk := func(paths ...string) *model.Disk {
+		disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID}
+		require.NoError(t, db.Create(disk).Error)
+		for i, p := range paths {
+			artifact := &model.Artifact{
+				DiskID:    disk.ID,
+				Path:      p,
+				Filename:  uuid.NewString(),
+				AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: uuid.NewString()}),
+			}
+			require.NoError(t, db.Create(artifact).Error, "artifact %d", i)
+		}
+		return disk
+	}
+
+	disk := newDisk("/", "/docs/", "/docs/2024/q1/", "/docs/2024/q1/", "/images/", "/docs/")
+	// Artifacts of another disk are not counted
+	newDisk("/docs/", "/other/")
+
+	counts, err := repo.CountByPath(ctx, disk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DirectoryCount{
+		{Path: "/", Count: 1},
+		{Path: "/docs/", Count: 2},
+		{Path: "/docs/2024/q1/", Count: 2},
+		{Path: "/images/", Count: 1},
+	}, counts)
+
+	counts, err = repo.CountByPath(ctx, newDisk().ID)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestArtifactRepo_ListByPath_MIMEPrefix(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}, &model.Artifact{}))
+	repo := NewArtifactRepo(db, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_artifact_mime",
+		SecretKeyHashPHC: "test_hash_artifact_mime",
+	}
+	require.NoError(t, db.Create(project).Error)
+	disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(disk).Error)
+	defer func() {
+		db.Exec("DELETE FROM artifacts WHERE disk_id = ?", disk.ID)
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	for _, f := range []struct{ filename, mime string }{
+		{"a.png", "image/png"},
+		{"b.jpg", "image/jpeg"},
+		{"c.pdf", "application/pdf"},
+		{"d.txt", "text/plain"},
+		// Only the stored asset's MIME type counts, not the one in meta
+		{"e.bin", "application/octet-stream"},
+	} {
+		artifact := &model.Artifact{
+			DiskID:    disk.ID,
+			Path:      "/",
+			Filename:  f.filename,
+			Meta:      map[string]interface{}{model.ArtifactInfoKey: map[string]interface{}{"mime": "image/png"}},
+			AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: uuid.NewString(), MIME: f.mime}),
+		}
+		require.NoError(t, db.Create(artifact).Error)
+	}
+
+	filenames := func(mimePrefix string) []string {
+		artifacts, err := repo.ListByPath(ctx, disk.ID, "/", mimePrefix)
+		require.NoError(t, err)
+		names := make([]string, 0, len(artifacts))
+		for _, a := range artifacts {
+			names = append(names, a.Filename)
+		}
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"a.png", "b.jpg"}, filenames("image/"))
+	assert.ElementsMatch(t, []string{"c.pdf", "e.bin"}, filenames("application/"))
+	assert.ElementsMatch(t, []string{"a.png", "b.jpg", "c.pdf", "d.txt", "e.bin"}, filenames(""))
+	// LIKE wildcards in the prefix are matched literally
+	assert.Empty(t, filenames("%"))
+}
+
+func TestArtifactRepo_PatchMeta(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}, &model.Artifact{}))
+	repo := NewArtifactRepo(db, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_artifact_patch",
+		SecretKeyHashPHC: "test_hash_artifact_patch",
+	}
+	require.NoError(t, db.Create(project).Error)
+	disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(disk).Error)
+	defer func() {
+		db.Exec("DELETE FROM artifacts WHERE disk_id = ?", disk.ID)
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	artifact := &model.Artifact{
+		DiskID:    disk.ID,
+		Path:      "/",
+		Filename:  "a.txt",
+		Meta:      map[string]interface{}{model.ArtifactInfoKey: map[string]interface{}{"filename": "a.txt"}, "stale": true},
+		AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: uuid.NewString()}),
+	}
+	require.NoError(t, db.Create(artifact).Error)
+
+	// Concurrent patches of different keys all land
+	const patches = 10
+	var wg sync.WaitGroup
+	for i := range patches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.PatchMeta(ctx, disk.ID, "/", "a.txt", map[string]any{fmt.Sprintf("key%d", i): i})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Columns other than meta are left alone
+	require.NoError(t, repo.AddAccess(ctx, artifact.ID, 3, time.Now()))
+	_, err := repo.AddTags(ctx, disk.ID, "/", "a.txt", []string{"invoice"})
+	require.NoError(t, err)
+
+	patched, err := repo.PatchMeta(ctx, disk.ID, "/", "a.txt", map[string]any{"stale": nil})
+	require.NoError(t, err)
+	assert.NotContains(t, patched.Meta, "stale")
+	assert.Contains(t, patched.Meta, model.ArtifactInfoKey)
+	for i := range patches {
+		assert.Contains(t, patched.Meta, fmt.Sprintf("key%d", i))
+	}
+
+	var stored model.Artifact
+	require.NoError(t, db.First(&stored, "id = ?", artifact.ID).Error)
+	assert.Equal(t, int64(3), stored.AccessCount)
+	assert.Equal(t, datatypes.JSONSlice[string]{"invoice"}, stored.Tags)
+	assert.Len(t, stored.Meta, patches+1)
+
+	_, err = repo.PatchMeta(ctx, disk.ID, "/", "missing.txt", map[string]any{"k": "v"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
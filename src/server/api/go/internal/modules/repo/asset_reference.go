@@ -18,19 +18,71 @@ type AssetReferenceRepo interface {
 	DecrementAssetRef(ctx context.Context, projectID uuid.UUID, asset model.Asset) error
 	BatchIncrementAssetRefs(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error
 	BatchDecrementAssetRefs(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error
+	GetS3KeyBySHA256(ctx context.Context, projectID uuid.UUID, bucket string, sha256 string) (string, error)
+	TouchAssetRef(ctx context.Context, projectID uuid.UUID, sha256 string) error
+	ListOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) ([]model.AssetReference, error)
+	DeleteOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) (int, error)
+	ListAssetReferences(ctx context.Context, projectID uuid.UUID, filter AssetReferenceFilter) ([]model.AssetReference, error)
+	SumAssetReferences(ctx context.Context, projectID uuid.UUID, filter AssetReferenceFilter) (count int64, totalSizeB int64, err error)
+	DeleteObjects(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error
+}
+
+// AssetReferenceFilter selects asset references of a project. Zero fields do not filter.
+type AssetReferenceFilter struct {
+	MinRefCount          int
+	OrphanedOnly         bool
+	LastReferencedBefore time.Time
+	// AfterCreatedAt and AfterID continue a listing ordered by (created_at, id) after the
+	// given row. They are ignored by SumAssetReferences.
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Limit          int
+}
+
+// assetRefKey identifies the asset reference of a project
+type assetRefKey struct {
+	bucket string
+	sha256 string
 }
 
 type assetReferenceRepo struct {
-	db *gorm.DB
-	s3 *blob.S3Deps
+	db        *gorm.DB
+	s3        *blob.S3Deps
+	projectS3 blob.S3Resolver
 }
 
-func NewAssetReferenceRepo(db *gorm.DB, s3 *blob.S3Deps) AssetReferenceRepo {
-	return &assetReferenceRepo{db: db, s3: s3}
+// NewAssetReferenceRepo deletes unreferenced objects from s3, the deployment's bucket, or from
+// the bucket of their project as resolved by projectS3. A nil projectS3 keeps every object
+// in s3.
+func NewAssetReferenceRepo(db *gorm.DB, s3 *blob.S3Deps, projectS3 blob.S3Resolver) AssetReferenceRepo {
+	return &assetReferenceRepo{db: db, s3: s3, projectS3: projectS3}
+}
+
+// storeOf returns the S3 holding the object of ref, as resolved from the bucket recorded in
+// its asset
+func (r *assetReferenceRepo) storeOf(ctx context.Context, projectID uuid.UUID, ref model.AssetReference) (*blob.S3Deps, error) {
+	return r.bucketStore(ctx, projectID, ref.AssetMeta.Data().Bucket)
+}
+
+// bucketStore returns the S3 holding the objects of projectID stored in bucket
+func (r *assetReferenceRepo) bucketStore(ctx context.Context, projectID uuid.UUID, bucket string) (*blob.S3Deps, error) {
+	if r.projectS3 == nil {
+		return r.s3, nil
+	}
+	return r.projectS3.ForObject(ctx, projectID, bucket)
+}
+
+// deleteObject deletes the object of ref from the bucket holding it
+func (r *assetReferenceRepo) deleteObject(ctx context.Context, projectID uuid.UUID, ref model.AssetReference) error {
+	store, err := r.storeOf(ctx, projectID, ref)
+	if err != nil {
+		return err
+	}
+	return store.DeleteObject(ctx, ref.S3Key)
 }
 
 // IncrementAssetRef finds or creates an asset reference and increments its RefCount.
-// It upserts by (project_id, sha256) and updates canonical fields.
+// It upserts by (project_id, bucket, sha256) and updates canonical fields.
 // Uses SkipHooks to prevent recursive hook triggers when called from other hooks.
 func (r *assetReferenceRepo) IncrementAssetRef(ctx context.Context, projectID uuid.UUID, asset model.Asset) error {
 	if projectID == uuid.Nil {
@@ -45,6 +97,7 @@ func (r *assetReferenceRepo) IncrementAssetRef(ctx context.Context, projectID uu
 	// Prepare row for insert
 	row := model.AssetReference{
 		ProjectID:        projectID,
+		Bucket:           asset.Bucket,
 		SHA256:           asset.SHA256,
 		S3Key:            asset.S3Key,
 		RefCount:         1,
@@ -52,11 +105,11 @@ func (r *assetReferenceRepo) IncrementAssetRef(ctx context.Context, projectID uu
 		LastReferencedAt: now,
 	}
 
-	// Upsert by (project_id, sha256), incrementing ref_count and refreshing metadata/s3_key
+	// Upsert by (project_id, bucket, sha256), incrementing ref_count and refreshing metadata/s3_key
 	// Use SkipHooks to prevent recursive hook triggers when called from Artifact hooks
 	return r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Clauses(
 		clause.OnConflict{
-			Columns: []clause.Column{{Name: "project_id"}, {Name: "sha256"}},
+			Columns: []clause.Column{{Name: "project_id"}, {Name: "bucket"}, {Name: "sha256"}},
 			DoUpdates: clause.Assignments(map[string]any{
 				// increment
 				"ref_count": gorm.Expr("asset_references.ref_count + 1"),
@@ -81,7 +134,7 @@ func (r *assetReferenceRepo) DecrementAssetRef(ctx context.Context, projectID uu
 	}
 
 	var ref model.AssetReference
-	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Where("project_id = ? AND sha256 = ?", projectID, asset.SHA256).First(&ref).Error
+	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Where("project_id = ? AND bucket = ? AND sha256 = ?", projectID, asset.Bucket, asset.SHA256).First(&ref).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil
@@ -90,19 +143,19 @@ func (r *assetReferenceRepo) DecrementAssetRef(ctx context.Context, projectID uu
 	}
 
 	if ref.RefCount <= 1 {
-		if err := r.s3.DeleteObject(ctx, ref.S3Key); err != nil {
+		if err := r.deleteObject(ctx, projectID, ref); err != nil {
 			return err
 		}
 		return r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Delete(&ref).Error
 	}
 
 	return r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Model(&model.AssetReference{}).
-		Where("project_id = ? AND sha256 = ?", projectID, asset.SHA256).
+		Where("project_id = ? AND bucket = ? AND sha256 = ?", projectID, asset.Bucket, asset.SHA256).
 		UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
 }
 
 // BatchIncrementAssetRefs increments reference counts for a slice of assets.
-// Duplicated assets (by sha256) in the slice are coalesced and counted.
+// Duplicated assets (by bucket and sha256) in the slice are coalesced and counted.
 // Uses SkipHooks to prevent recursive hook triggers when called from other hooks.
 func (r *assetReferenceRepo) BatchIncrementAssetRefs(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error {
 	if projectID == uuid.Nil {
@@ -112,19 +165,20 @@ func (r *assetReferenceRepo) BatchIncrementAssetRefs(ctx context.Context, projec
 		return nil
 	}
 
-	// group by sha256
+	// group by bucket and sha256
 	type agg struct {
 		asset model.Asset
 		count int
 	}
-	grouped := make(map[string]*agg)
+	grouped := make(map[assetRefKey]*agg)
 	for _, a := range assets {
 		if a.SHA256 == "" {
 			continue
 		}
-		g, ok := grouped[a.SHA256]
+		key := assetRefKey{bucket: a.Bucket, sha256: a.SHA256}
+		g, ok := grouped[key]
 		if !ok {
-			grouped[a.SHA256] = &agg{asset: a, count: 1}
+			grouped[key] = &agg{asset: a, count: 1}
 		} else {
 			g.count++
 		}
@@ -138,6 +192,7 @@ func (r *assetReferenceRepo) BatchIncrementAssetRefs(ctx context.Context, projec
 	for _, g := range grouped {
 		rows = append(rows, model.AssetReference{
 			ProjectID:        projectID,
+			Bucket:           g.asset.Bucket,
 			SHA256:           g.asset.SHA256,
 			S3Key:            g.asset.S3Key,
 			RefCount:         g.count,
@@ -149,7 +204,7 @@ func (r *assetReferenceRepo) BatchIncrementAssetRefs(ctx context.Context, projec
 	// Use SkipHooks to prevent recursive hook triggers when called from other hooks
 	return r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Clauses(
 		clause.OnConflict{
-			Columns: []clause.Column{{Name: "project_id"}, {Name: "sha256"}},
+			Columns: []clause.Column{{Name: "project_id"}, {Name: "bucket"}, {Name: "sha256"}},
 			DoUpdates: clause.Assignments(map[string]any{
 				"ref_count":          gorm.Expr("asset_references.ref_count + EXCLUDED.ref_count"),
 				"s3_key":             gorm.Expr("COALESCE(NULLIF(asset_references.s3_key, ''), EXCLUDED.s3_key)"),
@@ -171,24 +226,24 @@ func (r *assetReferenceRepo) BatchDecrementAssetRefs(ctx context.Context, projec
 		return nil
 	}
 
-	// group by sha256
-	grouped := make(map[string]int)
+	// group by bucket and sha256
+	grouped := make(map[assetRefKey]int)
 	for _, a := range assets {
 		if a.SHA256 == "" {
 			continue
 		}
-		grouped[a.SHA256]++
+		grouped[assetRefKey{bucket: a.Bucket, sha256: a.SHA256}]++
 	}
 	if len(grouped) == 0 {
 		return nil
 	}
 
-	// For each sha, decrement or delete
+	// For each asset, decrement or delete
 	// Use SkipHooks to prevent recursive hook triggers when called from other hooks
 	sessionTx := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true})
-	for sha, dec := range grouped {
+	for key, dec := range grouped {
 		var ref model.AssetReference
-		err := sessionTx.Where("project_id = ? AND sha256 = ?", projectID, sha).First(&ref).Error
+		err := sessionTx.Where("project_id = ? AND bucket = ? AND sha256 = ?", projectID, key.bucket, key.sha256).First(&ref).Error
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				continue
@@ -196,7 +251,7 @@ func (r *assetReferenceRepo) BatchDecrementAssetRefs(ctx context.Context, projec
 			return err
 		}
 		if ref.RefCount <= dec {
-			if err := r.s3.DeleteObject(ctx, ref.S3Key); err != nil {
+			if err := r.deleteObject(ctx, projectID, ref); err != nil {
 				return err
 			}
 			if err := sessionTx.Delete(&ref).Error; err != nil {
@@ -205,10 +260,152 @@ func (r *assetReferenceRepo) BatchDecrementAssetRefs(ctx context.Context, projec
 			continue
 		}
 		if err := sessionTx.Model(&model.AssetReference{}).
-			Where("project_id = ? AND sha256 = ?", projectID, sha).
+			Where("project_id = ? AND bucket = ? AND sha256 = ?", projectID, key.bucket, key.sha256).
 			UpdateColumn("ref_count", gorm.Expr("ref_count - ?", dec)).Error; err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// GetS3KeyBySHA256 returns the canonical S3 key stored in bucket for the given content
+// hash, or an empty string if the project has no asset with that hash in the bucket.
+func (r *assetReferenceRepo) GetS3KeyBySHA256(ctx context.Context, projectID uuid.UUID, bucket string, sha256 string) (string, error) {
+	var keys []string
+	err := r.db.WithContext(ctx).Model(&model.AssetReference{}).
+		Where("project_id = ? AND bucket = ? AND sha256 = ?", projectID, bucket, sha256).
+		Limit(1).
+		Pluck("s3_key", &keys).Error
+	if err != nil || len(keys) == 0 {
+		return "", err
+	}
+	return keys[0], nil
+}
+
+// TouchAssetRef moves the last referenced time of an asset to now, without counting a
+// reference or changing updated_at, so assets still being served do not look stale. The
+// asset is touched in every bucket holding its content.
+func (r *assetReferenceRepo) TouchAssetRef(ctx context.Context, projectID uuid.UUID, sha256 string) error {
+	return r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Model(&model.AssetReference{}).
+		Where("project_id = ? AND sha256 = ?", projectID, sha256).
+		UpdateColumn("last_referenced_at", time.Now()).Error
+}
+
+// ListOrphaned lists asset references with no remaining references (ref_count <= 0)
+// that were last updated before the given time.
+func (r *assetReferenceRepo) ListOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) ([]model.AssetReference, error) {
+	var refs []model.AssetReference
+	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).
+		Where("project_id = ? AND ref_count <= 0 AND updated_at < ?", projectID, before).
+		Order("updated_at ASC").
+		Find(&refs).Error
+	return refs, err
+}
+
+// DeleteOrphaned deletes orphaned asset references last updated before the given time,
+// together with their S3 objects. Rows are deleted first with the orphan condition
+// re-checked, so an asset re-referenced concurrently is never removed; if the S3 deletion
+// fails the transaction is rolled back and the rows are kept for the next sweep.
+func (r *assetReferenceRepo) DeleteOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) (int, error) {
+	if projectID == uuid.Nil {
+		return 0, fmt.Errorf("DeleteOrphaned: project_id is required")
+	}
+
+	deleted := 0
+	err := r.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		var refs []model.AssetReference
+		if err := tx.Clauses(clause.Returning{}).
+			Where("project_id = ? AND ref_count <= 0 AND updated_at < ?", projectID, before).
+			Delete(&refs).Error; err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+
+		keysByStore := map[*blob.S3Deps][]string{}
+		for _, ref := range refs {
+			store, err := r.storeOf(ctx, projectID, ref)
+			if err != nil {
+				return err
+			}
+			keysByStore[store] = append(keysByStore[store], ref.S3Key)
+		}
+		for store, keys := range keysByStore {
+			if err := store.DeleteObjects(ctx, keys); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(refs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// DeleteObjects deletes the objects of assets that are not reference counted, such as those
+// owned by a single artifact (see model.Artifact.OwnsObject), from the buckets holding them
+func (r *assetReferenceRepo) DeleteObjects(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error {
+	keysByStore := map[*blob.S3Deps][]string{}
+	for _, asset := range assets {
+		store, err := r.bucketStore(ctx, projectID, asset.Bucket)
+		if err != nil {
+			return err
+		}
+		keysByStore[store] = append(keysByStore[store], asset.S3Key)
+	}
+	for store, keys := range keysByStore {
+		if err := store.DeleteObjects(ctx, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAssetReferences lists the asset references of a project matching the filter, oldest
+// first.
+func (r *assetReferenceRepo) ListAssetReferences(ctx context.Context, projectID uuid.UUID, filter AssetReferenceFilter) ([]model.AssetReference, error) {
+	q := r.filterQuery(ctx, projectID, filter)
+	if !filter.AfterCreatedAt.IsZero() && filter.AfterID != uuid.Nil {
+		q = q.Where("(created_at > ?) OR (created_at = ? AND id > ?)", filter.AfterCreatedAt, filter.AfterCreatedAt, filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	var refs []model.AssetReference
+	return refs, q.Order("created_at ASC, id ASC").Find(&refs).Error
+}
+
+// SumAssetReferences counts the asset references of a project matching the filter and sums
+// their sizes from asset_meta, in a single query.
+func (r *assetReferenceRepo) SumAssetReferences(ctx context.Context, projectID uuid.UUID, filter AssetReferenceFilter) (int64, int64, error) {
+	var row struct {
+		Count      int64
+		TotalSizeB int64
+	}
+	err := r.filterQuery(ctx, projectID, filter).
+		Select("COUNT(*) AS count, COALESCE(SUM((asset_meta->>'size_b')::bigint), 0) AS total_size_b").
+		Take(&row).Error
+	return row.Count, row.TotalSizeB, err
+}
+
+// filterQuery builds the query of the asset references of a project matching the filter,
+// without pagination
+func (r *assetReferenceRepo) filterQuery(ctx context.Context, projectID uuid.UUID, filter AssetReferenceFilter) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&model.AssetReference{}).Where("project_id = ?", projectID)
+	if filter.MinRefCount > 0 {
+		q = q.Where("ref_count >= ?", filter.MinRefCount)
+	}
+	if filter.OrphanedOnly {
+		q = q.Where("ref_count <= 0")
+	}
+	if !filter.LastReferencedBefore.IsZero() {
+		q = q.Where("last_referenced_at < ?", filter.LastReferencedBefore)
+	}
+	return q
+}
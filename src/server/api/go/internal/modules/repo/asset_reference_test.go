@@ -0,0 +1,289 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestAssetReferenceRepo_ListAssetReferences(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.AssetReference{}))
+	repo := NewAssetReferenceRepo(db, nil, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_asset_refs",
+		SecretKeyHashPHC: "test_hash_asset_refs",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM asset_references WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	newRef := func(sha string, refCount int, size int64, age time.Duration) *model.AssetReference {
+		ref := &model.AssetReference{
+			ProjectID:        project.ID,
+			SHA256:           sha,
+			S3Key:            "assets/" + sha,
+			RefCount:         refCount,
+			AssetMeta:        datatypes.NewJSONType(model.Asset{SHA256: sha, SizeB: size}),
+			CreatedAt:        now.Add(-age),
+			LastReferencedAt: now.Add(-age),
+		}
+		require.NoError(t, db.Create(ref).Error)
+		return ref
+	}
+	orphanOld := newRef("orphan-old", 0, 100, 72*time.Hour)
+	shared := newRef("shared", 3, 200, 48*time.Hour)
+	single := newRef("single", 1, 300, 24*time.Hour)
+	orphanNew := newRef("orphan-new", 0, 400, time.Hour)
+
+	shas := func(refs []model.AssetReference) []string {
+		out := make([]string, 0, len(refs))
+		for _, r := range refs {
+			out = append(out, r.SHA256)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		filter    AssetReferenceFilter
+		wantSHAs  []string
+		wantSizeB int64
+	}{
+		{
+			name:      "all, oldest first",
+			wantSHAs:  []string{orphanOld.SHA256, shared.SHA256, single.SHA256, orphanNew.SHA256},
+			wantSizeB: 1000,
+		},
+		{
+			name:      "min ref count",
+			filter:    AssetReferenceFilter{MinRefCount: 2},
+			wantSHAs:  []string{shared.SHA256},
+			wantSizeB: 200,
+		},
+		{
+			name:      "orphaned only",
+			filter:    AssetReferenceFilter{OrphanedOnly: true},
+			wantSHAs:  []string{orphanOld.SHA256, orphanNew.SHA256},
+			wantSizeB: 500,
+		},
+		{
+			name:      "last referenced before",
+			filter:    AssetReferenceFilter{LastReferencedBefore: now.Add(-12 * time.Hour)},
+			wantSHAs:  []string{orphanOld.SHA256, shared.SHA256, single.SHA256},
+			wantSizeB: 600,
+		},
+		{
+			name:      "orphaned and stale",
+			filter:    AssetReferenceFilter{OrphanedOnly: true, LastReferencedBefore: now.Add(-12 * time.Hour)},
+			wantSHAs:  []string{orphanOld.SHA256},
+			wantSizeB: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := repo.ListAssetReferences(ctx, project.ID, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSHAs, shas(refs))
+
+			count, sizeB, err := repo.SumAssetReferences(ctx, project.ID, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.wantSHAs)), count)
+			assert.Equal(t, tt.wantSizeB, sizeB)
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		page, err := repo.ListAssetReferences(ctx, project.ID, AssetReferenceFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{orphanOld.SHA256, shared.SHA256}, shas(page))
+
+		last := page[len(page)-1]
+		page, err = repo.ListAssetReferences(ctx, project.ID, AssetReferenceFilter{AfterCreatedAt: last.CreatedAt, AfterID: last.ID, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{single.SHA256, orphanNew.SHA256}, shas(page))
+
+		// The cursor does not change the totals
+		count, sizeB, err := repo.SumAssetReferences(ctx, project.ID, AssetReferenceFilter{AfterCreatedAt: last.CreatedAt, AfterID: last.ID, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+		assert.Equal(t, int64(1000), sizeB)
+	})
+}
+
+func TestAssetReferenceRepo_TouchAssetRef(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.AssetReference{}))
+	repo := NewAssetReferenceRepo(db, nil, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_asset_touch",
+		SecretKeyHashPHC: "test_hash_asset_touch",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM asset_references WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	past := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	ref := &model.AssetReference{
+		ProjectID:        project.ID,
+		SHA256:           "touched",
+		S3Key:            "assets/touched",
+		RefCount:         0,
+		CreatedAt:        past,
+		UpdatedAt:        past,
+		LastReferencedAt: past,
+	}
+	require.NoError(t, db.Create(ref).Error)
+
+	before := time.Now()
+	require.NoError(t, repo.TouchAssetRef(ctx, project.ID, ref.SHA256))
+
+	var got model.AssetReference
+	require.NoError(t, db.First(&got, "id = ?", ref.ID).Error)
+	assert.False(t, got.LastReferencedAt.Before(before.Add(-time.Second)), "last referenced time should advance")
+	// A touch is not a reference change
+	assert.Equal(t, 0, got.RefCount)
+	assert.True(t, got.UpdatedAt.Equal(past))
+
+	// Touching an unknown asset is a no-op
+	assert.NoError(t, repo.TouchAssetRef(ctx, project.ID, "missing"))
+}
+
+func TestAssetReferenceRepo_StoreOf(t *testing.T) {
+	ctx := context.Background()
+	deployment := &blob.S3Deps{Bucket: "deployment"}
+	refIn := func(bucket string) model.AssetReference {
+		return model.AssetReference{S3Key: "disks/a", AssetMeta: datatypes.NewJSONType(model.Asset{Bucket: bucket, S3Key: "disks/a"})}
+	}
+
+	r := &assetReferenceRepo{s3: deployment, projectS3: blob.NewProjectS3Resolver(deployment, func(ctx context.Context, projectID uuid.UUID) (*blob.ProjectBucket, error) {
+		return &blob.ProjectBucket{Region: "us-east-1", Bucket: "project-own", AccessKey: "a", SecretKey: "a"}, nil
+	})}
+	for bucket, want := range map[string]string{
+		"project-own": "project-own",
+		// Message parts and assets from before the project had its own bucket
+		"deployment": "deployment",
+		"":           "deployment",
+	} {
+		store, err := r.storeOf(ctx, uuid.New(), refIn(bucket))
+		require.NoError(t, err)
+		assert.Equal(t, want, store.Bucket, bucket)
+	}
+
+	// Without a resolver every object is in the deployment's bucket
+	store, err := (&assetReferenceRepo{s3: deployment}).storeOf(ctx, uuid.New(), refIn("project-own"))
+	require.NoError(t, err)
+	assert.Same(t, deployment, store)
+}
+
+func TestAssetReferenceRepo_SameContentInTwoBuckets(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.AssetReference{}))
+	ctx := context.Background()
+
+	// Both buckets are served path-style by one fake S3 recording the deleted objects
+	var mu sync.Mutex
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	deployment := &blob.S3Deps{
+		Client: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(srv.URL),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+		}),
+		Bucket: "deployment",
+	}
+	projectS3 := blob.NewProjectS3Resolver(deployment, func(ctx context.Context, projectID uuid.UUID) (*blob.ProjectBucket, error) {
+		return &blob.ProjectBucket{Endpoint: srv.URL, Region: "us-east-1", Bucket: "project-own", AccessKey: "test", SecretKey: "test", UsePathStyle: true}, nil
+	})
+	repo := NewAssetReferenceRepo(db, deployment, projectS3)
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_asset_buckets",
+		SecretKeyHashPHC: "test_hash_asset_buckets",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM asset_references WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	// The same file uploaded as an artifact, to the bucket of the project, and as a message
+	// part, to the deployment's bucket
+	sha := "same-content"
+	artifact := model.Asset{Bucket: "project-own", S3Key: "disks/a.txt", SHA256: sha}
+	part := model.Asset{Bucket: "deployment", S3Key: "assets/a.txt", SHA256: sha}
+	require.NoError(t, repo.IncrementAssetRef(ctx, project.ID, artifact))
+	require.NoError(t, repo.BatchIncrementAssetRefs(ctx, project.ID, []model.Asset{part}))
+
+	var refs []model.AssetReference
+	require.NoError(t, db.Where("project_id = ?", project.ID).Order("bucket").Find(&refs).Error)
+	require.Len(t, refs, 2)
+	assert.Equal(t, "deployment", refs[0].Bucket)
+	assert.Equal(t, part.S3Key, refs[0].S3Key)
+	assert.Equal(t, "project-own", refs[1].Bucket)
+	assert.Equal(t, artifact.S3Key, refs[1].S3Key)
+
+	// Uploads only dedup against keys of the bucket they write to
+	key, err := repo.GetS3KeyBySHA256(ctx, project.ID, "project-own", sha)
+	require.NoError(t, err)
+	assert.Equal(t, artifact.S3Key, key)
+	key, err = repo.GetS3KeyBySHA256(ctx, project.ID, "deployment", sha)
+	require.NoError(t, err)
+	assert.Equal(t, part.S3Key, key)
+
+	// Deleting the artifact deletes its own object and keeps the message part
+	require.NoError(t, repo.DecrementAssetRef(ctx, project.ID, artifact))
+	assert.Equal(t, []string{"/project-own/disks/a.txt"}, deleted)
+	key, err = repo.GetS3KeyBySHA256(ctx, project.ID, "deployment", sha)
+	require.NoError(t, err)
+	assert.Equal(t, part.S3Key, key)
+
+	require.NoError(t, repo.BatchDecrementAssetRefs(ctx, project.ID, []model.Asset{part}))
+	assert.Equal(t, []string{"/project-own/disks/a.txt", "/deployment/assets/a.txt"}, deleted)
+}
@@ -2,7 +2,9 @@ package repo
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
@@ -15,20 +17,59 @@ type BlockRepo interface {
 	Create(ctx context.Context, b *model.Block) error
 	Delete(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) error
 	Get(ctx context.Context, id uuid.UUID) (*model.Block, error)
+	GetMany(ctx context.Context, blockIDs []uuid.UUID) ([]model.Block, error)
 	Update(ctx context.Context, b *model.Block) error
+	PatchProps(ctx context.Context, id uuid.UUID, patch map[string]any, validate func(b *model.Block) error) (*model.Block, error)
+	UpdateMany(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID, apply func(b *model.Block) error) (map[uuid.UUID]error, error)
 	ListBySpace(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error)
 	NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error)
 	MoveToParentAppend(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID) error
 	ReorderWithinGroup(ctx context.Context, id uuid.UUID, newSort int64) error
 	MoveToParentAtSort(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, targetSort int64) error
+	MoveBatch(ctx context.Context, blockIDs []uuid.UUID, newParentID *uuid.UUID, folderPaths map[uuid.UUID]string) error
+	MoveToSpace(ctx context.Context, id uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error
+	LastMove(ctx context.Context, id uuid.UUID) (*model.BlockMove, error)
+	UndoMove(ctx context.Context, move *model.BlockMove) error
+	CreateTree(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, blocks []*model.Block) error
+	CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error)
+	Depth(ctx context.Context, id uuid.UUID) (int, error)
+	SubtreeHeight(ctx context.Context, id uuid.UUID) (int, error)
+	RebalanceSorts(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error
+	SetLastViewed(ctx context.Context, views map[uuid.UUID]time.Time) error
+	ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error)
 }
 
-type blockRepo struct{ db *gorm.DB }
+type blockRepo struct {
+	db       *gorm.DB
+	sortStep int64
+}
 
-func NewBlockRepo(db *gorm.DB) BlockRepo { return &blockRepo{db: db} }
+// NewBlockRepo creates a block repo. With a sortStep of 1 the sorts of a group stay contiguous
+// and a move shifts the siblings in between. With a larger step siblings are spaced sortStep
+// apart and a move only updates the moved block, until its new neighbours leave no room and
+// the group is rebalanced.
+func NewBlockRepo(db *gorm.DB, sortStep int64) BlockRepo {
+	if sortStep < 1 {
+		sortStep = 1
+	}
+	return &blockRepo{db: db, sortStep: sortStep}
+}
 
+// Create appends the block to its group (space_id, parent_id), overwriting b.Sort. The sort is
+// computed and the row inserted in one transaction with the group locked, so concurrent creates
+// in a group get distinct, contiguous sorts.
 func (r *blockRepo) Create(ctx context.Context, b *model.Block) error {
-	return r.db.WithContext(ctx).Create(b).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroupInTransaction(tx, b.SpaceID, b.ParentID); err != nil {
+			return err
+		}
+		next, err := r.nextSortInTransaction(tx, b.SpaceID, b.ParentID)
+		if err != nil {
+			return err
+		}
+		b.Sort = next
+		return tx.Create(b).Error
+	})
 }
 
 func (r *blockRepo) Delete(ctx context.Context, spaceID uuid.UUID, id uuid.UUID) error {
@@ -52,10 +93,104 @@ func (r *blockRepo) Get(ctx context.Context, id uuid.UUID) (*model.Block, error)
 	return &b, nil
 }
 
+// GetMany loads the blocks with the given IDs in a single query. IDs without a block are
+// skipped and the blocks come back in no particular order.
+func (r *blockRepo) GetMany(ctx context.Context, blockIDs []uuid.UUID) ([]model.Block, error) {
+	var list []model.Block
+	if len(blockIDs) == 0 {
+		return list, nil
+	}
+	err := r.db.WithContext(ctx).
+		Preload("ToolSOPs.ToolReference").
+		Where("id IN ?", blockIDs).
+		Find(&list).Error
+	if err != nil {
+		return list, err
+	}
+
+	// Merge ToolSOPs into Props for SOP blocks
+	for i := range list {
+		r.mergeToolSOPsIntoProps(&list[i])
+	}
+
+	return list, nil
+}
+
 func (r *blockRepo) Update(ctx context.Context, b *model.Block) error {
 	return r.db.WithContext(ctx).Where(&model.Block{ID: b.ID}).Updates(b).Error
 }
 
+// PatchProps merges patch into the block's props under a row lock, so concurrent patches don't clobber each other.
+// The merged block is saved only if validate, when given, returns nil.
+func (r *blockRepo) PatchProps(ctx context.Context, id uuid.UUID, patch map[string]any, validate func(b *model.Block) error) (*model.Block, error) {
+	var b model.Block
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+
+		b.MergeProps(patch)
+		if validate != nil {
+			if err := validate(&b); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("props", b.Props).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// UpdateMany updates the title and props of the blocks of a space in a single transaction.
+// Each block is loaded under a row lock and passed to apply, which edits it in place; its
+// title and props are then saved. Blocks that are not in the space, or for which apply fails,
+// are left untouched and returned with their error, gorm.ErrRecordNotFound for missing ones.
+func (r *blockRepo) UpdateMany(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID, apply func(b *model.Block) error) (map[uuid.UUID]error, error) {
+	failed := make(map[uuid.UUID]error)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock in a stable order so concurrent updates cannot deadlock
+		var locked []model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("space_id = ? AND id IN ?", spaceID, ids).
+			Order("id").
+			Find(&locked).Error; err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]*model.Block, len(locked))
+		for i := range locked {
+			byID[locked[i].ID] = &locked[i]
+		}
+
+		for _, id := range ids {
+			b, ok := byID[id]
+			if !ok {
+				failed[id] = gorm.ErrRecordNotFound
+				continue
+			}
+			if err := apply(b); err != nil {
+				failed[id] = err
+				continue
+			}
+			err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(map[string]any{
+				"title": b.Title,
+				"props": b.Props,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+// ListBySpace lists the children of parentID, or the root blocks when it is nil, optionally
+// of one type. Without type and parent only the root pages and folders are returned.
 func (r *blockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	var list []model.Block
 	query := r.db.WithContext(ctx).
@@ -64,6 +199,9 @@ func (r *blockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockTyp
 
 	if blockType != "" {
 		query = query.Where("type = ?", blockType)
+	} else if parentID == nil {
+		// The root overview lists the pages and folders, the only types meant to live at the root
+		query = query.Where("type IN ?", []string{model.BlockTypePage, model.BlockTypeFolder})
 	}
 
 	if parentID == nil {
@@ -86,16 +224,113 @@ func (r *blockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockTyp
 	return list, nil
 }
 
-// NextSort returns max(sort)+1 within group (space_id, parent_id)
-func (r *blockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
-	type result struct{ Next int64 }
-	var res result
-	query := r.buildGroupQuery(r.db.WithContext(ctx), spaceID, parentID).
-		Select("COALESCE(MAX(sort), -1) + 1 AS next")
-	if err := query.Take(&res).Error; err != nil {
-		return 0, err
+// SetLastViewed moves the last view time of each block forward to the given time, leaving
+// updated_at untouched. Blocks deleted since are skipped.
+func (r *blockRepo) SetLastViewed(ctx context.Context, views map[uuid.UUID]time.Time) error {
+	if len(views) == 0 {
+		return nil
 	}
-	return res.Next, nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, at := range views {
+			err := tx.Model(&model.Block{}).
+				Where("id = ?", id).
+				UpdateColumn("last_viewed_at", gorm.Expr("GREATEST(COALESCE(last_viewed_at, ?), ?)", at, at)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListRecentlyViewed returns up to limit blocks of a space that were viewed, latest view first
+func (r *blockRepo) ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error) {
+	var list []model.Block
+	err := r.db.WithContext(ctx).
+		Where("space_id = ? AND last_viewed_at IS NOT NULL", spaceID).
+		Order("last_viewed_at DESC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// CountByType counts the blocks of a space per type in a single query.
+// With a parentID only its direct children are counted.
+func (r *blockRepo) CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error) {
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	query := r.db.WithContext(ctx).
+		Model(&model.Block{}).
+		Select("type, COUNT(*) AS count").
+		Where("space_id = ?", spaceID)
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if err := query.Group("type").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+// Depth returns the level of a block in its tree, 1 for a root block. A missing block has depth 0.
+func (r *blockRepo) Depth(ctx context.Context, id uuid.UUID) (int, error) {
+	var depth int
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id, 1 AS depth FROM blocks WHERE id = ?
+		UNION ALL
+		SELECT b.id, b.parent_id, a.depth + 1 FROM blocks b JOIN ancestors a ON b.id = a.parent_id
+	) SELECT COALESCE(MAX(depth), 0) FROM ancestors`, id).Scan(&depth).Error
+	return depth, err
+}
+
+// SubtreeHeight returns the number of levels of the subtree of a block, 1 for a block without
+// children. A missing block has height 0.
+func (r *blockRepo) SubtreeHeight(ctx context.Context, id uuid.UUID) (int, error) {
+	var height int
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE subtree AS (
+		SELECT id, 1 AS depth FROM blocks WHERE id = ?
+		UNION ALL
+		SELECT b.id, s.depth + 1 FROM blocks b JOIN subtree s ON b.parent_id = s.id
+	) SELECT COALESCE(MAX(depth), 0) FROM subtree`, id).Scan(&height).Error
+	return height, err
+}
+
+// NextSort returns max(sort)+sortStep within group (space_id, parent_id)
+func (r *blockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
+	return r.nextSortInTransaction(r.db.WithContext(ctx), spaceID, parentID)
+}
+
+// CreateTree inserts a block tree in a single transaction. Blocks must be ordered parents first
+// and numbered from 0 within their group; the top-level blocks (those under parentID) are appended
+// after the existing blocks of that group keeping their relative order, while nested blocks start
+// a group of their own.
+func (r *blockRepo) CreateTree(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, blocks []*model.Block) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockGroupInTransaction(tx, spaceID, parentID); err != nil {
+			return err
+		}
+		next, err := r.nextSortInTransaction(tx, spaceID, parentID)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range blocks {
+			if (b.ParentID == nil && parentID == nil) || (b.ParentID != nil && parentID != nil && *b.ParentID == *parentID) {
+				b.Sort = next + b.Sort*r.sortStep
+			} else {
+				b.Sort = r.firstSort() + b.Sort*r.sortStep
+			}
+		}
+
+		return tx.CreateInBatches(blocks, 100).Error
+	})
 }
 
 // MoveToParentAppend moves the block to new parent and sets sort to tail in a single transaction.
@@ -106,33 +341,43 @@ func (r *blockRepo) MoveToParentAppend(ctx context.Context, id uuid.UUID, newPar
 			return err
 		}
 
-		// Compute next sort in target group
-		var next int64
-		q := r.buildGroupQuery(tx, b.SpaceID, newParentID).Select("COALESCE(MAX(sort), -1) + 1")
-		if err := q.Take(&next).Error; err != nil {
+		before := b
+		if err := r.appendInTransaction(tx, &b, newParentID); err != nil {
 			return err
 		}
-
-		// Move to new parent at end
-		return tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Updates(map[string]any{
-			"parent_id": newParentID,
-			"sort":      next,
-		}).Error
+		return r.recordMoveInTransaction(tx, before)
 	})
 }
 
 // ReorderWithinGroup safely reorders an item to newSort within its current (space_id, parent_id) group.
+// The item takes the place of the block sorted newSort, or of the first block sorted after it.
 func (r *blockRepo) ReorderWithinGroup(ctx context.Context, id uuid.UUID, newSort int64) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var b model.Block
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
 			return err
 		}
-		return r.reorderInTransaction(tx, &b, newSort)
+
+		before := b
+		var err error
+		if r.gapped() {
+			var pos int64
+			if pos, err = r.positionOfSort(tx, b.SpaceID, b.ParentID, newSort); err != nil {
+				return err
+			}
+			err = r.placeInTransaction(tx, &b, b.ParentID, pos)
+		} else {
+			err = r.reorderInTransaction(tx, &b, newSort)
+		}
+		if err != nil {
+			return err
+		}
+		return r.recordMoveInTransaction(tx, before)
 	})
 }
 
-// MoveToParentAtSort moves a block to a specific position in the target parent group.
+// MoveToParentAtSort moves a block to a specific position in the target parent group, the place
+// of the block sorted targetSort there, or of the first block sorted after it.
 func (r *blockRepo) MoveToParentAtSort(ctx context.Context, id uuid.UUID, newParentID *uuid.UUID, targetSort int64) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Lock and load current block
@@ -141,20 +386,410 @@ func (r *blockRepo) MoveToParentAtSort(ctx context.Context, id uuid.UUID, newPar
 			return err
 		}
 
-		// Check if moving within same group
-		sameGroup := (b.ParentID == nil && newParentID == nil) ||
-			(b.ParentID != nil && newParentID != nil && *b.ParentID == *newParentID)
+		before := b
+		pos := targetSort
+		if r.gapped() {
+			var err error
+			if pos, err = r.positionOfSort(tx, b.SpaceID, newParentID, targetSort); err != nil {
+				return err
+			}
+		}
+		if err := r.moveInTransaction(tx, &b, newParentID, pos); err != nil {
+			return err
+		}
+		return r.recordMoveInTransaction(tx, before)
+	})
+}
 
-		if sameGroup {
-			// Same group: simple reorder
-			return r.reorderInTransaction(tx, &b, targetSort)
+// moveInTransaction moves a block to position targetSort of the target group
+func (r *blockRepo) moveInTransaction(tx *gorm.DB, b *model.Block, newParentID *uuid.UUID, targetSort int64) error {
+	if r.gapped() {
+		return r.placeInTransaction(tx, b, newParentID, targetSort)
+	}
+
+	// Check if moving within same group
+	sameGroup := (b.ParentID == nil && newParentID == nil) ||
+		(b.ParentID != nil && newParentID != nil && *b.ParentID == *newParentID)
+
+	if sameGroup {
+		// Same group: simple reorder
+		return r.reorderInTransaction(tx, b, targetSort)
+	}
+
+	// Different group: move to new parent
+	return r.moveToNewParentInTransaction(tx, b, b.ID, newParentID, targetSort)
+}
+
+// MoveBatch moves the blocks to the tail of the new parent group in a single transaction,
+// keeping the order of blockIDs. Blocks already in that group are moved to its tail too.
+// The folders in folderPaths get their new path in the same transaction.
+func (r *blockRepo) MoveBatch(ctx context.Context, blockIDs []uuid.UUID, newParentID *uuid.UUID, folderPaths map[uuid.UUID]string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock all blocks up front, in a stable order so concurrent batches cannot deadlock
+		var locked []model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", blockIDs).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		if len(locked) != len(blockIDs) {
+			return gorm.ErrRecordNotFound
 		}
 
-		// Different group: move to new parent
-		return r.moveToNewParentInTransaction(tx, &b, id, newParentID, targetSort)
+		for _, id := range blockIDs {
+			// Reload: earlier moves may have shifted this block's sort
+			var b model.Block
+			if err := tx.Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+				return err
+			}
+			if path, ok := folderPaths[id]; ok {
+				b.SetFolderPath(path)
+				if err := tx.Model(&model.Block{}).Where(&model.Block{ID: id}).Update("props", b.Props).Error; err != nil {
+					return err
+				}
+			}
+			before := b
+			if err := r.moveToTailInTransaction(tx, &b, newParentID); err != nil {
+				return err
+			}
+			if err := r.recordMoveInTransaction(tx, before); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
+// moveToTailInTransaction moves a block to the tail of the target group, like MoveBatch
+func (r *blockRepo) moveToTailInTransaction(tx *gorm.DB, b *model.Block, newParentID *uuid.UUID) error {
+	if r.gapped() {
+		return r.appendInTransaction(tx, b, newParentID)
+	}
+
+	sameGroup := (b.ParentID == nil && newParentID == nil) ||
+		(b.ParentID != nil && newParentID != nil && *b.ParentID == *newParentID)
+
+	var maxSort int64
+	q := r.buildGroupQuery(tx, b.SpaceID, newParentID).Select("COALESCE(MAX(sort), -1)")
+	if err := q.Take(&maxSort).Error; err != nil {
+		return err
+	}
+
+	if sameGroup {
+		return r.reorderInTransaction(tx, b, maxSort)
+	}
+	return r.moveToNewParentInTransaction(tx, b, b.ID, newParentID, maxSort+1)
+}
+
+// MoveToSpace moves a block and its subtree to another space of the same project, appending
+// the block to the tail of the newParentID group there, in a single transaction. Descendants
+// keep their parents and sorts. The move history of the subtree is dropped, since it refers
+// to positions in the old space. It returns gorm.ErrRecordNotFound when the target space is
+// not in the project of the block, or newParentID is not in the target space.
+func (r *blockRepo) MoveToSpace(ctx context.Context, id uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: id}).First(&b).Error; err != nil {
+			return err
+		}
+
+		sameProject := tx.Model(&model.Space{}).Select("project_id").Where("id = ?", b.SpaceID)
+		if err := tx.Select("id").Where("id = ? AND project_id = (?)", targetSpaceID, sameProject).Take(&model.Space{}).Error; err != nil {
+			return err
+		}
+		if err := r.lockGroupInTransaction(tx, targetSpaceID, newParentID); err != nil {
+			return err
+		}
+
+		var subtree []uuid.UUID
+		err := tx.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM blocks WHERE id = ?
+			UNION ALL
+			SELECT b.id FROM blocks b JOIN subtree s ON b.parent_id = s.id
+		) SELECT id FROM subtree`, b.ID).Scan(&subtree).Error
+		if err != nil {
+			return err
+		}
+
+		next, err := r.nextSortInTransaction(tx, targetSpaceID, newParentID)
+		if err != nil {
+			return err
+		}
+		// The block first, so its old sort cannot collide with a root block of the target space
+		err = tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Updates(map[string]any{
+			"space_id":  targetSpaceID,
+			"parent_id": newParentID,
+			"sort":      next,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Block{}).Where("id IN ?", subtree).Update("space_id", targetSpaceID).Error; err != nil {
+			return err
+		}
+
+		// Contiguous sorts close the gap left in the old group
+		if !r.gapped() {
+			oldGroup := r.buildGroupQuery(tx, b.SpaceID, b.ParentID)
+			if err := oldGroup.Where("sort > ?", b.Sort).Update("sort", gorm.Expr("sort - 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("block_id IN ?", subtree).Delete(&model.BlockMove{}).Error
+	})
+}
+
+// LastMove returns the latest recorded move of a block
+func (r *blockRepo) LastMove(ctx context.Context, id uuid.UUID) (*model.BlockMove, error) {
+	var move model.BlockMove
+	err := r.db.WithContext(ctx).Where("block_id = ?", id).Order("created_at DESC").First(&move).Error
+	if err != nil {
+		return nil, err
+	}
+	return &move, nil
+}
+
+// UndoMove moves a block back to where move took it from and drops move from the history,
+// in a single transaction. The block goes back to the position its old sort has among the
+// current siblings of the old parent. Undoing is not recorded as a move itself, so repeated
+// undos walk back through the history. It returns gorm.ErrRecordNotFound if move was
+// already undone.
+func (r *blockRepo) UndoMove(ctx context.Context, move *model.BlockMove) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: move.BlockID}).First(&b).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where("id = ? AND block_id = ?", move.ID, move.BlockID).Delete(&model.BlockMove{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Contiguous sorts are positions already; gapped ones are turned into the number of
+		// siblings sorted before them
+		pos := move.OldSort
+		if r.gapped() {
+			err := r.buildGroupQuery(tx, b.SpaceID, move.OldParentID).
+				Where("id <> ? AND sort < ?", b.ID, move.OldSort).
+				Count(&pos).Error
+			if err != nil {
+				return err
+			}
+		}
+		return r.moveInTransaction(tx, &b, move.OldParentID, pos)
+	})
+}
+
+// recordMoveInTransaction records the move of a block from its position in before to its
+// current one, keeping the latest model.MaxBlockMoveHistory moves of the block
+func (r *blockRepo) recordMoveInTransaction(tx *gorm.DB, before model.Block) error {
+	var after model.Block
+	if err := tx.Select("id", "parent_id", "sort").Where(&model.Block{ID: before.ID}).First(&after).Error; err != nil {
+		return err
+	}
+	sameParent := (before.ParentID == nil && after.ParentID == nil) ||
+		(before.ParentID != nil && after.ParentID != nil && *before.ParentID == *after.ParentID)
+	if sameParent && before.Sort == after.Sort {
+		return nil
+	}
+
+	move := &model.BlockMove{
+		BlockID:     before.ID,
+		OldParentID: before.ParentID,
+		OldSort:     before.Sort,
+		NewParentID: after.ParentID,
+		NewSort:     after.Sort,
+	}
+	if err := tx.Create(move).Error; err != nil {
+		return err
+	}
+
+	latest := tx.Model(&model.BlockMove{}).Select("id").
+		Where("block_id = ?", before.ID).
+		Order("created_at DESC").
+		Limit(model.MaxBlockMoveHistory)
+	return tx.Where("block_id = ? AND id NOT IN (?)", before.ID, latest).Delete(&model.BlockMove{}).Error
+}
+
+// RebalanceSorts re-spaces the sorts of a group sortStep apart, keeping their order
+func (r *blockRepo) RebalanceSorts(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return r.rebalanceInTransaction(tx, spaceID, parentID)
+	})
+}
+
+func (r *blockRepo) gapped() bool { return r.sortStep > 1 }
+
+// firstSort is the sort of the first block of a fresh or rebalanced group. Gapped groups keep
+// a gap in front of it, so blocks can be moved before it without a rebalance.
+func (r *blockRepo) firstSort() int64 {
+	if r.gapped() {
+		return r.sortStep
+	}
+	return 0
+}
+
+// lockGroupInTransaction serializes appends to the group (space_id, parent_id) until the
+// transaction ends, by locking the row owning the group: the parent block, or the space for
+// root blocks. FOR NO KEY UPDATE leaves the foreign key checks of inserted children unblocked.
+func (r *blockRepo) lockGroupInTransaction(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	lock := tx.Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Select("id")
+	if parentID == nil {
+		return lock.Where(&model.Space{ID: spaceID}).Take(&model.Space{}).Error
+	}
+	return lock.Where(&model.Block{ID: *parentID, SpaceID: spaceID}).Take(&model.Block{}).Error
+}
+
+// nextSortInTransaction returns the sort for a block appended to the group
+func (r *blockRepo) nextSortInTransaction(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) (int64, error) {
+	var next int64
+	err := r.buildGroupQuery(tx, spaceID, parentID).
+		Select("COALESCE(MAX(sort), ?) + ?", r.firstSort()-r.sortStep, r.sortStep).
+		Take(&next).Error
+	return next, err
+}
+
+// appendInTransaction moves a block to the tail of the target group
+func (r *blockRepo) appendInTransaction(tx *gorm.DB, b *model.Block, newParentID *uuid.UUID) error {
+	next, err := r.nextSortInTransaction(tx, b.SpaceID, newParentID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Updates(map[string]any{
+		"parent_id": newParentID,
+		"sort":      next,
+	}).Error
+}
+
+// placeInTransaction moves a block to position pos of the target group by giving it a sort
+// between its new neighbours, so only the block itself is updated. When the neighbours are
+// adjacent the group is rebalanced first.
+func (r *blockRepo) placeInTransaction(tx *gorm.DB, b *model.Block, parentID *uuid.UUID, pos int64) error {
+	if pos < 0 {
+		pos = 0
+	}
+	sameGroup := (b.ParentID == nil && parentID == nil) ||
+		(b.ParentID != nil && parentID != nil && *b.ParentID == *parentID)
+
+	for rebalanced := false; ; rebalanced = true {
+		prev, next, err := r.neighbourSorts(tx, b, parentID, pos)
+		if err != nil {
+			return err
+		}
+		if sameGroup && (prev == nil || *prev < b.Sort) && (next == nil || b.Sort < *next) {
+			return nil
+		}
+		if sort, ok := sortBetween(prev, next, r.sortStep); ok {
+			return tx.Model(&model.Block{}).Where(&model.Block{ID: b.ID}).Updates(map[string]any{
+				"parent_id": parentID,
+				"sort":      sort,
+			}).Error
+		}
+		if rebalanced {
+			return fmt.Errorf("no room for block %s at position %d after rebalancing", b.ID, pos)
+		}
+		if err := r.rebalanceInTransaction(tx, b.SpaceID, parentID); err != nil {
+			return err
+		}
+		if sameGroup {
+			if err := tx.Where(&model.Block{ID: b.ID}).First(b).Error; err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// positionOfSort turns a sort requested by a client into a position of the group: the number
+// of blocks sorted before it, the moved block included. The moved block thus takes the place
+// of the block holding that sort, as it does with contiguous sorts, where sorts are positions.
+func (r *blockRepo) positionOfSort(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID, sort int64) (int64, error) {
+	var pos int64
+	err := r.buildGroupQuery(tx, spaceID, parentID).Where("sort < ?", sort).Count(&pos).Error
+	return pos, err
+}
+
+// neighbourSorts returns the sorts of the blocks that end up right before and after b when it
+// is placed at position pos of the group. A nil sort means there is no block on that side.
+func (r *blockRepo) neighbourSorts(tx *gorm.DB, b *model.Block, parentID *uuid.UUID, pos int64) (*int64, *int64, error) {
+	siblings := r.buildGroupQuery(tx, b.SpaceID, parentID).Where("id <> ?", b.ID)
+
+	var sorts []int64
+	if pos == 0 {
+		if err := siblings.Order("sort").Limit(1).Pluck("sort", &sorts).Error; err != nil {
+			return nil, nil, err
+		}
+		if len(sorts) == 0 {
+			return nil, nil, nil
+		}
+		return nil, &sorts[0], nil
+	}
+
+	if err := siblings.Order("sort").Offset(int(pos-1)).Limit(2).Pluck("sort", &sorts).Error; err != nil {
+		return nil, nil, err
+	}
+	switch len(sorts) {
+	case 2:
+		return &sorts[0], &sorts[1], nil
+	case 1:
+		return &sorts[0], nil, nil
+	}
+
+	// Past the end of the group: append after the last sibling
+	if err := r.buildGroupQuery(tx, b.SpaceID, parentID).Where("id <> ?", b.ID).Order("sort DESC").Limit(1).Pluck("sort", &sorts).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(sorts) == 0 {
+		return nil, nil, nil
+	}
+	return &sorts[0], nil, nil
+}
+
+// sortBetween picks a sort strictly between prev and next, where nil means no bound on that
+// side. Sorts stay non-negative. It reports false when prev and next are adjacent.
+func sortBetween(prev, next *int64, step int64) (int64, bool) {
+	switch {
+	case prev == nil && next == nil:
+		return step, true
+	case next == nil:
+		return *prev + step, true
+	}
+
+	lo := int64(-1)
+	if prev != nil {
+		lo = *prev
+	}
+	if *next-lo < 2 {
+		return 0, false
+	}
+	return lo + (*next-lo)/2, true
+}
+
+// rebalanceInTransaction re-spaces the sorts of a group sortStep apart, starting at firstSort
+func (r *blockRepo) rebalanceInTransaction(tx *gorm.DB, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	group, args := "space_id = ? AND parent_id IS NULL", []any{spaceID}
+	if parentID != nil {
+		group, args = "space_id = ? AND parent_id = ?", []any{spaceID, *parentID}
+	}
+
+	// Park the group below both its lowest sort and 0 first, keeping the order, so the unique
+	// (space_id, parent_id, sort) index never sees two blocks on the same sort
+	park := `UPDATE blocks SET sort = g.base - g.cnt - 1 + g.rn FROM (
+		SELECT id, ROW_NUMBER() OVER (ORDER BY sort, id) AS rn, COUNT(*) OVER () AS cnt, LEAST(MIN(sort) OVER (), 0) AS base
+		FROM blocks WHERE ` + group + `
+	) g WHERE blocks.id = g.id`
+	if err := tx.Exec(park, args...).Error; err != nil {
+		return err
+	}
+
+	respace := `UPDATE blocks SET sort = ? + (g.rn - 1) * ? FROM (
+		SELECT id, ROW_NUMBER() OVER (ORDER BY sort) AS rn FROM blocks WHERE ` + group + `
+	) g WHERE blocks.id = g.id`
+	return tx.Exec(respace, append([]any{r.firstSort(), r.sortStep}, args...)...).Error
+}
+
 // reorderInTransaction reorders a block within its current parent group
 func (r *blockRepo) reorderInTransaction(tx *gorm.DB, b *model.Block, targetSort int64) error {
 	if targetSort < 0 {
@@ -2,7 +2,11 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
@@ -30,6 +34,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&model.Project{},
 		&model.Space{},
 		&model.Block{},
+		&model.BlockMove{},
 		&model.ToolReference{},
 		&model.ToolSOP{},
 	)
@@ -154,7 +159,7 @@ func TestBlockRepo_GetSOPBlockWithToolSOPs(t *testing.T) {
 	if db == nil {
 		return // Test was skipped
 	}
-	repo := NewBlockRepo(db)
+	repo := NewBlockRepo(db, 1)
 	ctx := context.Background()
 
 	// Create a project
@@ -266,7 +271,7 @@ func TestBlockRepo_ListSOPBlocksWithToolSOPs(t *testing.T) {
 	if db == nil {
 		return // Test was skipped
 	}
-	repo := NewBlockRepo(db)
+	repo := NewBlockRepo(db, 1)
 	ctx := context.Background()
 
 	// Create a project
@@ -362,13 +367,74 @@ func TestBlockRepo_ListSOPBlocksWithToolSOPs(t *testing.T) {
 	}
 }
 
+// TestBlockRepo_ToolSOPChangesTouchBlock tests that every change to the steps of a SOP block,
+// including a rename of their tool, moves the update time the block's ETag is derived from
+func TestBlockRepo_ToolSOPChangesTouchBlock(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	sopRepo := NewToolSOPRepo(db)
+	toolRepo := NewToolReferenceRepo(db)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_sop_touch",
+		SecretKeyHashPHC: "test_hash_sop_touch",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(space).Error)
+	pageBlock := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, db.Create(pageBlock).Error)
+	sopBlock := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeSOP, Title: "SOP", ParentID: &pageBlock.ID}
+	require.NoError(t, db.Create(sopBlock).Error)
+	tool := &model.ToolReference{ID: uuid.New(), ProjectID: project.ID, Name: "web_search"}
+	require.NoError(t, db.Create(tool).Error)
+
+	// changed asserts that edit moves the update time of the SOP block
+	changed := func(t *testing.T, edit func() error) {
+		before, err := repo.Get(ctx, sopBlock.ID)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+		require.NoError(t, edit())
+		after, err := repo.Get(ctx, sopBlock.ID)
+		require.NoError(t, err)
+		assert.True(t, after.UpdatedAt.After(before.UpdatedAt), "updated_at should advance")
+	}
+
+	first := &model.ToolSOP{SOPBlockID: sopBlock.ID, ToolReferenceID: tool.ID, Action: "search"}
+	second := &model.ToolSOP{SOPBlockID: sopBlock.ID, ToolReferenceID: tool.ID, Action: "search again"}
+	t.Run("append", func(t *testing.T) {
+		changed(t, func() error { return sopRepo.Append(ctx, first) })
+		changed(t, func() error { return sopRepo.Append(ctx, second) })
+	})
+	t.Run("reorder", func(t *testing.T) {
+		changed(t, func() error { return sopRepo.Reorder(ctx, sopBlock.ID, second.ID, 0) })
+	})
+	t.Run("delete", func(t *testing.T) {
+		changed(t, func() error { return sopRepo.Delete(ctx, sopBlock.ID, first.ID) })
+	})
+	t.Run("tool rename", func(t *testing.T) {
+		tool.Name = "search_web"
+		changed(t, func() error { return toolRepo.Update(ctx, tool) })
+	})
+	t.Run("tool delete", func(t *testing.T) {
+		changed(t, func() error { return toolRepo.Delete(ctx, project.ID, tool.ID) })
+	})
+}
+
 // TestBlockRepo_GetNonSOPBlock tests that non-SOP blocks don't get tool_sops merged
 func TestBlockRepo_GetNonSOPBlock(t *testing.T) {
 	db := setupTestDB(t)
 	if db == nil {
 		return // Test was skipped
 	}
-	repo := NewBlockRepo(db)
+	repo := NewBlockRepo(db, 1)
 	ctx := context.Background()
 
 	// Create a project
@@ -411,6 +477,679 @@ func TestBlockRepo_GetNonSOPBlock(t *testing.T) {
 }
 
 // Helper function to create string pointers
+// TestBlockRepo_MoveToParentAppend_ToRoot tests moving a nested page to root level
+func TestBlockRepo_MoveToParentAppend_ToRoot(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+	}
+	require.NoError(t, db.Create(space).Error)
+
+	// Root group: a folder and a page
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 0}
+	require.NoError(t, db.Create(folder).Error)
+	rootPage := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Root Page", Sort: 1}
+	require.NoError(t, db.Create(rootPage).Error)
+
+	// A page nested in the folder
+	nested := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Nested", ParentID: &folder.ID, Sort: 0}
+	require.NoError(t, db.Create(nested).Error)
+
+	require.NoError(t, repo.MoveToParentAppend(ctx, nested.ID, nil))
+
+	var moved model.Block
+	require.NoError(t, db.Where("id = ?", nested.ID).First(&moved).Error)
+	assert.Nil(t, moved.ParentID, "parent_id should be NULL")
+	assert.Equal(t, int64(2), moved.Sort, "should be appended after the existing root blocks")
+
+	var nullParents int64
+	require.NoError(t, db.Model(&model.Block{}).Where("id = ? AND parent_id IS NULL", nested.ID).Count(&nullParents).Error)
+	assert.Equal(t, int64(1), nullParents)
+}
+
+func TestBlockRepo_ListBySpace_RootOverview(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+	}
+	require.NoError(t, db.Create(space).Error)
+
+	// Root blocks of every type, including a text block left at the root
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page", Sort: 0}
+	require.NoError(t, db.Create(page).Error)
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "Folder", Sort: 1}
+	require.NoError(t, db.Create(folder).Error)
+	text := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, Title: "Text", Sort: 2}
+	require.NoError(t, db.Create(text).Error)
+
+	results, err := repo.ListBySpace(ctx, space.ID, "", nil)
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, b := range results {
+		ids = append(ids, b.ID)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{page.ID, folder.ID}, ids, "default query should return only root pages and folders")
+
+	// An explicit type still lists root blocks of that type
+	results, err = repo.ListBySpace(ctx, space.ID, model.BlockTypeText, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, text.ID, results[0].ID)
+}
+
+func TestBlockRepo_Create_ConcurrentSorts(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+	}
+	require.NoError(t, db.Create(space).Error)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, repo.Create(ctx, page))
+
+	for _, parentID := range []*uuid.UUID{&page.ID, nil} {
+		const n = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				b := &model.Block{SpaceID: space.ID, Type: model.BlockTypePage, Title: fmt.Sprintf("Block %d", i), ParentID: parentID}
+				if parentID != nil {
+					b.Type = model.BlockTypeText
+				}
+				errs <- repo.Create(ctx, b)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		var sorts []int64
+		require.NoError(t, repo.(*blockRepo).buildGroupQuery(db, space.ID, parentID).Order("sort").Pluck("sort", &sorts).Error)
+		count := n
+		if parentID == nil {
+			count++ // the page itself
+		}
+		want := make([]int64, count)
+		for i := range want {
+			want[i] = int64(i)
+		}
+		assert.Equal(t, want, sorts, "sorts should be unique and contiguous")
+	}
+}
+
+func TestBlockRepo_MoveBatch(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+	}
+	require.NoError(t, db.Create(space).Error)
+
+	newBlock := func(title string, parentID *uuid.UUID, sort int64) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: title, ParentID: parentID, Sort: sort}
+		if title == "Target" {
+			b.Type = model.BlockTypeFolder
+		}
+		require.NoError(t, db.Create(b).Error)
+		return b
+	}
+
+	// Root: Target(0), A(1), B(2), C(3), D(4); Target holds X(0)
+	target := newBlock("Target", nil, 0)
+	a := newBlock("A", nil, 1)
+	b := newBlock("B", nil, 2)
+	newBlock("C", nil, 3)
+	d := newBlock("D", nil, 4)
+	x := newBlock("X", &target.ID, 0)
+
+	folder := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeFolder, Title: "F", Sort: 5}
+	folder.SetFolderPath("F")
+	require.NoError(t, db.Create(folder).Error)
+	pathOf := func(id uuid.UUID) string {
+		var blk model.Block
+		require.NoError(t, db.Where("id = ?", id).First(&blk).Error)
+		return blk.GetFolderPath()
+	}
+
+	// Move D, B, X into Target in that order; X is already there and goes to the tail
+	require.NoError(t, repo.MoveBatch(ctx, []uuid.UUID{d.ID, b.ID, x.ID}, &target.ID, nil))
+
+	sorted := func(parentID *uuid.UUID) []string {
+		var blocks []model.Block
+		q := db.Where("space_id = ?", space.ID).Order("sort")
+		if parentID == nil {
+			q = q.Where("parent_id IS NULL")
+		} else {
+			q = q.Where("parent_id = ?", *parentID)
+		}
+		require.NoError(t, q.Find(&blocks).Error)
+		titles := make([]string, 0, len(blocks))
+		for i, blk := range blocks {
+			assert.Equal(t, int64(i), blk.Sort, "sorts stay contiguous")
+			titles = append(titles, blk.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"D", "B", "X"}, sorted(&target.ID))
+	assert.Equal(t, []string{"Target", "A", "C", "F"}, sorted(nil))
+
+	// A missing block rolls the whole batch back, folder paths included
+	err := repo.MoveBatch(ctx, []uuid.UUID{folder.ID, a.ID, uuid.New()}, &target.ID, map[uuid.UUID]string{folder.ID: "Target/F"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Equal(t, []string{"Target", "A", "C", "F"}, sorted(nil))
+	assert.Equal(t, "F", pathOf(folder.ID))
+
+	// Folders get their path with the move
+	require.NoError(t, repo.MoveBatch(ctx, []uuid.UUID{folder.ID}, &target.ID, map[uuid.UUID]string{folder.ID: "Target/F"}))
+	assert.Equal(t, []string{"D", "B", "X", "F"}, sorted(&target.ID))
+	assert.Equal(t, "Target/F", pathOf(folder.ID))
+}
+
+func TestBlockRepo_UndoMove(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(space).Error)
+
+	newBlock := func(title string, blockType string, sort int64) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: blockType, Title: title, Sort: sort}
+		require.NoError(t, db.Create(b).Error)
+		return b
+	}
+
+	// Root: A(0), B(1), C(2), Target(3)
+	newBlock("A", model.BlockTypePage, 0)
+	b := newBlock("B", model.BlockTypePage, 1)
+	newBlock("C", model.BlockTypePage, 2)
+	target := newBlock("Target", model.BlockTypeFolder, 3)
+
+	load := func(id uuid.UUID) model.Block {
+		var blk model.Block
+		require.NoError(t, db.Where("id = ?", id).First(&blk).Error)
+		return blk
+	}
+
+	require.NoError(t, repo.MoveToParentAtSort(ctx, b.ID, &target.ID, 0))
+	moved := load(b.ID)
+	require.NotNil(t, moved.ParentID)
+	assert.Equal(t, target.ID, *moved.ParentID)
+
+	move, err := repo.LastMove(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Nil(t, move.OldParentID)
+	assert.Equal(t, int64(1), move.OldSort)
+	assert.Equal(t, target.ID, *move.NewParentID)
+
+	require.NoError(t, repo.UndoMove(ctx, move))
+	restored := load(b.ID)
+	assert.Nil(t, restored.ParentID, "the original parent is restored")
+	assert.Equal(t, int64(1), restored.Sort, "the original sort is restored")
+
+	// The undone move is gone, and undoing it twice fails
+	_, err = repo.LastMove(ctx, b.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.UndoMove(ctx, move), gorm.ErrRecordNotFound)
+
+	t.Run("keeps the latest moves only", func(t *testing.T) {
+		for i := 0; i < model.MaxBlockMoveHistory+5; i++ {
+			require.NoError(t, repo.ReorderWithinGroup(ctx, b.ID, int64(i%2)))
+		}
+		var count int64
+		require.NoError(t, db.Model(&model.BlockMove{}).Where("block_id = ?", b.ID).Count(&count).Error)
+		assert.Equal(t, int64(model.MaxBlockMoveHistory), count)
+	})
+}
+
+func TestBlockRepo_MoveToSpace(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	newProject := func() *model.Project {
+		p := &model.Project{ID: uuid.New(), SecretKeyHMAC: "test_hmac", SecretKeyHashPHC: "test_hash"}
+		require.NoError(t, db.Create(p).Error)
+		return p
+	}
+	project := newProject()
+	defer cleanupTestDB(t, db, project.ID)
+	otherProject := newProject()
+	defer cleanupTestDB(t, db, otherProject.ID)
+
+	source := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	target := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	foreign := &model.Space{ID: uuid.New(), ProjectID: otherProject.ID}
+	for _, space := range []*model.Space{source, target, foreign} {
+		require.NoError(t, db.Create(space).Error)
+	}
+
+	newBlock := func(spaceID uuid.UUID, title string, blockType string, parentID *uuid.UUID, sort int64) *model.Block {
+		b := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: blockType, Title: title, ParentID: parentID, Sort: sort}
+		require.NoError(t, db.Create(b).Error)
+		return b
+	}
+	load := func(id uuid.UUID) model.Block {
+		var blk model.Block
+		require.NoError(t, db.Where("id = ?", id).First(&blk).Error)
+		return blk
+	}
+
+	// Source root: Before(0), Page(1), After(2); Page holds Child(0), which holds Text(0)
+	newBlock(source.ID, "Before", model.BlockTypePage, nil, 0)
+	page := newBlock(source.ID, "Page", model.BlockTypePage, nil, 1)
+	after := newBlock(source.ID, "After", model.BlockTypePage, nil, 2)
+	child := newBlock(source.ID, "Child", model.BlockTypePage, &page.ID, 0)
+	text := newBlock(source.ID, "Text", model.BlockTypeText, &child.ID, 0)
+	// Target root: Existing(0), Page(1)
+	newBlock(target.ID, "Existing", model.BlockTypePage, nil, 0)
+	newBlock(target.ID, "Page", model.BlockTypePage, nil, 1)
+
+	require.NoError(t, repo.ReorderWithinGroup(ctx, page.ID, 0))
+	require.NoError(t, repo.ReorderWithinGroup(ctx, page.ID, 1))
+
+	t.Run("target space of another project", func(t *testing.T) {
+		assert.ErrorIs(t, repo.MoveToSpace(ctx, page.ID, foreign.ID, nil), gorm.ErrRecordNotFound)
+		assert.Equal(t, source.ID, load(page.ID).SpaceID)
+	})
+
+	require.NoError(t, repo.MoveToSpace(ctx, page.ID, target.ID, nil))
+
+	moved := load(page.ID)
+	assert.Equal(t, target.ID, moved.SpaceID)
+	assert.Nil(t, moved.ParentID)
+	assert.Equal(t, int64(2), moved.Sort, "appended after the root blocks of the target space")
+
+	for _, id := range []uuid.UUID{child.ID, text.ID} {
+		descendant := load(id)
+		assert.Equal(t, target.ID, descendant.SpaceID, "descendants follow the block")
+		assert.Equal(t, int64(0), descendant.Sort)
+	}
+	assert.Equal(t, page.ID, *load(child.ID).ParentID)
+	assert.Equal(t, child.ID, *load(text.ID).ParentID)
+
+	assert.Equal(t, int64(1), load(after.ID).Sort, "the gap in the source space is closed")
+
+	var moves int64
+	require.NoError(t, db.Model(&model.BlockMove{}).Where("block_id = ?", page.ID).Count(&moves).Error)
+	assert.Zero(t, moves, "the move history of the moved block is dropped")
+}
+
+func TestBlockRepo_DepthAndSubtreeHeight(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{ID: uuid.New(), SecretKeyHMAC: "test_hmac", SecretKeyHashPHC: "test_hash"}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+	space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(space).Error)
+
+	// Folder > Sub > Page > Text
+	var parentID *uuid.UUID
+	var chain []uuid.UUID
+	for _, blockType := range []string{model.BlockTypeFolder, model.BlockTypeFolder, model.BlockTypePage, model.BlockTypeText} {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: blockType, Title: blockType, ParentID: parentID}
+		require.NoError(t, db.Create(b).Error)
+		chain = append(chain, b.ID)
+		parentID = &b.ID
+	}
+
+	for i, id := range chain {
+		depth, err := repo.Depth(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, depth)
+
+		height, err := repo.SubtreeHeight(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, len(chain)-i, height)
+	}
+
+	depth, err := repo.Depth(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Zero(t, depth, "a missing block has no depth")
+}
+
+func TestBlockRepo_UpdateMany(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{ID: uuid.New(), SecretKeyHMAC: "test_hmac", SecretKeyHashPHC: "test_hash"}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+	space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	other := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(space).Error)
+	require.NoError(t, db.Create(other).Error)
+
+	page := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: "Page"}
+	require.NoError(t, db.Create(page).Error)
+	var ids []uuid.UUID
+	for i, text := range []string{"a", "b", "c"} {
+		b := &model.Block{
+			ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypeText, Title: text, ParentID: &page.ID, Sort: int64(i),
+			Props: datatypes.NewJSONType(map[string]any{"text": text}),
+		}
+		require.NoError(t, db.Create(b).Error)
+		ids = append(ids, b.ID)
+	}
+	elsewhere := &model.Block{ID: uuid.New(), SpaceID: other.ID, Type: model.BlockTypePage, Title: "Elsewhere"}
+	require.NoError(t, db.Create(elsewhere).Error)
+	load := func(id uuid.UUID) model.Block {
+		var blk model.Block
+		require.NoError(t, db.Where("id = ?", id).First(&blk).Error)
+		return blk
+	}
+
+	t.Run("props of three blocks at once", func(t *testing.T) {
+		failed, err := repo.UpdateMany(ctx, space.ID, ids, func(b *model.Block) error {
+			b.MergeProps(map[string]any{"tag": "reviewed"})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+		for _, id := range ids {
+			props := load(id).Props.Data()
+			assert.Equal(t, "reviewed", props["tag"])
+			assert.NotEmpty(t, props["text"], "other props are kept")
+		}
+	})
+
+	t.Run("failed blocks are left untouched", func(t *testing.T) {
+		rejected := errors.New("rejected")
+		missing := uuid.New()
+		failed, err := repo.UpdateMany(ctx, space.ID, []uuid.UUID{ids[0], ids[1], elsewhere.ID, missing}, func(b *model.Block) error {
+			if b.ID == ids[1] {
+				return rejected
+			}
+			b.Title = "renamed"
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[uuid.UUID]error{
+			ids[1]:       rejected,
+			elsewhere.ID: gorm.ErrRecordNotFound,
+			missing:      gorm.ErrRecordNotFound,
+		}, failed)
+		assert.Equal(t, "renamed", load(ids[0]).Title)
+		assert.Equal(t, "b", load(ids[1]).Title)
+		assert.Equal(t, "Elsewhere", load(elsewhere.ID).Title)
+	})
+}
+
+func TestBlockRepo_LastViewed(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	repo := NewBlockRepo(db, 1)
+	ctx := context.Background()
+
+	project := &model.Project{ID: uuid.New(), SecretKeyHMAC: "test_hmac", SecretKeyHashPHC: "test_hash"}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+	space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(space).Error)
+
+	var blocks []*model.Block
+	for i, title := range []string{"A", "B", "Never viewed"} {
+		b := &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: title, Sort: int64(i)}
+		require.NoError(t, db.Create(b).Error)
+		blocks = append(blocks, b)
+	}
+	a, b := blocks[0], blocks[1]
+	var before model.Block
+	require.NoError(t, db.Where("id = ?", a.ID).First(&before).Error)
+
+	viewedAt := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, repo.SetLastViewed(ctx, map[uuid.UUID]time.Time{
+		a.ID: viewedAt.Add(-time.Minute),
+		b.ID: viewedAt.Add(-2 * time.Minute),
+	}))
+
+	list, err := repo.ListRecentlyViewed(ctx, space.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 2, "blocks never viewed are not listed")
+	assert.Equal(t, []uuid.UUID{a.ID, b.ID}, []uuid.UUID{list[0].ID, list[1].ID})
+
+	// A later view of B puts it on top; an older one never moves a block back
+	require.NoError(t, repo.SetLastViewed(ctx, map[uuid.UUID]time.Time{
+		a.ID: viewedAt.Add(-time.Hour),
+		b.ID: viewedAt,
+	}))
+	list, err = repo.ListRecentlyViewed(ctx, space.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, b.ID, list[0].ID)
+	assert.True(t, viewedAt.Equal(*list[0].LastViewedAt))
+
+	var reloaded model.Block
+	require.NoError(t, db.Where("id = ?", a.ID).First(&reloaded).Error)
+	assert.True(t, viewedAt.Add(-time.Minute).Equal(*reloaded.LastViewedAt))
+	assert.True(t, before.UpdatedAt.Equal(reloaded.UpdatedAt), "views leave updated_at untouched")
+}
+
+func TestSortBetween(t *testing.T) {
+	i64 := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name       string
+		prev, next *int64
+		want       int64
+		wantOK     bool
+	}{
+		{name: "empty group", want: 1000, wantOK: true},
+		{name: "after last", prev: i64(3000), want: 4000, wantOK: true},
+		{name: "before first", next: i64(1000), want: 499, wantOK: true},
+		{name: "between", prev: i64(1000), next: i64(2000), want: 1500, wantOK: true},
+		{name: "narrow gap", prev: i64(4), next: i64(6), want: 5, wantOK: true},
+		{name: "adjacent", prev: i64(4), next: i64(5), wantOK: false},
+		{name: "before first at zero", next: i64(0), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := sortBetween(tt.prev, tt.next, 1000)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+// TestBlockRepo_GappedSorts compares the rows updated by moves with contiguous and gapped sorts
+func TestBlockRepo_GappedSorts(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	ctx := context.Background()
+
+	var updatedRows int64
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:count_updated_rows", func(tx *gorm.DB) {
+		updatedRows += tx.Statement.RowsAffected
+	}))
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac",
+		SecretKeyHashPHC: "test_hash",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupTestDB(t, db, project.ID)
+
+	// newGroup creates a space with n root pages sorted first, first+step, ...
+	newGroup := func(n int, first, step int64) (uuid.UUID, []*model.Block) {
+		space := &model.Space{ID: uuid.New(), ProjectID: project.ID}
+		require.NoError(t, db.Create(space).Error)
+		blocks := make([]*model.Block, n)
+		for i := range blocks {
+			blocks[i] = &model.Block{ID: uuid.New(), SpaceID: space.ID, Type: model.BlockTypePage, Title: string(rune('A' + i)), Sort: first + int64(i)*step}
+			require.NoError(t, db.Create(blocks[i]).Error)
+		}
+		return space.ID, blocks
+	}
+
+	rootTitles := func(spaceID uuid.UUID) string {
+		var blocks []model.Block
+		require.NoError(t, db.Where("space_id = ? AND parent_id IS NULL", spaceID).Order("sort").Find(&blocks).Error)
+		titles := ""
+		for _, b := range blocks {
+			titles += b.Title
+		}
+		return titles
+	}
+
+	const siblings = 20
+	moveLastToFront := func(repo BlockRepo, blocks []*model.Block) int64 {
+		updatedRows = 0
+		require.NoError(t, repo.ReorderWithinGroup(ctx, blocks[siblings-1].ID, 0))
+		return updatedRows
+	}
+
+	denseSpace, denseBlocks := newGroup(siblings, 0, 1)
+	denseUpdates := moveLastToFront(NewBlockRepo(db, 1), denseBlocks)
+
+	gappedSpace, gappedBlocks := newGroup(siblings, 1000, 1000)
+	gappedUpdates := moveLastToFront(NewBlockRepo(db, 1000), gappedBlocks)
+
+	assert.Equal(t, rootTitles(denseSpace), rootTitles(gappedSpace))
+	assert.Equal(t, "TABCDEFGHIJKLMNOPQRS", rootTitles(gappedSpace))
+	assert.Equal(t, int64(1), gappedUpdates, "a gapped move updates only the moved block")
+	assert.Greater(t, denseUpdates, int64(siblings), "a contiguous move shifts every sibling in between")
+
+	t.Run("rebalances when the gap runs out", func(t *testing.T) {
+		repo := NewBlockRepo(db, 1000)
+		spaceID, blocks := newGroup(3, 0, 1) // A(0), B(1), C(2): no room anywhere
+
+		require.NoError(t, repo.ReorderWithinGroup(ctx, blocks[2].ID, 1))
+		assert.Equal(t, "ACB", rootTitles(spaceID))
+
+		var sorts []int64
+		require.NoError(t, db.Model(&model.Block{}).Where("space_id = ?", spaceID).Order("sort").Pluck("sort", &sorts).Error)
+		assert.Equal(t, []int64{1000, 1500, 2000}, sorts)
+	})
+
+	t.Run("a sort read back moves like a contiguous one", func(t *testing.T) {
+		repo := NewBlockRepo(db, 1000)
+		spaceID, blocks := newGroup(3, 1000, 1000) // A(1000), B(2000), C(3000)
+
+		// Taking the place of C, as with sort 2 in a contiguous group
+		require.NoError(t, repo.ReorderWithinGroup(ctx, blocks[0].ID, 3000))
+		assert.Equal(t, "BCA", rootTitles(spaceID))
+
+		var b model.Block
+		require.NoError(t, db.Where("id = ?", blocks[1].ID).First(&b).Error)
+		require.NoError(t, repo.ReorderWithinGroup(ctx, blocks[0].ID, b.Sort))
+		assert.Equal(t, "ABC", rootTitles(spaceID))
+	})
+
+	t.Run("moves into another group at a position", func(t *testing.T) {
+		repo := NewBlockRepo(db, 1000)
+		spaceID, blocks := newGroup(3, 1000, 1000)
+		folder := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "F", Sort: 5000}
+		require.NoError(t, db.Create(folder).Error)
+		require.NoError(t, repo.MoveToParentAppend(ctx, blocks[0].ID, &folder.ID))
+
+		updatedRows = 0
+		require.NoError(t, repo.MoveToParentAtSort(ctx, blocks[2].ID, &folder.ID, 0))
+		assert.Equal(t, int64(1), updatedRows)
+
+		var titles []string
+		require.NoError(t, db.Model(&model.Block{}).Where("parent_id = ?", folder.ID).Order("sort").Pluck("title", &titles).Error)
+		assert.Equal(t, []string{"C", "A"}, titles)
+		assert.Equal(t, "BF", rootTitles(spaceID))
+	})
+}
+
 func strPtr(s string) *string {
 	return &s
 }
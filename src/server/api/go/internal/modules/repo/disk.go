@@ -2,18 +2,26 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DiskRepo interface {
 	Create(ctx context.Context, d *model.Disk) error
+	GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error)
+	GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error)
 	Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error
+	Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error)
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error)
+	PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (artifactCount int, freedAssetCount int, err error)
 	ListWithCursor(ctx context.Context, projectID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error)
+	StatsByDiskIDs(ctx context.Context, diskIDs []uuid.UUID) (map[uuid.UUID]model.DiskStats, error)
 }
 
 type diskRepo struct {
@@ -29,48 +37,218 @@ func (r *diskRepo) Create(ctx context.Context, d *model.Disk) error {
 	return r.db.WithContext(ctx).Create(d).Error
 }
 
+func (r *diskRepo) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	var disk model.Disk
+	if err := r.db.WithContext(ctx).Where("id = ?", diskID).First(&disk).Error; err != nil {
+		return nil, err
+	}
+	return &disk, nil
+}
+
+// GetOrCreateDefault returns the default disk of the project, creating it first if the
+// project has none. The insert is a no-op when a concurrent call created it already, so
+// every caller gets the same disk.
+func (r *diskRepo) GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+	db := r.db.WithContext(ctx)
+
+	var disk model.Disk
+	err := db.Where("project_id = ? AND is_default", projectID).First(&disk).Error
+	if err == nil {
+		return &disk, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "project_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "is_default"}}},
+		DoNothing:   true,
+	}).Create(&model.Disk{ProjectID: projectID, IsDefault: true}).Error; err != nil {
+		return nil, fmt.Errorf("create default disk: %w", err)
+	}
+
+	if err := db.Where("project_id = ? AND is_default", projectID).First(&disk).Error; err != nil {
+		return nil, err
+	}
+	return &disk, nil
+}
+
+// Delete soft-deletes the disk, which hides it and its artifacts until it is restored or
+// purged. The references of its assets are kept, so no stored object is freed yet. A default
+// disk loses its default flag, so the project can get a new default disk meanwhile.
 func (r *diskRepo) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
-	// Use transaction to ensure atomicity
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Verify disk exists and belongs to project
-		var disk model.Disk
-		if err := tx.Where("id = ? AND project_id = ?", diskID, projectID).First(&disk).Error; err != nil {
-			return err
+	res := r.db.WithContext(ctx).
+		Model(&model.Disk{}).
+		Where("id = ? AND project_id = ?", diskID, projectID).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "is_default": false})
+	if res.Error != nil {
+		return fmt.Errorf("delete disk: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted disk of the project with its artifacts. A restored
+// default disk comes back as a regular disk.
+func (r *diskRepo) Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	db := r.db.WithContext(ctx)
+
+	res := db.Unscoped().
+		Model(&model.Disk{}).
+		Where("id = ? AND project_id = ? AND deleted_at IS NOT NULL", diskID, projectID).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return nil, fmt.Errorf("restore disk: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var disk model.Disk
+	if err := db.Where("id = ?", diskID).First(&disk).Error; err != nil {
+		return nil, err
+	}
+	return &disk, nil
+}
+
+// PurgeDeleted hard-deletes up to limit disks soft-deleted before the given time, oldest
+// first, with their artifacts, and releases the references of their assets, freeing the
+// objects no longer referenced. It returns the number of disks purged.
+func (r *diskRepo) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error) {
+	var disks []model.Disk
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Find(&disks).Error; err != nil {
+		return 0, fmt.Errorf("query deleted disks: %w", err)
+	}
+
+	var errs []error
+	purged := 0
+	for i := range disks {
+		ok, err := r.purge(ctx, &disks[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge disk %s: %w", disks[i].ID, err))
+			continue
+		}
+		if ok {
+			purged++
 		}
+	}
+	return purged, errors.Join(errs...)
+}
 
+// purge hard-deletes a soft-deleted disk with its artifacts and decrements the references
+// of their assets. It reports false when the disk was restored in the meantime.
+func (r *diskRepo) purge(ctx context.Context, disk *model.Disk) (bool, error) {
+	purged := false
+	// Use transaction to ensure atomicity
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Query all artifacts before deletion to collect asset meta for reference decrement
 		// Artifacts will be automatically deleted by CASCADE when disk is deleted
 		var artifacts []model.Artifact
-		if err := tx.Where("disk_id = ?", diskID).Find(&artifacts).Error; err != nil {
+		if err := tx.Where("disk_id = ?", disk.ID).Find(&artifacts).Error; err != nil {
 			return fmt.Errorf("query artifacts: %w", err)
 		}
 
-		// Collect asset meta from all artifacts for batch decrement
+		// Collect asset meta from all artifacts for batch decrement; the objects owned by
+		// artifacts are not reference counted and are deleted with them
 		assets := make([]model.Asset, 0, len(artifacts))
+		var owned []model.Asset
 		for _, artifact := range artifacts {
 			asset := artifact.AssetMeta.Data()
-			if asset.SHA256 != "" {
+			if artifact.OwnsObject() {
+				owned = append(owned, asset)
+			} else if asset.SHA256 != "" {
 				assets = append(assets, asset)
 			}
 		}
 
-		// Delete the disk (artifacts will be deleted automatically by CASCADE)
-		if err := tx.Delete(&disk).Error; err != nil {
-			return fmt.Errorf("delete disk: %w", err)
+		// Delete the disk (artifacts will be deleted automatically by CASCADE). Only a disk
+		// still deleted is purged, so a concurrent restore wins.
+		res := tx.Unscoped().Where("deleted_at IS NOT NULL").Delete(disk)
+		if res.Error != nil {
+			return fmt.Errorf("delete disk: %w", res.Error)
 		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		purged = true
 
 		// Batch decrement asset references
 		// Note: BatchDecrementAssetRefs uses its own DB connection and may involve S3 operations
 		// The database operations within BatchDecrementAssetRefs will not be part of this transaction,
 		// but the disk and artifacts deletion will be atomic
 		if len(assets) > 0 {
-			if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, assets); err != nil {
+			if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, disk.ProjectID, assets); err != nil {
 				return fmt.Errorf("decrement asset references: %w", err)
 			}
 		}
+		if len(owned) > 0 {
+			if err := r.assetReferenceRepo.DeleteObjects(ctx, disk.ProjectID, owned); err != nil {
+				return fmt.Errorf("delete artifact objects: %w", err)
+			}
+		}
 
 		return nil
 	})
+	return purged && err == nil, err
+}
+
+// PreviewDelete reports what Delete would remove without changing anything: the number of artifacts
+// in the disk and the number of assets whose reference count would drop to zero (freeing the S3 object).
+func (r *diskRepo) PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (int, int, error) {
+	db := r.db.WithContext(ctx)
+
+	// Verify disk exists and belongs to project
+	var disk model.Disk
+	if err := db.Where("id = ? AND project_id = ?", diskID, projectID).First(&disk).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var artifacts []model.Artifact
+	if err := db.Where("disk_id = ?", diskID).Find(&artifacts).Error; err != nil {
+		return 0, 0, fmt.Errorf("query artifacts: %w", err)
+	}
+
+	// Count how many references this disk holds per asset. Objects owned by an artifact
+	// are always freed.
+	refsBySHA := make(map[string]int)
+	freed := 0
+	for _, artifact := range artifacts {
+		asset := artifact.AssetMeta.Data()
+		if artifact.OwnsObject() {
+			freed++
+		} else if asset.SHA256 != "" {
+			refsBySHA[asset.SHA256]++
+		}
+	}
+	if len(refsBySHA) == 0 {
+		return len(artifacts), freed, nil
+	}
+
+	shas := make([]string, 0, len(refsBySHA))
+	for sha := range refsBySHA {
+		shas = append(shas, sha)
+	}
+
+	var refs []model.AssetReference
+	if err := db.Where("project_id = ? AND sha256 IN ?", projectID, shas).Find(&refs).Error; err != nil {
+		return 0, 0, fmt.Errorf("query asset references: %w", err)
+	}
+
+	for _, ref := range refs {
+		if ref.RefCount <= refsBySHA[ref.SHA256] {
+			freed++
+		}
+	}
+
+	return len(artifacts), freed, nil
 }
 
 func (r *diskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
@@ -98,3 +276,31 @@ func (r *diskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, afte
 	var disks []*model.Disk
 	return disks, q.Order(orderBy).Limit(limit).Find(&disks).Error
 }
+
+// StatsByDiskIDs counts the artifacts and sums their asset sizes for all given disks
+// in a single grouped query. Disks without artifacts are missing from the result.
+func (r *diskRepo) StatsByDiskIDs(ctx context.Context, diskIDs []uuid.UUID) (map[uuid.UUID]model.DiskStats, error) {
+	stats := make(map[uuid.UUID]model.DiskStats, len(diskIDs))
+	if len(diskIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []struct {
+		DiskID        uuid.UUID
+		ArtifactCount int64
+		TotalSizeB    int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&model.Artifact{}).
+		Select("disk_id, COUNT(*) AS artifact_count, COALESCE(SUM((asset_meta->>'size_b')::bigint), 0) AS total_size_b").
+		Where("disk_id IN ?", diskIDs).
+		Group("disk_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stats[row.DiskID] = model.DiskStats{ArtifactCount: row.ArtifactCount, TotalSizeB: row.TotalSizeB}
+	}
+	return stats, nil
+}
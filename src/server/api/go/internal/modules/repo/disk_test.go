@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestDiskRepo_StatsByDiskIDs(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}, &model.Artifact{}))
+	repo := NewDiskRepo(db, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_disk",
+		SecretKeyHashPHC: "test_hash_disk",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	newDisk := func(sizes ...int64) *model.Disk {
+		disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID}
+		require.NoError(t, db.Create(disk).Error)
+		for i, size := range sizes {
+			artifact := &model.Artifact{
+				DiskID:    disk.ID,
+				Path:      "/",
+				Filename:  uuid.NewString(),
+				AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: uuid.NewString(), SizeB: size}),
+			}
+			require.NoError(t, db.Create(artifact).Error, "artifact %d", i)
+		}
+		return disk
+	}
+
+	three := newDisk(100, 200, 300)
+	one := newDisk(42)
+	empty := newDisk()
+
+	stats, err := repo.StatsByDiskIDs(ctx, []uuid.UUID{three.ID, one.ID, empty.ID})
+	require.NoError(t, err)
+	assert.Equal(t, model.DiskStats{ArtifactCount: 3, TotalSizeB: 600}, stats[three.ID])
+	assert.Equal(t, model.DiskStats{ArtifactCount: 1, TotalSizeB: 42}, stats[one.ID])
+	_, ok := stats[empty.ID]
+	assert.False(t, ok, "disks without artifacts have no row")
+
+	stats, err = repo.StatsByDiskIDs(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func TestDiskRepo_GetOrCreateDefault_Concurrent(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}))
+	repo := NewDiskRepo(db, nil)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_default_disk",
+		SecretKeyHashPHC: "test_hash_default_disk",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	var (
+		wg    sync.WaitGroup
+		disks [2]*model.Disk
+		errs  [2]error
+	)
+	for i := range disks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			disks[i], errs[i] = repo.GetOrCreateDefault(ctx, project.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range disks {
+		require.NoError(t, errs[i])
+		assert.True(t, disks[i].IsDefault)
+	}
+	assert.Equal(t, disks[0].ID, disks[1].ID)
+
+	var count int64
+	require.NoError(t, db.Model(&model.Disk{}).Where("project_id = ?", project.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Later calls return the same disk
+	again, err := repo.GetOrCreateDefault(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, disks[0].ID, again.ID)
+}
+
+// decrementRecorder records the assets released and the objects deleted through it
+type decrementRecorder struct {
+	AssetReferenceRepo
+	released []model.Asset
+	deleted  []model.Asset
+}
+
+func (d *decrementRecorder) BatchDecrementAssetRefs(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error {
+	d.released = append(d.released, assets...)
+	return nil
+}
+
+func (d *decrementRecorder) DeleteObjects(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error {
+	d.deleted = append(d.deleted, assets...)
+	return nil
+}
+
+func TestDiskRepo_SoftDelete(t *testing.T) {
+	db := setupTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+	require.NoError(t, db.AutoMigrate(&model.Disk{}, &model.Artifact{}))
+	refs := &decrementRecorder{}
+	repo := NewDiskRepo(db, refs)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_soft_delete_disk",
+		SecretKeyHashPHC: "test_hash_soft_delete_disk",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer func() {
+		db.Exec("DELETE FROM disks WHERE project_id = ?", project.ID)
+		db.Exec("DELETE FROM projects WHERE id = ?", project.ID)
+	}()
+
+	newDisk := func() (*model.Disk, model.Asset) {
+		disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID}
+		require.NoError(t, db.Create(disk).Error)
+		asset := model.Asset{SHA256: uuid.NewString(), S3Key: "disks/" + uuid.NewString(), SizeB: 10}
+		require.NoError(t, db.Create(&model.Artifact{
+			DiskID:    disk.ID,
+			Path:      "/",
+			Filename:  "a.txt",
+			AssetMeta: datatypes.NewJSONType(asset),
+		}).Error)
+		return disk, asset
+	}
+	countArtifacts := func(diskID uuid.UUID) int64 {
+		var n int64
+		require.NoError(t, db.Model(&model.Artifact{}).Where("disk_id = ?", diskID).Count(&n).Error)
+		return n
+	}
+
+	t.Run("delete then restore keeps the artifacts", func(t *testing.T) {
+		disk, _ := newDisk()
+
+		require.NoError(t, repo.Delete(ctx, project.ID, disk.ID))
+		_, err := repo.GetByID(ctx, disk.ID)
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound), "deleted disks are hidden")
+		assert.True(t, errors.Is(repo.Delete(ctx, project.ID, disk.ID), gorm.ErrRecordNotFound))
+		assert.Equal(t, int64(1), countArtifacts(disk.ID))
+
+		restored, err := repo.Restore(ctx, project.ID, disk.ID)
+		require.NoError(t, err)
+		assert.Equal(t, disk.ID, restored.ID)
+		_, err = repo.GetByID(ctx, disk.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), countArtifacts(disk.ID))
+		assert.Empty(t, refs.released)
+
+		_, err = repo.Restore(ctx, project.ID, disk.ID)
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound), "live disks cannot be restored")
+	})
+
+	t.Run("delete then purge frees the assets", func(t *testing.T) {
+		disk, asset := newDisk()
+		recent, _ := newDisk()
+		require.NoError(t, repo.Delete(ctx, project.ID, disk.ID))
+		cutoff := time.Now()
+		require.NoError(t, repo.Delete(ctx, project.ID, recent.ID))
+
+		n, err := repo.PurgeDeleted(ctx, cutoff, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, int64(0), countArtifacts(disk.ID))
+		require.Len(t, refs.released, 1)
+		assert.Equal(t, asset.SHA256, refs.released[0].SHA256)
+
+		// Disks still in their grace period are kept
+		assert.Equal(t, int64(1), countArtifacts(recent.ID))
+		_, err = repo.Restore(ctx, project.ID, disk.ID)
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound), "purged disks are gone")
+	})
+
+	t.Run("purge deletes the objects owned by artifacts", func(t *testing.T) {
+		disk := &model.Disk{ID: uuid.New(), ProjectID: project.ID, PathAddressedKeys: true}
+		require.NoError(t, db.Create(disk).Error)
+		asset := model.Asset{SHA256: uuid.NewString(), S3Key: model.PathAddressedKey(disk.ID, "/", "a.txt"), SizeB: 10}
+		require.NoError(t, db.Create(&model.Artifact{DiskID: disk.ID, Path: "/", Filename: "a.txt", AssetMeta: datatypes.NewJSONType(asset)}).Error)
+
+		artifacts, freed, err := repo.PreviewDelete(ctx, project.ID, disk.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, artifacts)
+		assert.Equal(t, 1, freed)
+
+		released := len(refs.released)
+		require.NoError(t, repo.Delete(ctx, project.ID, disk.ID))
+		_, err = repo.PurgeDeleted(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		assert.Len(t, refs.released, released, "owned objects have no reference")
+		require.Len(t, refs.deleted, 1)
+		assert.Equal(t, asset.S3Key, refs.deleted[0].S3Key)
+	})
+
+	t.Run("deleting the default disk lets the project get a new one", func(t *testing.T) {
+		def, err := repo.GetOrCreateDefault(ctx, project.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, project.ID, def.ID))
+
+		again, err := repo.GetOrCreateDefault(ctx, project.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, def.ID, again.ID)
+
+		restored, err := repo.Restore(ctx, project.ID, def.ID)
+		require.NoError(t, err)
+		assert.False(t, restored.IsDefault)
+	})
+}
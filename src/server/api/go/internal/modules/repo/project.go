@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRepo interface {
+	UpdateSecretKey(ctx context.Context, projectID uuid.UUID, secretKeyHMAC string, secretKeyHashPHC string) error
+}
+
+type projectRepo struct{ db *gorm.DB }
+
+func NewProjectRepo(db *gorm.DB) ProjectRepo {
+	return &projectRepo{db: db}
+}
+
+// UpdateSecretKey replaces the secret key lookup HMAC and hash of a project in one transaction,
+// so the project always keeps exactly one valid key.
+func (r *projectRepo) UpdateSecretKey(ctx context.Context, projectID uuid.UUID, secretKeyHMAC string, secretKeyHashPHC string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the project row so concurrent rotations are serialized
+		var project model.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where(&model.Project{ID: projectID}).
+			First(&project).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&project).Updates(map[string]interface{}{
+			"secret_key_hmac":     secretKeyHMAC,
+			"secret_key_hash_phc": secretKeyHashPHC,
+		}).Error
+	})
+}
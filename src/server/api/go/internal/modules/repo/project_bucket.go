@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+type ProjectBucketRepo interface {
+	Get(ctx context.Context, projectID uuid.UUID) (*model.ProjectBucket, error)
+}
+
+type projectBucketRepo struct{ db *gorm.DB }
+
+func NewProjectBucketRepo(db *gorm.DB) ProjectBucketRepo {
+	return &projectBucketRepo{db: db}
+}
+
+// Get returns the bucket of a project, or nil if the project uses the deployment's bucket
+func (r *projectBucketRepo) Get(ctx context.Context, projectID uuid.UUID) (*model.ProjectBucket, error) {
+	var buckets []model.ProjectBucket
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Limit(1).Find(&buckets).Error
+	if err != nil || len(buckets) == 0 {
+		return nil, err
+	}
+	return &buckets[0], nil
+}
@@ -20,8 +20,12 @@ type SessionRepo interface {
 	GetDisableTaskTracking(ctx context.Context, sessionID uuid.UUID) (bool, error)
 	ListWithCursor(ctx context.Context, projectID uuid.UUID, spaceID *uuid.UUID, notConnected bool, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]model.Session, error)
 	CreateMessageWithAssets(ctx context.Context, msg *model.Message) error
+	CreateMessagesWithAssets(ctx context.Context, msgs []*model.Message) error
 	ListBySessionWithCursor(ctx context.Context, sessionID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]model.Message, error)
 	ListAllMessagesBySession(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error)
+	GetMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) (*model.Message, error)
+	ListMessageContentHashes(ctx context.Context, sessionID uuid.UUID, hashes []string) ([]string, error)
+	CountMessagesByRole(ctx context.Context, sessionID uuid.UUID) (map[string]int64, error)
 }
 
 type sessionRepo struct {
@@ -174,6 +178,44 @@ func (r *sessionRepo) CreateMessageWithAssets(ctx context.Context, msg *model.Me
 	})
 }
 
+// CreateMessagesWithAssets appends msgs to the end of the session in one transaction.
+// Each message becomes the parent of the next and gets a strictly increasing created_at,
+// so the imported order survives listing by created_at.
+func (r *sessionRepo) CreateMessagesWithAssets(ctx context.Context, msgs []*model.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parent := model.Message{}
+		if err := tx.Where(&model.Message{SessionID: msgs[0].SessionID}).Order("created_at desc").Limit(1).Find(&parent).Error; err != nil {
+			return err
+		}
+
+		// Postgres keeps microseconds, so step by one microsecond past the latest message
+		createdAt := time.Now().Truncate(time.Microsecond)
+		if !parent.CreatedAt.IsZero() && !createdAt.After(parent.CreatedAt) {
+			createdAt = parent.CreatedAt.Add(time.Microsecond)
+		}
+
+		var parentID *uuid.UUID
+		if parent.ID != uuid.Nil {
+			parentID = &parent.ID
+		}
+		for _, msg := range msgs {
+			if msg.ID == uuid.Nil {
+				msg.ID = uuid.New()
+			}
+			msg.ParentID = parentID
+			msg.CreatedAt = createdAt
+			msg.UpdatedAt = createdAt
+			parentID = &msg.ID
+			createdAt = createdAt.Add(time.Microsecond)
+		}
+
+		return tx.CreateInBatches(msgs, 100).Error
+	})
+}
+
 func (r *sessionRepo) ListBySessionWithCursor(ctx context.Context, sessionID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]model.Message, error) {
 	q := r.db.WithContext(ctx).Where("session_id = ?", sessionID)
 
@@ -205,3 +247,48 @@ func (r *sessionRepo) ListAllMessagesBySession(ctx context.Context, sessionID uu
 	err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).Find(&messages).Error
 	return messages, err
 }
+
+// GetMessage returns a message of the session, or gorm.ErrRecordNotFound
+func (r *sessionRepo) GetMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) (*model.Message, error) {
+	var msg model.Message
+	if err := r.db.WithContext(ctx).Where("session_id = ? AND id = ?", sessionID, messageID).First(&msg).Error; err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// ListMessageContentHashes returns which of the given content hashes the messages of the session already have
+func (r *sessionRepo) ListMessageContentHashes(ctx context.Context, sessionID uuid.UUID, hashes []string) ([]string, error) {
+	existing := []string{}
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+	err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("session_id = ? AND content_hash IN ?", sessionID, hashes).
+		Distinct().
+		Pluck("content_hash", &existing).Error
+	return existing, err
+}
+
+// CountMessagesByRole returns the number of messages of a session per role. System and
+// developer messages, stored as user messages, are counted under meta["role_kind"].
+func (r *sessionRepo) CountMessagesByRole(ctx context.Context, sessionID uuid.UUID) (map[string]int64, error) {
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Select("COALESCE(meta->>?, role) AS role, COUNT(*) AS count", model.MessageMetaRoleKind).
+		Where("session_id = ?", sessionID).
+		Group("1").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -27,6 +28,7 @@ func setupSessionTestDB(t *testing.T) *gorm.DB {
 	err = db.AutoMigrate(
 		&model.Project{},
 		&model.Session{},
+		&model.Message{},
 	)
 	require.NoError(t, err)
 
@@ -36,6 +38,7 @@ func setupSessionTestDB(t *testing.T) *gorm.DB {
 // cleanupSessionTestDB cleans up test data
 func cleanupSessionTestDB(t *testing.T, db *gorm.DB, projectID uuid.UUID) {
 	// Clean up in reverse order of foreign key dependencies
+	db.Exec("DELETE FROM messages WHERE session_id IN (SELECT id FROM sessions WHERE project_id = ?)", projectID)
 	db.Exec("DELETE FROM sessions WHERE project_id = ?", projectID)
 	db.Exec("DELETE FROM projects WHERE id = ?", projectID)
 }
@@ -124,3 +127,53 @@ func TestSessionRepo_GetDisableTaskTracking(t *testing.T) {
 		db.Delete(session)
 	})
 }
+
+func TestSessionRepo_CountMessagesByRole(t *testing.T) {
+	db := setupSessionTestDB(t)
+	if db == nil {
+		return // Test was skipped
+	}
+
+	logger, _ := zap.NewDevelopment()
+	repo := NewSessionRepo(db, nil, nil, logger)
+	ctx := context.Background()
+
+	project := &model.Project{
+		ID:               uuid.New(),
+		SecretKeyHMAC:    "test_hmac_session_roles",
+		SecretKeyHashPHC: "test_hash_session_roles",
+	}
+	require.NoError(t, db.Create(project).Error)
+	defer cleanupSessionTestDB(t, db, project.ID)
+
+	session := &model.Session{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(session).Error)
+	other := &model.Session{ID: uuid.New(), ProjectID: project.ID}
+	require.NoError(t, db.Create(other).Error)
+
+	newMessage := func(sessionID uuid.UUID, role string, meta map[string]any) {
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		msg := &model.Message{
+			SessionID:      sessionID,
+			Role:           role,
+			Meta:           datatypes.NewJSONType(meta),
+			PartsAssetMeta: datatypes.NewJSONType(model.Asset{}),
+		}
+		require.NoError(t, db.Create(msg).Error)
+	}
+	newMessage(session.ID, "user", nil)
+	newMessage(session.ID, "assistant", nil)
+	newMessage(session.ID, "user", nil)
+	newMessage(session.ID, "user", map[string]any{model.MessageMetaRoleKind: model.RoleKindSystem})
+	newMessage(other.ID, "assistant", nil)
+
+	counts, err := repo.CountMessagesByRole(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"user": 2, "assistant": 1, "system": 1}, counts)
+
+	counts, err = repo.CountMessagesByRole(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+type StorageStatsRepo interface {
+	Totals(ctx context.Context) (*model.StorageTotals, error)
+}
+
+type storageStatsRepo struct{ db *gorm.DB }
+
+func NewStorageStatsRepo(db *gorm.DB) StorageStatsRepo {
+	return &storageStatsRepo{db: db}
+}
+
+// Totals counts the artifacts, blocks and asset references of all projects and sums the
+// reference counts and sizes of the assets
+func (r *storageStatsRepo) Totals(ctx context.Context) (*model.StorageTotals, error) {
+	db := r.db.WithContext(ctx)
+	var totals model.StorageTotals
+	if err := db.Model(&model.Artifact{}).Count(&totals.Artifacts).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&model.Block{}).Count(&totals.Blocks).Error; err != nil {
+		return nil, err
+	}
+	var assets struct {
+		Count      int64
+		RefCount   int64
+		TotalSizeB int64
+	}
+	err := db.Model(&model.AssetReference{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(ref_count), 0) AS ref_count, COALESCE(SUM((asset_meta->>'size_b')::bigint), 0) AS total_size_b").
+		Take(&assets).Error
+	if err != nil {
+		return nil, err
+	}
+	totals.AssetReferences, totals.AssetRefs, totals.AssetBytes = assets.Count, assets.RefCount, assets.TotalSizeB
+	return &totals, nil
+}
@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ToolReferenceRepo interface {
+	Create(ctx context.Context, t *model.ToolReference) error
+	Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error)
+	Update(ctx context.Context, t *model.ToolReference) error
+	Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error)
+	ExistsByName(ctx context.Context, projectID uuid.UUID, name string, excludeID *uuid.UUID) (bool, error)
+	ListReferencingBlockIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+	Import(ctx context.Context, projectID uuid.UUID, tools []*model.ToolReference, update bool) (ToolReferenceImportCounts, error)
+}
+
+// ToolReferenceImportCounts counts the tools of an import by outcome
+type ToolReferenceImportCounts struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+type toolReferenceRepo struct{ db *gorm.DB }
+
+func NewToolReferenceRepo(db *gorm.DB) ToolReferenceRepo {
+	return &toolReferenceRepo{db: db}
+}
+
+// Create inserts the tool reference, returning gorm.ErrDuplicatedKey when the project already
+// has a tool of that name
+func (r *toolReferenceRepo) Create(ctx context.Context, t *model.ToolReference) error {
+	return duplicatedKey(r.db.WithContext(ctx).Create(t).Error)
+}
+
+func (r *toolReferenceRepo) Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error) {
+	var t model.ToolReference
+	err := r.db.WithContext(ctx).Where("id = ? AND project_id = ?", id, projectID).First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Update saves the tool reference, returning gorm.ErrDuplicatedKey when the project already
+// has another tool of its name. The SOP blocks using the tool are touched since they show
+// its name.
+func (r *toolReferenceRepo) Update(ctx context.Context, t *model.ToolReference) error {
+	return duplicatedKey(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.ToolReference{}).
+			Where("id = ? AND project_id = ?", t.ID, t.ProjectID).
+			Updates(map[string]any{
+				"name":             t.Name,
+				"description":      t.Description,
+				"arguments_schema": t.ArgumentsSchema,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		blockIDs, err := toolSOPBlockIDs(tx, t.ID)
+		if err != nil {
+			return err
+		}
+		return touchSOPBlocks(tx, blockIDs...)
+	}))
+}
+
+// Delete removes the tool reference together with its steps, touching the SOP blocks that
+// lose them
+func (r *toolReferenceRepo) Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Listed first: the steps are deleted with the tool
+		blockIDs, err := toolSOPBlockIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		res := tx.Where("id = ? AND project_id = ?", id, projectID).Delete(&model.ToolReference{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return touchSOPBlocks(tx, blockIDs...)
+	})
+}
+
+func (r *toolReferenceRepo) ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error) {
+	var list []model.ToolReference
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("name ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ExistsByName checks whether a tool reference with the given name exists in the project, optionally excluding one ID
+func (r *toolReferenceRepo) ExistsByName(ctx context.Context, projectID uuid.UUID, name string, excludeID *uuid.UUID) (bool, error) {
+	query := r.db.WithContext(ctx).Model(&model.ToolReference{}).
+		Where("project_id = ? AND name = ?", projectID, name)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListReferencingBlockIDs returns the distinct SOP block IDs whose tool steps use the tool reference
+func (r *toolReferenceRepo) ListReferencingBlockIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	return toolSOPBlockIDs(r.db.WithContext(ctx), id)
+}
+
+// Import creates the tools of a batch, whose names must be unique, in one transaction. A
+// tool whose name the project already uses updates the description and arguments schema of
+// that tool reference when update is set, and is skipped otherwise. A tool of the batch
+// created concurrently fails the import with gorm.ErrDuplicatedKey.
+func (r *toolReferenceRepo) Import(ctx context.Context, projectID uuid.UUID, tools []*model.ToolReference, update bool) (ToolReferenceImportCounts, error) {
+	var counts ToolReferenceImportCounts
+	if len(tools) == 0 {
+		return counts, nil
+	}
+
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Name)
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		counts = ToolReferenceImportCounts{}
+
+		var existing []string
+		if err := tx.Model(&model.ToolReference{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND name IN ?", projectID, names).
+			Pluck("name", &existing).Error; err != nil {
+			return err
+		}
+		exists := make(map[string]bool, len(existing))
+		for _, name := range existing {
+			exists[name] = true
+		}
+
+		toCreate := make([]*model.ToolReference, 0, len(tools))
+		for _, t := range tools {
+			t.ProjectID = projectID
+			if !exists[t.Name] {
+				toCreate = append(toCreate, t)
+				continue
+			}
+			if !update {
+				counts.Skipped++
+				continue
+			}
+			if err := tx.Model(&model.ToolReference{}).
+				Where("project_id = ? AND name = ?", projectID, t.Name).
+				Updates(map[string]any{
+					"description":      t.Description,
+					"arguments_schema": t.ArgumentsSchema,
+				}).Error; err != nil {
+				return err
+			}
+			counts.Updated++
+		}
+
+		if len(toCreate) > 0 {
+			if err := tx.Create(&toCreate).Error; err != nil {
+				return err
+			}
+		}
+		counts.Created = len(toCreate)
+		return nil
+	})
+	return counts, duplicatedKey(err)
+}
+
+// duplicatedKey turns a unique violation, such as a tool name the project already uses,
+// into gorm.ErrDuplicatedKey
+func duplicatedKey(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+// toolSOPBlockIDs returns the SOP blocks whose steps use the tool reference
+func toolSOPBlockIDs(tx *gorm.DB, id uuid.UUID) ([]uuid.UUID, error) {
+	var blockIDs []uuid.UUID
+	err := tx.Model(&model.ToolSOP{}).Where("tool_reference_id = ?", id).Distinct().Pluck("sop_block_id", &blockIDs).Error
+	return blockIDs, err
+}
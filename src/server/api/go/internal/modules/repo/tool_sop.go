@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ToolSOPRepo interface {
+	Get(ctx context.Context, blockID uuid.UUID, id uuid.UUID) (*model.ToolSOP, error)
+	ListByBlock(ctx context.Context, blockID uuid.UUID) ([]model.ToolSOP, error)
+	Append(ctx context.Context, t *model.ToolSOP) error
+	Delete(ctx context.Context, blockID uuid.UUID, id uuid.UUID) error
+	Reorder(ctx context.Context, blockID uuid.UUID, id uuid.UUID, newOrder int) error
+}
+
+type toolSOPRepo struct{ db *gorm.DB }
+
+func NewToolSOPRepo(db *gorm.DB) ToolSOPRepo { return &toolSOPRepo{db: db} }
+
+func (r *toolSOPRepo) Get(ctx context.Context, blockID uuid.UUID, id uuid.UUID) (*model.ToolSOP, error) {
+	var t model.ToolSOP
+	err := r.db.WithContext(ctx).
+		Preload("ToolReference").
+		Where(&model.ToolSOP{ID: id, SOPBlockID: blockID}).
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *toolSOPRepo) ListByBlock(ctx context.Context, blockID uuid.UUID) ([]model.ToolSOP, error) {
+	var list []model.ToolSOP
+	err := r.db.WithContext(ctx).
+		Preload("ToolReference").
+		Where(&model.ToolSOP{SOPBlockID: blockID}).
+		Order(`"order" ASC`).
+		Find(&list).Error
+	return list, err
+}
+
+// Append inserts the step at the tail of the block's steps in a single transaction.
+func (r *toolSOPRepo) Append(ctx context.Context, t *model.ToolSOP) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the SOP block so concurrent appends compute distinct orders
+		var b model.Block
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.Block{ID: t.SOPBlockID}).First(&b).Error; err != nil {
+			return err
+		}
+
+		var next int
+		q := r.buildStepsQuery(tx, t.SOPBlockID).Select(`COALESCE(MAX("order"), -1) + 1`)
+		if err := q.Take(&next).Error; err != nil {
+			return err
+		}
+
+		t.Order = next
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		return touchSOPBlocks(tx, t.SOPBlockID)
+	})
+}
+
+// Delete removes the step and closes the gap so orders stay contiguous.
+func (r *toolSOPRepo) Delete(ctx context.Context, blockID uuid.UUID, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var t model.ToolSOP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.ToolSOP{ID: id, SOPBlockID: blockID}).First(&t).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&t).Error; err != nil {
+			return err
+		}
+
+		// Close gap left by the deleted step
+		if err := r.buildStepsQuery(tx, blockID).
+			Where(`"order" > ?`, t.Order).
+			Update("order", gorm.Expr(`"order" - 1`)).Error; err != nil {
+			return err
+		}
+		return touchSOPBlocks(tx, blockID)
+	})
+}
+
+// Reorder moves a step to newOrder within its block, shifting the steps in between.
+func (r *toolSOPRepo) Reorder(ctx context.Context, blockID uuid.UUID, id uuid.UUID, newOrder int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var t model.ToolSOP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&model.ToolSOP{ID: id, SOPBlockID: blockID}).First(&t).Error; err != nil {
+			return err
+		}
+
+		// Normalize newOrder into [0, max]
+		var maxOrder int
+		if err := r.buildStepsQuery(tx, blockID).Select(`COALESCE(MAX("order"), 0)`).Take(&maxOrder).Error; err != nil {
+			return err
+		}
+		if newOrder < 0 {
+			newOrder = 0
+		}
+		if newOrder > maxOrder {
+			newOrder = maxOrder
+		}
+		if newOrder == t.Order {
+			return nil
+		}
+
+		// Set sentinel value to avoid conflicts
+		if err := tx.Model(&model.ToolSOP{}).Where(&model.ToolSOP{ID: id}).Update("order", math.MinInt32).Error; err != nil {
+			return err
+		}
+
+		steps := r.buildStepsQuery(tx, blockID)
+		if newOrder < t.Order {
+			// Moving up: shift steps down
+			if err := steps.Where(`"order" >= ? AND "order" < ?`, newOrder, t.Order).Update("order", gorm.Expr(`"order" + 1`)).Error; err != nil {
+				return err
+			}
+		} else {
+			// Moving down: shift steps up
+			if err := steps.Where(`"order" <= ? AND "order" > ?`, newOrder, t.Order).Update("order", gorm.Expr(`"order" - 1`)).Error; err != nil {
+				return err
+			}
+		}
+
+		// Set final position
+		if err := tx.Model(&model.ToolSOP{}).Where(&model.ToolSOP{ID: id}).Update("order", newOrder).Error; err != nil {
+			return err
+		}
+		return touchSOPBlocks(tx, blockID)
+	})
+}
+
+// buildStepsQuery builds a query for the tool steps of a SOP block
+func (r *toolSOPRepo) buildStepsQuery(tx *gorm.DB, blockID uuid.UUID) *gorm.DB {
+	return tx.Model(&model.ToolSOP{}).Where(&model.ToolSOP{SOPBlockID: blockID})
+}
+
+// touchSOPBlocks moves the update time of SOP blocks to now. Their steps are served as part
+// of the block, so a changed step must change the block's ETag and Last-Modified.
+func touchSOPBlocks(tx *gorm.DB, blockIDs ...uuid.UUID) error {
+	if len(blockIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&model.Block{}).Where("id IN ?", blockIDs).UpdateColumn("updated_at", time.Now()).Error
+}
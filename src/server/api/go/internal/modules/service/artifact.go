@@ -1,38 +1,118 @@
 package service
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"mime/multipart"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/infra/httpclient"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type ArtifactService interface {
 	Create(ctx context.Context, in CreateArtifactInput) (*model.Artifact, error)
+	CreateFromURL(ctx context.Context, in CreateFromURLInput) (*model.Artifact, error)
 	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error
 	GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error)
-	GetPresignedURL(ctx context.Context, artifact *model.Artifact, expire time.Duration) (string, error)
-	GetFileContent(ctx context.Context, artifact *model.Artifact) (*fileparser.FileContent, error)
+	GetByFullPath(ctx context.Context, diskID uuid.UUID, fullPath string) (*model.Artifact, error)
+	GetPresignedURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration) (string, error)
+	GetDownloadURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, filename string, expire time.Duration) (string, error)
+	GetPresignedURLsByPaths(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, items []model.ArtifactPath, expire time.Duration) (map[string]string, error)
+	GetFileContent(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*fileparser.FileContent, error)
+	VerifyChecksum(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ChecksumResult, error)
+	GetObjectMeta(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ArtifactObjectMeta, error)
 	UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error)
-	ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error)
+	PatchArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]interface{}) (*model.Artifact, error)
+	ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error)
+	ListByDisk(ctx context.Context, in ListArtifactsByDiskInput) (*ListArtifactsByDiskOutput, error)
+	WriteArchive(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, w io.Writer) error
+	ImportArchive(ctx context.Context, in ImportArchiveInput) (*ImportArchiveOutput, error)
 	GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error)
+	GetDirectoryTree(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error)
+	AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error)
+	RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error)
+	ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error)
+	GetAccessStats(ctx context.Context, artifact *model.Artifact) (*ArtifactAccessStats, error)
+	CopyArtifact(ctx context.Context, in CopyArtifactInput) (*model.Artifact, error)
+	RenameArtifact(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, oldFilename string, newFilename string) (*model.Artifact, error)
+	MoveArtifact(ctx context.Context, in MoveArtifactInput) (*model.Artifact, error)
+}
+
+var ErrInvalidArtifactTags = errors.New("invalid artifact tags")
+
+// ErrInvalidArtifactPath is returned when a full path does not name a file
+var ErrInvalidArtifactPath = errors.New("invalid artifact path")
+
+// ErrArtifactExists is returned when a copy would overwrite an existing artifact. Artifact
+// is the artifact already at the destination, so the caller can update or rename instead.
+type ErrArtifactExists struct {
+	Artifact *model.Artifact
+}
+
+func (e *ErrArtifactExists) Error() string {
+	return fmt.Sprintf("artifact already exists: %s%s", e.Artifact.Path, e.Artifact.Filename)
+}
+
+// ErrArtifactSHA256Mismatch is returned when an upload does not have the sha256 its client claimed
+var ErrArtifactSHA256Mismatch = errors.New("file content does not match the claimed sha256")
+
+// ErrArtifactObjectMissing is returned when the stored object of an existing artifact is gone
+var ErrArtifactObjectMissing = errors.New("artifact object missing from storage")
+
+// ErrChecksumVerifyBusy is returned when too many checksum verifications are running
+var ErrChecksumVerifyBusy = errors.New("too many checksum verifications in progress")
+
+// checksumVerifySlots bounds concurrent verifications, each of which downloads a whole object
+var checksumVerifySlots = make(chan struct{}, 4)
+
+const (
+	maxArtifactTags      = 50
+	maxArtifactTagLength = 64
+)
+
+// RemoteFileFetcher downloads the file at a URL, see httpclient.RemoteFetcher
+type RemoteFileFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*httpclient.RemoteFile, error)
 }
 
 type artifactService struct {
-	r  repo.ArtifactRepo
-	s3 *blob.S3Deps
+	r                  repo.ArtifactRepo
+	assetReferenceRepo repo.AssetReferenceRepo
+	s3                 blob.S3Resolver
+	access             ArtifactAccessCounter
+	touch              AssetRefToucher
+	fetch              RemoteFileFetcher
+	// verifyClaimedSHA256 hashes uploads skipped by a client-provided sha256 to check the claim
+	verifyClaimedSHA256 bool
 }
 
-func NewArtifactService(r repo.ArtifactRepo, s3 *blob.S3Deps) ArtifactService {
-	return &artifactService{r: r, s3: s3}
+// NewArtifactService creates the artifact service. A nil access counter disables access metrics,
+// a nil toucher stops downloads from refreshing the last referenced time of assets, a nil fetcher disables creating artifacts from URLs. With verifyClaimedSHA256, an upload is only skipped as unchanged once its hash is checked.
+// s3 picks the bucket of each project, see blob.S3Resolver.
+func NewArtifactService(r repo.ArtifactRepo, assetReferenceRepo repo.AssetReferenceRepo, s3 blob.S3Resolver, access ArtifactAccessCounter, touch AssetRefToucher, fetch RemoteFileFetcher, verifyClaimedSHA256 bool) ArtifactService {
+	return &artifactService{r: r, assetReferenceRepo: assetReferenceRepo, s3: s3, access: access, touch: touch, fetch: fetch, verifyClaimedSHA256: verifyClaimedSHA256}
 }
 
 type CreateArtifactInput struct {
@@ -42,9 +122,19 @@ type CreateArtifactInput struct {
 	Filename   string
 	FileHeader *multipart.FileHeader
 	UserMeta   map[string]interface{}
+	// SHA256 optionally claims the hex sha256 of the file. When it matches the artifact
+	// already at the path, the file is neither uploaded nor hashed.
+	SHA256 string
 }
 
 func (s *artifactService) Create(ctx context.Context, in CreateArtifactInput) (*model.Artifact, error) {
+	if in.SHA256 != "" {
+		artifact, err := s.unchangedArtifact(ctx, in)
+		if err != nil || artifact != nil {
+			return artifact, err
+		}
+	}
+
 	// Check if artifact with same path and filename already exists in the same disk
 	exists, err := s.r.ExistsByPathAndFilename(ctx, in.DiskID, in.Path, in.Filename, nil)
 	if err != nil {
@@ -56,36 +146,421 @@ func (s *artifactService) Create(ctx context.Context, in CreateArtifactInput) (*
 		}
 	}
 
-	asset, err := s.s3.UploadFormFile(ctx, "disks/"+in.ProjectID.String(), in.FileHeader)
+	s3, uploadOpts, err := s.diskUpload(ctx, in.ProjectID, in.DiskID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s3.UploadFormFile(ctx, s3.KeyPrefix(blob.KeyKindDisks, in.ProjectID, in.DiskID), in.FileHeader, uploadOpts(in.Path, in.Filename)...)
 	if err != nil {
 		return nil, fmt.Errorf("upload file to S3: %w", err)
 	}
 
-	// Build artifact metadata
+	artifact := &model.Artifact{
+		DiskID:    in.DiskID,
+		Path:      in.Path,
+		Filename:  in.Filename,
+		Meta:      artifactMeta(in.Path, in.FileHeader.Filename, asset, in.UserMeta),
+		AssetMeta: datatypes.NewJSONType(*asset),
+	}
+
+	if err := s.r.Create(ctx, in.ProjectID, artifact); err != nil {
+		return nil, fmt.Errorf("create artifact record: %w", err)
+	}
+
+	return artifact, nil
+}
+
+// ErrURLFetchDisabled is returned by CreateFromURL when the service has no fetcher
+var ErrURLFetchDisabled = errors.New("creating artifacts from urls is disabled")
+
+type CreateFromURLInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	Path      string
+	// Filename defaults to the name the server gives the file, or the last segment of the URL
+	Filename  string
+	SourceURL string
+	UserMeta  map[string]interface{}
+}
+
+// CreateFromURL downloads a public URL and stores it as an artifact, replacing the artifact
+// already at the path. The fetcher refuses internal addresses and files above its size limit.
+func (s *artifactService) CreateFromURL(ctx context.Context, in CreateFromURLInput) (*model.Artifact, error) {
+	if s.fetch == nil {
+		return nil, ErrURLFetchDisabled
+	}
+	if err := model.ValidateUserMeta(in.UserMeta); err != nil {
+		return nil, err
+	}
+
+	s3, uploadOpts, err := s.diskUpload(ctx, in.ProjectID, in.DiskID)
+	if err != nil {
+		return nil, err
+	}
+	upload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+		return s3.UploadBytes(ctx, s3.KeyPrefix(blob.KeyKindDisks, in.ProjectID, in.DiskID), data, contentType, filename, uploadOpts(dir, filename)...)
+	}
+	return createFromURL(ctx, in, s.fetch, s.r, upload)
+}
+
+func createFromURL(ctx context.Context, in CreateFromURLInput, fetch RemoteFileFetcher, r repo.ArtifactRepo, upload func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error)) (*model.Artifact, error) {
+	file, err := fetch.Fetch(ctx, in.SourceURL)
+	if err != nil {
+		return nil, err
+	}
+	filename := in.Filename
+	if filename == "" {
+		filename = file.Filename
+	}
+	if filename == "" {
+		return nil, fmt.Errorf("%w: no filename given and none found in %s", ErrInvalidArtifactPath, in.SourceURL)
+	}
+	if err := checkFilename(filename); err != nil {
+		return nil, err
+	}
+
+	// A generic type tells nothing about the file: guess from its name, then its content
+	contentType := file.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = fileparser.DetectContentType(file.Data)
+	}
+
+	// The replaced artifact goes first: deleting it after the upload would free the object
+	// the upload reused when the content is unchanged
+	exists, err := r.ExistsByPathAndFilename(ctx, in.DiskID, in.Path, filename, nil)
+	if err != nil {
+		return nil, fmt.Errorf("check artifact existence: %w", err)
+	}
+	if exists {
+		if err := r.DeleteByPath(ctx, in.ProjectID, in.DiskID, in.Path, filename); err != nil {
+			return nil, fmt.Errorf("upsert existing artifact: %w", err)
+		}
+	}
+
+	asset, err := upload(ctx, file.Data, contentType, in.Path, filename)
+	if err != nil {
+		return nil, fmt.Errorf("upload file to S3: %w", err)
+	}
+
+	artifact := &model.Artifact{
+		DiskID:    in.DiskID,
+		Path:      in.Path,
+		Filename:  filename,
+		Meta:      artifactMeta(in.Path, filename, asset, in.UserMeta),
+		AssetMeta: datatypes.NewJSONType(*asset),
+	}
+	if err := r.Create(ctx, in.ProjectID, artifact); err != nil {
+		return nil, fmt.Errorf("create artifact record: %w", err)
+	}
+	return artifact, nil
+}
+
+// diskUpload returns the S3 receiving the uploads of projectID to a disk, and the options
+// storing a file at dir and filename: under its path addressed key on a disk with path
+// addressed keys, else under a content-addressed key shared with the project's equal files
+func (s *artifactService) diskUpload(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*blob.S3Deps, func(dir string, filename string) []blob.UploadOption, error) {
+	s3, err := s.s3.ForProject(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pathAddressed, err := s.r.PathAddressedKeys(ctx, diskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get disk key format: %w", err)
+	}
+	opts := func(dir string, filename string) []blob.UploadOption {
+		return s.uploadOptions(projectID, diskID, s3.Bucket, pathAddressed, dir, filename)
+	}
+	return s3, opts, nil
+}
+
+// uploadOptions returns the options storing a file of projectID at dir and filename of a
+// disk, in bucket
+func (s *artifactService) uploadOptions(projectID uuid.UUID, diskID uuid.UUID, bucket string, pathAddressed bool, dir string, filename string) []blob.UploadOption {
+	if pathAddressed {
+		return []blob.UploadOption{blob.WithKey(model.PathAddressedKey(diskID, dir, filename)), blob.WithProject(projectID)}
+	}
+	return []blob.UploadOption{blob.WithKeyLookup(assetKeyLookup(s.assetReferenceRepo, projectID, bucket)), blob.WithProject(projectID)}
+}
+
+// artifactMeta builds the meta of a new artifact: the system info under
+// model.ArtifactInfoKey, then the user meta
+func artifactMeta(path string, filename string, asset *model.Asset, userMeta map[string]interface{}) map[string]interface{} {
 	meta := map[string]interface{}{
 		model.ArtifactInfoKey: map[string]interface{}{
-			"path":     in.Path,
-			"filename": in.FileHeader.Filename,
+			"path":     path,
+			"filename": filename,
 			"mime":     asset.MIME,
 			"size":     asset.SizeB,
 		},
 	}
+	for k, v := range userMeta {
+		meta[k] = v
+	}
+	return meta
+}
+
+// unchangedArtifact returns the artifact at the path of in when it already stores the content
+// claimed by in.SHA256, with its user meta replaced by in.UserMeta, or nil when the file has to
+// be uploaded. The claim is trusted unless verifyClaimedSHA256 is set; a wrong claim only keeps
+// the previous content, whose recorded checksum stays correct.
+func (s *artifactService) unchangedArtifact(ctx context.Context, in CreateArtifactInput) (*model.Artifact, error) {
+	artifact, err := s.r.GetByPath(ctx, in.DiskID, in.Path, in.Filename)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get existing artifact: %w", err)
+	}
+	if !strings.EqualFold(artifact.AssetMeta.Data().SHA256, in.SHA256) {
+		return nil, nil
+	}
+
+	if s.verifyClaimedSHA256 {
+		file, err := in.FileHeader.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open file: %w", err)
+		}
+		defer file.Close()
+		result, err := verifyChecksum(in.SHA256, file)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Valid {
+			return nil, ErrArtifactSHA256Mismatch
+		}
+	}
+
+	// Built as the stored type: DeepEqual never matches values of different types
+	meta := datatypes.JSONMap{}
+	if info, ok := artifact.Meta[model.ArtifactInfoKey]; ok {
+		meta[model.ArtifactInfoKey] = info
+	}
 	for k, v := range in.UserMeta {
 		meta[k] = v
 	}
+	if reflect.DeepEqual(meta, artifact.Meta) {
+		return artifact, nil
+	}
+	artifact.Meta = meta
+	if err := s.r.Update(ctx, artifact); err != nil {
+		return nil, fmt.Errorf("update artifact meta: %w", err)
+	}
+	return artifact, nil
+}
+
+type CopyArtifactInput struct {
+	ProjectID   uuid.UUID
+	SrcDiskID   uuid.UUID
+	SrcPath     string
+	SrcFilename string
+	DstDiskID   uuid.UUID
+	DstPath     string
+	DstFilename string
+}
+
+// CopyArtifact copies an artifact to another path, on the same or another disk of the
+// project. The copy of a shared, content-addressed asset to a disk without path addressed
+// keys points at the same asset and takes a reference on it, so no bytes are copied. An object
+// owned by the source, or a copy to a disk with path addressed keys, gets a new object. Both
+// disks must belong to in.ProjectID since references are counted per project.
+func (s *artifactService) CopyArtifact(ctx context.Context, in CopyArtifactInput) (*model.Artifact, error) {
+	src, err := s.r.GetByPath(ctx, in.SrcDiskID, in.SrcPath, in.SrcFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.r.GetByPath(ctx, in.DstDiskID, in.DstPath, in.DstFilename)
+	if err == nil {
+		return nil, &ErrArtifactExists{Artifact: existing}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("check artifact existence: %w", err)
+	}
+
+	pathAddressed, err := s.r.PathAddressedKeys(ctx, in.DstDiskID)
+	if err != nil {
+		return nil, fmt.Errorf("get disk key format: %w", err)
+	}
+	asset := src.AssetMeta.Data()
+	if pathAddressed || src.OwnsObject() {
+		copied, err := s.copyObject(ctx, in.ProjectID, asset, in.DstDiskID, in.DstPath, in.DstFilename, pathAddressed)
+		if err != nil {
+			return nil, fmt.Errorf("copy artifact object: %w", err)
+		}
+		asset = *copied
+	}
+
+	meta := make(map[string]interface{}, len(src.Meta))
+	for k, v := range src.Meta {
+		meta[k] = v
+	}
+	info := map[string]interface{}{}
+	if srcInfo, ok := src.Meta[model.ArtifactInfoKey].(map[string]interface{}); ok {
+		for k, v := range srcInfo {
+			info[k] = v
+		}
+	}
+	info["path"] = in.DstPath
+	info["filename"] = in.DstFilename
+	meta[model.ArtifactInfoKey] = info
 
 	artifact := &model.Artifact{
-		DiskID:    in.DiskID,
-		Path:      in.Path,
-		Filename:  in.Filename,
+		DiskID:    in.DstDiskID,
+		Path:      in.DstPath,
+		Filename:  in.DstFilename,
 		Meta:      meta,
-		AssetMeta: datatypes.NewJSONType(*asset),
+		AssetMeta: datatypes.NewJSONType(asset),
+		Tags:      append(datatypes.JSONSlice[string]{}, src.Tags...),
 	}
-
 	if err := s.r.Create(ctx, in.ProjectID, artifact); err != nil {
+		if artifact.OwnsObject() {
+			// Best effort: no record points at the new object
+			_ = s.deleteObject(ctx, in.ProjectID, asset)
+		}
 		return nil, fmt.Errorf("create artifact record: %w", err)
 	}
+	return artifact, nil
+}
+
+// copyObject stores a new copy of the object of asset for the artifact at dir and filename
+// of a disk: under its path addressed key when pathAddressed, else under a content-addressed
+// key, reusing an equal file of the project. Within a bucket the object is copied by S3.
+func (s *artifactService) copyObject(ctx context.Context, projectID uuid.UUID, asset model.Asset, diskID uuid.UUID, dir string, filename string, pathAddressed bool) (*model.Asset, error) {
+	src, err := s.s3.ForObject(ctx, projectID, asset.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := s.s3.ForProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pathAddressed && src.Bucket == dst.Bucket {
+		copied := asset
+		copied.S3Key = model.PathAddressedKey(diskID, dir, filename)
+		if err := dst.CopyObject(ctx, asset.S3Key, copied.S3Key, blob.WithProject(projectID)); err != nil {
+			return nil, err
+		}
+		return &copied, nil
+	}
+
+	data, err := src.DownloadFile(ctx, asset.S3Key)
+	if err != nil {
+		return nil, err
+	}
+	opts := s.uploadOptions(projectID, diskID, dst.Bucket, pathAddressed, dir, filename)
+	return dst.UploadBytes(ctx, dst.KeyPrefix(blob.KeyKindDisks, projectID, diskID), data, asset.MIME, filename, opts...)
+}
+
+// deleteObject deletes the object of asset, which must be owned by an artifact
+func (s *artifactService) deleteObject(ctx context.Context, projectID uuid.UUID, asset model.Asset) error {
+	store, err := s.s3.ForObject(ctx, projectID, asset.Bucket)
+	if err != nil {
+		return err
+	}
+	return store.DeleteObject(ctx, asset.S3Key)
+}
+
+// RenameArtifact renames an artifact within its directory, see MoveArtifact
+func (s *artifactService) RenameArtifact(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, oldFilename string, newFilename string) (*model.Artifact, error) {
+	return s.MoveArtifact(ctx, MoveArtifactInput{
+		ProjectID:   projectID,
+		DiskID:      diskID,
+		Path:        path,
+		Filename:    oldFilename,
+		DstPath:     path,
+		DstFilename: newFilename,
+	})
+}
+
+type MoveArtifactInput struct {
+	ProjectID   uuid.UUID
+	DiskID      uuid.UUID
+	Path        string
+	Filename    string
+	DstPath     string
+	DstFilename string
+}
+
+// MoveArtifact moves an artifact to another path of its disk. Its tags, user meta and access
+// metrics are kept, and the system meta gets the new path and filename. A shared asset is
+// kept as is, so only the record changes. An object owned by the artifact is relocated to its
+// new path addressed key: it is copied by S3, the record is moved, then the old object is
+// deleted.
+func (s *artifactService) MoveArtifact(ctx context.Context, in MoveArtifactInput) (*model.Artifact, error) {
+	if err := checkFilename(in.DstFilename); err != nil {
+		return nil, err
+	}
+	if err := path.ValidatePath(in.DstPath); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArtifactPath, err)
+	}
+
+	artifact, err := s.GetByPath(ctx, in.DiskID, in.Path, in.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if in.DstPath == in.Path && in.DstFilename == in.Filename {
+		return artifact, nil
+	}
+
+	exists, err := s.r.ExistsByPathAndFilename(ctx, in.DiskID, in.DstPath, in.DstFilename, &artifact.ID)
+	if err != nil {
+		return nil, fmt.Errorf("check artifact existence: %w", err)
+	}
+	if exists {
+		existing, err := s.r.GetByPath(ctx, in.DiskID, in.DstPath, in.DstFilename)
+		if err != nil {
+			return nil, fmt.Errorf("get existing artifact: %w", err)
+		}
+		return nil, &ErrArtifactExists{Artifact: existing}
+	}
+
+	owned := artifact.OwnsObject()
+	meta := make(map[string]interface{}, len(artifact.Meta))
+	for k, v := range artifact.Meta {
+		meta[k] = v
+	}
+	info := map[string]interface{}{}
+	if oldInfo, ok := artifact.Meta[model.ArtifactInfoKey].(map[string]interface{}); ok {
+		for k, v := range oldInfo {
+			info[k] = v
+		}
+	}
+	info["path"] = in.DstPath
+	info["filename"] = in.DstFilename
+	meta[model.ArtifactInfoKey] = info
 
+	artifact.Path = in.DstPath
+	artifact.Filename = in.DstFilename
+	artifact.Meta = meta
+	if !owned {
+		if err := s.r.Move(ctx, artifact); err != nil {
+			return nil, fmt.Errorf("move artifact: %w", err)
+		}
+		return artifact, nil
+	}
+
+	old := artifact.AssetMeta.Data()
+	store, err := s.s3.ForObject(ctx, in.ProjectID, old.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	moved := old
+	moved.S3Key = model.PathAddressedKey(in.DiskID, in.DstPath, in.DstFilename)
+	if err := store.CopyObject(ctx, old.S3Key, moved.S3Key, blob.WithProject(in.ProjectID)); err != nil {
+		return nil, fmt.Errorf("copy artifact object: %w", err)
+	}
+	artifact.AssetMeta = datatypes.NewJSONType(moved)
+	if err := s.r.Move(ctx, artifact); err != nil {
+		// Best effort: the record still points at the old object
+		_ = store.DeleteObject(ctx, moved.S3Key)
+		return nil, fmt.Errorf("move artifact: %w", err)
+	}
+	// The artifact is moved: failing to delete the old object only leaves it unreferenced
+	_ = store.DeleteObject(ctx, old.S3Key)
 	return artifact, nil
 }
 
@@ -103,7 +578,54 @@ func (s *artifactService) GetByPath(ctx context.Context, diskID uuid.UUID, path
 	return s.r.GetByPath(ctx, diskID, path, filename)
 }
 
-func (s *artifactService) GetPresignedURL(ctx context.Context, artifact *model.Artifact, expire time.Duration) (string, error) {
+// SplitArtifactPath splits the full path of a file into its canonical directory and its
+// filename. A relative path starts at the root, so "a/b.txt" is "b.txt" in "/a/" and
+// "file.txt" is "file.txt" in "/". A path ending with a slash names no file and is refused.
+func SplitArtifactPath(fullPath string) (string, string, error) {
+	dir, filename := path.SplitFilePath(fullPath)
+	if err := path.ValidatePath(dir); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidArtifactPath, err)
+	}
+	if filename == "" {
+		return "", "", fmt.Errorf("%w: %q has no filename", ErrInvalidArtifactPath, fullPath)
+	}
+	return dir, filename, nil
+}
+
+// checkFilename checks that name is a single path segment
+func checkFilename(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %q is not a filename", ErrInvalidArtifactPath, name)
+	}
+	return nil
+}
+
+// GetByFullPath returns the artifact at the full path of a file, such as "/a/b.txt"
+func (s *artifactService) GetByFullPath(ctx context.Context, diskID uuid.UUID, fullPath string) (*model.Artifact, error) {
+	dir, filename, err := SplitArtifactPath(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.GetByPath(ctx, diskID, dir, filename)
+}
+
+func (s *artifactService) GetPresignedURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration) (string, error) {
+	return s.presignGet(ctx, projectID, artifact, expire)
+}
+
+// GetDownloadURL returns a presigned URL that browsers download as an attachment named
+// filename, the artifact's filename when empty, rather than under the sha256 object key
+func (s *artifactService) GetDownloadURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, filename string, expire time.Duration) (string, error) {
+	if artifact == nil {
+		return "", errors.New("artifact is nil")
+	}
+	if filename == "" {
+		filename = artifact.Filename
+	}
+	return s.presignGet(ctx, projectID, artifact, expire, blob.AsAttachment(filename, artifact.AssetMeta.Data().MIME))
+}
+
+func (s *artifactService) presignGet(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration, opts ...blob.PresignGetOption) (string, error) {
 	if artifact == nil {
 		return "", errors.New("artifact is nil")
 	}
@@ -113,10 +635,96 @@ func (s *artifactService) GetPresignedURL(ctx context.Context, artifact *model.A
 		return "", errors.New("artifact has no S3 key")
 	}
 
-	return s.s3.PresignGet(ctx, assetData.S3Key, expire)
+	s3, err := s.s3.ForObject(ctx, projectID, assetData.Bucket)
+	if err != nil {
+		return "", err
+	}
+	url, err := s3.PresignGet(ctx, assetData.S3Key, expire, opts...)
+	if err != nil {
+		return "", err
+	}
+	s.recordAccess(ctx, artifact)
+	return url, nil
+}
+
+// presignWorkers bounds the number of presigned URLs generated concurrently
+const presignWorkers = 8
+
+// GetPresignedURLsByPaths returns presigned download URLs keyed by path+filename.
+// Artifacts that do not exist or have no S3 key are left out of the result.
+func (s *artifactService) GetPresignedURLsByPaths(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, items []model.ArtifactPath, expire time.Duration) (map[string]string, error) {
+	if len(items) == 0 {
+		return map[string]string{}, nil
+	}
+
+	artifacts, err := s.r.GetByPaths(ctx, diskID, items)
+	if err != nil {
+		return nil, fmt.Errorf("get artifacts: %w", err)
+	}
+	urls, err := presignArtifacts(ctx, artifacts, expire, func(ctx context.Context, asset model.Asset, expire time.Duration) (string, error) {
+		s3, err := s.s3.ForObject(ctx, projectID, asset.Bucket)
+		if err != nil {
+			return "", err
+		}
+		return s3.PresignGet(ctx, asset.S3Key, expire)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range artifacts {
+		if _, ok := urls[model.ArtifactPath{Path: a.Path, Filename: a.Filename}.Key()]; ok {
+			s.recordAccess(ctx, a)
+		}
+	}
+	return urls, nil
+}
+
+// presignArtifacts presigns the artifacts with a bounded pool of workers
+func presignArtifacts(ctx context.Context, artifacts []*model.Artifact, expire time.Duration, presign func(ctx context.Context, asset model.Asset, expire time.Duration) (string, error)) (map[string]string, error) {
+	urls := make(map[string]string, len(artifacts))
+	jobs := make(chan *model.Artifact)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < min(presignWorkers, len(artifacts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range jobs {
+				url, err := presign(ctx, a.AssetMeta.Data(), expire)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("presign %s%s: %w", a.Path, a.Filename, err)
+					}
+				} else {
+					urls[model.ArtifactPath{Path: a.Path, Filename: a.Filename}.Key()] = url
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, a := range artifacts {
+		if a.AssetMeta.Data().S3Key == "" {
+			continue
+		}
+		jobs <- a
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return urls, nil
 }
 
-func (s *artifactService) GetFileContent(ctx context.Context, artifact *model.Artifact) (*fileparser.FileContent, error) {
+// GetFileContent downloads and parses an artifact, choosing the parser by its filename and
+// detected MIME type. Files of unsupported types come back as binary content.
+func (s *artifactService) GetFileContent(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*fileparser.FileContent, error) {
 	if artifact == nil {
 		return nil, errors.New("artifact is nil")
 	}
@@ -126,20 +734,19 @@ func (s *artifactService) GetFileContent(ctx context.Context, artifact *model.Ar
 		return nil, errors.New("artifact has no S3 key")
 	}
 
-	// Check if file type is parsable before downloading
-	parser := fileparser.NewFileParser()
-	if !parser.CanParseFile(artifact.Filename, assetData.MIME) {
-		return nil, fmt.Errorf("unsupported file type: %s (mime: %s)", artifact.Filename, assetData.MIME)
-	}
-
 	// Download file content from S3
-	content, err := s.s3.DownloadFile(ctx, assetData.S3Key)
+	s3, err := s.s3.ForObject(ctx, projectID, assetData.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s3.DownloadFile(ctx, assetData.S3Key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file content: %w", err)
 	}
+	s.recordAccess(ctx, artifact)
 
 	// Parse file content
-	fileContent, err := parser.ParseFile(artifact.Filename, assetData.MIME, content)
+	fileContent, err := fileparser.NewFileParser().ParseFile(artifact.Filename, assetData.MIME, content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse file content: %w", err)
 	}
@@ -147,6 +754,164 @@ func (s *artifactService) GetFileContent(ctx context.Context, artifact *model.Ar
 	return fileContent, nil
 }
 
+// ArtifactAccessStats reports how often an artifact was downloaded
+type ArtifactAccessStats struct {
+	AccessCount    int64      `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+}
+
+// recordAccess counts a download of the artifact and touches its asset. Both are best effort
+// and never fail the download.
+func (s *artifactService) recordAccess(ctx context.Context, artifact *model.Artifact) {
+	if s.access != nil {
+		_ = s.access.Record(ctx, artifact.ID, time.Now())
+	}
+	if s.touch != nil {
+		if sha256 := artifact.AssetMeta.Data().SHA256; sha256 != "" {
+			_ = s.touch.Touch(ctx, artifact.DiskID, sha256)
+		}
+	}
+}
+
+// GetAccessStats returns the flushed access metrics of the artifact plus the pending ones
+func (s *artifactService) GetAccessStats(ctx context.Context, artifact *model.Artifact) (*ArtifactAccessStats, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact is nil")
+	}
+
+	stats := &ArtifactAccessStats{AccessCount: artifact.AccessCount, LastAccessedAt: artifact.LastAccessedAt}
+	if s.access == nil {
+		return stats, nil
+	}
+
+	count, last, err := s.access.Pending(ctx, artifact.ID)
+	if err != nil {
+		return nil, err
+	}
+	stats.AccessCount += count
+	if count > 0 && (stats.LastAccessedAt == nil || last.After(*stats.LastAccessedAt)) {
+		stats.LastAccessedAt = &last
+	}
+	return stats, nil
+}
+
+type ChecksumResult struct {
+	Valid    bool   `json:"valid"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// VerifyChecksum downloads the stored object and compares its sha256 with the one recorded
+// at upload. The whole object is read, so the cost grows with its size; at most
+// cap(checksumVerifySlots) verifications run at once and others fail with ErrChecksumVerifyBusy.
+func (s *artifactService) VerifyChecksum(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ChecksumResult, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact is nil")
+	}
+
+	assetData := artifact.AssetMeta.Data()
+	if assetData.S3Key == "" {
+		return nil, errors.New("artifact has no S3 key")
+	}
+
+	select {
+	case checksumVerifySlots <- struct{}{}:
+		defer func() { <-checksumVerifySlots }()
+	default:
+		return nil, ErrChecksumVerifyBusy
+	}
+
+	s3, err := s.s3.ForObject(ctx, projectID, assetData.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s3.OpenFile(ctx, assetData.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file content: %w", err)
+	}
+	defer body.Close()
+
+	return verifyChecksum(assetData.SHA256, body)
+}
+
+// verifyChecksum hashes r and compares the hex sha256 with expected
+func verifyChecksum(expected string, r io.Reader) (*ChecksumResult, error) {
+	if expected == "" {
+		return nil, errors.New("artifact has no recorded checksum")
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("read file content: %w", err)
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+
+	return &ChecksumResult{
+		Valid:    strings.EqualFold(actual, expected),
+		Expected: expected,
+		Actual:   actual,
+	}, nil
+}
+
+// ArtifactObjectMeta is the live metadata of the stored object of an artifact, with the
+// fields that disagree with the artifact record
+type ArtifactObjectMeta struct {
+	Object     *blob.ObjectMeta     `json:"object"`
+	InSync     bool                 `json:"in_sync"`
+	Mismatches []ObjectMetaMismatch `json:"mismatches"`
+}
+
+type ObjectMetaMismatch struct {
+	Field    string `json:"field"`
+	Recorded string `json:"recorded"` // Value in the artifact record
+	Stored   string `json:"stored"`   // Value reported by S3
+}
+
+// GetObjectMeta heads the stored object of the artifact and compares it with the record.
+// It returns ErrArtifactObjectMissing when the object is gone although the record exists.
+func (s *artifactService) GetObjectMeta(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ArtifactObjectMeta, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact is nil")
+	}
+
+	assetData := artifact.AssetMeta.Data()
+	if assetData.S3Key == "" {
+		return nil, errors.New("artifact has no S3 key")
+	}
+
+	s3, err := s.s3.ForObject(ctx, projectID, assetData.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s3.HeadObject(ctx, assetData.S3Key)
+	if errors.Is(err, blob.ErrObjectNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactObjectMissing, assetData.S3Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	mismatches := compareObjectMeta(assetData, obj)
+	return &ArtifactObjectMeta{Object: obj, InSync: len(mismatches) == 0, Mismatches: mismatches}, nil
+}
+
+// compareObjectMeta lists the fields of the recorded asset that disagree with the stored object.
+// The sha256 is compared with the one stored in the object metadata at upload, if any.
+func compareObjectMeta(asset model.Asset, obj *blob.ObjectMeta) []ObjectMetaMismatch {
+	mismatches := []ObjectMetaMismatch{}
+	check := func(field, recorded, stored string) {
+		if recorded != stored {
+			mismatches = append(mismatches, ObjectMetaMismatch{Field: field, Recorded: recorded, Stored: stored})
+		}
+	}
+	check("size_b", strconv.FormatInt(asset.SizeB, 10), strconv.FormatInt(obj.SizeB, 10))
+	check("etag", asset.ETag, obj.ETag)
+	check("mime", asset.MIME, obj.ContentType)
+	if stored, ok := obj.Metadata["sha256"]; ok {
+		check("sha256", strings.ToLower(asset.SHA256), strings.ToLower(stored))
+	}
+	return mismatches
+}
+
 func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error) {
 	// Get existing artifact
 	artifact, err := s.GetByPath(ctx, diskID, path, filename)
@@ -154,12 +919,8 @@ func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID u
 		return nil, err
 	}
 
-	// Validate that user meta doesn't contain system reserved keys
-	reservedKeys := model.GetReservedKeys()
-	for _, reservedKey := range reservedKeys {
-		if _, exists := userMeta[reservedKey]; exists {
-			return nil, fmt.Errorf("reserved key '%s' is not allowed in user meta", reservedKey)
-		}
+	if err := model.ValidateUserMeta(userMeta); err != nil {
+		return nil, err
 	}
 
 	// Get current system meta
@@ -185,10 +946,514 @@ func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID u
 	return artifact, nil
 }
 
-func (s *artifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error) {
-	return s.r.ListByPath(ctx, diskID, path)
+// PatchArtifactMetaByPath merges patch into the existing user meta; keys set to nil are removed
+func (s *artifactService) PatchArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]interface{}) (*model.Artifact, error) {
+	if path == "" || filename == "" {
+		return nil, errors.New("path and filename are required")
+	}
+	if err := model.ValidateUserMeta(patch); err != nil {
+		return nil, err
+	}
+
+	artifact, err := s.r.PatchMeta(ctx, diskID, path, filename, patch)
+	if err != nil {
+		return nil, fmt.Errorf("patch artifact meta: %w", err)
+	}
+
+	return artifact, nil
+}
+
+// ListByPath returns the artifacts in path, all of the disk when path is empty, optionally
+// only those whose MIME type starts with mimePrefix
+func (s *artifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error) {
+	return s.r.ListByPath(ctx, diskID, path, strings.TrimSpace(mimePrefix))
+}
+
+type ListArtifactsByDiskInput struct {
+	DiskID   uuid.UUID `json:"disk_id"`
+	Limit    int       `json:"limit"`
+	Cursor   string    `json:"cursor"`
+	TimeDesc bool      `json:"time_desc"`
+}
+
+type ListArtifactsByDiskOutput struct {
+	Items      []*model.Artifact `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// ListByDisk lists every artifact of a disk, whatever its path, with cursor-based pagination
+func (s *artifactService) ListByDisk(ctx context.Context, in ListArtifactsByDiskInput) (*ListArtifactsByDiskOutput, error) {
+	// Parse cursor (createdAt, id); an empty cursor indicates starting from the first
+	var afterT time.Time
+	var afterID uuid.UUID
+	var err error
+	if in.Cursor != "" {
+		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Query limit+1 is used to determine has_more
+	artifacts, err := s.r.ListByDiskWithCursor(ctx, in.DiskID, afterT, afterID, in.Limit+1, in.TimeDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ListArtifactsByDiskOutput{
+		Items:   artifacts,
+		HasMore: false,
+	}
+	if len(artifacts) > in.Limit {
+		out.HasMore = true
+		out.Items = artifacts[:in.Limit]
+		last := out.Items[len(out.Items)-1]
+		out.NextCursor = paging.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return out, nil
+}
+
+// ArchiveManifestName is the entry of a disk archive listing the metadata of its files
+const ArchiveManifestName = "manifest.json"
+
+// archivePageSize is the number of artifacts listed at a time while writing an archive
+const archivePageSize = 200
+
+// ArchiveManifest describes the files of a disk archive
+type ArchiveManifest struct {
+	DiskID     uuid.UUID              `json:"disk_id"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Files      []ArchiveManifestEntry `json:"files"`
+}
+
+// ArchiveManifestEntry is an artifact stored in a disk archive under Name
+type ArchiveManifestEntry struct {
+	Name      string                 `json:"name"`
+	Path      string                 `json:"path"`
+	Filename  string                 `json:"filename"`
+	MIME      string                 `json:"mime"`
+	SizeB     int64                  `json:"size_b"`
+	SHA256    string                 `json:"sha256"`
+	Meta      map[string]interface{} `json:"meta"`
+	Tags      []string               `json:"tags"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// WriteArchive streams a ZIP of every artifact of a disk to w, each under its path and
+// filename, followed by ArchiveManifestName. Objects are copied from S3 one at a time, so
+// neither the disk nor the archive is held in memory.
+func (s *artifactService) WriteArchive(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, w io.Writer) error {
+	return writeArchive(ctx, w, diskID, s.r.ListByDiskWithCursor, func(ctx context.Context, asset model.Asset) (io.ReadCloser, error) {
+		s3, err := s.s3.ForObject(ctx, projectID, asset.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		return s3.OpenFile(ctx, asset.S3Key)
+	})
+}
+
+// writeArchive writes the archive of a disk, listing its artifacts with list and reading
+// their objects with open
+func writeArchive(
+	ctx context.Context,
+	w io.Writer,
+	diskID uuid.UUID,
+	list func(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error),
+	open func(ctx context.Context, asset model.Asset) (io.ReadCloser, error),
+) error {
+	zw := zip.NewWriter(w)
+	manifest := ArchiveManifest{DiskID: diskID, ExportedAt: time.Now().UTC(), Files: []ArchiveManifestEntry{}}
+	// The manifest name is taken up front so that an artifact at /manifest.json gets renamed
+	names := map[string]bool{ArchiveManifestName: true}
+
+	var afterT time.Time
+	var afterID uuid.UUID
+	for {
+		artifacts, err := list(ctx, diskID, afterT, afterID, archivePageSize, false)
+		if err != nil {
+			return fmt.Errorf("list artifacts: %w", err)
+		}
+
+		for _, a := range artifacts {
+			asset := a.AssetMeta.Data()
+			name := uniqueArchiveName(names, strings.TrimPrefix(a.Path+a.Filename, "/"))
+			if err := writeArchiveEntry(ctx, zw, name, a.UpdatedAt, asset, open); err != nil {
+				return fmt.Errorf("archive %s%s: %w", a.Path, a.Filename, err)
+			}
+			manifest.Files = append(manifest.Files, ArchiveManifestEntry{
+				Name:      name,
+				Path:      a.Path,
+				Filename:  a.Filename,
+				MIME:      asset.MIME,
+				SizeB:     asset.SizeB,
+				SHA256:    asset.SHA256,
+				Meta:      a.Meta,
+				Tags:      a.Tags,
+				CreatedAt: a.CreatedAt,
+				UpdatedAt: a.UpdatedAt,
+			})
+		}
+
+		if len(artifacts) < archivePageSize {
+			break
+		}
+		last := artifacts[len(artifacts)-1]
+		afterT, afterID = last.CreatedAt, last.ID
+	}
+
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: ArchiveManifestName, Method: zip.Deflate, Modified: manifest.ExportedAt})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return zw.Close()
+}
+
+// writeArchiveEntry copies the object stored under key into a new entry of zw
+func writeArchiveEntry(ctx context.Context, zw *zip.Writer, name string, modified time.Time, asset model.Asset, open func(ctx context.Context, asset model.Asset) (io.ReadCloser, error)) error {
+	body, err := open(ctx, asset)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	ew, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ew, body)
+	return err
+}
+
+// uniqueArchiveName returns name, or name with a " (n)" suffix before its extension when
+// an earlier entry took it, and marks the result as taken
+func uniqueArchiveName(taken map[string]bool, name string) string {
+	unique := name
+	ext := filepath.Ext(name)
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// ErrInvalidArchive is returned when an uploaded archive is not a readable ZIP file
+var ErrInvalidArchive = errors.New("invalid zip archive")
+
+// maxArchiveEntrySizeB bounds the uncompressed size of a file imported from an archive,
+// which is held in memory while it is hashed and uploaded
+const maxArchiveEntrySizeB = 100 << 20
+
+// ArchiveImportMode decides what an archive import does with files already in the disk
+type ArchiveImportMode string
+
+const (
+	// ArchiveImportSkip keeps the existing artifact
+	ArchiveImportSkip ArchiveImportMode = "skip"
+	// ArchiveImportOverwrite replaces the existing artifact with the file of the archive
+	ArchiveImportOverwrite ArchiveImportMode = "overwrite"
+)
+
+type ImportArchiveInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	Archive   io.ReaderAt
+	Size      int64
+	Mode      ArchiveImportMode
+}
+
+// ArchiveImportFailure is an archive entry that could not be imported
+type ArchiveImportFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+type ImportArchiveOutput struct {
+	Created     int                    `json:"created"`
+	Overwritten int                    `json:"overwritten"`
+	Skipped     int                    `json:"skipped"`
+	Failed      []ArchiveImportFailure `json:"failed"`
+}
+
+// ImportArchive creates an artifact for every file of a ZIP archive, at the path given by
+// its entry name. An archive written by WriteArchive also gets the paths, meta and tags of
+// its manifest back. Entries are read one at a time; entries that fail are reported and
+// the import goes on with the next one.
+func (s *artifactService) ImportArchive(ctx context.Context, in ImportArchiveInput) (*ImportArchiveOutput, error) {
+	s3, uploadOpts, err := s.diskUpload(ctx, in.ProjectID, in.DiskID)
+	if err != nil {
+		return nil, err
+	}
+	upload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+		return s3.UploadBytes(ctx, s3.KeyPrefix(blob.KeyKindDisks, in.ProjectID, in.DiskID), data, contentType, filename, uploadOpts(dir, filename)...)
+	}
+	return importArchive(ctx, in, s.r, upload)
+}
+
+// archiveImport is the outcome of importing one archive entry
+type archiveImport int
+
+const (
+	archiveImportCreated archiveImport = iota
+	archiveImportOverwritten
+	archiveImportSkipped
+)
+
+// importArchive imports the entries of an archive into the disk of in, storing their
+// content with upload
+func importArchive(ctx context.Context, in ImportArchiveInput, r repo.ArtifactRepo, upload func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error)) (*ImportArchiveOutput, error) {
+	zr, err := zip.NewReader(in.Archive, in.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	manifest := readArchiveManifest(zr)
+
+	out := &ImportArchiveOutput{Failed: []ArchiveImportFailure{}}
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() || (manifest != nil && f.Name == ArchiveManifestName) {
+			continue
+		}
+
+		dir, filename := path.SplitFilePath(f.Name)
+		var userMeta map[string]interface{}
+		var tags []string
+		if entry, ok := manifest[f.Name]; ok {
+			dir, filename, tags = entry.Path, entry.Filename, entry.Tags
+			userMeta = make(map[string]interface{}, len(entry.Meta))
+			for k, v := range entry.Meta {
+				userMeta[k] = v
+			}
+			// The exported system meta is rebuilt on import
+			for _, key := range model.GetReservedKeys() {
+				delete(userMeta, key)
+			}
+			if err := model.ValidateUserMeta(userMeta); err != nil {
+				out.Failed = append(out.Failed, ArchiveImportFailure{Name: f.Name, Error: err.Error()})
+				continue
+			}
+		}
+
+		result, err := importArchiveEntry(ctx, in, r, upload, f, dir, filename, userMeta, tags)
+		if err != nil {
+			out.Failed = append(out.Failed, ArchiveImportFailure{Name: f.Name, Error: err.Error()})
+			continue
+		}
+		switch result {
+		case archiveImportCreated:
+			out.Created++
+		case archiveImportOverwritten:
+			out.Overwritten++
+		case archiveImportSkipped:
+			out.Skipped++
+		}
+	}
+	return out, nil
+}
+
+// readArchiveManifest returns the files listed by the manifest of an archive written by
+// WriteArchive, by entry name, or nil when the archive has no such manifest
+func readArchiveManifest(zr *zip.Reader) map[string]ArchiveManifestEntry {
+	for _, f := range zr.File {
+		if f.Name != ArchiveManifestName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil
+		}
+		defer rc.Close()
+
+		var manifest ArchiveManifest
+		if err := json.NewDecoder(rc).Decode(&manifest); err != nil || manifest.DiskID == uuid.Nil {
+			return nil
+		}
+		files := make(map[string]ArchiveManifestEntry, len(manifest.Files))
+		for _, entry := range manifest.Files {
+			files[entry.Name] = entry
+		}
+		return files
+	}
+	return nil
+}
+
+// importArchiveEntry creates the artifact of an archive entry at dir and filename
+func importArchiveEntry(
+	ctx context.Context,
+	in ImportArchiveInput,
+	r repo.ArtifactRepo,
+	upload func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error),
+	f *zip.File,
+	dir string,
+	filename string,
+	userMeta map[string]interface{},
+	tags []string,
+) (archiveImport, error) {
+	if filename == "" {
+		return 0, errors.New("missing filename")
+	}
+	if err := path.ValidatePath(dir + filename); err != nil {
+		return 0, fmt.Errorf("invalid path: %w", err)
+	}
+	if f.UncompressedSize64 > maxArchiveEntrySizeB {
+		return 0, fmt.Errorf("file larger than %d bytes", maxArchiveEntrySizeB)
+	}
+
+	exists, err := r.ExistsByPathAndFilename(ctx, in.DiskID, dir, filename, nil)
+	if err != nil {
+		return 0, fmt.Errorf("check artifact existence: %w", err)
+	}
+	result := archiveImportCreated
+	if exists {
+		if in.Mode != ArchiveImportOverwrite {
+			return archiveImportSkipped, nil
+		}
+		result = archiveImportOverwritten
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("open entry: %w", err)
+	}
+	defer rc.Close()
+	// The header size is not trusted: read one byte past the limit to catch a larger file
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveEntrySizeB+1))
+	if err != nil {
+		return 0, fmt.Errorf("read entry: %w", err)
+	}
+	if len(data) > maxArchiveEntrySizeB {
+		return 0, fmt.Errorf("file larger than %d bytes", maxArchiveEntrySizeB)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = fileparser.DetectContentType(data)
+	}
+	// The overwritten artifact goes first: deleting it after the upload would free the object
+	// the upload reused when the content is unchanged
+	if exists {
+		if err := r.DeleteByPath(ctx, in.ProjectID, in.DiskID, dir, filename); err != nil {
+			return 0, fmt.Errorf("overwrite existing artifact: %w", err)
+		}
+	}
+	asset, err := upload(ctx, data, contentType, dir, filename)
+	if err != nil {
+		return 0, fmt.Errorf("upload file to S3: %w", err)
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	artifact := &model.Artifact{
+		DiskID:    in.DiskID,
+		Path:      dir,
+		Filename:  filename,
+		Meta:      artifactMeta(dir, filename, asset, userMeta),
+		AssetMeta: datatypes.NewJSONType(*asset),
+		Tags:      tags,
+	}
+	if err := r.Create(ctx, in.ProjectID, artifact); err != nil {
+		return 0, fmt.Errorf("create artifact record: %w", err)
+	}
+	return result, nil
 }
 
 func (s *artifactService) GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error) {
 	return s.r.GetAllPaths(ctx, diskID)
 }
+
+// GetDirectoryTree returns every directory of a disk with the number of artifacts directly
+// in it, ordered by path. Directories only holding subdirectories, and the root, are
+// included with a count of 0.
+func (s *artifactService) GetDirectoryTree(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error) {
+	counts, err := s.r.CountByPath(ctx, diskID)
+	if err != nil {
+		return nil, err
+	}
+	return directoryTree(counts), nil
+}
+
+// directoryTree adds the missing ancestors of the counted directories
+func directoryTree(counts []model.DirectoryCount) []model.DirectoryCount {
+	byPath := map[string]int64{"/": 0}
+	for _, c := range counts {
+		byPath[c.Path] += c.Count
+		for _, parent := range path.ParentDirs(c.Path) {
+			if _, ok := byPath[parent]; !ok {
+				byPath[parent] = 0
+			}
+		}
+	}
+
+	tree := make([]model.DirectoryCount, 0, len(byPath))
+	for p, count := range byPath {
+		tree = append(tree, model.DirectoryCount{Path: p, Count: count})
+	}
+	sort.Slice(tree, func(i, j int) bool { return tree[i].Path < tree[j].Path })
+	return tree
+}
+
+func (s *artifactService) AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	if path == "" || filename == "" {
+		return nil, errors.New("path and filename are required")
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.AddTags(ctx, diskID, path, filename, tags)
+}
+
+func (s *artifactService) RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	if path == "" || filename == "" {
+		return nil, errors.New("path and filename are required")
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.RemoveTags(ctx, diskID, path, filename, tags)
+}
+
+func (s *artifactService) ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error) {
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return nil, err
+	}
+	return s.r.ListByTag(ctx, diskID, tags[0])
+}
+
+// normalizeTags trims and deduplicates tags, rejecting empty or overlong ones
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: at least one tag is required", ErrInvalidArtifactTags)
+	}
+	if len(tags) > maxArtifactTags {
+		return nil, fmt.Errorf("%w: at most %d tags per request", ErrInvalidArtifactTags, maxArtifactTags)
+	}
+
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tag cannot be empty", ErrInvalidArtifactTags)
+		}
+		if len(tag) > maxArtifactTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidArtifactTags, tag, maxArtifactTagLength)
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ArtifactAccessCounter counts artifact accesses (presigned URLs and content fetches) in a
+// fast store and periodically persists them on the artifact records
+type ArtifactAccessCounter interface {
+	// Record counts one access to the artifact
+	Record(ctx context.Context, artifactID uuid.UUID, at time.Time) error
+	// Pending returns the accesses not flushed yet, with the time of the latest one
+	Pending(ctx context.Context, artifactID uuid.UUID) (int64, time.Time, error)
+	// Flush adds the pending accesses to the artifact records
+	Flush(ctx context.Context) error
+}
+
+const (
+	// Redis hash per artifact holding its pending access count and latest access time
+	redisKeyPrefixArtifactAccess = "artifact:access:"
+	// Redis set of the artifacts with pending accesses
+	redisKeyArtifactAccessPending = "artifact:access:pending"
+)
+
+// takeArtifactAccessScript reads and clears the pending accesses of an artifact atomically,
+// so accesses recorded during a flush are kept for the next one
+var takeArtifactAccessScript = redis.NewScript(`
+local count = redis.call('HGET', KEYS[1], 'count') or '0'
+local last = redis.call('HGET', KEYS[1], 'last') or '0'
+redis.call('DEL', KEYS[1])
+redis.call('SREM', KEYS[2], ARGV[1])
+return {count, last}
+`)
+
+type redisArtifactAccessCounter struct {
+	rdb *redis.Client
+	r   repo.ArtifactRepo
+	log *zap.Logger
+}
+
+// NewRedisArtifactAccessCounter counts accesses with atomic Redis increments and flushes them to r
+func NewRedisArtifactAccessCounter(rdb *redis.Client, r repo.ArtifactRepo, log *zap.Logger) ArtifactAccessCounter {
+	return &redisArtifactAccessCounter{rdb: rdb, r: r, log: log}
+}
+
+func (c *redisArtifactAccessCounter) Record(ctx context.Context, artifactID uuid.UUID, at time.Time) error {
+	return c.add(ctx, artifactID, 1, at)
+}
+
+func (c *redisArtifactAccessCounter) add(ctx context.Context, artifactID uuid.UUID, count int64, at time.Time) error {
+	key := redisKeyPrefixArtifactAccess + artifactID.String()
+	pipe := c.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, key, "count", count)
+	pipe.HSet(ctx, key, "last", at.UnixMilli())
+	pipe.SAdd(ctx, redisKeyArtifactAccessPending, artifactID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("record access of artifact %s: %w", artifactID, err)
+	}
+	return nil
+}
+
+func (c *redisArtifactAccessCounter) Pending(ctx context.Context, artifactID uuid.UUID) (int64, time.Time, error) {
+	vals, err := c.rdb.HMGet(ctx, redisKeyPrefixArtifactAccess+artifactID.String(), "count", "last").Result()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("get pending accesses of artifact %s: %w", artifactID, err)
+	}
+	count, last := parseRedisInt(vals[0]), parseRedisInt(vals[1])
+	if count == 0 {
+		return 0, time.Time{}, nil
+	}
+	return count, time.UnixMilli(last), nil
+}
+
+func (c *redisArtifactAccessCounter) Flush(ctx context.Context) error {
+	ids, err := c.rdb.SMembers(ctx, redisKeyArtifactAccessPending).Result()
+	if err != nil {
+		return fmt.Errorf("list artifacts with pending accesses: %w", err)
+	}
+
+	var errs []error
+	for _, raw := range ids {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.rdb.SRem(ctx, redisKeyArtifactAccessPending, raw)
+			continue
+		}
+
+		vals, err := takeArtifactAccessScript.Run(ctx, c.rdb, []string{redisKeyPrefixArtifactAccess + raw, redisKeyArtifactAccessPending}, raw).Slice()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("take pending accesses of artifact %s: %w", id, err))
+			continue
+		}
+		count, last := parseRedisInt(vals[0]), parseRedisInt(vals[1])
+		if count == 0 {
+			continue
+		}
+
+		if err := c.r.AddAccess(ctx, id, count, time.UnixMilli(last)); err != nil {
+			// Put the accesses back for the next flush
+			if restoreErr := c.add(ctx, id, count, time.UnixMilli(last)); restoreErr != nil {
+				c.log.Error("lost artifact accesses", zap.String("artifact_id", raw), zap.Int64("count", count), zap.Error(restoreErr))
+			}
+			errs = append(errs, fmt.Errorf("persist accesses of artifact %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseRedisInt reads an integer reply that may be missing (nil) or a string
+func parseRedisInt(v any) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// RunArtifactAccessFlusher flushes the counter every interval until ctx is done, then
+// flushes one last time
+func RunArtifactAccessFlusher(ctx context.Context, counter ArtifactAccessCounter, interval time.Duration, log *zap.Logger) {
+	runFlusher(ctx, counter.Flush, interval, "artifact accesses", log)
+}
+
+// runFlusher calls flush every interval until ctx is done, then one last time
+func runFlusher(ctx context.Context, flush func(context.Context) error, interval time.Duration, what string, log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := flush(ctx); err != nil {
+				log.Warn("failed to flush "+what, zap.Error(err))
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := flush(flushCtx); err != nil {
+				log.Warn("failed to flush "+what, zap.Error(err))
+			}
+			cancel()
+			return
+		}
+	}
+}
@@ -1,18 +1,40 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/infra/httpclient"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockArtifactRepo is a mock implementation of ArtifactRepo
@@ -35,6 +57,16 @@ func (m *MockArtifactRepo) Update(ctx context.Context, f *model.Artifact) error
 	return args.Error(0)
 }
 
+func (m *MockArtifactRepo) Move(ctx context.Context, f *model.Artifact) error {
+	args := m.Called(ctx, f)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) PathAddressedKeys(ctx context.Context, diskID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, diskID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockArtifactRepo) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
 	args := m.Called(ctx, diskID, path, filename)
 	if args.Get(0) == nil {
@@ -43,8 +75,24 @@ func (m *MockArtifactRepo) GetByPath(ctx context.Context, diskID uuid.UUID, path
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error) {
-	args := m.Called(ctx, diskID, path)
+func (m *MockArtifactRepo) GetByPaths(ctx context.Context, diskID uuid.UUID, items []model.ArtifactPath) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, items)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, mimePrefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) ListByDiskWithCursor(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, afterCreatedAt, afterID, limit, timeDesc)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -64,6 +112,53 @@ func (m *MockArtifactRepo) ExistsByPathAndFilename(ctx context.Context, diskID u
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockArtifactRepo) AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) PatchMeta(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]any) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	a := args.Get(0).(*model.Artifact)
+	a.MergeMeta(patch)
+	return a, args.Error(1)
+}
+
+func (m *MockArtifactRepo) ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, tag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) AddAccess(ctx context.Context, id uuid.UUID, count int64, lastAccessedAt time.Time) error {
+	args := m.Called(ctx, id, count, lastAccessedAt)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) CountByPath(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error) {
+	args := m.Called(ctx, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.DirectoryCount), args.Error(1)
+}
+
 // MockArtifactS3Deps is a mock implementation of blob.S3Deps for file service
 type MockArtifactS3Deps struct {
 	mock.Mock
@@ -90,6 +185,14 @@ func (m *MockArtifactS3Deps) DownloadFile(ctx context.Context, s3Key string) ([]
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (m *MockArtifactS3Deps) HeadObject(ctx context.Context, s3Key string) (*blob.ObjectMeta, error) {
+	args := m.Called(ctx, s3Key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*blob.ObjectMeta), args.Error(1)
+}
+
 // Helper functions for creating test data
 func createTestArtifact() *model.Artifact {
 	diskID := uuid.New()
@@ -201,6 +304,10 @@ func (s *testArtifactService) Create(ctx context.Context, in CreateArtifactInput
 	return file, nil
 }
 
+func (s *testArtifactService) CreateFromURL(ctx context.Context, in CreateFromURLInput) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).CreateFromURL(ctx, in)
+}
+
 func (s *testArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
 	if path == "" || filename == "" {
 		return errors.New("path and filename are required")
@@ -215,7 +322,11 @@ func (s *testArtifactService) GetByPath(ctx context.Context, diskID uuid.UUID, p
 	return s.r.GetByPath(ctx, diskID, path, filename)
 }
 
-func (s *testArtifactService) GetPresignedURL(ctx context.Context, artifact *model.Artifact, expire time.Duration) (string, error) {
+func (s *testArtifactService) GetByFullPath(ctx context.Context, diskID uuid.UUID, fullPath string) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).GetByFullPath(ctx, diskID, fullPath)
+}
+
+func (s *testArtifactService) GetPresignedURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration) (string, error) {
 	if artifact == nil {
 		return "", errors.New("artifact is nil")
 	}
@@ -228,8 +339,74 @@ func (s *testArtifactService) GetPresignedURL(ctx context.Context, artifact *mod
 	return s.s3.PresignGet(ctx, assetData.S3Key, expire)
 }
 
-func (s *testArtifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error) {
-	return s.r.ListByPath(ctx, diskID, path)
+func (s *testArtifactService) GetDownloadURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, filename string, expire time.Duration) (string, error) {
+	return s.GetPresignedURL(ctx, projectID, artifact, expire)
+}
+
+func (s *testArtifactService) GetPresignedURLsByPaths(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, items []model.ArtifactPath, expire time.Duration) (map[string]string, error) {
+	if len(items) == 0 {
+		return map[string]string{}, nil
+	}
+
+	artifacts, err := s.r.GetByPaths(ctx, diskID, items)
+	if err != nil {
+		return nil, err
+	}
+	return presignArtifacts(ctx, artifacts, expire, func(ctx context.Context, asset model.Asset, expire time.Duration) (string, error) {
+		return s.s3.PresignGet(ctx, asset.S3Key, expire)
+	})
+}
+
+func (s *testArtifactService) AddTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).AddTags(ctx, diskID, path, filename, tags)
+}
+
+func (s *testArtifactService) RemoveTags(ctx context.Context, diskID uuid.UUID, path string, filename string, tags []string) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).RemoveTags(ctx, diskID, path, filename, tags)
+}
+
+func (s *testArtifactService) ListByTag(ctx context.Context, diskID uuid.UUID, tag string) ([]*model.Artifact, error) {
+	return (&artifactService{r: s.r}).ListByTag(ctx, diskID, tag)
+}
+
+func (s *testArtifactService) GetAccessStats(ctx context.Context, artifact *model.Artifact) (*ArtifactAccessStats, error) {
+	return (&artifactService{r: s.r}).GetAccessStats(ctx, artifact)
+}
+
+func (s *testArtifactService) CopyArtifact(ctx context.Context, in CopyArtifactInput) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).CopyArtifact(ctx, in)
+}
+
+func (s *testArtifactService) RenameArtifact(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, oldFilename string, newFilename string) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).RenameArtifact(ctx, projectID, diskID, path, oldFilename, newFilename)
+}
+
+func (s *testArtifactService) MoveArtifact(ctx context.Context, in MoveArtifactInput) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).MoveArtifact(ctx, in)
+}
+
+func (s *testArtifactService) GetDirectoryTree(ctx context.Context, diskID uuid.UUID) ([]model.DirectoryCount, error) {
+	return (&artifactService{r: s.r}).GetDirectoryTree(ctx, diskID)
+}
+
+func (s *testArtifactService) ListByPath(ctx context.Context, diskID uuid.UUID, path string, mimePrefix string) ([]*model.Artifact, error) {
+	return s.r.ListByPath(ctx, diskID, path, mimePrefix)
+}
+
+func (s *testArtifactService) ListByDisk(ctx context.Context, in ListArtifactsByDiskInput) (*ListArtifactsByDiskOutput, error) {
+	return (&artifactService{r: s.r}).ListByDisk(ctx, in)
+}
+
+func (s *testArtifactService) WriteArchive(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, w io.Writer) error {
+	return writeArchive(ctx, w, diskID, s.r.ListByDiskWithCursor, func(ctx context.Context, asset model.Asset) (io.ReadCloser, error) {
+		return nil, errors.New("not supported in tests")
+	})
+}
+
+func (s *testArtifactService) ImportArchive(ctx context.Context, in ImportArchiveInput) (*ImportArchiveOutput, error) {
+	return importArchive(ctx, in, s.r, func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+		return nil, errors.New("not supported in tests")
+	})
 }
 
 func (s *testArtifactService) GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error) {
@@ -243,12 +420,8 @@ func (s *testArtifactService) UpdateArtifactMetaByPath(ctx context.Context, disk
 		return nil, err
 	}
 
-	// Validate that user meta doesn't contain system reserved keys
-	reservedKeys := model.GetReservedKeys()
-	for _, reservedKey := range reservedKeys {
-		if _, exists := userMeta[reservedKey]; exists {
-			return nil, errors.New("reserved key '" + reservedKey + "' is not allowed in user meta")
-		}
+	if err := model.ValidateUserMeta(userMeta); err != nil {
+		return nil, err
 	}
 
 	// Get current system meta
@@ -274,7 +447,11 @@ func (s *testArtifactService) UpdateArtifactMetaByPath(ctx context.Context, disk
 	return artifact, nil
 }
 
-func (s *testArtifactService) GetFileContent(ctx context.Context, artifact *model.Artifact) (*fileparser.FileContent, error) {
+func (s *testArtifactService) PatchArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, patch map[string]interface{}) (*model.Artifact, error) {
+	return (&artifactService{r: s.r}).PatchArtifactMetaByPath(ctx, diskID, path, filename, patch)
+}
+
+func (s *testArtifactService) GetFileContent(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*fileparser.FileContent, error) {
 	// This is a test implementation that doesn't actually download from S3
 	// In real tests, you would mock the S3 download and file parsing
 	if artifact == nil {
@@ -287,6 +464,34 @@ func (s *testArtifactService) GetFileContent(ctx context.Context, artifact *mode
 	}, nil
 }
 
+func (s *testArtifactService) VerifyChecksum(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ChecksumResult, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact is nil")
+	}
+	assetData := artifact.AssetMeta.Data()
+	content, err := s.s3.DownloadFile(ctx, assetData.S3Key)
+	if err != nil {
+		return nil, err
+	}
+	return verifyChecksum(assetData.SHA256, bytes.NewReader(content))
+}
+
+func (s *testArtifactService) GetObjectMeta(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact) (*ArtifactObjectMeta, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact is nil")
+	}
+	assetData := artifact.AssetMeta.Data()
+	obj, err := s.s3.HeadObject(ctx, assetData.S3Key)
+	if errors.Is(err, blob.ErrObjectNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactObjectMissing, assetData.S3Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	mismatches := compareObjectMeta(assetData, obj)
+	return &ArtifactObjectMeta{Object: obj, InSync: len(mismatches) == 0, Mismatches: mismatches}, nil
+}
+
 // Test cases for Create method
 func TestArtifactService_Create(t *testing.T) {
 	projectID := uuid.New()
@@ -381,6 +586,104 @@ func TestArtifactService_Create(t *testing.T) {
 	}
 }
 
+// newFormFileHeader returns the header of a file uploaded in a multipart form
+func newFormFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	assert.NoError(t, err)
+	return form.File["file"][0]
+}
+
+func TestArtifactService_Create_UnchangedSHA256(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	content := []byte("quarterly report")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	existing := func() *model.Artifact {
+		a := createTestArtifact()
+		a.AssetMeta = datatypes.NewJSONType(model.Asset{S3Key: "disks/report.txt", SHA256: checksum})
+		return a
+	}
+	input := func(a *model.Artifact, sha string, userMeta map[string]interface{}) CreateArtifactInput {
+		return CreateArtifactInput{
+			ProjectID:  projectID,
+			DiskID:     a.DiskID,
+			Path:       a.Path,
+			Filename:   a.Filename,
+			FileHeader: newFormFileHeader(t, a.Filename, content),
+			UserMeta:   userMeta,
+			SHA256:     sha,
+		}
+	}
+
+	// The services have no S3 deps, so any upload would panic
+	t.Run("unchanged content skips the upload", func(t *testing.T) {
+		a := existing()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, a.Path, a.Filename).Return(a, nil)
+
+		got, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).Create(ctx, input(a, strings.ToUpper(checksum), nil))
+		assert.NoError(t, err)
+		assert.Same(t, a, got)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "ExistsByPathAndFilename", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unchanged content replaces user meta", func(t *testing.T) {
+		a := existing()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, a.Path, a.Filename).Return(a, nil)
+		repo.On("Update", ctx, mock.MatchedBy(func(u *model.Artifact) bool {
+			return u.Meta["year"] == "2024" && u.Meta[model.ArtifactInfoKey] != nil
+		})).Return(nil)
+
+		got, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).Create(ctx, input(a, checksum, map[string]interface{}{"year": "2024"}))
+		assert.NoError(t, err)
+		assert.Equal(t, a.ID, got.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("verified claim", func(t *testing.T) {
+		a := existing()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, a.Path, a.Filename).Return(a, nil)
+
+		got, err := NewArtifactService(repo, nil, nil, nil, nil, nil, true).Create(ctx, input(a, checksum, nil))
+		assert.NoError(t, err)
+		assert.Same(t, a, got)
+	})
+
+	t.Run("verified claim does not match the upload", func(t *testing.T) {
+		a := existing()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, a.Path, a.Filename).Return(a, nil)
+
+		in := input(a, checksum, nil)
+		in.FileHeader = newFormFileHeader(t, a.Filename, []byte("tampered report"))
+		_, err := NewArtifactService(repo, nil, nil, nil, nil, nil, true).Create(ctx, in)
+		assert.ErrorIs(t, err, ErrArtifactSHA256Mismatch)
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		a := existing()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, a.Path, a.Filename).Return(nil, errors.New("db error"))
+
+		_, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).Create(ctx, input(a, checksum, nil))
+		assert.ErrorContains(t, err, "db error")
+	})
+}
+
 // Test cases for UpdateArtifactMetaByPath method
 func TestArtifactService_UpdateArtifactMetaByPath(t *testing.T) {
 	diskID := uuid.New()
@@ -503,3 +806,1290 @@ func TestArtifactService_UpdateArtifactMetaByPath(t *testing.T) {
 		})
 	}
 }
+
+// Test cases for PatchArtifactMetaByPath method
+func TestArtifactService_PatchArtifactMetaByPath(t *testing.T) {
+	diskID := uuid.New()
+	path := "/test/path/"
+	filename := "test.txt"
+
+	newExistingArtifact := func() *model.Artifact {
+		a := createTestArtifact()
+		a.DiskID = diskID
+		a.Path = path
+		a.Filename = filename
+		a.Meta["description"] = "original"
+		a.Meta["version"] = "1.0"
+		return a
+	}
+
+	tests := []struct {
+		name         string
+		patch        map[string]interface{}
+		expectedMeta map[string]interface{}
+		expectError  bool
+		errorMsg     string
+	}{
+		{
+			name:  "merge add",
+			patch: map[string]interface{}{"owner": "alice"},
+			expectedMeta: map[string]interface{}{
+				"description": "original",
+				"version":     "1.0",
+				"owner":       "alice",
+			},
+		},
+		{
+			name:  "merge overwrite",
+			patch: map[string]interface{}{"version": "2.0"},
+			expectedMeta: map[string]interface{}{
+				"description": "original",
+				"version":     "2.0",
+			},
+		},
+		{
+			name:  "merge delete",
+			patch: map[string]interface{}{"version": nil},
+			expectedMeta: map[string]interface{}{
+				"description": "original",
+			},
+		},
+		{
+			name:        "reserved key in patch",
+			patch:       map[string]interface{}{model.ArtifactInfoKey: nil},
+			expectError: true,
+			errorMsg:    "reserved key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockArtifactRepo{}
+			if !tt.expectError {
+				mockRepo.On("PatchMeta", mock.Anything, diskID, path, filename, tt.patch).Return(newExistingArtifact(), nil)
+			}
+
+			service := NewArtifactService(mockRepo, nil, nil, nil, nil, nil, false)
+
+			artifact, err := service.PatchArtifactMetaByPath(context.Background(), diskID, path, filename, tt.patch)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, artifact)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Contains(t, artifact.Meta, model.ArtifactInfoKey)
+				userMeta := make(map[string]interface{})
+				for k, v := range artifact.Meta {
+					if k != model.ArtifactInfoKey {
+						userMeta[k] = v
+					}
+				}
+				assert.Equal(t, tt.expectedMeta, userMeta)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// Test that DeleteByPath attributes the asset reference decrement to the caller's project
+func TestArtifactService_DeleteByPath(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	tests := []struct {
+		name        string
+		path        string
+		filename    string
+		setup       func(*MockArtifactRepo)
+		expectError bool
+	}{
+		{
+			name:     "project id is passed to repo",
+			path:     "/test/path/",
+			filename: "test.txt",
+			setup: func(repo *MockArtifactRepo) {
+				repo.On("DeleteByPath", mock.Anything, projectID, diskID, "/test/path/", "test.txt").Return(nil)
+			},
+		},
+		{
+			name:        "missing filename",
+			path:        "/test/path/",
+			filename:    "",
+			setup:       func(repo *MockArtifactRepo) {},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockArtifactRepo{}
+			tt.setup(mockRepo)
+
+			service := NewArtifactService(mockRepo, nil, nil, nil, nil, nil, false)
+			err := service.DeleteByPath(context.Background(), projectID, diskID, tt.path, tt.filename)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactService_GetByFullPath(t *testing.T) {
+	ctx := context.Background()
+	diskID := uuid.New()
+
+	tests := []struct {
+		name         string
+		fullPath     string
+		wantPath     string
+		wantFilename string
+	}{
+		{name: "absolute path", fullPath: "/a/b.txt", wantPath: "/a/", wantFilename: "b.txt"},
+		{name: "relative path", fullPath: "a/b.txt", wantPath: "/a/", wantFilename: "b.txt"},
+		{name: "bare filename", fullPath: "file.txt", wantPath: "/", wantFilename: "file.txt"},
+		{name: "repeated slashes", fullPath: "//a//b/c.txt", wantPath: "/a/b/", wantFilename: "c.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockArtifactRepo{}
+			want := &model.Artifact{ID: uuid.New(), DiskID: diskID, Path: tt.wantPath, Filename: tt.wantFilename}
+			repo.On("GetByPath", ctx, diskID, tt.wantPath, tt.wantFilename).Return(want, nil)
+
+			got, err := newTestArtifactService(repo, &MockArtifactS3Deps{}).GetByFullPath(ctx, diskID, tt.fullPath)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+			repo.AssertExpectations(t)
+		})
+	}
+
+	for _, fullPath := range []string{"/a/", "", "/a/../b.txt"} {
+		t.Run("invalid "+fullPath, func(t *testing.T) {
+			repo := &MockArtifactRepo{}
+
+			_, err := newTestArtifactService(repo, &MockArtifactS3Deps{}).GetByFullPath(ctx, diskID, fullPath)
+			assert.ErrorIs(t, err, ErrInvalidArtifactPath)
+			repo.AssertNotCalled(t, "GetByPath", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestArtifactService_GetPresignedURLsByPaths(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	diskID := uuid.New()
+	expire := time.Hour
+
+	newArtifact := func(path, filename, s3Key string) *model.Artifact {
+		return &model.Artifact{
+			ID:        uuid.New(),
+			DiskID:    diskID,
+			Path:      path,
+			Filename:  filename,
+			AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: s3Key}),
+		}
+	}
+
+	items := []model.ArtifactPath{{Path: "/img/", Filename: "missing.png"}}
+	var artifacts []*model.Artifact
+	for i := 0; i < 20; i++ {
+		filename := fmt.Sprintf("%d.png", i)
+		items = append(items, model.ArtifactPath{Path: "/img/", Filename: filename})
+		artifacts = append(artifacts, newArtifact("/img/", filename, "disks/img/"+filename))
+	}
+	artifacts = append(artifacts, newArtifact("/img/", "pending.png", ""))
+	items = append(items, model.ArtifactPath{Path: "/img/", Filename: "pending.png"})
+
+	t.Run("presigns every stored artifact", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		s3 := &MockArtifactS3Deps{}
+		repo.On("GetByPaths", ctx, diskID, items).Return(artifacts, nil)
+		for _, a := range artifacts[:20] {
+			key := a.AssetMeta.Data().S3Key
+			s3.On("PresignGet", ctx, key, expire).Return("https://s3/"+key, nil).Once()
+		}
+
+		urls, err := newTestArtifactService(repo, s3).GetPresignedURLsByPaths(ctx, projectID, diskID, items, expire)
+		assert.NoError(t, err)
+		assert.Len(t, urls, 20)
+		assert.Equal(t, "https://s3/disks/img/7.png", urls["/img/7.png"])
+		assert.NotContains(t, urls, "/img/missing.png")
+		assert.NotContains(t, urls, "/img/pending.png")
+		s3.AssertExpectations(t)
+	})
+
+	t.Run("presign error", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		s3 := &MockArtifactS3Deps{}
+		repo.On("GetByPaths", ctx, diskID, items).Return(artifacts, nil)
+		s3.On("PresignGet", ctx, mock.AnythingOfType("string"), expire).Return("", errors.New("signing failed"))
+
+		_, err := newTestArtifactService(repo, s3).GetPresignedURLsByPaths(ctx, projectID, diskID, items, expire)
+		assert.Error(t, err)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPaths", ctx, diskID, items).Return(nil, errors.New("db down"))
+
+		_, err := newTestArtifactService(repo, &MockArtifactS3Deps{}).GetPresignedURLsByPaths(ctx, projectID, diskID, items, expire)
+		assert.Error(t, err)
+	})
+}
+
+func TestArtifactService_Tags(t *testing.T) {
+	ctx := context.Background()
+	diskID := uuid.New()
+
+	t.Run("add normalizes and deduplicates tags", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		tagged := &model.Artifact{DiskID: diskID, Path: "/docs/", Filename: "a.pdf", Tags: datatypes.JSONSlice[string]{"invoice", "2024"}}
+		repo.On("AddTags", ctx, diskID, "/docs/", "a.pdf", []string{"invoice", "2024"}).Return(tagged, nil)
+
+		artifact, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).AddTags(ctx, diskID, "/docs/", "a.pdf", []string{" invoice", "2024", "invoice "})
+		assert.NoError(t, err)
+		assert.Equal(t, datatypes.JSONSlice[string]{"invoice", "2024"}, artifact.Tags)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		repo.On("RemoveTags", ctx, diskID, "/docs/", "a.pdf", []string{"2024"}).
+			Return(&model.Artifact{Tags: datatypes.JSONSlice[string]{"invoice"}}, nil)
+
+		artifact, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).RemoveTags(ctx, diskID, "/docs/", "a.pdf", []string{"2024"})
+		assert.NoError(t, err)
+		assert.Equal(t, datatypes.JSONSlice[string]{"invoice"}, artifact.Tags)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("list by tag", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		repo.On("ListByTag", ctx, diskID, "invoice").Return([]*model.Artifact{{Filename: "a.pdf"}}, nil)
+
+		artifacts, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).ListByTag(ctx, diskID, " invoice ")
+		assert.NoError(t, err)
+		assert.Len(t, artifacts, 1)
+		repo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name string
+		tags []string
+	}{
+		{name: "no tags", tags: nil},
+		{name: "empty tag", tags: []string{"invoice", "  "}},
+		{name: "tag too long", tags: []string{strings.Repeat("x", maxArtifactTagLength+1)}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockArtifactRepo{}
+			_, err := NewArtifactService(repo, nil, nil, nil, nil, nil, false).AddTags(ctx, diskID, "/docs/", "a.pdf", tt.tags)
+			assert.ErrorIs(t, err, ErrInvalidArtifactTags)
+			repo.AssertNotCalled(t, "AddTags", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestArtifactService_VerifyChecksum(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	content := []byte("quarterly report")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	newArtifact := func(sha string) *model.Artifact {
+		a := createTestArtifact()
+		a.AssetMeta = datatypes.NewJSONType(model.Asset{S3Key: "disks/report.txt", SHA256: sha})
+		return a
+	}
+
+	t.Run("matching checksum", func(t *testing.T) {
+		s3 := &MockArtifactS3Deps{}
+		s3.On("DownloadFile", ctx, "disks/report.txt").Return(content, nil)
+
+		result, err := newTestArtifactService(&MockArtifactRepo{}, s3).VerifyChecksum(ctx, projectID, newArtifact(checksum))
+		assert.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, checksum, result.Expected)
+		assert.Equal(t, checksum, result.Actual)
+		s3.AssertExpectations(t)
+	})
+
+	t.Run("mismatching checksum", func(t *testing.T) {
+		s3 := &MockArtifactS3Deps{}
+		s3.On("DownloadFile", ctx, "disks/report.txt").Return([]byte("tampered report"), nil)
+
+		result, err := newTestArtifactService(&MockArtifactRepo{}, s3).VerifyChecksum(ctx, projectID, newArtifact(checksum))
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, checksum, result.Expected)
+		assert.NotEqual(t, checksum, result.Actual)
+	})
+
+	t.Run("no recorded checksum", func(t *testing.T) {
+		_, err := verifyChecksum("", bytes.NewReader(content))
+		assert.Error(t, err)
+	})
+}
+
+func TestArtifactService_GetObjectMeta(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	asset := model.Asset{S3Key: "disks/report.txt", ETag: "etag-1", SHA256: "ABC123", MIME: "text/plain", SizeB: 42}
+	artifact := createTestArtifact()
+	artifact.AssetMeta = datatypes.NewJSONType(asset)
+
+	t.Run("in sync", func(t *testing.T) {
+		s3 := &MockArtifactS3Deps{}
+		s3.On("HeadObject", ctx, "disks/report.txt").Return(&blob.ObjectMeta{
+			Key: "disks/report.txt", SizeB: 42, ETag: "etag-1", ContentType: "text/plain",
+			Metadata: map[string]string{"sha256": "abc123", "name": "report.txt"},
+		}, nil)
+
+		result, err := newTestArtifactService(&MockArtifactRepo{}, s3).GetObjectMeta(ctx, projectID, artifact)
+		assert.NoError(t, err)
+		assert.True(t, result.InSync)
+		assert.Empty(t, result.Mismatches)
+	})
+
+	t.Run("drifted object", func(t *testing.T) {
+		s3 := &MockArtifactS3Deps{}
+		s3.On("HeadObject", ctx, "disks/report.txt").Return(&blob.ObjectMeta{
+			Key: "disks/report.txt", SizeB: 40, ETag: "etag-2", ContentType: "text/plain",
+			Metadata: map[string]string{"sha256": "def456"},
+		}, nil)
+
+		result, err := newTestArtifactService(&MockArtifactRepo{}, s3).GetObjectMeta(ctx, projectID, artifact)
+		assert.NoError(t, err)
+		assert.False(t, result.InSync)
+		assert.Equal(t, []ObjectMetaMismatch{
+			{Field: "size_b", Recorded: "42", Stored: "40"},
+			{Field: "etag", Recorded: "etag-1", Stored: "etag-2"},
+			{Field: "sha256", Recorded: "abc123", Stored: "def456"},
+		}, result.Mismatches)
+	})
+
+	t.Run("object without stored sha256", func(t *testing.T) {
+		mismatches := compareObjectMeta(asset, &blob.ObjectMeta{SizeB: 42, ETag: "etag-1", ContentType: "text/plain"})
+		assert.Empty(t, mismatches)
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		s3 := &MockArtifactS3Deps{}
+		s3.On("HeadObject", ctx, "disks/report.txt").Return(nil, blob.ErrObjectNotFound)
+
+		_, err := newTestArtifactService(&MockArtifactRepo{}, s3).GetObjectMeta(ctx, projectID, artifact)
+		assert.ErrorIs(t, err, ErrArtifactObjectMissing)
+	})
+}
+
+// fakeAccessCounter keeps pending accesses in memory
+type fakeAccessCounter struct {
+	count map[uuid.UUID]int64
+	last  map[uuid.UUID]time.Time
+	err   error
+}
+
+func newFakeAccessCounter() *fakeAccessCounter {
+	return &fakeAccessCounter{count: map[uuid.UUID]int64{}, last: map[uuid.UUID]time.Time{}}
+}
+
+func (f *fakeAccessCounter) Record(ctx context.Context, artifactID uuid.UUID, at time.Time) error {
+	f.count[artifactID]++
+	f.last[artifactID] = at
+	return f.err
+}
+
+func (f *fakeAccessCounter) Pending(ctx context.Context, artifactID uuid.UUID) (int64, time.Time, error) {
+	return f.count[artifactID], f.last[artifactID], f.err
+}
+
+func (f *fakeAccessCounter) Flush(ctx context.Context) error { return f.err }
+
+func TestArtifactService_GetAccessStats(t *testing.T) {
+	ctx := context.Background()
+	flushedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	newArtifact := func() *model.Artifact {
+		return &model.Artifact{ID: uuid.New(), AccessCount: 5, LastAccessedAt: &flushedAt}
+	}
+
+	t.Run("adds pending accesses", func(t *testing.T) {
+		counter := newFakeAccessCounter()
+		s := &artifactService{access: counter}
+		artifact := newArtifact()
+		s.recordAccess(ctx, artifact)
+		s.recordAccess(ctx, artifact)
+
+		stats, err := s.GetAccessStats(ctx, artifact)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), stats.AccessCount)
+		assert.True(t, stats.LastAccessedAt.Equal(counter.last[artifact.ID]))
+	})
+
+	t.Run("nothing pending", func(t *testing.T) {
+		stats, err := (&artifactService{access: newFakeAccessCounter()}).GetAccessStats(ctx, newArtifact())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), stats.AccessCount)
+		assert.True(t, stats.LastAccessedAt.Equal(flushedAt))
+	})
+
+	t.Run("metrics disabled", func(t *testing.T) {
+		s := &artifactService{}
+		artifact := newArtifact()
+		s.recordAccess(ctx, artifact)
+
+		stats, err := s.GetAccessStats(ctx, artifact)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), stats.AccessCount)
+	})
+
+	t.Run("counter errors", func(t *testing.T) {
+		counter := newFakeAccessCounter()
+		counter.err = errors.New("redis unavailable")
+		s := &artifactService{access: counter}
+		artifact := newArtifact()
+		// recording is best effort
+		s.recordAccess(ctx, artifact)
+
+		_, err := s.GetAccessStats(ctx, artifact)
+		assert.Error(t, err)
+	})
+}
+
+// fakeAssetToucher records touches in memory
+type fakeAssetToucher struct {
+	touched []string
+	err     error
+}
+
+func (f *fakeAssetToucher) Touch(ctx context.Context, diskID uuid.UUID, sha256 string) error {
+	f.touched = append(f.touched, diskID.String()+":"+sha256)
+	return f.err
+}
+
+func (f *fakeAssetToucher) Flush(ctx context.Context) error { return f.err }
+
+func TestArtifactService_RecordAccess_TouchesAsset(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("touches the asset", func(t *testing.T) {
+		toucher := &fakeAssetToucher{}
+		artifact := createTestArtifact()
+		(&artifactService{touch: toucher}).recordAccess(ctx, artifact)
+		assert.Equal(t, []string{artifact.DiskID.String() + ":" + artifact.AssetMeta.Data().SHA256}, toucher.touched)
+	})
+
+	t.Run("artifact without asset hash", func(t *testing.T) {
+		toucher := &fakeAssetToucher{}
+		artifact := createTestArtifact()
+		artifact.AssetMeta = datatypes.NewJSONType(model.Asset{S3Key: "disks/a.txt"})
+		(&artifactService{touch: toucher}).recordAccess(ctx, artifact)
+		assert.Empty(t, toucher.touched)
+	})
+
+	t.Run("toucher errors", func(t *testing.T) {
+		counter := newFakeAccessCounter()
+		artifact := createTestArtifact()
+		// touching is best effort and does not stop the access count
+		(&artifactService{access: counter, touch: &fakeAssetToucher{err: errors.New("redis unavailable")}}).recordAccess(ctx, artifact)
+		assert.Equal(t, int64(1), counter.count[artifact.ID])
+	})
+}
+
+func TestArtifactService_CopyArtifact(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	dstDiskID := uuid.New()
+
+	t.Run("shares the asset", func(t *testing.T) {
+		src := createTestArtifact()
+		src.Tags = []string{"report"}
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, src.DiskID, src.Path, src.Filename).Return(src, nil)
+		repo.On("GetByPath", ctx, dstDiskID, "/copies/", "copy.txt").Return(nil, gorm.ErrRecordNotFound)
+		repo.On("PathAddressedKeys", ctx, dstDiskID).Return(false, nil)
+		// Create takes a reference on the asset of the new artifact
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+
+		// No S3 client: a copy must not upload anything
+		s := &artifactService{r: repo}
+		copied, err := s.CopyArtifact(ctx, CopyArtifactInput{
+			ProjectID:   projectID,
+			SrcDiskID:   src.DiskID,
+			SrcPath:     src.Path,
+			SrcFilename: src.Filename,
+			DstDiskID:   dstDiskID,
+			DstPath:     "/copies/",
+			DstFilename: "copy.txt",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, dstDiskID, copied.DiskID)
+		assert.Equal(t, "/copies/", copied.Path)
+		assert.Equal(t, "copy.txt", copied.Filename)
+		assert.Equal(t, src.AssetMeta.Data(), copied.AssetMeta.Data())
+		assert.Equal(t, []string{"report"}, []string(copied.Tags))
+		info := copied.Meta[model.ArtifactInfoKey].(map[string]interface{})
+		assert.Equal(t, "/copies/", info["path"])
+		assert.Equal(t, "copy.txt", info["filename"])
+		assert.Equal(t, "text/plain", info["mime"])
+		// The source meta is left untouched
+		assert.Equal(t, "/test/path", src.Meta[model.ArtifactInfoKey].(map[string]interface{})["path"])
+		repo.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("destination exists", func(t *testing.T) {
+		src := createTestArtifact()
+		repo := &MockArtifactRepo{}
+		dst := createTestArtifact()
+		dst.Path, dst.Filename = "/copies/", "copy.txt"
+		repo.On("GetByPath", ctx, src.DiskID, src.Path, src.Filename).Return(src, nil)
+		repo.On("GetByPath", ctx, dst.DiskID, dst.Path, dst.Filename).Return(dst, nil)
+
+		_, err := (&artifactService{r: repo}).CopyArtifact(ctx, CopyArtifactInput{
+			ProjectID:   projectID,
+			SrcDiskID:   src.DiskID,
+			SrcPath:     src.Path,
+			SrcFilename: src.Filename,
+			DstDiskID:   dst.DiskID,
+			DstPath:     dst.Path,
+			DstFilename: dst.Filename,
+		})
+		var exists *ErrArtifactExists
+		assert.ErrorAs(t, err, &exists)
+		assert.Same(t, dst, exists.Artifact)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("source not found", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, mock.Anything, "/", "missing.txt").Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := (&artifactService{r: repo}).CopyArtifact(ctx, CopyArtifactInput{ProjectID: projectID, SrcPath: "/", SrcFilename: "missing.txt"})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestArtifactService_RenameArtifact(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	t.Run("updates the filename and system meta", func(t *testing.T) {
+		a := createTestArtifact()
+		a.Meta["owner"] = "alice"
+		asset := a.AssetMeta.Data()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, "/test/path", "test.txt").Return(a, nil)
+		repo.On("ExistsByPathAndFilename", ctx, a.DiskID, "/test/path", "renamed.txt", &a.ID).Return(false, nil)
+		repo.On("Move", ctx, a).Return(nil)
+
+		// No S3 client: a rename must not touch the shared object
+		renamed, err := (&artifactService{r: repo}).RenameArtifact(ctx, projectID, a.DiskID, "/test/path", "test.txt", "renamed.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed.txt", renamed.Filename)
+		assert.Equal(t, "/test/path", renamed.Path)
+		info := renamed.Meta[model.ArtifactInfoKey].(map[string]interface{})
+		assert.Equal(t, "renamed.txt", info["filename"])
+		assert.Equal(t, "/test/path", info["path"])
+		assert.Equal(t, "text/plain", info["mime"])
+		assert.Equal(t, "alice", renamed.Meta["owner"])
+		assert.Equal(t, asset, renamed.AssetMeta.Data())
+		repo.AssertNumberOfCalls(t, "Move", 1)
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		a := createTestArtifact()
+		taken := createTestArtifact()
+		taken.DiskID, taken.Filename = a.DiskID, "taken.txt"
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, "/test/path", "test.txt").Return(a, nil)
+		repo.On("ExistsByPathAndFilename", ctx, a.DiskID, "/test/path", "taken.txt", &a.ID).Return(true, nil)
+		repo.On("GetByPath", ctx, a.DiskID, "/test/path", "taken.txt").Return(taken, nil)
+
+		_, err := (&artifactService{r: repo}).RenameArtifact(ctx, projectID, a.DiskID, "/test/path", "test.txt", "taken.txt")
+		var exists *ErrArtifactExists
+		assert.ErrorAs(t, err, &exists)
+		assert.Same(t, taken, exists.Artifact)
+		repo.AssertNotCalled(t, "Move", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid filename", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		for _, name := range []string{"", "..", "a/b.txt"} {
+			_, err := (&artifactService{r: repo}).RenameArtifact(ctx, projectID, uuid.New(), "/test/path", "test.txt", name)
+			assert.ErrorIs(t, err, ErrInvalidArtifactPath)
+		}
+		repo.AssertNotCalled(t, "GetByPath", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestArtifactService_GetDirectoryTree(t *testing.T) {
+	ctx := context.Background()
+	diskID := uuid.New()
+
+	repo := &MockArtifactRepo{}
+	repo.On("CountByPath", ctx, diskID).Return([]model.DirectoryCount{
+		{Path: "/docs/2024/q1/", Count: 2},
+		{Path: "/docs/", Count: 1},
+		{Path: "/images/", Count: 3},
+	}, nil)
+
+	tree, err := newTestArtifactService(repo, nil).GetDirectoryTree(ctx, diskID)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.DirectoryCount{
+		{Path: "/", Count: 0},
+		{Path: "/docs/", Count: 1},
+		{Path: "/docs/2024/", Count: 0},
+		{Path: "/docs/2024/q1/", Count: 2},
+		{Path: "/images/", Count: 3},
+	}, tree)
+
+	t.Run("empty disk", func(t *testing.T) {
+		emptyDiskID := uuid.New()
+		repo.On("CountByPath", ctx, emptyDiskID).Return([]model.DirectoryCount{}, nil)
+
+		tree, err := newTestArtifactService(repo, nil).GetDirectoryTree(ctx, emptyDiskID)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.DirectoryCount{{Path: "/", Count: 0}}, tree)
+	})
+}
+
+func TestWriteArchive(t *testing.T) {
+	ctx := context.Background()
+	diskID := uuid.New()
+
+	// archiveOf writes the archive of artifacts, whose objects hold their S3 key as content
+	archiveOf := func(t *testing.T, artifacts []*model.Artifact) (*zip.Reader, error) {
+		list := func(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+			start := 0
+			for i, a := range artifacts {
+				if a.ID == afterID {
+					start = i + 1
+				}
+			}
+			return artifacts[start:min(start+limit, len(artifacts))], nil
+		}
+		open := func(ctx context.Context, asset model.Asset) (io.ReadCloser, error) {
+			if asset.S3Key == "" {
+				return nil, errors.New("key is empty")
+			}
+			return io.NopCloser(strings.NewReader(asset.S3Key)), nil
+		}
+
+		var buf bytes.Buffer
+		if err := writeArchive(ctx, &buf, diskID, list, open); err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		return zr, nil
+	}
+	artifact := func(path, filename string) *model.Artifact {
+		return &model.Artifact{
+			ID:        uuid.New(),
+			DiskID:    diskID,
+			Path:      path,
+			Filename:  filename,
+			Meta:      map[string]interface{}{"owner": "alice"},
+			Tags:      []string{"backup"},
+			AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/" + path + filename, MIME: "text/plain", SizeB: 3, SHA256: "abc"}),
+		}
+	}
+	readEntry := func(t *testing.T, f *zip.File) string {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("entries and manifest", func(t *testing.T) {
+		zr, err := archiveOf(t, []*model.Artifact{
+			artifact("/", "readme.md"),
+			artifact("/docs/", "a.pdf"),
+			artifact("/docs/2024/", "b.pdf"),
+			artifact("/", "manifest.json"),
+		})
+		assert.NoError(t, err)
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, []string{"readme.md", "docs/a.pdf", "docs/2024/b.pdf", "manifest (2).json", "manifest.json"}, names)
+		assert.Equal(t, "disks//docs/2024/b.pdf", readEntry(t, zr.File[2]))
+		assert.Equal(t, "disks//manifest.json", readEntry(t, zr.File[3]))
+
+		var manifest ArchiveManifest
+		assert.NoError(t, json.Unmarshal([]byte(readEntry(t, zr.File[4])), &manifest))
+		assert.Equal(t, diskID, manifest.DiskID)
+		assert.Len(t, manifest.Files, 4)
+		assert.Equal(t, ArchiveManifestEntry{
+			Name:     "docs/a.pdf",
+			Path:     "/docs/",
+			Filename: "a.pdf",
+			MIME:     "text/plain",
+			SizeB:    3,
+			SHA256:   "abc",
+			Meta:     map[string]interface{}{"owner": "alice"},
+			Tags:     []string{"backup"},
+		}, manifest.Files[1])
+		assert.Equal(t, "manifest (2).json", manifest.Files[3].Name)
+		assert.Equal(t, "/manifest.json", manifest.Files[3].Path+manifest.Files[3].Filename)
+	})
+
+	t.Run("pages through large disks", func(t *testing.T) {
+		var artifacts []*model.Artifact
+		for i := 0; i < archivePageSize*2+1; i++ {
+			artifacts = append(artifacts, artifact("/logs/", fmt.Sprintf("%03d.log", i)))
+		}
+		zr, err := archiveOf(t, artifacts)
+		assert.NoError(t, err)
+		assert.Len(t, zr.File, len(artifacts)+1)
+		assert.Equal(t, "logs/400.log", zr.File[len(artifacts)-1].Name)
+	})
+
+	t.Run("empty disk", func(t *testing.T) {
+		zr, err := archiveOf(t, nil)
+		assert.NoError(t, err)
+		assert.Len(t, zr.File, 1)
+		assert.Equal(t, ArchiveManifestName, zr.File[0].Name)
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		broken := artifact("/", "broken.txt")
+		broken.AssetMeta = datatypes.NewJSONType(model.Asset{})
+		_, err := archiveOf(t, []*model.Artifact{broken})
+		assert.ErrorContains(t, err, "archive /broken.txt")
+	})
+}
+
+func TestUniqueArchiveName(t *testing.T) {
+	taken := map[string]bool{}
+	assert.Equal(t, "docs/a.pdf", uniqueArchiveName(taken, "docs/a.pdf"))
+	assert.Equal(t, "docs/a (2).pdf", uniqueArchiveName(taken, "docs/a.pdf"))
+	assert.Equal(t, "docs/a (3).pdf", uniqueArchiveName(taken, "docs/a.pdf"))
+	assert.Equal(t, "Makefile", uniqueArchiveName(taken, "Makefile"))
+	assert.Equal(t, "Makefile (2)", uniqueArchiveName(taken, "Makefile"))
+}
+
+func TestImportArchive(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	// zipOf builds an archive of the given entries; names ending in "/" are directories
+	zipOf := func(t *testing.T, entries ...[2]string) *bytes.Reader {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, e := range entries {
+			w, err := zw.Create(e[0])
+			assert.NoError(t, err)
+			_, err = io.WriteString(w, e[1])
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, zw.Close())
+		return bytes.NewReader(buf.Bytes())
+	}
+	// importOf imports archive into a disk holding the existing paths, returning the created artifacts
+	importOf := func(t *testing.T, archive *bytes.Reader, mode ArchiveImportMode, existing ...string) (*ImportArchiveOutput, []*model.Artifact, *MockArtifactRepo, error) {
+		repo := new(MockArtifactRepo)
+		for _, e := range existing {
+			dir, filename := path.SplitFilePath(e)
+			repo.On("ExistsByPathAndFilename", ctx, diskID, dir, filename, (*uuid.UUID)(nil)).Return(true, nil)
+		}
+		repo.On("ExistsByPathAndFilename", ctx, diskID, mock.Anything, mock.Anything, (*uuid.UUID)(nil)).Return(false, nil).Maybe()
+		repo.On("DeleteByPath", ctx, projectID, diskID, mock.Anything, mock.Anything).Return(nil).Maybe()
+		var created []*model.Artifact
+		repo.On("Create", ctx, projectID, mock.Anything).Run(func(args mock.Arguments) {
+			created = append(created, args.Get(2).(*model.Artifact))
+		}).Return(nil).Maybe()
+		upload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+			sum := sha256.Sum256(data)
+			return &model.Asset{S3Key: "disks/" + hex.EncodeToString(sum[:]), SHA256: hex.EncodeToString(sum[:]), MIME: contentType, SizeB: int64(len(data))}, nil
+		}
+
+		out, err := importArchive(ctx, ImportArchiveInput{ProjectID: projectID, DiskID: diskID, Archive: archive, Size: archive.Size(), Mode: mode}, repo, upload)
+		return out, created, repo, err
+	}
+	pathsOf := func(artifacts []*model.Artifact) []string {
+		var paths []string
+		for _, a := range artifacts {
+			paths = append(paths, a.Path+a.Filename)
+		}
+		return paths
+	}
+
+	t.Run("nested folders", func(t *testing.T) {
+		archive := zipOf(t,
+			[2]string{"readme.md", "# Notes"},
+			[2]string{"docs/", ""},
+			[2]string{"docs/a.txt", "a"},
+			[2]string{"docs/2024/q1/b.json", `{"b":1}`},
+			[2]string{"docs/existing.txt", "new"},
+			[2]string{"../evil.txt", "x"},
+		)
+		out, created, _, err := importOf(t, archive, ArchiveImportSkip, "/docs/existing.txt")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, out.Created)
+		assert.Equal(t, 0, out.Overwritten)
+		assert.Equal(t, 1, out.Skipped)
+		assert.Len(t, out.Failed, 1)
+		assert.Equal(t, "../evil.txt", out.Failed[0].Name)
+		assert.Contains(t, out.Failed[0].Error, "invalid path")
+
+		assert.Equal(t, []string{"/readme.md", "/docs/a.txt", "/docs/2024/q1/b.json"}, pathsOf(created))
+		b := created[2]
+		assert.Equal(t, diskID, b.DiskID)
+		assert.Equal(t, "application/json", b.AssetMeta.Data().MIME)
+		assert.Equal(t, int64(7), b.AssetMeta.Data().SizeB)
+		assert.Equal(t, "/docs/2024/q1/", b.Meta[model.ArtifactInfoKey].(map[string]interface{})["path"])
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		archive := zipOf(t, [2]string{"docs/existing.txt", "new"}, [2]string{"docs/fresh.txt", "fresh"})
+		out, created, repo, err := importOf(t, archive, ArchiveImportOverwrite, "/docs/existing.txt")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, out.Created)
+		assert.Equal(t, 1, out.Overwritten)
+		assert.Equal(t, 0, out.Skipped)
+		assert.Equal(t, []string{"/docs/existing.txt", "/docs/fresh.txt"}, pathsOf(created))
+		repo.AssertCalled(t, "DeleteByPath", ctx, projectID, diskID, "/docs/", "existing.txt")
+		repo.AssertNumberOfCalls(t, "DeleteByPath", 1)
+	})
+
+	t.Run("overwrite with identical content keeps the object", func(t *testing.T) {
+		// A bucket counting references like the asset references: uploads of known content
+		// reuse the stored object, which is freed when its last artifact is deleted
+		stored := map[string]bool{}
+		refs := map[string]int{}
+		key := func(data []byte) string {
+			sum := sha256.Sum256(data)
+			return "disks/" + hex.EncodeToString(sum[:])
+		}
+		same := key([]byte("same"))
+		stored[same], refs[same] = true, 1
+
+		repo := new(MockArtifactRepo)
+		repo.On("ExistsByPathAndFilename", ctx, diskID, "/docs/", "a.txt", (*uuid.UUID)(nil)).Return(true, nil)
+		repo.On("DeleteByPath", ctx, projectID, diskID, "/docs/", "a.txt").Run(func(mock.Arguments) {
+			if refs[same]--; refs[same] <= 0 {
+				delete(stored, same)
+			}
+		}).Return(nil)
+		repo.On("Create", ctx, projectID, mock.Anything).Run(func(args mock.Arguments) {
+			refs[args.Get(2).(*model.Artifact).AssetMeta.Data().S3Key]++
+		}).Return(nil)
+		upload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+			stored[key(data)] = true
+			return &model.Asset{S3Key: key(data), SizeB: int64(len(data))}, nil
+		}
+
+		archive := zipOf(t, [2]string{"docs/a.txt", "same"})
+		out, err := importArchive(ctx, ImportArchiveInput{ProjectID: projectID, DiskID: diskID, Archive: archive, Size: archive.Size(), Mode: ArchiveImportOverwrite}, repo, upload)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, out.Overwritten)
+		assert.True(t, stored[same], "the object of the overwritten file must be kept")
+		assert.Equal(t, 1, refs[same])
+	})
+
+	t.Run("restores an exported disk", func(t *testing.T) {
+		exported := []*model.Artifact{
+			{
+				ID:        uuid.New(),
+				Path:      "/docs/",
+				Filename:  "a.txt",
+				Meta:      map[string]interface{}{model.ArtifactInfoKey: map[string]interface{}{"path": "/old/"}, "owner": "alice"},
+				Tags:      []string{"backup"},
+				AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "a"}),
+			},
+			{ID: uuid.New(), Path: "/", Filename: "manifest.json", AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "{}"})},
+		}
+		list := func(ctx context.Context, diskID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+			return exported, nil
+		}
+		open := func(ctx context.Context, asset model.Asset) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(asset.S3Key)), nil
+		}
+		var buf bytes.Buffer
+		assert.NoError(t, writeArchive(ctx, &buf, uuid.New(), list, open))
+
+		out, created, _, err := importOf(t, bytes.NewReader(buf.Bytes()), ArchiveImportSkip)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, out.Created)
+		assert.Empty(t, out.Failed)
+		assert.Equal(t, []string{"/docs/a.txt", "/manifest.json"}, pathsOf(created))
+		assert.Equal(t, "alice", created[0].Meta["owner"])
+		assert.Equal(t, "/docs/", created[0].Meta[model.ArtifactInfoKey].(map[string]interface{})["path"])
+		assert.Equal(t, []string{"backup"}, []string(created[0].Tags))
+		assert.Equal(t, int64(2), created[1].AssetMeta.Data().SizeB)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		_, _, _, err := importOf(t, bytes.NewReader([]byte("not a zip")), ArchiveImportSkip)
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+	})
+}
+
+type fakeRemoteFetcher struct {
+	file *httpclient.RemoteFile
+	err  error
+}
+
+func (f fakeRemoteFetcher) Fetch(ctx context.Context, rawURL string) (*httpclient.RemoteFile, error) {
+	return f.file, f.err
+}
+
+func TestCreateFromURL(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	upload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+		return &model.Asset{S3Key: "disks/" + filename, MIME: contentType, SizeB: int64(len(data))}, nil
+	}
+
+	t.Run("stores the fetched file", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		repo.On("ExistsByPathAndFilename", ctx, diskID, "/docs/", "report.pdf", (*uuid.UUID)(nil)).Return(false, nil)
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+		fetch := fakeRemoteFetcher{file: &httpclient.RemoteFile{Data: []byte("%PDF-1.4"), ContentType: "application/octet-stream", Filename: "report.pdf"}}
+
+		artifact, err := createFromURL(ctx, CreateFromURLInput{ProjectID: projectID, DiskID: diskID, Path: "/docs/", SourceURL: "https://example.com/report.pdf", UserMeta: map[string]interface{}{"source": "web"}}, fetch, repo, upload)
+		assert.NoError(t, err)
+		assert.Equal(t, "report.pdf", artifact.Filename)
+		assert.Equal(t, "application/pdf", artifact.AssetMeta.Data().MIME)
+		assert.Equal(t, "web", artifact.Meta["source"])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("given filename wins over the remote one", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		repo.On("ExistsByPathAndFilename", ctx, diskID, "/", "notes.txt", (*uuid.UUID)(nil)).Return(true, nil)
+		repo.On("DeleteByPath", ctx, projectID, diskID, "/", "notes.txt").Return(nil)
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+		fetch := fakeRemoteFetcher{file: &httpclient.RemoteFile{Data: []byte("hello"), ContentType: "text/plain", Filename: "download"}}
+
+		artifact, err := createFromURL(ctx, CreateFromURLInput{ProjectID: projectID, DiskID: diskID, Path: "/", Filename: "notes.txt", SourceURL: "https://example.com/download"}, fetch, repo, upload)
+		assert.NoError(t, err)
+		assert.Equal(t, "notes.txt", artifact.Filename)
+		assert.Equal(t, "text/plain", artifact.AssetMeta.Data().MIME)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("replaced artifact is deleted before the upload", func(t *testing.T) {
+		// An unchanged file reuses the stored object: deleting the old artifact after the
+		// upload would free that object
+		var calls []string
+		repo := new(MockArtifactRepo)
+		repo.On("ExistsByPathAndFilename", ctx, diskID, "/", "report.pdf", (*uuid.UUID)(nil)).Return(true, nil)
+		repo.On("DeleteByPath", ctx, projectID, diskID, "/", "report.pdf").Run(func(mock.Arguments) { calls = append(calls, "delete") }).Return(nil)
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+		recordUpload := func(ctx context.Context, data []byte, contentType string, dir string, filename string) (*model.Asset, error) {
+			calls = append(calls, "upload")
+			return upload(ctx, data, contentType, dir, filename)
+		}
+		fetch := fakeRemoteFetcher{file: &httpclient.RemoteFile{Data: []byte("%PDF-1.4"), ContentType: "application/pdf", Filename: "report.pdf"}}
+
+		_, err := createFromURL(ctx, CreateFromURLInput{ProjectID: projectID, DiskID: diskID, Path: "/", SourceURL: "https://example.com/report.pdf"}, fetch, repo, recordUpload)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"delete", "upload"}, calls)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("forbidden url", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		fetch := fakeRemoteFetcher{err: fmt.Errorf("%w: http://169.254.169.254/", safehttp.ErrForbiddenURL)}
+
+		_, err := createFromURL(ctx, CreateFromURLInput{ProjectID: projectID, DiskID: diskID, Path: "/", SourceURL: "http://169.254.169.254/"}, fetch, repo, upload)
+		assert.ErrorIs(t, err, safehttp.ErrForbiddenURL)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("no filename", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		fetch := fakeRemoteFetcher{file: &httpclient.RemoteFile{Data: []byte("x")}}
+
+		_, err := createFromURL(ctx, CreateFromURLInput{ProjectID: projectID, DiskID: diskID, Path: "/", SourceURL: "https://example.com/"}, fetch, repo, upload)
+		assert.ErrorIs(t, err, ErrInvalidArtifactPath)
+	})
+}
+
+// memS3 is an in-memory, path-style S3 bucket answering PUT, copy, GET, HEAD, listings and
+// DELETE, recording the requests it receives
+type memS3 struct {
+	mu      sync.Mutex
+	calls   []string
+	objects map[string][]byte
+}
+
+func (m *memS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/test-bucket/")
+	switch {
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		m.calls = append(m.calls, "copy")
+		src, _ := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "test-bucket/"))
+		data, ok := m.objects[src]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		m.objects[key] = data
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+	case r.Method == http.MethodPut:
+		m.calls = append(m.calls, "put")
+		m.objects[key], _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		m.calls = append(m.calls, "list")
+		var contents strings.Builder
+		for k := range m.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				contents.WriteString("<Contents><Key>" + k + "</Key></Contents>")
+			}
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<ListBucketResult><Name>test-bucket</Name>` + contents.String() + `<IsTruncated>false</IsTruncated></ListBucketResult>`))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		m.calls = append(m.calls, strings.ToLower(r.Method))
+		data, ok := m.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		m.calls = append(m.calls, "delete")
+		delete(m.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// newMemS3Resolver returns a resolver of every project to a new memS3 bucket
+func newMemS3Resolver(t *testing.T) (blob.S3Resolver, *memS3) {
+	mem := &memS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(mem)
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+	})
+	return blob.NewDefaultS3Resolver(&blob.S3Deps{Client: client, Uploader: manager.NewUploader(client), Bucket: "test-bucket"}), mem
+}
+
+func TestArtifactService_Create_KeyFormat(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	diskID := uuid.New()
+	content := []byte("%PDF-1.4 quarterly report")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	create := func(t *testing.T, pathAddressed bool) (*model.Artifact, *memS3) {
+		resolver, mem := newMemS3Resolver(t)
+		repo := &MockArtifactRepo{}
+		repo.On("PathAddressedKeys", ctx, diskID).Return(pathAddressed, nil)
+		repo.On("ExistsByPathAndFilename", ctx, diskID, "/docs/", "report.pdf", (*uuid.UUID)(nil)).Return(false, nil)
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+
+		artifact, err := NewArtifactService(repo, nil, resolver, nil, nil, nil, false).Create(ctx, CreateArtifactInput{
+			ProjectID:  projectID,
+			DiskID:     diskID,
+			Path:       "/docs/",
+			Filename:   "report.pdf",
+			FileHeader: newFormFileHeader(t, "report.pdf", content),
+		})
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		return artifact, mem
+	}
+
+	t.Run("content addressed by default", func(t *testing.T) {
+		artifact, mem := create(t, false)
+		key := artifact.AssetMeta.Data().S3Key
+		assert.Regexp(t, `^disks/`+projectID.String()+`/\d{4}/\d{2}/\d{2}/`+checksum+`\.pdf$`, key)
+		assert.False(t, artifact.OwnsObject())
+		assert.Equal(t, content, mem.objects[key])
+	})
+
+	t.Run("path addressed", func(t *testing.T) {
+		artifact, mem := create(t, true)
+		key := artifact.AssetMeta.Data().S3Key
+		assert.Equal(t, "disks/"+diskID.String()+"/docs/report.pdf", key)
+		assert.Equal(t, checksum, artifact.AssetMeta.Data().SHA256)
+		assert.True(t, artifact.OwnsObject())
+		assert.Equal(t, content, mem.objects[key])
+		// Uploaded under its key, without looking for an equal file to share
+		assert.Equal(t, []string{"put"}, mem.calls)
+	})
+}
+
+func TestArtifactService_MoveArtifact(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	content := []byte("quarterly report")
+
+	// ownedArtifact returns an artifact stored under its path addressed key in mem
+	ownedArtifact := func(mem *memS3) *model.Artifact {
+		a := createTestArtifact()
+		a.Path, a.Filename = "/docs/", "report.txt"
+		asset := a.AssetMeta.Data()
+		asset.S3Key = model.PathAddressedKey(a.DiskID, a.Path, a.Filename)
+		a.AssetMeta = datatypes.NewJSONType(asset)
+		mem.objects[asset.S3Key] = content
+		return a
+	}
+	input := func(a *model.Artifact) MoveArtifactInput {
+		return MoveArtifactInput{ProjectID: projectID, DiskID: a.DiskID, Path: "/docs/", Filename: "report.txt", DstPath: "/archive/2024/", DstFilename: "q1.txt"}
+	}
+
+	t.Run("relocates an owned object", func(t *testing.T) {
+		resolver, mem := newMemS3Resolver(t)
+		a := ownedArtifact(mem)
+		a.Tags = []string{"report"}
+		oldKey := a.AssetMeta.Data().S3Key
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, "/docs/", "report.txt").Return(a, nil)
+		repo.On("ExistsByPathAndFilename", ctx, a.DiskID, "/archive/2024/", "q1.txt", &a.ID).Return(false, nil)
+		repo.On("Move", ctx, a).Return(nil)
+
+		moved, err := (&artifactService{r: repo, s3: resolver}).MoveArtifact(ctx, input(a))
+		assert.NoError(t, err)
+		newKey := "disks/" + a.DiskID.String() + "/archive/2024/q1.txt"
+		assert.Equal(t, newKey, moved.AssetMeta.Data().S3Key)
+		assert.Equal(t, "test-sha256", moved.AssetMeta.Data().SHA256)
+		assert.True(t, moved.OwnsObject())
+		assert.Equal(t, "/archive/2024/", moved.Path)
+		assert.Equal(t, "q1.txt", moved.Filename)
+		info := moved.Meta[model.ArtifactInfoKey].(map[string]interface{})
+		assert.Equal(t, "/archive/2024/", info["path"])
+		assert.Equal(t, "q1.txt", info["filename"])
+		assert.Equal(t, []string{"report"}, []string(moved.Tags))
+
+		assert.Equal(t, []string{"copy", "delete"}, mem.calls)
+		assert.Equal(t, map[string][]byte{newKey: content}, mem.objects)
+		assert.NotContains(t, mem.objects, oldKey)
+	})
+
+	t.Run("failed record update keeps the old object", func(t *testing.T) {
+		resolver, mem := newMemS3Resolver(t)
+		a := ownedArtifact(mem)
+		oldKey := a.AssetMeta.Data().S3Key
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, "/docs/", "report.txt").Return(a, nil)
+		repo.On("ExistsByPathAndFilename", ctx, a.DiskID, "/archive/2024/", "q1.txt", &a.ID).Return(false, nil)
+		repo.On("Move", ctx, a).Return(errors.New("db unavailable"))
+
+		_, err := (&artifactService{r: repo, s3: resolver}).MoveArtifact(ctx, input(a))
+		assert.Error(t, err)
+		assert.Equal(t, map[string][]byte{oldKey: content}, mem.objects, "the copy is removed")
+	})
+
+	t.Run("shared asset only moves the record", func(t *testing.T) {
+		a := createTestArtifact()
+		a.Path, a.Filename = "/docs/", "report.txt"
+		asset := a.AssetMeta.Data()
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, a.DiskID, "/docs/", "report.txt").Return(a, nil)
+		repo.On("ExistsByPathAndFilename", ctx, a.DiskID, "/archive/2024/", "q1.txt", &a.ID).Return(false, nil)
+		repo.On("Move", ctx, a).Return(nil)
+
+		// No S3 client: the shared object must not be touched
+		moved, err := (&artifactService{r: repo}).MoveArtifact(ctx, input(a))
+		assert.NoError(t, err)
+		assert.Equal(t, asset, moved.AssetMeta.Data())
+		assert.Equal(t, "/archive/2024/", moved.Path)
+		assert.Equal(t, "q1.txt", moved.Filename)
+	})
+
+	t.Run("invalid destination", func(t *testing.T) {
+		repo := &MockArtifactRepo{}
+		_, err := (&artifactService{r: repo}).MoveArtifact(ctx, MoveArtifactInput{ProjectID: projectID, DiskID: uuid.New(), Path: "/docs/", Filename: "report.txt", DstPath: "/../", DstFilename: "q1.txt"})
+		assert.ErrorIs(t, err, ErrInvalidArtifactPath)
+		repo.AssertNotCalled(t, "GetByPath", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestArtifactService_CopyArtifact_PathAddressed(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	dstDiskID := uuid.New()
+	content := []byte("quarterly report")
+
+	copyTo := func(t *testing.T, resolver blob.S3Resolver, src *model.Artifact, pathAddressed bool) *model.Artifact {
+		repo := &MockArtifactRepo{}
+		repo.On("GetByPath", ctx, src.DiskID, src.Path, src.Filename).Return(src, nil)
+		repo.On("GetByPath", ctx, dstDiskID, "/copies/", "copy.txt").Return(nil, gorm.ErrRecordNotFound)
+		repo.On("PathAddressedKeys", ctx, dstDiskID).Return(pathAddressed, nil)
+		repo.On("Create", ctx, projectID, mock.AnythingOfType("*model.Artifact")).Return(nil)
+
+		copied, err := (&artifactService{r: repo, s3: resolver}).CopyArtifact(ctx, CopyArtifactInput{
+			ProjectID:   projectID,
+			SrcDiskID:   src.DiskID,
+			SrcPath:     src.Path,
+			SrcFilename: src.Filename,
+			DstDiskID:   dstDiskID,
+			DstPath:     "/copies/",
+			DstFilename: "copy.txt",
+		})
+		assert.NoError(t, err)
+		return copied
+	}
+
+	t.Run("to a path addressed disk", func(t *testing.T) {
+		resolver, mem := newMemS3Resolver(t)
+		src := createTestArtifact()
+		mem.objects[src.AssetMeta.Data().S3Key] = content
+
+		copied := copyTo(t, resolver, src, true)
+		key := "disks/" + dstDiskID.String() + "/copies/copy.txt"
+		assert.Equal(t, key, copied.AssetMeta.Data().S3Key)
+		assert.True(t, copied.OwnsObject())
+		assert.Equal(t, []string{"copy"}, mem.calls)
+		assert.Equal(t, content, mem.objects[key])
+	})
+
+	t.Run("from an owned object to a content addressed disk", func(t *testing.T) {
+		resolver, mem := newMemS3Resolver(t)
+		src := createTestArtifact()
+		asset := src.AssetMeta.Data()
+		asset.S3Key = model.PathAddressedKey(src.DiskID, src.Path, src.Filename)
+		src.AssetMeta = datatypes.NewJSONType(asset)
+		mem.objects[asset.S3Key] = content
+		sum := sha256.Sum256(content)
+
+		copied := copyTo(t, resolver, src, false)
+		key := copied.AssetMeta.Data().S3Key
+		assert.Regexp(t, `^disks/`+projectID.String()+`/\d{4}/\d{2}/\d{2}/`+hex.EncodeToString(sum[:])+`\.txt$`, key)
+		assert.False(t, copied.OwnsObject())
+		assert.Equal(t, content, mem.objects[key])
+		assert.Equal(t, content, mem.objects[asset.S3Key], "the source keeps its object")
+	})
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+)
+
+// orphanGracePeriod keeps freshly orphaned assets around for a while, so in-flight
+// requests that are about to reference them again are not raced by the sweeper
+const orphanGracePeriod = 24 * time.Hour
+
+type AssetReferenceService interface {
+	SweepOrphanedAssets(ctx context.Context, projectID uuid.UUID, dryRun bool) (int, error)
+	ListAssetReferences(ctx context.Context, in ListAssetReferencesInput) (*ListAssetReferencesOutput, error)
+}
+
+type assetReferenceService struct {
+	r repo.AssetReferenceRepo
+}
+
+func NewAssetReferenceService(r repo.AssetReferenceRepo) AssetReferenceService {
+	return &assetReferenceService{r: r}
+}
+
+// SweepOrphanedAssets deletes the S3 objects and rows of asset references with no
+// remaining references that have been orphaned for longer than the grace period.
+// In dry-run mode nothing is deleted and the number of assets that would be is returned.
+func (s *assetReferenceService) SweepOrphanedAssets(ctx context.Context, projectID uuid.UUID, dryRun bool) (int, error) {
+	before := time.Now().Add(-orphanGracePeriod)
+
+	if dryRun {
+		refs, err := s.r.ListOrphaned(ctx, projectID, before)
+		if err != nil {
+			return 0, fmt.Errorf("list orphaned assets: %w", err)
+		}
+		return len(refs), nil
+	}
+
+	deleted, err := s.r.DeleteOrphaned(ctx, projectID, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete orphaned assets: %w", err)
+	}
+	return deleted, nil
+}
+
+// DefaultAssetReferencesLimit is the page size of ListAssetReferences when Limit is not positive
+const DefaultAssetReferencesLimit = 20
+
+type ListAssetReferencesInput struct {
+	ProjectID            uuid.UUID `json:"project_id"`
+	MinRefCount          int       `json:"min_ref_count"`
+	OrphanedOnly         bool      `json:"orphaned_only"`
+	LastReferencedBefore time.Time `json:"last_referenced_before"`
+	Limit                int       `json:"limit"`
+	Cursor               string    `json:"cursor"`
+}
+
+// ListAssetReferencesOutput is a page of asset references. TotalCount and TotalSizeB cover
+// every reference matching the filters, not only the page.
+type ListAssetReferencesOutput struct {
+	Items      []model.AssetReference `json:"items"`
+	TotalCount int64                  `json:"total_count"`
+	TotalSizeB int64                  `json:"total_size_b"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+	HasMore    bool                   `json:"has_more"`
+}
+
+// ListAssetReferences lists the stored assets of a project with their reference counts, to
+// help with capacity planning and garbage collection decisions.
+func (s *assetReferenceService) ListAssetReferences(ctx context.Context, in ListAssetReferencesInput) (*ListAssetReferencesOutput, error) {
+	if in.Limit <= 0 {
+		in.Limit = DefaultAssetReferencesLimit
+	}
+
+	// Parse cursor (createdAt, id); an empty cursor starts from the oldest reference
+	var afterT time.Time
+	var afterID uuid.UUID
+	var err error
+	if in.Cursor != "" {
+		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter := repo.AssetReferenceFilter{
+		MinRefCount:          in.MinRefCount,
+		OrphanedOnly:         in.OrphanedOnly,
+		LastReferencedBefore: in.LastReferencedBefore,
+	}
+	count, totalSizeB, err := s.r.SumAssetReferences(ctx, in.ProjectID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("sum asset references: %w", err)
+	}
+
+	// Query limit+1 is used to determine has_more
+	filter.AfterCreatedAt, filter.AfterID, filter.Limit = afterT, afterID, in.Limit+1
+	refs, err := s.r.ListAssetReferences(ctx, in.ProjectID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list asset references: %w", err)
+	}
+
+	out := &ListAssetReferencesOutput{
+		Items:      refs,
+		TotalCount: count,
+		TotalSizeB: totalSizeB,
+	}
+	if len(refs) > in.Limit {
+		out.HasMore = true
+		out.Items = refs[:in.Limit]
+		last := out.Items[len(out.Items)-1]
+		out.NextCursor = paging.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return out, nil
+}
+
+// assetKeyLookup resolves content hashes to S3 keys already stored in bucket through the
+// asset_references table, so deduplicated uploads can skip scanning the bucket.
+func assetKeyLookup(r repo.AssetReferenceRepo, projectID uuid.UUID, bucket string) blob.KeyLookup {
+	if r == nil {
+		return nil
+	}
+	return func(ctx context.Context, sha256 string) (string, error) {
+		return r.GetS3KeyBySHA256(ctx, projectID, bucket, sha256)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAssetReferenceService_SweepOrphanedAssets(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	// The cutoff must leave the grace period untouched
+	withinGrace := mock.MatchedBy(func(before time.Time) bool {
+		return before.Before(time.Now().Add(-orphanGracePeriod + time.Minute))
+	})
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		setup       func(*MockAssetReferenceRepo)
+		wantDeleted int
+		wantErr     bool
+	}{
+		{
+			name:   "dry run only counts orphans",
+			dryRun: true,
+			setup: func(r *MockAssetReferenceRepo) {
+				r.On("ListOrphaned", ctx, projectID, withinGrace).Return([]model.AssetReference{
+					{SHA256: "a", S3Key: "assets/a.png"},
+					{SHA256: "b", S3Key: "assets/b.png"},
+				}, nil)
+			},
+			wantDeleted: 2,
+		},
+		{
+			name: "sweep deletes orphans",
+			setup: func(r *MockAssetReferenceRepo) {
+				r.On("DeleteOrphaned", ctx, projectID, withinGrace).Return(3, nil)
+			},
+			wantDeleted: 3,
+		},
+		{
+			name: "sweep error",
+			setup: func(r *MockAssetReferenceRepo) {
+				r.On("DeleteOrphaned", ctx, projectID, withinGrace).Return(0, errors.New("s3 unavailable"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MockAssetReferenceRepo{}
+			tt.setup(r)
+
+			service := NewAssetReferenceService(r)
+			deleted, err := service.SweepOrphanedAssets(ctx, projectID, tt.dryRun)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantDeleted, deleted)
+			}
+			r.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAssetReferenceService_ListAssetReferences(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := repo.AssetReferenceFilter{MinRefCount: 2, LastReferencedBefore: cutoff}
+
+	refs := []model.AssetReference{
+		{ID: uuid.New(), SHA256: "a", CreatedAt: cutoff.Add(-3 * time.Hour)},
+		{ID: uuid.New(), SHA256: "b", CreatedAt: cutoff.Add(-2 * time.Hour)},
+		{ID: uuid.New(), SHA256: "c", CreatedAt: cutoff.Add(-time.Hour)},
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		r := &MockAssetReferenceRepo{}
+		r.On("SumAssetReferences", ctx, projectID, filter).Return(int64(3), int64(600), nil)
+		paged := filter
+		paged.Limit = 3
+		r.On("ListAssetReferences", ctx, projectID, paged).Return(refs, nil)
+
+		out, err := NewAssetReferenceService(r).ListAssetReferences(ctx, ListAssetReferencesInput{
+			ProjectID: projectID, MinRefCount: 2, LastReferencedBefore: cutoff, Limit: 2,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, refs[:2], out.Items)
+		assert.Equal(t, int64(3), out.TotalCount)
+		assert.Equal(t, int64(600), out.TotalSizeB)
+		assert.True(t, out.HasMore)
+		assert.Equal(t, paging.EncodeCursor(refs[1].CreatedAt, refs[1].ID), out.NextCursor)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		r := &MockAssetReferenceRepo{}
+		r.On("SumAssetReferences", ctx, projectID, filter).Return(int64(3), int64(600), nil)
+		paged := filter
+		paged.AfterCreatedAt, paged.AfterID = refs[1].CreatedAt, refs[1].ID
+		paged.Limit = 3
+		r.On("ListAssetReferences", ctx, projectID, paged).Return(refs[2:], nil)
+
+		out, err := NewAssetReferenceService(r).ListAssetReferences(ctx, ListAssetReferencesInput{
+			ProjectID: projectID, MinRefCount: 2, LastReferencedBefore: cutoff, Limit: 2,
+			Cursor: paging.EncodeCursor(refs[1].CreatedAt, refs[1].ID),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, refs[2:], out.Items)
+		assert.False(t, out.HasMore)
+		assert.Empty(t, out.NextCursor)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("zero limit uses the default", func(t *testing.T) {
+		r := &MockAssetReferenceRepo{}
+		r.On("SumAssetReferences", ctx, projectID, repo.AssetReferenceFilter{}).Return(int64(3), int64(600), nil)
+		r.On("ListAssetReferences", ctx, projectID, repo.AssetReferenceFilter{Limit: DefaultAssetReferencesLimit + 1}).Return(refs, nil)
+
+		out, err := NewAssetReferenceService(r).ListAssetReferences(ctx, ListAssetReferencesInput{ProjectID: projectID})
+		assert.NoError(t, err)
+		assert.Equal(t, refs, out.Items)
+		assert.False(t, out.HasMore)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		r := &MockAssetReferenceRepo{}
+
+		_, err := NewAssetReferenceService(r).ListAssetReferences(ctx, ListAssetReferencesInput{ProjectID: projectID, Limit: 2, Cursor: "not-a-cursor"})
+		assert.Error(t, err)
+		r.AssertNotCalled(t, "SumAssetReferences", mock.Anything, mock.Anything, mock.Anything)
+		r.AssertNotCalled(t, "ListAssetReferences", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// AssetRefToucher marks assets as in use when their artifacts are served, so the orphan
+// sweep never collects an asset that was read since its last reference change. Touches are
+// batched in a fast store and periodically written to the asset references.
+type AssetRefToucher interface {
+	// Touch marks the asset of a disk as used now
+	Touch(ctx context.Context, diskID uuid.UUID, sha256 string) error
+	// Flush refreshes the last referenced time of the touched assets
+	Flush(ctx context.Context) error
+}
+
+const (
+	// Redis set of the pending touches, as "<disk id>:<sha256>"
+	redisKeyAssetTouchPending = "asset:touch:pending"
+	// Number of touches taken from Redis per round trip during a flush
+	assetTouchFlushBatch = 500
+)
+
+type redisAssetRefToucher struct {
+	rdb      *redis.Client
+	diskRepo repo.DiskRepo
+	r        repo.AssetReferenceRepo
+	log      *zap.Logger
+}
+
+// NewRedisAssetRefToucher collects touches in a Redis set and flushes them to r. Repeated
+// touches of an asset between two flushes cost a single update.
+func NewRedisAssetRefToucher(rdb *redis.Client, diskRepo repo.DiskRepo, r repo.AssetReferenceRepo, log *zap.Logger) AssetRefToucher {
+	return &redisAssetRefToucher{rdb: rdb, diskRepo: diskRepo, r: r, log: log}
+}
+
+func (t *redisAssetRefToucher) Touch(ctx context.Context, diskID uuid.UUID, sha256 string) error {
+	if err := t.rdb.SAdd(ctx, redisKeyAssetTouchPending, diskID.String()+":"+sha256).Err(); err != nil {
+		return fmt.Errorf("touch asset %s: %w", sha256, err)
+	}
+	return nil
+}
+
+func (t *redisAssetRefToucher) Flush(ctx context.Context) error {
+	// Take every pending touch first, so touches put back on failure wait for the next flush
+	var touches []string
+	for {
+		batch, err := t.rdb.SPopN(ctx, redisKeyAssetTouchPending, assetTouchFlushBatch).Result()
+		if err != nil {
+			t.restore(ctx, touches)
+			return fmt.Errorf("take pending asset touches: %w", err)
+		}
+		touches = append(touches, batch...)
+		if len(batch) < assetTouchFlushBatch {
+			break
+		}
+	}
+
+	var (
+		errs     []error
+		failed   []string
+		projects = map[uuid.UUID]uuid.UUID{}
+	)
+	for _, touch := range touches {
+		rawDiskID, sha256, ok := strings.Cut(touch, ":")
+		diskID, err := uuid.Parse(rawDiskID)
+		if !ok || err != nil || sha256 == "" {
+			continue
+		}
+
+		projectID, ok := projects[diskID]
+		if !ok {
+			disk, err := t.diskRepo.GetByID(ctx, diskID)
+			if err != nil {
+				// A deleted disk released its references, nothing to keep alive
+				continue
+			}
+			projectID = disk.ProjectID
+			projects[diskID] = projectID
+		}
+
+		if err := t.r.TouchAssetRef(ctx, projectID, sha256); err != nil {
+			failed = append(failed, touch)
+			errs = append(errs, fmt.Errorf("touch asset %s of project %s: %w", sha256, projectID, err))
+		}
+	}
+	t.restore(ctx, failed)
+	return errors.Join(errs...)
+}
+
+// restore puts touches back for the next flush
+func (t *redisAssetRefToucher) restore(ctx context.Context, touches []string) {
+	if len(touches) == 0 {
+		return
+	}
+	members := make([]any, len(touches))
+	for i, touch := range touches {
+		members[i] = touch
+	}
+	if err := t.rdb.SAdd(ctx, redisKeyAssetTouchPending, members...).Err(); err != nil {
+		t.log.Error("lost asset touches", zap.Int("count", len(touches)), zap.Error(err))
+	}
+}
+
+// RunAssetRefTouchFlusher flushes the toucher every interval until ctx is done, then
+// flushes one last time
+func RunAssetRefTouchFlusher(ctx context.Context, toucher AssetRefToucher, interval time.Duration, log *zap.Logger) {
+	runFlusher(ctx, toucher.Flush, interval, "asset touches", log)
+}
@@ -3,36 +3,71 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidBlockMove   = errors.New("invalid block move")
+	ErrInvalidBlockUpdate = errors.New("invalid block update")
 )
 
 type BlockService interface {
 	// Create - unified method, handles special logic for folder path
 	Create(ctx context.Context, b *model.Block) error
+	CheckTreeLimits(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error
 
 	// Delete - unified method
 	Delete(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) error
 
 	// Properties - unified methods
-	GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error)
-	UpdateBlockProperties(ctx context.Context, b *model.Block) error
+	GetBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error)
+	GetMany(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID) ([]model.Block, []uuid.UUID, error)
+	UpdateBlockProperties(ctx context.Context, spaceID uuid.UUID, b *model.Block) error
+	PatchBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, patch map[string]any) (*model.Block, error)
+	BulkUpdate(ctx context.Context, spaceID uuid.UUID, updates []BlockUpdate, propsPatch map[string]any) (map[uuid.UUID]error, error)
 
 	// List - unified method with optional filters
 	List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error)
 
 	// Move - unified method, handles special logic for folder path
-	Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error
+	Move(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error
+	MoveBatch(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID, newParentID *uuid.UUID) error
+	MoveToSpace(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error
+	UndoLastMove(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.BlockMove, error)
 
 	// Sort - unified method
-	UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error
+	UpdateSort(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, sort int64) error
+
+	// Import - create a whole block tree at once
+	ImportBlocks(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, nodes []BlockNode) ([]*model.Block, error)
+
+	// Count - number of blocks per type
+	CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error)
+
+	// Views - blocks of a space by last view
+	ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error)
 }
 
-type blockService struct{ r repo.BlockRepo }
+type blockService struct {
+	r      repo.BlockRepo
+	views  BlockViewRecorder
+	limits BlockTreeLimits
+}
 
-func NewBlockService(r repo.BlockRepo) BlockService { return &blockService{r: r} }
+// NewBlockService creates the block service. A nil view recorder disables view tracking, so
+// ListRecentlyViewed only returns views flushed before. Creates, moves and imports are refused
+// with ErrBlockTreeLimit when they would break limits.
+func NewBlockService(r repo.BlockRepo, views BlockViewRecorder, limits BlockTreeLimits) BlockService {
+	return &blockService{r: r, views: views, limits: limits}
+}
 
 // validateAndPrepareCreate validates a block for creation and prepares its parent
 func (s *blockService) validateAndPrepareCreate(ctx context.Context, b *model.Block) (*model.Block, error) {
@@ -56,17 +91,11 @@ func (s *blockService) validateAndPrepareCreate(ctx context.Context, b *model.Bl
 		return nil, err
 	}
 
-	return parent, nil
-}
-
-// prepareBlockForCreation sets the sort order for a new block
-func (s *blockService) prepareBlockForCreation(ctx context.Context, b *model.Block) error {
-	next, err := s.r.NextSort(ctx, b.SpaceID, b.ParentID)
-	if err != nil {
-		return err
+	if err := s.checkTreeLimits(ctx, parent, 1, 1); err != nil {
+		return nil, err
 	}
-	b.Sort = next
-	return nil
+
+	return parent, nil
 }
 
 // Create - unified create method for all block types
@@ -92,10 +121,7 @@ func (s *blockService) Create(ctx context.Context, b *model.Block) error {
 		b.SetFolderPath(path)
 	}
 
-	if err := s.prepareBlockForCreation(ctx, b); err != nil {
-		return err
-	}
-
+	// The repo appends the block to its group, assigning the sort
 	return s.r.Create(ctx, b)
 }
 
@@ -183,22 +209,143 @@ func (s *blockService) Delete(ctx context.Context, spaceID uuid.UUID, blockID uu
 	return s.r.Delete(ctx, spaceID, blockID)
 }
 
-// GetBlockProperties - unified get properties method
-func (s *blockService) GetBlockProperties(ctx context.Context, blockID uuid.UUID) (*model.Block, error) {
+// getInSpace loads a block of a space. A block of another space is not found, so its ID
+// reveals nothing to the callers of that space.
+func (s *blockService) getInSpace(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
 	if len(blockID) == 0 {
 		return nil, errors.New("block id is empty")
 	}
-	return s.r.Get(ctx, blockID)
+	b, err := s.r.Get(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SpaceID != spaceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return b, nil
 }
 
-// UpdateBlockProperties - unified update properties method
-func (s *blockService) UpdateBlockProperties(ctx context.Context, b *model.Block) error {
-	if len(b.ID) == 0 {
-		return errors.New("block id is empty")
+// GetBlockProperties - unified get properties method, recording a view of the block
+func (s *blockService) GetBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	b, err := s.getInSpace(ctx, spaceID, blockID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordViews(ctx, spaceID, b.ID)
+	return b, nil
+}
+
+// recordViews marks blocks of a space as viewed now. It is best effort and never fails the read.
+func (s *blockService) recordViews(ctx context.Context, spaceID uuid.UUID, blockIDs ...uuid.UUID) {
+	if s.views == nil {
+		return
+	}
+	now := time.Now()
+	for _, id := range blockIDs {
+		_ = s.views.Record(ctx, spaceID, id, now)
+	}
+}
+
+// GetMany returns the blocks of a space with the given IDs in the requested order, along with
+// the IDs that have no block in the space. Repeated IDs are returned once.
+func (s *blockService) GetMany(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID) ([]model.Block, []uuid.UUID, error) {
+	list, err := s.r.GetMany(ctx, blockIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]model.Block, len(list))
+	for _, b := range list {
+		if b.SpaceID == spaceID {
+			byID[b.ID] = b
+		}
+	}
+
+	blocks := make([]model.Block, 0, len(blockIDs))
+	found := make([]uuid.UUID, 0, len(blockIDs))
+	missing := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool, len(blockIDs))
+	for _, id := range blockIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if b, ok := byID[id]; ok {
+			blocks = append(blocks, b)
+			found = append(found, id)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	s.recordViews(ctx, spaceID, found...)
+	return blocks, missing, nil
+}
+
+// UpdateBlockProperties - unified update properties method. The props must satisfy the schema
+// of the block type, see model.BlockPropsSchemas.
+func (s *blockService) UpdateBlockProperties(ctx context.Context, spaceID uuid.UUID, b *model.Block) error {
+	existing, err := s.getInSpace(ctx, spaceID, b.ID)
+	if err != nil {
+		return err
+	}
+	if err := model.ValidateBlockProps(existing.Type, b.Props.Data()); err != nil {
+		return err
 	}
 	return s.r.Update(ctx, b)
 }
 
+// PatchBlockProperties - merge patch into existing props, keys set to null are removed. The
+// merged props must satisfy the schema of the block type.
+func (s *blockService) PatchBlockProperties(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, patch map[string]any) (*model.Block, error) {
+	if _, err := s.getInSpace(ctx, spaceID, blockID); err != nil {
+		return nil, err
+	}
+	return s.r.PatchProps(ctx, blockID, patch, func(b *model.Block) error {
+		return model.ValidateBlockProps(b.Type, b.Props.Data())
+	})
+}
+
+// BlockUpdate is the change of one block in a bulk update. Nil fields are left as they are;
+// Props replaces the whole props of the block.
+type BlockUpdate struct {
+	BlockID uuid.UUID
+	Title   *string
+	Props   map[string]any
+}
+
+// BulkUpdate applies the updates to blocks of a space in a single transaction, then merges
+// propsPatch into the props of each of them, removing keys set to null. The resulting props
+// must satisfy the schema of the block type. The blocks that could not be updated are
+// returned with their error; the others are updated.
+func (s *blockService) BulkUpdate(ctx context.Context, spaceID uuid.UUID, updates []BlockUpdate, propsPatch map[string]any) (map[uuid.UUID]error, error) {
+	byID := make(map[uuid.UUID]BlockUpdate, len(updates))
+	ids := make([]uuid.UUID, 0, len(updates))
+	for _, u := range updates {
+		if _, ok := byID[u.BlockID]; ok {
+			return nil, fmt.Errorf("%w: block %s is listed more than once", ErrInvalidBlockUpdate, u.BlockID)
+		}
+		byID[u.BlockID] = u
+		ids = append(ids, u.BlockID)
+	}
+	if len(ids) == 0 {
+		return map[uuid.UUID]error{}, nil
+	}
+
+	return s.r.UpdateMany(ctx, spaceID, ids, func(b *model.Block) error {
+		u := byID[b.ID]
+		if u.Title != nil {
+			b.Title = *u.Title
+		}
+		if u.Props != nil {
+			b.Props = datatypes.NewJSONType(u.Props)
+		}
+		if propsPatch != nil {
+			b.MergeProps(propsPatch)
+		}
+		return model.ValidateBlockProps(b.Type, b.Props.Data())
+	})
+}
+
 // List - unified list method with optional type and parent_id filters
 func (s *blockService) List(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	if len(spaceID) == 0 {
@@ -207,40 +354,284 @@ func (s *blockService) List(ctx context.Context, spaceID uuid.UUID, blockType st
 	return s.r.ListBySpace(ctx, spaceID, blockType, parentID)
 }
 
-// Move - unified move method for all block types
-func (s *blockService) Move(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
+// CountByType returns the number of blocks per type, with every known type present
+func (s *blockService) CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error) {
+	if len(spaceID) == 0 {
+		return nil, errors.New("space id is empty")
+	}
+	counts, err := s.r.CountByType(ctx, spaceID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for blockType := range model.BlockTypes {
+		if _, ok := counts[blockType]; !ok {
+			counts[blockType] = 0
+		}
+	}
+	return counts, nil
+}
+
+// ListRecentlyViewed returns up to limit blocks of a space by last view, latest first. Views
+// not flushed yet are taken into account.
+func (s *blockService) ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error) {
+	if len(spaceID) == 0 {
+		return nil, errors.New("space id is empty")
+	}
+	list, err := s.r.ListRecentlyViewed(ctx, spaceID, limit)
+	if err != nil || s.views == nil {
+		return list, err
+	}
+
+	pending, err := s.views.Pending(ctx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return list, nil
+	}
+
+	// Pending views of listed blocks move them forward; the other viewed blocks are loaded
+	listed := make(map[uuid.UUID]bool, len(list))
+	for i := range list {
+		listed[list[i].ID] = true
+		if at, ok := pending[list[i].ID]; ok && (list[i].LastViewedAt == nil || at.After(*list[i].LastViewedAt)) {
+			list[i].LastViewedAt = &at
+		}
+	}
+	var unlisted []uuid.UUID
+	for id := range pending {
+		if !listed[id] {
+			unlisted = append(unlisted, id)
+		}
+	}
+	if len(unlisted) > 0 {
+		more, err := s.r.GetMany(ctx, unlisted)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range more {
+			// A block moved to another space since its view is not listed here
+			if b.SpaceID != spaceID {
+				continue
+			}
+			if at := pending[b.ID]; b.LastViewedAt == nil || at.After(*b.LastViewedAt) {
+				b.LastViewedAt = &at
+			}
+			list = append(list, b)
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].LastViewedAt.After(*list[j].LastViewedAt) })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+// Move - unified move method for all block types. The new parent must be in the space of the block.
+func (s *blockService) Move(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, newParentID *uuid.UUID, targetSort *int64) error {
+	if _, err := s.getInSpace(ctx, spaceID, blockID); err != nil {
+		return err
+	}
+
 	block, parent, err := s.validateAndPrepareMove(ctx, blockID, newParentID)
 	if err != nil {
 		return err
 	}
+	if parent != nil && parent.SpaceID != spaceID {
+		return fmt.Errorf("%w: new parent %s is not in space %s", ErrInvalidBlockMove, parent.ID, spaceID)
+	}
+
+	height, err := s.subtreeHeight(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	added := 1
+	if isChildOf(block, parent) {
+		added = 0
+	}
+	if err := s.checkTreeLimits(ctx, parent, height, added); err != nil {
+		return err
+	}
 
 	// Special handling for folder type - update path
-	if block.Type == model.BlockTypeFolder {
-		path := block.Title
-		if parent != nil {
-			parentPath := parent.GetFolderPath()
-			if parentPath != "" {
-				path = parentPath + "/" + block.Title
+	if err := s.updateFolderPath(ctx, block, parent); err != nil {
+		return err
+	}
+
+	if targetSort == nil {
+		return s.r.MoveToParentAppend(ctx, blockID, newParentID)
+	}
+	return s.r.MoveToParentAtSort(ctx, blockID, newParentID, *targetSort)
+}
+
+// MoveBatch moves several blocks of a space to the tail of a new parent, in the given order.
+// Each block is validated like in Move before anything is changed.
+func (s *blockService) MoveBatch(ctx context.Context, spaceID uuid.UUID, blockIDs []uuid.UUID, newParentID *uuid.UUID) error {
+	if len(blockIDs) == 0 {
+		return fmt.Errorf("%w: no blocks to move", ErrInvalidBlockMove)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(blockIDs))
+	var folders []*model.Block
+	var parent *model.Block
+	var height, added int
+	for _, id := range blockIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: block %s is listed twice", ErrInvalidBlockMove, id)
+		}
+		seen[id] = true
+
+		block, p, err := s.validateAndPrepareMove(ctx, id, newParentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
 			}
+			return fmt.Errorf("%w: block %s: %v", ErrInvalidBlockMove, id, err)
+		}
+		if block.SpaceID != spaceID || (p != nil && p.SpaceID != spaceID) {
+			return fmt.Errorf("%w: block %s is not in space %s", ErrInvalidBlockMove, id, spaceID)
+		}
+		parent = p
+		if block.Type == model.BlockTypeFolder {
+			folders = append(folders, block)
 		}
-		block.SetFolderPath(path)
 
-		// Update the folder properties with the new path
-		if err := s.r.Update(ctx, block); err != nil {
+		h, err := s.subtreeHeight(ctx, id)
+		if err != nil {
 			return err
 		}
+		height = max(height, h)
+		if !isChildOf(block, p) {
+			added++
+		}
 	}
 
-	if targetSort == nil {
-		return s.r.MoveToParentAppend(ctx, blockID, newParentID)
+	if err := s.checkTreeLimits(ctx, parent, height, added); err != nil {
+		return err
 	}
-	return s.r.MoveToParentAtSort(ctx, blockID, newParentID, *targetSort)
+
+	// Folders keep their path in props, like in Move; it is saved with the move so a failed
+	// move leaves no path rewritten
+	folderPaths := make(map[uuid.UUID]string, len(folders))
+	for _, folder := range folders {
+		folderPaths[folder.ID] = folderPath(folder, parent)
+	}
+
+	return s.r.MoveBatch(ctx, blockIDs, newParentID, folderPaths)
+}
+
+// MoveToSpace moves a block of a space, with its subtree, to the tail of a new parent in another
+// space of the same project; a nil parent moves it to the root of that space. The repo returns
+// gorm.ErrRecordNotFound when the target space is in another project.
+func (s *blockService) MoveToSpace(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	block, err := s.getInSpace(ctx, spaceID, blockID)
+	if err != nil {
+		return err
+	}
+	if targetSpaceID == spaceID {
+		return fmt.Errorf("%w: block %s is already in space %s", ErrInvalidBlockMove, blockID, spaceID)
+	}
+
+	// The subtree stays in the old space until the move, so the new parent cannot be part of it
+	var parent *model.Block
+	if newParentID != nil {
+		parent, err = s.r.Get(ctx, *newParentID)
+		if err != nil {
+			return err
+		}
+		if parent.SpaceID != targetSpaceID {
+			return fmt.Errorf("%w: new parent %s is not in space %s", ErrInvalidBlockMove, parent.ID, targetSpaceID)
+		}
+	}
+	if err := block.ValidateParentType(parent); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlockMove, err)
+	}
+
+	height, err := s.subtreeHeight(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkTreeLimits(ctx, parent, height, 1); err != nil {
+		return err
+	}
+
+	// Folders keep their path in props, like in Move
+	if err := s.updateFolderPath(ctx, block, parent); err != nil {
+		return err
+	}
+	return s.r.MoveToSpace(ctx, blockID, targetSpaceID, newParentID)
+}
+
+// UndoLastMove moves a block back to where its latest recorded move took it from and returns
+// that move. Each undo walks one move further back, up to model.MaxBlockMoveHistory moves.
+// It returns gorm.ErrRecordNotFound when there is no move to undo.
+func (s *blockService) UndoLastMove(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.BlockMove, error) {
+	if _, err := s.getInSpace(ctx, spaceID, blockID); err != nil {
+		return nil, err
+	}
+
+	move, err := s.r.LastMove(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The old parent may have been deleted or changed since, so the move back is validated
+	// like any other move
+	block, parent, err := s.validateAndPrepareMove(ctx, blockID, move.OldParentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot undo move: %v", ErrInvalidBlockMove, err)
+	}
+
+	// The old parent may also have grown since, so the tree limits are checked again
+	height, err := s.subtreeHeight(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	added := 1
+	if isChildOf(block, parent) {
+		added = 0
+	}
+	if err := s.checkTreeLimits(ctx, parent, height, added); err != nil {
+		return nil, err
+	}
+
+	if err := s.updateFolderPath(ctx, block, parent); err != nil {
+		return nil, err
+	}
+
+	if err := s.r.UndoMove(ctx, move); err != nil {
+		return nil, err
+	}
+	return move, nil
+}
+
+// updateFolderPath sets the path of a folder moving under parent, nil for the root. Other
+// block types are left untouched.
+func (s *blockService) updateFolderPath(ctx context.Context, block *model.Block, parent *model.Block) error {
+	if block.Type != model.BlockTypeFolder {
+		return nil
+	}
+	block.SetFolderPath(folderPath(block, parent))
+
+	// Update the folder properties with the new path
+	return s.r.Update(ctx, block)
+}
+
+// folderPath is the path of a folder once moved under parent, nil for the root level
+func folderPath(folder *model.Block, parent *model.Block) string {
+	if parent != nil {
+		if parentPath := parent.GetFolderPath(); parentPath != "" {
+			return parentPath + "/" + folder.Title
+		}
+	}
+	return folder.Title
 }
 
 // UpdateSort - unified sort method for all block types
-func (s *blockService) UpdateSort(ctx context.Context, blockID uuid.UUID, sort int64) error {
-	if len(blockID) == 0 {
-		return errors.New("block id is empty")
+func (s *blockService) UpdateSort(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, sort int64) error {
+	if _, err := s.getInSpace(ctx, spaceID, blockID); err != nil {
+		return err
 	}
 	return s.r.ReorderWithinGroup(ctx, blockID, sort)
 }
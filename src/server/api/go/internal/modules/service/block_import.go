@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
+	"gorm.io/datatypes"
+)
+
+// maxImportBlocks bounds the size of a single import
+const maxImportBlocks = 1000
+
+var ErrInvalidBlockImport = errors.New("invalid block import")
+
+// BlockNode is one node of a block outline to import
+type BlockNode struct {
+	Type     string         `json:"type" example:"page"`
+	Title    string         `json:"title" example:"Getting started"`
+	Props    map[string]any `json:"props,omitempty"`
+	Children []BlockNode    `json:"children,omitempty"`
+}
+
+var markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+
+// ParseMarkdownOutline converts Markdown into a block outline. Headings become folders when
+// they contain sub-headings and pages otherwise; paragraphs become text blocks. Paragraphs
+// directly under a heading that also has sub-headings go into a page with the heading's title.
+func ParseMarkdownOutline(md string) ([]BlockNode, error) {
+	type heading struct {
+		level int
+		node  *BlockNode
+	}
+	root := &BlockNode{}
+	stack := []heading{{level: 0, node: root}}
+
+	var paragraph []string
+	inFence := false
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(paragraph, "\n"))
+		paragraph = paragraph[:0]
+		if text == "" {
+			return
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, BlockNode{
+			Type:  model.BlockTypeText,
+			Props: map[string]any{"text": text},
+		})
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		// Fenced code blocks are kept verbatim in a single text block
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			paragraph = append(paragraph, line)
+			continue
+		}
+		if inFence {
+			paragraph = append(paragraph, line)
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			level := len(m[1])
+			for stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, BlockNode{Title: m[2]})
+			stack = append(stack, heading{level: level, node: &parent.Children[len(parent.Children)-1]})
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	if inFence {
+		return nil, fmt.Errorf("%w: unterminated code fence", ErrInvalidBlockImport)
+	}
+	flush()
+
+	nodes := root.Children
+	for i := range nodes {
+		assignHeadingTypes(&nodes[i])
+	}
+	return nodes, nil
+}
+
+// assignHeadingTypes turns heading nodes into folders or pages depending on their children
+func assignHeadingTypes(n *BlockNode) {
+	if n.Type == model.BlockTypeText {
+		return
+	}
+
+	var texts, headings []BlockNode
+	for _, child := range n.Children {
+		if child.Type == model.BlockTypeText {
+			texts = append(texts, child)
+		} else {
+			headings = append(headings, child)
+		}
+	}
+
+	if len(headings) == 0 {
+		n.Type = model.BlockTypePage
+		return
+	}
+
+	n.Type = model.BlockTypeFolder
+	n.Children = headings
+	if len(texts) > 0 {
+		n.Children = append([]BlockNode{{Type: model.BlockTypePage, Title: n.Title, Children: texts}}, n.Children...)
+	}
+	for i := range n.Children {
+		assignHeadingTypes(&n.Children[i])
+	}
+}
+
+// ImportBlocks creates the block tree described by nodes under parentID (or at the space root)
+// in a single transaction and returns the created top-level blocks.
+func (s *blockService) ImportBlocks(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, nodes []BlockNode) ([]*model.Block, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: nothing to import", ErrInvalidBlockImport)
+	}
+
+	var target *model.Block
+	if parentID != nil {
+		var err error
+		target, err = s.r.Get(ctx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if target.SpaceID != spaceID {
+			return nil, fmt.Errorf("%w: parent block not found in space", ErrInvalidBlockImport)
+		}
+	}
+
+	var blocks, roots []*model.Block
+	height := 0
+	var build func(nodes []BlockNode, parent *model.Block, level int) error
+	build = func(nodes []BlockNode, parent *model.Block, level int) error {
+		height = max(height, level)
+		if s.limits.MaxChildren > 0 && parent != target && len(nodes) > s.limits.MaxChildren {
+			return fmt.Errorf("%w: %q would have %d children, more than the maximum of %d", ErrBlockTreeLimit, parent.Title, len(nodes), s.limits.MaxChildren)
+		}
+		for i, node := range nodes {
+			if len(blocks) >= maxImportBlocks {
+				return fmt.Errorf("%w: import exceeds %d blocks", ErrInvalidBlockImport, maxImportBlocks)
+			}
+			if _, filename := path.SplitFilePath(node.Title); filename != node.Title {
+				return fmt.Errorf("%w: title cannot contain path: %q", ErrInvalidBlockImport, node.Title)
+			}
+
+			props := node.Props
+			if props == nil {
+				props = map[string]any{}
+			}
+			b := &model.Block{
+				ID:      uuid.New(),
+				SpaceID: spaceID,
+				Type:    node.Type,
+				Title:   node.Title,
+				Props:   datatypes.NewJSONType(props),
+				Sort:    int64(i),
+			}
+			if parent != nil {
+				b.ParentID = &parent.ID
+			}
+
+			if err := b.Validate(); err != nil {
+				return fmt.Errorf("%w: %q: %v", ErrInvalidBlockImport, node.Title, err)
+			}
+			if err := b.ValidateParentType(parent); err != nil {
+				return fmt.Errorf("%w: %q: %v", ErrInvalidBlockImport, node.Title, err)
+			}
+			if len(node.Children) > 0 && !b.CanHaveChildren() {
+				return fmt.Errorf("%w: %q: block type '%s' cannot have children", ErrInvalidBlockImport, node.Title, b.Type)
+			}
+
+			if b.Type == model.BlockTypeFolder {
+				folderPath := b.Title
+				if parent != nil && parent.GetFolderPath() != "" {
+					folderPath = parent.GetFolderPath() + "/" + b.Title
+				}
+				b.SetFolderPath(folderPath)
+			}
+
+			blocks = append(blocks, b)
+			if parent == target {
+				roots = append(roots, b)
+			}
+			if err := build(node.Children, b, level+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := build(nodes, target, 1); err != nil {
+		return nil, err
+	}
+	if err := s.checkTreeLimits(ctx, target, height, len(nodes)); err != nil {
+		return nil, err
+	}
+
+	if err := s.r.CreateTree(ctx, spaceID, parentID, blocks); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
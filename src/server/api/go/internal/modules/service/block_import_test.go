@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestParseMarkdownOutline(t *testing.T) {
+	t.Run("headings and paragraphs", func(t *testing.T) {
+		nodes, err := ParseMarkdownOutline("# Guide\n\nWelcome to the guide.\n\n## Setup\n\nInstall the SDK.\nThen configure it.\n\n## Usage ##\n\n```\n# not a heading\n\nstill code\n```\n\n# FAQ\n")
+		assert.NoError(t, err)
+		assert.Len(t, nodes, 2)
+
+		guide := nodes[0]
+		assert.Equal(t, model.BlockTypeFolder, guide.Type)
+		assert.Equal(t, "Guide", guide.Title)
+		assert.Len(t, guide.Children, 3)
+
+		// Intro paragraphs of a folder heading go into a page of the same title
+		assert.Equal(t, model.BlockTypePage, guide.Children[0].Type)
+		assert.Equal(t, "Guide", guide.Children[0].Title)
+		assert.Equal(t, "Welcome to the guide.", guide.Children[0].Children[0].Props["text"])
+
+		setup := guide.Children[1]
+		assert.Equal(t, model.BlockTypePage, setup.Type)
+		assert.Equal(t, "Setup", setup.Title)
+		assert.Len(t, setup.Children, 1)
+		assert.Equal(t, model.BlockTypeText, setup.Children[0].Type)
+		assert.Equal(t, "Install the SDK.\nThen configure it.", setup.Children[0].Props["text"])
+
+		usage := guide.Children[2]
+		assert.Equal(t, "Usage", usage.Title)
+		assert.Len(t, usage.Children, 1)
+		assert.Equal(t, "```\n# not a heading\n\nstill code\n```", usage.Children[0].Props["text"])
+
+		assert.Equal(t, model.BlockTypePage, nodes[1].Type)
+		assert.Equal(t, "FAQ", nodes[1].Title)
+		assert.Empty(t, nodes[1].Children)
+	})
+
+	t.Run("paragraphs only", func(t *testing.T) {
+		nodes, err := ParseMarkdownOutline("first\n\nsecond")
+		assert.NoError(t, err)
+		assert.Len(t, nodes, 2)
+		assert.Equal(t, model.BlockTypeText, nodes[0].Type)
+		assert.Equal(t, "second", nodes[1].Props["text"])
+	})
+
+	t.Run("unterminated code fence", func(t *testing.T) {
+		_, err := ParseMarkdownOutline("# A\n\n```\ncode")
+		assert.ErrorIs(t, err, ErrInvalidBlockImport)
+	})
+}
+
+func TestBlockService_ImportBlocks(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	pageID := uuid.New()
+	page := &model.Block{ID: pageID, SpaceID: spaceID, Type: model.BlockTypePage}
+
+	outline := []BlockNode{
+		{
+			Type:  model.BlockTypeFolder,
+			Title: "Guide",
+			Children: []BlockNode{
+				{Type: model.BlockTypeFolder, Title: "Setup", Children: []BlockNode{
+					{Type: model.BlockTypePage, Title: "Install", Children: []BlockNode{
+						{Type: model.BlockTypeText, Props: map[string]any{"text": "pip install acontext"}},
+						{Type: model.BlockTypeText, Props: map[string]any{"text": "npm i @acontext/acontext"}},
+					}},
+				}},
+			},
+		},
+		{Type: model.BlockTypePage, Title: "FAQ"},
+	}
+
+	t.Run("creates the tree in one call", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		var created []*model.Block
+		repo.On("CreateTree", ctx, spaceID, (*uuid.UUID)(nil), mock.Anything).
+			Run(func(args mock.Arguments) { created = args.Get(3).([]*model.Block) }).
+			Return(nil)
+
+		roots, err := NewBlockService(repo, nil, BlockTreeLimits{}).ImportBlocks(ctx, spaceID, nil, outline)
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+
+		assert.Len(t, roots, 2)
+		assert.Equal(t, "Guide", roots[0].Title)
+		assert.Equal(t, "FAQ", roots[1].Title)
+		assert.Equal(t, int64(0), roots[0].Sort)
+		assert.Equal(t, int64(1), roots[1].Sort)
+
+		// Parents come before their children
+		assert.Len(t, created, 6)
+		index := map[uuid.UUID]int{}
+		for i, b := range created {
+			index[b.ID] = i
+			if b.ParentID != nil {
+				assert.Less(t, index[*b.ParentID], i)
+			}
+		}
+
+		setup, install := created[1], created[2]
+		assert.Equal(t, "Guide/Setup", setup.GetFolderPath())
+		assert.Equal(t, setup.ID, *install.ParentID)
+		assert.Equal(t, int64(0), created[3].Sort)
+		assert.Equal(t, int64(1), created[4].Sort)
+	})
+
+	t.Run("text blocks under an existing page", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, pageID).Return(page, nil)
+		repo.On("CreateTree", ctx, spaceID, &pageID, mock.Anything).Return(nil)
+
+		roots, err := NewBlockService(repo, nil, BlockTreeLimits{}).ImportBlocks(ctx, spaceID, &pageID, []BlockNode{
+			{Type: model.BlockTypeText, Props: map[string]any{"text": "hello"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, pageID, *roots[0].ParentID)
+		repo.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name     string
+		parentID *uuid.UUID
+		nodes    []BlockNode
+		setup    func(*MockBlockRepo)
+		wantErr  error
+	}{
+		{
+			name:    "title with path separator",
+			nodes:   []BlockNode{{Type: model.BlockTypePage, Title: "a/b"}},
+			setup:   func(r *MockBlockRepo) {},
+			wantErr: ErrInvalidBlockImport,
+		},
+		{
+			name:    "text at root level",
+			nodes:   []BlockNode{{Type: model.BlockTypeText, Props: map[string]any{"text": "orphan"}}},
+			setup:   func(r *MockBlockRepo) {},
+			wantErr: ErrInvalidBlockImport,
+		},
+		{
+			name: "page inside page",
+			nodes: []BlockNode{{Type: model.BlockTypePage, Title: "Outer", Children: []BlockNode{
+				{Type: model.BlockTypePage, Title: "Inner"},
+			}}},
+			setup:   func(r *MockBlockRepo) {},
+			wantErr: ErrInvalidBlockImport,
+		},
+		{
+			name:    "nothing to import",
+			setup:   func(r *MockBlockRepo) {},
+			wantErr: ErrInvalidBlockImport,
+		},
+		{
+			name:     "parent in another space",
+			parentID: &pageID,
+			nodes:    []BlockNode{{Type: model.BlockTypeText}},
+			setup: func(r *MockBlockRepo) {
+				r.On("Get", ctx, pageID).Return(&model.Block{ID: pageID, SpaceID: uuid.New(), Type: model.BlockTypePage}, nil)
+			},
+			wantErr: ErrInvalidBlockImport,
+		},
+		{
+			name:     "parent not found",
+			parentID: &pageID,
+			nodes:    []BlockNode{{Type: model.BlockTypeText}},
+			setup: func(r *MockBlockRepo) {
+				r.On("Get", ctx, pageID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			tt.setup(repo)
+
+			_, err := NewBlockService(repo, nil, BlockTreeLimits{}).ImportBlocks(ctx, spaceID, tt.parentID, tt.nodes)
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+			repo.AssertNotCalled(t, "CreateTree", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			repo.AssertExpectations(t)
+		})
+	}
+}
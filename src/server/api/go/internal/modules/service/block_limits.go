@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+var ErrBlockTreeLimit = errors.New("block tree limit exceeded")
+
+// BlockTreeLimits bounds the shape of block trees, so the recursive operations on them stay
+// cheap. A zero limit is disabled. The limits are checked before a write without locking the
+// tree, so concurrent writes may overshoot them slightly.
+type BlockTreeLimits struct {
+	// MaxDepth is the deepest level a block may sit at, root blocks being at level 1
+	MaxDepth int
+	// MaxChildren is the largest number of direct children of a block
+	MaxChildren int
+}
+
+// CheckTreeLimits checks that one more block can be created under parentID, nil for the root
+// of the space
+func (s *blockService) CheckTreeLimits(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	var parent *model.Block
+	if parentID != nil {
+		var err error
+		if parent, err = s.getInSpace(ctx, spaceID, *parentID); err != nil {
+			return err
+		}
+	}
+	return s.checkTreeLimits(ctx, parent, 1, 1)
+}
+
+// checkTreeLimits checks that adding subtrees under parent, nil for the root, keeps the tree
+// within the limits. height is the number of levels of the tallest subtree and added the
+// number of subtrees that become new children of parent.
+func (s *blockService) checkTreeLimits(ctx context.Context, parent *model.Block, height int, added int) error {
+	if s.limits.MaxDepth > 0 {
+		depth := 0
+		if parent != nil {
+			var err error
+			if depth, err = s.r.Depth(ctx, parent.ID); err != nil {
+				return err
+			}
+		}
+		if depth+height > s.limits.MaxDepth {
+			return fmt.Errorf("%w: blocks would be nested %d levels deep, more than the maximum of %d", ErrBlockTreeLimit, depth+height, s.limits.MaxDepth)
+		}
+	}
+
+	if s.limits.MaxChildren > 0 && parent != nil && added > 0 {
+		counts, err := s.r.CountByType(ctx, parent.SpaceID, &parent.ID)
+		if err != nil {
+			return err
+		}
+		var children int64
+		for _, n := range counts {
+			children += n
+		}
+		if children+int64(added) > int64(s.limits.MaxChildren) {
+			return fmt.Errorf("%w: block %s would have %d children, more than the maximum of %d", ErrBlockTreeLimit, parent.ID, children+int64(added), s.limits.MaxChildren)
+		}
+	}
+	return nil
+}
+
+// subtreeHeight returns the number of levels of the subtree of a block. The tree is only
+// walked when the depth is limited.
+func (s *blockService) subtreeHeight(ctx context.Context, id uuid.UUID) (int, error) {
+	if s.limits.MaxDepth <= 0 {
+		return 1, nil
+	}
+	return s.r.SubtreeHeight(ctx, id)
+}
+
+// isChildOf reports whether the block is already a direct child of parent, nil for the root
+func isChildOf(b *model.Block, parent *model.Block) bool {
+	if parent == nil {
+		return b.ParentID == nil
+	}
+	return b.ParentID != nil && *b.ParentID == parent.ID
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBlockService_TreeLimits(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	limits := BlockTreeLimits{MaxDepth: 3, MaxChildren: 2}
+
+	// Root folder > folder > inner page, so inner is at the maximum depth
+	root := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Root"}
+	folder := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Folder", ParentID: &root.ID}
+	inner := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Inner", ParentID: &folder.ID}
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Page"}
+	other := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Other"}
+
+	setup := func() *MockBlockRepo {
+		repo := &MockBlockRepo{}
+		for _, b := range []*model.Block{root, folder, inner, page, other} {
+			repo.On("Get", ctx, b.ID).Return(b, nil)
+		}
+		repo.On("Depth", ctx, root.ID).Return(1, nil)
+		repo.On("Depth", ctx, folder.ID).Return(2, nil)
+		repo.On("Depth", ctx, inner.ID).Return(3, nil)
+		return repo
+	}
+
+	t.Run("create beyond the maximum depth", func(t *testing.T) {
+		repo := setup()
+		text := &model.Block{SpaceID: spaceID, Type: model.BlockTypeText, Title: "Too deep", ParentID: &inner.ID}
+
+		err := NewBlockService(repo, nil, limits).Create(ctx, text)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		assert.ErrorContains(t, err, "nested 4 levels deep, more than the maximum of 3")
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("create under a parent with the maximum children", func(t *testing.T) {
+		repo := setup()
+		repo.On("CountByType", ctx, spaceID, &root.ID).Return(map[string]int64{model.BlockTypeFolder: 1, model.BlockTypePage: 1}, nil)
+
+		err := NewBlockService(repo, nil, limits).CheckTreeLimits(ctx, spaceID, &root.ID)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		assert.ErrorContains(t, err, "would have 3 children, more than the maximum of 2")
+	})
+
+	t.Run("move a subtree too deep", func(t *testing.T) {
+		repo := setup()
+		// Page has children of its own: at level 3 its subtree would reach level 4
+		repo.On("SubtreeHeight", ctx, page.ID).Return(2, nil)
+		repo.On("CountByType", ctx, spaceID, &folder.ID).Return(map[string]int64{model.BlockTypePage: 1}, nil)
+
+		err := NewBlockService(repo, nil, limits).Move(ctx, spaceID, page.ID, &folder.ID, nil)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		repo.AssertNotCalled(t, "MoveToParentAppend", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("move a batch into a parent short of room", func(t *testing.T) {
+		repo := setup()
+		repo.On("SubtreeHeight", ctx, page.ID).Return(1, nil)
+		repo.On("SubtreeHeight", ctx, other.ID).Return(1, nil)
+		repo.On("CountByType", ctx, spaceID, &folder.ID).Return(map[string]int64{model.BlockTypePage: 1}, nil)
+
+		err := NewBlockService(repo, nil, limits).MoveBatch(ctx, spaceID, []uuid.UUID{page.ID, other.ID}, &folder.ID)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		assert.ErrorContains(t, err, "would have 3 children, more than the maximum of 2")
+		repo.AssertNotCalled(t, "MoveBatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reordering within a full parent is allowed", func(t *testing.T) {
+		repo := setup()
+		repo.On("SubtreeHeight", ctx, inner.ID).Return(1, nil)
+		repo.On("MoveToParentAtSort", ctx, inner.ID, &folder.ID, int64(0)).Return(nil)
+		sort := int64(0)
+
+		assert.NoError(t, NewBlockService(repo, nil, limits).Move(ctx, spaceID, inner.ID, &folder.ID, &sort))
+		repo.AssertNotCalled(t, "CountByType", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("undo a move into a parent that has filled up", func(t *testing.T) {
+		repo := setup()
+		move := &model.BlockMove{BlockID: page.ID, OldParentID: &root.ID}
+		repo.On("LastMove", ctx, page.ID).Return(move, nil)
+		repo.On("SubtreeHeight", ctx, page.ID).Return(1, nil)
+		repo.On("CountByType", ctx, spaceID, &root.ID).Return(map[string]int64{model.BlockTypeFolder: 1, model.BlockTypePage: 1}, nil)
+
+		_, err := NewBlockService(repo, nil, limits).UndoLastMove(ctx, spaceID, page.ID)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		assert.ErrorContains(t, err, "would have 3 children, more than the maximum of 2")
+		repo.AssertNotCalled(t, "UndoMove", mock.Anything, mock.Anything)
+	})
+
+	t.Run("import a tree too wide", func(t *testing.T) {
+		repo := setup()
+		nodes := []BlockNode{{Type: model.BlockTypePage, Title: "Guide", Children: []BlockNode{
+			{Type: model.BlockTypeText, Title: "One"},
+			{Type: model.BlockTypeText, Title: "Two"},
+			{Type: model.BlockTypeText, Title: "Three"},
+		}}}
+
+		_, err := NewBlockService(repo, nil, limits).ImportBlocks(ctx, spaceID, nil, nodes)
+		assert.ErrorIs(t, err, ErrBlockTreeLimit)
+		assert.ErrorContains(t, err, `"Guide" would have 3 children`)
+		repo.AssertNotCalled(t, "CreateTree", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disabled limits skip the tree queries", func(t *testing.T) {
+		repo := setup()
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		text := &model.Block{SpaceID: spaceID, Type: model.BlockTypeText, Title: "Deep", ParentID: &inner.ID}
+
+		assert.NoError(t, NewBlockService(repo, nil, BlockTreeLimits{}).Create(ctx, text))
+		repo.AssertNotCalled(t, "Depth", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "CountByType", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/gorm"
+)
+
+// maxBlockArtifactRefs bounds the references resolved for one block; the rest are reported broken
+const maxBlockArtifactRefs = 100
+
+type BlockReferenceService interface {
+	ResolveBlockReferences(ctx context.Context, in ResolveBlockReferencesInput) (*ResolvedBlock, error)
+}
+
+type blockReferenceService struct {
+	blockRepo    repo.BlockRepo
+	diskRepo     repo.DiskRepo
+	artifactRepo repo.ArtifactRepo
+	artifacts    ArtifactService
+}
+
+func NewBlockReferenceService(blockRepo repo.BlockRepo, diskRepo repo.DiskRepo, artifactRepo repo.ArtifactRepo, artifacts ArtifactService) BlockReferenceService {
+	return &blockReferenceService{
+		blockRepo:    blockRepo,
+		diskRepo:     diskRepo,
+		artifactRepo: artifactRepo,
+		artifacts:    artifacts,
+	}
+}
+
+type ResolveBlockReferencesInput struct {
+	ProjectID uuid.UUID
+	SpaceID   uuid.UUID
+	BlockID   uuid.UUID
+	Expire    time.Duration
+}
+
+// ResolvedBlock is a block with the artifacts its props reference, see model.ArtifactRefKey
+type ResolvedBlock struct {
+	Block     *model.Block        `json:"block"`
+	Artifacts []ResolvedArtifact  `json:"artifacts"`
+	Broken    []BrokenArtifactRef `json:"broken"`
+}
+
+type ResolvedArtifact struct {
+	model.ArtifactRef
+	Artifact  *model.Artifact `json:"artifact"`
+	PublicURL string          `json:"public_url"`
+}
+
+// BrokenArtifactRef is a reference whose artifact could not be returned
+type BrokenArtifactRef struct {
+	model.ArtifactRef
+	Reason string `json:"reason"`
+}
+
+const (
+	brokenRefNotFound = "artifact not found"
+	brokenRefPresign  = "failed to presign artifact"
+	brokenRefTooMany  = "too many references"
+)
+
+// ResolveBlockReferences returns a block of the space with the artifacts referenced in its
+// props and presigned URLs to download them. References to missing artifacts, or to disks
+// of another project, are reported as broken instead of failing the call. Malformed
+// references are skipped.
+func (s *blockReferenceService) ResolveBlockReferences(ctx context.Context, in ResolveBlockReferencesInput) (*ResolvedBlock, error) {
+	b, err := s.blockRepo.Get(ctx, in.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SpaceID != in.SpaceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	refs, _ := model.ParseArtifactRefs(b.Props.Data())
+	out := &ResolvedBlock{Block: b, Artifacts: []ResolvedArtifact{}, Broken: []BrokenArtifactRef{}}
+	if len(refs) > maxBlockArtifactRefs {
+		for _, ref := range refs[maxBlockArtifactRefs:] {
+			out.Broken = append(out.Broken, BrokenArtifactRef{ArtifactRef: ref, Reason: brokenRefTooMany})
+		}
+		refs = refs[:maxBlockArtifactRefs]
+	}
+
+	// Look the artifacts up disk by disk, keeping the order of the references
+	byDisk := map[uuid.UUID][]model.ArtifactPath{}
+	var diskIDs []uuid.UUID
+	for _, ref := range refs {
+		if _, ok := byDisk[ref.DiskID]; !ok {
+			diskIDs = append(diskIDs, ref.DiskID)
+		}
+		byDisk[ref.DiskID] = append(byDisk[ref.DiskID], ref.Split())
+	}
+
+	found := map[model.ArtifactRef]*model.Artifact{}
+	for _, diskID := range diskIDs {
+		disk, err := s.diskRepo.GetByID(ctx, diskID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && disk.ProjectID != in.ProjectID) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		artifacts, err := s.artifactRepo.GetByPaths(ctx, diskID, byDisk[diskID])
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range artifacts {
+			key := model.ArtifactPath{Path: a.Path, Filename: a.Filename}.Key()
+			found[model.ArtifactRef{DiskID: diskID, Path: key}] = a
+		}
+	}
+
+	for _, ref := range refs {
+		a, ok := found[ref]
+		if !ok {
+			out.Broken = append(out.Broken, BrokenArtifactRef{ArtifactRef: ref, Reason: brokenRefNotFound})
+			continue
+		}
+		url, err := s.artifacts.GetPresignedURL(ctx, in.ProjectID, a, in.Expire)
+		if err != nil {
+			out.Broken = append(out.Broken, BrokenArtifactRef{ArtifactRef: ref, Reason: brokenRefPresign})
+			continue
+		}
+		out.Artifacts = append(out.Artifacts, ResolvedArtifact{ArtifactRef: ref, Artifact: a, PublicURL: url})
+	}
+	return out, nil
+}
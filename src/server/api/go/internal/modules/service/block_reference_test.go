@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// presignOnlyArtifactService presigns artifacts as "https://s3/<key>", failing for failKey
+type presignOnlyArtifactService struct {
+	ArtifactService
+	failKey string
+}
+
+func (s presignOnlyArtifactService) GetPresignedURL(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, expire time.Duration) (string, error) {
+	key := artifact.AssetMeta.Data().S3Key
+	if key == s.failKey {
+		return "", errors.New("presign failed")
+	}
+	return "https://s3/" + key, nil
+}
+
+func TestBlockReferenceService_ResolveBlockReferences(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	spaceID := uuid.New()
+	diskID := uuid.New()
+	foreignDiskID := uuid.New()
+	missingDiskID := uuid.New()
+
+	ref := func(diskID uuid.UUID, path string) map[string]any {
+		return map[string]any{model.ArtifactRefKey: map[string]any{"disk_id": diskID.String(), "path": path}}
+	}
+	block := &model.Block{
+		ID:      uuid.New(),
+		SpaceID: spaceID,
+		Type:    model.BlockTypeText,
+		Props: datatypes.NewJSONType(map[string]any{
+			"files": []any{
+				ref(diskID, "/docs/report.pdf"),
+				ref(diskID, "/docs/missing.pdf"),
+				ref(diskID, "/docs/locked.pdf"),
+				ref(foreignDiskID, "/docs/secret.pdf"),
+				ref(missingDiskID, "/docs/gone.pdf"),
+				map[string]any{model.ArtifactRefKey: "malformed"},
+			},
+		}),
+	}
+	newArtifact := func(filename string) *model.Artifact {
+		return &model.Artifact{
+			ID:        uuid.New(),
+			DiskID:    diskID,
+			Path:      "/docs/",
+			Filename:  filename,
+			AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/" + filename}),
+		}
+	}
+	report := newArtifact("report.pdf")
+	locked := newArtifact("locked.pdf")
+
+	blockRepo := &MockBlockRepo{}
+	blockRepo.On("Get", ctx, block.ID).Return(block, nil)
+	diskRepo := &MockDiskRepo{}
+	diskRepo.On("GetByID", ctx, diskID).Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
+	diskRepo.On("GetByID", ctx, foreignDiskID).Return(&model.Disk{ID: foreignDiskID, ProjectID: uuid.New()}, nil)
+	diskRepo.On("GetByID", ctx, missingDiskID).Return(nil, gorm.ErrRecordNotFound)
+	artifactRepo := &MockArtifactRepo{}
+	artifactRepo.On("GetByPaths", ctx, diskID, []model.ArtifactPath{
+		{Path: "/docs/", Filename: "report.pdf"},
+		{Path: "/docs/", Filename: "missing.pdf"},
+		{Path: "/docs/", Filename: "locked.pdf"},
+	}).Return([]*model.Artifact{report, locked}, nil)
+
+	svc := NewBlockReferenceService(blockRepo, diskRepo, artifactRepo, presignOnlyArtifactService{failKey: "disks/locked.pdf"})
+
+	t.Run("resolves references", func(t *testing.T) {
+		resolved, err := svc.ResolveBlockReferences(ctx, ResolveBlockReferencesInput{ProjectID: projectID, SpaceID: spaceID, BlockID: block.ID, Expire: time.Hour})
+		assert.NoError(t, err)
+		assert.Equal(t, block, resolved.Block)
+		assert.Equal(t, []ResolvedArtifact{{
+			ArtifactRef: model.ArtifactRef{DiskID: diskID, Path: "/docs/report.pdf"},
+			Artifact:    report,
+			PublicURL:   "https://s3/disks/report.pdf",
+		}}, resolved.Artifacts)
+		assert.Equal(t, []BrokenArtifactRef{
+			{ArtifactRef: model.ArtifactRef{DiskID: diskID, Path: "/docs/missing.pdf"}, Reason: brokenRefNotFound},
+			{ArtifactRef: model.ArtifactRef{DiskID: diskID, Path: "/docs/locked.pdf"}, Reason: brokenRefPresign},
+			{ArtifactRef: model.ArtifactRef{DiskID: foreignDiskID, Path: "/docs/secret.pdf"}, Reason: brokenRefNotFound},
+			{ArtifactRef: model.ArtifactRef{DiskID: missingDiskID, Path: "/docs/gone.pdf"}, Reason: brokenRefNotFound},
+		}, resolved.Broken)
+		// Artifacts of another project's disk are never looked up
+		artifactRepo.AssertNotCalled(t, "GetByPaths", ctx, foreignDiskID, mock.Anything)
+	})
+
+	t.Run("block of another space", func(t *testing.T) {
+		_, err := svc.ResolveBlockReferences(ctx, ResolveBlockReferencesInput{ProjectID: projectID, SpaceID: uuid.New(), BlockID: block.ID})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockBlockRepo is a mock implementation of BlockRepo
@@ -29,11 +32,34 @@ func (m *MockBlockRepo) Get(ctx context.Context, id uuid.UUID) (*model.Block, er
 	return args.Get(0).(*model.Block), args.Error(1)
 }
 
+func (m *MockBlockRepo) GetMany(ctx context.Context, blockIDs []uuid.UUID) ([]model.Block, error) {
+	args := m.Called(ctx, blockIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
 func (m *MockBlockRepo) Update(ctx context.Context, b *model.Block) error {
 	args := m.Called(ctx, b)
 	return args.Error(0)
 }
 
+func (m *MockBlockRepo) PatchProps(ctx context.Context, id uuid.UUID, patch map[string]any, validate func(b *model.Block) error) (*model.Block, error) {
+	args := m.Called(ctx, id, patch, validate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	b := args.Get(0).(*model.Block)
+	// Like the repo, a block failing validation is not saved
+	if validate != nil {
+		if err := validate(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, args.Error(1)
+}
+
 func (m *MockBlockRepo) Delete(ctx context.Context, spaceID, blockID uuid.UUID) error {
 	args := m.Called(ctx, spaceID, blockID)
 	return args.Error(0)
@@ -44,11 +70,78 @@ func (m *MockBlockRepo) NextSort(ctx context.Context, spaceID uuid.UUID, parentI
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockBlockRepo) CountByType(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) (map[string]int64, error) {
+	args := m.Called(ctx, spaceID, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
 func (m *MockBlockRepo) MoveToParentAppend(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID) error {
 	args := m.Called(ctx, blockID, newParentID)
 	return args.Error(0)
 }
 
+func (m *MockBlockRepo) MoveBatch(ctx context.Context, blockIDs []uuid.UUID, newParentID *uuid.UUID, folderPaths map[uuid.UUID]string) error {
+	args := m.Called(ctx, blockIDs, newParentID, folderPaths)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) MoveToSpace(ctx context.Context, blockID uuid.UUID, targetSpaceID uuid.UUID, newParentID *uuid.UUID) error {
+	args := m.Called(ctx, blockID, targetSpaceID, newParentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) LastMove(ctx context.Context, blockID uuid.UUID) (*model.BlockMove, error) {
+	args := m.Called(ctx, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BlockMove), args.Error(1)
+}
+
+func (m *MockBlockRepo) UndoMove(ctx context.Context, move *model.BlockMove) error {
+	args := m.Called(ctx, move)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) RebalanceSorts(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID) error {
+	args := m.Called(ctx, spaceID, parentID)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) Depth(ctx context.Context, id uuid.UUID) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockRepo) SubtreeHeight(ctx context.Context, id uuid.UUID) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlockRepo) UpdateMany(ctx context.Context, spaceID uuid.UUID, ids []uuid.UUID, apply func(b *model.Block) error) (map[uuid.UUID]error, error) {
+	args := m.Called(ctx, spaceID, ids, apply)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]error), args.Error(1)
+}
+
+func (m *MockBlockRepo) SetLastViewed(ctx context.Context, views map[uuid.UUID]time.Time) error {
+	args := m.Called(ctx, views)
+	return args.Error(0)
+}
+
+func (m *MockBlockRepo) ListRecentlyViewed(ctx context.Context, spaceID uuid.UUID, limit int) ([]model.Block, error) {
+	args := m.Called(ctx, spaceID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Block), args.Error(1)
+}
+
 func (m *MockBlockRepo) MoveToParentAtSort(ctx context.Context, blockID uuid.UUID, newParentID *uuid.UUID, sort int64) error {
 	args := m.Called(ctx, blockID, newParentID, sort)
 	return args.Error(0)
@@ -59,6 +152,11 @@ func (m *MockBlockRepo) ReorderWithinGroup(ctx context.Context, blockID uuid.UUI
 	return args.Error(0)
 }
 
+func (m *MockBlockRepo) CreateTree(ctx context.Context, spaceID uuid.UUID, parentID *uuid.UUID, blocks []*model.Block) error {
+	args := m.Called(ctx, spaceID, parentID, blocks)
+	return args.Error(0)
+}
+
 func (m *MockBlockRepo) ListBySpace(ctx context.Context, spaceID uuid.UUID, blockType string, parentID *uuid.UUID) ([]model.Block, error) {
 	args := m.Called(ctx, spaceID, blockType, parentID)
 	if args.Get(0) == nil {
@@ -87,9 +185,8 @@ func TestBlockService_Create_Page(t *testing.T) {
 				Title:   "Test Page",
 			},
 			setup: func(repo *MockBlockRepo) {
-				repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypePage && b.Sort == 1
+					return b.Type == model.BlockTypePage
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -108,9 +205,8 @@ func TestBlockService_Create_Page(t *testing.T) {
 					Type: model.BlockTypeFolder,
 				}
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(2), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypePage && b.Sort == 2
+					return b.Type == model.BlockTypePage
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -158,7 +254,7 @@ func TestBlockService_Create_Page(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			err := service.Create(ctx, tt.block)
 
 			if tt.wantErr {
@@ -220,7 +316,7 @@ func TestBlockService_Delete(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			err := service.Delete(ctx, spaceID, tt.blockID)
 
 			if tt.wantErr {
@@ -263,9 +359,8 @@ func TestBlockService_Create_Text(t *testing.T) {
 					Type: model.BlockTypePage,
 				}
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(1), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == "text" && b.Sort == 1
+					return b.Type == "text"
 				})).Return(nil)
 			},
 			wantErr: false,
@@ -360,7 +455,7 @@ func TestBlockService_Create_Text(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			err := service.Create(ctx, tt.block)
 
 			if tt.wantErr {
@@ -398,9 +493,8 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				Title:   "RootFolder",
 			},
 			setup: func(repo *MockBlockRepo) {
-				repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypeFolder && b.Sort == 1 && b.GetFolderPath() == "RootFolder"
+					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "RootFolder"
 				})).Return(nil)
 			},
 			wantErr:      false,
@@ -421,9 +515,8 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				}
 				parentBlock.SetFolderPath("RootFolder")
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(2), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
-					return b.Type == model.BlockTypeFolder && b.Sort == 2 && b.GetFolderPath() == "RootFolder/Subfolder"
+					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "RootFolder/Subfolder"
 				})).Return(nil)
 			},
 			wantErr:      false,
@@ -444,7 +537,6 @@ func TestBlockService_Create_Folder(t *testing.T) {
 				}
 				parentBlock.SetFolderPath("Folder1/Folder2/Folder3")
 				repo.On("Get", ctx, parentID).Return(parentBlock, nil)
-				repo.On("NextSort", ctx, spaceID, &parentID).Return(int64(1), nil)
 				repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
 					return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "Folder1/Folder2/Folder3/DeepFolder"
 				})).Return(nil)
@@ -495,7 +587,7 @@ func TestBlockService_Create_Folder(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			err := service.Create(ctx, tt.block)
 
 			if tt.wantErr {
@@ -518,6 +610,7 @@ func TestBlockService_Create_Folder(t *testing.T) {
 
 func TestBlockService_Move_Folder(t *testing.T) {
 	ctx := context.Background()
+	spaceID := uuid.New()
 	folderID := uuid.New()
 	newParentID := uuid.New()
 
@@ -538,9 +631,10 @@ func TestBlockService_Move_Folder(t *testing.T) {
 			targetSort:  nil,
 			setup: func(repo *MockBlockRepo) {
 				folder := &model.Block{
-					ID:    folderID,
-					Type:  model.BlockTypeFolder,
-					Title: "MovedFolder",
+					ID:      folderID,
+					SpaceID: spaceID,
+					Type:    model.BlockTypeFolder,
+					Title:   "MovedFolder",
 				}
 				folder.SetFolderPath("OldParent/MovedFolder")
 				repo.On("Get", ctx, folderID).Return(folder, nil)
@@ -559,13 +653,15 @@ func TestBlockService_Move_Folder(t *testing.T) {
 			targetSort:  nil,
 			setup: func(repo *MockBlockRepo) {
 				folder := &model.Block{
-					ID:    folderID,
-					Type:  model.BlockTypeFolder,
-					Title: "MovedFolder",
+					ID:      folderID,
+					SpaceID: spaceID,
+					Type:    model.BlockTypeFolder,
+					Title:   "MovedFolder",
 				}
 				newParent := &model.Block{
-					ID:   newParentID,
-					Type: model.BlockTypeFolder,
+					ID:      newParentID,
+					SpaceID: spaceID,
+					Type:    model.BlockTypeFolder,
 				}
 				newParent.SetFolderPath("NewParent")
 				repo.On("Get", ctx, folderID).Return(folder, nil)
@@ -585,13 +681,15 @@ func TestBlockService_Move_Folder(t *testing.T) {
 			targetSort:  nil,
 			setup: func(repo *MockBlockRepo) {
 				folder := &model.Block{
-					ID:    folderID,
-					Type:  model.BlockTypeFolder,
-					Title: "MovedFolder",
+					ID:      folderID,
+					SpaceID: spaceID,
+					Type:    model.BlockTypeFolder,
+					Title:   "MovedFolder",
 				}
 				invalidParent := &model.Block{
-					ID:   newParentID,
-					Type: model.BlockTypePage, // pages cannot be folder parents
+					ID:      newParentID,
+					SpaceID: spaceID,
+					Type:    model.BlockTypePage, // pages cannot be folder parents
 				}
 				repo.On("Get", ctx, folderID).Return(folder, nil)
 				repo.On("Get", ctx, newParentID).Return(invalidParent, nil)
@@ -606,8 +704,8 @@ func TestBlockService_Move_Folder(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
-			err := service.Move(ctx, tt.folderID, tt.newParentID, tt.targetSort)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
+			err := service.Move(ctx, spaceID, tt.folderID, tt.newParentID, tt.targetSort)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -683,7 +781,7 @@ func TestBlockService_List(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			_, err := service.List(ctx, tt.spaceID, tt.blockType, tt.parentID)
 
 			if tt.wantErr {
@@ -711,12 +809,11 @@ func TestBlockService_ComprehensiveNesting(t *testing.T) {
 			Type:    model.BlockTypeFolder,
 			Title:   "Root",
 		}
-		repo.On("NextSort", ctx, spaceID, (*uuid.UUID)(nil)).Return(int64(1), nil)
 		repo.On("Create", ctx, mock.MatchedBy(func(b *model.Block) bool {
 			return b.Type == model.BlockTypeFolder && b.GetFolderPath() == "Root"
 		})).Return(nil)
 
-		service := NewBlockService(repo)
+		service := NewBlockService(repo, nil, BlockTreeLimits{})
 		err := service.Create(ctx, rootFolder)
 		assert.NoError(t, err)
 		assert.Equal(t, "Root", rootFolder.GetFolderPath())
@@ -741,7 +838,7 @@ func TestBlockService_ComprehensiveNesting(t *testing.T) {
 		}
 		repo.On("Get", ctx, pageID).Return(pageBlock, nil)
 
-		service := NewBlockService(repo)
+		service := NewBlockService(repo, nil, BlockTreeLimits{})
 		err := service.Create(ctx, folderUnderPage)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "cannot be a child of")
@@ -758,7 +855,7 @@ func TestBlockService_ComprehensiveNesting(t *testing.T) {
 			Title:   "InvalidText",
 		}
 
-		service := NewBlockService(repo)
+		service := NewBlockService(repo, nil, BlockTreeLimits{})
 		err := service.Create(ctx, textAtRoot)
 		assert.Error(t, err)
 		// The error comes from Validate() which checks RequireParent first
@@ -768,6 +865,58 @@ func TestBlockService_ComprehensiveNesting(t *testing.T) {
 	})
 }
 
+func TestBlockService_UndoLastMove(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folderID := uuid.New()
+	oldParentID := uuid.New()
+
+	folder := func() *model.Block {
+		b := &model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Moved"}
+		b.SetFolderPath("Elsewhere/Moved")
+		return b
+	}
+	move := &model.BlockMove{BlockID: folderID, OldParentID: &oldParentID, OldSort: 3}
+
+	t.Run("moves back and restores the folder path", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("LastMove", ctx, folderID).Return(move, nil)
+		repo.On("Get", ctx, folderID).Return(folder(), nil)
+		oldParent := &model.Block{ID: oldParentID, SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Origin"}
+		oldParent.SetFolderPath("Origin")
+		repo.On("Get", ctx, oldParentID).Return(oldParent, nil)
+		repo.On("Update", ctx, mock.MatchedBy(func(b *model.Block) bool {
+			return b.GetFolderPath() == "Origin/Moved"
+		})).Return(nil)
+		repo.On("UndoMove", ctx, move).Return(nil)
+
+		undone, err := NewBlockService(repo, nil, BlockTreeLimits{}).UndoLastMove(ctx, spaceID, folderID)
+		assert.NoError(t, err)
+		assert.Equal(t, move, undone)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("nothing to undo", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, folderID).Return(folder(), nil)
+		repo.On("LastMove", ctx, folderID).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := NewBlockService(repo, nil, BlockTreeLimits{}).UndoLastMove(ctx, spaceID, folderID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("old parent deleted", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("LastMove", ctx, folderID).Return(move, nil)
+		repo.On("Get", ctx, folderID).Return(folder(), nil)
+		repo.On("Get", ctx, oldParentID).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := NewBlockService(repo, nil, BlockTreeLimits{}).UndoLastMove(ctx, spaceID, folderID)
+		assert.ErrorIs(t, err, ErrInvalidBlockMove)
+		repo.AssertNotCalled(t, "UndoMove", mock.Anything, mock.Anything)
+	})
+}
+
 func TestBlockService_Move_CircularReference(t *testing.T) {
 	ctx := context.Background()
 	spaceID := uuid.New()
@@ -976,6 +1125,7 @@ func TestBlockService_Move_CircularReference(t *testing.T) {
 					ID:       folderCID,
 					Type:     model.BlockTypeFolder,
 					Title:    "FolderC",
+					SpaceID:  spaceID,
 					ParentID: &unrelatedID, // Different parent, so not a descendant
 				}
 				repo.On("Get", ctx, folderCID).Return(folderC, nil)
@@ -999,8 +1149,8 @@ func TestBlockService_Move_CircularReference(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
-			err := service.Move(ctx, tt.blockID, tt.newParentID, nil)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
+			err := service.Move(ctx, spaceID, tt.blockID, tt.newParentID, nil)
 
 			if tt.wantErr {
 				assert.Error(t, err, "Expected error for: %s", tt.description)
@@ -1143,7 +1293,7 @@ func TestBlockService_isDescendant(t *testing.T) {
 			repo := &MockBlockRepo{}
 			tt.setup(repo)
 
-			service := NewBlockService(repo)
+			service := NewBlockService(repo, nil, BlockTreeLimits{})
 			result, err := service.(*blockService).isDescendant(ctx, tt.ancestorID, tt.candidateID)
 
 			if tt.wantErr {
@@ -1157,3 +1307,409 @@ func TestBlockService_isDescendant(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockService_CountByType(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+
+	repo := &MockBlockRepo{}
+	repo.On("CountByType", ctx, spaceID, (*uuid.UUID)(nil)).Return(map[string]int64{"page": 2, "text": 5}, nil)
+
+	counts, err := NewBlockService(repo, nil, BlockTreeLimits{}).CountByType(ctx, spaceID, nil)
+	assert.NoError(t, err)
+	// Types without blocks are reported as zero
+	assert.Equal(t, map[string]int64{"page": 2, "text": 5, "folder": 0, "sop": 0}, counts)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_MoveBatch(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	folderID := uuid.New()
+	folder := &model.Block{ID: folderID, SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Archive"}
+	folder.SetFolderPath("Archive")
+	page1 := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "One"}
+	page2 := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Two"}
+	subfolder := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Old"}
+	otherSpace := &model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypePage, Title: "Elsewhere"}
+
+	// repoWith knows the given blocks; Maybe since a failed validation stops at the first bad block
+	repoWith := func(blocks ...*model.Block) *MockBlockRepo {
+		repo := &MockBlockRepo{}
+		for _, b := range blocks {
+			repo.On("Get", ctx, b.ID).Return(b, nil).Maybe()
+		}
+		return repo
+	}
+
+	t.Run("moves all blocks in one repo call", func(t *testing.T) {
+		repo := repoWith(folder, page1, page2, subfolder)
+		ids := []uuid.UUID{page2.ID, subfolder.ID, page1.ID}
+		// The folder path is saved by the repo with the move, not before it
+		repo.On("MoveBatch", ctx, ids, &folderID, map[uuid.UUID]string{subfolder.ID: "Archive/Old"}).Return(nil).Once()
+
+		assert.NoError(t, NewBlockService(repo, nil, BlockTreeLimits{}).MoveBatch(ctx, spaceID, ids, &folderID))
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name string
+		ids  []uuid.UUID
+	}{
+		{name: "no blocks"},
+		{name: "duplicate block", ids: []uuid.UUID{page1.ID, page1.ID}},
+		{name: "block of another space", ids: []uuid.UUID{page1.ID, otherSpace.ID}},
+		{name: "folder into itself", ids: []uuid.UUID{folderID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repoWith(folder, page1, otherSpace)
+			err := NewBlockService(repo, nil, BlockTreeLimits{}).MoveBatch(ctx, spaceID, tt.ids, &folderID)
+			assert.ErrorIs(t, err, ErrInvalidBlockMove)
+			repo.AssertNotCalled(t, "MoveBatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBlockService_MoveToSpace(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	targetSpaceID := uuid.New()
+	folder := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Specs"}
+	folder.SetFolderPath("Specs")
+	page := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Roadmap"}
+	target := &model.Block{ID: uuid.New(), SpaceID: targetSpaceID, Type: model.BlockTypeFolder, Title: "Imported"}
+	target.SetFolderPath("Imported")
+	targetText := &model.Block{ID: uuid.New(), SpaceID: targetSpaceID, Type: model.BlockTypeText, Title: "Note"}
+	sameSpaceParent := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeFolder, Title: "Local"}
+
+	// repoWith knows the blocks a subtest reads
+	repoWith := func(blocks ...*model.Block) *MockBlockRepo {
+		repo := &MockBlockRepo{}
+		for _, b := range blocks {
+			repo.On("Get", ctx, b.ID).Return(b, nil)
+		}
+		return repo
+	}
+
+	t.Run("folder under a folder of the target space", func(t *testing.T) {
+		repo := repoWith(folder, target)
+		repo.On("Update", ctx, mock.MatchedBy(func(b *model.Block) bool {
+			return b.ID == folder.ID && b.GetFolderPath() == "Imported/Specs"
+		})).Return(nil).Once()
+		repo.On("MoveToSpace", ctx, folder.ID, targetSpaceID, &target.ID).Return(nil).Once()
+
+		assert.NoError(t, NewBlockService(repo, nil, BlockTreeLimits{}).MoveToSpace(ctx, spaceID, folder.ID, targetSpaceID, &target.ID))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("page to the root of the target space", func(t *testing.T) {
+		repo := repoWith(page)
+		repo.On("MoveToSpace", ctx, page.ID, targetSpaceID, (*uuid.UUID)(nil)).Return(nil).Once()
+
+		assert.NoError(t, NewBlockService(repo, nil, BlockTreeLimits{}).MoveToSpace(ctx, spaceID, page.ID, targetSpaceID, nil))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("block of another space", func(t *testing.T) {
+		repo := repoWith(page)
+		err := NewBlockService(repo, nil, BlockTreeLimits{}).MoveToSpace(ctx, uuid.New(), page.ID, targetSpaceID, nil)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		repo.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name          string
+		targetSpaceID uuid.UUID
+		parent        *model.Block
+	}{
+		{name: "same space", targetSpaceID: spaceID},
+		{name: "parent outside the target space", targetSpaceID: targetSpaceID, parent: sameSpaceParent},
+		{name: "parent cannot have children", targetSpaceID: targetSpaceID, parent: targetText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repoWith(page)
+			var parentID *uuid.UUID
+			if tt.parent != nil {
+				repo.On("Get", ctx, tt.parent.ID).Return(tt.parent, nil)
+				parentID = &tt.parent.ID
+			}
+			err := NewBlockService(repo, nil, BlockTreeLimits{}).MoveToSpace(ctx, spaceID, page.ID, tt.targetSpaceID, parentID)
+			assert.ErrorIs(t, err, ErrInvalidBlockMove)
+			repo.AssertExpectations(t)
+			repo.AssertNotCalled(t, "MoveToSpace", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBlockService_GetMany(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	a := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "A"}
+	b := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, Title: "B"}
+	elsewhere := model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypePage, Title: "Elsewhere"}
+	unknown := uuid.New()
+
+	ids := []uuid.UUID{b.ID, unknown, a.ID, elsewhere.ID, b.ID}
+	repo := &MockBlockRepo{}
+	repo.On("GetMany", ctx, ids).Return([]model.Block{a, elsewhere, b}, nil)
+
+	blocks, missing, err := NewBlockService(repo, nil, BlockTreeLimits{}).GetMany(ctx, spaceID, ids)
+	assert.NoError(t, err)
+	// Requested order, each block once; blocks of other spaces count as missing
+	assert.Equal(t, []model.Block{b, a}, blocks)
+	assert.Equal(t, []uuid.UUID{unknown, elsewhere.ID}, missing)
+	repo.AssertExpectations(t)
+}
+
+func TestBlockService_GetBlockProperties(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	block := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "A"}
+	repo := &MockBlockRepo{}
+	repo.On("Get", ctx, block.ID).Return(block, nil)
+
+	got, err := NewBlockService(repo, nil, BlockTreeLimits{}).GetBlockProperties(ctx, spaceID, block.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, block, got)
+
+	// A block of another space is not found
+	_, err = NewBlockService(repo, nil, BlockTreeLimits{}).GetBlockProperties(ctx, uuid.New(), block.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestBlockService_BulkUpdate(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	newBlocks := func() []*model.Block {
+		return []*model.Block{
+			{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, Title: "A", Props: datatypes.NewJSONType(map[string]any{"text": "a", "tag": "old"})},
+			{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeText, Title: "B", Props: datatypes.NewJSONType(map[string]any{"text": "b"})},
+			{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeSOP, Title: "C", Props: datatypes.NewJSONType(map[string]any{"preferences": ""})},
+		}
+	}
+
+	t.Run("updates three blocks at once", func(t *testing.T) {
+		blocks := newBlocks()
+		ids := []uuid.UUID{blocks[0].ID, blocks[1].ID, blocks[2].ID}
+		renamed := "B2"
+		repo := &MockBlockRepo{}
+		repo.On("UpdateMany", ctx, spaceID, ids, mock.Anything).Run(func(args mock.Arguments) {
+			apply := args.Get(3).(func(*model.Block) error)
+			for _, b := range blocks {
+				assert.NoError(t, apply(b))
+			}
+		}).Return(map[uuid.UUID]error{}, nil)
+
+		failed, err := NewBlockService(repo, nil, BlockTreeLimits{}).BulkUpdate(ctx, spaceID, []BlockUpdate{
+			{BlockID: blocks[0].ID},
+			{BlockID: blocks[1].ID, Title: &renamed, Props: map[string]any{"text": "replaced"}},
+			{BlockID: blocks[2].ID},
+		}, map[string]any{"tag": "reviewed"})
+		assert.NoError(t, err)
+		assert.Empty(t, failed)
+
+		assert.Equal(t, map[string]any{"text": "a", "tag": "reviewed"}, blocks[0].Props.Data())
+		assert.Equal(t, "B2", blocks[1].Title)
+		assert.Equal(t, map[string]any{"text": "replaced", "tag": "reviewed"}, blocks[1].Props.Data())
+		assert.Equal(t, map[string]any{"preferences": "", "tag": "reviewed"}, blocks[2].Props.Data())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("props breaking the schema of the type fail", func(t *testing.T) {
+		blocks := newBlocks()
+		repo := &MockBlockRepo{}
+		var applyErr error
+		repo.On("UpdateMany", ctx, spaceID, []uuid.UUID{blocks[2].ID}, mock.Anything).Run(func(args mock.Arguments) {
+			applyErr = args.Get(3).(func(*model.Block) error)(blocks[2])
+		}).Return(map[uuid.UUID]error{}, nil)
+
+		_, err := NewBlockService(repo, nil, BlockTreeLimits{}).BulkUpdate(ctx, spaceID, []BlockUpdate{{BlockID: blocks[2].ID}}, map[string]any{"preferences": nil})
+		assert.NoError(t, err)
+		assert.ErrorIs(t, applyErr, model.ErrInvalidBlockProps)
+	})
+
+	t.Run("repeated block", func(t *testing.T) {
+		id := uuid.New()
+		repo := &MockBlockRepo{}
+		_, err := NewBlockService(repo, nil, BlockTreeLimits{}).BulkUpdate(ctx, spaceID, []BlockUpdate{{BlockID: id}, {BlockID: id}}, nil)
+		assert.ErrorIs(t, err, ErrInvalidBlockUpdate)
+		repo.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// fakeBlockViewRecorder keeps pending views in memory
+type fakeBlockViewRecorder struct {
+	views map[uuid.UUID]map[uuid.UUID]time.Time
+}
+
+func newFakeBlockViewRecorder() *fakeBlockViewRecorder {
+	return &fakeBlockViewRecorder{views: map[uuid.UUID]map[uuid.UUID]time.Time{}}
+}
+
+func (f *fakeBlockViewRecorder) Record(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, at time.Time) error {
+	if f.views[spaceID] == nil {
+		f.views[spaceID] = map[uuid.UUID]time.Time{}
+	}
+	f.views[spaceID][blockID] = at
+	return nil
+}
+
+func (f *fakeBlockViewRecorder) Pending(ctx context.Context, spaceID uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	return f.views[spaceID], nil
+}
+
+func (f *fakeBlockViewRecorder) Flush(ctx context.Context) error { return nil }
+
+func TestBlockService_ListRecentlyViewed(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	earlier := time.Now().Add(-time.Hour)
+	older := earlier.Add(-time.Hour)
+	a := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "A", LastViewedAt: &earlier}
+	b := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "B", LastViewedAt: &older}
+	c := model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "C"}
+
+	t.Run("viewed block comes first", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, c.ID).Return(&c, nil)
+		repo.On("ListRecentlyViewed", ctx, spaceID, 2).Return([]model.Block{a, b}, nil)
+		repo.On("GetMany", ctx, []uuid.UUID{c.ID}).Return([]model.Block{c}, nil)
+		svc := NewBlockService(repo, newFakeBlockViewRecorder(), BlockTreeLimits{})
+
+		_, err := svc.GetBlockProperties(ctx, spaceID, c.ID)
+		assert.NoError(t, err)
+
+		recent, err := svc.ListRecentlyViewed(ctx, spaceID, 2)
+		assert.NoError(t, err)
+		if assert.Len(t, recent, 2) {
+			assert.Equal(t, c.ID, recent[0].ID)
+			assert.NotNil(t, recent[0].LastViewedAt)
+			assert.Equal(t, a.ID, recent[1].ID)
+		}
+	})
+
+	t.Run("pending view of a listed block moves it forward", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("GetMany", ctx, []uuid.UUID{b.ID}).Return([]model.Block{b}, nil)
+		repo.On("ListRecentlyViewed", ctx, spaceID, 2).Return([]model.Block{a, b}, nil)
+		svc := NewBlockService(repo, newFakeBlockViewRecorder(), BlockTreeLimits{})
+
+		_, _, err := svc.GetMany(ctx, spaceID, []uuid.UUID{b.ID})
+		assert.NoError(t, err)
+
+		recent, err := svc.ListRecentlyViewed(ctx, spaceID, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{b.ID, a.ID}, []uuid.UUID{recent[0].ID, recent[1].ID})
+		repo.AssertNumberOfCalls(t, "GetMany", 1)
+	})
+
+	t.Run("without tracking only flushed views are listed", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, c.ID).Return(&c, nil)
+		repo.On("ListRecentlyViewed", ctx, spaceID, 2).Return([]model.Block{a, b}, nil)
+		svc := NewBlockService(repo, nil, BlockTreeLimits{})
+
+		_, err := svc.GetBlockProperties(ctx, spaceID, c.ID)
+		assert.NoError(t, err)
+
+		recent, err := svc.ListRecentlyViewed(ctx, spaceID, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.Block{a, b}, recent)
+	})
+}
+
+func TestBlockService_UpdateBlockProperties_SOPSchema(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	sop := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypeSOP, Title: "Star a repo"}
+
+	t.Run("valid props", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, sop.ID).Return(sop, nil)
+		update := &model.Block{ID: sop.ID, Props: datatypes.NewJSONType(map[string]any{"use_when": "star a repo on github.com", "preferences": "use the web ui"})}
+		repo.On("Update", ctx, update).Return(nil).Once()
+
+		assert.NoError(t, NewBlockService(repo, nil, BlockTreeLimits{}).UpdateBlockProperties(ctx, spaceID, update))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("invalid props", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, sop.ID).Return(sop, nil)
+		update := &model.Block{ID: sop.ID, Props: datatypes.NewJSONType(map[string]any{"use_when": "star a repo on github.com", "preferences": []any{"web ui"}})}
+
+		err := NewBlockService(repo, nil, BlockTreeLimits{}).UpdateBlockProperties(ctx, spaceID, update)
+		assert.ErrorIs(t, err, model.ErrInvalidBlockProps)
+		assert.ErrorContains(t, err, "preferences: expected string, got array")
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("patch with invalid merged props", func(t *testing.T) {
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, sop.ID).Return(sop, nil)
+		patch := map[string]any{"preferences": []any{"web ui"}}
+		merged := &model.Block{ID: sop.ID, SpaceID: spaceID, Type: model.BlockTypeSOP, Props: datatypes.NewJSONType(map[string]any{"use_when": "star a repo on github.com", "preferences": []any{"web ui"}})}
+		repo.On("PatchProps", ctx, sop.ID, patch, mock.Anything).Return(merged, nil)
+
+		_, err := NewBlockService(repo, nil, BlockTreeLimits{}).PatchBlockProperties(ctx, spaceID, sop.ID, patch)
+		assert.ErrorIs(t, err, model.ErrInvalidBlockProps)
+		assert.ErrorContains(t, err, "preferences: expected string, got array")
+	})
+}
+
+func TestBlockService_BlockOfAnotherSpace(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	page := &model.Block{ID: uuid.New(), SpaceID: uuid.New(), Type: model.BlockTypePage, Title: "Elsewhere"}
+	sort := int64(1)
+
+	tests := []struct {
+		name string
+		call func(BlockService) error
+	}{
+		{name: "update properties", call: func(s BlockService) error {
+			return s.UpdateBlockProperties(ctx, spaceID, &model.Block{ID: page.ID, Title: "Stolen"})
+		}},
+		{name: "patch properties", call: func(s BlockService) error {
+			_, err := s.PatchBlockProperties(ctx, spaceID, page.ID, map[string]any{"color": "red"})
+			return err
+		}},
+		{name: "move", call: func(s BlockService) error {
+			return s.Move(ctx, spaceID, page.ID, nil, &sort)
+		}},
+		{name: "undo move", call: func(s BlockService) error {
+			_, err := s.UndoLastMove(ctx, spaceID, page.ID)
+			return err
+		}},
+		{name: "update sort", call: func(s BlockService) error {
+			return s.UpdateSort(ctx, spaceID, page.ID, sort)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBlockRepo{}
+			repo.On("Get", ctx, page.ID).Return(page, nil)
+
+			assert.ErrorIs(t, tt.call(NewBlockService(repo, nil, BlockTreeLimits{})), gorm.ErrRecordNotFound)
+			// Only the lookup ran, nothing was written
+			repo.AssertExpectations(t)
+			assert.Len(t, repo.Calls, 1)
+		})
+	}
+
+	t.Run("move under a parent of another space", func(t *testing.T) {
+		block := &model.Block{ID: uuid.New(), SpaceID: spaceID, Type: model.BlockTypePage, Title: "Mine"}
+		parent := &model.Block{ID: uuid.New(), SpaceID: page.SpaceID, Type: model.BlockTypeFolder, Title: "Theirs"}
+		repo := &MockBlockRepo{}
+		repo.On("Get", ctx, block.ID).Return(block, nil)
+		repo.On("Get", ctx, parent.ID).Return(parent, nil)
+
+		err := NewBlockService(repo, nil, BlockTreeLimits{}).Move(ctx, spaceID, block.ID, &parent.ID, nil)
+		assert.ErrorIs(t, err, ErrInvalidBlockMove)
+		repo.AssertNotCalled(t, "MoveToParentAppend", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
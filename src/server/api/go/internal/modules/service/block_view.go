@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// BlockViewRecorder keeps the latest read of each block in a fast store and periodically
+// persists it as the last view time of the block
+type BlockViewRecorder interface {
+	// Record marks the block of the space as viewed at the given time
+	Record(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, at time.Time) error
+	// Pending returns the views of the space not flushed yet, by block
+	Pending(ctx context.Context, spaceID uuid.UUID) (map[uuid.UUID]time.Time, error)
+	// Flush writes the pending views to the blocks
+	Flush(ctx context.Context) error
+}
+
+const (
+	// Redis hash per space holding the latest pending view time of its blocks
+	redisKeyPrefixBlockView = "block:view:"
+	// Redis set of the spaces with pending views
+	redisKeyBlockViewPending = "block:view:pending"
+)
+
+// recordBlockViewScript keeps the latest of the pending and recorded view times, so views
+// put back after a failed flush never hide a newer one
+var recordBlockViewScript = redis.NewScript(`
+local cur = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if tonumber(ARGV[2]) > cur then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+redis.call('SADD', KEYS[2], ARGV[3])
+return 1
+`)
+
+// takeBlockViewsScript reads and clears the pending views of a space atomically, so views
+// recorded during a flush are kept for the next one
+var takeBlockViewsScript = redis.NewScript(`
+local views = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+redis.call('SREM', KEYS[2], ARGV[1])
+return views
+`)
+
+type redisBlockViewRecorder struct {
+	rdb *redis.Client
+	r   repo.BlockRepo
+	log *zap.Logger
+}
+
+// NewRedisBlockViewRecorder records views in Redis and flushes them to r. Repeated views of a
+// block between two flushes cost a single update.
+func NewRedisBlockViewRecorder(rdb *redis.Client, r repo.BlockRepo, log *zap.Logger) BlockViewRecorder {
+	return &redisBlockViewRecorder{rdb: rdb, r: r, log: log}
+}
+
+func (v *redisBlockViewRecorder) Record(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, at time.Time) error {
+	keys := []string{redisKeyPrefixBlockView + spaceID.String(), redisKeyBlockViewPending}
+	if err := recordBlockViewScript.Run(ctx, v.rdb, keys, blockID.String(), at.UnixMilli(), spaceID.String()).Err(); err != nil {
+		return fmt.Errorf("record view of block %s: %w", blockID, err)
+	}
+	return nil
+}
+
+func (v *redisBlockViewRecorder) Pending(ctx context.Context, spaceID uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	raw, err := v.rdb.HGetAll(ctx, redisKeyPrefixBlockView+spaceID.String()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get pending views of space %s: %w", spaceID, err)
+	}
+	views := make(map[uuid.UUID]time.Time, len(raw))
+	for rawID, rawAt := range raw {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			continue
+		}
+		views[id] = time.UnixMilli(parseRedisInt(rawAt))
+	}
+	return views, nil
+}
+
+func (v *redisBlockViewRecorder) Flush(ctx context.Context) error {
+	spaces, err := v.rdb.SMembers(ctx, redisKeyBlockViewPending).Result()
+	if err != nil {
+		return fmt.Errorf("list spaces with pending block views: %w", err)
+	}
+
+	var errs []error
+	for _, rawSpaceID := range spaces {
+		spaceID, err := uuid.Parse(rawSpaceID)
+		if err != nil {
+			v.rdb.SRem(ctx, redisKeyBlockViewPending, rawSpaceID)
+			continue
+		}
+
+		raw, err := takeBlockViewsScript.Run(ctx, v.rdb, []string{redisKeyPrefixBlockView + rawSpaceID, redisKeyBlockViewPending}, rawSpaceID).StringSlice()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("take pending views of space %s: %w", spaceID, err))
+			continue
+		}
+		views := make(map[uuid.UUID]time.Time, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			id, err := uuid.Parse(raw[i])
+			if err != nil {
+				continue
+			}
+			views[id] = time.UnixMilli(parseRedisInt(raw[i+1]))
+		}
+
+		if err := v.r.SetLastViewed(ctx, views); err != nil {
+			// Put the views back for the next flush
+			for id, at := range views {
+				if restoreErr := v.Record(ctx, spaceID, id, at); restoreErr != nil {
+					v.log.Error("lost block view", zap.String("block_id", id.String()), zap.Error(restoreErr))
+				}
+			}
+			errs = append(errs, fmt.Errorf("persist views of space %s: %w", spaceID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunBlockViewFlusher flushes the recorder every interval until ctx is done, then flushes
+// one last time
+func RunBlockViewFlusher(ctx context.Context, views BlockViewRecorder, interval time.Duration, log *zap.Logger) {
+	runFlusher(ctx, views.Flush, interval, "block views", log)
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+	"gorm.io/datatypes"
+)
+
+// ErrContextBudgetExceeded is returned when the messages that must be kept do not fit the budget
+var ErrContextBudgetExceeded = errors.New("context budget exceeded by messages that must be kept")
+
+// TrimToBudget fits messages (ordered oldest first) into maxTokens. Starting from the oldest,
+// messages are dropped (together with the tool results answering their tool calls) or, when
+// only part of a text message has to go, truncated. The most recent user message and messages
+// with meta["pinned"]=true are always kept, along with the tool calls and results they pair with. It returns the kept messages in their original order
+// and the dropped ones. A nil tokenizer uses tokenizer.Heuristic.
+func TrimToBudget(messages []model.Message, maxTokens int, tok tokenizer.Tokenizer) ([]model.Message, []model.Message, error) {
+	if maxTokens <= 0 {
+		return nil, nil, fmt.Errorf("max tokens must be > 0, got %d", maxTokens)
+	}
+	if tok == nil {
+		tok = tokenizer.Heuristic{}
+	}
+
+	counts := make([]int, len(messages))
+	total := 0
+	for i, msg := range messages {
+		n, err := tok.CountMessageTokens(msg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("count tokens of message %s: %w", msg.ID, err)
+		}
+		counts[i] = n
+		total += n
+	}
+	if total <= maxTokens {
+		return messages, nil, nil
+	}
+
+	protected := make([]bool, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			protected[i] = true
+			break
+		}
+	}
+	for i := range messages {
+		if messages[i].IsPinned() {
+			protected[i] = true
+		}
+	}
+
+	// Tool results answering a dropped tool call have to go with it
+	resultIndex := map[string]int{}
+	callIndex := map[string]int{}
+	for i, msg := range messages {
+		for _, p := range msg.Parts {
+			if id, ok := p.Meta["tool_call_id"].(string); ok && p.Type == "tool-result" {
+				resultIndex[id] = i
+			}
+			if id, ok := p.Meta["id"].(string); ok && p.Type == "tool-call" {
+				callIndex[id] = i
+			}
+		}
+	}
+
+	// Providers reject a tool call without its result and the other way around, so the
+	// messages pairing with a kept one are kept too, until no pair is left split
+	for changed := true; changed; {
+		changed = false
+		for i, msg := range messages {
+			if !protected[i] {
+				continue
+			}
+			for _, p := range msg.Parts {
+				j, found := pairedMessage(p, resultIndex, callIndex)
+				if found && !protected[j] {
+					protected[j] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	kept := make([]model.Message, len(messages))
+	copy(kept, messages)
+	removed := make([]bool, len(messages))
+
+	for i := 0; i < len(messages) && total > maxTokens; i++ {
+		if protected[i] || removed[i] {
+			continue
+		}
+
+		excess := total - maxTokens
+		if excess < counts[i] && !hasToolCall(messages[i]) {
+			if msg, n, ok := truncateMessage(messages[i], counts[i]-excess, tok); ok {
+				kept[i] = msg
+				total -= counts[i] - n
+				continue
+			}
+		}
+
+		removed[i] = true
+		total -= counts[i]
+		for _, p := range messages[i].Parts {
+			id, ok := p.Meta["id"].(string)
+			if !ok || p.Type != "tool-call" {
+				continue
+			}
+			if j, found := resultIndex[id]; found && !removed[j] && !protected[j] {
+				removed[j] = true
+				total -= counts[j]
+			}
+		}
+	}
+
+	if total > maxTokens {
+		return nil, nil, fmt.Errorf("%w: %d tokens over a budget of %d", ErrContextBudgetExceeded, total, maxTokens)
+	}
+
+	result := make([]model.Message, 0, len(messages))
+	var dropped []model.Message
+	for i := range messages {
+		if removed[i] {
+			dropped = append(dropped, messages[i])
+		} else {
+			result = append(result, kept[i])
+		}
+	}
+	return result, dropped, nil
+}
+
+// pairedMessage returns the index of the message holding the result of a tool-call part, or
+// the call of a tool-result part
+func pairedMessage(p model.Part, resultIndex, callIndex map[string]int) (int, bool) {
+	switch p.Type {
+	case "tool-call":
+		if id, ok := p.Meta["id"].(string); ok {
+			j, found := resultIndex[id]
+			return j, found
+		}
+	case "tool-result":
+		if id, ok := p.Meta["tool_call_id"].(string); ok {
+			j, found := callIndex[id]
+			return j, found
+		}
+	}
+	return 0, false
+}
+
+func hasToolCall(msg model.Message) bool {
+	for _, p := range msg.Parts {
+		if p.Type == "tool-call" {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateMessage shortens the tail of a text-only message to at most maxTokens,
+// returning the truncated copy and its token count. It reports false when the message
+// is not text-only or nothing meaningful would be left.
+func truncateMessage(msg model.Message, maxTokens int, tok tokenizer.Tokenizer) (model.Message, int, bool) {
+	if maxTokens <= 0 || len(msg.Parts) == 0 {
+		return msg, 0, false
+	}
+	var texts []string
+	for _, p := range msg.Parts {
+		if p.Type != "text" {
+			return msg, 0, false
+		}
+		texts = append(texts, p.Text)
+	}
+	runes := []rune(strings.Join(texts, "\n"))
+
+	count := func(n int) (int, error) {
+		return tok.CountMessageTokens(model.Message{Parts: []model.Part{{Type: "text", Text: string(runes[:n]) + truncationMarker}}})
+	}
+
+	// Longest prefix that fits, by binary search over the rune length
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		n, err := count(mid)
+		if err != nil {
+			return msg, 0, false
+		}
+		if n <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 {
+		return msg, 0, false
+	}
+
+	n, err := count(lo)
+	if err != nil {
+		return msg, 0, false
+	}
+
+	meta := map[string]any{}
+	for k, v := range msg.MetaMap() {
+		meta[k] = v
+	}
+	meta["truncated"] = true
+	msg.Meta = datatypes.NewJSONType(meta)
+	msg.Parts = []model.Part{{Type: "text", Text: string(runes[:lo]) + truncationMarker}}
+	return msg, n, true
+}
+
+// truncationMarker marks where a truncated message was cut
+const truncationMarker = "…"
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func budgetMsg(role string, text string, meta map[string]any) model.Message {
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	return model.Message{
+		ID:    uuid.New(),
+		Role:  role,
+		Meta:  datatypes.NewJSONType(meta),
+		Parts: []model.Part{{Type: "text", Text: text}},
+	}
+}
+
+func TestTrimToBudget(t *testing.T) {
+	// Each "x" is one token, plus one token for the separator newline
+	tok := tokenizer.Func(func(text string) (int, error) { return len([]rune(text)), nil })
+
+	t.Run("within budget returns messages unchanged", func(t *testing.T) {
+		msgs := []model.Message{budgetMsg("user", "xxx", nil), budgetMsg("assistant", "xxx", nil)}
+		kept, dropped, err := TrimToBudget(msgs, 100, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, msgs, kept)
+		assert.Empty(t, dropped)
+	})
+
+	t.Run("drops oldest messages first", func(t *testing.T) {
+		msgs := []model.Message{
+			budgetMsg("user", strings.Repeat("x", 9), nil),
+			budgetMsg("assistant", strings.Repeat("x", 9), nil),
+			budgetMsg("user", strings.Repeat("x", 9), nil),
+		}
+		kept, dropped, err := TrimToBudget(msgs, 20, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{msgs[1].ID, msgs[2].ID}, ids(kept))
+		assert.Equal(t, []uuid.UUID{msgs[0].ID}, ids(dropped))
+	})
+
+	t.Run("keeps pinned and latest user messages", func(t *testing.T) {
+		msgs := []model.Message{
+			budgetMsg("user", strings.Repeat("x", 9), map[string]any{"pinned": true}),
+			budgetMsg("assistant", strings.Repeat("x", 9), nil),
+			budgetMsg("user", strings.Repeat("x", 9), nil),
+			budgetMsg("assistant", strings.Repeat("x", 9), nil),
+		}
+		kept, dropped, err := TrimToBudget(msgs, 30, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{msgs[0].ID, msgs[2].ID, msgs[3].ID}, ids(kept))
+		assert.Equal(t, []uuid.UUID{msgs[1].ID}, ids(dropped))
+	})
+
+	t.Run("truncates when dropping a whole message is not needed", func(t *testing.T) {
+		msgs := []model.Message{
+			budgetMsg("assistant", strings.Repeat("x", 19), nil),
+			budgetMsg("user", strings.Repeat("x", 9), nil),
+		}
+		kept, dropped, err := TrimToBudget(msgs, 25, tok)
+		assert.NoError(t, err)
+		assert.Empty(t, dropped)
+		assert.Len(t, kept, 2)
+		assert.Equal(t, strings.Repeat("x", 13)+truncationMarker, kept[0].Parts[0].Text)
+		assert.Equal(t, true, kept[0].Meta.Data()["truncated"])
+		// The caller's messages are left untouched
+		assert.Equal(t, strings.Repeat("x", 19), msgs[0].Parts[0].Text)
+		assert.Nil(t, msgs[0].Meta.Data()["truncated"])
+	})
+
+	t.Run("drops tool results with their tool call", func(t *testing.T) {
+		call := model.Message{
+			ID:   uuid.New(),
+			Role: "assistant",
+			Parts: []model.Part{{Type: "tool-call", Meta: map[string]any{
+				"id": "call_1", "name": "search", "arguments": "{}",
+			}}},
+		}
+		result := model.Message{
+			ID:    uuid.New(),
+			Role:  "user",
+			Parts: []model.Part{{Type: "tool-result", Text: "xxxxx", Meta: map[string]any{"tool_call_id": "call_1"}}},
+		}
+		last := budgetMsg("user", "xxxx", nil)
+
+		kept, dropped, err := TrimToBudget([]model.Message{call, result, last}, 10, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{last.ID}, ids(kept))
+		assert.Equal(t, []uuid.UUID{call.ID, result.ID}, ids(dropped))
+	})
+
+	t.Run("keeps the tool result of a pinned tool call", func(t *testing.T) {
+		call := model.Message{
+			ID:   uuid.New(),
+			Role: "assistant",
+			Meta: datatypes.NewJSONType(map[string]any{"pinned": true}),
+			Parts: []model.Part{{Type: "tool-call", Meta: map[string]any{
+				"id": "call_1", "name": "search", "arguments": "{}",
+			}}},
+		}
+		result := model.Message{
+			ID:    uuid.New(),
+			Role:  "user",
+			Parts: []model.Part{{Type: "tool-result", Text: "xxxxx", Meta: map[string]any{"tool_call_id": "call_1"}}},
+		}
+		// Dropping the short message is enough, but it comes after the larger result
+		short := budgetMsg("assistant", "x", nil)
+		last := budgetMsg("user", "xxxx", nil)
+
+		budget := 0
+		for _, msg := range []model.Message{call, result, last} {
+			n, err := tok.CountMessageTokens(msg)
+			assert.NoError(t, err)
+			budget += n
+		}
+
+		kept, dropped, err := TrimToBudget([]model.Message{call, result, short, last}, budget, tok)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{call.ID, result.ID, last.ID}, ids(kept))
+		assert.Equal(t, []uuid.UUID{short.ID}, ids(dropped))
+	})
+
+	t.Run("pinned messages alone over budget", func(t *testing.T) {
+		msgs := []model.Message{
+			budgetMsg("assistant", strings.Repeat("x", 20), map[string]any{"pinned": true}),
+			budgetMsg("user", strings.Repeat("x", 20), nil),
+		}
+		_, _, err := TrimToBudget(msgs, 10, tok)
+		assert.ErrorIs(t, err, ErrContextBudgetExceeded)
+	})
+
+	t.Run("invalid budget", func(t *testing.T) {
+		_, _, err := TrimToBudget(nil, 0, nil)
+		assert.Error(t, err)
+	})
+}
+
+func ids(msgs []model.Message) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m.ID)
+	}
+	return out
+}
@@ -10,11 +10,17 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"go.uber.org/zap"
 )
 
 type DiskService interface {
-	Create(ctx context.Context, projectID uuid.UUID) (*model.Disk, error)
+	Create(ctx context.Context, projectID uuid.UUID, pathAddressedKeys bool) (*model.Disk, error)
+	GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error)
+	GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error)
 	Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error
+	Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+	PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DeleteDiskPreview, error)
 	List(ctx context.Context, in ListDisksInput) (*ListDisksOutput, error)
 }
 
@@ -24,9 +30,12 @@ func NewDiskService(r repo.DiskRepo) DiskService {
 	return &diskService{r: r}
 }
 
-func (s *diskService) Create(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+// Create creates a disk of the project, storing its artifacts under path addressed keys when
+// pathAddressedKeys is set
+func (s *diskService) Create(ctx context.Context, projectID uuid.UUID, pathAddressedKeys bool) (*model.Disk, error) {
 	disk := &model.Disk{
-		ProjectID: projectID,
+		ProjectID:         projectID,
+		PathAddressedKeys: pathAddressedKeys,
 	}
 
 	if err := s.r.Create(ctx, disk); err != nil {
@@ -36,6 +45,19 @@ func (s *diskService) Create(ctx context.Context, projectID uuid.UUID) (*model.D
 	return disk, nil
 }
 
+func (s *diskService) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	return s.r.GetByID(ctx, diskID)
+}
+
+// GetOrCreateDefault returns the default disk of the project, creating it on first use
+func (s *diskService) GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+	disk, err := s.r.GetOrCreateDefault(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get default disk: %w", err)
+	}
+	return disk, nil
+}
+
 func (s *diskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	if len(diskID) == 0 {
 		return errors.New("disk id is empty")
@@ -43,11 +65,80 @@ func (s *diskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uu
 	return s.r.Delete(ctx, projectID, diskID)
 }
 
+// Restore brings back a deleted disk with its artifacts, as long as it was not purged yet
+func (s *diskService) Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	if len(diskID) == 0 {
+		return nil, errors.New("disk id is empty")
+	}
+	return s.r.Restore(ctx, projectID, diskID)
+}
+
+// diskPurgeBatchSize is the number of deleted disks purged per query
+const diskPurgeBatchSize = 100
+
+// PurgeDeleted purges every disk deleted before the given time, releasing the assets of its
+// artifacts. It returns the number of disks purged.
+func (s *diskService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := s.r.PurgeDeleted(ctx, before, diskPurgeBatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < diskPurgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunDiskPurger purges the disks deleted more than grace ago every interval until ctx is done
+func RunDiskPurger(ctx context.Context, disks DiskService, grace time.Duration, interval time.Duration, log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n, err := disks.PurgeDeleted(ctx, time.Now().Add(-grace))
+			if err != nil {
+				log.Warn("failed to purge deleted disks", zap.Error(err))
+			}
+			if n > 0 {
+				log.Info("purged deleted disks", zap.Int("count", n))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type DeleteDiskPreview struct {
+	ArtifactCount    int `json:"artifact_count"`
+	FreedObjectCount int `json:"freed_object_count"`
+}
+
+func (s *diskService) PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DeleteDiskPreview, error) {
+	if len(diskID) == 0 {
+		return nil, errors.New("disk id is empty")
+	}
+
+	artifactCount, freedCount, err := s.r.PreviewDelete(ctx, projectID, diskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteDiskPreview{
+		ArtifactCount:    artifactCount,
+		FreedObjectCount: freedCount,
+	}, nil
+}
+
 type ListDisksInput struct {
 	ProjectID uuid.UUID `json:"project_id"`
 	Limit     int       `json:"limit"`
 	Cursor    string    `json:"cursor"`
 	TimeDesc  bool      `json:"time_desc"`
+	WithStats bool      `json:"with_stats"`
 }
 
 type ListDisksOutput struct {
@@ -85,5 +176,20 @@ func (s *diskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOu
 		out.NextCursor = paging.EncodeCursor(last.CreatedAt, last.ID)
 	}
 
+	if in.WithStats && len(out.Items) > 0 {
+		diskIDs := make([]uuid.UUID, 0, len(out.Items))
+		for _, d := range out.Items {
+			diskIDs = append(diskIDs, d.ID)
+		}
+		stats, err := s.r.StatsByDiskIDs(ctx, diskIDs)
+		if err != nil {
+			return nil, fmt.Errorf("get disk stats: %w", err)
+		}
+		for _, d := range out.Items {
+			st := stats[d.ID]
+			d.Stats = &st
+		}
+	}
+
 	return out, nil
 }
@@ -22,11 +22,45 @@ func (m *MockDiskRepo) Create(ctx context.Context, a *model.Disk) error {
 	return args.Error(0)
 }
 
+func (m *MockDiskRepo) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskRepo) GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
 func (m *MockDiskRepo) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	args := m.Called(ctx, projectID, diskID)
 	return args.Error(0)
 }
 
+func (m *MockDiskRepo) Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskRepo) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDiskRepo) PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (int, int, error) {
+	args := m.Called(ctx, projectID, diskID)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
 func (m *MockDiskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
 	args := m.Called(ctx, projectID, afterCreatedAt, afterID, limit, timeDesc)
 	if args.Get(0) == nil {
@@ -35,6 +69,14 @@ func (m *MockDiskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID,
 	return args.Get(0).([]*model.Disk), args.Error(1)
 }
 
+func (m *MockDiskRepo) StatsByDiskIDs(ctx context.Context, diskIDs []uuid.UUID) (map[uuid.UUID]model.DiskStats, error) {
+	args := m.Called(ctx, diskIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID]model.DiskStats), args.Error(1)
+}
+
 // MockS3Deps is a mock implementation of blob.S3Deps
 type MockS3Deps struct {
 	mock.Mock
@@ -63,10 +105,11 @@ func newTestDiskService(r *MockDiskRepo, s3 *MockS3Deps) DiskService {
 	return &testDiskService{r: r, s3: s3}
 }
 
-func (s *testDiskService) Create(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+func (s *testDiskService) Create(ctx context.Context, projectID uuid.UUID, pathAddressedKeys bool) (*model.Disk, error) {
 	disk := &model.Disk{
-		ID:        uuid.New(),
-		ProjectID: projectID,
+		ID:                uuid.New(),
+		ProjectID:         projectID,
+		PathAddressedKeys: pathAddressedKeys,
 	}
 
 	if err := s.r.Create(ctx, disk); err != nil {
@@ -76,6 +119,14 @@ func (s *testDiskService) Create(ctx context.Context, projectID uuid.UUID) (*mod
 	return disk, nil
 }
 
+func (s *testDiskService) GetByID(ctx context.Context, diskID uuid.UUID) (*model.Disk, error) {
+	return s.r.GetByID(ctx, diskID)
+}
+
+func (s *testDiskService) GetOrCreateDefault(ctx context.Context, projectID uuid.UUID) (*model.Disk, error) {
+	return s.r.GetOrCreateDefault(ctx, projectID)
+}
+
 func (s *testDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	if diskID == uuid.Nil {
 		return errors.New("disk id is empty")
@@ -83,6 +134,22 @@ func (s *testDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskI
 	return s.r.Delete(ctx, projectID, diskID)
 }
 
+func (s *testDiskService) Restore(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	return (&diskService{r: s.r}).Restore(ctx, projectID, diskID)
+}
+
+func (s *testDiskService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	return (&diskService{r: s.r}).PurgeDeleted(ctx, before)
+}
+
+func (s *testDiskService) PreviewDelete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DeleteDiskPreview, error) {
+	artifactCount, freedCount, err := s.r.PreviewDelete(ctx, projectID, diskID)
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteDiskPreview{ArtifactCount: artifactCount, FreedObjectCount: freedCount}, nil
+}
+
 func (s *testDiskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOutput, error) {
 	disks, err := s.r.ListWithCursor(ctx, in.ProjectID, time.Time{}, uuid.UUID{}, in.Limit, in.TimeDesc)
 	if err != nil {
@@ -138,7 +205,7 @@ func TestDiskService_Create(t *testing.T) {
 
 			service := newTestDiskService(mockRepo, &MockS3Deps{})
 
-			disk, err := service.Create(context.Background(), projectID)
+			disk, err := service.Create(context.Background(), projectID, false)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -156,6 +223,21 @@ func TestDiskService_Create(t *testing.T) {
 	}
 }
 
+func TestDiskService_Create_PathAddressedKeys(t *testing.T) {
+	projectID := uuid.New()
+	for _, pathAddressed := range []bool{false, true} {
+		repo := &MockDiskRepo{}
+		repo.On("Create", mock.Anything, mock.MatchedBy(func(d *model.Disk) bool {
+			return d.ProjectID == projectID && d.PathAddressedKeys == pathAddressed
+		})).Return(nil)
+
+		disk, err := NewDiskService(repo).Create(context.Background(), projectID, pathAddressed)
+		assert.NoError(t, err)
+		assert.Equal(t, pathAddressed, disk.PathAddressedKeys)
+		repo.AssertExpectations(t)
+	}
+}
+
 func TestDiskService_List(t *testing.T) {
 	projectID := uuid.New()
 	disk1 := createTestDisk()
@@ -236,6 +318,39 @@ func TestDiskService_List(t *testing.T) {
 	}
 }
 
+func TestDiskService_List_WithStats(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	disk1 := createTestDisk()
+	disk2 := createTestDisk()
+	disk3 := createTestDisk()
+
+	repo := &MockDiskRepo{}
+	repo.On("ListWithCursor", ctx, projectID, time.Time{}, uuid.UUID{}, 3, false).Return([]*model.Disk{disk1, disk2, disk3}, nil)
+	// Only the disks of the page are aggregated
+	repo.On("StatsByDiskIDs", ctx, []uuid.UUID{disk1.ID, disk2.ID}).Return(map[uuid.UUID]model.DiskStats{
+		disk1.ID: {ArtifactCount: 3, TotalSizeB: 1024},
+	}, nil)
+
+	out, err := NewDiskService(repo).List(ctx, ListDisksInput{ProjectID: projectID, Limit: 2, WithStats: true})
+	assert.NoError(t, err)
+	assert.True(t, out.HasMore)
+	assert.Len(t, out.Items, 2)
+	assert.Equal(t, &model.DiskStats{ArtifactCount: 3, TotalSizeB: 1024}, out.Items[0].Stats)
+	assert.Equal(t, &model.DiskStats{}, out.Items[1].Stats)
+	repo.AssertExpectations(t)
+
+	t.Run("without stats", func(t *testing.T) {
+		repo := &MockDiskRepo{}
+		repo.On("ListWithCursor", ctx, projectID, time.Time{}, uuid.UUID{}, 11, false).Return([]*model.Disk{createTestDisk()}, nil)
+
+		out, err := NewDiskService(repo).List(ctx, ListDisksInput{ProjectID: projectID, Limit: 10})
+		assert.NoError(t, err)
+		assert.Nil(t, out.Items[0].Stats)
+		repo.AssertNotCalled(t, "StatsByDiskIDs", mock.Anything, mock.Anything)
+	})
+}
+
 func TestDiskService_Delete(t *testing.T) {
 	projectID := uuid.New()
 	diskID := uuid.New()
@@ -298,3 +413,29 @@ func TestDiskService_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskService_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Hour)
+
+	t.Run("purges batch after batch", func(t *testing.T) {
+		repo := &MockDiskRepo{}
+		repo.On("PurgeDeleted", ctx, before, diskPurgeBatchSize).Return(diskPurgeBatchSize, nil).Once()
+		repo.On("PurgeDeleted", ctx, before, diskPurgeBatchSize).Return(3, nil).Once()
+
+		n, err := newTestDiskService(repo, &MockS3Deps{}).PurgeDeleted(ctx, before)
+		assert.NoError(t, err)
+		assert.Equal(t, diskPurgeBatchSize+3, n)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("stops at the first failing batch", func(t *testing.T) {
+		repo := &MockDiskRepo{}
+		repo.On("PurgeDeleted", ctx, before, diskPurgeBatchSize).Return(2, errors.New("s3 down")).Once()
+
+		n, err := newTestDiskService(repo, &MockS3Deps{}).PurgeDeleted(ctx, before)
+		assert.ErrorContains(t, err, "s3 down")
+		assert.Equal(t, 2, n)
+		repo.AssertExpectations(t)
+	})
+}
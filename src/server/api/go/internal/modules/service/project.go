@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/tokens"
+)
+
+type ProjectService interface {
+	RotateProjectSecret(ctx context.Context, projectID uuid.UUID) (string, error)
+}
+
+type projectService struct {
+	r   repo.ProjectRepo
+	cfg *config.Config
+}
+
+func NewProjectService(r repo.ProjectRepo, cfg *config.Config) ProjectService {
+	return &projectService{r: r, cfg: cfg}
+}
+
+// RotateProjectSecret replaces the project's secret key with a newly generated one and
+// returns the new bearer token. The plaintext is never stored, so this is the only time
+// it can be read; tokens issued before the rotation stop authenticating immediately.
+func (s *projectService) RotateProjectSecret(ctx context.Context, projectID uuid.UUID) (string, error) {
+	secret, err := secrets.GenerateSecret()
+	if err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+
+	pepper := s.cfg.Root.SecretPepper
+	phc, err := secrets.HashSecret(secret, pepper)
+	if err != nil {
+		return "", fmt.Errorf("hash secret: %w", err)
+	}
+
+	if err := s.r.UpdateSecretKey(ctx, projectID, tokens.HMAC256Hex(pepper, secret), phc); err != nil {
+		return "", fmt.Errorf("update project secret: %w", err)
+	}
+
+	return s.cfg.Root.ProjectBearerTokenPrefix + secret, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+)
+
+// ProjectBucketLoader loads the bucket of a project from the project_buckets table and
+// decrypts its credentials with key, for blob.NewProjectS3Resolver
+func ProjectBucketLoader(r repo.ProjectBucketRepo, key []byte) blob.ProjectBucketLoader {
+	return func(ctx context.Context, projectID uuid.UUID) (*blob.ProjectBucket, error) {
+		b, err := r.Get(ctx, projectID)
+		if err != nil || b == nil {
+			return nil, err
+		}
+		accessKey, err := secrets.Decrypt(key, b.AccessKeyEncrypted)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt access key: %w", err)
+		}
+		secretKey, err := secrets.Decrypt(key, b.SecretKeyEncrypted)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt secret key: %w", err)
+		}
+		return &blob.ProjectBucket{
+			Endpoint:     b.Endpoint,
+			Region:       b.Region,
+			Bucket:       b.Bucket,
+			AccessKey:    accessKey,
+			SecretKey:    secretKey,
+			UsePathStyle: b.UsePathStyle,
+		}, nil
+	}
+}
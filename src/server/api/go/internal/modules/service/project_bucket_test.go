@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProjectBucketRepo struct {
+	mock.Mock
+}
+
+func (m *MockProjectBucketRepo) Get(ctx context.Context, projectID uuid.UUID) (*model.ProjectBucket, error) {
+	args := m.Called(ctx, projectID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ProjectBucket), args.Error(1)
+}
+
+func TestProjectBucketLoader(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	key := make([]byte, secrets.EncryptionKeyBytes)
+	_, _ = rand.Read(key)
+
+	encrypt := func(t *testing.T, s string) string {
+		out, err := secrets.Encrypt(key, s)
+		assert.NoError(t, err)
+		return out
+	}
+
+	t.Run("decrypts the credentials", func(t *testing.T) {
+		r := &MockProjectBucketRepo{}
+		r.On("Get", ctx, projectID).Return(&model.ProjectBucket{
+			ProjectID:          projectID,
+			Endpoint:           "https://minio.customer.example",
+			Region:             "eu-west-1",
+			Bucket:             "customer-artifacts",
+			UsePathStyle:       true,
+			AccessKeyEncrypted: encrypt(t, "AKIA123"),
+			SecretKeyEncrypted: encrypt(t, "s3cr3t"),
+		}, nil)
+
+		b, err := ProjectBucketLoader(r, key)(ctx, projectID)
+		assert.NoError(t, err)
+		assert.Equal(t, &blob.ProjectBucket{
+			Endpoint:     "https://minio.customer.example",
+			Region:       "eu-west-1",
+			Bucket:       "customer-artifacts",
+			AccessKey:    "AKIA123",
+			SecretKey:    "s3cr3t",
+			UsePathStyle: true,
+		}, b)
+	})
+
+	t.Run("project without a bucket", func(t *testing.T) {
+		r := &MockProjectBucketRepo{}
+		r.On("Get", ctx, projectID).Return(nil, nil)
+
+		b, err := ProjectBucketLoader(r, key)(ctx, projectID)
+		assert.NoError(t, err)
+		assert.Nil(t, b)
+	})
+
+	t.Run("credentials encrypted with another key", func(t *testing.T) {
+		other := make([]byte, secrets.EncryptionKeyBytes)
+		_, _ = rand.Read(other)
+		r := &MockProjectBucketRepo{}
+		r.On("Get", ctx, projectID).Return(&model.ProjectBucket{
+			ProjectID:          projectID,
+			Bucket:             "customer-artifacts",
+			AccessKeyEncrypted: encrypt(t, "AKIA123"),
+			SecretKeyEncrypted: encrypt(t, "s3cr3t"),
+		}, nil)
+
+		_, err := ProjectBucketLoader(r, other)(ctx, projectID)
+		assert.ErrorContains(t, err, "decrypt access key")
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		r := &MockProjectBucketRepo{}
+		r.On("Get", ctx, projectID).Return(nil, errors.New("db down"))
+
+		_, err := ProjectBucketLoader(r, key)(ctx, projectID)
+		assert.ErrorContains(t, err, "db down")
+	})
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockProjectRepo is a mock implementation of ProjectRepo
+type MockProjectRepo struct {
+	mock.Mock
+}
+
+func (m *MockProjectRepo) UpdateSecretKey(ctx context.Context, projectID uuid.UUID, secretKeyHMAC string, secretKeyHashPHC string) error {
+	args := m.Called(ctx, projectID, secretKeyHMAC, secretKeyHashPHC)
+	return args.Error(0)
+}
+
+func TestProjectService_RotateProjectSecret(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	cfg := &config.Config{Root: config.RootCfg{ProjectBearerTokenPrefix: "sk-ac-", SecretPepper: "pepper"}}
+
+	t.Run("rotates to a new verifiable secret", func(t *testing.T) {
+		oldSecret := "old-secret"
+		oldHMAC := tokens.HMAC256Hex(cfg.Root.SecretPepper, oldSecret)
+
+		var storedHMAC, storedPHC string
+		r := &MockProjectRepo{}
+		r.On("UpdateSecretKey", ctx, projectID, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				storedHMAC = args.String(2)
+				storedPHC = args.String(3)
+			}).
+			Return(nil)
+
+		token, err := NewProjectService(r, cfg).RotateProjectSecret(ctx, projectID)
+		assert.NoError(t, err)
+		r.AssertExpectations(t)
+
+		secret, ok := tokens.ParseToken(token, cfg.Root.ProjectBearerTokenPrefix)
+		assert.True(t, ok)
+
+		// The new token resolves to the stored key
+		assert.Equal(t, tokens.HMAC256Hex(cfg.Root.SecretPepper, secret), storedHMAC)
+		pass, err := secrets.VerifySecret(secret, cfg.Root.SecretPepper, storedPHC)
+		assert.NoError(t, err)
+		assert.True(t, pass)
+
+		// The old token no longer matches
+		assert.NotEqual(t, oldHMAC, storedHMAC)
+		pass, err = secrets.VerifySecret(oldSecret, cfg.Root.SecretPepper, storedPHC)
+		assert.NoError(t, err)
+		assert.False(t, pass)
+	})
+
+	t.Run("each rotation returns a different token", func(t *testing.T) {
+		r := &MockProjectRepo{}
+		r.On("UpdateSecretKey", ctx, projectID, mock.Anything, mock.Anything).Return(nil)
+		svc := NewProjectService(r, cfg)
+
+		token1, err := svc.RotateProjectSecret(ctx, projectID)
+		assert.NoError(t, err)
+		token2, err := svc.RotateProjectSecret(ctx, projectID)
+		assert.NoError(t, err)
+		assert.NotEqual(t, token1, token2)
+		assert.True(t, strings.HasPrefix(token1, "sk-ac-"))
+	})
+
+	t.Run("update failure returns no token", func(t *testing.T) {
+		r := &MockProjectRepo{}
+		r.On("UpdateSecretKey", ctx, projectID, mock.Anything, mock.Anything).Return(errors.New("tx failed"))
+
+		token, err := NewProjectService(r, cfg).RotateProjectSecret(ctx, projectID)
+		assert.Error(t, err)
+		assert.Empty(t, token)
+	})
+}
@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
@@ -18,6 +22,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/editor"
 	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/datatypes"
@@ -30,8 +35,13 @@ type SessionService interface {
 	GetByID(ctx context.Context, ss *model.Session) (*model.Session, error)
 	List(ctx context.Context, in ListSessionsInput) (*ListSessionsOutput, error)
 	StoreMessage(ctx context.Context, in StoreMessageInput) (*model.Message, error)
+	IngestMessages(ctx context.Context, projectID uuid.UUID, sessionID uuid.UUID, in []IngestMessageIn, skipDuplicates bool) ([]*model.Message, error)
 	GetMessages(ctx context.Context, in GetMessagesInput) (*GetMessagesOutput, error)
 	GetAllMessages(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error)
+	GetPartContentURL(ctx context.Context, in GetPartContentInput) (*PublicURL, error)
+	ExportMessages(ctx context.Context, in ExportMessagesInput) (*GetMessagesOutput, error)
+	GetSummary(ctx context.Context, sessionID uuid.UUID, tok tokenizer.Tokenizer) (*SessionSummary, error)
+	DiffSessions(ctx context.Context, sessionA uuid.UUID, sessionB uuid.UUID) (*SessionDiff, error)
 }
 
 type sessionService struct {
@@ -39,6 +49,7 @@ type sessionService struct {
 	assetReferenceRepo repo.AssetReferenceRepo
 	log                *zap.Logger
 	s3                 *blob.S3Deps
+	projectS3          blob.S3Resolver
 	publisher          *mq.Publisher
 	cfg                *config.Config
 	redis              *redis.Client
@@ -51,12 +62,15 @@ const (
 	defaultPartsCacheTTL = time.Hour
 )
 
-func NewSessionService(sessionRepo repo.SessionRepo, assetReferenceRepo repo.AssetReferenceRepo, log *zap.Logger, s3 *blob.S3Deps, publisher *mq.Publisher, cfg *config.Config, redis *redis.Client) SessionService {
+// NewSessionService stores the parts of messages in s3, the deployment's bucket. projectS3
+// resolves the bucket of an asset recorded elsewhere; when nil every asset is read from s3.
+func NewSessionService(sessionRepo repo.SessionRepo, assetReferenceRepo repo.AssetReferenceRepo, log *zap.Logger, s3 *blob.S3Deps, projectS3 blob.S3Resolver, publisher *mq.Publisher, cfg *config.Config, redis *redis.Client) SessionService {
 	return &sessionService{
 		sessionRepo:        sessionRepo,
 		assetReferenceRepo: assetReferenceRepo,
 		log:                log,
 		s3:                 s3,
+		projectS3:          projectS3,
 		publisher:          publisher,
 		cfg:                cfg,
 		redis:              redis,
@@ -146,6 +160,13 @@ type StoreMessageInput struct {
 	Files       map[string]*multipart.FileHeader
 }
 
+// IngestMessageIn is one normalized message of a bulk import
+type IngestMessageIn struct {
+	Role        string
+	Parts       []PartIn
+	MessageMeta map[string]interface{}
+}
+
 type StoreMQPublishJSON struct {
 	ProjectID uuid.UUID `json:"project_id"`
 	SessionID uuid.UUID `json:"session_id"`
@@ -157,6 +178,9 @@ type PartIn struct {
 	Text      string                 `json:"text,omitempty"`                                                                        // Text sharding
 	FileField string                 `json:"file_field,omitempty"`                                                                  // File field name in the form
 	Meta      map[string]interface{} `json:"meta,omitempty"`                                                                        // [Optional] metadata
+	ID        string                 `json:"id,omitempty"`                                                                          // [Optional] stable part ID, generated when empty
+	Index     int                    `json:"index,omitempty"`                                                                       // Position in the message, set by AssignPartIDs
+	Asset     *model.Asset           `json:"-"`                                                                                     // Stored file of the part, set by OffloadInlineData
 }
 
 func (p *PartIn) Validate() error {
@@ -206,13 +230,240 @@ func (p *PartIn) Validate() error {
 	return nil
 }
 
+// CompactParts merges consecutive text parts into one, joined by a newline, and drops
+// empty text parts. Text parts carrying meta (e.g. cache_control or refusals) and all
+// non-text parts are kept untouched, so tool/image/file parts never move.
+func CompactParts(parts []PartIn) []PartIn {
+	out := make([]PartIn, 0, len(parts))
+	for _, p := range parts {
+		if p.Type != "text" || len(p.Meta) > 0 {
+			out = append(out, p)
+			continue
+		}
+		if p.Text == "" {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Type == "text" && len(out[n-1].Meta) == 0 {
+			out[n-1].Text += "\n" + p.Text
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// AssignPartIDs sets the index of each part to its position and gives parts without an ID
+// a deterministic one (see PartID), so the same message normalized again from any format
+// gets the same IDs.
+func AssignPartIDs(parts []PartIn) []PartIn {
+	for i := range parts {
+		parts[i].Index = i
+		if parts[i].ID == "" {
+			parts[i].ID = PartID(parts[i], i)
+		}
+	}
+	return parts
+}
+
+// PartID derives the ID of a part at index. Tool calls and results are keyed by their call
+// ID, so a call and its result keep their IDs wherever the formats place them; other parts
+// by type, index and text. Media sources are left out as they change on export (an uploaded
+// image comes back as its public URL).
+func PartID(p PartIn, index int) string {
+	var key string
+	switch p.Type {
+	case "tool-call":
+		if id, _ := p.Meta["id"].(string); id != "" {
+			key = "tool-call\x00" + id
+		}
+	case "tool-result":
+		if id, _ := p.Meta["tool_call_id"].(string); id != "" {
+			key = "tool-result\x00" + id
+		}
+	}
+	if key == "" {
+		key = fmt.Sprintf("%s\x00%d\x00%s", p.Type, index, p.Text)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "part_" + hex.EncodeToString(sum[:8])
+}
+
+// InlineDataUploader stores the decoded inline data of a part and returns its asset
+type InlineDataUploader func(ctx context.Context, data []byte, mediaType string, filename string) (*model.Asset, error)
+
+// OffloadInlineData moves the base64 data of image and file parts to blob storage: each such
+// part gets the asset returned by upload in place of meta "data", and meta "type" becomes
+// "asset". The parts are copied, so parts keeps its data. Data that is not valid base64 is
+// left inline.
+func OffloadInlineData(ctx context.Context, parts []PartIn, upload InlineDataUploader) ([]PartIn, error) {
+	out := make([]PartIn, len(parts))
+	copy(out, parts)
+	for i, p := range out {
+		if p.Type != "image" && p.Type != "file" {
+			continue
+		}
+		data, _ := p.Meta["data"].(string)
+		if data == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			continue
+		}
+
+		mediaType, _ := p.Meta["media_type"].(string)
+		filename, _ := p.Meta["filename"].(string)
+		asset, err := upload(ctx, decoded, mediaType, filename)
+		if err != nil {
+			return nil, fmt.Errorf("parts[%d]: upload inline data: %w", i, err)
+		}
+
+		meta := make(map[string]interface{}, len(p.Meta))
+		for k, v := range p.Meta {
+			meta[k] = v
+		}
+		delete(meta, "data")
+		meta["type"] = "asset"
+		out[i].Meta = meta
+		out[i].Asset = asset
+	}
+	return out, nil
+}
+
+// MessageContentHash returns a stable hash of the role and parts of a message, used to
+// detect messages stored twice. Parts are hashed by type, text and meta, with meta keys
+// sorted so their order does not matter; the content of uploaded files is not covered.
+func MessageContentHash(role string, parts []PartIn) string {
+	type hashedPart struct {
+		Type string                 `json:"type"`
+		Text string                 `json:"text,omitempty"`
+		Meta map[string]interface{} `json:"meta,omitempty"`
+	}
+	hashed := make([]hashedPart, 0, len(parts))
+	for _, p := range parts {
+		hashed = append(hashed, hashedPart{Type: p.Type, Text: p.Text, Meta: p.Meta})
+	}
+
+	// encoding/json writes map keys in sorted order, nested maps included
+	raw, err := json.Marshal(struct {
+		Role  string       `json:"role"`
+		Parts []hashedPart `json:"parts"`
+	}{Role: role, Parts: hashed})
+	if err != nil {
+		// Meta decoded from JSON always marshals; hash what we have otherwise
+		raw = []byte(fmt.Sprintf("%s%v", role, hashed))
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput) (*model.Message, error) {
-	parts := make([]model.Part, 0, len(in.Parts))
+	msg, err := s.buildMessage(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.CreateMessageWithAssets(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	s.publishStoredMessages(ctx, in.ProjectID, in.SessionID, msg)
+
+	return msg, nil
+}
+
+// IngestMessages stores already normalized messages of a bulk import in order, creating all
+// of them in a single transaction. Messages carrying file parts are rejected since the
+// import has no uploaded files to refer to. With skipDuplicates, messages whose content
+// hash the session already has, or that repeat an earlier message of in, are skipped and
+// left out of the returned messages.
+func (s *sessionService) IngestMessages(ctx context.Context, projectID uuid.UUID, sessionID uuid.UUID, in []IngestMessageIn, skipDuplicates bool) ([]*model.Message, error) {
+	if skipDuplicates {
+		var err error
+		if in, err = s.skipDuplicateMessages(ctx, sessionID, in); err != nil {
+			return nil, err
+		}
+	}
+
+	msgs := make([]*model.Message, 0, len(in))
+	for i, m := range in {
+		msg, err := s.buildMessage(ctx, StoreMessageInput{
+			ProjectID:   projectID,
+			SessionID:   sessionID,
+			Role:        m.Role,
+			Parts:       m.Parts,
+			MessageMeta: m.MessageMeta,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err := s.sessionRepo.CreateMessagesWithAssets(ctx, msgs); err != nil {
+		return nil, err
+	}
 
-	for idx, p := range in.Parts {
+	s.publishStoredMessages(ctx, projectID, sessionID, msgs...)
+
+	return msgs, nil
+}
+
+// skipDuplicateMessages drops the messages whose content hash the session already has or
+// that repeat an earlier message of in
+func (s *sessionService) skipDuplicateMessages(ctx context.Context, sessionID uuid.UUID, in []IngestMessageIn) ([]IngestMessageIn, error) {
+	hashes := make([]string, len(in))
+	for i, m := range in {
+		hashes[i] = MessageContentHash(m.Role, m.Parts)
+	}
+	existing, err := s.sessionRepo.ListMessageContentHashes(ctx, sessionID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("list message content hashes: %w", err)
+	}
+
+	seen := make(map[string]bool, len(existing)+len(in))
+	for _, h := range existing {
+		seen[h] = true
+	}
+	out := make([]IngestMessageIn, 0, len(in))
+	for i, m := range in {
+		if seen[hashes[i]] {
+			continue
+		}
+		seen[hashes[i]] = true
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// buildMessage uploads the message files and parts to S3 and returns the message to insert.
+// With normalizer.offloadInlineData, the base64 data of image and file parts is uploaded
+// as well and the parts reference it (see OffloadInlineData).
+func (s *sessionService) buildMessage(ctx context.Context, in StoreMessageInput) (*model.Message, error) {
+	partsIn := in.Parts
+	if s.cfg != nil && s.cfg.Normalizer.OffloadInlineData {
+		var err error
+		if partsIn, err = OffloadInlineData(ctx, in.Parts, s.uploadInlineData(in.ProjectID)); err != nil {
+			return nil, err
+		}
+	}
+
+	parts := make([]model.Part, 0, len(partsIn))
+
+	for idx, p := range partsIn {
 		part := model.Part{
-			Type: p.Type,
-			Meta: p.Meta,
+			Type:  p.Type,
+			ID:    p.ID,
+			Index: p.Index,
+			Meta:  p.Meta,
+		}
+
+		if p.Asset != nil {
+			if err := s.assetReferenceRepo.IncrementAssetRef(ctx, in.ProjectID, *p.Asset); err != nil {
+				return nil, fmt.Errorf("increment asset reference: %w", err)
+			}
+			part.Asset = p.Asset
+			part.Filename, _ = p.Meta["filename"].(string)
 		}
 
 		if p.FileField != "" {
@@ -222,7 +473,7 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 			}
 
 			// upload asset to S3
-			asset, err := s.s3.UploadFormFile(ctx, "assets/"+in.ProjectID.String(), fh)
+			asset, err := s.s3.UploadFormFile(ctx, s.s3.KeyPrefix(blob.KeyKindAssets, in.ProjectID, uuid.Nil), fh, blob.WithKeyLookup(assetKeyLookup(s.assetReferenceRepo, in.ProjectID, s.s3.Bucket)), blob.WithProject(in.ProjectID))
 			if err != nil {
 				return nil, fmt.Errorf("upload %s failed: %w", p.FileField, err)
 			}
@@ -243,7 +494,7 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 	}
 
 	// upload parts to S3 as JSON file
-	asset, err := s.s3.UploadJSON(ctx, "parts/"+in.ProjectID.String(), parts)
+	asset, err := s.s3.UploadJSON(ctx, s.s3.KeyPrefix(blob.KeyKindParts, in.ProjectID, uuid.Nil), parts, blob.WithKeyLookup(assetKeyLookup(s.assetReferenceRepo, in.ProjectID, s.s3.Bucket)), blob.WithProject(in.ProjectID))
 	if err != nil {
 		return nil, fmt.Errorf("upload parts to S3 failed: %w", err)
 	}
@@ -266,35 +517,46 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 		messageMeta = make(map[string]interface{})
 	}
 
-	msg := model.Message{
+	return &model.Message{
 		SessionID:      in.SessionID,
 		Role:           in.Role,
 		Meta:           datatypes.NewJSONType(messageMeta), // Store message-level metadata
 		PartsAssetMeta: datatypes.NewJSONType(*asset),
 		Parts:          parts,
-	}
+		ContentHash:    MessageContentHash(in.Role, in.Parts),
+	}, nil
+}
 
-	if err := s.sessionRepo.CreateMessageWithAssets(ctx, &msg); err != nil {
-		return nil, err
+// uploadInlineData uploads the inline data of a part as an asset of the project
+func (s *sessionService) uploadInlineData(projectID uuid.UUID) InlineDataUploader {
+	return func(ctx context.Context, data []byte, mediaType string, filename string) (*model.Asset, error) {
+		return s.s3.UploadBytes(ctx, s.s3.KeyPrefix(blob.KeyKindAssets, projectID, uuid.Nil), data, mediaType, filename, blob.WithKeyLookup(assetKeyLookup(s.assetReferenceRepo, projectID, s.s3.Bucket)), blob.WithProject(projectID))
 	}
+}
 
+// publishStoredMessages notifies the core about new messages unless task tracking is disabled
+func (s *sessionService) publishStoredMessages(ctx context.Context, projectID uuid.UUID, sessionID uuid.UUID, msgs ...*model.Message) {
 	// Check if task tracking is disabled for this session
-	disableTaskTracking, err := s.sessionRepo.GetDisableTaskTracking(ctx, in.SessionID)
+	disableTaskTracking, err := s.sessionRepo.GetDisableTaskTracking(ctx, sessionID)
 	if err != nil {
 		s.log.Error("failed to get disable_task_tracking for session", zap.Error(err))
 		// Continue without publishing, but don't fail the request
-	} else if s.publisher != nil && !disableTaskTracking {
-		// Only publish to MQ if task tracking is enabled
+		return
+	}
+	if s.publisher == nil || disableTaskTracking {
+		return
+	}
+
+	// Only publish to MQ if task tracking is enabled
+	for _, msg := range msgs {
 		if err := s.publisher.PublishJSON(ctx, s.cfg.RabbitMQ.ExchangeName.SessionMessage, s.cfg.RabbitMQ.RoutingKey.SessionMessageInsert, StoreMQPublishJSON{
-			ProjectID: in.ProjectID,
-			SessionID: in.SessionID,
+			ProjectID: projectID,
+			SessionID: sessionID,
 			MessageID: msg.ID,
 		}); err != nil {
 			s.log.Error("publish session message", zap.Error(err))
 		}
 	}
-
-	return &msg, nil
 }
 
 type GetMessagesInput struct {
@@ -389,27 +651,151 @@ func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (
 
 	// Generate presigned URLs for assets if requested
 	if in.WithAssetPublicURL && s.s3 != nil {
-		out.PublicURLs = make(map[string]PublicURL)
-		for _, m := range out.Items {
-			for _, p := range m.Parts {
-				if p.Asset == nil {
-					continue
-				}
-				url, err := s.s3.PresignGet(ctx, p.Asset.S3Key, in.AssetExpire)
-				if err != nil {
-					return nil, fmt.Errorf("get presigned url for asset %s: %w", p.Asset.S3Key, err)
-				}
-				out.PublicURLs[p.Asset.SHA256] = PublicURL{
-					URL:      url,
-					ExpireAt: time.Now().Add(in.AssetExpire),
-				}
+		out.PublicURLs, err = s.presignPartAssets(ctx, out.Items, in.AssetExpire)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// presignPartAssets generates presigned URLs for every asset referenced by the message parts
+func (s *sessionService) presignPartAssets(ctx context.Context, msgs []model.Message, expire time.Duration) (map[string]PublicURL, error) {
+	urls := make(map[string]PublicURL)
+	for _, m := range msgs {
+		for _, p := range m.Parts {
+			if p.Asset == nil {
+				continue
+			}
+			url, err := s.s3.PresignGet(ctx, p.Asset.S3Key, expire)
+			if err != nil {
+				return nil, fmt.Errorf("get presigned url for asset %s: %w", p.Asset.S3Key, err)
+			}
+			urls[p.Asset.SHA256] = PublicURL{
+				URL:      url,
+				ExpireAt: time.Now().Add(expire),
 			}
 		}
 	}
+	return urls, nil
+}
+
+// ErrPartAssetNotFound is returned when a message has no part at the given index, or the
+// part is not backed by an asset of the project
+var ErrPartAssetNotFound = errors.New("message part has no asset")
+
+type GetPartContentInput struct {
+	ProjectID uuid.UUID     `json:"project_id"`
+	SessionID uuid.UUID     `json:"session_id"`
+	MessageID uuid.UUID     `json:"message_id"`
+	Index     int           `json:"index"`
+	Expire    time.Duration `json:"expire"`
+}
+
+// GetPartContentURL returns a presigned URL to the asset behind a part of a message, in the
+// bucket recorded for it. The asset must be referenced by the project in that bucket, so a
+// part can only serve content the project stored itself.
+func (s *sessionService) GetPartContentURL(ctx context.Context, in GetPartContentInput) (*PublicURL, error) {
+	msg, err := s.sessionRepo.GetMessage(ctx, in.SessionID, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := s.loadPartsForMessage(ctx, msg.PartsAssetMeta.Data())
+	if in.Index < 0 || in.Index >= len(parts) || parts[in.Index].Asset == nil {
+		return nil, fmt.Errorf("%w: part %d of message %s", ErrPartAssetNotFound, in.Index, in.MessageID)
+	}
+	asset := parts[in.Index].Asset
+
+	key, err := s.assetReferenceRepo.GetS3KeyBySHA256(ctx, in.ProjectID, asset.Bucket, asset.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("get asset reference %s: %w", asset.SHA256, err)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%w: asset %s is not referenced by the project", ErrPartAssetNotFound, asset.SHA256)
+	}
+
+	store := s.s3
+	if s.projectS3 != nil {
+		if store, err = s.projectS3.ForObject(ctx, in.ProjectID, asset.Bucket); err != nil {
+			return nil, err
+		}
+	}
+	url, err := store.PresignGet(ctx, asset.S3Key, in.Expire)
+	if err != nil {
+		return nil, fmt.Errorf("get presigned url for asset %s: %w", asset.S3Key, err)
+	}
+	return &PublicURL{URL: url, ExpireAt: time.Now().Add(in.Expire)}, nil
+}
+
+type ExportMessagesInput struct {
+	SessionID   uuid.UUID     `json:"session_id"`
+	Limit       int           `json:"limit"`
+	Since       *time.Time    `json:"since,omitempty"`
+	AssetExpire time.Duration `json:"asset_expire"`
+}
+
+// ExportMessages returns the session transcript from old to new, ready to be replayed.
+// Since drops messages created before the given time, and Limit keeps only the most
+// recent messages. Leading tool results whose tool calls were cut off are dropped so
+// the exported transcript never starts with an unpaired tool result.
+func (s *sessionService) ExportMessages(ctx context.Context, in ExportMessagesInput) (*GetMessagesOutput, error) {
+	msgs, err := s.sessionRepo.ListAllMessagesBySession(ctx, in.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].CreatedAt.Equal(msgs[j].CreatedAt) {
+			return msgs[i].ID.String() < msgs[j].ID.String()
+		}
+		return msgs[i].CreatedAt.Before(msgs[j].CreatedAt)
+	})
+
+	// Filter before loading parts so we only download what is exported
+	if in.Since != nil {
+		start := sort.Search(len(msgs), func(i int) bool {
+			return !msgs[i].CreatedAt.Before(*in.Since)
+		})
+		msgs = msgs[start:]
+	}
+	if in.Limit > 0 && len(msgs) > in.Limit {
+		msgs = msgs[len(msgs)-in.Limit:]
+	}
+
+	for i, m := range msgs {
+		msgs[i].Parts = s.loadPartsForMessage(ctx, m.PartsAssetMeta.Data())
+	}
+	msgs = trimUnpairedToolResults(msgs)
+
+	out := &GetMessagesOutput{Items: msgs}
+	if s.s3 != nil {
+		out.PublicURLs, err = s.presignPartAssets(ctx, msgs, in.AssetExpire)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return out, nil
 }
 
+// trimUnpairedToolResults drops leading messages that only carry tool results,
+// since the tool calls they answer were cut off by the export filters
+func trimUnpairedToolResults(msgs []model.Message) []model.Message {
+	for i, m := range msgs {
+		if len(m.Parts) == 0 {
+			return msgs[i:]
+		}
+		for _, p := range m.Parts {
+			if p.Type != "tool-result" {
+				return msgs[i:]
+			}
+		}
+	}
+	return msgs[:0]
+}
+
 // cachePartsInRedis stores message parts in Redis with a fixed TTL
 func (s *sessionService) cachePartsInRedis(ctx context.Context, sha256 string, parts []model.Part) error {
 	if s.redis == nil {
@@ -511,13 +897,17 @@ func (s *sessionService) GetAllMessages(ctx context.Context, sessionID uuid.UUID
 		msgs[i].Parts = s.loadPartsForMessage(ctx, meta)
 	}
 
-	// Sort messages from old to new (ascending by created_at)
+	sortMessagesByTime(msgs)
+
+	return msgs, nil
+}
+
+// sortMessagesByTime sorts messages from old to new (ascending by created_at)
+func sortMessagesByTime(msgs []model.Message) {
 	sort.Slice(msgs, func(i, j int) bool {
 		if msgs[i].CreatedAt.Equal(msgs[j].CreatedAt) {
 			return msgs[i].ID.String() < msgs[j].ID.String()
 		}
 		return msgs[i].CreatedAt.Before(msgs[j].CreatedAt)
 	})
-
-	return msgs, nil
 }
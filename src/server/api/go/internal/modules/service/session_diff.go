@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// maxSessionDiffMessages bounds the messages of each session compared by DiffSessions
+const maxSessionDiffMessages = 1000
+
+// ErrSessionDiffTooLarge is returned when a session has too many messages to be diffed
+var ErrSessionDiffTooLarge = fmt.Errorf("sessions with more than %d messages cannot be diffed", maxSessionDiffMessages)
+
+// SessionDiffOp is the kind of a difference between two sessions
+type SessionDiffOp string
+
+const (
+	// SessionDiffAdded is a message only in session b
+	SessionDiffAdded SessionDiffOp = "added"
+	// SessionDiffRemoved is a message only in session a
+	SessionDiffRemoved SessionDiffOp = "removed"
+	// SessionDiffChanged is a message of session a replaced by a message of the same role in session b
+	SessionDiffChanged SessionDiffOp = "changed"
+)
+
+// SessionDiffEntry is a difference between the messages of two sessions. IndexA and IndexB
+// are positions in the chronological messages of each session: removed entries only have
+// the A side, added entries only the B side.
+type SessionDiffEntry struct {
+	Op     SessionDiffOp  `json:"op"`
+	IndexA *int           `json:"index_a,omitempty"`
+	IndexB *int           `json:"index_b,omitempty"`
+	A      *model.Message `json:"a,omitempty"`
+	B      *model.Message `json:"b,omitempty"`
+}
+
+// SessionDiff lists the differences between the messages of two sessions, in the order
+// of the conversation
+type SessionDiff struct {
+	SessionA  uuid.UUID          `json:"session_a"`
+	SessionB  uuid.UUID          `json:"session_b"`
+	CountA    int                `json:"count_a"`
+	CountB    int                `json:"count_b"`
+	Unchanged int                `json:"unchanged"`
+	Entries   []SessionDiffEntry `json:"entries"`
+}
+
+// DiffSessions compares the messages of two sessions. Messages are matched by
+// MessageContentHash, keeping their order, so a message only differs when its role or
+// parts do; message meta, IDs and timestamps are not compared. Sessions with more than
+// maxSessionDiffMessages messages return ErrSessionDiffTooLarge.
+func (s *sessionService) DiffSessions(ctx context.Context, sessionA uuid.UUID, sessionB uuid.UUID) (*SessionDiff, error) {
+	msgsA, err := s.listDiffMessages(ctx, sessionA)
+	if err != nil {
+		return nil, err
+	}
+	msgsB, err := s.listDiffMessages(ctx, sessionB)
+	if err != nil {
+		return nil, err
+	}
+	return diffMessages(sessionA, sessionB, msgsA, msgsB), nil
+}
+
+// listDiffMessages returns the messages of a session with their parts, from old to new
+func (s *sessionService) listDiffMessages(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error) {
+	msgs, err := s.sessionRepo.ListAllMessagesBySession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if len(msgs) > maxSessionDiffMessages {
+		return nil, fmt.Errorf("%w: session %s has %d", ErrSessionDiffTooLarge, sessionID, len(msgs))
+	}
+	for i := range msgs {
+		msgs[i].Parts = s.loadPartsForMessage(ctx, msgs[i].PartsAssetMeta.Data())
+	}
+	sortMessagesByTime(msgs)
+	return msgs, nil
+}
+
+// diffMessages aligns the messages of two sessions on their longest common subsequence of
+// content hashes. Between two aligned messages, removed and added messages of the same role
+// are paired up, in order, as changed.
+func diffMessages(sessionA uuid.UUID, sessionB uuid.UUID, a []model.Message, b []model.Message) *SessionDiff {
+	ha, hb := messageHashes(a), messageHashes(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of ha[i:] and hb[j:]
+	lcs := make([][]int32, len(ha)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(hb)+1)
+	}
+	for i := len(ha) - 1; i >= 0; i-- {
+		for j := len(hb) - 1; j >= 0; j-- {
+			if ha[i] == hb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := &SessionDiff{SessionA: sessionA, SessionB: sessionB, CountA: len(a), CountB: len(b), Entries: []SessionDiffEntry{}}
+	var removed, added []int
+	flush := func() {
+		ri, ai := 0, 0
+		for ri < len(removed) || ai < len(added) {
+			switch {
+			case ri < len(removed) && ai < len(added) && a[removed[ri]].Role == b[added[ai]].Role:
+				diff.Entries = append(diff.Entries, SessionDiffEntry{Op: SessionDiffChanged, IndexA: &removed[ri], IndexB: &added[ai], A: &a[removed[ri]], B: &b[added[ai]]})
+				ri++
+				ai++
+			case ri < len(removed):
+				diff.Entries = append(diff.Entries, SessionDiffEntry{Op: SessionDiffRemoved, IndexA: &removed[ri], A: &a[removed[ri]]})
+				ri++
+			default:
+				diff.Entries = append(diff.Entries, SessionDiffEntry{Op: SessionDiffAdded, IndexB: &added[ai], B: &b[added[ai]]})
+				ai++
+			}
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(ha) && j < len(hb) {
+		switch {
+		case ha[i] == hb[j]:
+			flush()
+			diff.Unchanged++
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+	for ; i < len(ha); i++ {
+		removed = append(removed, i)
+	}
+	for ; j < len(hb); j++ {
+		added = append(added, j)
+	}
+	flush()
+	return diff
+}
+
+// messageHashes returns the content hash of every message, computed from its stored parts
+// so that messages stored before content hashing compare too
+func messageHashes(msgs []model.Message) []string {
+	hashes := make([]string, len(msgs))
+	for i, m := range msgs {
+		parts := make([]PartIn, 0, len(m.Parts))
+		for _, p := range m.Parts {
+			parts = append(parts, PartIn{Type: p.Type, Text: p.Text, Meta: p.Meta})
+		}
+		hashes[i] = MessageContentHash(m.Role, parts)
+	}
+	return hashes
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
+)
+
+// SessionSummary holds the message statistics of a session
+type SessionSummary struct {
+	MessageCount  int64            `json:"message_count"`
+	RoleCounts    map[string]int64 `json:"role_counts"`
+	TotalTokens   int              `json:"total_tokens"`
+	ToolCallCount int              `json:"tool_call_count"`
+	ArtifactCount int              `json:"artifact_count"` // Parts with an uploaded file, e.g. images
+}
+
+// GetSummary returns the message statistics of a session. Message counts come from an
+// aggregate query; tokens, tool calls and artifacts need the parts of every message, which
+// are served from the parts cache when possible. A nil tokenizer uses tokenizer.Heuristic.
+func (s *sessionService) GetSummary(ctx context.Context, sessionID uuid.UUID, tok tokenizer.Tokenizer) (*SessionSummary, error) {
+	roleCounts, err := s.sessionRepo.CountMessagesByRole(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	summary := &SessionSummary{RoleCounts: roleCounts}
+	for _, n := range roleCounts {
+		summary.MessageCount += n
+	}
+	if summary.MessageCount == 0 {
+		return summary, nil
+	}
+
+	msgs, err := s.sessionRepo.ListAllMessagesBySession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	for i := range msgs {
+		msgs[i].Parts = s.loadPartsForMessage(ctx, msgs[i].PartsAssetMeta.Data())
+	}
+	if err := summary.addParts(msgs, tok); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// addParts adds the tokens, tool calls and artifacts of the parts of msgs to the summary
+func (sum *SessionSummary) addParts(msgs []model.Message, tok tokenizer.Tokenizer) error {
+	if tok == nil {
+		tok = tokenizer.Heuristic{}
+	}
+	for _, msg := range msgs {
+		n, err := tok.CountMessageTokens(msg)
+		if err != nil {
+			return fmt.Errorf("count tokens of message %s: %w", msg.ID, err)
+		}
+		sum.TotalTokens += n
+
+		for _, p := range msg.Parts {
+			if p.Type == "tool-call" {
+				sum.ToolCallCount++
+			}
+			if p.Asset != nil {
+				sum.ArtifactCount++
+			}
+		}
+	}
+	return nil
+}
@@ -2,16 +2,32 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockSessionRepo is a mock implementation of SessionRepo
@@ -52,6 +68,11 @@ func (m *MockSessionRepo) CreateMessageWithAssets(ctx context.Context, msg *mode
 	return args.Error(0)
 }
 
+func (m *MockSessionRepo) CreateMessagesWithAssets(ctx context.Context, msgs []*model.Message) error {
+	args := m.Called(ctx, msgs)
+	return args.Error(0)
+}
+
 func (m *MockSessionRepo) ListBySessionWithCursor(ctx context.Context, sessionID uuid.UUID, afterT time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]model.Message, error) {
 	args := m.Called(ctx, sessionID, afterT, afterID, limit, timeDesc)
 	if args.Get(0) == nil {
@@ -76,6 +97,30 @@ func (m *MockSessionRepo) ListAllMessagesBySession(ctx context.Context, sessionI
 	return args.Get(0).([]model.Message), args.Error(1)
 }
 
+func (m *MockSessionRepo) GetMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) (*model.Message, error) {
+	args := m.Called(ctx, sessionID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Message), args.Error(1)
+}
+
+func (m *MockSessionRepo) ListMessageContentHashes(ctx context.Context, sessionID uuid.UUID, hashes []string) ([]string, error) {
+	args := m.Called(ctx, sessionID, hashes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockSessionRepo) CountMessagesByRole(ctx context.Context, sessionID uuid.UUID) (map[string]int64, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
 // MockAssetReferenceRepo is a mock implementation of AssetReferenceRepo
 type MockAssetReferenceRepo struct {
 	mock.Mock
@@ -101,6 +146,47 @@ func (m *MockAssetReferenceRepo) BatchDecrementAssetRefs(ctx context.Context, pr
 	return args.Error(0)
 }
 
+func (m *MockAssetReferenceRepo) GetS3KeyBySHA256(ctx context.Context, projectID uuid.UUID, bucket string, sha256 string) (string, error) {
+	args := m.Called(ctx, projectID, bucket, sha256)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAssetReferenceRepo) TouchAssetRef(ctx context.Context, projectID uuid.UUID, sha256 string) error {
+	args := m.Called(ctx, projectID, sha256)
+	return args.Error(0)
+}
+
+func (m *MockAssetReferenceRepo) ListOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) ([]model.AssetReference, error) {
+	args := m.Called(ctx, projectID, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.AssetReference), args.Error(1)
+}
+
+func (m *MockAssetReferenceRepo) DeleteOrphaned(ctx context.Context, projectID uuid.UUID, before time.Time) (int, error) {
+	args := m.Called(ctx, projectID, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAssetReferenceRepo) ListAssetReferences(ctx context.Context, projectID uuid.UUID, filter repo.AssetReferenceFilter) ([]model.AssetReference, error) {
+	args := m.Called(ctx, projectID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.AssetReference), args.Error(1)
+}
+
+func (m *MockAssetReferenceRepo) SumAssetReferences(ctx context.Context, projectID uuid.UUID, filter repo.AssetReferenceFilter) (int64, int64, error) {
+	args := m.Called(ctx, projectID, filter)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAssetReferenceRepo) DeleteObjects(ctx context.Context, projectID uuid.UUID, assets []model.Asset) error {
+	args := m.Called(ctx, projectID, assets)
+	return args.Error(0)
+}
+
 // MockBlobService is a mock implementation of blob service
 type MockBlobService struct {
 	mock.Mock
@@ -185,7 +271,7 @@ func TestSessionService_Create(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			err := service.Create(ctx, tt.session)
 
@@ -263,7 +349,7 @@ func TestSessionService_Delete(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			err := service.Delete(ctx, tt.projectID, tt.sessionID)
 
@@ -348,7 +434,7 @@ func TestSessionService_GetByID(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			result, err := service.GetByID(ctx, tt.session)
 
@@ -420,7 +506,7 @@ func TestSessionService_UpdateByID(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			err := service.UpdateByID(ctx, tt.session)
 
@@ -558,7 +644,7 @@ func TestSessionService_List(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			result, err := service.List(ctx, tt.input)
 
@@ -838,7 +924,7 @@ func TestSessionService_GetMessages(t *testing.T) {
 				},
 			}
 			// Note: blob is nil in test, so GetMessages will skip DownloadJSON and PresignGet
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			result, err := service.GetMessages(ctx, tt.input)
 
@@ -998,7 +1084,7 @@ func TestSessionService_GetMessages_SortOrder(t *testing.T) {
 					},
 				},
 			}
-			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, cfg, nil)
+			service := NewSessionService(repo, mockAssetRefRepo, logger, nil, nil, nil, cfg, nil)
 
 			result, err := service.GetMessages(ctx, tt.input)
 
@@ -1032,3 +1118,516 @@ func TestSessionService_GetMessages_SortOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionService_ExportMessages(t *testing.T) {
+	ctx := context.Background()
+	sessionID := uuid.New()
+	now := time.Now()
+
+	msg1ID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	msg2ID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	msg3ID := uuid.MustParse("00000000-0000-0000-0000-000000000003")
+
+	repoMessages := func() []model.Message {
+		return []model.Message{
+			{ID: msg3ID, SessionID: sessionID, Role: "user", CreatedAt: now.Add(-1 * time.Hour)},
+			{ID: msg1ID, SessionID: sessionID, Role: "user", CreatedAt: now.Add(-3 * time.Hour)},
+			{ID: msg2ID, SessionID: sessionID, Role: "assistant", CreatedAt: now.Add(-2 * time.Hour)},
+		}
+	}
+	since := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name          string
+		input         ExportMessagesInput
+		expectedOrder []uuid.UUID
+	}{
+		{
+			name:          "all messages from old to new",
+			input:         ExportMessagesInput{SessionID: sessionID},
+			expectedOrder: []uuid.UUID{msg1ID, msg2ID, msg3ID},
+		},
+		{
+			name:          "limit keeps the most recent messages",
+			input:         ExportMessagesInput{SessionID: sessionID, Limit: 2},
+			expectedOrder: []uuid.UUID{msg2ID, msg3ID},
+		},
+		{
+			name:          "since drops older messages",
+			input:         ExportMessagesInput{SessionID: sessionID, Since: &since},
+			expectedOrder: []uuid.UUID{msg2ID, msg3ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockSessionRepo{}
+			repo.On("ListAllMessagesBySession", ctx, sessionID).Return(repoMessages(), nil)
+
+			service := NewSessionService(repo, &MockAssetReferenceRepo{}, zap.NewNop(), nil, nil, nil, &config.Config{}, nil)
+			result, err := service.ExportMessages(ctx, tt.input)
+
+			assert.NoError(t, err)
+			assert.Len(t, result.Items, len(tt.expectedOrder))
+			for i, expectedID := range tt.expectedOrder {
+				assert.Equal(t, expectedID, result.Items[i].ID)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTrimUnpairedToolResults(t *testing.T) {
+	toolResult := model.Message{Role: "user", Parts: []model.Part{
+		{Type: "tool-result", Meta: map[string]interface{}{"tool_call_id": "call_1"}},
+	}}
+	assistant := model.Message{Role: "assistant", Parts: []model.Part{{Type: "text", Text: "done"}}}
+
+	msgs := trimUnpairedToolResults([]model.Message{toolResult, toolResult, assistant, toolResult})
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, "assistant", msgs[0].Role)
+
+	assert.Empty(t, trimUnpairedToolResults([]model.Message{toolResult}))
+}
+
+func TestCompactParts(t *testing.T) {
+	image := PartIn{Type: "image", Meta: map[string]interface{}{"url": "https://example.com/a.png"}}
+	toolCall := PartIn{Type: "tool-call", Meta: map[string]interface{}{"id": "call_1", "name": "search", "arguments": "{}"}}
+	refusal := PartIn{Type: "text", Text: "no", Meta: map[string]interface{}{"is_refusal": true}}
+
+	tests := []struct {
+		name  string
+		input []PartIn
+		want  []PartIn
+	}{
+		{
+			name:  "empty input",
+			input: []PartIn{},
+			want:  []PartIn{},
+		},
+		{
+			name:  "single part",
+			input: []PartIn{{Type: "text", Text: "hello"}},
+			want:  []PartIn{{Type: "text", Text: "hello"}},
+		},
+		{
+			name:  "adjacent text parts are merged",
+			input: []PartIn{{Type: "text", Text: "a"}, {Type: "text", Text: "b"}, {Type: "text", Text: "c"}},
+			want:  []PartIn{{Type: "text", Text: "a\nb\nc"}},
+		},
+		{
+			name:  "leading and trailing empty parts are dropped",
+			input: []PartIn{{Type: "text"}, {Type: "text", Text: "a"}, {Type: "text", Text: "b"}, {Type: "text"}},
+			want:  []PartIn{{Type: "text", Text: "a\nb"}},
+		},
+		{
+			name:  "only empty parts",
+			input: []PartIn{{Type: "text"}, {Type: "text"}},
+			want:  []PartIn{},
+		},
+		{
+			name:  "non-text parts split text runs",
+			input: []PartIn{{Type: "text", Text: "a"}, image, {Type: "text", Text: "b"}, {Type: "text", Text: "c"}, toolCall},
+			want:  []PartIn{{Type: "text", Text: "a"}, image, {Type: "text", Text: "b\nc"}, toolCall},
+		},
+		{
+			name:  "text parts with meta are kept",
+			input: []PartIn{{Type: "text", Text: "a"}, refusal, {Type: "text", Text: "b"}},
+			want:  []PartIn{{Type: "text", Text: "a"}, refusal, {Type: "text", Text: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CompactParts(tt.input))
+		})
+	}
+}
+
+func TestAssignPartIDs(t *testing.T) {
+	newParts := func() []PartIn {
+		return []PartIn{
+			{Type: "text", Text: "Weather?"},
+			{Type: "tool-call", Meta: map[string]interface{}{"id": "call_1", "name": "weather", "arguments": "{}"}},
+			{Type: "tool-result", Text: "Sunny", Meta: map[string]interface{}{"tool_call_id": "call_1"}},
+			{Type: "text", Text: "Weather?"},
+		}
+	}
+
+	parts := AssignPartIDs(newParts())
+	ids := map[string]bool{}
+	for i, p := range parts {
+		assert.Equal(t, i, p.Index)
+		assert.NotEmpty(t, p.ID)
+		ids[p.ID] = true
+	}
+	assert.Len(t, ids, 4, "repeated content at another index gets another ID")
+	assert.Equal(t, parts, AssignPartIDs(newParts()), "IDs are deterministic")
+
+	t.Run("tool parts are keyed by their call", func(t *testing.T) {
+		moved := AssignPartIDs([]PartIn{newParts()[2]})
+		assert.Equal(t, parts[2].ID, moved[0].ID)
+		assert.Equal(t, 0, moved[0].Index)
+	})
+
+	t.Run("explicit IDs are kept", func(t *testing.T) {
+		explicit := newParts()
+		explicit[0].ID = "mine"
+		assert.Equal(t, "mine", AssignPartIDs(explicit)[0].ID)
+	})
+}
+
+func TestOffloadInlineData(t *testing.T) {
+	ctx := context.Background()
+	png := []byte("\x89PNG fake image")
+	newParts := func() []PartIn {
+		return []PartIn{
+			{Type: "text", Text: "What is in this image?"},
+			{Type: "image", Meta: map[string]interface{}{"type": "base64", "media_type": "image/png", "data": base64.StdEncoding.EncodeToString(png)}},
+			{Type: "image", Meta: map[string]interface{}{"type": "url", "url": "https://example.com/cat.png"}},
+			{Type: "file", Meta: map[string]interface{}{"type": "base64", "media_type": "application/pdf", "data": "not base64!", "filename": "doc.pdf"}},
+		}
+	}
+
+	var uploaded [][]byte
+	upload := func(ctx context.Context, data []byte, mediaType string, filename string) (*model.Asset, error) {
+		uploaded = append(uploaded, data)
+		sum := sha256.Sum256(data)
+		return &model.Asset{S3Key: "assets/" + hex.EncodeToString(sum[:]), SHA256: hex.EncodeToString(sum[:]), MIME: mediaType, SizeB: int64(len(data))}, nil
+	}
+
+	parts := newParts()
+	out, err := OffloadInlineData(ctx, parts, upload)
+	assert.NoError(t, err)
+	assert.Len(t, out, 4)
+	assert.Equal(t, [][]byte{png}, uploaded)
+
+	// The base64 image references its uploaded asset and carries no data
+	image := out[1]
+	if assert.NotNil(t, image.Asset) {
+		assert.Equal(t, "image/png", image.Asset.MIME)
+		assert.Equal(t, int64(len(png)), image.Asset.SizeB)
+	}
+	assert.NotContains(t, image.Meta, "data")
+	assert.Equal(t, "asset", image.Meta["type"])
+	assert.Equal(t, "image/png", image.Meta["media_type"])
+
+	// Other parts and data that is not base64 are left as they are
+	assert.Equal(t, parts[0], out[0])
+	assert.Equal(t, parts[2], out[2])
+	assert.Nil(t, out[3].Asset)
+	assert.Equal(t, "not base64!", out[3].Meta["data"])
+
+	// The input keeps its data, it is what the content hash covers
+	assert.Equal(t, newParts(), parts)
+
+	t.Run("upload error", func(t *testing.T) {
+		_, err := OffloadInlineData(ctx, newParts(), func(context.Context, []byte, string, string) (*model.Asset, error) {
+			return nil, errors.New("s3 down")
+		})
+		assert.ErrorContains(t, err, "parts[1]: upload inline data: s3 down")
+	})
+}
+
+func TestMessageContentHash(t *testing.T) {
+	newParts := func() []PartIn {
+		return []PartIn{
+			{Type: "text", Text: "Weather?"},
+			{Type: "tool-call", Meta: map[string]interface{}{"id": "call_1", "name": "weather", "arguments": `{"city":"Paris"}`}},
+		}
+	}
+
+	t.Run("identical messages hash equal", func(t *testing.T) {
+		assert.Equal(t, MessageContentHash("user", newParts()), MessageContentHash("user", newParts()))
+	})
+
+	t.Run("meta key order does not matter", func(t *testing.T) {
+		// Go randomizes map iteration, so build the maps with opposite insertion orders
+		a := map[string]interface{}{}
+		b := map[string]interface{}{}
+		keys := []string{"id", "name", "arguments", "nested"}
+		values := map[string]interface{}{"id": "call_1", "name": "weather", "arguments": "{}", "nested": map[string]interface{}{"x": 1.0, "y": 2.0}}
+		for i := range keys {
+			a[keys[i]] = values[keys[i]]
+			b[keys[len(keys)-1-i]] = values[keys[len(keys)-1-i]]
+		}
+		for i := 0; i < 20; i++ {
+			assert.Equal(t,
+				MessageContentHash("assistant", []PartIn{{Type: "tool-call", Meta: a}}),
+				MessageContentHash("assistant", []PartIn{{Type: "tool-call", Meta: b}}))
+		}
+	})
+
+	t.Run("content changes the hash", func(t *testing.T) {
+		base := MessageContentHash("user", newParts())
+		assert.NotEqual(t, base, MessageContentHash("assistant", newParts()))
+
+		changedText := newParts()
+		changedText[0].Text = "Weather in Paris?"
+		assert.NotEqual(t, base, MessageContentHash("user", changedText))
+
+		changedMeta := newParts()
+		changedMeta[1].Meta["arguments"] = `{"city":"Rome"}`
+		assert.NotEqual(t, base, MessageContentHash("user", changedMeta))
+
+		reordered := newParts()
+		reordered[0], reordered[1] = reordered[1], reordered[0]
+		assert.NotEqual(t, base, MessageContentHash("user", reordered))
+	})
+
+	t.Run("nil and empty meta hash equal", func(t *testing.T) {
+		assert.Equal(t,
+			MessageContentHash("user", []PartIn{{Type: "text", Text: "hi"}}),
+			MessageContentHash("user", []PartIn{{Type: "text", Text: "hi", Meta: map[string]interface{}{}}}))
+	})
+}
+
+func TestSessionService_SkipDuplicateMessages(t *testing.T) {
+	ctx := context.Background()
+	sessionID := uuid.New()
+	msg := func(role, text string) IngestMessageIn {
+		return IngestMessageIn{Role: role, Parts: []PartIn{{Type: "text", Text: text}}}
+	}
+	stored := msg("user", "already stored")
+	in := []IngestMessageIn{stored, msg("user", "hello"), msg("assistant", "hi"), msg("user", "hello")}
+
+	repo := &MockSessionRepo{}
+	repo.On("ListMessageContentHashes", ctx, sessionID, mock.AnythingOfType("[]string")).
+		Return([]string{MessageContentHash(stored.Role, stored.Parts)}, nil)
+	s := &sessionService{sessionRepo: repo}
+
+	out, err := s.skipDuplicateMessages(ctx, sessionID, in)
+	assert.NoError(t, err)
+	assert.Equal(t, []IngestMessageIn{msg("user", "hello"), msg("assistant", "hi")}, out)
+}
+
+func TestSessionService_GetSummary(t *testing.T) {
+	ctx := context.Background()
+	sessionID := uuid.New()
+
+	t.Run("counts messages per role", func(t *testing.T) {
+		repo := &MockSessionRepo{}
+		repo.On("CountMessagesByRole", ctx, sessionID).Return(map[string]int64{"user": 3, "assistant": 2, "system": 1}, nil)
+		repo.On("ListAllMessagesBySession", ctx, sessionID).Return([]model.Message{}, nil)
+		s := &sessionService{sessionRepo: repo}
+
+		summary, err := s.GetSummary(ctx, sessionID, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), summary.MessageCount)
+		assert.Equal(t, map[string]int64{"user": 3, "assistant": 2, "system": 1}, summary.RoleCounts)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("empty session does not load messages", func(t *testing.T) {
+		repo := &MockSessionRepo{}
+		repo.On("CountMessagesByRole", ctx, sessionID).Return(map[string]int64{}, nil)
+		s := &sessionService{sessionRepo: repo}
+
+		summary, err := s.GetSummary(ctx, sessionID, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &SessionSummary{RoleCounts: map[string]int64{}}, summary)
+		repo.AssertNotCalled(t, "ListAllMessagesBySession", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDiffMessages(t *testing.T) {
+	sessionA, sessionB := uuid.New(), uuid.New()
+	msg := func(role string, text string) model.Message {
+		return model.Message{ID: uuid.New(), Role: role, Parts: []model.Part{{Type: "text", Text: text}}}
+	}
+	conversation := func() []model.Message {
+		return []model.Message{
+			msg("user", "hi"),
+			msg("assistant", "hello, how can I help?"),
+			msg("user", "what is 2+2?"),
+			msg("assistant", "4"),
+			msg("user", "thanks"),
+		}
+	}
+	// ops lists the op and indexes of each entry, e.g. "changed 3 3"
+	ops := func(diff *SessionDiff) []string {
+		out := []string{}
+		for _, e := range diff.Entries {
+			op := string(e.Op)
+			for _, idx := range []*int{e.IndexA, e.IndexB} {
+				if idx == nil {
+					op += " -"
+				} else {
+					op += fmt.Sprintf(" %d", *idx)
+				}
+			}
+			out = append(out, op)
+		}
+		return out
+	}
+
+	t.Run("single differing message", func(t *testing.T) {
+		a, b := conversation(), conversation()
+		b[3] = msg("assistant", "four")
+
+		diff := diffMessages(sessionA, sessionB, a, b)
+		assert.Equal(t, sessionA, diff.SessionA)
+		assert.Equal(t, sessionB, diff.SessionB)
+		assert.Equal(t, 5, diff.CountA)
+		assert.Equal(t, 5, diff.CountB)
+		assert.Equal(t, 4, diff.Unchanged)
+		assert.Equal(t, []string{"changed 3 3"}, ops(diff))
+		assert.Equal(t, "4", diff.Entries[0].A.Parts[0].Text)
+		assert.Equal(t, "four", diff.Entries[0].B.Parts[0].Text)
+	})
+
+	t.Run("identical sessions", func(t *testing.T) {
+		diff := diffMessages(sessionA, sessionB, conversation(), conversation())
+		assert.Equal(t, 5, diff.Unchanged)
+		assert.Empty(t, diff.Entries)
+	})
+
+	t.Run("added and removed", func(t *testing.T) {
+		a, b := conversation(), conversation()
+		b = append(b[:1], b[2:]...)
+		b = append(b, msg("assistant", "you're welcome"))
+
+		diff := diffMessages(sessionA, sessionB, a, b)
+		assert.Equal(t, 4, diff.Unchanged)
+		assert.Equal(t, []string{"removed 1 -", "added - 4"}, ops(diff))
+	})
+
+	t.Run("a different role is not a change", func(t *testing.T) {
+		a, b := conversation(), conversation()
+		b[4] = msg("assistant", "anything else?")
+
+		diff := diffMessages(sessionA, sessionB, a, b)
+		assert.Equal(t, []string{"removed 4 -", "added - 4"}, ops(diff))
+	})
+
+	t.Run("meta and ids are not compared", func(t *testing.T) {
+		a, b := conversation(), conversation()
+		b[0].Meta = datatypes.NewJSONType(map[string]any{"source": "replay"})
+		b[0].Parts[0].ID = "part-1"
+
+		diff := diffMessages(sessionA, sessionB, a, b)
+		assert.Empty(t, diff.Entries)
+	})
+
+	t.Run("empty session", func(t *testing.T) {
+		diff := diffMessages(sessionA, sessionB, nil, conversation()[:2])
+		assert.Equal(t, []string{"added - 0", "added - 1"}, ops(diff))
+	})
+}
+
+func TestSessionService_DiffSessions_TooLarge(t *testing.T) {
+	ctx := context.Background()
+	sessionA, sessionB := uuid.New(), uuid.New()
+	repo := &MockSessionRepo{}
+	repo.On("ListAllMessagesBySession", ctx, sessionA).Return(make([]model.Message, maxSessionDiffMessages+1), nil)
+	s := &sessionService{sessionRepo: repo}
+
+	_, err := s.DiffSessions(ctx, sessionA, sessionB)
+	assert.ErrorIs(t, err, ErrSessionDiffTooLarge)
+	repo.AssertNotCalled(t, "ListAllMessagesBySession", ctx, sessionB)
+}
+
+func TestSessionService_GetPartContentURL_NotFound(t *testing.T) {
+	ctx := context.Background()
+	sessionID, messageID := uuid.New(), uuid.New()
+	in := GetPartContentInput{ProjectID: uuid.New(), SessionID: sessionID, MessageID: messageID, Index: 0, Expire: time.Minute}
+
+	t.Run("unknown message", func(t *testing.T) {
+		repo := &MockSessionRepo{}
+		repo.On("GetMessage", ctx, sessionID, messageID).Return(nil, gorm.ErrRecordNotFound)
+		s := &sessionService{sessionRepo: repo}
+
+		_, err := s.GetPartContentURL(ctx, in)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		repo := &MockSessionRepo{}
+		repo.On("GetMessage", ctx, sessionID, messageID).Return(&model.Message{ID: messageID, SessionID: sessionID}, nil)
+		assetRepo := &MockAssetReferenceRepo{}
+		s := &sessionService{sessionRepo: repo, assetReferenceRepo: assetRepo}
+
+		_, err := s.GetPartContentURL(ctx, in)
+		assert.ErrorIs(t, err, ErrPartAssetNotFound)
+		assetRepo.AssertNotCalled(t, "GetS3KeyBySHA256", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSessionService_GetPartContentURL(t *testing.T) {
+	ctx := context.Background()
+	projectID, sessionID, messageID := uuid.New(), uuid.New(), uuid.New()
+	parts := []model.Part{
+		{Type: "image", Asset: &model.Asset{Bucket: "deployment", S3Key: "assets/cat.png", SHA256: "cat"}},
+		{Type: "file", Asset: &model.Asset{Bucket: "project-own", S3Key: "disks/report.pdf", SHA256: "report"}},
+	}
+
+	// Serves the parts of the message, path-style, for both buckets
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(parts)
+	}))
+	defer srv.Close()
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+	})
+	deployment := &blob.S3Deps{Client: client, Presigner: s3.NewPresignClient(client), Bucket: "deployment"}
+	projectS3 := blob.NewProjectS3Resolver(deployment, func(ctx context.Context, projectID uuid.UUID) (*blob.ProjectBucket, error) {
+		return &blob.ProjectBucket{Endpoint: srv.URL, Region: "us-east-1", Bucket: "project-own", AccessKey: "test", SecretKey: "test", UsePathStyle: true}, nil
+	})
+
+	repo := &MockSessionRepo{}
+	repo.On("GetMessage", ctx, sessionID, messageID).Return(&model.Message{
+		ID:             messageID,
+		SessionID:      sessionID,
+		PartsAssetMeta: datatypes.NewJSONType(model.Asset{Bucket: "deployment", S3Key: "parts/message.json", SHA256: "parts"}),
+	}, nil)
+	assetRepo := &MockAssetReferenceRepo{}
+	// The canonical keys of the references are other copies of the same content
+	assetRepo.On("GetS3KeyBySHA256", ctx, projectID, "deployment", "cat").Return("assets/canonical-cat.png", nil)
+	assetRepo.On("GetS3KeyBySHA256", ctx, projectID, "project-own", "report").Return("disks/canonical-report.pdf", nil)
+	s := NewSessionService(repo, assetRepo, zap.NewNop(), deployment, projectS3, nil, &config.Config{}, nil)
+
+	for index, want := range []string{"/deployment/assets/cat.png", "/project-own/disks/report.pdf"} {
+		out, err := s.GetPartContentURL(ctx, GetPartContentInput{ProjectID: projectID, SessionID: sessionID, MessageID: messageID, Index: index, Expire: time.Minute})
+		require.NoError(t, err)
+		assert.Contains(t, out.URL, srv.URL+want)
+	}
+
+	t.Run("asset not referenced in its bucket", func(t *testing.T) {
+		assetRepo := &MockAssetReferenceRepo{}
+		assetRepo.On("GetS3KeyBySHA256", ctx, projectID, "deployment", "cat").Return("", nil)
+		s := NewSessionService(repo, assetRepo, zap.NewNop(), deployment, projectS3, nil, &config.Config{}, nil)
+
+		_, err := s.GetPartContentURL(ctx, GetPartContentInput{ProjectID: projectID, SessionID: sessionID, MessageID: messageID, Index: 0, Expire: time.Minute})
+		assert.ErrorIs(t, err, ErrPartAssetNotFound)
+	})
+}
+
+func TestSessionSummary_AddParts(t *testing.T) {
+	msgs := []model.Message{
+		{Role: "user", Parts: []model.Part{
+			{Type: "text", Text: "what is in this picture?"},
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/cat.png"}},
+		}},
+		{Role: "assistant", Parts: []model.Part{
+			{Type: "text", Text: "let me check"},
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "describe"}},
+			{Type: "tool-call", Meta: map[string]any{"id": "call_2", "name": "search"}},
+		}},
+		{Role: "user", Parts: []model.Part{
+			{Type: "tool-result", Text: "a cat", Meta: map[string]any{"tool_call_id": "call_1"}},
+			{Type: "file", Asset: &model.Asset{S3Key: "assets/report.pdf"}, Filename: "report.pdf"},
+		}},
+		{Role: "assistant", Parts: []model.Part{{Type: "text", Text: "it is a cat"}}},
+	}
+	tok := tokenizer.Func(func(text string) (int, error) { return 1, nil })
+
+	summary := &SessionSummary{}
+	assert.NoError(t, summary.addParts(msgs, tok))
+	assert.Equal(t, 4, summary.TotalTokens, "one token per message with text")
+	assert.Equal(t, 2, summary.ToolCallCount)
+	assert.Equal(t, 2, summary.ArtifactCount)
+}
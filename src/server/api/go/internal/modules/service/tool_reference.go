@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/gorm"
+)
+
+var ErrToolReferenceNameExists = errors.New("tool reference name already exists")
+
+// ErrInvalidToolReferenceImport is returned for an import batch that cannot be applied as a whole
+var ErrInvalidToolReferenceImport = errors.New("invalid tool reference import")
+
+// ToolReferenceInUseError is returned when deleting a tool reference that SOP blocks still use
+type ToolReferenceInUseError struct {
+	BlockIDs []uuid.UUID
+}
+
+func (e *ToolReferenceInUseError) Error() string {
+	return fmt.Sprintf("tool reference is used by %d sop block(s)", len(e.BlockIDs))
+}
+
+type ToolReferenceService interface {
+	Create(ctx context.Context, in ToolReferenceInput) (*model.ToolReference, error)
+	Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error)
+	List(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error)
+	Update(ctx context.Context, id uuid.UUID, in ToolReferenceInput) (*model.ToolReference, error)
+	Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error
+	Import(ctx context.Context, in ImportToolReferencesInput) (*ImportToolReferencesOutput, error)
+}
+
+type toolReferenceService struct{ r repo.ToolReferenceRepo }
+
+func NewToolReferenceService(r repo.ToolReferenceRepo) ToolReferenceService {
+	return &toolReferenceService{r: r}
+}
+
+type ToolReferenceInput struct {
+	ProjectID       uuid.UUID
+	Name            string
+	Description     *string
+	ArgumentsSchema map[string]any
+}
+
+func (s *toolReferenceService) Create(ctx context.Context, in ToolReferenceInput) (*model.ToolReference, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, errors.New("tool reference name is empty")
+	}
+
+	exists, err := s.r.ExistsByName(ctx, in.ProjectID, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrToolReferenceNameExists
+	}
+
+	t := &model.ToolReference{
+		ProjectID:       in.ProjectID,
+		Name:            name,
+		Description:     in.Description,
+		ArgumentsSchema: in.ArgumentsSchema,
+	}
+	if err := s.r.Create(ctx, t); err != nil {
+		// The name was taken after the check above
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrToolReferenceNameExists
+		}
+		return nil, fmt.Errorf("create tool reference record: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *toolReferenceService) Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error) {
+	if len(id) == 0 {
+		return nil, errors.New("tool reference id is empty")
+	}
+	return s.r.Get(ctx, projectID, id)
+}
+
+func (s *toolReferenceService) List(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error) {
+	return s.r.ListByProject(ctx, projectID)
+}
+
+func (s *toolReferenceService) Update(ctx context.Context, id uuid.UUID, in ToolReferenceInput) (*model.ToolReference, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, errors.New("tool reference name is empty")
+	}
+
+	t, err := s.r.Get(ctx, in.ProjectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != t.Name {
+		exists, err := s.r.ExistsByName(ctx, in.ProjectID, name, &id)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrToolReferenceNameExists
+		}
+	}
+
+	t.Name = name
+	t.Description = in.Description
+	t.ArgumentsSchema = in.ArgumentsSchema
+	if err := s.r.Update(ctx, t); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrToolReferenceNameExists
+		}
+		return nil, fmt.Errorf("update tool reference record: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *toolReferenceService) Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error {
+	if _, err := s.r.Get(ctx, projectID, id); err != nil {
+		return err
+	}
+
+	// Refuse to delete tools that SOP blocks still rely on
+	blockIDs, err := s.r.ListReferencingBlockIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(blockIDs) > 0 {
+		return &ToolReferenceInUseError{BlockIDs: blockIDs}
+	}
+
+	return s.r.Delete(ctx, projectID, id)
+}
+
+// ToolImportMode tells what an import does with a tool whose name the project already uses
+type ToolImportMode string
+
+const (
+	ToolImportModeSkip   ToolImportMode = "skip"
+	ToolImportModeUpdate ToolImportMode = "update"
+)
+
+type ImportToolReferencesInput struct {
+	ProjectID uuid.UUID
+	// Mode defaults to ToolImportModeSkip
+	Mode  ToolImportMode
+	Tools []ToolReferenceInput
+}
+
+type ImportToolReferencesOutput struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// Import creates many tool references at once, in a single transaction. Names must be unique
+// within the batch; a tool named like an existing one is skipped or updates it, per in.Mode.
+func (s *toolReferenceService) Import(ctx context.Context, in ImportToolReferencesInput) (*ImportToolReferencesOutput, error) {
+	mode := in.Mode
+	if mode == "" {
+		mode = ToolImportModeSkip
+	}
+	if mode != ToolImportModeSkip && mode != ToolImportModeUpdate {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidToolReferenceImport, mode)
+	}
+	if len(in.Tools) == 0 {
+		return nil, fmt.Errorf("%w: no tools", ErrInvalidToolReferenceImport)
+	}
+
+	tools := make([]*model.ToolReference, 0, len(in.Tools))
+	seen := make(map[string]bool, len(in.Tools))
+	for i, tool := range in.Tools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: tool %d has no name", ErrInvalidToolReferenceImport, i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate tool name %q", ErrInvalidToolReferenceImport, name)
+		}
+		seen[name] = true
+
+		tools = append(tools, &model.ToolReference{
+			ProjectID:       in.ProjectID,
+			Name:            name,
+			Description:     tool.Description,
+			ArgumentsSchema: tool.ArgumentsSchema,
+		})
+	}
+
+	counts, err := s.r.Import(ctx, in.ProjectID, tools, mode == ToolImportModeUpdate)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrToolReferenceNameExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import tool references: %w", err)
+	}
+
+	return &ImportToolReferencesOutput{
+		Created: counts.Created,
+		Updated: counts.Updated,
+		Skipped: counts.Skipped,
+	}, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockToolReferenceRepo is a mock implementation of ToolReferenceRepo
+type MockToolReferenceRepo struct {
+	mock.Mock
+}
+
+func (m *MockToolReferenceRepo) Create(ctx context.Context, t *model.ToolReference) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockToolReferenceRepo) Get(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.ToolReference, error) {
+	args := m.Called(ctx, projectID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceRepo) Update(ctx context.Context, t *model.ToolReference) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockToolReferenceRepo) Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error {
+	args := m.Called(ctx, projectID, id)
+	return args.Error(0)
+}
+
+func (m *MockToolReferenceRepo) ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ToolReference, error) {
+	args := m.Called(ctx, projectID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.ToolReference), args.Error(1)
+}
+
+func (m *MockToolReferenceRepo) ExistsByName(ctx context.Context, projectID uuid.UUID, name string, excludeID *uuid.UUID) (bool, error) {
+	args := m.Called(ctx, projectID, name, excludeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockToolReferenceRepo) ListReferencingBlockIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockToolReferenceRepo) Import(ctx context.Context, projectID uuid.UUID, tools []*model.ToolReference, update bool) (repo.ToolReferenceImportCounts, error) {
+	args := m.Called(ctx, projectID, tools, update)
+	return args.Get(0).(repo.ToolReferenceImportCounts), args.Error(1)
+}
+
+func TestToolReferenceService_Create(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	tests := []struct {
+		name    string
+		in      ToolReferenceInput
+		setup   func(*MockToolReferenceRepo)
+		wantErr error
+		errMsg  string
+	}{
+		{
+			name: "successful creation",
+			in:   ToolReferenceInput{ProjectID: projectID, Name: "search_web"},
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("ExistsByName", ctx, projectID, "search_web", (*uuid.UUID)(nil)).Return(false, nil)
+				repo.On("Create", ctx, mock.MatchedBy(func(t *model.ToolReference) bool {
+					return t.ProjectID == projectID && t.Name == "search_web"
+				})).Return(nil)
+			},
+		},
+		{
+			name:   "empty name",
+			in:     ToolReferenceInput{ProjectID: projectID, Name: "  "},
+			setup:  func(repo *MockToolReferenceRepo) {},
+			errMsg: "name is empty",
+		},
+		{
+			name: "duplicate name",
+			in:   ToolReferenceInput{ProjectID: projectID, Name: "search_web"},
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("ExistsByName", ctx, projectID, "search_web", (*uuid.UUID)(nil)).Return(true, nil)
+			},
+			wantErr: ErrToolReferenceNameExists,
+		},
+		{
+			name: "name taken concurrently",
+			in:   ToolReferenceInput{ProjectID: projectID, Name: "search_web"},
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("ExistsByName", ctx, projectID, "search_web", (*uuid.UUID)(nil)).Return(false, nil)
+				repo.On("Create", ctx, mock.Anything).Return(gorm.ErrDuplicatedKey)
+			},
+			wantErr: ErrToolReferenceNameExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockToolReferenceRepo{}
+			tt.setup(repo)
+
+			service := NewToolReferenceService(repo)
+			result, err := service.Create(ctx, tt.in)
+
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, result)
+			case tt.errMsg != "":
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, "search_web", result.Name)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolReferenceService_Delete(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	toolID := uuid.New()
+	blockID := uuid.New()
+
+	tests := []struct {
+		name         string
+		setup        func(*MockToolReferenceRepo)
+		wantErr      bool
+		wantInUseIDs []uuid.UUID
+	}{
+		{
+			name: "successful deletion",
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("Get", ctx, projectID, toolID).Return(&model.ToolReference{ID: toolID, ProjectID: projectID}, nil)
+				repo.On("ListReferencingBlockIDs", ctx, toolID).Return([]uuid.UUID{}, nil)
+				repo.On("Delete", ctx, projectID, toolID).Return(nil)
+			},
+		},
+		{
+			name: "still referenced by sop block",
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("Get", ctx, projectID, toolID).Return(&model.ToolReference{ID: toolID, ProjectID: projectID}, nil)
+				repo.On("ListReferencingBlockIDs", ctx, toolID).Return([]uuid.UUID{blockID}, nil)
+			},
+			wantErr:      true,
+			wantInUseIDs: []uuid.UUID{blockID},
+		},
+		{
+			name: "not found",
+			setup: func(repo *MockToolReferenceRepo) {
+				repo.On("Get", ctx, projectID, toolID).Return(nil, errors.New("record not found"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockToolReferenceRepo{}
+			tt.setup(repo)
+
+			service := NewToolReferenceService(repo)
+			err := service.Delete(ctx, projectID, toolID)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.wantInUseIDs != nil {
+					var inUse *ToolReferenceInUseError
+					assert.ErrorAs(t, err, &inUse)
+					assert.Equal(t, tt.wantInUseIDs, inUse.BlockIDs)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolReferenceService_Import(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	description := "Search the web"
+
+	names := func(tools []*model.ToolReference) []string {
+		out := make([]string, 0, len(tools))
+		for _, t := range tools {
+			out = append(out, t.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		in         ImportToolReferencesInput
+		wantUpdate bool
+		wantNames  []string
+		wantErr    string
+	}{
+		{
+			name: "skip by default",
+			in: ImportToolReferencesInput{ProjectID: projectID, Tools: []ToolReferenceInput{
+				{Name: " search_web ", Description: &description},
+				{Name: "read_file"},
+			}},
+			wantNames: []string{"search_web", "read_file"},
+		},
+		{
+			name: "update mode",
+			in: ImportToolReferencesInput{ProjectID: projectID, Mode: ToolImportModeUpdate, Tools: []ToolReferenceInput{
+				{Name: "search_web"},
+			}},
+			wantUpdate: true,
+			wantNames:  []string{"search_web"},
+		},
+		{
+			name: "duplicate name in batch",
+			in: ImportToolReferencesInput{ProjectID: projectID, Tools: []ToolReferenceInput{
+				{Name: "search_web"},
+				{Name: "search_web "},
+			}},
+			wantErr: `duplicate tool name "search_web"`,
+		},
+		{
+			name:    "empty name",
+			in:      ImportToolReferencesInput{ProjectID: projectID, Tools: []ToolReferenceInput{{Name: " "}}},
+			wantErr: "tool 0 has no name",
+		},
+		{
+			name:    "unknown mode",
+			in:      ImportToolReferencesInput{ProjectID: projectID, Mode: "replace", Tools: []ToolReferenceInput{{Name: "search_web"}}},
+			wantErr: "unknown mode",
+		},
+		{
+			name:    "no tools",
+			in:      ImportToolReferencesInput{ProjectID: projectID},
+			wantErr: "no tools",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MockToolReferenceRepo{}
+			if tt.wantErr == "" {
+				r.On("Import", ctx, projectID, mock.MatchedBy(func(tools []*model.ToolReference) bool {
+					return assert.ObjectsAreEqual(tt.wantNames, names(tools))
+				}), tt.wantUpdate).Return(repo.ToolReferenceImportCounts{Created: 1, Updated: 0, Skipped: len(tt.wantNames) - 1}, nil)
+			}
+
+			out, err := NewToolReferenceService(r).Import(ctx, tt.in)
+
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidToolReferenceImport)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				r.AssertNotCalled(t, "Import", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, &ImportToolReferencesOutput{Created: 1, Skipped: len(tt.wantNames) - 1}, out)
+			r.AssertExpectations(t)
+		})
+	}
+}
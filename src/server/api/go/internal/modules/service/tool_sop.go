@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/gorm"
+)
+
+var ErrNotSOPBlock = errors.New("block is not a sop block")
+
+// ErrEmptySOPAction is returned for a step whose action is empty or only whitespace
+var ErrEmptySOPAction = errors.New("action is empty")
+
+type ToolSOPService interface {
+	AppendStep(ctx context.Context, in AppendToolSOPInput) (*model.ToolSOP, error)
+	DeleteStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID) error
+	ReorderStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID, order int) error
+}
+
+type toolSOPService struct {
+	r                 repo.ToolSOPRepo
+	blockRepo         repo.BlockRepo
+	toolReferenceRepo repo.ToolReferenceRepo
+}
+
+func NewToolSOPService(r repo.ToolSOPRepo, blockRepo repo.BlockRepo, toolReferenceRepo repo.ToolReferenceRepo) ToolSOPService {
+	return &toolSOPService{
+		r:                 r,
+		blockRepo:         blockRepo,
+		toolReferenceRepo: toolReferenceRepo,
+	}
+}
+
+type AppendToolSOPInput struct {
+	ProjectID       uuid.UUID
+	SpaceID         uuid.UUID
+	BlockID         uuid.UUID
+	ToolReferenceID uuid.UUID
+	Action          string
+}
+
+// getSOPBlock loads the block and ensures it is a SOP block inside the given space. A
+// block of another space is not found.
+func (s *toolSOPService) getSOPBlock(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID) (*model.Block, error) {
+	b, err := s.blockRepo.Get(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SpaceID != spaceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if b.Type != model.BlockTypeSOP {
+		return nil, ErrNotSOPBlock
+	}
+	return b, nil
+}
+
+func (s *toolSOPService) AppendStep(ctx context.Context, in AppendToolSOPInput) (*model.ToolSOP, error) {
+	action := strings.TrimSpace(in.Action)
+	if action == "" {
+		return nil, ErrEmptySOPAction
+	}
+
+	if _, err := s.getSOPBlock(ctx, in.SpaceID, in.BlockID); err != nil {
+		return nil, err
+	}
+
+	// Tool reference must belong to the same project as the block
+	tool, err := s.toolReferenceRepo.Get(ctx, in.ProjectID, in.ToolReferenceID)
+	if err != nil {
+		return nil, fmt.Errorf("get tool reference: %w", err)
+	}
+
+	step := &model.ToolSOP{
+		Action:          action,
+		ToolReferenceID: tool.ID,
+		SOPBlockID:      in.BlockID,
+	}
+	if err := s.r.Append(ctx, step); err != nil {
+		return nil, fmt.Errorf("append tool sop: %w", err)
+	}
+	step.ToolReference = tool
+
+	return step, nil
+}
+
+func (s *toolSOPService) DeleteStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID) error {
+	if _, err := s.getSOPBlock(ctx, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.r.Delete(ctx, blockID, stepID)
+}
+
+func (s *toolSOPService) ReorderStep(ctx context.Context, spaceID uuid.UUID, blockID uuid.UUID, stepID uuid.UUID, order int) error {
+	if _, err := s.getSOPBlock(ctx, spaceID, blockID); err != nil {
+		return err
+	}
+	return s.r.Reorder(ctx, blockID, stepID, order)
+}
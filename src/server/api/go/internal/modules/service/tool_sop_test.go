@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockToolSOPRepo is a mock implementation of ToolSOPRepo
+type MockToolSOPRepo struct {
+	mock.Mock
+}
+
+func (m *MockToolSOPRepo) Get(ctx context.Context, blockID uuid.UUID, id uuid.UUID) (*model.ToolSOP, error) {
+	args := m.Called(ctx, blockID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ToolSOP), args.Error(1)
+}
+
+func (m *MockToolSOPRepo) ListByBlock(ctx context.Context, blockID uuid.UUID) ([]model.ToolSOP, error) {
+	args := m.Called(ctx, blockID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.ToolSOP), args.Error(1)
+}
+
+func (m *MockToolSOPRepo) Append(ctx context.Context, t *model.ToolSOP) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockToolSOPRepo) Delete(ctx context.Context, blockID uuid.UUID, id uuid.UUID) error {
+	args := m.Called(ctx, blockID, id)
+	return args.Error(0)
+}
+
+func (m *MockToolSOPRepo) Reorder(ctx context.Context, blockID uuid.UUID, id uuid.UUID, newOrder int) error {
+	args := m.Called(ctx, blockID, id, newOrder)
+	return args.Error(0)
+}
+
+func TestToolSOPService_AppendStep(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	toolID := uuid.New()
+
+	in := AppendToolSOPInput{
+		ProjectID:       projectID,
+		SpaceID:         spaceID,
+		BlockID:         blockID,
+		ToolReferenceID: toolID,
+		Action:          "search the docs",
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(*MockToolSOPRepo, *MockBlockRepo, *MockToolReferenceRepo)
+		wantErr error
+	}{
+		{
+			name: "successful append",
+			setup: func(r *MockToolSOPRepo, br *MockBlockRepo, tr *MockToolReferenceRepo) {
+				br.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID, Type: model.BlockTypeSOP}, nil)
+				tr.On("Get", ctx, projectID, toolID).Return(&model.ToolReference{ID: toolID, ProjectID: projectID, Name: "search"}, nil)
+				r.On("Append", ctx, mock.MatchedBy(func(s *model.ToolSOP) bool {
+					return s.SOPBlockID == blockID && s.ToolReferenceID == toolID && s.Action == "search the docs"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "block is not a sop block",
+			setup: func(r *MockToolSOPRepo, br *MockBlockRepo, tr *MockToolReferenceRepo) {
+				br.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID, Type: model.BlockTypeText}, nil)
+			},
+			wantErr: ErrNotSOPBlock,
+		},
+		{
+			name: "block of another space",
+			setup: func(r *MockToolSOPRepo, br *MockBlockRepo, tr *MockToolReferenceRepo) {
+				br.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: uuid.New(), Type: model.BlockTypeSOP}, nil)
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+		{
+			name: "tool reference from another project",
+			setup: func(r *MockToolSOPRepo, br *MockBlockRepo, tr *MockToolReferenceRepo) {
+				br.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID, Type: model.BlockTypeSOP}, nil)
+				tr.On("Get", ctx, projectID, toolID).Return(nil, gorm.ErrRecordNotFound)
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MockToolSOPRepo{}
+			br := &MockBlockRepo{}
+			tr := &MockToolReferenceRepo{}
+			tt.setup(r, br, tr)
+
+			service := NewToolSOPService(r, br, tr)
+			step, err := service.AppendStep(ctx, in)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, step)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, toolID, step.ToolReferenceID)
+			}
+
+			r.AssertExpectations(t)
+			br.AssertExpectations(t)
+			tr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestToolSOPService_AppendStep_EmptyAction(t *testing.T) {
+	service := NewToolSOPService(&MockToolSOPRepo{}, &MockBlockRepo{}, &MockToolReferenceRepo{})
+	step, err := service.AppendStep(context.Background(), AppendToolSOPInput{
+		ProjectID:       uuid.New(),
+		SpaceID:         uuid.New(),
+		BlockID:         uuid.New(),
+		ToolReferenceID: uuid.New(),
+		Action:          " \t\n",
+	})
+
+	assert.ErrorIs(t, err, ErrEmptySOPAction)
+	assert.Nil(t, step)
+}
+
+func TestToolSOPService_ReorderStep(t *testing.T) {
+	ctx := context.Background()
+	spaceID := uuid.New()
+	blockID := uuid.New()
+	stepID := uuid.New()
+
+	r := &MockToolSOPRepo{}
+	br := &MockBlockRepo{}
+	br.On("Get", ctx, blockID).Return(&model.Block{ID: blockID, SpaceID: spaceID, Type: model.BlockTypeSOP}, nil)
+	r.On("Reorder", ctx, blockID, stepID, 2).Return(nil)
+
+	service := NewToolSOPService(r, br, &MockToolReferenceRepo{})
+	err := service.ReorderStep(ctx, spaceID, blockID, stepID, 2)
+
+	assert.NoError(t, err)
+	r.AssertExpectations(t)
+	br.AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"context"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 )
@@ -16,7 +17,7 @@ type AcontextMessage struct {
 	SessionID                string         `json:"session_id"`
 	ParentID                 *string        `json:"parent_id"` // Nullable for message threading
 	Role                     string         `json:"role"`
-	Parts                    []model.Part   `json:"parts"`
+	Parts                    []AcontextPart `json:"parts"`
 	SessionTaskProcessStatus string         `json:"session_task_process_status"` // Task processing state
 	Meta                     map[string]any `json:"meta,omitempty"`
 	TaskID                   *string        `json:"task_id"`
@@ -24,8 +25,15 @@ type AcontextMessage struct {
 	UpdatedAt                string         `json:"updated_at"` // ISO 8601 timestamp
 }
 
+// AcontextPart is a message part with the public URL of its asset, so a frontend can show
+// the asset without presigning it separately
+type AcontextPart struct {
+	model.Part
+	PublicURL string `json:"public_url,omitempty"`
+}
+
 // Convert converts internal model.Message to Acontext format
-func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+func (c *AcontextConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
 	result := make([]AcontextMessage, len(messages))
 
 	for i, msg := range messages {
@@ -33,7 +41,7 @@ func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[str
 			ID:                       msg.ID.String(),
 			SessionID:                msg.SessionID.String(),
 			Role:                     msg.Role,
-			Parts:                    msg.Parts,
+			Parts:                    c.convertParts(msg.Parts, publicURLs),
 			SessionTaskProcessStatus: msg.SessionTaskProcessStatus,
 			CreatedAt:                msg.CreatedAt.Format("2006-01-02T15:04:05.999999Z07:00"), // ISO 8601 / RFC3339
 			UpdatedAt:                msg.UpdatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
@@ -51,7 +59,7 @@ func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[str
 		}
 
 		// Convert meta if present - handle datatypes.JSONType
-		if metaData := msg.Meta.Data(); len(metaData) > 0 {
+		if metaData := msg.MetaMap(); len(metaData) > 0 {
 			acontextMsg.Meta = metaData
 		}
 
@@ -60,3 +68,17 @@ func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[str
 
 	return result, nil
 }
+
+// convertParts attaches the public URL of each asset found in publicURLs
+func (c *AcontextConverter) convertParts(parts []model.Part, publicURLs map[string]service.PublicURL) []AcontextPart {
+	result := make([]AcontextPart, len(parts))
+	for i, part := range parts {
+		result[i] = AcontextPart{Part: part}
+		if part.Asset != nil {
+			if publicURL, ok := publicURLs[part.Asset.S3Key]; ok {
+				result[i].PublicURL = publicURL.URL
+			}
+		}
+	}
+	return result
+}
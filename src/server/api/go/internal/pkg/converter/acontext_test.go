@@ -1,6 +1,8 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -21,7 +23,7 @@ func TestAcontextConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages, ok := result.([]AcontextMessage)
@@ -56,7 +58,7 @@ func TestAcontextConverter_Convert_WithAsset(t *testing.T) {
 		"assets/test.jpg": {URL: "https://example.com/test.jpg"},
 	}
 
-	result, err := converter.Convert(messages, publicURLs)
+	result, err := converter.Convert(context.Background(), messages, publicURLs)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -70,6 +72,32 @@ func TestAcontextConverter_Convert_WithAsset(t *testing.T) {
 	assert.Equal(t, "test.jpg", part.Filename)     // Filename is in Part, not Asset
 	assert.Equal(t, "image/jpeg", part.Asset.MIME) // MIME instead of ContentType
 	assert.Equal(t, int64(1024), part.Asset.SizeB) // SizeB instead of Size
+	assert.Equal(t, "https://example.com/test.jpg", part.PublicURL)
+
+	out, err := json.Marshal(part)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"public_url":"https://example.com/test.jpg"`)
+}
+
+func TestAcontextConverter_Convert_AssetWithoutPublicURL(t *testing.T) {
+	converter := &AcontextConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/unsigned.jpg", MIME: "image/jpeg"}},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, map[string]service.PublicURL{
+		"assets/other.jpg": {URL: "https://example.com/other.jpg"},
+	})
+	require.NoError(t, err)
+
+	part := result.([]AcontextMessage)[0].Parts[0]
+	assert.Empty(t, part.PublicURL)
+	out, err := json.Marshal(part)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "public_url")
 }
 
 func TestAcontextConverter_Convert_WithCacheControl(t *testing.T) {
@@ -89,7 +117,7 @@ func TestAcontextConverter_Convert_WithCacheControl(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -115,7 +143,7 @@ func TestAcontextConverter_Convert_MessageMeta(t *testing.T) {
 		}),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -144,7 +172,7 @@ func TestAcontextConverter_Convert_MultipleParts(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -169,7 +197,7 @@ func TestAcontextConverter_Convert_EmptyMeta(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -197,7 +225,7 @@ func TestAcontextConverter_Convert_MultipleMessages(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -226,7 +254,7 @@ func TestAcontextConverter_Convert_Timestamps(t *testing.T) {
 
 	messages := []model.Message{msg}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -268,7 +296,7 @@ func TestAcontextConverter_Convert_ParentID(t *testing.T) {
 	}
 	msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-	result, err := converter.Convert([]model.Message{msg}, nil)
+	result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -297,7 +325,7 @@ func TestAcontextConverter_Convert_NoParentID(t *testing.T) {
 	}
 	msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-	result, err := converter.Convert([]model.Message{msg}, nil)
+	result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 	require.NoError(t, err)
 
 	acontextMessages := result.([]AcontextMessage)
@@ -337,7 +365,7 @@ func TestAcontextConverter_Convert_SessionTaskProcessStatus(t *testing.T) {
 			}
 			msg.Meta = datatypes.NewJSONType(map[string]any{})
 
-			result, err := converter.Convert([]model.Message{msg}, nil)
+			result, err := converter.Convert(context.Background(), []model.Message{msg}, nil)
 			require.NoError(t, err)
 
 			acontextMessages := result.([]AcontextMessage)
@@ -348,3 +376,28 @@ func TestAcontextConverter_Convert_SessionTaskProcessStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestAcontextConverter_Convert_Video(t *testing.T) {
+	converter := &AcontextConverter{}
+
+	videoPart := model.Part{
+		Type:     "video",
+		Filename: "clip.mp4",
+		Asset: &model.Asset{
+			S3Key: "assets/clip.mp4",
+			MIME:  "video/mp4",
+		},
+		Meta: map[string]any{"duration_s": 12},
+	}
+
+	result, err := converter.Convert(context.Background(), []model.Message{createTestMessage("user", []model.Part{videoPart}, nil)}, nil)
+	require.NoError(t, err)
+
+	acontextMessages, ok := result.([]AcontextMessage)
+	require.True(t, ok)
+	require.Len(t, acontextMessages, 1)
+
+	// Acontext is the internal format, so video parts round-trip unchanged
+	require.Len(t, acontextMessages[0].Parts, 1)
+	assert.Equal(t, AcontextPart{Part: videoPart}, acontextMessages[0].Parts[0])
+}
@@ -1,43 +1,126 @@
 package converter
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
+)
+
+const (
+	// DefaultImageDownloadTimeout bounds a single image download of InlineRemoteImages
+	DefaultImageDownloadTimeout = 10 * time.Second
+	// DefaultMaxImageSizeB is the largest image InlineRemoteImages embeds, Anthropic's per-image limit
+	DefaultMaxImageSizeB = 5 << 20
 )
 
 // AnthropicConverter converts messages to Anthropic Claude-compatible format using official SDK types
-type AnthropicConverter struct{}
+type AnthropicConverter struct {
+	// InlineRemoteImages downloads http(s) images and embeds them as base64 instead of
+	// passing their URL, for consumers that cannot reach the image URLs. Asset images are
+	// read from Assets, other images are fetched from their URL.
+	InlineRemoteImages bool
+	// Assets reads the stored asset images; without it they are passed by URL
+	Assets AssetOpener
+	// ImageDownloadTimeout bounds each download, DefaultImageDownloadTimeout when zero
+	ImageDownloadTimeout time.Duration
+	// MaxImageSizeB is the largest image downloaded, DefaultMaxImageSizeB when zero.
+	// Larger images are passed by URL instead.
+	MaxImageSizeB int64
+
+	// client downloads the images given by URL, safehttp.DefaultClient when nil
+	client *safehttp.Client
+}
 
-func (c *AnthropicConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+// Convert converts messages to Anthropic messages. Anthropic takes system prompts outside
+// the message list, so system and developer messages (meta["role_kind"]) are left out;
+// ConvertWithSystem returns them in the system prompt.
+func (c *AnthropicConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
 	result := make([]anthropic.MessageParam, 0, len(messages))
+	messages = slices.DeleteFunc(slices.Clone(messages), isSystemMessage)
+
+	// A cache breakpoint caches the whole prefix up to it, so marking the latest pinned
+	// message covers all pinned content while staying within Anthropic's breakpoint limit
+	lastPinned := -1
+	for i := range messages {
+		if messages[i].IsPinned() {
+			lastPinned = i
+		}
+	}
 
-	for _, msg := range messages {
-		anthropicMsg := c.convertMessage(msg, publicURLs)
+	for i, msg := range messages {
+		anthropicMsg, err := c.convertMessage(ctx, msg, publicURLs)
+		if err != nil {
+			return nil, err
+		}
+		if i == lastPinned && len(anthropicMsg.Content) > 0 {
+			if cacheControl := anthropicMsg.Content[len(anthropicMsg.Content)-1].GetCacheControl(); cacheControl != nil {
+				*cacheControl = anthropic.NewCacheControlEphemeralParam()
+			}
+		}
 		result = append(result, anthropicMsg)
 	}
 
 	return result, nil
 }
 
-func (c *AnthropicConverter) convertMessage(msg model.Message, publicURLs map[string]service.PublicURL) anthropic.MessageParam {
+// ConvertWithSystem converts messages and returns system separately, to be sent as
+// Anthropic's top-level system parameter. The text of system and developer messages is
+// appended to system.
+func (c *AnthropicConverter) ConvertWithSystem(ctx context.Context, messages []model.Message, system string, publicURLs map[string]service.PublicURL) (*SystemConvertResult, error) {
+	converted, err := c.Convert(ctx, messages, publicURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := []string{}
+	if system != "" {
+		sections = append(sections, system)
+	}
+	for i := range messages {
+		if !isSystemMessage(messages[i]) {
+			continue
+		}
+		for _, part := range messages[i].Parts {
+			if part.Type == "text" && part.Text != "" {
+				sections = append(sections, part.Text)
+			}
+		}
+	}
+	return &SystemConvertResult{System: strings.Join(sections, "\n\n"), Messages: converted}, nil
+}
+
+// isSystemMessage reports whether msg is a system or developer message kept by the normalizer
+func isSystemMessage(msg model.Message) bool {
+	kind := msg.RoleKind()
+	return kind == model.RoleKindSystem || kind == model.RoleKindDeveloper
+}
+
+func (c *AnthropicConverter) convertMessage(ctx context.Context, msg model.Message, publicURLs map[string]service.PublicURL) (anthropic.MessageParam, error) {
 	role := c.convertRole(msg.Role)
 
 	// Convert parts to content blocks
-	contentBlocks := c.convertParts(msg.Parts, publicURLs)
+	contentBlocks, err := c.convertParts(ctx, msg.Parts, publicURLs)
+	if err != nil {
+		return anthropic.MessageParam{}, err
+	}
 
 	if role == "user" {
-		return anthropic.NewUserMessage(contentBlocks...)
+		return anthropic.NewUserMessage(contentBlocks...), nil
 	} else {
-		return anthropic.NewAssistantMessage(contentBlocks...)
+		return anthropic.NewAssistantMessage(contentBlocks...), nil
 	}
 }
 
@@ -53,7 +136,7 @@ func (c *AnthropicConverter) convertRole(role string) string {
 	}
 }
 
-func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[string]service.PublicURL) []anthropic.ContentBlockParamUnion {
+func (c *AnthropicConverter) convertParts(ctx context.Context, parts []model.Part, publicURLs map[string]service.PublicURL) ([]anthropic.ContentBlockParamUnion, error) {
 	contentBlocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
 
 	for _, part := range parts {
@@ -75,7 +158,10 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 			}
 
 		case "image":
-			imageBlock := c.convertImagePart(part, publicURLs)
+			imageBlock, err := c.convertImagePart(ctx, part, publicURLs)
+			if err != nil {
+				return nil, err
+			}
 			if imageBlock != nil {
 				contentBlocks = append(contentBlocks, *imageBlock)
 			}
@@ -95,6 +181,12 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 				contentBlocks = append(contentBlocks, *toolResultBlock)
 			}
 
+		case "audio", "video":
+			// Anthropic has no audio or video content block and document blocks only
+			// accept PDF/text, so these are surfaced as a text placeholder instead of dropped
+			placeholder := UnsupportedPartPlaceholder(part, c.getAssetURL(part.Asset, publicURLs))
+			contentBlocks = append(contentBlocks, anthropic.NewTextBlock(placeholder))
+
 		case "file":
 			// Convert file to document block
 			if part.Meta != nil {
@@ -106,12 +198,14 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 		}
 	}
 
-	return contentBlocks
+	return contentBlocks, nil
 }
 
-func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[string]service.PublicURL) *anthropic.ContentBlockParamUnion {
+// convertImagePart only fails when ctx is done while downloading the image
+func (c *AnthropicConverter) convertImagePart(ctx context.Context, part model.Part, publicURLs map[string]service.PublicURL) (*anthropic.ContentBlockParamUnion, error) {
 	// Try to get image URL from asset
-	imageURL := c.getAssetURL(part.Asset, publicURLs)
+	assetURL := c.getAssetURL(part.Asset, publicURLs)
+	imageURL := assetURL
 	if imageURL == "" && part.Meta != nil {
 		if url, ok := part.Meta["url"].(string); ok {
 			imageURL = url
@@ -119,7 +213,7 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 	}
 
 	if imageURL == "" {
-		return nil
+		return nil, nil
 	}
 
 	// Check if it's a base64 data URL or regular URL
@@ -127,7 +221,7 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 		// Extract base64 data and media type
 		parts := strings.SplitN(imageURL, ",", 2)
 		if len(parts) != 2 {
-			return nil
+			return nil, nil
 		}
 
 		// Parse media type from data URL (e.g., "data:image/png;base64")
@@ -138,18 +232,35 @@ func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[st
 		}
 
 		block := anthropic.NewImageBlockBase64(mediaType, parts[1])
-		return &block
+		return &block, nil
 	}
 
-	// Try to download and convert to base64
-	if base64Data, mediaType := c.downloadImageAsBase64(imageURL); base64Data != "" {
-		block := anthropic.NewImageBlockBase64(mediaType, base64Data)
-		return &block
+	if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+		return nil, nil
 	}
 
-	// Fall back to URL if available (note: Anthropic might not support URL directly for images in some contexts)
-	// In practice, we convert to base64
-	return nil
+	if c.InlineRemoteImages {
+		var base64Data, mediaType string
+		var err error
+		if assetURL != "" {
+			// Our own assets are read from the store, which may well sit on an internal address
+			base64Data, mediaType, err = c.readAssetAsBase64(ctx, part.Asset)
+		} else {
+			base64Data, mediaType, err = c.downloadImageAsBase64(ctx, imageURL)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("download image: %w", ctx.Err())
+		}
+		if err == nil {
+			block := anthropic.NewImageBlockBase64(mediaType, base64Data)
+			return &block, nil
+		}
+	}
+
+	// Anthropic fetches URL sources itself, which keeps the download out of this request.
+	// It is also the fallback for images that could not be downloaded.
+	block := anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: imageURL})
+	return &block, nil
 }
 
 func (c *AnthropicConverter) convertToolCallPart(part model.Part) *anthropic.ContentBlockParamUnion {
@@ -199,11 +310,42 @@ func (c *AnthropicConverter) convertToolResultPart(part model.Part) *anthropic.C
 		return nil
 	}
 
+	// Tool results holding images or documents keep their content items
+	if content, ok := part.Meta[normalizer.ToolResultContentKey].([]any); ok && len(content) > 0 {
+		if items, err := toolResultContent(content); err == nil {
+			block := anthropic.ContentBlockParamUnion{OfToolResult: &anthropic.ToolResultBlockParam{
+				ToolUseID: toolUseID,
+				Content:   items,
+				IsError:   anthropic.Bool(isError),
+			}}
+			return &block
+		}
+	}
+
 	block := anthropic.NewToolResultBlock(toolUseID, part.Text, isError)
 	return &block
 }
 
+// toolResultContent rebuilds tool result content items stored by the Anthropic normalizer
+func toolResultContent(content []any) ([]anthropic.ToolResultBlockParamContentUnion, error) {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var items []anthropic.ToolResultBlockParamContentUnion
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func (c *AnthropicConverter) convertDocumentPart(part model.Part, publicURLs map[string]service.PublicURL) *anthropic.ContentBlockParamUnion {
+	// A document offloaded to an asset is sent by its URL
+	if url := c.getAssetURL(part.Asset, publicURLs); url != "" {
+		block := anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: url})
+		return &block
+	}
+
 	// Try to get document URL or base64 data from meta
 	if part.Meta == nil {
 		return nil
@@ -238,20 +380,37 @@ func (c *AnthropicConverter) convertDocumentPart(part model.Part, publicURLs map
 	return nil
 }
 
-func (c *AnthropicConverter) downloadImageAsBase64(imageURL string) (string, string) {
-	resp, err := http.Get(imageURL)
-	if err != nil {
-		return "", ""
+func (c *AnthropicConverter) httpClient() *safehttp.Client {
+	if c.client == nil {
+		return safehttp.DefaultClient
 	}
-	defer resp.Body.Close()
+	return c.client
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", ""
+// imageLimits returns ImageDownloadTimeout and MaxImageSizeB with their defaults applied
+func (c *AnthropicConverter) imageLimits() (time.Duration, int64) {
+	timeout := c.ImageDownloadTimeout
+	if timeout <= 0 {
+		timeout = DefaultImageDownloadTimeout
 	}
+	maxSize := c.MaxImageSizeB
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImageSizeB
+	}
+	return timeout, maxSize
+}
 
-	data, err := io.ReadAll(resp.Body)
+// downloadImageAsBase64 downloads a user-supplied image URL within ImageDownloadTimeout,
+// rejecting images over MaxImageSizeB and images on internal addresses, and returns it
+// base64-encoded along with its media type
+func (c *AnthropicConverter) downloadImageAsBase64(ctx context.Context, imageURL string) (string, string, error) {
+	timeout, maxSize := c.imageLimits()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := c.httpClient().Get(ctx, imageURL, maxSize)
 	if err != nil {
-		return "", ""
+		return "", "", err
 	}
 
 	// Determine media type
@@ -261,9 +420,43 @@ func (c *AnthropicConverter) downloadImageAsBase64(imageURL string) (string, str
 	}
 
 	// Encode to base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
+	base64Data := base64.StdEncoding.EncodeToString(resp.Data)
+
+	return base64Data, mediaType, nil
+}
+
+// readAssetAsBase64 reads a stored asset image from Assets within ImageDownloadTimeout,
+// rejecting images over MaxImageSizeB, and returns it base64-encoded along with its media type
+func (c *AnthropicConverter) readAssetAsBase64(ctx context.Context, asset *model.Asset) (string, string, error) {
+	if c.Assets == nil {
+		return "", "", errors.New("no asset store")
+	}
+	timeout, maxSize := c.imageLimits()
+	if asset.SizeB > maxSize {
+		return "", "", fmt.Errorf("asset is larger than %d bytes", maxSize)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	body, err := c.Assets.OpenFile(ctx, asset.S3Key)
+	if err != nil {
+		return "", "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return "", "", err
+	}
+	if int64(len(data)) > maxSize {
+		return "", "", fmt.Errorf("asset is larger than %d bytes", maxSize)
+	}
 
-	return base64Data, mediaType
+	mediaType := asset.MIME
+	if mediaType == "" {
+		mediaType = "image/png" // default
+	}
+	return base64.StdEncoding.EncodeToString(data), mediaType, nil
 }
 
 func (c *AnthropicConverter) getAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
@@ -1,10 +1,24 @@
 package converter
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/memodb-io/Acontext/internal/pkg/safehttp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +32,7 @@ func TestAnthropicConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	// Anthropic converter returns []anthropic.MessageParam
@@ -43,7 +57,7 @@ func TestAnthropicConverter_Convert_WithCacheControl(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -66,7 +80,7 @@ func TestAnthropicConverter_Convert_ToolCall(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -87,11 +101,68 @@ func TestAnthropicConverter_Convert_ToolResult(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
 
+func TestAnthropicConverter_Convert_ToolResultWithImage(t *testing.T) {
+	input := `{
+		"role": "user",
+		"content": [
+			{
+				"type": "tool_result",
+				"tool_use_id": "toolu_123",
+				"content": [
+					{"type": "text", "text": "Screenshot taken"},
+					{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+				]
+			}
+		]
+	}`
+	role, partsIn, _, err := (&normalizer.AnthropicNormalizer{}).NormalizeFromAnthropicMessage([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, partsIn, 1)
+
+	parts := []model.Part{{Type: partsIn[0].Type, Text: partsIn[0].Text, Meta: partsIn[0].Meta}}
+	result, err := (&AnthropicConverter{}).Convert(context.Background(), []model.Message{createTestMessage(role, parts, nil)}, nil)
+	require.NoError(t, err)
+
+	content := result.([]anthropic.MessageParam)[0].Content
+	require.Len(t, content, 1)
+	toolResult := content[0].OfToolResult
+	require.NotNil(t, toolResult)
+	assert.Equal(t, "toolu_123", toolResult.ToolUseID)
+	require.Len(t, toolResult.Content, 2)
+	require.NotNil(t, toolResult.Content[0].OfText)
+	assert.Equal(t, "Screenshot taken", toolResult.Content[0].OfText.Text)
+	require.NotNil(t, toolResult.Content[1].OfImage)
+	require.NotNil(t, toolResult.Content[1].OfImage.Source.OfBase64)
+	assert.Equal(t, "iVBORw0KGgo=", toolResult.Content[1].OfImage.Source.OfBase64.Data)
+	assert.Equal(t, anthropic.Base64ImageSourceMediaType("image/png"), toolResult.Content[1].OfImage.Source.OfBase64.MediaType)
+}
+
+func TestAnthropicConverter_Convert_OpenAIToolResultWithImage(t *testing.T) {
+	raw := json.RawMessage(`{
+		"role": "tool",
+		"tool_call_id": "call_123",
+		"content": [
+			{"type": "text", "text": "Screenshot taken"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}}
+		]
+	}`)
+	role, partsIn := normalizeForFormat(t, model.FormatOpenAI, raw)
+
+	result, err := (&AnthropicConverter{}).Convert(context.Background(), []model.Message{storedMessage(role, partsIn)}, nil)
+	require.NoError(t, err)
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_123","is_error":false,"content":[
+		{"type":"text","text":"Screenshot taken"},
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}}
+	]}]}]`, string(out))
+}
+
 func TestAnthropicConverter_Convert_Image(t *testing.T) {
 	converter := &AnthropicConverter{}
 
@@ -113,7 +184,287 @@ func TestAnthropicConverter_Convert_Image(t *testing.T) {
 		"assets/image.jpg": {URL: "https://example.com/image.jpg"},
 	}
 
-	result, err := converter.Convert(messages, publicURLs)
+	result, err := converter.Convert(context.Background(), messages, publicURLs)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+// loopbackClient lets the converter download from the test servers, which listen on loopback
+var loopbackClient = &safehttp.Client{AllowAddr: func(netip.Addr) bool { return true }}
+
+func TestAnthropicConverter_Convert_ImageSource(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg-bytes"))
+	}))
+	defer server.Close()
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "image", Asset: &model.Asset{S3Key: "assets/image.jpg", MIME: "image/jpeg"}},
+			{Type: "image", Meta: map[string]any{"url": "data:image/gif;base64,R0lGOD"}},
+		}, nil),
+	}
+	publicURLs := map[string]service.PublicURL{
+		"assets/image.jpg": {URL: server.URL + "/image.jpg"},
+	}
+
+	t.Run("url mode makes no request", func(t *testing.T) {
+		requests.Store(0)
+		result, err := (&AnthropicConverter{}).Convert(context.Background(), messages, publicURLs)
+		require.NoError(t, err)
+
+		content := result.([]anthropic.MessageParam)[0].Content
+		require.Len(t, content, 2)
+		require.NotNil(t, content[0].OfImage.Source.OfURL)
+		assert.Equal(t, server.URL+"/image.jpg", content[0].OfImage.Source.OfURL.URL)
+		// Data URLs are always embedded
+		require.NotNil(t, content[1].OfImage.Source.OfBase64)
+		assert.Equal(t, "R0lGOD", content[1].OfImage.Source.OfBase64.Data)
+		assert.Equal(t, int32(0), requests.Load())
+	})
+
+	t.Run("inline mode reads assets from the store", func(t *testing.T) {
+		requests.Store(0)
+		assets := fakeAssets{"assets/image.jpg": "jpeg-bytes"}
+		result, err := (&AnthropicConverter{InlineRemoteImages: true, Assets: assets}).Convert(context.Background(), messages, publicURLs)
+		require.NoError(t, err)
+
+		// The store is read even though the presigned URL points at loopback
+		content := result.([]anthropic.MessageParam)[0].Content
+		require.NotNil(t, content[0].OfImage.Source.OfBase64)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("jpeg-bytes")), content[0].OfImage.Source.OfBase64.Data)
+		assert.Equal(t, "image/jpeg", string(content[0].OfImage.Source.OfBase64.MediaType))
+		assert.Equal(t, int32(0), requests.Load())
+	})
+
+	t.Run("inline mode without a store passes assets by URL", func(t *testing.T) {
+		requests.Store(0)
+		result, err := (&AnthropicConverter{InlineRemoteImages: true, client: loopbackClient}).Convert(context.Background(), messages, publicURLs)
+		require.NoError(t, err)
+
+		content := result.([]anthropic.MessageParam)[0].Content
+		require.NotNil(t, content[0].OfImage.Source.OfURL)
+		assert.Equal(t, int32(0), requests.Load())
+	})
+
+	t.Run("inline mode downloads user URLs", func(t *testing.T) {
+		requests.Store(0)
+		userImage := []model.Message{
+			createTestMessage("user", []model.Part{{Type: "image", Meta: map[string]any{"url": server.URL + "/user.jpg"}}}, nil),
+		}
+		result, err := (&AnthropicConverter{InlineRemoteImages: true, client: loopbackClient}).Convert(context.Background(), userImage, nil)
+		require.NoError(t, err)
+
+		content := result.([]anthropic.MessageParam)[0].Content
+		require.NotNil(t, content[0].OfImage.Source.OfBase64)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("jpeg-bytes")), content[0].OfImage.Source.OfBase64.Data)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("inline mode does not download user URLs on internal addresses", func(t *testing.T) {
+		requests.Store(0)
+		userImage := []model.Message{
+			createTestMessage("user", []model.Part{{Type: "image", Meta: map[string]any{"url": server.URL + "/user.jpg"}}}, nil),
+		}
+		result, err := (&AnthropicConverter{InlineRemoteImages: true}).Convert(context.Background(), userImage, nil)
+		require.NoError(t, err)
+
+		// The image is left for Anthropic to fetch
+		content := result.([]anthropic.MessageParam)[0].Content
+		require.NotNil(t, content[0].OfImage.Source.OfURL)
+		assert.Equal(t, int32(0), requests.Load())
+	})
+}
+
+// fakeAssets serves stored assets from memory by key
+type fakeAssets map[string]string
+
+func (f fakeAssets) OpenFile(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := f[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func TestAnthropicConverter_Convert_ImageDownloadLimits(t *testing.T) {
+	imageMessage := func(url string) []model.Message {
+		return []model.Message{
+			createTestMessage("user", []model.Part{{Type: "image", Meta: map[string]any{"url": url}}}, nil),
+		}
+	}
+
+	t.Run("aborts when the context is canceled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := (&AnthropicConverter{InlineRemoteImages: true, client: loopbackClient}).Convert(ctx, imageMessage(server.URL+"/hang.png"), nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("timeout falls back to the URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		converter := &AnthropicConverter{InlineRemoteImages: true, ImageDownloadTimeout: 50 * time.Millisecond, client: loopbackClient}
+		result, err := converter.Convert(context.Background(), imageMessage(server.URL+"/hang.png"), nil)
+		require.NoError(t, err)
+		source := result.([]anthropic.MessageParam)[0].Content[0].OfImage.Source
+		require.NotNil(t, source.OfURL)
+		assert.Equal(t, server.URL+"/hang.png", source.OfURL.URL)
+	})
+
+	t.Run("oversized image falls back to the URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, 2048))
+		}))
+		defer server.Close()
+
+		converter := &AnthropicConverter{InlineRemoteImages: true, MaxImageSizeB: 1024, client: loopbackClient}
+		result, err := converter.Convert(context.Background(), imageMessage(server.URL+"/big.png"), nil)
+		require.NoError(t, err)
+		source := result.([]anthropic.MessageParam)[0].Content[0].OfImage.Source
+		assert.Nil(t, source.OfBase64)
+		assert.NotNil(t, source.OfURL)
+	})
+}
+
+func TestAnthropicConverter_Convert_Audio(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Transcribe this"},
+			{
+				Type: "audio",
+				Meta: map[string]any{
+					"data":   "UklGRiQAAABXQVZF",
+					"format": "wav",
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs, ok := result.([]anthropic.MessageParam)
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+
+	// Audio is not dropped: it becomes a text placeholder without the raw data
+	require.Len(t, msgs[0].Content, 2)
+	require.NotNil(t, msgs[0].Content[1].OfText)
+	assert.Equal(t, "[audio: format=wav, base64 data omitted (16 bytes)]", msgs[0].Content[1].OfText.Text)
+}
+
+func TestAnthropicConverter_Convert_Video(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{
+				Type: "video",
+				Meta: map[string]any{
+					"url": "https://example.com/clip.mp4",
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs, ok := result.([]anthropic.MessageParam)
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0].Content, 1)
+	require.NotNil(t, msgs[0].Content[0].OfText)
+	assert.Equal(t, "[video: url=https://example.com/clip.mp4]", msgs[0].Content[0].OfText.Text)
+}
+
+func TestAnthropicConverter_Convert_PinnedAddsCacheControl(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Project rules"},
+		}, map[string]any{"pinned": true}),
+		createTestMessage("assistant", []model.Part{
+			{Type: "text", Text: "Understood"},
+			{Type: "text", Text: "Ready"},
+		}, map[string]any{"pinned": true}),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Go"},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs, ok := result.([]anthropic.MessageParam)
+	require.True(t, ok)
+	require.Len(t, msgs, 3)
+
+	// Only the last block of the latest pinned message becomes a cache breakpoint
+	assert.Equal(t, "", string(msgs[0].Content[0].OfText.CacheControl.Type))
+	assert.Equal(t, "", string(msgs[1].Content[0].OfText.CacheControl.Type))
+	assert.Equal(t, "ephemeral", string(msgs[1].Content[1].OfText.CacheControl.Type))
+	assert.Equal(t, "", string(msgs[2].Content[0].OfText.CacheControl.Type))
+}
+
+func TestAnthropicConverter_ConvertWithSystem(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Hello!"},
+		}, nil),
+	}
+
+	result, err := converter.ConvertWithSystem(context.Background(), messages, "You are a helpful assistant.", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "You are a helpful assistant.", result.System)
+
+	// The system prompt is not part of the messages
+	msgs, ok := result.Messages.([]anthropic.MessageParam)
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, anthropic.MessageParamRoleUser, msgs[0].Role)
+}
+
+func TestAnthropicConverter_ConvertWithSystem_SystemMessages(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Be brief."},
+		}, map[string]any{model.MessageMetaRoleKind: model.RoleKindDeveloper}),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Hello!"},
+		}, nil),
+	}
+
+	result, err := converter.ConvertWithSystem(context.Background(), messages, "You are a helpful assistant.", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "You are a helpful assistant.\n\nBe brief.", result.System)
+
+	// System and developer messages are moved out of the messages
+	msgs, ok := result.Messages.([]anthropic.MessageParam)
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Hello!", msgs[0].Content[0].OfText.Text)
+}
@@ -1,7 +1,11 @@
 package converter
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
@@ -12,15 +16,60 @@ type ConvertMessagesInput struct {
 	Messages   []model.Message
 	Format     model.MessageFormat
 	PublicURLs map[string]service.PublicURL
+	Options    ConvertOptions
+}
+
+// ConvertOptions tunes which messages are converted and how
+type ConvertOptions struct {
+	// ExcludeEphemeral leaves out messages with meta["ephemeral"]=true
+	ExcludeEphemeral bool
+	// InlineRemoteImages embeds remote images as base64 for Anthropic instead of passing their URL
+	InlineRemoteImages bool
+	// Assets reads the asset images embedded by InlineRemoteImages, usually the project's *blob.S3Deps
+	Assets AssetOpener
+	// ImageDownloadTimeout and MaxImageSizeB bound the downloads of InlineRemoteImages;
+	// zero uses the AnthropicConverter defaults
+	ImageDownloadTimeout time.Duration
+	MaxImageSizeB        int64
+	// DropReasoning leaves reasoning parts out of OpenAI assistant messages
+	DropReasoning bool
+	// EmptyAssistant handles OpenAI assistant messages with neither content nor tool calls
+	EmptyAssistant EmptyAssistantPolicy
+}
+
+// filter returns the messages selected by the options
+func (o ConvertOptions) filter(messages []model.Message) []model.Message {
+	if !o.ExcludeEphemeral {
+		return messages
+	}
+	result := make([]model.Message, 0, len(messages))
+	for i := range messages {
+		if !messages[i].IsEphemeral() {
+			result = append(result, messages[i])
+		}
+	}
+	return result
+}
+
+// AssetOpener reads stored assets by their S3 key
+type AssetOpener interface {
+	OpenFile(ctx context.Context, key string) (io.ReadCloser, error)
 }
 
 // MessageConverter interface for extensible message conversion
 type MessageConverter interface {
-	Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error)
+	Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error)
+}
+
+// SystemConvertResult is the output of a system-aware conversion
+type SystemConvertResult struct {
+	// System is set for providers that take the system prompt outside the message list (Anthropic)
+	System   string      `json:"system,omitempty"`
+	Messages interface{} `json:"messages"`
 }
 
 // ConvertMessages converts messages to the specified format
-func ConvertMessages(input ConvertMessagesInput) (interface{}, error) {
+func ConvertMessages(ctx context.Context, input ConvertMessagesInput) (interface{}, error) {
 	var converter MessageConverter
 
 	// Default to Acontext format if not specified
@@ -33,39 +82,75 @@ func ConvertMessages(input ConvertMessagesInput) (interface{}, error) {
 	case model.FormatAcontext:
 		converter = &AcontextConverter{}
 	case model.FormatOpenAI:
-		converter = &OpenAIConverter{}
+		converter = &OpenAIConverter{
+			DropReasoning:  input.Options.DropReasoning,
+			EmptyAssistant: input.Options.EmptyAssistant,
+		}
 	case model.FormatAnthropic:
-		converter = &AnthropicConverter{}
+		converter = &AnthropicConverter{
+			InlineRemoteImages:   input.Options.InlineRemoteImages,
+			Assets:               input.Options.Assets,
+			ImageDownloadTimeout: input.Options.ImageDownloadTimeout,
+			MaxImageSizeB:        input.Options.MaxImageSizeB,
+		}
+	case model.FormatVercel:
+		converter = &VercelConverter{}
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
 
-	return converter.Convert(input.Messages, input.PublicURLs)
+	return converter.Convert(ctx, input.Options.filter(input.Messages), input.PublicURLs)
 }
 
 // ValidateFormat checks if the format is valid
 func ValidateFormat(format string) (model.MessageFormat, error) {
 	mf := model.MessageFormat(format)
 	switch mf {
-	case model.FormatAcontext, model.FormatOpenAI, model.FormatAnthropic:
+	case model.FormatAcontext, model.FormatOpenAI, model.FormatAnthropic, model.FormatVercel:
 		return mf, nil
 	default:
-		return "", fmt.Errorf("invalid format: %s, supported formats: acontext, openai, anthropic", format)
+		return "", fmt.Errorf("invalid format: %s, supported formats: acontext, openai, anthropic, vercel", format)
 	}
 }
 
 // GetConvertedMessagesOutput wraps the converted messages with metadata
 func GetConvertedMessagesOutput(
+	ctx context.Context,
 	messages []model.Message,
 	format model.MessageFormat,
 	publicURLs map[string]service.PublicURL,
 	nextCursor string,
 	hasMore bool,
 ) (map[string]interface{}, error) {
-	convertedData, err := ConvertMessages(ConvertMessagesInput{
+	return GetConvertedMessagesOutputWithOptions(ctx, messages, format, publicURLs, nextCursor, hasMore, ConvertOptions{})
+}
+
+// GetConvertedMessagesOutputWithOptions is GetConvertedMessagesOutput with conversion options applied;
+// messages excluded by the options are left out of both items and ids
+func GetConvertedMessagesOutputWithOptions(
+	ctx context.Context,
+	messages []model.Message,
+	format model.MessageFormat,
+	publicURLs map[string]service.PublicURL,
+	nextCursor string,
+	hasMore bool,
+	opts ConvertOptions,
+) (map[string]interface{}, error) {
+	messages = opts.filter(messages)
+
+	convertedData, err := ConvertMessages(ctx, ConvertMessagesInput{
 		Messages:   messages,
 		Format:     format,
 		PublicURLs: publicURLs,
+		// Messages are filtered already
+		Options: ConvertOptions{
+			InlineRemoteImages:   opts.InlineRemoteImages,
+			Assets:               opts.Assets,
+			ImageDownloadTimeout: opts.ImageDownloadTimeout,
+			MaxImageSizeB:        opts.MaxImageSizeB,
+			DropReasoning:        opts.DropReasoning,
+			EmptyAssistant:       opts.EmptyAssistant,
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -94,3 +179,35 @@ func GetConvertedMessagesOutput(
 
 	return result, nil
 }
+
+// UnsupportedPartPlaceholder renders a media part that the target format cannot represent
+// (e.g. audio for Anthropic) as a text placeholder, so the content is not silently lost on
+// export. Inline base64 data is never copied into the placeholder, only its size.
+func UnsupportedPartPlaceholder(part model.Part, url string) string {
+	fields := []string{}
+	if part.Filename != "" {
+		fields = append(fields, "filename="+part.Filename)
+	}
+	for _, key := range []string{"format", "media_type"} {
+		if v, ok := part.Meta[key].(string); ok && v != "" {
+			fields = append(fields, key+"="+v)
+		}
+	}
+	if part.Asset != nil && part.Asset.MIME != "" {
+		fields = append(fields, "mime="+part.Asset.MIME)
+	}
+	if url == "" {
+		url, _ = part.Meta["url"].(string)
+	}
+	if url != "" {
+		fields = append(fields, "url="+url)
+	}
+	if data, ok := part.Meta["data"].(string); ok && data != "" {
+		fields = append(fields, fmt.Sprintf("base64 data omitted (%d bytes)", len(data)))
+	}
+
+	if len(fields) == 0 {
+		return fmt.Sprintf("[%s]", part.Type)
+	}
+	return fmt.Sprintf("[%s: %s]", part.Type, strings.Join(fields, ", "))
+}
@@ -1,12 +1,15 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/datatypes"
@@ -38,7 +41,7 @@ func TestConvertMessages_InvalidFormat(t *testing.T) {
 		}, nil),
 	}
 
-	_, err := ConvertMessages(ConvertMessagesInput{
+	_, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 		Messages:   messages,
 		Format:     "invalid_format",
 		PublicURLs: nil,
@@ -56,7 +59,7 @@ func TestConvertMessages_DefaultFormat(t *testing.T) {
 	}
 
 	// Empty format should default to Acontext
-	result, err := ConvertMessages(ConvertMessagesInput{
+	result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 		Messages:   messages,
 		Format:     "",
 		PublicURLs: nil,
@@ -82,7 +85,7 @@ func TestConvertMessages_AllFormats(t *testing.T) {
 
 	for _, format := range formats {
 		t.Run(string(format), func(t *testing.T) {
-			result, err := ConvertMessages(ConvertMessagesInput{
+			result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
 				Messages:   messages,
 				Format:     format,
 				PublicURLs: nil,
@@ -94,6 +97,36 @@ func TestConvertMessages_AllFormats(t *testing.T) {
 	}
 }
 
+func TestConvertMessages_UninitializedMeta(t *testing.T) {
+	// Meta is left as the zero JSONType, as for messages built without it
+	messages := []model.Message{
+		{ID: uuid.New(), Role: "user", Parts: []model.Part{{Type: "text", Text: "What is the weather?"}}},
+		{ID: uuid.New(), Role: "assistant", Parts: []model.Part{
+			{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": "{}"}},
+		}},
+		{ID: uuid.New(), Role: "user", Parts: []model.Part{{Type: "tool-result", Text: "Sunny", Meta: map[string]any{"tool_call_id": "call_1"}}}},
+	}
+
+	formats := []model.MessageFormat{
+		model.FormatAcontext,
+		model.FormatOpenAI,
+		model.FormatAnthropic,
+		model.FormatVercel,
+	}
+
+	for _, format := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+				Messages: messages,
+				Format:   format,
+			})
+
+			require.NoError(t, err)
+			assert.NotNil(t, result)
+		})
+	}
+}
+
 func TestValidateFormat(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -119,6 +152,12 @@ func TestValidateFormat(t *testing.T) {
 			want:    model.FormatAnthropic,
 			wantErr: false,
 		},
+		{
+			name:    "valid vercel",
+			format:  "vercel",
+			want:    model.FormatVercel,
+			wantErr: false,
+		},
 		{
 			name:    "invalid format",
 			format:  "invalid",
@@ -158,6 +197,7 @@ func TestGetConvertedMessagesOutput(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatAcontext,
 		publicURLs,
@@ -187,6 +227,7 @@ func TestGetConvertedMessagesOutput_NonAcontextFormat(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatOpenAI,
 		publicURLs,
@@ -208,6 +249,7 @@ func TestGetConvertedMessagesOutput_EmptyMessages(t *testing.T) {
 	messages := []model.Message{}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatOpenAI,
 		nil,
@@ -239,6 +281,7 @@ func TestGetConvertedMessagesOutput_SingleMessage(t *testing.T) {
 	messages := []model.Message{msg}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatAnthropic,
 		nil,
@@ -275,6 +318,7 @@ func TestGetConvertedMessagesOutput_IDOrderMatchesItemOrder(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatOpenAI,
 		nil,
@@ -304,6 +348,7 @@ func TestGetConvertedMessagesOutput_DifferentFormats(t *testing.T) {
 
 	for _, format := range formats {
 		result, err := GetConvertedMessagesOutput(
+			context.Background(),
 			messages,
 			format,
 			nil,
@@ -334,6 +379,7 @@ func TestGetConvertedMessagesOutput_WithPublicURLs(t *testing.T) {
 	}
 
 	result, err := GetConvertedMessagesOutput(
+		context.Background(),
 		messages,
 		model.FormatAcontext,
 		publicURLs,
@@ -350,3 +396,122 @@ func TestGetConvertedMessagesOutput_WithPublicURLs(t *testing.T) {
 	_, hasURLs := result["public_urls"]
 	assert.True(t, hasURLs, "public_urls should exist for Acontext format")
 }
+
+func TestConvertMessages_ExcludeEphemeral(t *testing.T) {
+	kept := createTestMessage("user", []model.Part{
+		{Type: "text", Text: "Keep me"},
+	}, nil)
+	ephemeral := createTestMessage("assistant", []model.Part{
+		{Type: "text", Text: "Scratch note"},
+	}, map[string]any{"ephemeral": true})
+	messages := []model.Message{kept, ephemeral}
+
+	t.Run("excluded when the option is set", func(t *testing.T) {
+		result, err := GetConvertedMessagesOutputWithOptions(context.Background(), messages, model.FormatOpenAI, nil, "", false, ConvertOptions{ExcludeEphemeral: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{kept.ID.String()}, result["ids"])
+
+		items, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatAcontext,
+			Options:  ConvertOptions{ExcludeEphemeral: true},
+		})
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+	})
+
+	t.Run("kept by default", func(t *testing.T) {
+		result, err := GetConvertedMessagesOutput(context.Background(), messages, model.FormatOpenAI, nil, "", false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{kept.ID.String(), ephemeral.ID.String()}, result["ids"])
+	})
+}
+
+// normalizeForFormat normalizes a message of the given format, as the store endpoint does
+func normalizeForFormat(t *testing.T, format model.MessageFormat, raw json.RawMessage) (string, []service.PartIn) {
+	t.Helper()
+	var (
+		role  string
+		parts []service.PartIn
+		err   error
+	)
+	switch format {
+	case model.FormatAcontext:
+		role, parts, _, err = (&normalizer.AcontextNormalizer{}).NormalizeFromAcontextMessage(raw)
+	case model.FormatOpenAI:
+		role, parts, _, err = (&normalizer.OpenAINormalizer{}).NormalizeFromOpenAIMessage(raw)
+	case model.FormatAnthropic:
+		role, parts, _, err = (&normalizer.AnthropicNormalizer{}).NormalizeFromAnthropicMessage(raw)
+	case model.FormatVercel:
+		role, parts, _, err = (&normalizer.VercelNormalizer{}).NormalizeFromVercelMessage(raw)
+	}
+	require.NoError(t, err, string(raw))
+	return role, parts
+}
+
+// storedMessage builds the message stored for normalized parts
+func storedMessage(role string, parts []service.PartIn) model.Message {
+	stored := make([]model.Part, 0, len(parts))
+	for _, p := range parts {
+		stored = append(stored, model.Part{Type: p.Type, ID: p.ID, Index: p.Index, Text: p.Text, Meta: p.Meta})
+	}
+	return createTestMessage(role, stored, nil)
+}
+
+func TestPartIDs_RoundTrip(t *testing.T) {
+	source := []string{
+		`{"role":"user","parts":[{"type":"text","text":"What is the weather in Paris?"},{"type":"image","meta":{"url":"https://example.com/paris.png","media_type":"image/png"}}]}`,
+		`{"role":"assistant","parts":[{"type":"text","text":"Let me check."},{"type":"tool-call","meta":{"id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}","type":"function"}}]}`,
+		`{"role":"user","parts":[{"type":"tool-result","text":"Sunny","meta":{"tool_call_id":"call_1"}}]}`,
+	}
+
+	messages := make([]model.Message, 0, len(source))
+	ids := []string{}
+	for _, raw := range source {
+		role, parts := normalizeForFormat(t, model.FormatAcontext, json.RawMessage(raw))
+		for i, p := range parts {
+			assert.NotEmpty(t, p.ID)
+			assert.Equal(t, i, p.Index)
+			ids = append(ids, p.ID)
+		}
+		messages = append(messages, storedMessage(role, parts))
+	}
+	// The image was uploaded when stored
+	messages[0].Parts[1].Asset = &model.Asset{S3Key: "assets/paris.png", MIME: "image/png"}
+	publicURLs := map[string]service.PublicURL{"assets/paris.png": {URL: "https://cdn.example.com/paris.png"}}
+	assert.Len(t, ids, 5)
+	seen := map[string]bool{}
+	for _, id := range ids {
+		assert.False(t, seen[id], "part IDs are unique")
+		seen[id] = true
+	}
+
+	for _, format := range []model.MessageFormat{model.FormatAcontext, model.FormatOpenAI, model.FormatAnthropic, model.FormatVercel} {
+		t.Run(string(format), func(t *testing.T) {
+			converted, err := ConvertMessages(context.Background(), ConvertMessagesInput{Messages: messages, Format: format, PublicURLs: publicURLs})
+			require.NoError(t, err)
+			raw, err := json.Marshal(converted)
+			require.NoError(t, err)
+			var items []json.RawMessage
+			require.NoError(t, json.Unmarshal(raw, &items))
+
+			// Formats may move parts between messages (e.g. Vercel folds tool results into
+			// the call), so the IDs are compared as a whole
+			got := []string{}
+			for _, item := range items {
+				_, parts := normalizeForFormat(t, format, item)
+				for _, p := range parts {
+					got = append(got, p.ID)
+				}
+			}
+			assert.ElementsMatch(t, ids, got)
+		})
+	}
+
+	t.Run("explicit IDs are kept", func(t *testing.T) {
+		_, parts := normalizeForFormat(t, model.FormatAcontext, json.RawMessage(`{"role":"user","parts":[{"type":"text","text":"Hi","id":"greeting"},{"type":"text","text":"There"}]}`))
+		assert.Equal(t, "greeting", parts[0].ID)
+		assert.Equal(t, 1, parts[1].Index)
+		assert.NotEmpty(t, parts[1].ID)
+	})
+}
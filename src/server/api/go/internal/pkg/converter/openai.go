@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"context"
 	"encoding/json"
 
 	openai "github.com/openai/openai-go/v3"
@@ -8,15 +9,41 @@ import (
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
 )
 
 // OpenAIConverter converts messages to OpenAI-compatible format using official SDK types
-type OpenAIConverter struct{}
+type OpenAIConverter struct {
+	// DropReasoning leaves reasoning parts out of assistant messages instead of
+	// returning them as reasoning_content
+	DropReasoning bool
+	// EmptyAssistant decides what happens to assistant messages left with neither content
+	// nor tool calls; the zero value is EmptyAssistantEmptyContent
+	EmptyAssistant EmptyAssistantPolicy
+}
+
+// EmptyAssistantPolicy handles assistant messages that would convert to neither content nor
+// tool calls (e.g. when all their parts are unsupported), which OpenAI rejects
+type EmptyAssistantPolicy string
 
-func (c *OpenAIConverter) Convert(messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+const (
+	// EmptyAssistantEmptyContent emits the message with an empty string content
+	EmptyAssistantEmptyContent EmptyAssistantPolicy = "empty_content"
+	// EmptyAssistantSkip leaves the message out, so the converted messages no longer line
+	// up one to one with the input
+	EmptyAssistantSkip EmptyAssistantPolicy = "skip"
+)
+
+func (c *OpenAIConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
 	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
 
 	for _, msg := range messages {
+		// System and developer messages kept by the normalizer get their own role back
+		if kind := msg.RoleKind(); kind == model.RoleKindSystem || kind == model.RoleKindDeveloper {
+			result = append(result, c.convertToSystemMessage(msg, kind))
+			continue
+		}
+
 		// Special handling: if user role contains only tool-result parts,
 		// convert to OpenAI's tool role
 		if msg.Role == "user" && c.isToolResultOnly(msg.Parts) {
@@ -29,8 +56,10 @@ func (c *OpenAIConverter) Convert(messages []model.Message, publicURLs map[strin
 				userMsg := c.convertToUserMessage(msg, publicURLs)
 				result = append(result, userMsg)
 			case "assistant":
-				assistantMsg := c.convertToAssistantMessage(msg)
-				result = append(result, assistantMsg)
+				assistantMsg, ok := c.convertToAssistantMessage(msg)
+				if ok {
+					result = append(result, assistantMsg)
+				}
 			default:
 				// Default to user message
 				userMsg := c.convertToUserMessage(msg, publicURLs)
@@ -42,6 +71,59 @@ func (c *OpenAIConverter) Convert(messages []model.Message, publicURLs map[strin
 	return result, nil
 }
 
+// ConvertWithSystem converts messages and prepends system as an OpenAI system message
+func (c *OpenAIConverter) ConvertWithSystem(ctx context.Context, messages []model.Message, system string, publicURLs map[string]service.PublicURL) (*SystemConvertResult, error) {
+	converted, err := c.Convert(ctx, messages, publicURLs)
+	if err != nil {
+		return nil, err
+	}
+	if system == "" {
+		return &SystemConvertResult{Messages: converted}, nil
+	}
+
+	msgs := converted.([]openai.ChatCompletionMessageParamUnion)
+	result := make([]openai.ChatCompletionMessageParamUnion, 0, len(msgs)+1)
+	result = append(result, openai.SystemMessage(system))
+	result = append(result, msgs...)
+	return &SystemConvertResult{Messages: result}, nil
+}
+
+// convertToSystemMessage re-emits a message stored with meta["role_kind"] as a system or
+// developer message. Only its text parts are kept.
+func (c *OpenAIConverter) convertToSystemMessage(msg model.Message, kind string) openai.ChatCompletionMessageParamUnion {
+	contentParts := make([]openai.ChatCompletionContentPartTextParam, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		if part.Type == "text" {
+			contentParts = append(contentParts, openai.ChatCompletionContentPartTextParam{Text: part.Text})
+		}
+	}
+	name, _ := msg.MetaMap()["name"].(string)
+
+	if kind == model.RoleKindDeveloper {
+		developer := openai.ChatCompletionDeveloperMessageParam{}
+		if len(contentParts) == 1 {
+			developer.Content.OfString = param.NewOpt(contentParts[0].Text)
+		} else {
+			developer.Content.OfArrayOfContentParts = contentParts
+		}
+		if name != "" {
+			developer.Name = param.NewOpt(name)
+		}
+		return openai.ChatCompletionMessageParamUnion{OfDeveloper: &developer}
+	}
+
+	system := openai.ChatCompletionSystemMessageParam{}
+	if len(contentParts) == 1 {
+		system.Content.OfString = param.NewOpt(contentParts[0].Text)
+	} else {
+		system.Content.OfArrayOfContentParts = contentParts
+	}
+	if name != "" {
+		system.Name = param.NewOpt(name)
+	}
+	return openai.ChatCompletionMessageParamUnion{OfSystem: &system}
+}
+
 func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map[string]service.PublicURL) openai.ChatCompletionMessageParamUnion {
 	// Check if content should be string or array
 	if len(msg.Parts) == 1 && msg.Parts[0].Type == "text" {
@@ -53,10 +135,8 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 		}
 
 		// Add name field from message meta if present
-		if metaData := msg.Meta.Data(); len(metaData) > 0 {
-			if name, ok := metaData["name"].(string); ok && name != "" {
-				userParam.Name = param.NewOpt(name)
-			}
+		if name, ok := msg.MetaMap()["name"].(string); ok && name != "" {
+			userParam.Name = param.NewOpt(name)
 		}
 
 		return openai.ChatCompletionMessageParamUnion{
@@ -101,6 +181,10 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 				}
 				contentParts = append(contentParts, openai.InputAudioContentPart(audioParam))
 			}
+		case "video":
+			// Chat Completions cannot carry video, keep a text placeholder with its URL/metadata
+			placeholder := UnsupportedPartPlaceholder(part, c.getAssetURL(part.Asset, publicURLs))
+			contentParts = append(contentParts, openai.TextContentPart(placeholder))
 		case "file":
 			if part.Meta != nil {
 				fileParam := openai.ChatCompletionContentPartFileFileParam{}
@@ -138,10 +222,8 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 	}
 
 	// Add name field from message meta if present
-	if metaData := msg.Meta.Data(); len(metaData) > 0 {
-		if name, ok := metaData["name"].(string); ok && name != "" {
-			userParam.Name = param.NewOpt(name)
-		}
+	if name, ok := msg.MetaMap()["name"].(string); ok && name != "" {
+		userParam.Name = param.NewOpt(name)
 	}
 
 	return openai.ChatCompletionMessageParamUnion{
@@ -149,14 +231,22 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 	}
 }
 
-func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.ChatCompletionMessageParamUnion {
-	// Separate text content and tool calls
-	var textContent string
+// convertToAssistantMessage returns false when the message is empty and skipped by the
+// EmptyAssistant policy
+func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) (openai.ChatCompletionMessageParamUnion, bool) {
+	// Separate text content, reasoning and tool calls
+	var textContent, reasoningContent string
 	var toolCalls []openai.ChatCompletionMessageToolCallUnionParam
 
 	for _, part := range msg.Parts {
 		switch part.Type {
 		case "text":
+			if part.IsReasoning() {
+				if !c.DropReasoning {
+					reasoningContent += part.Text
+				}
+				continue
+			}
 			textContent += part.Text
 		case "tool-call":
 			if part.Meta != nil {
@@ -171,7 +261,15 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 	// Build assistant message
 	assistantParam := openai.ChatCompletionAssistantMessageParam{}
 
-	if textContent != "" {
+	if textContent == "" && len(toolCalls) == 0 {
+		if c.EmptyAssistant == EmptyAssistantSkip {
+			return openai.ChatCompletionMessageParamUnion{}, false
+		}
+		// An assistant message needs content or tool calls, so emit an empty content
+		assistantParam.Content = openai.ChatCompletionAssistantMessageParamContentUnion{
+			OfString: param.NewOpt(""),
+		}
+	} else if textContent != "" {
 		assistantParam.Content = openai.ChatCompletionAssistantMessageParamContentUnion{
 			OfString: param.NewOpt(textContent),
 		}
@@ -181,16 +279,19 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 		assistantParam.ToolCalls = toolCalls
 	}
 
+	// reasoning_content is not part of the SDK types, as for OpenAI-compatible providers
+	if reasoningContent != "" {
+		assistantParam.SetExtraFields(map[string]any{"reasoning_content": reasoningContent})
+	}
+
 	// Add name field from message meta if present
-	if metaData := msg.Meta.Data(); len(metaData) > 0 {
-		if name, ok := metaData["name"].(string); ok && name != "" {
-			assistantParam.Name = param.NewOpt(name)
-		}
+	if name, ok := msg.MetaMap()["name"].(string); ok && name != "" {
+		assistantParam.Name = param.NewOpt(name)
 	}
 
 	return openai.ChatCompletionMessageParamUnion{
 		OfAssistant: &assistantParam,
-	}
+	}, true
 }
 
 func (c *OpenAIConverter) convertToToolMessage(msg model.Message) openai.ChatCompletionMessageParamUnion {
@@ -205,6 +306,19 @@ func (c *OpenAIConverter) convertToToolMessage(msg model.Message) openai.ChatCom
 		},
 	}
 
+	// name and content parts other than text are not part of the SDK types, but
+	// function-style tools and tools returning images rely on them
+	extra := map[string]any{}
+	if name := c.extractToolResultName(msg.Parts); name != "" {
+		extra["name"] = name
+	}
+	if contentParts := c.extractToolResultContentParts(msg.Parts); len(contentParts) > 0 {
+		extra["content"] = contentParts
+	}
+	if len(extra) > 0 {
+		toolParam.SetExtraFields(extra)
+	}
+
 	return openai.ChatCompletionMessageParamUnion{
 		OfTool: &toolParam,
 	}
@@ -269,6 +383,17 @@ func (c *OpenAIConverter) extractToolCallID(parts []model.Part) string {
 	return ""
 }
 
+func (c *OpenAIConverter) extractToolResultName(parts []model.Part) string {
+	for _, part := range parts {
+		if part.Type == "tool-result" && part.Meta != nil {
+			if name, ok := part.Meta["name"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
 func (c *OpenAIConverter) extractToolResultContent(parts []model.Part) string {
 	content := ""
 	for _, part := range parts {
@@ -279,6 +404,73 @@ func (c *OpenAIConverter) extractToolResultContent(parts []model.Part) string {
 	return content
 }
 
+// extractToolResultContentParts returns the content items of tool results holding images or
+// documents as OpenAI content parts, or nil when they are text only
+func (c *OpenAIConverter) extractToolResultContentParts(parts []model.Part) []openai.ChatCompletionContentPartUnionParam {
+	var contentParts []openai.ChatCompletionContentPartUnionParam
+	structured := false
+	for _, part := range parts {
+		if part.Type != "tool-result" {
+			continue
+		}
+		items, _ := part.Meta[normalizer.ToolResultContentKey].([]any)
+		if len(items) == 0 {
+			if part.Text != "" {
+				contentParts = append(contentParts, openai.TextContentPart(part.Text))
+			}
+			continue
+		}
+		for _, item := range items {
+			m, _ := item.(map[string]any)
+			source, _ := m["source"].(map[string]any)
+			switch m["type"] {
+			case "text":
+				if text, _ := m["text"].(string); text != "" {
+					contentParts = append(contentParts, openai.TextContentPart(text))
+				}
+			case "image":
+				if url := contentSourceURL(source); url != "" {
+					contentParts = append(contentParts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: url}))
+					structured = true
+				}
+			case "document":
+				// OpenAI takes documents as inline file data only
+				if source["type"] != "base64" {
+					continue
+				}
+				file := openai.ChatCompletionContentPartFileFileParam{FileData: param.NewOpt(contentSourceURL(source))}
+				if title, _ := m["title"].(string); title != "" {
+					file.Filename = param.NewOpt(title)
+				}
+				contentParts = append(contentParts, openai.FileContentPart(file))
+				structured = true
+			}
+		}
+	}
+	if !structured {
+		return nil
+	}
+	return contentParts
+}
+
+// contentSourceURL returns the source of a tool result content item as a URL, inlining
+// base64 data as a data URL
+func contentSourceURL(source map[string]any) string {
+	switch source["type"] {
+	case "base64":
+		mediaType, _ := source["media_type"].(string)
+		data, _ := source["data"].(string)
+		if data == "" {
+			return ""
+		}
+		return "data:" + mediaType + ";base64," + data
+	case "url":
+		url, _ := source["url"].(string)
+		return url
+	}
+	return ""
+}
+
 func (c *OpenAIConverter) getAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
 	if asset == nil {
 		return ""
@@ -1,9 +1,15 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,7 +23,7 @@ func TestOpenAIConverter_Convert_TextMessage(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 
 	// OpenAI converter returns []openai.ChatCompletionMessageParamUnion
@@ -43,7 +49,7 @@ func TestOpenAIConverter_Convert_AssistantWithToolCalls(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -63,7 +69,231 @@ func TestOpenAIConverter_Convert_ToolResult(t *testing.T) {
 		}, nil),
 	}
 
-	result, err := converter.Convert(messages, nil)
+	result, err := converter.Convert(context.Background(), messages, nil)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestOpenAIConverter_Convert_Video(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "What happens in this clip?"},
+			{
+				Type:     "video",
+				Filename: "clip.mp4",
+				Asset: &model.Asset{
+					S3Key: "assets/clip.mp4",
+					MIME:  "video/mp4",
+				},
+			},
+		}, nil),
+	}
+
+	publicURLs := map[string]service.PublicURL{
+		"assets/clip.mp4": {URL: "https://example.com/clip.mp4"},
+	}
+
+	result, err := converter.Convert(context.Background(), messages, publicURLs)
+	require.NoError(t, err)
+
+	msgs, ok := result.([]openai.ChatCompletionMessageParamUnion)
+	require.True(t, ok)
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].OfUser)
+
+	// Video degrades to a text placeholder instead of vanishing
+	content := msgs[0].OfUser.Content.OfArrayOfContentParts
+	require.Len(t, content, 2)
+	require.NotNil(t, content[1].OfText)
+	assert.Equal(t, "[video: filename=clip.mp4, mime=video/mp4, url=https://example.com/clip.mp4]", content[1].OfText.Text)
+}
+
+func TestOpenAIConverter_ConvertWithSystem(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Hello!"},
+		}, nil),
+	}
+
+	result, err := converter.ConvertWithSystem(context.Background(), messages, "You are a helpful assistant.", nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.System)
+
+	msgs, ok := result.Messages.([]openai.ChatCompletionMessageParamUnion)
+	require.True(t, ok)
+	require.Len(t, msgs, 2)
+	require.NotNil(t, msgs[0].OfSystem)
+	assert.Equal(t, "You are a helpful assistant.", msgs[0].OfSystem.Content.OfString.Value)
+	assert.NotNil(t, msgs[1].OfUser)
+
+	// Without a system prompt nothing is prepended
+	result, err = converter.ConvertWithSystem(context.Background(), messages, "", nil)
+	require.NoError(t, err)
+	assert.Len(t, result.Messages.([]openai.ChatCompletionMessageParamUnion), 1)
+}
+
+func TestOpenAIConverter_Convert_SystemMessages(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "You are a helpful assistant."},
+		}, map[string]any{model.MessageMetaRoleKind: model.RoleKindSystem}),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Be brief."},
+			{Type: "text", Text: "Use markdown."},
+		}, map[string]any{model.MessageMetaRoleKind: model.RoleKindDeveloper, "name": "ops"}),
+		createTestMessage("user", []model.Part{
+			{Type: "text", Text: "Hello!"},
+		}, nil),
+	}
+
+	result, err := converter.Convert(context.Background(), messages, nil)
+	require.NoError(t, err)
+
+	msgs, ok := result.([]openai.ChatCompletionMessageParamUnion)
+	require.True(t, ok)
+	require.Len(t, msgs, 3)
+	require.NotNil(t, msgs[0].OfSystem)
+	assert.Equal(t, "You are a helpful assistant.", msgs[0].OfSystem.Content.OfString.Value)
+	require.NotNil(t, msgs[1].OfDeveloper)
+	require.Len(t, msgs[1].OfDeveloper.Content.OfArrayOfContentParts, 2)
+	assert.Equal(t, "Use markdown.", msgs[1].OfDeveloper.Content.OfArrayOfContentParts[1].Text)
+	assert.Equal(t, "ops", msgs[1].OfDeveloper.Name.Value)
+	assert.NotNil(t, msgs[2].OfUser)
+}
+
+func TestOpenAIConverter_Convert_Reasoning(t *testing.T) {
+	raw := json.RawMessage(`{"role":"assistant","reasoning_content":"2+2 is basic arithmetic.","content":"4"}`)
+	role, parts := normalizeForFormat(t, model.FormatOpenAI, raw)
+	messages := []model.Message{storedMessage(role, parts)}
+
+	t.Run("round trip keeps reasoning_content separate", func(t *testing.T) {
+		result, err := (&OpenAIConverter{}).Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+		out, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[`+string(raw)+`]`, string(out))
+
+		var items []json.RawMessage
+		require.NoError(t, json.Unmarshal(out, &items))
+		_, again := normalizeForFormat(t, model.FormatOpenAI, items[0])
+		assert.Equal(t, parts, again)
+	})
+
+	t.Run("dropped by option", func(t *testing.T) {
+		result, err := ConvertMessages(context.Background(), ConvertMessagesInput{
+			Messages: messages,
+			Format:   model.FormatOpenAI,
+			Options:  ConvertOptions{DropReasoning: true},
+		})
+		require.NoError(t, err)
+		out, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"role":"assistant","content":"4"}]`, string(out))
+	})
+}
+
+func TestOpenAIConverter_Convert_ToolMessageName(t *testing.T) {
+	raw := json.RawMessage(`{"role":"tool","tool_call_id":"call_1","name":"get_weather","content":"Sunny"}`)
+	role, parts := normalizeForFormat(t, model.FormatOpenAI, raw)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "get_weather", parts[0].Meta["name"])
+
+	result, err := (&OpenAIConverter{}).Convert(context.Background(), []model.Message{storedMessage(role, parts)}, nil)
+	require.NoError(t, err)
+	out, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[`+string(raw)+`]`, string(out))
+}
+
+func TestOpenAIConverter_Convert_ToolMessageImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   model.MessageFormat
+		raw      string
+		expected string
+	}{
+		{
+			name:     "openai tool message round-trips",
+			format:   model.FormatOpenAI,
+			raw:      `{"role":"tool","tool_call_id":"call_1","content":[{"type":"text","text":"Here is the chart"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}`,
+			expected: `{"role":"tool","tool_call_id":"call_1","content":[{"type":"text","text":"Here is the chart"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}`,
+		},
+		{
+			name:     "anthropic tool result",
+			format:   model.FormatAnthropic,
+			raw:      `{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":[{"type":"text","text":"Here is the chart"},{"type":"image","source":{"type":"url","url":"https://example.com/chart.png"}}]}]}`,
+			expected: `{"role":"tool","tool_call_id":"call_1","content":[{"type":"text","text":"Here is the chart"},{"type":"image_url","image_url":{"url":"https://example.com/chart.png"}}]}`,
+		},
+		{
+			name:     "openai tool message with a document",
+			format:   model.FormatOpenAI,
+			raw:      `{"role":"tool","tool_call_id":"call_1","content":[{"type":"file","file":{"file_data":"data:application/pdf;base64,JVBERi0=","filename":"report.pdf"}}]}`,
+			expected: `{"role":"tool","tool_call_id":"call_1","content":[{"type":"file","file":{"file_data":"data:application/pdf;base64,JVBERi0=","filename":"report.pdf"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, parts := normalizeForFormat(t, tt.format, json.RawMessage(tt.raw))
+
+			result, err := (&OpenAIConverter{}).Convert(context.Background(), []model.Message{storedMessage(role, parts)}, nil)
+			require.NoError(t, err)
+			out, err := json.Marshal(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, `[`+tt.expected+`]`, string(out))
+		})
+	}
+}
+
+func TestOpenAIConverter_Convert_EmptyAssistant(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage("user", []model.Part{{Type: "text", Text: "Play it back"}}, nil),
+		createTestMessage("assistant", []model.Part{
+			{Type: "audio", Asset: &model.Asset{S3Key: "assets/reply.wav", MIME: "audio/wav"}},
+		}, nil),
+	}
+
+	tests := []struct {
+		name     string
+		policy   EmptyAssistantPolicy
+		expected string
+	}{
+		{
+			name:     "empty content by default",
+			expected: `[{"role":"user","content":"Play it back"},{"role":"assistant","content":""}]`,
+		},
+		{
+			name:     "empty content",
+			policy:   EmptyAssistantEmptyContent,
+			expected: `[{"role":"user","content":"Play it back"},{"role":"assistant","content":""}]`,
+		},
+		{
+			name:     "skip",
+			policy:   EmptyAssistantSkip,
+			expected: `[{"role":"user","content":"Play it back"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&OpenAIConverter{EmptyAssistant: tt.policy}).Convert(context.Background(), messages, nil)
+			require.NoError(t, err)
+			out, err := json.Marshal(result)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(out))
+
+			// Every emitted assistant message is one OpenAI accepts
+			for _, msg := range result.([]openai.ChatCompletionMessageParamUnion) {
+				if msg.OfAssistant != nil {
+					assert.True(t, !param.IsOmitted(msg.OfAssistant.Content.OfString) || len(msg.OfAssistant.ToolCalls) > 0)
+				}
+			}
+		})
+	}
+}
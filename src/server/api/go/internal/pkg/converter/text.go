@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// TextConverter renders messages as a plain-text transcript for logging and search
+// indexing. Every part is a line: text as "ROLE: text", tool calls as
+// "ROLE: [calls tool NAME(args)]" and tool results as "[tool NAME → result]". The
+// transcript is returned as a single string.
+type TextConverter struct{}
+
+func (c *TextConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	// tool names by tool call id, so results can name the tool they answer
+	toolNames := map[string]string{}
+	lines := []string{}
+
+	for _, msg := range messages {
+		role := msg.Role
+		// System and developer messages kept by the normalizer get their own role back
+		if kind := msg.RoleKind(); kind == model.RoleKindSystem || kind == model.RoleKindDeveloper {
+			role = kind
+		}
+		role = strings.ToUpper(role)
+
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "text":
+				lines = append(lines, fmt.Sprintf("%s: %s", role, part.Text))
+			case "tool-call":
+				name, _ := part.Meta["name"].(string)
+				if id, _ := part.Meta["id"].(string); id != "" {
+					toolNames[id] = name
+				}
+				lines = append(lines, fmt.Sprintf("%s: [calls tool %s(%s)]", role, name, toolCallArguments(part.Meta["arguments"])))
+			case "tool-result":
+				id, _ := part.Meta["tool_call_id"].(string)
+				name, ok := toolNames[id]
+				if !ok {
+					name, _ = part.Meta["name"].(string)
+				}
+				if name == "" {
+					name = id
+				}
+				lines = append(lines, fmt.Sprintf("[tool %s → %s]", name, part.Text))
+			default:
+				url := ""
+				if part.Asset != nil {
+					url = publicURLs[part.Asset.S3Key].URL
+				}
+				lines = append(lines, fmt.Sprintf("%s: %s", role, UnsupportedPartPlaceholder(part, url)))
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// toolCallArguments returns the arguments of a tool call as stored, or as JSON when they
+// are not a string
+func toolCallArguments(arguments any) string {
+	switch v := arguments.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
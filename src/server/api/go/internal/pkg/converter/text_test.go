@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextConverter_Convert(t *testing.T) {
+	converter := &TextConverter{}
+
+	t.Run("multi-turn conversation with a tool call", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("user", []model.Part{
+				{Type: "text", Text: "You are a weather bot."},
+			}, map[string]any{model.MessageMetaRoleKind: model.RoleKindSystem}),
+			createTestMessage("user", []model.Part{
+				{Type: "text", Text: "What's the weather in Paris?"},
+			}, nil),
+			createTestMessage("assistant", []model.Part{
+				{Type: "text", Text: "Let me check."},
+				{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": `{"city":"Paris"}`}},
+			}, nil),
+			createTestMessage("user", []model.Part{
+				{Type: "tool-result", Text: "sunny, 24°C", Meta: map[string]any{"tool_call_id": "call_1"}},
+			}, nil),
+			createTestMessage("assistant", []model.Part{
+				{Type: "text", Text: "It is sunny and 24°C in Paris."},
+			}, nil),
+		}
+
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "SYSTEM: You are a weather bot.\n"+
+			"USER: What's the weather in Paris?\n"+
+			"ASSISTANT: Let me check.\n"+
+			"ASSISTANT: [calls tool get_weather({\"city\":\"Paris\"})]\n"+
+			"[tool get_weather → sunny, 24°C]\n"+
+			"ASSISTANT: It is sunny and 24°C in Paris.", result)
+	})
+
+	t.Run("structured arguments and unmatched results", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("assistant", []model.Part{
+				{Type: "tool-call", Meta: map[string]any{"id": "call_2", "name": "search", "arguments": map[string]any{"q": "acontext"}}},
+			}, nil),
+			createTestMessage("user", []model.Part{
+				{Type: "tool-result", Text: "3 hits", Meta: map[string]any{"tool_call_id": "call_9", "name": "lookup"}},
+				{Type: "tool-result", Text: "done", Meta: map[string]any{"tool_call_id": "call_10"}},
+			}, nil),
+		}
+
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "ASSISTANT: [calls tool search({\"q\":\"acontext\"})]\n"+
+			"[tool lookup → 3 hits]\n"+
+			"[tool call_10 → done]", result)
+	})
+
+	t.Run("media parts are placeholders", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("user", []model.Part{
+				{Type: "image", Asset: &model.Asset{S3Key: "parts/cat.png", MIME: "image/png"}, Filename: "cat.png"},
+			}, nil),
+		}
+		publicURLs := map[string]service.PublicURL{"parts/cat.png": {URL: "https://s3/cat.png"}}
+
+		result, err := converter.Convert(context.Background(), messages, publicURLs)
+		require.NoError(t, err)
+		assert.Equal(t, "USER: [image: filename=cat.png, mime=image/png, url=https://s3/cat.png]", result)
+	})
+
+	t.Run("no messages", func(t *testing.T) {
+		result, err := converter.Convert(context.Background(), nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "", result)
+	})
+}
@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+)
+
+// VercelConverter converts messages to Vercel AI SDK UI messages
+type VercelConverter struct{}
+
+// VercelMessage is a Vercel AI SDK UI message. Content repeats the text of the parts for
+// clients that only read it.
+type VercelMessage struct {
+	ID      string       `json:"id"`
+	Role    string       `json:"role"`
+	Content string       `json:"content"`
+	Parts   []VercelPart `json:"parts"`
+}
+
+// VercelPart is one part of a Vercel message: text, tool-invocation, file, or tool-result
+// for a result whose call is not among the converted messages
+type VercelPart struct {
+	Type           string                `json:"type"`
+	Text           string                `json:"text,omitempty"`
+	ToolInvocation *VercelToolInvocation `json:"toolInvocation,omitempty"`
+	ToolCallID     string                `json:"toolCallId,omitempty"`
+	ToolName       string                `json:"toolName,omitempty"`
+	Result         any                   `json:"result,omitempty"`
+	MimeType       string                `json:"mimeType,omitempty"`
+	Data           string                `json:"data,omitempty"`
+	Filename       string                `json:"filename,omitempty"`
+}
+
+// VercelToolInvocation is a tool call, in state "call", or "result" once its result is known
+type VercelToolInvocation struct {
+	State      string `json:"state"`
+	ToolCallID string `json:"toolCallId"`
+	ToolName   string `json:"toolName"`
+	Args       any    `json:"args"`
+	Result     any    `json:"result,omitempty"`
+}
+
+// Convert converts internal messages to Vercel format. A tool result is folded into the
+// tool-invocation of its call, as the AI SDK expects, and a message left with no parts by
+// that is dropped.
+func (c *VercelConverter) Convert(ctx context.Context, messages []model.Message, publicURLs map[string]service.PublicURL) (interface{}, error) {
+	result := make([]VercelMessage, 0, len(messages))
+	invocations := map[string]*VercelToolInvocation{}
+
+	for _, msg := range messages {
+		role := msg.Role
+		// System and developer messages kept by the normalizers get the system role back
+		if kind := msg.RoleKind(); kind == model.RoleKindSystem || kind == model.RoleKindDeveloper {
+			role = "system"
+		}
+
+		vmsg := VercelMessage{ID: msg.ID.String(), Role: role, Parts: make([]VercelPart, 0, len(msg.Parts))}
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "text":
+				vmsg.Parts = append(vmsg.Parts, VercelPart{Type: "text", Text: part.Text})
+				vmsg.Content += part.Text
+			case "tool-call":
+				inv := c.convertToolCall(part)
+				if inv == nil {
+					continue
+				}
+				invocations[inv.ToolCallID] = inv
+				vmsg.Parts = append(vmsg.Parts, VercelPart{Type: "tool-invocation", ToolInvocation: inv})
+			case "tool-result":
+				toolCallID, _ := part.Meta["tool_call_id"].(string)
+				if inv, ok := invocations[toolCallID]; ok {
+					inv.State = "result"
+					inv.Result = c.toolResult(part)
+					continue
+				}
+				toolName, _ := part.Meta["name"].(string)
+				vmsg.Parts = append(vmsg.Parts, VercelPart{Type: "tool-result", ToolCallID: toolCallID, ToolName: toolName, Result: c.toolResult(part)})
+			case "image", "audio", "video", "file":
+				if file, ok := c.convertFile(part, publicURLs); ok {
+					vmsg.Parts = append(vmsg.Parts, file)
+				} else {
+					placeholder := UnsupportedPartPlaceholder(part, "")
+					vmsg.Parts = append(vmsg.Parts, VercelPart{Type: "text", Text: placeholder})
+					vmsg.Content += placeholder
+				}
+			default:
+				placeholder := UnsupportedPartPlaceholder(part, "")
+				vmsg.Parts = append(vmsg.Parts, VercelPart{Type: "text", Text: placeholder})
+				vmsg.Content += placeholder
+			}
+		}
+
+		if len(vmsg.Parts) == 0 && len(msg.Parts) > 0 {
+			continue
+		}
+		result = append(result, vmsg)
+	}
+
+	return result, nil
+}
+
+// convertToolCall maps a unified tool-call part to a tool invocation, parsing its arguments
+func (c *VercelConverter) convertToolCall(part model.Part) *VercelToolInvocation {
+	id, _ := part.Meta["id"].(string)
+	name, _ := part.Meta["name"].(string)
+	if id == "" || name == "" {
+		return nil
+	}
+
+	var args any = map[string]any{}
+	switch arguments := part.Meta["arguments"].(type) {
+	case string:
+		if arguments != "" && json.Unmarshal([]byte(arguments), &args) != nil {
+			// Not JSON, keep the raw arguments
+			args = arguments
+		}
+	case nil:
+	default:
+		args = arguments
+	}
+
+	return &VercelToolInvocation{State: "call", ToolCallID: id, ToolName: name, Args: args}
+}
+
+// toolResult returns the result of a tool-result part: its JSON result when the normalizer
+// kept one, its text otherwise
+func (c *VercelConverter) toolResult(part model.Part) any {
+	if result, ok := part.Meta[normalizer.VercelToolResultKey]; ok {
+		return result
+	}
+	return part.Text
+}
+
+// convertFile maps a media part to a file part, with its inline data or URL
+func (c *VercelConverter) convertFile(part model.Part, publicURLs map[string]service.PublicURL) (VercelPart, bool) {
+	file := VercelPart{Type: "file", Filename: part.Filename}
+	file.MimeType, _ = part.Meta["media_type"].(string)
+	if file.Filename == "" {
+		file.Filename, _ = part.Meta["filename"].(string)
+	}
+	if part.Asset != nil && file.MimeType == "" {
+		file.MimeType = part.Asset.MIME
+	}
+
+	if data, ok := part.Meta["data"].(string); ok && data != "" {
+		file.Data = data
+	} else if url := c.getAssetURL(part.Asset, publicURLs); url != "" {
+		file.Data = url
+	} else if url, ok := part.Meta["url"].(string); ok && url != "" {
+		file.Data = url
+	}
+	return file, file.Data != ""
+}
+
+func (c *VercelConverter) getAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
+	if asset == nil {
+		return ""
+	}
+	if publicURL, ok := publicURLs[asset.S3Key]; ok {
+		return publicURL.URL
+	}
+	return ""
+}
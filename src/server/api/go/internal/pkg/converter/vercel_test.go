@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVercelConverter_Convert(t *testing.T) {
+	converter := &VercelConverter{}
+
+	t.Run("tool results are folded into their invocation", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("assistant", []model.Part{
+				{Type: "text", Text: "Let me check."},
+				{Type: "tool-call", Meta: map[string]any{"id": "call_1", "name": "get_weather", "arguments": `{"city":"Paris"}`, "type": "function"}},
+			}, nil),
+			createTestMessage("user", []model.Part{
+				{Type: "tool-result", Text: "sunny", Meta: map[string]any{"tool_call_id": "call_1"}},
+			}, nil),
+			createTestMessage("user", []model.Part{
+				{Type: "tool-result", Text: "orphan", Meta: map[string]any{"tool_call_id": "call_unknown", "name": "lookup"}},
+			}, nil),
+		}
+
+		result, err := converter.Convert(context.Background(), messages, nil)
+		require.NoError(t, err)
+		vmsgs := result.([]VercelMessage)
+		require.Len(t, vmsgs, 2)
+
+		assert.Equal(t, messages[0].ID.String(), vmsgs[0].ID)
+		assert.Equal(t, "Let me check.", vmsgs[0].Content)
+		assert.Equal(t, &VercelToolInvocation{
+			State:      "result",
+			ToolCallID: "call_1",
+			ToolName:   "get_weather",
+			Args:       map[string]any{"city": "Paris"},
+			Result:     "sunny",
+		}, vmsgs[0].Parts[1].ToolInvocation)
+
+		// A result whose call is not in the conversation stays a tool-result part
+		assert.Equal(t, messages[2].ID.String(), vmsgs[1].ID)
+		assert.Equal(t, []VercelPart{{Type: "tool-result", ToolCallID: "call_unknown", ToolName: "lookup", Result: "orphan"}}, vmsgs[1].Parts)
+	})
+
+	t.Run("asset parts use their public URL", func(t *testing.T) {
+		messages := []model.Message{
+			createTestMessage("user", []model.Part{
+				{Type: "image", Asset: &model.Asset{S3Key: "parts/cat.png", MIME: "image/png"}, Filename: "cat.png"},
+			}, nil),
+		}
+		publicURLs := map[string]service.PublicURL{"parts/cat.png": {URL: "https://s3/cat.png"}}
+
+		result, err := converter.Convert(context.Background(), messages, publicURLs)
+		require.NoError(t, err)
+		assert.Equal(t, []VercelPart{{Type: "file", MimeType: "image/png", Data: "https://s3/cat.png", Filename: "cat.png"}}, result.([]VercelMessage)[0].Parts)
+	})
+}
+
+func TestVercel_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name: "user message with files",
+			input: `{
+				"role": "user",
+				"content": "Summarize these",
+				"parts": [
+					{"type": "text", "text": "Summarize these"},
+					{"type": "file", "mimeType": "image/png", "data": "aGVsbG8="},
+					{"type": "file", "mimeType": "application/pdf", "data": "https://example.com/report.pdf", "filename": "report.pdf"}
+				]
+			}`,
+		},
+		{
+			name: "assistant message with tool invocations",
+			input: `{
+				"role": "assistant",
+				"content": "Checking both.",
+				"parts": [
+					{"type": "text", "text": "Checking both."},
+					{"type": "tool-invocation", "toolInvocation": {"state": "result", "toolCallId": "call_1", "toolName": "get_weather", "args": {"city": "Paris"}, "result": {"temp": 21}}},
+					{"type": "tool-invocation", "toolInvocation": {"state": "result", "toolCallId": "call_2", "toolName": "get_time", "args": {}, "result": "10:00"}},
+					{"type": "tool-invocation", "toolInvocation": {"state": "call", "toolCallId": "call_3", "toolName": "get_news", "args": {"topic": "go"}}}
+				]
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, partsIn, _, err := (&normalizer.VercelNormalizer{}).NormalizeFromVercelMessage(json.RawMessage(tt.input))
+			require.NoError(t, err)
+
+			parts := make([]model.Part, 0, len(partsIn))
+			for _, p := range partsIn {
+				parts = append(parts, model.Part{Type: p.Type, Text: p.Text, Meta: p.Meta})
+			}
+			msg := createTestMessage(role, parts, nil)
+
+			result, err := (&VercelConverter{}).Convert(context.Background(), []model.Message{msg}, nil)
+			require.NoError(t, err)
+			vmsgs := result.([]VercelMessage)
+			require.Len(t, vmsgs, 1)
+
+			got, err := json.Marshal(vmsgs[0])
+			require.NoError(t, err)
+			var want map[string]any
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &want))
+			want["id"] = msg.ID.String()
+			wantJSON, err := json.Marshal(want)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wantJSON), string(got))
+		})
+	}
+}
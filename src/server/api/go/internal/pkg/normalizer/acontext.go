@@ -8,12 +8,21 @@ import (
 )
 
 // AcontextNormalizer normalizes Acontext (internal) format
-type AcontextNormalizer struct{}
+type AcontextNormalizer struct {
+	// CompactParts merges adjacent text parts of the normalized message (see service.CompactParts)
+	CompactParts bool
+	// Limits bounds the size of the accepted messages
+	Limits Limits
+}
 
 // NormalizeFromAcontextMessage converts Acontext format to internal format
 // This is essentially a validation step since Acontext IS the internal format
 // Returns: role, parts, messageMeta, error
 func (n *AcontextNormalizer) NormalizeFromAcontextMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(messageJSON); err != nil {
+		return "", nil, nil, err
+	}
+
 	var msg struct {
 		Role  string                 `json:"role"`
 		Parts []service.PartIn       `json:"parts"`
@@ -30,6 +39,10 @@ func (n *AcontextNormalizer) NormalizeFromAcontextMessage(messageJSON json.RawMe
 		return "", nil, nil, fmt.Errorf("invalid role: %s (must be one of: user, assistant)", msg.Role)
 	}
 
+	if err := n.Limits.checkParts(msg.Parts); err != nil {
+		return "", nil, nil, err
+	}
+
 	// Validate each part
 	for i, part := range msg.Parts {
 		if err := part.Validate(); err != nil {
@@ -48,5 +61,10 @@ func (n *AcontextNormalizer) NormalizeFromAcontextMessage(messageJSON json.RawMe
 		messageMeta["source_format"] = "acontext"
 	}
 
+	if n.CompactParts {
+		msg.Parts = service.CompactParts(msg.Parts)
+	}
+	msg.Parts = service.AssignPartIDs(msg.Parts)
+
 	return msg.Role, msg.Parts, messageMeta, nil
 }
@@ -3,6 +3,7 @@ package normalizer
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/openai/openai-go/v3/packages/param"
@@ -11,11 +12,20 @@ import (
 )
 
 // AnthropicNormalizer normalizes Anthropic format to internal format using official SDK types
-type AnthropicNormalizer struct{}
+type AnthropicNormalizer struct {
+	// CompactParts merges adjacent text parts of the normalized message (see service.CompactParts)
+	CompactParts bool
+	// Limits bounds the size of the accepted messages
+	Limits Limits
+}
 
 // NormalizeFromAnthropicMessage converts Anthropic MessageParam to internal format
 // Returns: role, parts, messageMeta, error
 func (n *AnthropicNormalizer) NormalizeFromAnthropicMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(messageJSON); err != nil {
+		return "", nil, nil, err
+	}
+
 	// Parse using official Anthropic SDK types
 	var message anthropic.MessageParam
 	if err := message.UnmarshalJSON(messageJSON); err != nil {
@@ -28,6 +38,10 @@ func (n *AnthropicNormalizer) NormalizeFromAnthropicMessage(messageJSON json.Raw
 		return "", nil, nil, fmt.Errorf("invalid Anthropic role: %s (only 'user' and 'assistant' are supported)", role)
 	}
 
+	if err := n.Limits.checkPartCount(len(message.Content)); err != nil {
+		return "", nil, nil, err
+	}
+
 	// Convert content blocks
 	parts := []service.PartIn{}
 	for _, blockUnion := range message.Content {
@@ -37,12 +51,20 @@ func (n *AnthropicNormalizer) NormalizeFromAnthropicMessage(messageJSON json.Raw
 		}
 		parts = append(parts, part)
 	}
+	if err := n.Limits.checkParts(parts); err != nil {
+		return "", nil, nil, err
+	}
 
 	// Extract message-level metadata
 	messageMeta := map[string]interface{}{
 		"source_format": "anthropic",
 	}
 
+	if n.CompactParts {
+		parts = service.CompactParts(parts)
+	}
+	parts = service.AssignPartIDs(parts)
+
 	return role, parts, messageMeta, nil
 }
 
@@ -107,11 +129,15 @@ func normalizeAnthropicContentBlock(blockUnion anthropic.ContentBlockParamUnion)
 			Meta: meta,
 		}, nil
 	} else if blockUnion.OfToolResult != nil {
-		// Handle tool result content
+		// Handle tool result content; the text is concatenated, and when the result also
+		// holds images or documents every item is kept in meta "content"
 		var resultText string
+		structured := false
 		for _, contentItem := range blockUnion.OfToolResult.Content {
 			if contentItem.OfText != nil {
 				resultText += contentItem.OfText.Text
+			} else {
+				structured = true
 			}
 		}
 
@@ -131,6 +157,14 @@ func normalizeAnthropicContentBlock(blockUnion anthropic.ContentBlockParamUnion)
 			meta["cache_control"] = ExtractAnthropicCacheControl(blockUnion.OfToolResult.CacheControl)
 		}
 
+		if structured {
+			content, err := toolResultContentMeta(blockUnion.OfToolResult.Content)
+			if err != nil {
+				return service.PartIn{}, err
+			}
+			meta[ToolResultContentKey] = content
+		}
+
 		return service.PartIn{
 			Type: "tool-result",
 			Text: resultText,
@@ -162,6 +196,25 @@ func normalizeAnthropicContentBlock(blockUnion anthropic.ContentBlockParamUnion)
 	return service.PartIn{}, fmt.Errorf("unsupported Anthropic content block type")
 }
 
+// ToolResultContentKey is the tool-result part meta key holding the content items of an
+// Anthropic tool result that is not plain text, in the Anthropic format, e.g.
+//
+//	[{"type": "text", "text": "..."}, {"type": "image", "source": {"type": "base64", ...}}]
+const ToolResultContentKey = "content"
+
+// toolResultContentMeta stores tool result content items as plain JSON values
+func toolResultContentMeta(items []anthropic.ToolResultBlockParamContentUnion) ([]any, error) {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool result content: %w", err)
+	}
+	var content []any
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool result content: %w", err)
+	}
+	return content, nil
+}
+
 // CacheControl represents cache control configuration
 type CacheControl struct {
 	Type string `json:"type"` // "ephemeral"
@@ -196,3 +249,57 @@ func BuildAnthropicCacheControl(meta map[string]any) *anthropic.CacheControlEphe
 	param := anthropic.NewCacheControlEphemeralParam()
 	return &param
 }
+
+// toolResultContentItems converts the unified parts of a non-Anthropic tool result to
+// content items stored under ToolResultContentKey. Parts without an Anthropic counterpart,
+// such as audio or files referenced by ID, keep only their text in the part.
+func toolResultContentItems(parts []service.PartIn) []any {
+	items := make([]any, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				items = append(items, map[string]any{"type": "text", "text": p.Text})
+			}
+		case "image":
+			if source := contentItemSource(p.Meta, "url"); source != nil {
+				items = append(items, map[string]any{"type": "image", "source": source})
+			}
+		case "file":
+			if source := contentItemSource(p.Meta, "file_data"); source != nil {
+				item := map[string]any{"type": "document", "source": source}
+				if filename, ok := p.Meta["filename"].(string); ok && filename != "" {
+					item["title"] = filename
+				}
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// contentItemSource returns the Anthropic source of an image or file part: its base64 data,
+// the data URL or plain URL under urlKey, or nil when it has neither
+func contentItemSource(meta map[string]interface{}, urlKey string) map[string]any {
+	if data, ok := meta["data"].(string); ok && data != "" {
+		mediaType, _ := meta["media_type"].(string)
+		return map[string]any{"type": "base64", "media_type": mediaType, "data": data}
+	}
+	url, _ := meta[urlKey].(string)
+	if url == "" {
+		return nil
+	}
+	if strings.HasPrefix(url, "data:") {
+		header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok {
+			return nil
+		}
+		mediaType, _, _ := strings.Cut(header, ";")
+		return map[string]any{"type": "base64", "media_type": mediaType, "data": data}
+	}
+	if urlKey == "file_data" {
+		// file_data without a data: prefix is the bare base64 payload of a PDF, the only file type OpenAI takes
+		return map[string]any{"type": "base64", "media_type": "application/pdf", "data": url}
+	}
+	return map[string]any{"type": "url", "url": url}
+}
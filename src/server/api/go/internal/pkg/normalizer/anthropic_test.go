@@ -7,6 +7,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAnthropicNormalizer_NormalizeFromAnthropicMessage(t *testing.T) {
@@ -306,6 +307,33 @@ func TestAnthropicNormalizer_ContentBlockTypes(t *testing.T) {
 				// UNIFIED FORMAT: was "tool_use_id", now "tool_call_id"
 				assert.Equal(t, "toolu_789", meta["tool_call_id"])
 				assert.Equal(t, false, meta["is_error"])
+				assert.NotContains(t, meta, ToolResultContentKey)
+			},
+		},
+		{
+			name: "tool_result block with image",
+			input: `{
+				"role": "user",
+				"content": [
+					{
+						"type": "tool_result",
+						"tool_use_id": "toolu_789",
+						"content": [
+							{"type": "text", "text": "Screenshot taken"},
+							{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+						]
+					}
+				]
+			}`,
+			wantPartType: "tool-result",
+			checkMeta: func(t *testing.T, meta map[string]interface{}) {
+				assert.Equal(t, "toolu_789", meta["tool_call_id"])
+				content, ok := meta[ToolResultContentKey].([]any)
+				require.True(t, ok)
+				require.Len(t, content, 2)
+				image := content[1].(map[string]any)
+				assert.Equal(t, "image", image["type"])
+				assert.Equal(t, "iVBORw0KGgo=", image["source"].(map[string]any)["data"])
 			},
 		},
 		{
@@ -0,0 +1,316 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// LangChainNormalizer normalizes LangChain message dumps (BaseMessage.model_dump()) to
+// internal format
+type LangChainNormalizer struct {
+	// CompactParts merges adjacent text parts of the normalized message (see service.CompactParts)
+	CompactParts bool
+	// Limits bounds the size of the accepted messages
+	Limits Limits
+}
+
+// langChainMessage is a dumped LangChain message. Only the fields needed to map the
+// human, ai and tool messages are decoded.
+type langChainMessage struct {
+	Type             string              `json:"type"`
+	Content          json.RawMessage     `json:"content"`
+	Name             string              `json:"name"`
+	AdditionalKwargs langChainKwargs     `json:"additional_kwargs"`
+	ToolCalls        []langChainToolCall `json:"tool_calls"`
+	ToolCallID       string              `json:"tool_call_id"`
+	Status           string              `json:"status"`
+}
+
+// langChainKwargs holds the provider fields of a message. Older LangChain versions only
+// keep the tool calls of an ai message here, in the OpenAI format.
+type langChainKwargs struct {
+	ToolCalls []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// langChainToolCall is a tool call of an ai message, with parsed arguments
+type langChainToolCall struct {
+	ID   string                 `json:"id"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// langChainContentBlock is one item of a multimodal content list
+type langChainContentBlock struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text"`
+	ImageURL json.RawMessage `json:"image_url"`
+	// Standard image blocks: source_type is "url" or "base64"
+	SourceType string `json:"source_type"`
+	URL        string `json:"url"`
+	Data       string `json:"data"`
+	Base64     string `json:"base64"`
+	MimeType   string `json:"mime_type"`
+}
+
+// NormalizeFromLangChainMessage converts a LangChain message dump to internal format.
+// human messages map to user and ai messages to assistant; tool messages are stored as
+// user messages with a tool-result part.
+// Returns: role, parts, messageMeta, error
+func (n *LangChainNormalizer) NormalizeFromLangChainMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(messageJSON); err != nil {
+		return "", nil, nil, err
+	}
+	role, parts, messageMeta, err := normalizeLangChainMessage(messageJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := n.Limits.checkParts(parts); err != nil {
+		return "", nil, nil, err
+	}
+	if n.CompactParts {
+		parts = service.CompactParts(parts)
+	}
+	parts = service.AssignPartIDs(parts)
+	return role, parts, messageMeta, nil
+}
+
+func normalizeLangChainMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	var msg langChainMessage
+	if err := json.Unmarshal(messageJSON, &msg); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to unmarshal LangChain message: %w", err)
+	}
+
+	messageMeta := map[string]interface{}{
+		"source_format": "langchain",
+	}
+	if msg.Name != "" && msg.Type != "tool" {
+		messageMeta["name"] = msg.Name
+	}
+
+	switch msg.Type {
+	case "human":
+		parts, err := normalizeLangChainContent(msg.Content)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if len(parts) == 0 {
+			return "", nil, nil, fmt.Errorf("LangChain human message must have content")
+		}
+		return "user", parts, messageMeta, nil
+	case "ai":
+		parts, err := normalizeLangChainContent(msg.Content)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		toolCalls, err := normalizeLangChainToolCalls(msg)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		parts = append(parts, toolCalls...)
+		if len(parts) == 0 {
+			return "", nil, nil, fmt.Errorf("LangChain ai message must have content or tool_calls")
+		}
+		return "assistant", parts, messageMeta, nil
+	case "tool":
+		part, err := normalizeLangChainToolMessage(msg)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return "user", []service.PartIn{part}, messageMeta, nil
+	case "system":
+		return "", nil, nil, fmt.Errorf("system messages are not supported. Use session-level or skill-level configuration for system prompts")
+	}
+
+	return "", nil, nil, fmt.Errorf("unsupported LangChain message type: %s", msg.Type)
+}
+
+// normalizeLangChainContent converts the content of a message, either a string or a list of
+// strings and content blocks. Empty text is dropped.
+func normalizeLangChainContent(raw json.RawMessage) ([]service.PartIn, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text == "" {
+			return nil, nil
+		}
+		return []service.PartIn{{Type: "text", Text: text}}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal LangChain message content: %w", err)
+	}
+
+	parts := make([]service.PartIn, 0, len(items))
+	for _, item := range items {
+		if err := json.Unmarshal(item, &text); err == nil {
+			if text != "" {
+				parts = append(parts, service.PartIn{Type: "text", Text: text})
+			}
+			continue
+		}
+
+		var block langChainContentBlock
+		if err := json.Unmarshal(item, &block); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal LangChain content block: %w", err)
+		}
+		part, ok, err := normalizeLangChainContentBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			parts = append(parts, part)
+		}
+	}
+	return parts, nil
+}
+
+var errLangChainImageSource = errors.New("LangChain image block requires a url or base64 data")
+
+// normalizeLangChainContentBlock converts a content block. It reports false for blocks that
+// are skipped: empty text and the tool_use blocks of Anthropic models, which LangChain
+// repeats in tool_calls.
+func normalizeLangChainContentBlock(block langChainContentBlock) (service.PartIn, bool, error) {
+	switch block.Type {
+	case "text":
+		if block.Text == "" {
+			return service.PartIn{}, false, nil
+		}
+		return service.PartIn{Type: "text", Text: block.Text}, true, nil
+	case "image_url":
+		// image_url is either the URL or {"url": ..., "detail": ...}
+		var image struct {
+			URL    string `json:"url"`
+			Detail string `json:"detail"`
+		}
+		if err := json.Unmarshal(block.ImageURL, &image.URL); err != nil {
+			_ = json.Unmarshal(block.ImageURL, &image)
+		}
+		if image.URL == "" {
+			return service.PartIn{}, false, errLangChainImageSource
+		}
+		meta := map[string]interface{}{"url": image.URL}
+		if image.Detail != "" {
+			meta["detail"] = image.Detail
+		}
+		return service.PartIn{Type: "image", Meta: meta}, true, nil
+	case "image":
+		data := block.Data
+		if data == "" {
+			data = block.Base64
+		}
+		switch {
+		case block.URL != "" && block.SourceType != "base64":
+			return service.PartIn{Type: "image", Meta: map[string]interface{}{"type": "url", "url": block.URL}}, true, nil
+		case data != "":
+			return service.PartIn{Type: "image", Meta: map[string]interface{}{"type": "base64", "media_type": block.MimeType, "data": data}}, true, nil
+		}
+		return service.PartIn{}, false, errLangChainImageSource
+	case "tool_use":
+		return service.PartIn{}, false, nil
+	}
+
+	return service.PartIn{}, false, fmt.Errorf("unsupported LangChain content block type: %s", block.Type)
+}
+
+// normalizeLangChainToolCalls converts the tool calls of an ai message to unified tool-call
+// parts, falling back to the OpenAI tool calls of additional_kwargs
+func normalizeLangChainToolCalls(msg langChainMessage) ([]service.PartIn, error) {
+	parts := []service.PartIn{}
+	for _, call := range msg.ToolCalls {
+		if call.Name == "" {
+			return nil, fmt.Errorf("LangChain tool call requires name")
+		}
+		args := call.Args
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		argsBytes, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal LangChain tool call args: %w", err)
+		}
+		parts = append(parts, service.PartIn{
+			Type: "tool-call",
+			Meta: map[string]interface{}{
+				"id":        call.ID,
+				"name":      call.Name,
+				"arguments": string(argsBytes),
+				"type":      "function",
+			},
+		})
+	}
+	if len(parts) > 0 {
+		return parts, nil
+	}
+
+	for _, call := range msg.AdditionalKwargs.ToolCalls {
+		if call.Function.Name == "" {
+			return nil, fmt.Errorf("LangChain tool call requires name")
+		}
+		parts = append(parts, service.PartIn{
+			Type: "tool-call",
+			Meta: map[string]interface{}{
+				"id":        call.ID,
+				"name":      call.Function.Name,
+				"arguments": call.Function.Arguments,
+				"type":      "function",
+			},
+		})
+	}
+	return parts, nil
+}
+
+// normalizeLangChainToolMessage converts a tool message to a tool-result part. The text of
+// the content is concatenated; when it also holds images every part is kept as tool result
+// content items, as for OpenAI tool messages.
+func normalizeLangChainToolMessage(msg langChainMessage) (service.PartIn, error) {
+	if msg.ToolCallID == "" {
+		return service.PartIn{}, fmt.Errorf("LangChain tool message requires tool_call_id")
+	}
+
+	contentParts, err := normalizeLangChainContent(msg.Content)
+	if err != nil {
+		return service.PartIn{}, err
+	}
+
+	meta := map[string]interface{}{
+		"tool_call_id": msg.ToolCallID,
+	}
+	if msg.Name != "" {
+		meta["name"] = msg.Name
+	}
+	if msg.Status == "error" {
+		meta["is_error"] = true
+	}
+
+	var text string
+	structured := false
+	for _, p := range contentParts {
+		if p.Type == "text" {
+			text += p.Text
+		} else {
+			structured = true
+		}
+	}
+	if structured {
+		meta[ToolResultContentKey] = toolResultContentItems(contentParts)
+	}
+
+	return service.PartIn{
+		Type: "tool-result",
+		Text: text,
+		Meta: meta,
+	}, nil
+}
@@ -0,0 +1,221 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLangChainNormalizer_NormalizeFromLangChainMessage(t *testing.T) {
+	normalizer := &LangChainNormalizer{}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantRole     string
+		wantPartType []string
+		wantErr      bool
+		errContains  string
+	}{
+		{
+			name:         "human message with string content",
+			input:        `{"type": "human", "content": "Hello", "additional_kwargs": {}, "response_metadata": {}}`,
+			wantRole:     "user",
+			wantPartType: []string{"text"},
+		},
+		{
+			name: "human message with multimodal content",
+			input: `{
+				"type": "human",
+				"content": [
+					{"type": "text", "text": "What's in these images?"},
+					{"type": "image_url", "image_url": {"url": "https://example.com/a.jpg", "detail": "high"}},
+					{"type": "image_url", "image_url": "https://example.com/b.jpg"},
+					{"type": "image", "source_type": "base64", "data": "aGVsbG8=", "mime_type": "image/png"}
+				]
+			}`,
+			wantRole:     "user",
+			wantPartType: []string{"text", "image", "image", "image"},
+		},
+		{
+			name: "ai message with tool calls",
+			input: `{
+				"type": "ai",
+				"content": "Let me check the weather.",
+				"tool_calls": [
+					{"name": "get_weather", "args": {"city": "Paris"}, "id": "call_1", "type": "tool_call"},
+					{"name": "get_time", "args": {}, "id": "call_2", "type": "tool_call"}
+				],
+				"invalid_tool_calls": []
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"text", "tool-call", "tool-call"},
+		},
+		{
+			name: "ai message with only tool calls",
+			input: `{
+				"type": "ai",
+				"content": "",
+				"tool_calls": [{"name": "get_weather", "args": {"city": "Paris"}, "id": "call_1"}]
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"tool-call"},
+		},
+		{
+			name: "ai message with tool calls in additional_kwargs",
+			input: `{
+				"type": "ai",
+				"content": "",
+				"additional_kwargs": {"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Paris\"}"}}]}
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"tool-call"},
+		},
+		{
+			name: "ai message from an Anthropic model skips tool_use blocks",
+			input: `{
+				"type": "ai",
+				"content": [
+					{"type": "text", "text": "Checking."},
+					{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+				],
+				"tool_calls": [{"name": "get_weather", "args": {"city": "Paris"}, "id": "toolu_1"}]
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"text", "tool-call"},
+		},
+		{
+			name:         "tool message",
+			input:        `{"type": "tool", "content": "sunny", "tool_call_id": "call_1", "name": "get_weather", "status": "success"}`,
+			wantRole:     "user",
+			wantPartType: []string{"tool-result"},
+		},
+		{
+			name:        "tool message without tool_call_id",
+			input:       `{"type": "tool", "content": "sunny"}`,
+			wantErr:     true,
+			errContains: "requires tool_call_id",
+		},
+		{
+			name:        "empty human message",
+			input:       `{"type": "human", "content": ""}`,
+			wantErr:     true,
+			errContains: "must have content",
+		},
+		{
+			name:        "image without source",
+			input:       `{"type": "human", "content": [{"type": "image_url", "image_url": {}}]}`,
+			wantErr:     true,
+			errContains: "requires a url or base64 data",
+		},
+		{
+			name:        "system message (not supported)",
+			input:       `{"type": "system", "content": "Be concise"}`,
+			wantErr:     true,
+			errContains: "system messages are not supported",
+		},
+		{
+			name:        "unsupported message type",
+			input:       `{"type": "function", "content": "42", "name": "answer"}`,
+			wantErr:     true,
+			errContains: "unsupported LangChain message type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, parts, messageMeta, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(tt.input))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errContains != "" {
+					assert.Contains(t, err.Error(), tt.errContains)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRole, role)
+			assert.Len(t, parts, len(tt.wantPartType))
+			for i, partType := range tt.wantPartType {
+				assert.Equal(t, partType, parts[i].Type)
+				assert.NoError(t, parts[i].Validate())
+			}
+			assert.Equal(t, "langchain", messageMeta["source_format"])
+		})
+	}
+}
+
+func TestLangChainNormalizer_ToolCallsAndResults(t *testing.T) {
+	normalizer := &LangChainNormalizer{}
+
+	t.Run("tool calls map to unified tool-call parts", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(`{
+			"type": "ai",
+			"content": "",
+			"tool_calls": [{"name": "calculate", "args": {"x": 5}, "id": "call_123", "type": "tool_call"}]
+		}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "call_123", parts[0].Meta["id"])
+		assert.Equal(t, "calculate", parts[0].Meta["name"])
+		assert.JSONEq(t, `{"x": 5}`, parts[0].Meta["arguments"].(string))
+		assert.Equal(t, "function", parts[0].Meta["type"])
+	})
+
+	t.Run("tool message keeps call id, name and error status", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(`{
+			"type": "tool",
+			"content": "city not found",
+			"tool_call_id": "call_123",
+			"name": "get_weather",
+			"status": "error"
+		}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "city not found", parts[0].Text)
+		assert.Equal(t, "call_123", parts[0].Meta["tool_call_id"])
+		assert.Equal(t, "get_weather", parts[0].Meta["name"])
+		assert.Equal(t, true, parts[0].Meta["is_error"])
+	})
+
+	t.Run("tool message with an image keeps every content part", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(`{
+			"type": "tool",
+			"tool_call_id": "call_123",
+			"content": [
+				{"type": "text", "text": "Here is the chart"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/chart.png"}}
+			]
+		}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "Here is the chart", parts[0].Text)
+		assert.Equal(t, []any{
+			map[string]any{"type": "text", "text": "Here is the chart"},
+			map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": "https://example.com/chart.png"}},
+		}, parts[0].Meta[ToolResultContentKey])
+	})
+
+	t.Run("tool message with a base64 image keeps its data", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(`{
+			"type": "tool",
+			"tool_call_id": "call_123",
+			"content": [{"type": "image", "source_type": "base64", "mime_type": "image/jpeg", "data": "/9j/4AAQ"}]
+		}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, []any{
+			map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/jpeg", "data": "/9j/4AAQ"}},
+		}, parts[0].Meta[ToolResultContentKey])
+	})
+
+	t.Run("human message name is kept", func(t *testing.T) {
+		_, _, messageMeta, err := normalizer.NormalizeFromLangChainMessage(json.RawMessage(`{"type": "human", "content": "Hi", "name": "alice"}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "alice", messageMeta["name"])
+	})
+}
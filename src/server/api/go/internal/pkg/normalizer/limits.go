@@ -0,0 +1,80 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// ErrMessageTooLarge is returned when a message exceeds the normalizer limits
+var ErrMessageTooLarge = errors.New("message too large")
+
+// Limits bounds the messages accepted by the normalizers. Zero fields are not enforced.
+type Limits struct {
+	// MaxParts is the largest number of parts of a message
+	MaxParts int
+	// MaxPartDataSizeB is the largest inline (base64) payload of a single part
+	MaxPartDataSizeB int
+	// MaxMessageSizeB is the largest raw message JSON
+	MaxMessageSizeB int
+}
+
+// checkMessageSize rejects a raw message over MaxMessageSizeB before it is parsed
+func (l Limits) checkMessageSize(messageJSON json.RawMessage) error {
+	if l.MaxMessageSizeB > 0 && len(messageJSON) > l.MaxMessageSizeB {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", ErrMessageTooLarge, len(messageJSON), l.MaxMessageSizeB)
+	}
+	return nil
+}
+
+// checkPartCount rejects a message with more than MaxParts parts
+func (l Limits) checkPartCount(n int) error {
+	if l.MaxParts > 0 && n > l.MaxParts {
+		return fmt.Errorf("%w: %d parts exceeds the limit of %d parts", ErrMessageTooLarge, n, l.MaxParts)
+	}
+	return nil
+}
+
+// checkParts rejects parts whose inline payload exceeds MaxPartDataSizeB
+func (l Limits) checkParts(parts []service.PartIn) error {
+	if err := l.checkPartCount(len(parts)); err != nil {
+		return err
+	}
+	if l.MaxPartDataSizeB <= 0 {
+		return nil
+	}
+	for i, p := range parts {
+		if size := partDataSize(p); size > l.MaxPartDataSizeB {
+			return fmt.Errorf("%w: part %d carries %d bytes of inline data, exceeding the limit of %d bytes", ErrMessageTooLarge, i, size, l.MaxPartDataSizeB)
+		}
+	}
+	return nil
+}
+
+// partDataSize is the length of the inline payloads of a part: base64 data, file data,
+// data URLs and the sources of tool result content items
+func partDataSize(p service.PartIn) int {
+	size := 0
+	for _, key := range []string{"data", "file_data"} {
+		if s, ok := p.Meta[key].(string); ok {
+			size += len(s)
+		}
+	}
+	if url, ok := p.Meta["url"].(string); ok && strings.HasPrefix(url, "data:") {
+		size += len(url)
+	}
+	// Images and documents inside a tool result
+	if items, ok := p.Meta[ToolResultContentKey].([]any); ok {
+		for _, item := range items {
+			m, _ := item.(map[string]any)
+			source, _ := m["source"].(map[string]any)
+			if data, ok := source["data"].(string); ok {
+				size += len(data)
+			}
+		}
+	}
+	return size
+}
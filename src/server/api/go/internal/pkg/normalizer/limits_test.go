@@ -0,0 +1,117 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxParts: 3, MaxPartDataSizeB: 100, MaxMessageSizeB: 2048}
+
+	textParts := func(n int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = fmt.Sprintf(`{"type":"text","text":"part %d"}`, i)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	blob := strings.Repeat("A", 200)
+	padding := strings.Repeat("x", 4096)
+
+	normalizers := map[string]func(json.RawMessage) (string, []service.PartIn, map[string]interface{}, error){
+		"acontext":  (&AcontextNormalizer{Limits: limits}).NormalizeFromAcontextMessage,
+		"openai":    (&OpenAINormalizer{Limits: limits}).NormalizeFromOpenAIMessage,
+		"anthropic": (&AnthropicNormalizer{Limits: limits}).NormalizeFromAnthropicMessage,
+	}
+
+	tests := []struct {
+		name     string
+		messages map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "within limits",
+			messages: map[string]string{
+				"acontext":  `{"role":"user","parts":` + textParts(3) + `}`,
+				"openai":    `{"role":"user","content":` + textParts(3) + `}`,
+				"anthropic": `{"role":"user","content":` + textParts(3) + `}`,
+			},
+		},
+		{
+			name: "too many parts",
+			messages: map[string]string{
+				"acontext":  `{"role":"user","parts":` + textParts(4) + `}`,
+				"openai":    `{"role":"user","content":` + textParts(4) + `}`,
+				"anthropic": `{"role":"user","content":` + textParts(4) + `}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "part data too large",
+			messages: map[string]string{
+				"acontext":  `{"role":"user","parts":[{"type":"image","meta":{"url":"data:image/png;base64,` + blob + `"}}]}`,
+				"openai":    `{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"` + blob + `","format":"wav"}}]}`,
+				"anthropic": `{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + blob + `"}}]}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "message too large",
+			messages: map[string]string{
+				"acontext":  `{"role":"user","parts":[{"type":"text","text":"` + padding + `"}]}`,
+				"openai":    `{"role":"user","content":"` + padding + `"}`,
+				"anthropic": `{"role":"user","content":[{"type":"text","text":"` + padding + `"}]}`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		for format, normalize := range normalizers {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				_, parts, _, err := normalize(json.RawMessage(tt.messages[format]))
+				if tt.wantErr {
+					assert.ErrorIs(t, err, ErrMessageTooLarge)
+					return
+				}
+				require.NoError(t, err)
+				assert.Len(t, parts, 3)
+			})
+		}
+	}
+
+	t.Run("tool result images count towards the part data", func(t *testing.T) {
+		toolResults := map[string]func() error{
+			"openai": func() error {
+				_, _, _, err := (&OpenAINormalizer{Limits: limits}).NormalizeFromOpenAIMessage(json.RawMessage(
+					`{"role":"tool","tool_call_id":"call_1","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + blob + `"}}]}`))
+				return err
+			},
+			"langchain": func() error {
+				_, _, _, err := (&LangChainNormalizer{Limits: limits}).NormalizeFromLangChainMessage(json.RawMessage(
+					`{"type":"tool","tool_call_id":"call_1","content":[{"type":"image","source_type":"base64","mime_type":"image/png","data":"` + blob + `"}]}`))
+				return err
+			},
+			"anthropic": func() error {
+				_, _, _, err := (&AnthropicNormalizer{Limits: limits}).NormalizeFromAnthropicMessage(json.RawMessage(
+					`{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + blob + `"}}]}]}`))
+				return err
+			},
+		}
+		for format, normalize := range toolResults {
+			assert.ErrorIs(t, normalize(), ErrMessageTooLarge, format)
+		}
+	})
+
+	t.Run("zero limits are not enforced", func(t *testing.T) {
+		_, parts, _, err := (&AnthropicNormalizer{}).NormalizeFromAnthropicMessage(json.RawMessage(`{"role":"user","content":` + textParts(50) + `}`))
+		require.NoError(t, err)
+		assert.Len(t, parts, 50)
+	})
+}
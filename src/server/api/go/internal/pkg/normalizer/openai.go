@@ -7,15 +7,42 @@ import (
 	openai "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
 
+	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 )
 
 // OpenAINormalizer normalizes OpenAI format to internal format using official SDK types
-type OpenAINormalizer struct{}
+type OpenAINormalizer struct {
+	// CompactParts merges adjacent text parts of the normalized message (see service.CompactParts)
+	CompactParts bool
+	// Limits bounds the size of the accepted messages
+	Limits Limits
+	// KeepSystemMessages stores system and developer messages as user messages with
+	// meta["role_kind"] set to their role, instead of rejecting them
+	KeepSystemMessages bool
+}
 
 // NormalizeFromOpenAIMessage converts OpenAI ChatCompletionMessageParamUnion to internal format
 // Returns: role, parts, messageMeta, error
 func (n *OpenAINormalizer) NormalizeFromOpenAIMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(messageJSON); err != nil {
+		return "", nil, nil, err
+	}
+	role, parts, messageMeta, err := normalizeOpenAIMessage(messageJSON, n.KeepSystemMessages)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := n.Limits.checkParts(parts); err != nil {
+		return "", nil, nil, err
+	}
+	if n.CompactParts {
+		parts = service.CompactParts(parts)
+	}
+	parts = service.AssignPartIDs(parts)
+	return role, parts, messageMeta, nil
+}
+
+func normalizeOpenAIMessage(messageJSON json.RawMessage, keepSystem bool) (string, []service.PartIn, map[string]interface{}, error) {
 	// Parse using official OpenAI SDK types
 	var message openai.ChatCompletionMessageParamUnion
 	if err := message.UnmarshalJSON(messageJSON); err != nil {
@@ -26,20 +53,52 @@ func (n *OpenAINormalizer) NormalizeFromOpenAIMessage(messageJSON json.RawMessag
 	if message.OfUser != nil {
 		return normalizeOpenAIUserMessage(*message.OfUser)
 	} else if message.OfAssistant != nil {
-		return normalizeOpenAIAssistantMessage(*message.OfAssistant)
+		return normalizeOpenAIAssistantMessage(*message.OfAssistant, messageJSON)
 	} else if message.OfSystem != nil {
-		return "", nil, nil, fmt.Errorf("system messages are not supported. Use session-level or skill-level configuration for system prompts")
+		if !keepSystem {
+			return "", nil, nil, fmt.Errorf("system messages are not supported. Use session-level or skill-level configuration for system prompts")
+		}
+		return normalizeOpenAISystemMessage(model.RoleKindSystem, message.OfSystem.Content.OfString, message.OfSystem.Content.OfArrayOfContentParts, message.OfSystem.Name)
 	} else if message.OfTool != nil {
-		return normalizeOpenAIToolMessage(*message.OfTool)
+		return normalizeOpenAIToolMessage(*message.OfTool, messageJSON)
 	} else if message.OfFunction != nil {
 		return normalizeOpenAIFunctionMessage(*message.OfFunction)
 	} else if message.OfDeveloper != nil {
-		return "", nil, nil, fmt.Errorf("developer messages are not supported. Use session-level or skill-level configuration for system prompts")
+		if !keepSystem {
+			return "", nil, nil, fmt.Errorf("developer messages are not supported. Use session-level or skill-level configuration for system prompts")
+		}
+		return normalizeOpenAISystemMessage(model.RoleKindDeveloper, message.OfDeveloper.Content.OfString, message.OfDeveloper.Content.OfArrayOfContentParts, message.OfDeveloper.Name)
 	}
 
 	return "", nil, nil, fmt.Errorf("unknown OpenAI message type")
 }
 
+// normalizeOpenAISystemMessage stores a system or developer message as a user message with
+// meta["role_kind"] set to kind, so converters can re-emit it with its own role
+func normalizeOpenAISystemMessage(kind string, content param.Opt[string], contentParts []openai.ChatCompletionContentPartTextParam, name param.Opt[string]) (string, []service.PartIn, map[string]interface{}, error) {
+	parts := []service.PartIn{}
+	if !param.IsOmitted(content) {
+		parts = append(parts, service.PartIn{Type: "text", Text: content.Value})
+	} else {
+		for _, p := range contentParts {
+			parts = append(parts, service.PartIn{Type: "text", Text: p.Text})
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil, nil, fmt.Errorf("OpenAI %s message must have content", kind)
+	}
+
+	messageMeta := map[string]interface{}{
+		"source_format":           "openai",
+		model.MessageMetaRoleKind: kind,
+	}
+	if !param.IsOmitted(name) {
+		messageMeta["name"] = name.Value
+	}
+
+	return "user", parts, messageMeta, nil
+}
+
 func normalizeOpenAIUserMessage(msg openai.ChatCompletionUserMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
 	parts := []service.PartIn{}
 
@@ -74,9 +133,23 @@ func normalizeOpenAIUserMessage(msg openai.ChatCompletionUserMessageParam) (stri
 	return "user", parts, messageMeta, nil
 }
 
-func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
+func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessageParam, messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
 	parts := []service.PartIn{}
 
+	// Reasoning models (and OpenAI-compatible providers) return reasoning_content, which the
+	// SDK does not model. Keep it as a reasoning part so it never merges into the content.
+	reasoning, err := normalizeOpenAIReasoningContent(messageJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if reasoning != "" {
+		parts = append(parts, service.PartIn{
+			Type: "text",
+			Text: reasoning,
+			Meta: map[string]interface{}{model.PartMetaReasoning: true},
+		})
+	}
+
 	// Handle content - can be string or array
 	if !param.IsOmitted(msg.Content.OfString) {
 		if msg.Content.OfString.Value != "" {
@@ -135,25 +208,60 @@ func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessagePa
 	return "assistant", parts, messageMeta, nil
 }
 
-func normalizeOpenAIToolMessage(msg openai.ChatCompletionToolMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
+// normalizeOpenAIReasoningContent returns the reasoning_content of a raw assistant message
+func normalizeOpenAIReasoningContent(messageJSON json.RawMessage) (string, error) {
+	var raw struct {
+		ReasoningContent string `json:"reasoning_content"`
+	}
+	if err := json.Unmarshal(messageJSON, &raw); err != nil {
+		return "", fmt.Errorf("failed to unmarshal OpenAI assistant message reasoning: %w", err)
+	}
+	return raw.ReasoningContent, nil
+}
+
+func normalizeOpenAIToolMessage(msg openai.ChatCompletionToolMessageParam, messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
 	parts := []service.PartIn{}
+	partMeta := map[string]interface{}{
+		"tool_call_id": msg.ToolCallID, // Keep as tool_call_id (unified format)
+	}
 
 	// Tool messages are converted to user messages with tool-result parts
 	var content string
 	if !param.IsOmitted(msg.Content.OfString) {
 		content = msg.Content.OfString.Value
 	} else if len(msg.Content.OfArrayOfContentParts) > 0 {
+		hasNonText := false
 		for _, textPart := range msg.Content.OfArrayOfContentParts {
 			content += textPart.Text
+			if textPart.Type != "text" {
+				hasNonText = true
+			}
+		}
+
+		// The SDK only models text parts for tool content, so tools returning
+		// images would lose their output. Keep them as tool result content items.
+		if hasNonText {
+			contentParts, err := normalizeOpenAIToolContentParts(messageJSON)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			partMeta[ToolResultContentKey] = toolResultContentItems(contentParts)
 		}
 	}
 
+	// Function-style tools identify their result by name, which the SDK does not model
+	name, err := normalizeOpenAIToolName(messageJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if name != "" {
+		partMeta["name"] = name
+	}
+
 	parts = append(parts, service.PartIn{
 		Type: "tool-result",
 		Text: content,
-		Meta: map[string]interface{}{
-			"tool_call_id": msg.ToolCallID, // Keep as tool_call_id (unified format)
-		},
+		Meta: partMeta,
 	})
 
 	// Extract message-level metadata
@@ -164,6 +272,38 @@ func normalizeOpenAIToolMessage(msg openai.ChatCompletionToolMessageParam) (stri
 	return "user", parts, messageMeta, nil
 }
 
+// normalizeOpenAIToolName returns the name of a raw tool message
+func normalizeOpenAIToolName(messageJSON json.RawMessage) (string, error) {
+	var raw struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(messageJSON, &raw); err != nil {
+		return "", fmt.Errorf("failed to unmarshal OpenAI tool message name: %w", err)
+	}
+	return raw.Name, nil
+}
+
+// normalizeOpenAIToolContentParts parses the raw content array of a tool message
+func normalizeOpenAIToolContentParts(messageJSON json.RawMessage) ([]service.PartIn, error) {
+	var raw struct {
+		Content []openai.ChatCompletionContentPartUnionParam `json:"content"`
+	}
+	if err := json.Unmarshal(messageJSON, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OpenAI tool message content: %w", err)
+	}
+
+	contentParts := make([]service.PartIn, 0, len(raw.Content))
+	for _, partUnion := range raw.Content {
+		part, err := normalizeOpenAIContentPart(partUnion)
+		if err != nil {
+			return nil, err
+		}
+		contentParts = append(contentParts, part)
+	}
+
+	return contentParts, nil
+}
+
 func normalizeOpenAIFunctionMessage(msg openai.ChatCompletionFunctionMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
 	// Function messages are converted to user messages with tool-result parts
 	content := ""
@@ -0,0 +1,227 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// openAIResponsesItem is one item of the OpenAI Responses API `input`/`output` arrays.
+// Only the fields needed to map the supported item types are decoded.
+type openAIResponsesItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	Text      string          `json:"text"`
+	ImageURL  string          `json:"image_url"`
+	FileID    string          `json:"file_id"`
+	Detail    string          `json:"detail"`
+	CallID    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Output    json.RawMessage `json:"output"`
+}
+
+// openAIResponsesContent is one content part of a Responses API message item
+type openAIResponsesContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL string `json:"image_url"`
+	FileID   string `json:"file_id"`
+	Detail   string `json:"detail"`
+	Refusal  string `json:"refusal"`
+}
+
+// NormalizeFromOpenAIResponsesItem converts an OpenAI Responses API item to internal format
+// Returns: role, parts, messageMeta, error
+func (n *OpenAINormalizer) NormalizeFromOpenAIResponsesItem(itemJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(itemJSON); err != nil {
+		return "", nil, nil, err
+	}
+	role, parts, messageMeta, err := normalizeOpenAIResponsesItem(itemJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := n.Limits.checkParts(parts); err != nil {
+		return "", nil, nil, err
+	}
+	if n.CompactParts {
+		parts = service.CompactParts(parts)
+	}
+	parts = service.AssignPartIDs(parts)
+	return role, parts, messageMeta, nil
+}
+
+func normalizeOpenAIResponsesItem(itemJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	var item openAIResponsesItem
+	if err := json.Unmarshal(itemJSON, &item); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to unmarshal OpenAI Responses item: %w", err)
+	}
+
+	messageMeta := map[string]interface{}{
+		"source_format": "openai_responses",
+	}
+
+	switch item.Type {
+	case "message", "":
+		role, parts, err := normalizeOpenAIResponsesMessage(item)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return role, parts, messageMeta, nil
+	case "input_text", "input_image", "output_text":
+		// A bare content item is treated as a single-part message
+		part, err := normalizeOpenAIResponsesContent(openAIResponsesContent{
+			Type:     item.Type,
+			Text:     item.Text,
+			ImageURL: item.ImageURL,
+			FileID:   item.FileID,
+			Detail:   item.Detail,
+		})
+		if err != nil {
+			return "", nil, nil, err
+		}
+		role := "user"
+		if item.Type == "output_text" {
+			role = "assistant"
+		}
+		return role, []service.PartIn{part}, messageMeta, nil
+	case "function_call":
+		if item.CallID == "" || item.Name == "" {
+			return "", nil, nil, fmt.Errorf("OpenAI Responses function_call item requires call_id and name")
+		}
+		return "assistant", []service.PartIn{
+			{
+				Type: "tool-call",
+				Meta: map[string]interface{}{
+					"id":        item.CallID,
+					"name":      item.Name,
+					"arguments": item.Arguments,
+					"type":      "function",
+				},
+			},
+		}, messageMeta, nil
+	case "function_call_output":
+		if item.CallID == "" {
+			return "", nil, nil, fmt.Errorf("OpenAI Responses function_call_output item requires call_id")
+		}
+		output, err := normalizeOpenAIResponsesOutput(item.Output)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		// Tool outputs are stored as user messages with tool-result parts
+		return "user", []service.PartIn{
+			{
+				Type: "tool-result",
+				Text: output,
+				Meta: map[string]interface{}{
+					"tool_call_id": item.CallID,
+				},
+			},
+		}, messageMeta, nil
+	}
+
+	return "", nil, nil, fmt.Errorf("unsupported OpenAI Responses item type: %s", item.Type)
+}
+
+func normalizeOpenAIResponsesMessage(item openAIResponsesItem) (string, []service.PartIn, error) {
+	switch item.Role {
+	case "user", "assistant":
+	case "system", "developer":
+		return "", nil, fmt.Errorf("%s messages are not supported. Use session-level or skill-level configuration for system prompts", item.Role)
+	default:
+		return "", nil, fmt.Errorf("invalid OpenAI Responses message role: %s", item.Role)
+	}
+
+	if len(item.Content) == 0 {
+		return "", nil, fmt.Errorf("OpenAI Responses message must have content")
+	}
+
+	// Content can be a plain string or an array of content parts
+	var text string
+	if err := json.Unmarshal(item.Content, &text); err == nil {
+		return item.Role, []service.PartIn{{Type: "text", Text: text}}, nil
+	}
+
+	var contents []openAIResponsesContent
+	if err := json.Unmarshal(item.Content, &contents); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal OpenAI Responses message content: %w", err)
+	}
+	if len(contents) == 0 {
+		return "", nil, fmt.Errorf("OpenAI Responses message must have content")
+	}
+
+	parts := make([]service.PartIn, 0, len(contents))
+	for _, content := range contents {
+		part, err := normalizeOpenAIResponsesContent(content)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, part)
+	}
+
+	return item.Role, parts, nil
+}
+
+func normalizeOpenAIResponsesContent(content openAIResponsesContent) (service.PartIn, error) {
+	switch content.Type {
+	case "input_text", "output_text":
+		return service.PartIn{
+			Type: "text",
+			Text: content.Text,
+		}, nil
+	case "refusal":
+		return service.PartIn{
+			Type: "text",
+			Text: content.Refusal,
+			Meta: map[string]interface{}{
+				"is_refusal": true,
+			},
+		}, nil
+	case "input_image":
+		if content.ImageURL == "" && content.FileID == "" {
+			return service.PartIn{}, fmt.Errorf("OpenAI Responses input_image requires image_url or file_id")
+		}
+		meta := map[string]interface{}{}
+		if content.ImageURL != "" {
+			meta["url"] = content.ImageURL
+		}
+		if content.FileID != "" {
+			meta["file_id"] = content.FileID
+		}
+		if content.Detail != "" {
+			meta["detail"] = content.Detail
+		}
+		return service.PartIn{
+			Type: "image",
+			Meta: meta,
+		}, nil
+	}
+
+	return service.PartIn{}, fmt.Errorf("unsupported OpenAI Responses content type: %s", content.Type)
+}
+
+// normalizeOpenAIResponsesOutput flattens a function_call_output `output`, which is
+// either a string or an array of content parts, into text
+func normalizeOpenAIResponsesOutput(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var output string
+	if err := json.Unmarshal(raw, &output); err == nil {
+		return output, nil
+	}
+
+	var contents []openAIResponsesContent
+	if err := json.Unmarshal(raw, &contents); err != nil {
+		return "", fmt.Errorf("failed to unmarshal OpenAI Responses function_call_output: %w", err)
+	}
+	for _, content := range contents {
+		if content.Type == "input_text" || content.Type == "output_text" {
+			output += content.Text
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,189 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAINormalizer_NormalizeFromOpenAIResponsesItem(t *testing.T) {
+	normalizer := &OpenAINormalizer{}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantRole     string
+		wantPartType []string
+		wantErr      bool
+		errContains  string
+	}{
+		{
+			name:         "easy input message with string content",
+			input:        `{"role": "user", "content": "Hello"}`,
+			wantRole:     "user",
+			wantPartType: []string{"text"},
+		},
+		{
+			name: "message with input_text and input_image",
+			input: `{
+				"type": "message",
+				"role": "user",
+				"content": [
+					{"type": "input_text", "text": "What's in this image?"},
+					{"type": "input_image", "image_url": "https://example.com/img.jpg", "detail": "high"}
+				]
+			}`,
+			wantRole:     "user",
+			wantPartType: []string{"text", "image"},
+		},
+		{
+			name: "output message with output_text",
+			input: `{
+				"type": "message",
+				"role": "assistant",
+				"status": "completed",
+				"content": [
+					{"type": "output_text", "text": "It is a cat.", "annotations": []}
+				]
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"text"},
+		},
+		{
+			name:         "bare input_text item",
+			input:        `{"type": "input_text", "text": "Hello"}`,
+			wantRole:     "user",
+			wantPartType: []string{"text"},
+		},
+		{
+			name:         "bare input_image item",
+			input:        `{"type": "input_image", "file_id": "file-abc"}`,
+			wantRole:     "user",
+			wantPartType: []string{"image"},
+		},
+		{
+			name:         "bare output_text item",
+			input:        `{"type": "output_text", "text": "Done"}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"text"},
+		},
+		{
+			name: "function_call item",
+			input: `{
+				"type": "function_call",
+				"id": "fc_123",
+				"call_id": "call_123",
+				"name": "get_weather",
+				"arguments": "{\"city\": \"Paris\"}"
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"tool-call"},
+		},
+		{
+			name:         "function_call_output item",
+			input:        `{"type": "function_call_output", "call_id": "call_123", "output": "sunny"}`,
+			wantRole:     "user",
+			wantPartType: []string{"tool-result"},
+		},
+		{
+			name:        "input_image without source",
+			input:       `{"type": "input_image"}`,
+			wantErr:     true,
+			errContains: "requires image_url or file_id",
+		},
+		{
+			name:        "input_image with only detail",
+			input:       `{"type": "input_image", "detail": "high"}`,
+			wantErr:     true,
+			errContains: "requires image_url or file_id",
+		},
+		{
+			name:        "function_call without call_id",
+			input:       `{"type": "function_call", "name": "get_weather", "arguments": "{}"}`,
+			wantErr:     true,
+			errContains: "requires call_id",
+		},
+		{
+			name:        "developer message (not supported)",
+			input:       `{"role": "developer", "content": "Be concise"}`,
+			wantErr:     true,
+			errContains: "developer messages are not supported",
+		},
+		{
+			name:        "unsupported item type",
+			input:       `{"type": "reasoning", "summary": []}`,
+			wantErr:     true,
+			errContains: "unsupported OpenAI Responses item type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, parts, messageMeta, err := normalizer.NormalizeFromOpenAIResponsesItem(json.RawMessage(tt.input))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errContains != "" {
+					assert.Contains(t, err.Error(), tt.errContains)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRole, role)
+			assert.Len(t, parts, len(tt.wantPartType))
+			for i, partType := range tt.wantPartType {
+				assert.Equal(t, partType, parts[i].Type)
+				assert.NoError(t, parts[i].Validate())
+			}
+			assert.Equal(t, "openai_responses", messageMeta["source_format"])
+		})
+	}
+}
+
+func TestOpenAINormalizer_ResponsesToolCallAndOutput(t *testing.T) {
+	normalizer := &OpenAINormalizer{}
+
+	t.Run("function_call maps to unified tool-call", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromOpenAIResponsesItem(json.RawMessage(`{
+			"type": "function_call",
+			"call_id": "call_123",
+			"name": "calculate",
+			"arguments": "{\"x\": 5}"
+		}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "call_123", parts[0].Meta["id"])
+		assert.Equal(t, "calculate", parts[0].Meta["name"])
+		assert.Equal(t, "{\"x\": 5}", parts[0].Meta["arguments"])
+		assert.Equal(t, "function", parts[0].Meta["type"])
+	})
+
+	t.Run("function_call_output with content parts", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromOpenAIResponsesItem(json.RawMessage(`{
+			"type": "function_call_output",
+			"call_id": "call_123",
+			"output": [
+				{"type": "input_text", "text": "Result: "},
+				{"type": "input_text", "text": "8"}
+			]
+		}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Result: 8", parts[0].Text)
+		assert.Equal(t, "call_123", parts[0].Meta["tool_call_id"])
+	})
+
+	t.Run("input_image keeps url and detail", func(t *testing.T) {
+		_, parts, _, err := normalizer.NormalizeFromOpenAIResponsesItem(json.RawMessage(`{
+			"type": "input_image",
+			"image_url": "https://example.com/img.jpg",
+			"detail": "low"
+		}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/img.jpg", parts[0].Meta["url"])
+		assert.Equal(t, "low", parts[0].Meta["detail"])
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -335,6 +336,68 @@ func TestOpenAINormalizer_ToolCallsAndResults(t *testing.T) {
 		assert.Equal(t, "openai", messageMeta["source_format"])
 	})
 
+	t.Run("tool result message with text and image parts", func(t *testing.T) {
+		input := `{
+			"role": "tool",
+			"content": [
+				{"type": "text", "text": "Here is the chart"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/chart.png", "detail": "high"}}
+			],
+			"tool_call_id": "call_123"
+		}`
+
+		role, parts, _, err := normalizer.NormalizeFromOpenAIMessage(json.RawMessage(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user", role)
+		assert.Len(t, parts, 1)
+		assert.Equal(t, "tool-result", parts[0].Type)
+		assert.Equal(t, "Here is the chart", parts[0].Text)
+		assert.Equal(t, "call_123", parts[0].Meta["tool_call_id"])
+
+		assert.Equal(t, []any{
+			map[string]any{"type": "text", "text": "Here is the chart"},
+			map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": "https://example.com/chart.png"}},
+		}, parts[0].Meta[ToolResultContentKey])
+	})
+
+	t.Run("tool result message with inline image and file parts", func(t *testing.T) {
+		input := `{
+			"role": "tool",
+			"content": [
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}},
+				{"type": "file", "file": {"file_data": "data:application/pdf;base64,JVBERi0=", "filename": "report.pdf"}},
+				{"type": "file", "file": {"file_id": "file-abc"}}
+			],
+			"tool_call_id": "call_123"
+		}`
+
+		_, parts, _, err := normalizer.NormalizeFromOpenAIMessage(json.RawMessage(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []any{
+			map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+			map[string]any{"type": "document", "title": "report.pdf", "source": map[string]any{"type": "base64", "media_type": "application/pdf", "data": "JVBERi0="}},
+		}, parts[0].Meta[ToolResultContentKey])
+	})
+
+	t.Run("tool result message with text-only parts stays flat", func(t *testing.T) {
+		input := `{
+			"role": "tool",
+			"content": [
+				{"type": "text", "text": "Result: "},
+				{"type": "text", "text": "8"}
+			],
+			"tool_call_id": "call_123"
+		}`
+
+		_, parts, _, err := normalizer.NormalizeFromOpenAIMessage(json.RawMessage(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Result: 8", parts[0].Text)
+		assert.NotContains(t, parts[0].Meta, ToolResultContentKey)
+	})
+
 	t.Run("deprecated function call", func(t *testing.T) {
 		input := `{
 			"role": "assistant",
@@ -406,3 +469,90 @@ func TestOpenAINormalizer_MessageWithName(t *testing.T) {
 	assert.Equal(t, "openai", messageMeta["source_format"])
 	assert.Equal(t, "Alice", messageMeta["name"])
 }
+
+func TestOpenAINormalizer_KeepSystemMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantKind string
+		wantText []string
+	}{
+		{
+			name:     "system message with string content",
+			input:    `{"role": "system", "content": "You are a helpful assistant."}`,
+			wantKind: "system",
+			wantText: []string{"You are a helpful assistant."},
+		},
+		{
+			name:     "developer message with array content",
+			input:    `{"role": "developer", "content": [{"type": "text", "text": "Be brief."}, {"type": "text", "text": "Use markdown."}]}`,
+			wantKind: "developer",
+			wantText: []string{"Be brief.", "Use markdown."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected by default
+			_, _, _, err := (&OpenAINormalizer{}).NormalizeFromOpenAIMessage(json.RawMessage(tt.input))
+			assert.ErrorContains(t, err, "messages are not supported")
+
+			role, parts, messageMeta, err := (&OpenAINormalizer{KeepSystemMessages: true}).NormalizeFromOpenAIMessage(json.RawMessage(tt.input))
+			assert.NoError(t, err)
+			assert.Equal(t, "user", role)
+			assert.Equal(t, tt.wantKind, messageMeta["role_kind"])
+			assert.Equal(t, "openai", messageMeta["source_format"])
+			texts := make([]string, 0, len(parts))
+			for _, p := range parts {
+				assert.Equal(t, "text", p.Type)
+				texts = append(texts, p.Text)
+			}
+			assert.Equal(t, tt.wantText, texts)
+		})
+	}
+
+	t.Run("system message without content", func(t *testing.T) {
+		_, _, _, err := (&OpenAINormalizer{KeepSystemMessages: true}).NormalizeFromOpenAIMessage(json.RawMessage(`{"role": "system"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestOpenAINormalizer_CompactParts(t *testing.T) {
+	input := `{
+		"role": "user",
+		"content": [
+			{"type": "text", "text": "First part"},
+			{"type": "text", "text": ""},
+			{"type": "text", "text": "Second part"}
+		]
+	}`
+
+	_, parts, _, err := (&OpenAINormalizer{}).NormalizeFromOpenAIMessage(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Len(t, parts, 3, "parts are left untouched unless compaction is enabled")
+
+	_, parts, _, err = (&OpenAINormalizer{CompactParts: true}).NormalizeFromOpenAIMessage(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Len(t, parts, 1)
+	assert.Equal(t, "First part\nSecond part", parts[0].Text)
+}
+
+func TestOpenAINormalizer_ReasoningContent(t *testing.T) {
+	input := `{
+		"role": "assistant",
+		"reasoning_content": "The user wants the weather, so call the tool.",
+		"content": "Let me check.",
+		"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{}"}}]
+	}`
+
+	role, parts, _, err := (&OpenAINormalizer{CompactParts: true}).NormalizeFromOpenAIMessage(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "assistant", role)
+	assert.Len(t, parts, 3)
+	assert.Equal(t, "text", parts[0].Type)
+	assert.Equal(t, "The user wants the weather, so call the tool.", parts[0].Text)
+	assert.Equal(t, map[string]interface{}{model.PartMetaReasoning: true}, parts[0].Meta)
+	assert.Equal(t, "Let me check.", parts[1].Text, "reasoning is never compacted into the content")
+	assert.Empty(t, parts[1].Meta)
+	assert.Equal(t, "tool-call", parts[2].Type)
+}
@@ -0,0 +1,269 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+// VercelNormalizer normalizes Vercel AI SDK UI messages to internal format
+type VercelNormalizer struct {
+	// CompactParts merges adjacent text parts of the normalized message (see service.CompactParts)
+	CompactParts bool
+	// Limits bounds the size of the accepted messages
+	Limits Limits
+}
+
+// vercelMessage is a Vercel AI SDK UI message. Messages without parts fall back to content.
+type vercelMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+	Parts   []vercelPart    `json:"parts"`
+}
+
+// vercelPart is one part of a Vercel message. tool-invocation parts carry the call, and its
+// result once state is "result"; tool-call and tool-result parts come from core messages.
+type vercelPart struct {
+	Type           string                `json:"type"`
+	Text           string                `json:"text"`
+	ToolInvocation *vercelToolInvocation `json:"toolInvocation"`
+	ToolCallID     string                `json:"toolCallId"`
+	ToolName       string                `json:"toolName"`
+	Args           json.RawMessage       `json:"args"`
+	Result         json.RawMessage       `json:"result"`
+	IsError        bool                  `json:"isError"`
+	// file parts: the media type is mimeType (v4) or mediaType (v5), the content is inline
+	// base64 data or a URL
+	MimeType  string `json:"mimeType"`
+	MediaType string `json:"mediaType"`
+	Data      string `json:"data"`
+	URL       string `json:"url"`
+	Filename  string `json:"filename"`
+}
+
+type vercelToolInvocation struct {
+	State      string          `json:"state"`
+	ToolCallID string          `json:"toolCallId"`
+	ToolName   string          `json:"toolName"`
+	Args       json.RawMessage `json:"args"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// VercelToolResultKey is the tool-result part meta key holding a result that is not a
+// string, as JSON. The part text holds the same result serialized, for other formats.
+const VercelToolResultKey = "result"
+
+// NormalizeFromVercelMessage converts a Vercel AI SDK message to internal format.
+// A tool-invocation with a result becomes a tool-call part followed by its tool-result
+// part in the same assistant message; tool messages are stored as user messages.
+// Returns: role, parts, messageMeta, error
+func (n *VercelNormalizer) NormalizeFromVercelMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	if err := n.Limits.checkMessageSize(messageJSON); err != nil {
+		return "", nil, nil, err
+	}
+	role, parts, messageMeta, err := normalizeVercelMessage(messageJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := n.Limits.checkParts(parts); err != nil {
+		return "", nil, nil, err
+	}
+	if n.CompactParts {
+		parts = service.CompactParts(parts)
+	}
+	parts = service.AssignPartIDs(parts)
+	return role, parts, messageMeta, nil
+}
+
+func normalizeVercelMessage(messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
+	var msg vercelMessage
+	if err := json.Unmarshal(messageJSON, &msg); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to unmarshal Vercel message: %w", err)
+	}
+
+	var role string
+	switch msg.Role {
+	case "user", "assistant":
+		role = msg.Role
+	case "tool":
+		// Tool results are stored as user messages with tool-result parts
+		role = "user"
+	case "system":
+		return "", nil, nil, fmt.Errorf("system messages are not supported. Use session-level or skill-level configuration for system prompts")
+	default:
+		return "", nil, nil, fmt.Errorf("invalid Vercel message role: %s", msg.Role)
+	}
+
+	vparts := msg.Parts
+	if len(vparts) == 0 && len(msg.Content) > 0 {
+		// Core messages have content instead of parts: a string or an array of parts
+		var text string
+		if err := json.Unmarshal(msg.Content, &text); err == nil {
+			if text != "" {
+				vparts = []vercelPart{{Type: "text", Text: text}}
+			}
+		} else if err := json.Unmarshal(msg.Content, &vparts); err != nil {
+			return "", nil, nil, fmt.Errorf("failed to unmarshal Vercel message content: %w", err)
+		}
+	}
+
+	parts := make([]service.PartIn, 0, len(vparts))
+	for _, p := range vparts {
+		converted, err := normalizeVercelPart(p)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		parts = append(parts, converted...)
+	}
+	if len(parts) == 0 {
+		return "", nil, nil, fmt.Errorf("Vercel message must have parts or content")
+	}
+
+	messageMeta := map[string]interface{}{
+		"source_format": "vercel",
+	}
+	return role, parts, messageMeta, nil
+}
+
+func normalizeVercelPart(p vercelPart) ([]service.PartIn, error) {
+	switch p.Type {
+	case "text":
+		if p.Text == "" {
+			return nil, nil
+		}
+		return []service.PartIn{{Type: "text", Text: p.Text}}, nil
+	case "step-start":
+		// UI marker between the steps of a multi-step call, nothing to store
+		return nil, nil
+	case "tool-invocation":
+		inv := p.ToolInvocation
+		if inv == nil {
+			return nil, fmt.Errorf("Vercel tool-invocation part requires toolInvocation")
+		}
+		call, err := vercelToolCall(inv.ToolCallID, inv.ToolName, inv.Args)
+		if err != nil {
+			return nil, err
+		}
+		if inv.State != "result" {
+			return []service.PartIn{call}, nil
+		}
+		result, err := vercelToolResult(inv.ToolCallID, inv.ToolName, inv.Result, false)
+		if err != nil {
+			return nil, err
+		}
+		return []service.PartIn{call, result}, nil
+	case "tool-call":
+		call, err := vercelToolCall(p.ToolCallID, p.ToolName, p.Args)
+		if err != nil {
+			return nil, err
+		}
+		return []service.PartIn{call}, nil
+	case "tool-result":
+		result, err := vercelToolResult(p.ToolCallID, p.ToolName, p.Result, p.IsError)
+		if err != nil {
+			return nil, err
+		}
+		return []service.PartIn{result}, nil
+	case "file":
+		part, err := normalizeVercelFile(p)
+		if err != nil {
+			return nil, err
+		}
+		return []service.PartIn{part}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported Vercel part type: %s", p.Type)
+}
+
+// vercelToolCall builds a unified tool-call part, with the args serialized as arguments
+func vercelToolCall(toolCallID, toolName string, args json.RawMessage) (service.PartIn, error) {
+	if toolCallID == "" || toolName == "" {
+		return service.PartIn{}, fmt.Errorf("Vercel tool call requires toolCallId and toolName")
+	}
+	arguments := "{}"
+	if len(args) > 0 && string(args) != "null" {
+		arguments = string(args)
+	}
+	return service.PartIn{
+		Type: "tool-call",
+		Meta: map[string]interface{}{
+			"id":        toolCallID,
+			"name":      toolName,
+			"arguments": arguments,
+			"type":      "function",
+		},
+	}, nil
+}
+
+// vercelToolResult builds a unified tool-result part. A string result is the part text;
+// any other result is kept as JSON in meta VercelToolResultKey and serialized in the text.
+func vercelToolResult(toolCallID, toolName string, result json.RawMessage, isError bool) (service.PartIn, error) {
+	if toolCallID == "" {
+		return service.PartIn{}, fmt.Errorf("Vercel tool result requires toolCallId")
+	}
+	meta := map[string]interface{}{
+		"tool_call_id": toolCallID,
+	}
+	if toolName != "" {
+		meta["name"] = toolName
+	}
+	if isError {
+		meta["is_error"] = true
+	}
+
+	var text string
+	if len(result) > 0 && string(result) != "null" {
+		if err := json.Unmarshal(result, &text); err != nil {
+			var value interface{}
+			if err := json.Unmarshal(result, &value); err != nil {
+				return service.PartIn{}, fmt.Errorf("failed to unmarshal Vercel tool result: %w", err)
+			}
+			meta[VercelToolResultKey] = value
+			text = string(result)
+		}
+	}
+
+	return service.PartIn{
+		Type: "tool-result",
+		Text: text,
+		Meta: meta,
+	}, nil
+}
+
+// normalizeVercelFile converts a file part to an image part for image media types, and to
+// a file part otherwise
+func normalizeVercelFile(p vercelPart) (service.PartIn, error) {
+	mediaType := p.MediaType
+	if mediaType == "" {
+		mediaType = p.MimeType
+	}
+
+	meta := map[string]interface{}{}
+	if mediaType != "" {
+		meta["media_type"] = mediaType
+	}
+	switch {
+	case p.URL != "":
+		meta["type"] = "url"
+		meta["url"] = p.URL
+	case strings.HasPrefix(p.Data, "http://") || strings.HasPrefix(p.Data, "https://"):
+		meta["type"] = "url"
+		meta["url"] = p.Data
+	case p.Data != "":
+		meta["type"] = "base64"
+		meta["data"] = p.Data
+	default:
+		return service.PartIn{}, fmt.Errorf("Vercel file part requires data or url")
+	}
+	if p.Filename != "" {
+		meta["filename"] = p.Filename
+	}
+
+	partType := "file"
+	if strings.HasPrefix(mediaType, "image/") {
+		partType = "image"
+	}
+	return service.PartIn{Type: partType, Meta: meta}, nil
+}
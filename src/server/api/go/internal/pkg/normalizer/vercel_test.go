@@ -0,0 +1,157 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVercelNormalizer_NormalizeFromVercelMessage(t *testing.T) {
+	normalizer := &VercelNormalizer{}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantRole     string
+		wantPartType []string
+		wantErr      bool
+		errContains  string
+	}{
+		{
+			name:         "user message with text part",
+			input:        `{"id": "msg_1", "role": "user", "content": "Hello", "parts": [{"type": "text", "text": "Hello"}]}`,
+			wantRole:     "user",
+			wantPartType: []string{"text"},
+		},
+		{
+			name:         "message with content only",
+			input:        `{"role": "user", "content": "Hello"}`,
+			wantRole:     "user",
+			wantPartType: []string{"text"},
+		},
+		{
+			name: "user message with files",
+			input: `{
+				"role": "user",
+				"parts": [
+					{"type": "text", "text": "Summarize these"},
+					{"type": "file", "mimeType": "image/png", "data": "aGVsbG8="},
+					{"type": "file", "mediaType": "application/pdf", "url": "https://example.com/report.pdf", "filename": "report.pdf"}
+				]
+			}`,
+			wantRole:     "user",
+			wantPartType: []string{"text", "image", "file"},
+		},
+		{
+			name: "assistant message with a pending tool invocation",
+			input: `{
+				"role": "assistant",
+				"parts": [
+					{"type": "step-start"},
+					{"type": "text", "text": "Let me check."},
+					{"type": "tool-invocation", "toolInvocation": {"state": "call", "toolCallId": "call_1", "toolName": "get_weather", "args": {"city": "Paris"}}}
+				]
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"text", "tool-call"},
+		},
+		{
+			name: "assistant message with a tool invocation result",
+			input: `{
+				"role": "assistant",
+				"parts": [
+					{"type": "tool-invocation", "toolInvocation": {"state": "result", "toolCallId": "call_1", "toolName": "get_weather", "args": {"city": "Paris"}, "result": {"temp": 21}}}
+				]
+			}`,
+			wantRole:     "assistant",
+			wantPartType: []string{"tool-call", "tool-result"},
+		},
+		{
+			name: "tool message with tool-result content",
+			input: `{
+				"role": "tool",
+				"content": [{"type": "tool-result", "toolCallId": "call_1", "toolName": "get_weather", "result": "sunny"}]
+			}`,
+			wantRole:     "user",
+			wantPartType: []string{"tool-result"},
+		},
+		{
+			name:        "tool invocation without toolCallId",
+			input:       `{"role": "assistant", "parts": [{"type": "tool-invocation", "toolInvocation": {"state": "call", "toolName": "get_weather"}}]}`,
+			wantErr:     true,
+			errContains: "requires toolCallId and toolName",
+		},
+		{
+			name:        "file without content",
+			input:       `{"role": "user", "parts": [{"type": "file", "mimeType": "image/png"}]}`,
+			wantErr:     true,
+			errContains: "requires data or url",
+		},
+		{
+			name:        "empty message",
+			input:       `{"role": "user", "parts": [{"type": "step-start"}]}`,
+			wantErr:     true,
+			errContains: "must have parts or content",
+		},
+		{
+			name:        "system message (not supported)",
+			input:       `{"role": "system", "content": "Be concise"}`,
+			wantErr:     true,
+			errContains: "system messages are not supported",
+		},
+		{
+			name:        "unsupported part type",
+			input:       `{"role": "assistant", "parts": [{"type": "reasoning", "reasoning": "..."}]}`,
+			wantErr:     true,
+			errContains: "unsupported Vercel part type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, parts, messageMeta, err := normalizer.NormalizeFromVercelMessage(json.RawMessage(tt.input))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errContains != "" {
+					assert.Contains(t, err.Error(), tt.errContains)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRole, role)
+			assert.Len(t, parts, len(tt.wantPartType))
+			for i, partType := range tt.wantPartType {
+				assert.Equal(t, partType, parts[i].Type)
+				assert.NoError(t, parts[i].Validate())
+			}
+			assert.Equal(t, "vercel", messageMeta["source_format"])
+		})
+	}
+}
+
+func TestVercelNormalizer_ToolInvocation(t *testing.T) {
+	normalizer := &VercelNormalizer{}
+
+	_, parts, _, err := normalizer.NormalizeFromVercelMessage(json.RawMessage(`{
+		"role": "assistant",
+		"parts": [
+			{"type": "tool-invocation", "toolInvocation": {"state": "result", "toolCallId": "call_123", "toolName": "calculate", "args": {"x": 5}, "result": {"value": 8}}}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+
+	// The call keeps its toolCallId as the unified id
+	assert.Equal(t, "call_123", parts[0].Meta["id"])
+	assert.Equal(t, "calculate", parts[0].Meta["name"])
+	assert.JSONEq(t, `{"x": 5}`, parts[0].Meta["arguments"].(string))
+
+	// A JSON result is kept as is, and serialized in the text for other formats
+	assert.Equal(t, "call_123", parts[1].Meta["tool_call_id"])
+	assert.Equal(t, map[string]interface{}{"value": float64(8)}, parts[1].Meta[VercelToolResultKey])
+	assert.JSONEq(t, `{"value": 8}`, parts[1].Text)
+}
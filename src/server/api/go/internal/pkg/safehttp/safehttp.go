@@ -0,0 +1,187 @@
+// Package safehttp fetches URLs supplied by clients without letting them reach the network
+// of the server: metadata endpoints, loopback and private ranges are refused.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenURL is returned for URLs that are not http(s) or that reach a private,
+// loopback or otherwise internal address
+var ErrForbiddenURL = errors.New("url not allowed")
+
+// ErrTooLarge is returned when a response body is larger than the caller allows
+var ErrTooLarge = errors.New("response too large")
+
+// ErrFetchFailed is returned when the server cannot be reached or does not answer 200 OK
+var ErrFetchFailed = errors.New("fetch failed")
+
+const (
+	// DefaultTimeout bounds a fetch, redirects included, when the client sets no timeout
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxRedirects is the number of redirects followed when the client sets none
+	DefaultMaxRedirects = 5
+)
+
+// Response is a fetched body with the headers of the last response
+type Response struct {
+	Data   []byte
+	Header http.Header
+	// URL is the URL the body was read from, after redirects
+	URL *url.URL
+}
+
+// Client fetches URLs, checking every connection, redirects included, against the resolved
+// IP address so a hostname cannot point the server at its own network. The zero value is
+// ready to use.
+type Client struct {
+	// Timeout bounds a whole fetch, DefaultTimeout when zero
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed, DefaultMaxRedirects when zero
+	MaxRedirects int
+	// AllowAddr reports whether an address may be connected to, IsPublicAddr when nil
+	AllowAddr func(netip.Addr) bool
+
+	once   sync.Once
+	client *http.Client
+}
+
+// DefaultClient is the client used by Get
+var DefaultClient = &Client{}
+
+// Get fetches rawURL with DefaultClient, refusing bodies larger than maxBytes
+func Get(ctx context.Context, rawURL string, maxBytes int64) (*Response, error) {
+	return DefaultClient.Get(ctx, rawURL, maxBytes)
+}
+
+// Get fetches rawURL, refusing bodies larger than maxBytes
+func (c *Client) Get(ctx context.Context, rawURL string, maxBytes int64) (*Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrForbiddenURL, err)
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenURL) {
+			return nil, fmt.Errorf("%w: %s", ErrForbiddenURL, rawURL)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s answered %s", ErrFetchFailed, rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, more than %d", ErrTooLarge, resp.ContentLength, maxBytes)
+	}
+	// The announced length is not trusted: read one byte past the limit to catch a larger body
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s: %v", ErrFetchFailed, rawURL, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxBytes)
+	}
+	return &Response{Data: data, Header: resp.Header, URL: resp.Request.URL}, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	c.once.Do(func() {
+		timeout := c.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		maxRedirects := c.MaxRedirects
+		if maxRedirects <= 0 {
+			maxRedirects = DefaultMaxRedirects
+		}
+		allowed := c.AllowAddr
+		if allowed == nil {
+			allowed = IsPublicAddr
+		}
+
+		dialer := &net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return fmt.Errorf("%w: %v", ErrForbiddenURL, err)
+				}
+				if !allowed(addrPort.Addr().Unmap()) {
+					return fmt.Errorf("%w: %s is not a public address", ErrForbiddenURL, addrPort.Addr())
+				}
+				return nil
+			},
+		}
+		c.client = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// No proxy: the address checked must be the one connected to
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return checkURL(req.URL)
+			},
+		}
+	})
+	return c.client
+}
+
+// checkURL accepts absolute http(s) URLs without credentials
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrForbiddenURL)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrForbiddenURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in the url", ErrForbiddenURL)
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the ranges IsGlobalUnicast accepts that still do not reach the internet
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+}
+
+// IsPublicAddr reports whether addr is a globally routable unicast address. Loopback,
+// link-local (which holds the cloud metadata endpoints), private and unspecified
+// addresses are not.
+func IsPublicAddr(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// Tokenizer counts the tokens of texts and messages
+type Tokenizer interface {
+	CountTokens(text string) (int, error)
+	CountMessageTokens(msg model.Message) (int, error)
+}
+
+// Func adapts a text counting function to the Tokenizer interface
+type Func func(text string) (int, error)
+
+func (f Func) CountTokens(text string) (int, error) { return f(text) }
+
+func (f Func) CountMessageTokens(msg model.Message) (int, error) { return countMessage(f, msg) }
+
+// Heuristic estimates one token per four characters. It needs no vocabulary, which makes it
+// the default when no exact tokenizer is available for a model.
+type Heuristic struct{}
+
+func (Heuristic) CountTokens(text string) (int, error) {
+	return (utf8.RuneCountInString(text) + 3) / 4, nil
+}
+
+func (h Heuristic) CountMessageTokens(msg model.Message) (int, error) { return countMessage(h, msg) }
+
+// BPE counts tokens exactly with one of OpenAI's tiktoken encodings, e.g. cl100k_base or o200k_base
+type BPE struct {
+	encoding string
+	codec    tokenizer.Codec
+}
+
+// codecs caches the loaded encodings, which are expensive to build
+var codecs sync.Map // encoding name -> tokenizer.Codec
+
+// NewBPE returns a BPE tokenizer for a tiktoken encoding
+func NewBPE(encoding string) (*BPE, error) {
+	if c, ok := codecs.Load(encoding); ok {
+		return &BPE{encoding: encoding, codec: c.(tokenizer.Codec)}, nil
+	}
+	c, err := tokenizer.Get(tokenizer.Encoding(encoding))
+	if err != nil {
+		return nil, fmt.Errorf("encoding %q: %w", encoding, err)
+	}
+	return newCachedBPE(c), nil
+}
+
+// ForModel returns a BPE tokenizer with the encoding of an OpenAI model, e.g. o200k_base for
+// gpt-4o. It fails for models without a known encoding.
+func ForModel(modelName string) (*BPE, error) {
+	c, err := tokenizer.ForModel(tokenizer.Model(modelName))
+	if err != nil {
+		return nil, fmt.Errorf("model %q: %w", modelName, err)
+	}
+	return newCachedBPE(c), nil
+}
+
+// newCachedBPE returns a BPE tokenizer with the cached codec of c's encoding, caching c if
+// there is none yet
+func newCachedBPE(c tokenizer.Codec) *BPE {
+	actual, _ := codecs.LoadOrStore(c.GetName(), c)
+	return &BPE{encoding: c.GetName(), codec: actual.(tokenizer.Codec)}
+}
+
+// Encoding returns the name of the encoding, e.g. o200k_base
+func (b *BPE) Encoding() string { return b.encoding }
+
+func (b *BPE) CountTokens(text string) (int, error) {
+	count, err := b.codec.Count(text)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return count, nil
+}
+
+func (b *BPE) CountMessageTokens(msg model.Message) (int, error) { return countMessage(b, msg) }
+
+// MessageText is the text of a message that counts towards its tokens: text parts, tool calls
+// and the text of tool results
+func MessageText(parts []model.Part) (string, error) {
+	text, err := ExtractTextAndToolContent(parts)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(text)
+	for _, p := range parts {
+		if p.Type == "tool-result" && p.Text != "" {
+			b.WriteString(p.Text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
+
+func countMessage(tok interface{ CountTokens(string) (int, error) }, msg model.Message) (int, error) {
+	text, err := MessageText(msg.Parts)
+	if err != nil || text == "" {
+		return 0, err
+	}
+	return tok.CountTokens(text)
+}
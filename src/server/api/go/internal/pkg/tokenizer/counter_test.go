@@ -0,0 +1,73 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeuristic(t *testing.T) {
+	n, err := Heuristic{}.CountTokens(strings.Repeat("a", 9))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, _ = Heuristic{}.CountTokens("")
+	assert.Equal(t, 0, n)
+}
+
+func TestBPE(t *testing.T) {
+	t.Run("encodings", func(t *testing.T) {
+		for _, encoding := range []string{"cl100k_base", "o200k_base"} {
+			bpe, err := NewBPE(encoding)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, bpe.Encoding())
+
+			n, err := bpe.CountTokens("hello world")
+			require.NoError(t, err)
+			assert.Equal(t, 2, n)
+		}
+
+		_, err := NewBPE("unknown")
+		assert.Error(t, err)
+	})
+
+	t.Run("models", func(t *testing.T) {
+		for modelName, encoding := range map[string]string{
+			"gpt-4o":             "o200k_base",
+			"gpt-4o-2024-08-06":  "o200k_base",
+			"gpt-4":              "cl100k_base",
+			"gpt-3.5-turbo-0125": "cl100k_base",
+		} {
+			bpe, err := ForModel(modelName)
+			require.NoError(t, err, modelName)
+			assert.Equal(t, encoding, bpe.Encoding(), modelName)
+		}
+
+		_, err := ForModel("claude-sonnet-4")
+		assert.Error(t, err)
+	})
+}
+
+func TestCountMessageTokens(t *testing.T) {
+	msg := model.Message{Parts: []model.Part{
+		{Type: "text", Text: "abcd"},
+		{Type: "image"},
+		{Type: "tool-result", Text: "efgh"},
+	}}
+
+	// "abcd\nefgh\n" is 10 characters
+	n, err := Heuristic{}.CountMessageTokens(msg)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = Func(func(text string) (int, error) { return len(text), nil }).CountMessageTokens(msg)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	n, err = Heuristic{}.CountMessageTokens(model.Message{Parts: []model.Part{{Type: "image"}}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -3,18 +3,20 @@ package fileparser
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
+	"regexp"
 	"strings"
-
-	"github.com/bytedance/sonic"
 )
 
 // FileContent represents the parsed content of a file
 type FileContent struct {
-	Type string `json:"type"` // "text", "json", "csv", "code"
-	Raw  string `json:"raw"`  // Raw text content
+	Type string `json:"type"`           // "text", "json", "csv", "code", "markdown", "pdf", "binary"
+	Raw  string `json:"raw"`            // Raw text content, the extracted text for PDF, empty for binary
+	Text string `json:"text,omitempty"` // Readable form when it differs from raw: pretty-printed JSON, markdown as plain text
+	Data []byte `json:"data,omitempty"` // Content of a binary file, base64 encoded in JSON
 }
 
 // Parser interface for different file types
@@ -28,7 +30,7 @@ type TextParser struct{}
 
 func (p *TextParser) CanParse(filename string, mimeType string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
-	textExts := []string{".txt", ".md", ".markdown", ".log", ".yml", ".yaml", ".xml", ".html", ".htm"}
+	textExts := []string{".txt", ".log", ".yml", ".yaml", ".xml", ".html", ".htm"}
 
 	for _, textExt := range textExts {
 		if ext == textExt {
@@ -66,15 +68,16 @@ func (p *JSONParser) CanParse(filename string, mimeType string) bool {
 }
 
 func (p *JSONParser) Parse(content []byte) (*FileContent, error) {
-	// Validate that it's valid JSON using sonic
-	var jsonData interface{}
-	if err := sonic.Unmarshal(content, &jsonData); err != nil {
+	// Indent validates the JSON and keeps the key order of the file
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, content, "", "  "); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
 	return &FileContent{
 		Type: "json",
 		Raw:  string(content),
+		Text: strings.TrimSpace(pretty.String()),
 	}, nil
 }
 
@@ -103,6 +106,109 @@ func (p *CSVParser) Parse(content []byte) (*FileContent, error) {
 	}, nil
 }
 
+// MarkdownParser handles Markdown files
+type MarkdownParser struct{}
+
+func (p *MarkdownParser) CanParse(filename string, mimeType string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".md" || ext == ".markdown" {
+		return true
+	}
+	return strings.HasPrefix(mimeType, "text/markdown") || strings.HasPrefix(mimeType, "text/x-markdown")
+}
+
+func (p *MarkdownParser) Parse(content []byte) (*FileContent, error) {
+	return &FileContent{
+		Type: "markdown",
+		Raw:  string(content),
+		Text: MarkdownToText(string(content)),
+	}, nil
+}
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	mdQuote      = regexp.MustCompile(`^\s*(>\s?)+`)
+	mdListItem   = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}((-\s*){3,}|(\*\s*){3,}|(_\s*){3,})$`)
+	mdTableSep   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdLinkDef    = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink   = regexp.MustCompile(`<((https?|mailto):[^>\s]+)>`)
+	mdHTMLTag    = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdCode       = regexp.MustCompile("`([^`]+)`")
+	mdStrong     = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdEmphasis   = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdUnderscore = regexp.MustCompile(`(^|\s)_(\S(?:[^_]*?\S)?)_`)
+	mdStrike     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+)
+
+// MarkdownToText strips the Markdown syntax of a document, keeping its text: headings,
+// quotes and list markers are removed, links and images keep their text, tables become
+// tab-separated rows and code blocks are kept as is.
+func MarkdownToText(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		isTableSep := strings.Contains(line, "|") && mdTableSep.MatchString(line)
+		if mdRule.MatchString(line) || isTableSep || mdLinkDef.MatchString(line) {
+			continue
+		}
+
+		line = mdHeading.ReplaceAllString(line, "")
+		line = mdQuote.ReplaceAllString(line, "")
+		line = mdListItem.ReplaceAllString(line, "$1")
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "|") && strings.HasSuffix(trimmed, "|") {
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			line = strings.Join(cells, "\t")
+		}
+
+		line = mdImage.ReplaceAllString(line, "$1")
+		line = mdLink.ReplaceAllString(line, "$1")
+		line = mdAutolink.ReplaceAllString(line, "$1")
+		line = mdHTMLTag.ReplaceAllString(line, "")
+		line = mdCode.ReplaceAllString(line, "$1")
+		line = mdStrong.ReplaceAllString(line, "$2")
+		line = mdEmphasis.ReplaceAllString(line, "$1")
+		line = mdUnderscore.ReplaceAllString(line, "$1$2")
+		line = mdStrike.ReplaceAllString(line, "$1")
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(collapseBlankLines(out))
+}
+
+// collapseBlankLines joins lines, keeping at most one blank line in a row
+func collapseBlankLines(lines []string) string {
+	var b strings.Builder
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				b.WriteString("\n")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
 // CodeParser handles code files
 type CodeParser struct{}
 
@@ -159,6 +265,8 @@ func NewFileParser() *FileParser {
 		parsers: []Parser{
 			&JSONParser{},
 			&CSVParser{},
+			&MarkdownParser{},
+			&PDFParser{},
 			&CodeParser{},
 			&TextParser{}, // Text parser should be last as it's the fallback
 		},
@@ -175,7 +283,8 @@ func (fp *FileParser) CanParseFile(filename string, mimeType string) bool {
 	return false
 }
 
-// ParseFile attempts to parse file content based on filename and MIME type
+// ParseFile attempts to parse file content based on filename and MIME type. Files no parser
+// supports (e.g., images, archives) are returned as is with type "binary".
 func (fp *FileParser) ParseFile(filename string, mimeType string, content []byte) (*FileContent, error) {
 	// Try each parser in order
 	for _, parser := range fp.parsers {
@@ -184,9 +293,10 @@ func (fp *FileParser) ParseFile(filename string, mimeType string, content []byte
 		}
 	}
 
-	// If no parser matches (e.g., images, binary files), return nil
-	// Only text-based files should be parsed
-	return nil, fmt.Errorf("unsupported file type: %s (mime: %s)", filename, mimeType)
+	return &FileContent{
+		Type: "binary",
+		Data: content,
+	}, nil
 }
 
 // ParseFileFromReader parses file content from an io.Reader
@@ -1,6 +1,9 @@
 package fileparser
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -40,7 +43,7 @@ func TestFileParser(t *testing.T) {
 			filename: "test.md",
 			mimeType: "text/markdown",
 			content:  []byte("# Test\n\nThis is a test markdown file."),
-			expected: "text",
+			expected: "markdown",
 		},
 		{
 			name:     "Plain text file",
@@ -111,16 +114,103 @@ func TestCSVParser(t *testing.T) {
 
 func TestUnsupportedFileType(t *testing.T) {
 	parser := NewFileParser()
+	content := readFixture(t, "sample.bin")
+
+	// Images and other binary files are returned as is
+	for _, file := range []struct{ filename, mimeType string }{
+		{"image.png", "image/png"},
+		{"binary.exe", "application/octet-stream"},
+	} {
+		result, err := parser.ParseFile(file.filename, file.mimeType, content)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) error = %v", file.filename, err)
+		}
+		if result.Type != "binary" {
+			t.Errorf("ParseFile(%s) type = %v, want binary", file.filename, result.Type)
+		}
+		if !bytes.Equal(result.Data, content) {
+			t.Errorf("ParseFile(%s) data = %v, want %v", file.filename, result.Data, content)
+		}
+		if result.Raw != "" {
+			t.Errorf("ParseFile(%s) raw = %q, want empty", file.filename, result.Raw)
+		}
+	}
+}
 
-	// Test image file (should not be supported)
-	_, err := parser.ParseFile("image.png", "image/png", []byte{})
-	if err == nil {
-		t.Error("ParseFile() should return error for unsupported file type")
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
 	}
+	return content
+}
 
-	// Test binary file
-	_, err = parser.ParseFile("binary.exe", "application/octet-stream", []byte{})
-	if err == nil {
-		t.Error("ParseFile() should return error for unsupported file type")
+func TestJSONParser_PrettyPrints(t *testing.T) {
+	content := readFixture(t, "sample.json")
+	result, err := NewFileParser().ParseFile("sample.json", "application/json", content)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if result.Raw != string(content) {
+		t.Errorf("ParseFile() raw = %v, want the file content", result.Raw)
+	}
+	// Keys keep the order of the file
+	want := `{
+  "name": "report",
+  "tags": [
+    "q1",
+    "draft"
+  ],
+  "stats": {
+    "pages": 3,
+    "final": false
+  }
+}`
+	if result.Text != want {
+		t.Errorf("ParseFile() text = %v, want %v", result.Text, want)
+	}
+}
+
+func TestMarkdownParser(t *testing.T) {
+	content := readFixture(t, "sample.md")
+
+	// The MIME type alone selects the parser
+	result, err := NewFileParser().ParseFile("notes", "text/markdown", content)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if result.Type != "markdown" {
+		t.Errorf("ParseFile() type = %v, want markdown", result.Type)
+	}
+	if result.Raw != string(content) {
+		t.Errorf("ParseFile() raw = %v, want the file content", result.Raw)
+	}
+
+	want := "Weekly Report\n" +
+		"\n" +
+		"Progress was good this week, see the dashboard and make test.\n" +
+		"\n" +
+		"Shipping is on track.\n" +
+		"\n" +
+		"First item\n" +
+		"Done item\n" +
+		"Numbered item\n" +
+		"\n" +
+		"Name\tStatus\n" +
+		"API\tdone\n" +
+		"\n" +
+		"chart\n" +
+		"\n" +
+		"fmt.Println(\"kept as is\")"
+	if result.Text != want {
+		t.Errorf("ParseFile() text = %q, want %q", result.Text, want)
+	}
+}
+
+func TestMarkdownToText_KeepsSnakeCase(t *testing.T) {
+	if got := MarkdownToText("call get_user_id, not *this*"); got != "call get_user_id, not this" {
+		t.Errorf("MarkdownToText() = %q", got)
 	}
 }
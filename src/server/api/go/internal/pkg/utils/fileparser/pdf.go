@@ -0,0 +1,370 @@
+package fileparser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDFParser extracts the text of PDF files. Extraction is basic: the text shown by the page
+// content streams, uncompressed or Flate-compressed, decoded as Latin-1 or UTF-16. Text in
+// fonts with custom encodings comes out garbled and scanned pages have none.
+type PDFParser struct{}
+
+func (p *PDFParser) CanParse(filename string, mimeType string) bool {
+	if strings.ToLower(filepath.Ext(filename)) == ".pdf" {
+		return true
+	}
+	return strings.HasPrefix(mimeType, "application/pdf")
+}
+
+func (p *PDFParser) Parse(content []byte) (*FileContent, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("failed to parse PDF: missing %%PDF header")
+	}
+
+	return &FileContent{
+		Type: "pdf",
+		Raw:  extractPDFText(content),
+	}, nil
+}
+
+// maxPDFDecodedSizeB bounds the size of the content streams of a PDF once decompressed,
+// all streams together, so a small file of many Flate streams cannot exhaust memory
+const maxPDFDecodedSizeB = 64 << 20
+
+// Filters other than FlateDecode, whose streams are not decoded
+var pdfUnsupportedFilters = []string{
+	"/ASCII85Decode", "/ASCIIHexDecode", "/LZWDecode", "/RunLengthDecode",
+	"/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode", "/Crypt",
+}
+
+// extractPDFText returns the text of the content streams of a PDF, one stream after the
+// other. Streams of images, fonts, forms and other typed objects are skipped, and the
+// streams past maxPDFDecodedSizeB of decoded data are left out.
+func extractPDFText(content []byte) string {
+	var lines []string
+	pos := 0
+	budget := int64(maxPDFDecodedSizeB)
+	for budget > 0 {
+		// The dictionary of the next stream is searched for after the previous stream only
+		prev := pos
+		i := bytes.Index(content[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		// "endstream" contains "stream" too
+		if start >= 3 && string(content[start-3:start]) == "end" {
+			pos = start + len("stream")
+			continue
+		}
+
+		dataStart := start + len("stream")
+		if dataStart < len(content) && content[dataStart] == '\r' {
+			dataStart++
+		}
+		if dataStart < len(content) && content[dataStart] == '\n' {
+			dataStart++
+		}
+		end := bytes.Index(content[dataStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := content[dataStart : dataStart+end]
+		pos = dataStart + end + len("endstream")
+
+		// The stream dictionary sits between the object header and the stream keyword
+		dictStart := prev
+		if i := bytes.LastIndex(content[prev:start], []byte("obj")); i >= 0 {
+			dictStart += i
+		}
+		data, ok := decodePDFStream(string(content[dictStart:start]), data, budget)
+		if !ok {
+			continue
+		}
+		budget -= int64(len(data))
+		if text := strings.TrimSpace(pdfContentText(data)); text != "" {
+			lines = append(lines, strings.Split(text, "\n")...)
+		}
+	}
+
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.TrimSpace(collapseBlankLines(lines))
+}
+
+// decodePDFStream returns the data of a content stream, inflated when Flate-compressed, up
+// to limit bytes. It reports false for streams that are not page content or cannot be
+// decoded.
+func decodePDFStream(dict string, data []byte, limit int64) ([]byte, bool) {
+	for _, key := range []string{"/Type", "/Subtype", "/Length1", "/Length2", "/Length3"} {
+		if strings.Contains(dict, key) {
+			return nil, false
+		}
+	}
+	if !strings.Contains(dict, "/Filter") {
+		return data[:min(int64(len(data)), limit)], true
+	}
+	if !strings.Contains(dict, "/FlateDecode") {
+		return nil, false
+	}
+	for _, filter := range pdfUnsupportedFilters {
+		if strings.Contains(dict, filter) {
+			return nil, false
+		}
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	// A truncated stream still yields the text inflated before the error
+	inflated, _ := io.ReadAll(io.LimitReader(r, limit))
+	return inflated, len(inflated) > 0
+}
+
+// pdfOperand is an operand of a content stream operator: a string, a number, an array or
+// anything else (names, dictionaries), which is kept only as a placeholder
+type pdfOperand struct {
+	str   string
+	isStr bool
+	num   float64
+	isNum bool
+	array []pdfOperand
+}
+
+var pdfInlineImageEnd = regexp.MustCompile(`\sEI(\s|$)`)
+
+// pdfContentText runs the text operators of a content stream: strings shown by Tj, TJ, '
+// and " are written out, and moves to another line start a new line
+func pdfContentText(data []byte) string {
+	var (
+		b        strings.Builder
+		operands []pdfOperand
+		array    []pdfOperand
+		inArray  bool
+	)
+	push := func(op pdfOperand) {
+		if inArray {
+			array = append(array, op)
+		} else {
+			operands = append(operands, op)
+		}
+	}
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	last := func() pdfOperand {
+		if len(operands) == 0 {
+			return pdfOperand{}
+		}
+		return operands[len(operands)-1]
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := readPDFLiteralString(data, i)
+			push(pdfOperand{str: decodePDFString(s), isStr: true})
+			i = next
+		case c == '<' && i+1 < len(data) && data[i+1] == '<':
+			push(pdfOperand{})
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				end = len(data) - i
+			}
+			push(pdfOperand{str: decodePDFString(decodePDFHex(data[i+1 : i+end])), isStr: true})
+			i += end + 1
+		case c == '>':
+			i++
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, pdfOperand{array: array})
+			i++
+		default:
+			start := i
+			i++
+			for i < len(data) && !isPDFWhitespace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+			word := string(data[start:i])
+			if c == '/' {
+				push(pdfOperand{})
+				continue
+			}
+			if num, err := strconv.ParseFloat(word, 64); err == nil {
+				push(pdfOperand{num: num, isNum: true})
+				continue
+			}
+
+			switch word {
+			case "Tj":
+				b.WriteString(last().str)
+			case "'", "\"":
+				newline()
+				b.WriteString(last().str)
+			case "TJ":
+				for _, item := range last().array {
+					switch {
+					case item.isStr:
+						b.WriteString(item.str)
+					case item.isNum && item.num < -200 && !strings.HasSuffix(b.String(), " "):
+						// A large negative adjustment is a word gap
+						b.WriteByte(' ')
+					}
+				}
+			case "Td", "TD":
+				if ty := last(); ty.isNum && ty.num != 0 {
+					newline()
+				}
+			case "T*", "Tm", "ET":
+				newline()
+			case "ID":
+				// Skip the data of an inline image
+				if loc := pdfInlineImageEnd.FindIndex(data[i:]); loc != nil {
+					i += loc[1]
+				} else {
+					i = len(data)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return b.String()
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// readPDFLiteralString reads the literal string starting at the "(" at data[start], with its
+// escapes resolved, and returns it with the index after its closing ")"
+func readPDFLiteralString(data []byte, start int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	i := start
+	for i < len(data) {
+		c := data[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+			i++
+		case ')':
+			depth--
+			i++
+			if depth == 0 {
+				return out, i
+			}
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return out, i
+			}
+			e := data[i]
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// Line continuation
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					out = append(out, byte(n))
+					continue
+				}
+				out = append(out, e)
+			}
+			i++
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out, i
+}
+
+// decodePDFHex decodes the digits of a hex string, a missing last digit being 0
+func decodePDFHex(digits []byte) []byte {
+	var hex []byte
+	for _, c := range digits {
+		if !isPDFWhitespace(c) {
+			hex = append(hex, c)
+		}
+	}
+	if len(hex)%2 == 1 {
+		hex = append(hex, '0')
+	}
+	out := make([]byte, 0, len(hex)/2)
+	for i := 0; i < len(hex); i += 2 {
+		v, err := strconv.ParseUint(string(hex[i:i+2]), 16, 8)
+		if err != nil {
+			continue
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+// decodePDFString decodes the bytes of a string: UTF-16BE after a byte order mark,
+// Latin-1 otherwise
+func decodePDFString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
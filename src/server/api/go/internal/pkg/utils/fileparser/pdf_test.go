@@ -0,0 +1,125 @@
+package fileparser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPDFParser(t *testing.T) {
+	content := readFixture(t, "sample.pdf")
+
+	result, err := NewFileParser().ParseFile("report.pdf", "application/pdf", content)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if result.Type != "pdf" {
+		t.Errorf("ParseFile() type = %v, want pdf", result.Type)
+	}
+
+	// The first page is uncompressed, the second Flate-compressed with TJ and a hex string
+	want := "Hello PDF\nSecond line with (parens)\nPage two\nHex string"
+	if result.Raw != want {
+		t.Errorf("ParseFile() raw = %q, want %q", result.Raw, want)
+	}
+}
+
+func TestPDFParser_NotAPDF(t *testing.T) {
+	if _, err := (&PDFParser{}).Parse([]byte("just text")); err == nil {
+		t.Error("PDFParser.Parse() should return error without the PDF header")
+	}
+}
+
+func TestPDFContentText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "escapes and octal",
+			content: `BT (Tab\there \101\102C) Tj ET`,
+			want:    "Tab\there ABC",
+		},
+		{
+			name:    "quote operators start a line",
+			content: `BT (one) Tj (two) ' 1 2 (three) " ET`,
+			want:    "one\ntwo\nthree",
+		},
+		{
+			name:    "UTF-16 string",
+			content: `BT <FEFF00E9007400E9> Tj ET`,
+			want:    "été",
+		},
+		{
+			name:    "horizontal move stays on the line",
+			content: `BT (left) Tj 50 0 Td ( right) Tj ET`,
+			want:    "left right",
+		},
+		{
+			name:    "inline image data is skipped",
+			content: "BT (before) Tj ET BI /W 1 /H 1 ID \x00(Tj)\xff EI BT (after) Tj ET",
+			want:    "before\nafter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pdfContentText([]byte(tt.content))
+			if got != tt.want && got != tt.want+"\n" {
+				t.Errorf("pdfContentText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFText_SkipsUnsupportedStreams(t *testing.T) {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	_, _ = w.Write([]byte("BT (from flate) Tj ET"))
+	_ = w.Close()
+
+	pdf := "%PDF-1.7\n" +
+		"1 0 obj\n<< /Length 5 /Filter /DCTDecode >>\nstream\n(jpg) Tj\nendstream\nendobj\n" +
+		"2 0 obj\n<< /Type /XObject /Subtype /Image /Length 3 >>\nstream\n(img) Tj\nendstream\nendobj\n" +
+		"3 0 obj\n<< /Length 10 /Filter /FlateDecode >>\nstream\n" + compressed.String() + "\nendstream\nendobj\n"
+	if got := extractPDFText([]byte(pdf)); got != "from flate" {
+		t.Errorf("extractPDFText() = %q, want %q", got, "from flate")
+	}
+}
+
+func TestExtractPDFText_DecodedSizeBudget(t *testing.T) {
+	flate := func(s string) string {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		_, _ = w.Write([]byte(s))
+		_ = w.Close()
+		return compressed.String()
+	}
+
+	// Streams inflating to 1MB each, the first one shown and the ones past the budget not
+	const streamSizeB = 1 << 20
+	pad := strings.Repeat(" ", streamSizeB-len("BT (first) Tj ET"))
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.7\n")
+	for i := 0; i <= maxPDFDecodedSizeB/streamSizeB; i++ {
+		text := "BT (middle) Tj ET"
+		switch i {
+		case 0:
+			text = "BT (first) Tj ET"
+		case maxPDFDecodedSizeB / streamSizeB:
+			text = "BT (last) Tj ET"
+		}
+		fmt.Fprintf(&pdf, "%d 0 obj\n<< /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", i+1, flate(text+pad))
+	}
+
+	got := extractPDFText([]byte(pdf.String()))
+	if !strings.HasPrefix(got, "first") {
+		t.Errorf("extractPDFText() = %.20q..., want the first stream", got)
+	}
+	if strings.Contains(got, "last") {
+		t.Error("extractPDFText() decoded a stream past the budget")
+	}
+}
@@ -0,0 +1,35 @@
+package fileparser
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// heifBrands maps the major brands of ISO BMFF files to the image types they hold
+var heifBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic-sequence",
+	"hevx": "image/heic-sequence",
+	"mif1": "image/heif",
+	"msf1": "image/heif-sequence",
+}
+
+// DetectContentType returns the MIME type of content from its leading bytes. WebP and
+// HEIC/HEIF images are recognised by their magic bytes, which http.DetectContentType does
+// not fully cover; everything else is left to http.DetectContentType.
+func DetectContentType(content []byte) string {
+	// RIFF <size> WEBP
+	if len(content) >= 12 && bytes.Equal(content[0:4], []byte("RIFF")) && bytes.Equal(content[8:12], []byte("WEBP")) {
+		return "image/webp"
+	}
+	// <box size> ftyp <major brand>
+	if len(content) >= 12 && bytes.Equal(content[4:8], []byte("ftyp")) {
+		if ct, ok := heifBrands[string(content[8:12])]; ok {
+			return ct
+		}
+	}
+	return http.DetectContentType(content)
+}
@@ -0,0 +1,26 @@
+package fileparser
+
+import "testing"
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{name: "webp", content: readFixture(t, "sample.webp"), want: "image/webp"},
+		{name: "heic", content: readFixture(t, "sample.heic"), want: "image/heic"},
+		{name: "heif", content: readFixture(t, "sample.heif"), want: "image/heif"},
+		{name: "pdf", content: readFixture(t, "sample.pdf"), want: "application/pdf"},
+		{name: "other iso media", content: []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), want: "video/mp4"},
+		{name: "truncated riff", content: []byte("RIFF\x1a\x00"), want: "application/octet-stream"},
+		{name: "empty", content: nil, want: "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.content); got != tt.want {
+				t.Errorf("DetectContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema := MustParse(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5},
+			"count": {"type": "integer"},
+			"level": {"enum": ["low", "high", 3]},
+			"note": {"type": ["string", "null"]},
+			"steps": {
+				"type": "array",
+				"items": {"type": "object", "required": ["action"], "additionalProperties": false, "properties": {"action": {"type": "string"}}}
+			}
+		}
+	}`)
+
+	decode := func(t *testing.T, src string) any {
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(src), &v))
+		return v
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "valid", value: `{"name": "a", "count": 2, "level": 3, "note": null, "steps": [{"action": "x"}], "extra": true}`},
+		{name: "not an object", value: `[]`, wantErr: "expected object, got array"},
+		{name: "missing required", value: `{}`, wantErr: "name: is required"},
+		{name: "wrong type", value: `{"name": 1}`, wantErr: "name: expected string, got number"},
+		{name: "too short", value: `{"name": ""}`, wantErr: "name: must be at least 1 characters"},
+		{name: "too long", value: `{"name": "abcdef"}`, wantErr: "name: must be at most 5 characters"},
+		{name: "fractional integer", value: `{"name": "a", "count": 1.5}`, wantErr: "count: expected integer, got number"},
+		{name: "not in enum", value: `{"name": "a", "level": "mid"}`, wantErr: "level: must be one of [low high 3]"},
+		{name: "type list", value: `{"name": "a", "note": 1}`, wantErr: "note: expected one of [string null], got number"},
+		{name: "array item", value: `{"name": "a", "steps": [{"action": "x"}, {}]}`, wantErr: "steps[1].action: is required"},
+		{name: "additional property", value: `{"name": "a", "steps": [{"action": "x", "tool": "y"}]}`, wantErr: "steps[0].tool: is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(decode(t, tt.value))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParse_InvalidType(t *testing.T) {
+	_, err := Parse([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+}
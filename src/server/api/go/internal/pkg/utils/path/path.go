@@ -133,6 +133,43 @@ func GetDirectoriesFromPaths(parentPath string, filePaths []string) []string {
 	return result
 }
 
+// CanonicalDir normalizes a directory path to a single leading and trailing slash
+// with duplicate slashes collapsed, so equivalent spellings match the stored path
+// Examples:
+//
+//	"/a//b/" -> "/a/b/"
+//	"a/b" -> "/a/b/"
+//	"" -> "/"
+func CanonicalDir(dir string) string {
+	parts := strings.Split(strings.TrimSpace(dir), "/")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	if len(kept) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(kept, "/") + "/"
+}
+
+// ParentDirs returns the ancestors of a canonical directory, from the root down
+// Examples:
+//
+//	"/a/b/" -> "/", "/a/"
+//	"/a/" -> "/"
+//	"/" -> none
+func ParentDirs(dir string) []string {
+	var parents []string
+	for i := 0; i < len(dir)-1; i++ {
+		if dir[i] == '/' {
+			parents = append(parents, dir[:i+1])
+		}
+	}
+	return parents
+}
+
 // SplitFilePath splits a file path into directory path and filename
 // Examples:
 //
@@ -140,6 +177,7 @@ func GetDirectoriesFromPaths(parentPath string, filePaths []string) []string {
 //	"/report.pdf" -> "/", "report.pdf"
 //	"report.pdf" -> "/", "report.pdf"
 //	"/documents/" -> "/documents/", ""
+//	"/a//b/report.pdf" -> "/a/b/", "report.pdf"
 func SplitFilePath(filePath string) (path, filename string) {
 	if filePath == "" {
 		return "/", ""
@@ -158,14 +196,9 @@ func SplitFilePath(filePath string) (path, filename string) {
 		return "/", filePath
 	}
 
-	// Split into path and filename
-	path = filePath[:lastSlash+1] // Include the trailing slash
+	// Split into path and filename; the directory is returned in canonical form
+	path = CanonicalDir(filePath[:lastSlash+1])
 	filename = filePath[lastSlash+1:]
 
-	// If path is empty after splitting, make it root
-	if path == "" {
-		path = "/"
-	}
-
 	return path, filename
 }
@@ -609,7 +609,7 @@ func TestSplitFilePath(t *testing.T) {
 		{
 			name:         "path with multiple slashes",
 			input:        "//documents//report.pdf",
-			expectedPath: "//documents//",
+			expectedPath: "/documents/",
 			expectedFile: "report.pdf",
 		},
 		{
@@ -621,7 +621,7 @@ func TestSplitFilePath(t *testing.T) {
 		{
 			name:         "path with only slashes",
 			input:        "///",
-			expectedPath: "///",
+			expectedPath: "/",
 			expectedFile: "",
 		},
 		{
@@ -670,3 +670,44 @@ func TestSplitFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "duplicate slashes", input: "/a//b/", expected: "/a/b/"},
+		{name: "missing leading and trailing slash", input: "a/b", expected: "/a/b/"},
+		{name: "missing trailing slash", input: "/docs", expected: "/docs/"},
+		{name: "already canonical", input: "/docs/", expected: "/docs/"},
+		{name: "root", input: "/", expected: "/"},
+		{name: "only slashes", input: "///", expected: "/"},
+		{name: "empty is root", input: "", expected: "/"},
+		{name: "surrounding spaces", input: "  /docs/reports  ", expected: "/docs/reports/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CanonicalDir(tt.input))
+		})
+	}
+}
+
+func TestParentDirs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "nested", input: "/a/b/c/", expected: []string{"/", "/a/", "/a/b/"}},
+		{name: "top level", input: "/docs/", expected: []string{"/"}},
+		{name: "root", input: "/", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParentDirs(tt.input))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package secrets
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
@@ -16,8 +18,19 @@ const (
 	Threads   = 4
 	KeyLen    = 32
 	SaltBytes = 16
+	// SecretBytes is the amount of randomness in a generated project secret
+	SecretBytes = 32
 )
 
+// GenerateSecret returns a new random URL-safe project secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 func HashSecret(secret, pepper string) (string, error) {
 	if secret == "" {
 		return "", errors.New("empty secret")
@@ -68,3 +81,53 @@ func VerifySecret(secret, pepper, phc string) (bool, error) {
 	}
 	return diff == 0, nil
 }
+
+// EncryptionKeyBytes is the size of the AES-256 keys taken by Encrypt and Decrypt
+const EncryptionKeyBytes = 32
+
+// Encrypt seals plaintext with AES-256-GCM under key and returns the random nonce followed
+// by the ciphertext, base64-encoded
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with the same key
+func Decrypt(key []byte, ciphertext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decode ciphertext: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.New("ciphertext cannot be decrypted with this key")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeyBytes {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeyBytes, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
@@ -12,21 +13,29 @@ import (
 	"github.com/memodb-io/Acontext/internal/middleware"
 	"github.com/memodb-io/Acontext/internal/modules/handler"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/telemetry"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type RouterDeps struct {
-	Config          *config.Config
-	DB              *gorm.DB
-	Log             *zap.Logger
-	SpaceHandler    *handler.SpaceHandler
-	BlockHandler    *handler.BlockHandler
-	SessionHandler  *handler.SessionHandler
-	DiskHandler     *handler.DiskHandler
-	ArtifactHandler *handler.ArtifactHandler
-	TaskHandler     *handler.TaskHandler
-	ToolHandler     *handler.ToolHandler
+	Config                *config.Config
+	DB                    *gorm.DB
+	Log                   *zap.Logger
+	Redis                 *redis.Client
+	SpaceHandler          *handler.SpaceHandler
+	BlockHandler          *handler.BlockHandler
+	SessionHandler        *handler.SessionHandler
+	DiskHandler           *handler.DiskHandler
+	ArtifactHandler       *handler.ArtifactHandler
+	TaskHandler           *handler.TaskHandler
+	ToolHandler           *handler.ToolHandler
+	ToolReferenceHandler  *handler.ToolReferenceHandler
+	ToolSOPHandler        *handler.ToolSOPHandler
+	BlockReferenceHandler *handler.BlockReferenceHandler
+	AssetReferenceHandler *handler.AssetReferenceHandler
+	ProjectHandler        *handler.ProjectHandler
+	Metrics               telemetry.Metrics
 }
 
 func NewRouter(d RouterDeps) *gin.Engine {
@@ -44,10 +53,19 @@ func NewRouter(d RouterDeps) *gin.Engine {
 	}
 
 	r.Use(middleware.ZapLogger(d.Log))
+	r.Use(middleware.CORS(d.Config.CORS))
+	if d.Metrics != nil {
+		r.Use(middleware.Metrics(d.Metrics))
+	}
 
 	// health
 	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, serializer.Response{Msg: "ok"}) })
 
+	// prometheus metrics
+	if pm, ok := d.Metrics.(*telemetry.PrometheusMetrics); ok {
+		r.GET("/metrics", gin.WrapH(pm.Handler()))
+	}
+
 	// swagger
 	r.GET("/swagger", func(c *gin.Context) {
 		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
@@ -58,6 +76,12 @@ func NewRouter(d RouterDeps) *gin.Engine {
 	{
 		v1.Use(middleware.ProjectAuth(d.Config, d.DB))
 
+		// Idempotency-Key support for create endpoints that clients may retry
+		idempotent := middleware.Idempotency(middleware.NewRedisIdempotencyStore(d.Redis), middleware.DefaultIdempotencyTTL)
+
+		// gzip for read-heavy endpoints returning large JSON payloads
+		compressed := middleware.Gzip(middleware.DefaultGzipMinSize)
+
 		// ping endpoint
 		v1.GET("/ping", func(c *gin.Context) { c.JSON(http.StatusOK, serializer.Response{Msg: "pong"}) })
 
@@ -65,7 +89,7 @@ func NewRouter(d RouterDeps) *gin.Engine {
 		{
 			space.GET("/status")
 
-			space.GET("", d.SpaceHandler.GetSpaces)
+			space.GET("", compressed, d.SpaceHandler.GetSpaces)
 			space.POST("", d.SpaceHandler.CreateSpace)
 			space.DELETE("/:space_id", d.SpaceHandler.DeleteSpace)
 
@@ -79,22 +103,38 @@ func NewRouter(d RouterDeps) *gin.Engine {
 
 			block := space.Group("/:space_id/block")
 			{
-				block.GET("", d.BlockHandler.ListBlocks)
-				block.POST("", d.BlockHandler.CreateBlock)
+				block.GET("", compressed, d.BlockHandler.ListBlocks)
+				block.GET("/count", d.BlockHandler.CountBlocks)
+				block.GET("/recent", d.BlockHandler.ListRecentBlocks)
+				block.POST("", idempotent, d.BlockHandler.CreateBlock)
+				block.POST("/import", d.BlockHandler.ImportBlocks)
+				block.POST("/move-batch", d.BlockHandler.MoveBlocksBatch)
+				block.POST("/batch-get", d.BlockHandler.GetBlocksBatch)
+				block.POST("/bulk-update", d.BlockHandler.BulkUpdateBlocks)
 				block.DELETE("/:block_id", d.BlockHandler.DeleteBlock)
 
+				block.GET("/:block_id", d.BlockHandler.GetBlock)
 				block.GET("/:block_id/properties", d.BlockHandler.GetBlockProperties)
 				block.PUT("/:block_id/properties", d.BlockHandler.UpdateBlockProperties)
+				block.PATCH("/:block_id/properties", d.BlockHandler.PatchBlockProperties)
+				block.GET("/:block_id/references", d.BlockReferenceHandler.ResolveBlockReferences)
 
 				block.PUT("/:block_id/move", d.BlockHandler.MoveBlock)
+				block.PUT("/:block_id/move-space", d.BlockHandler.MoveBlockToSpace)
+				block.POST("/:block_id/undo-move", d.BlockHandler.UndoBlockMove)
 				block.PUT("/:block_id/sort", d.BlockHandler.UpdateBlockSort)
+
+				block.POST("/:block_id/sop-steps", d.ToolSOPHandler.AppendSOPStep)
+				block.DELETE("/:block_id/sop-steps/:step_id", d.ToolSOPHandler.DeleteSOPStep)
+				block.PUT("/:block_id/sop-steps/:step_id/order", d.ToolSOPHandler.ReorderSOPStep)
 			}
 		}
 
 		session := v1.Group("/session")
 		{
-			session.GET("", d.SessionHandler.GetSessions)
+			session.GET("", compressed, d.SessionHandler.GetSessions)
 			session.POST("", d.SessionHandler.CreateSession)
+			session.GET("/diff", d.SessionHandler.DiffSessions)
 			session.DELETE("/:session_id", d.SessionHandler.DeleteSession)
 
 			session.PUT("/:session_id/configs", d.SessionHandler.UpdateConfigs)
@@ -103,32 +143,56 @@ func NewRouter(d RouterDeps) *gin.Engine {
 			session.POST("/:session_id/connect_to_space", d.SessionHandler.ConnectToSpace)
 
 			session.POST("/:session_id/messages", d.SessionHandler.StoreMessage)
-			session.GET("/:session_id/messages", d.SessionHandler.GetMessages)
+			session.POST("/:session_id/messages/ingest", d.SessionHandler.IngestMessages)
+			session.GET("/:session_id/messages", compressed, d.SessionHandler.GetMessages)
+			session.GET("/:session_id/messages/export", compressed, d.SessionHandler.ExportMessages)
+			session.GET("/:session_id/message/:message_id/part/:index/content", d.SessionHandler.GetMessagePartContent)
 
 			session.POST("/:session_id/flush", d.SessionHandler.SessionFlush)
 			session.GET("/:session_id/get_learning_status", d.SessionHandler.GetLearningStatus)
 
 			session.GET("/:session_id/token_counts", d.SessionHandler.GetTokenCounts)
+			session.GET("/:session_id/token-count", d.SessionHandler.CountSessionTokens)
+			session.GET("/:session_id/summary", d.SessionHandler.GetSessionSummary)
 
 			task := session.Group("/:session_id/task")
 			{
-				task.GET("", d.TaskHandler.GetTasks)
+				task.GET("", compressed, d.TaskHandler.GetTasks)
 			}
 		}
 
 		disk := v1.Group("/disk")
 		{
-			disk.GET("", d.DiskHandler.ListDisks)
+			disk.GET("", compressed, d.DiskHandler.ListDisks)
 			disk.POST("", d.DiskHandler.CreateDisk)
+			disk.GET("/default", d.DiskHandler.GetDefaultDisk)
 			disk.DELETE("/:disk_id", d.DiskHandler.DeleteDisk)
+			disk.POST("/:disk_id/restore", d.DiskHandler.RestoreDisk)
 
 			artifact := disk.Group("/:disk_id/artifact")
 			{
-				artifact.POST("", d.ArtifactHandler.UpsertArtifact)
+				artifact.POST("", idempotent, d.ArtifactHandler.UpsertArtifact)
+				artifact.POST("/from-url", idempotent, d.ArtifactHandler.CreateArtifactFromURL)
 				artifact.GET("", d.ArtifactHandler.GetArtifact)
 				artifact.PUT("", d.ArtifactHandler.UpdateArtifact)
+				artifact.POST("/copy", d.ArtifactHandler.CopyArtifact)
+				artifact.POST("/rename", d.ArtifactHandler.RenameArtifact)
+				artifact.POST("/move", d.ArtifactHandler.MoveArtifact)
 				artifact.DELETE("", d.ArtifactHandler.DeleteArtifact)
-				artifact.GET("/ls", d.ArtifactHandler.ListArtifacts)
+				artifact.GET("/ls", compressed, d.ArtifactHandler.ListArtifacts)
+				artifact.GET("/all", compressed, d.ArtifactHandler.ListAllArtifacts)
+				artifact.GET("/archive", d.ArtifactHandler.DownloadDiskArchive)
+				artifact.POST("/archive", d.ArtifactHandler.ImportDiskArchive)
+				artifact.GET("/dirs", compressed, d.ArtifactHandler.ListArtifactDirs)
+				artifact.GET("/meta", d.ArtifactHandler.GetArtifactMeta)
+				artifact.GET("/verify", d.ArtifactHandler.VerifyArtifact)
+				artifact.GET("/s3-meta", d.ArtifactHandler.GetArtifactS3Meta)
+				artifact.GET("/stats", d.ArtifactHandler.GetArtifactStats)
+				artifact.POST("/presign-batch", d.ArtifactHandler.PresignArtifactsBatch)
+
+				artifact.GET("/tags", compressed, d.ArtifactHandler.ListArtifactsByTag)
+				artifact.POST("/tags", d.ArtifactHandler.AddArtifactTags)
+				artifact.DELETE("/tags", d.ArtifactHandler.RemoveArtifactTags)
 			}
 		}
 
@@ -137,6 +201,22 @@ func NewRouter(d RouterDeps) *gin.Engine {
 			tool.PUT("/name", d.ToolHandler.RenameToolName)
 			tool.GET("/name", d.ToolHandler.GetToolName)
 		}
+
+		project := v1.Group("/project")
+		{
+			toolReference := project.Group("/tool-reference")
+			{
+				toolReference.GET("", d.ToolReferenceHandler.ListToolReferences)
+				toolReference.POST("", d.ToolReferenceHandler.CreateToolReference)
+				toolReference.POST("/import", d.ToolReferenceHandler.ImportToolReferences)
+				toolReference.PUT("/:tool_reference_id", d.ToolReferenceHandler.UpdateToolReference)
+				toolReference.DELETE("/:tool_reference_id", d.ToolReferenceHandler.DeleteToolReference)
+			}
+
+			project.GET("/asset", d.AssetReferenceHandler.ListAssetReferences)
+			project.POST("/asset/sweep-orphans", d.AssetReferenceHandler.SweepOrphanedAssets)
+			project.POST("/rotate-key", d.ProjectHandler.RotateKey)
+		}
 	}
 	return r
 }
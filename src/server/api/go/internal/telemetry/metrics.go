@@ -0,0 +1,161 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Directions of the bytes moved to and from object storage
+const (
+	StorageUpload   = "upload"
+	StorageDownload = "download"
+)
+
+// Metrics records the request and storage metrics of the server
+type Metrics interface {
+	// ObserveRequest records a handled request; route is the matched route pattern, empty
+	// when no route matched
+	ObserveRequest(method string, route string, status int, elapsed time.Duration)
+	// AddStorageBytes records n bytes moved to (StorageUpload) or from (StorageDownload)
+	// object storage
+	AddStorageBytes(direction string, n int64)
+}
+
+// NopMetrics discards every metric, for when metrics are disabled
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveRequest(string, string, int, time.Duration) {}
+
+func (NopMetrics) AddStorageBytes(string, int64) {}
+
+// StorageTotalsFunc reads the amounts of data stored across all projects
+type StorageTotalsFunc func(ctx context.Context) (*model.StorageTotals, error)
+
+// PrometheusMetrics keeps the metrics in a Prometheus registry of its own, served by Handler
+type PrometheusMetrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.HistogramVec
+	storageBytes *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics registers the request and storage metrics, the Go runtime and process
+// metrics, and gauges of the stored data read from totals when scraped
+func NewPrometheusMetrics(totals StorageTotalsFunc) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "acontext_http_request_duration_seconds",
+			Help:    "Latency of the HTTP requests by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storageBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "acontext_storage_bytes_total",
+			Help: "Bytes uploaded to and downloaded from object storage.",
+		}, []string{"direction"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.storageBytes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if totals != nil {
+		m.registry.MustRegister(newStorageCollector(totals))
+	}
+	return m
+}
+
+func (m *PrometheusMetrics) ObserveRequest(method string, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		// Unmatched paths would make one series per path
+		route = "unmatched"
+	}
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+func (m *PrometheusMetrics) AddStorageBytes(direction string, n int64) {
+	m.storageBytes.WithLabelValues(direction).Add(float64(n))
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// storageTotalsTTL is how long the stored data totals are reused between scrapes, since
+// counting them scans whole tables
+const storageTotalsTTL = 30 * time.Second
+
+// storageCollector exports the stored data totals as gauges
+type storageCollector struct {
+	totals StorageTotalsFunc
+
+	artifacts       *prometheus.Desc
+	blocks          *prometheus.Desc
+	assetReferences *prometheus.Desc
+	assetRefs       *prometheus.Desc
+	assetBytes      *prometheus.Desc
+	up              *prometheus.Desc
+
+	mu       sync.Mutex
+	cached   *model.StorageTotals
+	cachedAt time.Time
+}
+
+func newStorageCollector(totals StorageTotalsFunc) *storageCollector {
+	return &storageCollector{
+		totals:          totals,
+		artifacts:       prometheus.NewDesc("acontext_artifacts", "Number of artifacts.", nil, nil),
+		blocks:          prometheus.NewDesc("acontext_blocks", "Number of blocks.", nil, nil),
+		assetReferences: prometheus.NewDesc("acontext_asset_references", "Number of stored assets, one per content hash and project.", nil, nil),
+		assetRefs:       prometheus.NewDesc("acontext_asset_refs", "Sum of the reference counts of the stored assets.", nil, nil),
+		assetBytes:      prometheus.NewDesc("acontext_asset_stored_bytes", "Total size of the stored assets.", nil, nil),
+		up:              prometheus.NewDesc("acontext_storage_totals_up", "Whether the stored data totals could be read.", nil, nil),
+	}
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.artifacts, c.blocks, c.assetReferences, c.assetRefs, c.assetBytes, c.up} {
+		ch <- d
+	}
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	totals, err := c.read()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(c.artifacts, prometheus.GaugeValue, float64(totals.Artifacts))
+	ch <- prometheus.MustNewConstMetric(c.blocks, prometheus.GaugeValue, float64(totals.Blocks))
+	ch <- prometheus.MustNewConstMetric(c.assetReferences, prometheus.GaugeValue, float64(totals.AssetReferences))
+	ch <- prometheus.MustNewConstMetric(c.assetRefs, prometheus.GaugeValue, float64(totals.AssetRefs))
+	ch <- prometheus.MustNewConstMetric(c.assetBytes, prometheus.GaugeValue, float64(totals.AssetBytes))
+}
+
+// read returns the totals, reading them again once they are older than storageTotalsTTL
+func (c *storageCollector) read() (*model.StorageTotals, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached != nil && time.Since(c.cachedAt) < storageTotalsTTL {
+		return c.cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	totals, err := c.totals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.cached, c.cachedAt = totals, time.Now()
+	return totals, nil
+}
@@ -1,4 +1,5 @@
 from dataclasses import dataclass, field
+from datetime import datetime
 from sqlalchemy import (
     String,
     ForeignKey,
@@ -7,6 +8,7 @@ from sqlalchemy import (
     Column,
     Boolean,
     BigInteger,
+    DateTime,
 )
 from sqlalchemy.orm import relationship
 from sqlalchemy.dialects.postgresql import JSONB, UUID
@@ -99,6 +101,7 @@ class Block(CommonMixin):
         Index("idx_blocks_space_type", "space_id", "type"),
         Index("idx_blocks_space_title", "space_id", "title"),
         Index("idx_blocks_space_type_archived", "space_id", "type", "is_archived"),
+        Index("idx_blocks_space_last_viewed", "space_id", "last_viewed_at"),
         # Unique constraint for space, parent, sort combination
         Index(
             "ux_blocks_space_parent_sort", "space_id", "parent_id", "sort", unique=True
@@ -185,6 +188,17 @@ class Block(CommonMixin):
         },
     )
 
+    # Flushed time of the latest read of the block, written by the API
+    last_viewed_at: Optional[datetime] = field(
+        default=None,
+        metadata={
+            "db": Column(
+                DateTime(timezone=True),
+                nullable=True,
+            )
+        },
+    )
+
     # Relationships
     space: "Space" = field(
         init=False,
@@ -68,6 +68,7 @@ class Message(CommonMixin):
         Index("ix_message_session_id", "session_id"),
         Index("ix_message_parent_id", "parent_id"),
         Index("idx_session_created", "session_id", "created_at"),
+        Index("idx_session_content_hash", "session_id", "content_hash"),
     )
 
     session_id: asUUID = field(
@@ -117,6 +118,12 @@ class Message(CommonMixin):
         metadata={"db": Column(String, nullable=False, server_default="pending")},
     )
 
+    # Hash of the role and parts, written by the API to find duplicate messages
+    content_hash: Optional[str] = field(
+        default=None,
+        metadata={"db": Column(String, nullable=True)},
+    )
+
     # Relationships
     session: "Session" = field(
         init=False, metadata={"db": relationship("Session", back_populates="messages")}
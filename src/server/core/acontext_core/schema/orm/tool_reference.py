@@ -22,7 +22,7 @@ class ToolReference(CommonMixin):
 
     __table_args__ = (
         Index("ix_tool_reference_project_id", "project_id"),
-        Index("ix_tool_reference_project_id_name", "project_id", "name"),
+        Index("ix_tool_reference_project_id_name", "project_id", "name", unique=True),
     )
 
     name: str = field(metadata={"db": Column(String, nullable=False)})
@@ -0,0 +1,19 @@
+-- Migration: Make tool reference names unique per project
+-- Date: 2026-10-16
+-- Description: Replace the (project_id, name) index of tool_references with a unique one, so two
+-- concurrent requests cannot create tool references of the same name
+
+-- Duplicates make the unique index fail; list them and rename or delete them first:
+-- SELECT project_id, name, COUNT(*)
+-- FROM tool_references
+-- GROUP BY project_id, name
+-- HAVING COUNT(*) > 1;
+
+BEGIN;
+
+DROP INDEX IF EXISTS ix_tool_reference_project_id_name;
+
+CREATE UNIQUE INDEX ix_tool_reference_project_id_name
+ON tool_references (project_id, name);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Migration: Key asset references by bucket
+-- Date: 2026-10-16
+-- Description: Add the bucket of the stored object to asset_references and make it part of the
+-- unique key, so the same content can be referenced in the bucket of a project and in the
+-- deployment's bucket at once
+
+BEGIN;
+
+ALTER TABLE asset_references
+ADD COLUMN IF NOT EXISTS bucket TEXT NOT NULL DEFAULT '';
+
+-- Every asset records the bucket it was uploaded to
+UPDATE asset_references
+SET bucket = COALESCE(asset_meta->>'bucket', '')
+WHERE bucket = '';
+
+DROP INDEX IF EXISTS idx_project_sha256;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_bucket_sha256
+ON asset_references (project_id, bucket, sha256);
+
+COMMIT;